@@ -42,6 +42,8 @@ import (
 	"github.com/mapprotocol/atlas/accounts/scwallet"
 	"github.com/mapprotocol/atlas/chains"
 	"github.com/mapprotocol/atlas/chains/interfaces"
+	"github.com/mapprotocol/atlas/consensus"
+	"github.com/mapprotocol/atlas/consensus/istanbul"
 	"github.com/mapprotocol/atlas/consensus/misc"
 	"github.com/mapprotocol/atlas/core"
 	"github.com/mapprotocol/atlas/core/chain"
@@ -1621,6 +1623,139 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	return fields, nil
 }
 
+// PublicAtlasAPI provides an API to access the data specific to atlas blocks.
+type PublicAtlasAPI struct {
+	b Backend
+}
+
+// NewPublicAtlasAPI creates a new API definition for the atlas specific methods.
+func NewPublicAtlasAPI(b Backend) *PublicAtlasAPI {
+	return &PublicAtlasAPI{b}
+}
+
+// GetBlockFinalizationReceipt returns the receipt of the logs emitted while
+// finalizing the block outside of its transactions, such as the epoch rewards
+// and validator set changes. It has the block hash as transaction hash and
+// follows the receipts of the transactions. Blocks without such logs have none.
+func (s *PublicAtlasAPI) GetBlockFinalizationReceipt(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (map[string]interface{}, error) {
+	block, err := s.b.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if block == nil || err != nil {
+		return nil, err
+	}
+	receipts, err := s.b.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	return finalizationReceiptFields(block, receipts), nil
+}
+
+// finalizationReceiptFields returns the RPC representation of the block
+// finalization receipt, or nil if the receipts of the block have none.
+func finalizationReceiptFields(block *types.Block, receipts types.Receipts) map[string]interface{} {
+	index := len(block.Transactions())
+	if len(receipts) != index+1 {
+		return nil
+	}
+	receipt := receipts[index]
+	fields := map[string]interface{}{
+		"blockHash":         block.Hash(),
+		"blockNumber":       hexutil.Uint64(block.NumberU64()),
+		"transactionHash":   block.Hash(),
+		"transactionIndex":  hexutil.Uint64(index),
+		"gasUsed":           hexutil.Uint64(0),
+		"cumulativeGasUsed": hexutil.Uint64(receipt.CumulativeGasUsed),
+		"logs":              receipt.Logs,
+		"logsBloom":         receipt.Bloom,
+		"status":            hexutil.Uint(receipt.Status),
+	}
+	if receipt.Logs == nil {
+		fields["logs"] = []*types.Log{}
+	}
+	return fields
+}
+
+var errNotIstanbul = errors.New("epoch rewards are only available with the istanbul engine")
+
+// EpochRewards creates a subscription notified with the rewards distributed at
+// the end of every epoch reached by the canonical chain. Websocket clients
+// subscribe with atlas_subscribe and "epochRewards".
+func (s *PublicAtlasAPI) EpochRewards(ctx context.Context) (*rpc.Subscription, error) {
+	engine, ok := s.b.Engine().(consensus.Istanbul)
+	if !ok {
+		return nil, errNotIstanbul
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		rewards := make(chan istanbul.EpochRewardsProcessed)
+		rewardsSub := engine.SubscribeEpochRewards(rewards)
+		defer rewardsSub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-rewards:
+				notifier.Notify(rpcSub.ID, epochRewardsFields(&ev))
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// GetEpochRewards returns the rewards distributed at the end of the given
+// epoch, if it is among the recent epochs kept in memory.
+func (s *PublicAtlasAPI) GetEpochRewards(epoch hexutil.Uint64) (map[string]interface{}, error) {
+	engine, ok := s.b.Engine().(consensus.Istanbul)
+	if !ok {
+		return nil, errNotIstanbul
+	}
+	rewards := engine.EpochRewards(uint64(epoch))
+	if rewards == nil {
+		return nil, fmt.Errorf("rewards of epoch %d not available", epoch)
+	}
+	return epochRewardsFields(rewards), nil
+}
+
+// GetRecentEpochRewards returns the rewards of the recent epochs kept in
+// memory, ordered by epoch.
+func (s *PublicAtlasAPI) GetRecentEpochRewards() ([]map[string]interface{}, error) {
+	engine, ok := s.b.Engine().(consensus.Istanbul)
+	if !ok {
+		return nil, errNotIstanbul
+	}
+	recent := engine.RecentEpochRewards()
+	fields := make([]map[string]interface{}, len(recent))
+	for i, rewards := range recent {
+		fields[i] = epochRewardsFields(rewards)
+	}
+	return fields, nil
+}
+
+// epochRewardsFields returns the RPC representation of the rewards of an epoch.
+func epochRewardsFields(rewards *istanbul.EpochRewardsProcessed) map[string]interface{} {
+	payments := make(map[common.Address]*hexutil.Big, len(rewards.ValidatorPayments))
+	for addr, payment := range rewards.ValidatorPayments {
+		payments[addr] = (*hexutil.Big)(payment)
+	}
+	return map[string]interface{}{
+		"epoch":             hexutil.Uint64(rewards.Epoch),
+		"blockNumber":       hexutil.Uint64(rewards.BlockNumber),
+		"blockHash":         rewards.BlockHash,
+		"validatorPayments": payments,
+		"totalVoterRewards": (*hexutil.Big)(rewards.TotalVoterRewards),
+		"communityFund":     (*hexutil.Big)(rewards.CommunityFund),
+	}
+}
+
 // sign is a helper function that signs a transaction with the private key of the given address.
 func (s *PublicTransactionPoolAPI) sign(addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
 	// Look up the wallet containing the requested signer
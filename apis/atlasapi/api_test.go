@@ -2,10 +2,13 @@ package atlasapi
 
 import (
 	"fmt"
-	//"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	//"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/trie"
-	"testing"
 	"github.com/mapprotocol/atlas/core/types"
 )
 
@@ -16,4 +19,28 @@ func Test01(t *testing.T) {
 	fmt.Println(EmptyRootHash0)
 }
 
+func TestFinalizationReceiptFields(t *testing.T) {
+	tx := types.NewTransaction(1, common.HexToAddress("0x1"), big.NewInt(1), 21000, big.NewInt(1), nil)
+	final := &types.Receipt{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{{Address: common.HexToAddress("0x2")}}}
 
+	for _, txs := range []types.Transactions{nil, {tx}} {
+		block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(100)}).WithBody(txs, nil, nil)
+		receipts := make(types.Receipts, len(txs))
+		for i := range receipts {
+			receipts[i] = &types.Receipt{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 21000}
+		}
+		if fields := finalizationReceiptFields(block, receipts); fields != nil {
+			t.Errorf("%d txs: finalization receipt of a block without one: %v", len(txs), fields)
+		}
+		fields := finalizationReceiptFields(block, append(receipts, final))
+		if fields == nil {
+			t.Fatalf("%d txs: missing finalization receipt", len(txs))
+		}
+		if fields["transactionHash"] != block.Hash() || fields["transactionIndex"] != hexutil.Uint64(len(txs)) {
+			t.Errorf("%d txs: fields mismatch: %v", len(txs), fields)
+		}
+		if logs := fields["logs"].([]*types.Log); len(logs) != 1 {
+			t.Errorf("%d txs: log count mismatch: have %d, want 1", len(txs), len(logs))
+		}
+	}
+}
@@ -139,6 +139,11 @@ func GetAPIs(apiBackend Backend, engine consensus.Engine) []rpc.API {
 			Version:   "1.0",
 			Service:   NewPrivateAccountAPI(apiBackend, nonceLock),
 			Public:    false,
+		}, {
+			Namespace: "atlas",
+			Version:   "1.0",
+			Service:   NewPublicAtlasAPI(apiBackend),
+			Public:    true,
 		}, {
 			Namespace: "header",
 			Version:   "1.0",
@@ -19,6 +19,7 @@ package web3ext
 
 var Modules = map[string]string{
 	"admin":    AdminJs,
+	"atlas":    AtlasJs,
 	"clique":   CliqueJs,
 	"ethash":   EthashJs,
 	"debug":    DebugJs,
@@ -74,6 +75,32 @@ web3._extend({
 });
 `
 
+const AtlasJs = `
+web3._extend({
+	property: 'atlas',
+	methods:
+	[
+		new web3._extend.Method({
+			name: 'getBlockFinalizationReceipt',
+			call: 'atlas_getBlockFinalizationReceipt',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getEpochRewards',
+			call: 'atlas_getEpochRewards',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getRecentEpochRewards',
+			call: 'atlas_getRecentEpochRewards',
+			params: 0
+		}),
+	],
+	properties: []
+});
+`
+
 const Istanbul_JS = `
 web3._extend({
 	property: 'istanbul',
@@ -91,6 +118,12 @@ web3._extend({
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'getValidatorsBLSAggregate',
+			call: 'istanbul_getValidatorsBLSAggregate',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 		new web3._extend.Method({
 			name: 'getValidatorsBLSPublicKeys',
 			call: 'istanbul_getValidatorsBLSPublicKeys',
@@ -103,12 +136,40 @@ web3._extend({
 			params: 2,
 			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, null]
 		}),
+		new web3._extend.Method({
+			name: 'getEpochSize',
+			call: 'istanbul_getEpochSize',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'getSigners',
+			call: 'istanbul_getSigners',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 		new web3._extend.Method({
 			name: 'getLookbackWindow',
 			call: 'istanbul_getLookbackWindow',
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'getElectedValidators',
+			call: 'istanbul_getElectedValidators',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getElectionSize',
+			call: 'istanbul_getElectionSize',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getEvidence',
+			call: 'istanbul_getEvidence',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'addProxy',
 			call: 'istanbul_addProxy',
@@ -141,6 +202,16 @@ web3._extend({
 			call: 'istanbul_stopValidating',
 			params: 0,
 		}),
+		new web3._extend.Method({
+			name: 'startAnnouncing',
+			call: 'istanbul_startAnnouncing',
+			params: 0,
+		}),
+		new web3._extend.Method({
+			name: 'stopAnnouncing',
+			call: 'istanbul_stopAnnouncing',
+			params: 0,
+		}),
 		new web3._extend.Property({
 			name: 'valEnodeTableInfo',
 			getter: 'istanbul_getValEnodeTable',
@@ -157,6 +228,10 @@ web3._extend({
 			name: 'proxies',
 			getter: 'istanbul_getProxiesInfo',
 		}),
+		new web3._extend.Property({
+			name: 'proxyStatus',
+			getter: 'istanbul_getProxyStatus',
+		}),
 		new web3._extend.Property({
 			name: 'proxiedValidators',
 			getter: 'istanbul_getProxiedValidators',
@@ -169,6 +244,10 @@ web3._extend({
 			name: 'replicaState',
 			getter: 'istanbul_getCurrentReplicaState',
 		}),
+		new web3._extend.Property({
+			name: 'engineStatus',
+			getter: 'istanbul_getEngineStatus',
+		}),
 	],
 	properties: []
 });
@@ -397,6 +476,12 @@ web3._extend({
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'getEpochBoundary',
+			call: 'debug_getEpochBoundary',
+			params: 1,
+			inputFormatter: [null]
+		}),
 		new web3._extend.Method({
 			name: 'chaindbProperty',
 			call: 'debug_chaindbProperty',
@@ -585,6 +670,11 @@ web3._extend({
 			call: 'debug_getBadBlocks',
 			params: 0,
 		}),
+		new web3._extend.Method({
+			name: 'replayBadBlock',
+			call: 'debug_replayBadBlock',
+			params: 1,
+		}),
 		new web3._extend.Method({
 			name: 'storageRangeAt',
 			call: 'debug_storageRangeAt',
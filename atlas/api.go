@@ -300,6 +300,33 @@ func (api *PublicDebugAPI) DumpBlock(blockNr rpc.BlockNumber) (state.Dump, error
 	return stateDb.RawDump(opts), nil
 }
 
+// EpochBoundaryResult is the last block of an epoch as returned by
+// debug_getEpochBoundary.
+type EpochBoundaryResult struct {
+	Epoch  hexutil.Uint64 `json:"epoch"`
+	Number hexutil.Uint64 `json:"number"`
+	Hash   common.Hash    `json:"hash"`
+}
+
+// GetEpochBoundary returns the last block of the given epoch, or of the latest
+// finalized epoch if none is given.
+func (api *PublicDebugAPI) GetEpochBoundary(epoch *hexutil.Uint64) (*EpochBoundaryResult, error) {
+	bc := api.eth.BlockChain()
+	if epoch == nil {
+		latest, _ := bc.LatestFinalizedEpoch()
+		epoch = (*hexutil.Uint64)(&latest)
+	}
+	boundary := bc.GetEpochBoundary(uint64(*epoch))
+	if boundary == nil {
+		return nil, fmt.Errorf("epoch %d not finalized", uint64(*epoch))
+	}
+	return &EpochBoundaryResult{
+		Epoch:  *epoch,
+		Number: hexutil.Uint64(boundary.Number),
+		Hash:   boundary.Hash,
+	}, nil
+}
+
 // PrivateDebugAPI is the collection of Ethereum full node APIs exposed over
 // the private debugging endpoint.
 type PrivateDebugAPI struct {
@@ -322,9 +349,12 @@ func (api *PrivateDebugAPI) Preimage(ctx context.Context, hash common.Hash) (hex
 
 // BadBlockArgs represents the entries in the list returned when bad blocks are queried.
 type BadBlockArgs struct {
-	Hash  common.Hash            `json:"hash"`
-	Block map[string]interface{} `json:"block"`
-	RLP   string                 `json:"rlp"`
+	Hash     common.Hash            `json:"hash"`
+	Block    map[string]interface{} `json:"block"`
+	RLP      string                 `json:"rlp"`
+	Received uint64                 `json:"received,omitempty"`
+	Peer     string                 `json:"peer,omitempty"`
+	Error    string                 `json:"error,omitempty"`
 }
 
 // GetBadBlocks returns a list of the last 'bad blocks' that the client has seen on the network
@@ -335,8 +365,9 @@ func (api *PrivateDebugAPI) GetBadBlocks(ctx context.Context) ([]*BadBlockArgs,
 		blocks  = rawdb.ReadAllBadBlocks(api.eth.chainDb)
 		results = make([]*BadBlockArgs, 0, len(blocks))
 	)
-	for _, block := range blocks {
+	for _, bad := range blocks {
 		var (
+			block     = bad.Block
 			blockRlp  string
 			blockJSON map[string]interface{}
 		)
@@ -348,15 +379,30 @@ func (api *PrivateDebugAPI) GetBadBlocks(ctx context.Context) ([]*BadBlockArgs,
 		if blockJSON, err = atlasapi.RPCMarshalBlock(block, true, true, api.eth.APIBackend.ChainConfig()); err != nil {
 			blockJSON = map[string]interface{}{"error": err.Error()}
 		}
-		results = append(results, &BadBlockArgs{
+		result := &BadBlockArgs{
 			Hash:  block.Hash(),
 			RLP:   blockRlp,
 			Block: blockJSON,
-		})
+		}
+		if bad.Meta != nil {
+			result.Received, result.Peer, result.Error = bad.Meta.Received, bad.Meta.Peer, bad.Meta.Error
+		}
+		results = append(results, result)
 	}
 	return results, nil
 }
 
+// ReplayBadBlock re-executes the stored bad block with the given hash on the
+// state of its parent and reports the first check it fails, along with the
+// expected and computed values.
+func (api *PrivateDebugAPI) ReplayBadBlock(ctx context.Context, hash common.Hash) (*chain.ReplayResult, error) {
+	block := rawdb.ReadBadBlock(api.eth.chainDb, hash)
+	if block == nil {
+		return nil, fmt.Errorf("bad block %#x not found", hash)
+	}
+	return api.eth.blockchain.ReplayBlock(block)
+}
+
 // AccountRangeMaxResults is the maximum number of results to be returned per call
 const AccountRangeMaxResults = 256
 
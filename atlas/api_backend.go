@@ -19,6 +19,7 @@ package atlas
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/mapprotocol/atlas/core/chain"
 	"math/big"
 	"time"
@@ -192,9 +193,9 @@ func (b *EthAPIBackend) GetLogs(ctx context.Context, hash common.Hash) ([][]*typ
 	if number == nil {
 		return nil, errors.New("failed to get block number from hash")
 	}
-	logs := rawdb.ReadLogs(db, hash, *number)
-	if logs == nil {
-		return nil, errors.New("failed to get logs for block")
+	logs, err := rawdb.ReadLogs(db, hash, *number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get logs for block: %w", err)
 	}
 	return logs, nil
 }
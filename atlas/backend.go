@@ -174,6 +174,9 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		} else if bcVersion == nil || *bcVersion < chain.BlockChainVersion {
 			if bcVersion != nil { // only print warning on upgrade, not on init
 				log.Warn("Upgrade blockchain database version", "from", dbVer, "to", chain.BlockChainVersion)
+				if err := rawdb.MigrateSchema(chainDb, *bcVersion, chain.BlockChainVersion); err != nil {
+					return nil, err
+				}
 			}
 			rawdb.WriteDatabaseVersion(chainDb, chain.BlockChainVersion)
 		}
@@ -194,6 +197,7 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			Preimages:           config.Preimages,
 		}
 	)
+	rawdb.SetBadBlockRetention(config.BadBlockRetention)
 	eth.blockchain, err = chain.NewBlockChain(chainDb, cacheConfig, chainConfig, eth.engine, vmConfig, eth.shouldPreserve, &config.TxLookupLimit)
 	if err != nil {
 		return nil, err
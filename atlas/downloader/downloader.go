@@ -209,6 +209,10 @@ type BlockChain interface {
 	// InsertChain inserts a batch of blocks into the local chain.
 	InsertChain(types.Blocks) (int, error)
 
+	// InsertChainFromPeer inserts a batch of blocks received from the given
+	// peer into the local chain.
+	InsertChainFromPeer(types.Blocks, string) (int, error)
+
 	// InsertReceiptChain inserts a batch of receipts into the local chain.
 	InsertReceiptChain(types.Blocks, []types.Receipts, uint64) (int, error)
 
@@ -1765,7 +1769,11 @@ func (d *Downloader) importBlockResults(results []*fetchResult) error {
 	for i, result := range results {
 		blocks[i] = types.NewBlockWithHeader(result.Header).WithBody(result.Transactions, result.Randomness, result.EpochSnarkData)
 	}
-	if index, err := d.blockchain.InsertChain(blocks); err != nil {
+	// The blocks are attributed to the master peer of the sync
+	d.cancelLock.RLock()
+	peer := d.cancelPeer
+	d.cancelLock.RUnlock()
+	if index, err := d.blockchain.InsertChainFromPeer(blocks, peer); err != nil {
 		if index < len(results) {
 			log.Debug("Downloaded item processing failed", "number", results[index].Header.Number, "hash", results[index].Header.Hash(), "err", err)
 		} else {
@@ -294,6 +294,11 @@ func (dl *downloadTester) InsertHeaderChain(headers []*types.Header, checkFreq i
 	return len(headers), nil
 }
 
+// InsertChainFromPeer injects a new batch of blocks into the simulated chain.
+func (dl *downloadTester) InsertChainFromPeer(blocks types.Blocks, peer string) (i int, err error) {
+	return dl.InsertChain(blocks)
+}
+
 // InsertChain injects a new batch of blocks into the simulated chain.
 func (dl *downloadTester) InsertChain(blocks types.Blocks) (i int, err error) {
 	dl.lock.Lock()
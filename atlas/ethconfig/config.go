@@ -57,6 +57,7 @@ var Defaults = Config{
 	SyncMode:                downloader.SnapSync,
 	NetworkId:               params.MainnetNetWorkID,
 	TxLookupLimit:           2350000,
+	BadBlockRetention:       10,
 	LightPeers:              100,
 	LightServ:               0,
 	UltraLightFraction:      75,
@@ -126,6 +127,8 @@ type Config struct {
 
 	TxLookupLimit uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
 
+	BadBlockRetention int `toml:",omitempty"` // The number of rejected blocks kept in the database for debugging.
+
 	// Whitelist of required block number -> hash values to accept
 	Whitelist map[uint64]common.Hash `toml:"-"`
 
@@ -0,0 +1,38 @@
+package ethereum
+
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/mapprotocol/atlas/chains"
+	"github.com/mapprotocol/atlas/core/rawdb"
+)
+
+// ReadHeader retrieves the ethereum header of the given chain from the local
+// database, or nil if it is not stored.
+func ReadHeader(db ethdb.KeyValueReader, hash common.Hash, number uint64, chain chains.ChainType) *Header {
+	data := rawdb.ReadHeaderRLPChains(db, hash, number, chain)
+	if len(data) == 0 {
+		return nil
+	}
+	header := new(Header)
+	if err := rlp.Decode(bytes.NewReader(data), header); err != nil {
+		log.Error("Invalid ethereum header RLP", "chain", chain, "hash", hash, "err", err)
+		return nil
+	}
+	return header
+}
+
+// WriteHeader stores the ethereum header of the given chain into the local
+// database along with its hash-to-number mapping.
+func WriteHeader(db ethdb.KeyValueWriter, header *Header, chain chains.ChainType) {
+	data, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		log.Crit("Failed to RLP encode ethereum header", "err", err)
+	}
+	rawdb.WriteHeaderRLPChains(db, header.Hash(), header.Number.Uint64(), data, chain)
+}
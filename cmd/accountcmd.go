@@ -282,7 +282,7 @@ func accountCreate(ctx *cli.Context) error {
 	if err != nil {
 		utils.Fatalf("Failed to create account: %v", err)
 	}
-	accountBls, err := accountTool.LoadAccount(account.URL.Path, password)
+	accountBls, err := accountTool.LoadAccount(account.URL.Path, "", password)
 	if err != nil {
 		utils.Fatalf("Failed to create account: %v", err)
 	}
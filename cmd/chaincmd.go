@@ -175,6 +175,22 @@ The export-preimages command export hash preimages to an RLP encoded stream`,
 This command dumps out the state for a given block (or latest, if none provided).
 `,
 	}
+	replayBadBlockCommand = cli.Command{
+		Action:    utils.MigrateFlags(replayBadBlock),
+		Name:      "replay-bad-block",
+		Usage:     "Re-execute a stored bad block and report why it was rejected",
+		ArgsUsage: "<blockHash>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The replay-bad-block command re-executes a block rejected by the node on the
+state of its parent. It prints the first failing check, e.g. the header
+verification or the state root, with the expected and computed values. Without
+a hash, the stored bad blocks are listed.`,
+	}
 )
 
 // initGenesis will initialise the given JSON format genesis file and writes it as
@@ -464,6 +480,37 @@ func dump(ctx *cli.Context) error {
 	return nil
 }
 
+func replayBadBlock(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, db := utils.MakeChain(ctx, stack)
+	defer db.Close()
+	defer chain.Stop()
+
+	if len(ctx.Args()) < 1 {
+		for _, bad := range rawdb.ReadAllBadBlocks(db) {
+			fmt.Printf("%d %s\n", bad.Block.NumberU64(), bad.Block.Hash().Hex())
+		}
+		return nil
+	}
+	hash := common.HexToHash(ctx.Args().First())
+	block := rawdb.ReadBadBlock(db, hash)
+	if block == nil {
+		return fmt.Errorf("bad block %s not found", hash.Hex())
+	}
+	result, err := chain.ReplayBlock(block)
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
 // hashish returns true for strings that look like hashes.
 func hashish(x string) bool {
 	_, err := strconv.Atoi(x)
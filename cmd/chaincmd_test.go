@@ -52,7 +52,7 @@ func Test_CreatAccount(T *testing.T) {
 		if err != nil {
 			utils.Fatalf("Failed to create account: %v", err)
 		}
-		accountBls, err := accountTool.LoadAccount(account.URL.Path, password)
+		accountBls, err := accountTool.LoadAccount(account.URL.Path, "", password)
 		if err != nil {
 			utils.Fatalf("Failed to create account: %v", err)
 		}
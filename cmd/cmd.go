@@ -84,6 +84,7 @@ var (
 		utils.GCModeFlag,
 		utils.SnapshotFlag,
 		utils.TxLookupLimitFlag,
+		utils.BadBlockRetentionFlag,
 		utils.LightServeFlag,
 		utils.LightIngressFlag,
 		utils.LightEgressFlag,
@@ -198,6 +199,7 @@ func init() {
 		exportPreimagesCommand,
 		removedbCommand,
 		dumpCommand,
+		replayBadBlockCommand,
 		dumpGenesisCommand,
 		// See accountcmd.go:
 		accountCommand,
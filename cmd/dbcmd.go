@@ -62,6 +62,10 @@ Remove blockchain and state databases`,
 			dbPutCmd,
 			dbGetSlotsCmd,
 			dbDumpFreezerIndex,
+			dbCheckAncientsCmd,
+			dbRepairAncientsCmd,
+			dbCheckCmd,
+			dbRepairCanonicalCmd,
 		},
 	}
 	dbInspectCmd = cli.Command{
@@ -171,6 +175,64 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		},
 		Description: "This command displays information about the freezer index.",
 	}
+	dbCheckAncientsCmd = cli.Command{
+		Action: utils.MigrateFlags(checkAncients),
+		Name:   "check-ancients",
+		Usage:  "Check the consistency of the ancient database",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.SyncModeFlag,
+			utils.MainnetFlag,
+			utils.TestnetFlag,
+		},
+		Description: `This command checks that all the freezer tables have the same length, that
+the frozen headers match their hashes and that leveldb continues the chain where
+the freezer left off.`,
+	}
+	dbRepairAncientsCmd = cli.Command{
+		Action: utils.MigrateFlags(repairAncients),
+		Name:   "repair-ancients",
+		Usage:  "Truncate the ancient database to its last consistent item (WARNING: drops ancient data)",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.SyncModeFlag,
+			utils.MainnetFlag,
+			utils.TestnetFlag,
+		},
+		Description: `This command truncates all the freezer tables to the last item that passes
+the check-ancients checks.
+WARNING: The dropped blocks have to be synced again!`,
+	}
+	dbCheckCmd = cli.Command{
+		Action:    utils.MigrateFlags(checkCanonical),
+		Name:      "check",
+		Usage:     "Check the consistency of the canonical chain",
+		ArgsUsage: "[<from> <to>]",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.SyncModeFlag,
+			utils.MainnetFlag,
+			utils.TestnetFlag,
+		},
+		Description: `This command walks the canonical chain, by default from genesis to the head
+header, and checks the number->hash->header->parent linkage, that the total
+difficulty increases and that the blocks up to the head block have their body
+and receipts. All the gaps and mismatches found are listed.`,
+	}
+	dbRepairCanonicalCmd = cli.Command{
+		Action: utils.MigrateFlags(repairCanonical),
+		Name:   "repair-canonical",
+		Usage:  "Rewind the head pointers to the highest consistent canonical block",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.SyncModeFlag,
+			utils.MainnetFlag,
+			utils.TestnetFlag,
+		},
+		Description: `This command rewinds the head header, fast block and block pointers to the
+highest block up to which the canonical chain passes the check command's checks.
+No data is deleted, the blocks above it are synced again.`,
+	}
 )
 
 func removeDB(ctx *cli.Context) error {
@@ -483,3 +545,98 @@ func freezerInspect(ctx *cli.Context) error {
 	}
 	return nil
 }
+
+// checkAncients validates the ancient database against itself and leveldb
+func checkAncients(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	valid, err := rawdb.ValidateAncients(db)
+	if err != nil {
+		log.Error("Ancient database is inconsistent", "consistent", valid, "err", err)
+		return err
+	}
+	log.Info("Ancient database is consistent", "items", valid)
+	return nil
+}
+
+// repairAncients truncates the ancient database to its last consistent item
+func repairAncients(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	valid, err := rawdb.RepairAncients(db)
+	if err != nil {
+		log.Error("Failed to repair ancient database", "items", valid, "err", err)
+		return err
+	}
+	log.Info("Ancient database repaired", "items", valid)
+	return nil
+}
+
+// checkCanonical validates the linkage of the canonical chain
+func checkCanonical(ctx *cli.Context) error {
+	if ctx.NArg() != 0 && ctx.NArg() != 2 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	var from, to uint64
+	if ctx.NArg() == 2 {
+		var err error
+		if from, err = strconv.ParseUint(ctx.Args().Get(0), 10, 64); err != nil {
+			return err
+		}
+		if to, err = strconv.ParseUint(ctx.Args().Get(1), 10, 64); err != nil {
+			return err
+		}
+	} else {
+		head := rawdb.ReadHeaderNumber(db, rawdb.ReadHeadHeaderHash(db))
+		if head == nil {
+			return fmt.Errorf("missing head header")
+		}
+		to = *head
+	}
+	report, err := rawdb.CheckCanonicalChain(db, from, to)
+	if err != nil {
+		return err
+	}
+	for _, number := range report.Gaps {
+		log.Error("Missing canonical hash", "number", number)
+	}
+	for _, issue := range report.Issues {
+		log.Error("Inconsistent canonical block", "number", issue.Number, "hash", issue.Hash, "reason", issue.Reason)
+	}
+	if !report.OK() {
+		return fmt.Errorf("canonical chain is inconsistent: %d gaps, %d issues, first %d blocks consistent", len(report.Gaps), len(report.Issues), report.Consistent)
+	}
+	log.Info("Canonical chain is consistent", "from", from, "to", to)
+	return nil
+}
+
+// repairCanonical rewinds the head pointers to the last consistent canonical block
+func repairCanonical(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	head, err := rawdb.RepairCanonicalChain(db)
+	if err != nil {
+		log.Error("Failed to repair canonical chain", "err", err)
+		return err
+	}
+	log.Info("Canonical chain repaired", "head", head)
+	return nil
+}
@@ -2,6 +2,7 @@ package account
 
 import (
 	"crypto/ecdsa"
+	"encoding/json"
 	"fmt"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/common"
@@ -10,6 +11,10 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/mapprotocol/atlas/helper/bls"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 )
 
 // Account represents a atlas Account
@@ -96,10 +101,21 @@ func (a *Account) String() string {
 		a.PrivateKeyHex(),
 	)
 }
-func LoadAccount(path string, password string) (*Account, error) {
-	keyjson, err := ioutil.ReadFile(path)
+
+// LoadAccount decrypts the account of a keystore. The path is either a key file
+// or a keystore directory, in which case the key file is the one of the account
+// given by selector: its index among the key files of the directory, sorted by
+// name as geth lists them, or its address. The selector may be empty if the
+// directory holds a single key file.
+func LoadAccount(path string, selector string, password string) (*Account, error) {
+	keyFile, err := SelectKeyFile(path, selector)
+	if err != nil {
+		log.Error("LoadAccount", "msg", err)
+		return nil, err
+	}
+	keyjson, err := ioutil.ReadFile(keyFile)
 	if err != nil {
-		log.Error("LoadAccount", "msg", fmt.Errorf("failed to read the keyfile at '%s': %v", path, err))
+		log.Error("LoadAccount", "msg", fmt.Errorf("failed to read the keyfile at '%s': %v", keyFile, err))
 		return nil, err
 	}
 	key, err := keystore.DecryptKey(keyjson, password)
@@ -117,3 +133,93 @@ func LoadAccount(path string, password string) (*Account, error) {
 		PrivateKey: priKey1,
 	}, nil
 }
+
+// SelectKeyFile returns the key file of the account given by selector in the
+// keystore directory path, or path itself if it is a file.
+func SelectKeyFile(path string, selector string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the keystore at '%s': %v", path, err)
+	}
+	if !info.IsDir() {
+		if selector != "" {
+			return "", fmt.Errorf("an account can only be selected in a keystore directory, '%s' is a file", path)
+		}
+		return path, nil
+	}
+
+	keyFiles, addresses, err := keyFiles(path)
+	if err != nil {
+		return "", err
+	}
+	if len(keyFiles) == 0 {
+		return "", fmt.Errorf("no key file in the keystore directory '%s'", path)
+	}
+
+	switch {
+	case selector == "":
+		if len(keyFiles) > 1 {
+			return "", fmt.Errorf("%d key files in the keystore directory '%s', select the account by index or address", len(keyFiles), path)
+		}
+		return keyFiles[0], nil
+
+	case common.IsHexAddress(selector):
+		address := common.HexToAddress(selector)
+		var matches []string
+		for i, keyFile := range keyFiles {
+			if addresses[i] == address {
+				matches = append(matches, keyFile)
+			}
+		}
+		if len(matches) == 0 {
+			return "", fmt.Errorf("no key file of account %v in the keystore directory '%s'", address, path)
+		}
+		if len(matches) > 1 {
+			return "", fmt.Errorf("%d key files of account %v in the keystore directory '%s': %s", len(matches), address, path, strings.Join(matches, ", "))
+		}
+		return matches[0], nil
+
+	default:
+		index, err := strconv.Atoi(selector)
+		if err != nil || index < 0 {
+			return "", fmt.Errorf("invalid account %q, want an index or an address", selector)
+		}
+		if index >= len(keyFiles) {
+			return "", fmt.Errorf("no account with index %d, the keystore directory '%s' has %d key files", index, path, len(keyFiles))
+		}
+		return keyFiles[index], nil
+	}
+}
+
+// keyFiles lists the key files of a keystore directory sorted by name along
+// with their addresses, skipping the files which are no keys as geth does.
+func keyFiles(dir string) ([]string, []common.Address, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read the keystore directory '%s': %v", dir, err)
+	}
+	var (
+		files     []string
+		addresses []common.Address
+	)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") {
+			continue
+		}
+		file := filepath.Join(dir, name)
+		keyjson, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read the keyfile at '%s': %v", file, err)
+		}
+		var key struct {
+			Address string `json:"address"`
+		}
+		if err := json.Unmarshal(keyjson, &key); err != nil || !common.IsHexAddress(key.Address) {
+			continue
+		}
+		files = append(files, file)
+		addresses = append(addresses, common.HexToAddress(key.Address))
+	}
+	return files, addresses, nil
+}
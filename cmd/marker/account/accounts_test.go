@@ -0,0 +1,76 @@
+package account
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestSelectKeyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "marker-keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	addr1 := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	addr2 := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	writeKey := func(name string, address common.Address) string {
+		file := filepath.Join(dir, name)
+		content := fmt.Sprintf(`{"address":"%x","crypto":{},"version":3}`, address)
+		if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+	key1 := writeKey("UTC--2021-01-01T00-00-00.000000000Z--1111111111111111111111111111111111111111", addr1)
+	if have, err := SelectKeyFile(dir, ""); err != nil || have != key1 {
+		t.Errorf("single key file: have (%s, %v), want %s", have, err, key1)
+	}
+	if have, err := SelectKeyFile(key1, ""); err != nil || have != key1 {
+		t.Errorf("key file path: have (%s, %v), want %s", have, err, key1)
+	}
+
+	key2 := writeKey("UTC--2021-01-02T00-00-00.000000000Z--2222222222222222222222222222222222222222", addr2)
+	writeKey(".hidden", addr2)
+	if err := ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		selector string
+		want     string
+	}{
+		{"", ""},
+		{"0", key1},
+		{"1", key2},
+		{"2", ""},
+		{"-1", ""},
+		{"first", ""},
+		{addr2.Hex(), key2},
+		{"0x3333333333333333333333333333333333333333", ""},
+	}
+	for _, tt := range tests {
+		have, err := SelectKeyFile(dir, tt.selector)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("selector %q: have %s, want error", tt.selector, have)
+			}
+		} else if err != nil || have != tt.want {
+			t.Errorf("selector %q: have (%s, %v), want %s", tt.selector, have, err, tt.want)
+		}
+	}
+
+	// A second key file of the same account is ambiguous
+	writeKey("UTC--2021-01-03T00-00-00.000000000Z--2222222222222222222222222222222222222222", addr2)
+	if have, err := SelectKeyFile(dir, addr2.Hex()); err == nil {
+		t.Errorf("ambiguous address: have %s, want error", have)
+	}
+	if _, err := SelectKeyFile(key1, "0"); err == nil {
+		t.Errorf("selector on a key file: want error")
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	ethchain "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/mapprotocol/atlas/accounts/abi"
+	"github.com/mapprotocol/atlas/cmd/marker/connections"
+	"github.com/mapprotocol/atlas/params"
+)
+
+var accountCommand = cli.Command{
+	Name:  "account",
+	Usage: "Account related queries",
+	Subcommands: []cli.Command{
+		{
+			Name:   "summary",
+			Usage:  "Report balance, locked gold, pending withdrawals, validator status and votes of `target` (default: the loaded account)",
+			Action: MigrateFlags(accountSummary),
+			Flags:  Flags,
+		},
+	},
+}
+
+type pendingWithdrawal struct {
+	Index     int      `json:"index"`
+	Value     *big.Int `json:"value"`
+	Timestamp *big.Int `json:"timestamp"`
+}
+
+type validatorSummary struct {
+	Signer         common.Address `json:"signer"`
+	Commission     string         `json:"commission"`
+	BlsPublicKey   hexutil.Bytes  `json:"blsPublicKey"`
+	BlsG1PublicKey hexutil.Bytes  `json:"blsG1PublicKey"`
+	Score          string         `json:"score"`
+}
+
+type voteSummary struct {
+	Validator common.Address `json:"validator"`
+	Pending   *big.Int       `json:"pending"`
+	Active    *big.Int       `json:"active"`
+}
+
+type accountSummaryResult struct {
+	Account             common.Address      `json:"account"`
+	BlockNumber         uint64              `json:"blockNumber"`
+	IsAccount           bool                `json:"isAccount"`
+	Balance             *big.Int            `json:"balance"`
+	TotalLockedGold     *big.Int            `json:"totalLockedGold"`
+	NonvotingLockedGold *big.Int            `json:"nonvotingLockedGold"`
+	VotingLockedGold    *big.Int            `json:"votingLockedGold"`
+	PendingWithdrawals  []pendingWithdrawal `json:"pendingWithdrawals"`
+	IsValidator         bool                `json:"isValidator"`
+	Validator           *validatorSummary   `json:"validator,omitempty"`
+	Votes               []voteSummary       `json:"votes"`
+}
+
+// contractCall is a single eth_call to be sent as part of a batch. After the
+// batch is executed out holds the unpacked return values, or err is set.
+type contractCall struct {
+	to     common.Address
+	abi    *abi.ABI
+	method string
+	args   []interface{}
+
+	out []interface{}
+	err error
+}
+
+func newContractCall(to common.Address, abi *abi.ABI, method string, args ...interface{}) *contractCall {
+	return &contractCall{to: to, abi: abi, method: method, args: args}
+}
+
+// callContractsAt executes all calls in a single JSON-RPC batch against the
+// state of the given block, so the results are consistent with each other.
+// Any extra elements are sent along in the same batch; checking their errors
+// is left to the caller.
+func callContractsAt(client *rpc.Client, from common.Address, number *big.Int, calls []*contractCall, extra ...rpc.BatchElem) error {
+	var (
+		batch   = make([]rpc.BatchElem, len(calls), len(calls)+len(extra))
+		outputs = make([]hexutil.Bytes, len(calls))
+	)
+	for i, call := range calls {
+		input, err := call.abi.Pack(call.method, call.args...)
+		if err != nil {
+			return fmt.Errorf("pack %s: %v", call.method, err)
+		}
+		to := call.to
+		batch[i] = rpc.BatchElem{
+			Method: "eth_call",
+			Args:   []interface{}{toCallArg(ethchain.CallMsg{From: from, To: &to, Data: input}), hexutil.EncodeBig(number)},
+			Result: &outputs[i],
+		}
+	}
+	batch = append(batch, extra...)
+	if err := client.BatchCallContext(context.Background(), batch); err != nil {
+		return err
+	}
+	copy(extra, batch[len(calls):])
+	for i, call := range calls {
+		if batch[i].Error != nil {
+			call.err = batch[i].Error
+			continue
+		}
+		call.out, call.err = call.abi.Unpack(call.method, outputs[i])
+	}
+	return nil
+}
+
+func accountSummary(_ *cli.Context, core *listener) error {
+	target := core.cfg.TargetAddress
+	if target == params.ZeroAddress {
+		target = core.cfg.From
+	}
+	client, _ := connections.DialRpc(core.cfg)
+	if client == nil {
+		return fmt.Errorf("failed to connect to %s:%d", core.cfg.Ip, core.cfg.Port)
+	}
+	defer client.Close()
+
+	number, err := core.conn.BlockNumber(context.Background())
+	if err != nil {
+		return err
+	}
+	block := new(big.Int).SetUint64(number)
+	summary := &accountSummaryResult{Account: target, BlockNumber: number}
+
+	var (
+		accountsAddress   = core.cfg.AccountsParameters.AccountsAddress
+		abiAccounts       = core.cfg.AccountsParameters.AccountsABI
+		lockedGoldAddress = core.cfg.LockedGoldParameters.LockedGoldAddress
+		abiLockedGold     = core.cfg.LockedGoldParameters.LockedGoldABI
+		validatorAddress  = core.cfg.ValidatorParameters.ValidatorAddress
+		abiValidators     = core.cfg.ValidatorParameters.ValidatorABI
+		electionAddress   = core.cfg.ElectionParameters.ElectionAddress
+		abiElection       = core.cfg.ElectionParameters.ElectionABI
+	)
+	// First round: everything that only depends on the target address.
+	var (
+		isAccount          = newContractCall(accountsAddress, abiAccounts, "isAccount", target)
+		totalLocked        = newContractCall(lockedGoldAddress, abiLockedGold, "getAccountTotalLockedGold", target)
+		nonvotingLocked    = newContractCall(lockedGoldAddress, abiLockedGold, "getAccountNonvotingLockedGold", target)
+		pendingWithdrawals = newContractCall(lockedGoldAddress, abiLockedGold, "getPendingWithdrawals", target)
+		isValidator        = newContractCall(validatorAddress, abiValidators, "isValidator", target)
+		votingLocked       = newContractCall(electionAddress, abiElection, "getTotalVotesByAccount", target)
+		votedFor           = newContractCall(electionAddress, abiElection, "getValidatorsVotedForByAccount", target)
+		balance            hexutil.Big
+	)
+	calls := []*contractCall{isAccount, totalLocked, nonvotingLocked, pendingWithdrawals, isValidator, votingLocked, votedFor}
+	getBalance := []rpc.BatchElem{{
+		Method: "eth_getBalance",
+		Args:   []interface{}{target, hexutil.EncodeBig(block)},
+		Result: &balance,
+	}}
+	if err := callContractsAt(client, core.cfg.CallSender(), block, calls, getBalance...); err != nil {
+		return err
+	}
+	for _, call := range calls {
+		if call.err != nil {
+			return fmt.Errorf("%s: %v", call.method, call.err)
+		}
+	}
+	if getBalance[0].Error != nil {
+		return fmt.Errorf("eth_getBalance: %v", getBalance[0].Error)
+	}
+	summary.Balance = balance.ToInt()
+	summary.IsAccount = isAccount.out[0].(bool)
+	summary.TotalLockedGold = totalLocked.out[0].(*big.Int)
+	summary.NonvotingLockedGold = nonvotingLocked.out[0].(*big.Int)
+	summary.VotingLockedGold = votingLocked.out[0].(*big.Int)
+	summary.IsValidator = isValidator.out[0].(bool)
+
+	values, timestamps := pendingWithdrawals.out[0].([]*big.Int), pendingWithdrawals.out[1].([]*big.Int)
+	summary.PendingWithdrawals = make([]pendingWithdrawal, len(values))
+	for i := range values {
+		summary.PendingWithdrawals[i] = pendingWithdrawal{Index: i, Value: values[i], Timestamp: timestamps[i]}
+	}
+
+	// Second round: validator details and the per-validator vote split.
+	validators := votedFor.out[0].([]common.Address)
+	calls = calls[:0]
+	var validator *contractCall
+	if summary.IsValidator {
+		validator = newContractCall(validatorAddress, abiValidators, "getValidator", target)
+		calls = append(calls, validator)
+	}
+	pending := make([]*contractCall, len(validators))
+	active := make([]*contractCall, len(validators))
+	for i, v := range validators {
+		pending[i] = newContractCall(electionAddress, abiElection, "getPendingVotesForValidatorByAccount", v, target)
+		active[i] = newContractCall(electionAddress, abiElection, "getActiveVotesForValidatorByAccount", v, target)
+		calls = append(calls, pending[i], active[i])
+	}
+	if len(calls) > 0 {
+		if err := callContractsAt(client, core.cfg.CallSender(), block, calls); err != nil {
+			return err
+		}
+		for _, call := range calls {
+			if call.err != nil {
+				return fmt.Errorf("%s: %v", call.method, call.err)
+			}
+		}
+	}
+	if validator != nil {
+		summary.Validator = &validatorSummary{
+			BlsPublicKey:   validator.out[1].([]byte),
+			BlsG1PublicKey: validator.out[2].([]byte),
+			Score:          ConvertToFraction(validator.out[3]),
+			Signer:         validator.out[4].(common.Address),
+			Commission:     ConvertToFraction(validator.out[5]),
+		}
+	}
+	summary.Votes = make([]voteSummary, len(validators))
+	for i, v := range validators {
+		summary.Votes[i] = voteSummary{
+			Validator: v,
+			Pending:   pending[i].out[0].(*big.Int),
+			Active:    active[i].out[0].(*big.Int),
+		}
+	}
+	return printAccountSummary(core.cfg.Output, summary)
+}
+
+func printAccountSummary(output string, summary *accountSummaryResult) error {
+	if output == "json" {
+		data, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	log.Info("=== account summary ===", "account", summary.Account, "blockNumber", summary.BlockNumber)
+	log.Info("", "isAccount", summary.IsAccount)
+	log.Info("", "balance", summary.Balance)
+	log.Info("", "totalLockedGold", summary.TotalLockedGold)
+	log.Info("", "nonvotingLockedGold", summary.NonvotingLockedGold)
+	log.Info("", "votingLockedGold", summary.VotingLockedGold)
+	if len(summary.PendingWithdrawals) == 0 {
+		log.Info("pendingWithdrawals", "result", "nil")
+	}
+	for _, w := range summary.PendingWithdrawals {
+		log.Info("pendingWithdrawal", "index", w.Index, "value", w.Value, "timestamp", w.Timestamp)
+	}
+	log.Info("", "isValidator", summary.IsValidator)
+	if v := summary.Validator; v != nil {
+		log.Info("validator", "signer", v.Signer, "commission", v.Commission, "score", v.Score)
+		log.Info("validator", "blsPublicKey", v.BlsPublicKey, "blsG1PublicKey", v.BlsG1PublicKey)
+	}
+	if len(summary.Votes) == 0 {
+		log.Info("votes", "result", "nil")
+	}
+	for _, v := range summary.Votes {
+		log.Info("vote", "validator", v.Validator, "pending", v.Pending, "active", v.Active)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	ethchain "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// batchCaller is the part of the RPC client needed to send batch requests.
+type batchCaller interface {
+	BatchCallContext(ctx context.Context, b []rpc.BatchElem) error
+}
+
+// toCallArg converts msg into eth_call arguments, the same way ethclient does.
+func toCallArg(msg ethchain.CallMsg) interface{} {
+	arg := map[string]interface{}{
+		"from": msg.From,
+		"to":   msg.To,
+	}
+	if len(msg.Data) > 0 {
+		arg["data"] = hexutil.Bytes(msg.Data)
+	}
+	if msg.Value != nil {
+		arg["value"] = (*hexutil.Big)(msg.Value)
+	}
+	if msg.Gas != 0 {
+		arg["gas"] = hexutil.Uint64(msg.Gas)
+	}
+	if msg.GasPrice != nil {
+		arg["gasPrice"] = (*hexutil.Big)(msg.GasPrice)
+	}
+	return arg
+}
+
+// batchCall executes all calls against the latest block in a single JSON-RPC
+// batch request and returns their outputs in order.
+func (l *listener) batchCall(calls []ethchain.CallMsg) ([][]byte, error) {
+	if l.rpc == nil {
+		return nil, errors.New("no RPC connection")
+	}
+	return batchCall(context.Background(), l.rpc, calls)
+}
+
+func batchCall(ctx context.Context, client batchCaller, calls []ethchain.CallMsg) ([][]byte, error) {
+	if len(calls) == 0 {
+		return nil, nil
+	}
+	var (
+		batch   = make([]rpc.BatchElem, len(calls))
+		outputs = make([]hexutil.Bytes, len(calls))
+	)
+	for i, msg := range calls {
+		batch[i] = rpc.BatchElem{
+			Method: "eth_call",
+			Args:   []interface{}{toCallArg(msg), "latest"},
+			Result: &outputs[i],
+		}
+	}
+	if err := client.BatchCallContext(ctx, batch); err != nil {
+		return nil, err
+	}
+	results := make([][]byte, len(calls))
+	for i := range batch {
+		if batch[i].Error != nil {
+			return nil, fmt.Errorf("call #%d: %v", i, batch[i].Error)
+		}
+		results[i] = outputs[i]
+	}
+	return results, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ethchain "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// echoBatchCaller answers every eth_call with its input data, failing the
+// calls without any.
+type echoBatchCaller struct {
+	requests int
+}
+
+func (c *echoBatchCaller) BatchCallContext(_ context.Context, b []rpc.BatchElem) error {
+	c.requests++
+	for i := range b {
+		arg := b[i].Args[0].(map[string]interface{})
+		data, ok := arg["data"].(hexutil.Bytes)
+		if !ok {
+			b[i].Error = errors.New("execution reverted")
+			continue
+		}
+		*b[i].Result.(*hexutil.Bytes) = data
+	}
+	return nil
+}
+
+func TestBatchCall(t *testing.T) {
+	to := common.HexToAddress("0x01")
+	calls := []ethchain.CallMsg{
+		{To: &to, Data: []byte{0x01}},
+		{To: &to, Data: []byte{0x02, 0x03}},
+		{To: &to, Data: []byte{0x04}},
+	}
+	client := new(echoBatchCaller)
+	outputs, err := batchCall(context.Background(), client, calls)
+	if err != nil {
+		t.Fatalf("batch failed: %v", err)
+	}
+	if client.requests != 1 {
+		t.Fatalf("request count mismatch: have %d, want 1", client.requests)
+	}
+	for i, call := range calls {
+		if string(outputs[i]) != string(call.Data) {
+			t.Errorf("call #%d output mismatch: have %x, want %x", i, outputs[i], call.Data)
+		}
+	}
+	if outputs, err := batchCall(context.Background(), client, nil); err != nil || outputs != nil || client.requests != 1 {
+		t.Fatalf("empty batch: have (%v, %v) after %d requests, want (nil, nil) after 1", outputs, err, client.requests)
+	}
+	calls[1].Data = nil
+	if _, err := batchCall(context.Background(), client, calls); err == nil {
+		t.Fatalf("failed call not reported")
+	}
+}
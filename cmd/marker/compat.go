@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+
+	"gopkg.in/urfave/cli.v1"
+)
+
+// flagNames returns the names and aliases of all flags defined on the app
+// and its (sub)commands.
+func flagNames(app *cli.App) map[string]struct{} {
+	names := make(map[string]struct{})
+	addFlags := func(flags []cli.Flag) {
+		for _, flag := range flags {
+			for _, name := range strings.Split(flag.GetName(), ",") {
+				names[strings.TrimSpace(name)] = struct{}{}
+			}
+		}
+	}
+	var addCommands func(cmds []cli.Command)
+	addCommands = func(cmds []cli.Command) {
+		for _, cmd := range cmds {
+			addFlags(cmd.Flags)
+			addCommands(cmd.Subcommands)
+		}
+	}
+	addFlags(app.Flags)
+	addCommands(app.Commands)
+	return names
+}
+
+// legacyFlagArgs rewrites flags spelled with a different letter case than the
+// marker flag they refer to, as accepted by the former validator_cli (e.g.
+// --VoteNum for --voteNum), into their canonical spelling. Unknown flags and
+// everything after a "--" terminator are left untouched.
+func legacyFlagArgs(app *cli.App, args []string) []string {
+	names := flagNames(app)
+	folded := make(map[string]string, len(names))
+	for name := range names {
+		key := strings.ToLower(name)
+		if _, ok := folded[key]; ok {
+			folded[key] = "" // ambiguous, keep the spelling as given
+			continue
+		}
+		folded[key] = name
+	}
+	out := make([]string, len(args))
+	copy(out, args)
+	for i, arg := range out {
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		dashes := "-"
+		if strings.HasPrefix(arg, "--") {
+			dashes = "--"
+		}
+		name, value := strings.TrimPrefix(arg, dashes), ""
+		if eq := strings.Index(name, "="); eq >= 0 {
+			name, value = name[:eq], name[eq:]
+		}
+		if _, ok := names[name]; ok {
+			continue
+		}
+		if canonical := folded[strings.ToLower(name)]; canonical != "" {
+			out[i] = dashes + canonical + value
+		}
+	}
+	return out
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLegacyFlagArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{
+			[]string{"marker", "vote", "--VoteNum", "10", "--Target", "0x1"},
+			[]string{"marker", "vote", "--voteNum", "10", "--target", "0x1"},
+		},
+		{
+			[]string{"marker", "locked", "-lockednum=5", "--rpcport", "7445"},
+			[]string{"marker", "locked", "-lockedNum=5", "--rpcport", "7445"},
+		},
+		{
+			// Unknown flags, values and arguments after the terminator are kept.
+			[]string{"marker", "vote", "--Lesser", "VoteNum", "--", "--VoteNum"},
+			[]string{"marker", "vote", "--Lesser", "VoteNum", "--", "--VoteNum"},
+		},
+		{
+			// Subcommand flags are known as well.
+			[]string{"marker", "genesis", "dev", "--Accounts", "4"},
+			[]string{"marker", "genesis", "dev", "--accounts", "4"},
+		},
+	}
+	for _, tt := range tests {
+		if got := legacyFlagArgs(app, tt.args); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("legacyFlagArgs(%v): have %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
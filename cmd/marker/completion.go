@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/mapprotocol/atlas/cmd/marker/config"
+)
+
+var completionCommand = cli.Command{
+	Name:      "completion",
+	Usage:     "Print a shell completion script, e.g. `source <(marker completion bash)`",
+	ArgsUsage: "bash|zsh|fish",
+	Action: func(ctx *cli.Context) error {
+		return writeCompletion(ctx.App.Writer, ctx.App, ctx.Args().First())
+	},
+}
+
+// completeTargetsCommand is run by the completion scripts to complete the
+// value of --target with the registered validators of the configured node.
+var completeTargetsCommand = cli.Command{
+	Name:   "completeTargets",
+	Hidden: true,
+	Action: MigrateFlags(completeTargets),
+	Flags:  Flags,
+}
+
+func completeTargets(ctx *cli.Context, core *listener) error {
+	if core.conn == nil {
+		return nil
+	}
+	ret, err := core.newClient().RegisteredValidators(context.Background())
+	if err != nil {
+		return err
+	}
+	for _, validator := range ret {
+		fmt.Fprintln(ctx.App.Writer, validator.Hex())
+	}
+	return nil
+}
+
+// completionNode holds the words that can follow a command path.
+type completionNode struct {
+	path        string // space separated command names, empty for the app
+	subcommands []cli.Command
+	flags       []string
+}
+
+// completionNodes walks the app definition and returns one node per command
+// path, in a stable order.
+func completionNodes(app *cli.App) []completionNode {
+	var nodes []completionNode
+	var walk func(path string, cmds []cli.Command, flags []cli.Flag)
+	walk = func(path string, cmds []cli.Command, flags []cli.Flag) {
+		var visible []cli.Command
+		for _, cmd := range cmds {
+			if !cmd.Hidden {
+				visible = append(visible, cmd)
+			}
+		}
+		sort.SliceStable(visible, func(i, j int) bool { return visible[i].Name < visible[j].Name })
+		nodes = append(nodes, completionNode{path: path, subcommands: visible, flags: flagList(flags)})
+		for _, cmd := range visible {
+			walk(strings.TrimSpace(path+" "+cmd.Name), cmd.Subcommands, cmd.Flags)
+		}
+	}
+	walk("", app.Commands, app.Flags)
+	return nodes
+}
+
+func flagList(flags []cli.Flag) []string {
+	var names []string
+	for _, flag := range flags {
+		for _, name := range strings.Split(flag.GetName(), ",") {
+			names = append(names, "--"+strings.TrimSpace(name))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (n completionNode) words() []string {
+	var words []string
+	for _, cmd := range n.subcommands {
+		words = append(words, cmd.Name)
+	}
+	return append(words, n.flags...)
+}
+
+func writeCompletion(w io.Writer, app *cli.App, shell string) error {
+	switch shell {
+	case "bash":
+		return writeBashCompletion(w, app)
+	case "zsh":
+		// zsh can run bash completion functions through bashcompinit.
+		fmt.Fprintln(w, "autoload -U +X bashcompinit && bashcompinit")
+		return writeBashCompletion(w, app)
+	case "fish":
+		return writeFishCompletion(w, app)
+	default:
+		return fmt.Errorf("unsupported shell %q, want bash, zsh or fish", shell)
+	}
+}
+
+// rpcFlagNames returns the flags forwarded to completeTargets so that it
+// queries the same node as the command being completed.
+func rpcFlagNames() []string {
+	return []string{config.RPCListenAddrFlag.Name, config.RPCPortFlag.Name}
+}
+
+func writeBashCompletion(w io.Writer, app *cli.App) error {
+	nodes := completionNodes(app)
+	name := app.Name
+	fn := "_" + name + "_completion"
+
+	var paths []string
+	for _, node := range nodes {
+		paths = append(paths, node.path)
+	}
+	var rpcCases []string
+	for _, flag := range rpcFlagNames() {
+		rpcCases = append(rpcCases, "-"+flag, "--"+flag)
+	}
+
+	fmt.Fprintf(w, "# %s completion, generated by `%s completion`\n", name, name)
+	fmt.Fprintf(w, "%s() {\n", fn)
+	fmt.Fprintf(w, "    local cur prev path candidate words i\n")
+	fmt.Fprintf(w, "    local paths='|%s|'\n", strings.Join(paths, "|"))
+	fmt.Fprintf(w, "    local -a rpc=()\n")
+	fmt.Fprintf(w, "    cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	fmt.Fprintf(w, "    prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	fmt.Fprintf(w, "    path=\"\"\n")
+	fmt.Fprintf(w, "    for ((i = 1; i < COMP_CWORD; i++)); do\n")
+	fmt.Fprintf(w, "        case \"${COMP_WORDS[i]}\" in\n")
+	fmt.Fprintf(w, "        %s)\n", strings.Join(rpcCases, "|"))
+	fmt.Fprintf(w, "            rpc+=(\"${COMP_WORDS[i]}\" \"${COMP_WORDS[i+1]}\") ;;\n")
+	fmt.Fprintf(w, "        -*) ;;\n")
+	fmt.Fprintf(w, "        *)\n")
+	fmt.Fprintf(w, "            candidate=\"${path:+$path }${COMP_WORDS[i]}\"\n")
+	fmt.Fprintf(w, "            if [[ \"$paths\" == *\"|$candidate|\"* ]]; then path=\"$candidate\"; fi ;;\n")
+	fmt.Fprintf(w, "        esac\n")
+	fmt.Fprintf(w, "    done\n")
+	fmt.Fprintf(w, "    if [[ \"$prev\" == \"--%s\" || \"$prev\" == \"-%s\" ]]; then\n", config.TargetAddressFlag.Name, config.TargetAddressFlag.Name)
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W \"$(%s %s \"${rpc[@]}\" 2>/dev/null)\" -- \"$cur\"))\n", name, completeTargetsCommand.Name)
+	fmt.Fprintf(w, "        return\n")
+	fmt.Fprintf(w, "    fi\n")
+	fmt.Fprintf(w, "    case \"$path\" in\n")
+	for _, node := range nodes {
+		fmt.Fprintf(w, "    '%s') words='%s' ;;\n", node.path, strings.Join(node.words(), " "))
+	}
+	fmt.Fprintf(w, "    esac\n")
+	fmt.Fprintf(w, "    COMPREPLY=($(compgen -W \"$words\" -- \"$cur\"))\n")
+	fmt.Fprintf(w, "}\n")
+	fmt.Fprintf(w, "complete -F %s %s\n", fn, name)
+	return nil
+}
+
+func writeFishCompletion(w io.Writer, app *cli.App) error {
+	nodes := completionNodes(app)
+	name := app.Name
+
+	fmt.Fprintf(w, "# %s completion, generated by `%s completion`\n", name, name)
+	fmt.Fprintf(w, "complete -c %s -f\n", name)
+	for _, node := range nodes {
+		condition := "__fish_use_subcommand"
+		if node.path != "" {
+			// Fish only knows about the last command of the path.
+			condition = "__fish_seen_subcommand_from " + node.path[strings.LastIndex(node.path, " ")+1:]
+		}
+		for _, cmd := range node.subcommands {
+			fmt.Fprintf(w, "complete -c %s -n '%s' -a %s -d %s\n", name, condition, cmd.Name, fishQuote(cmd.Usage))
+		}
+		for _, flag := range node.flags {
+			fmt.Fprintf(w, "complete -c %s -n '%s' -l %s\n", name, condition, strings.TrimPrefix(flag, "--"))
+		}
+	}
+	var rpc []string
+	for _, flag := range rpcFlagNames() {
+		rpc = append(rpc, fmt.Sprintf("(__fish_%s_flag_value %s)", name, flag))
+	}
+	fmt.Fprintf(w, "function __fish_%s_flag_value\n", name)
+	fmt.Fprintf(w, "    set -l tokens (commandline -opc)\n")
+	fmt.Fprintf(w, "    set -l index (contains -i -- --$argv[1] $tokens)\n")
+	fmt.Fprintf(w, "    and set -q tokens[(math $index + 1)]\n")
+	fmt.Fprintf(w, "    and printf '%%s\\n' --$argv[1] $tokens[(math $index + 1)]\n")
+	fmt.Fprintf(w, "end\n")
+	fmt.Fprintf(w, "complete -c %s -l %s -x -a '(%s %s %s 2>/dev/null)'\n", name, config.TargetAddressFlag.Name, name, completeTargetsCommand.Name, strings.Join(rpc, " "))
+	return nil
+}
+
+// fishQuote quotes s as a single quoted fish string.
+func fishQuote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
+	return "'" + s + "'"
+}
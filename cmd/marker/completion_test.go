@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCompletionCoversApp(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		var buf bytes.Buffer
+		if err := writeCompletion(&buf, app, shell); err != nil {
+			t.Fatalf("%s: %v", shell, err)
+		}
+		script := buf.String()
+		for _, node := range completionNodes(app) {
+			for _, cmd := range node.subcommands {
+				if !strings.Contains(script, cmd.Name) {
+					t.Errorf("%s: command %q missing", shell, strings.TrimSpace(node.path+" "+cmd.Name))
+				}
+			}
+		}
+		for _, want := range []string{"voteNum", "dev.accounts", completeTargetsCommand.Name} {
+			if !strings.Contains(script, want) {
+				t.Errorf("%s: %q missing", shell, want)
+			}
+		}
+	}
+	if err := writeCompletion(new(bytes.Buffer), app, "powershell"); err == nil {
+		t.Error("unsupported shell accepted")
+	}
+}
+
+func TestCompletionNodes(t *testing.T) {
+	nodes := make(map[string]completionNode)
+	for _, node := range completionNodes(app) {
+		nodes[node.path] = node
+	}
+	genesis, ok := nodes["genesis"]
+	if !ok {
+		t.Fatal("genesis command missing")
+	}
+	if _, ok := nodes["genesis dev"]; !ok {
+		t.Error("genesis subcommand dev missing")
+	}
+	if len(genesis.flags) == 0 {
+		t.Error("genesis flags missing")
+	}
+	for _, cmd := range nodes[""].subcommands {
+		if cmd.Name == completeTargetsCommand.Name {
+			t.Error("hidden command offered for completion")
+		}
+	}
+}
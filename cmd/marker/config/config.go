@@ -2,16 +2,19 @@ package config
 
 import (
 	"crypto/ecdsa"
+	"fmt"
 	"github.com/mapprotocol/atlas/cmd/marker/mapprotocol"
 	"gopkg.in/urfave/cli.v1"
 	"math/big"
 
 	"github.com/mapprotocol/atlas/accounts/abi"
 	"github.com/mapprotocol/atlas/cmd/marker/account"
+	"github.com/mapprotocol/atlas/console/prompt"
 	blscrypto "github.com/mapprotocol/atlas/helper/bls"
 	"github.com/mapprotocol/atlas/params"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
 )
 
 type LockedGoldParameters struct {
@@ -49,7 +52,7 @@ type Config struct {
 	BlsPub     blscrypto.SerializedPublicKey
 	BlsG1Pub   blscrypto.SerializedG1PublicKey
 	BLSProof   []byte
-	Value      uint64
+	Value      *big.Int // in wei
 	Duration   int64
 	Commission uint64
 	Fixed      string
@@ -59,16 +62,31 @@ type Config struct {
 	LockedNum     *big.Int
 	WithdrawIndex *big.Int
 	RelockIndex   *big.Int
+	DryRun        bool
+	AutoWithdraw  bool
+	Estimate      bool
+	Confirmations uint64
 
+	// Offline signing, see --sign-only
+	SignOnly   bool
+	Nonce      uint64
+	NonceSet   bool // Nonce overrides the pending nonce of the account
+	ChainID    *big.Int
+	GasPrice   *big.Int
+	SignOutput string
+
+	CallFrom              common.Address // overrides From in eth_call only, never used for signing
 	TargetAddress         common.Address
+	TokenAddress          common.Address
 	ContractAddress       common.Address
 	SignerPriv            string
 	AccountAddress        common.Address //validator
 	ImplementationAddress common.Address
 	Ip                    string
 	Port                  int
+	WSPort                int
 	GasLimit              int64
-	Verbosity             string
+	Output                string
 	NamePrefix            string
 	LockedGoldParameters  LockedGoldParameters
 	AccountsParameters    AccountsParameters
@@ -79,23 +97,114 @@ type Config struct {
 	GoldTokenParameters   GoldTokenParameters
 }
 
+type LogConfig struct {
+	Verbosity string
+	Vmodule   string
+	File      string
+	MaxSize   int // megabytes
+	MaxFiles  int
+}
+
+// AssemblyLogConfig collects the logging flags. They are looked up on the
+// command first and on the app second, so the result is the same whether
+// the flags were given before or after the command name.
+func AssemblyLogConfig(ctx *cli.Context) *LogConfig {
+	config := LogConfig{
+		Verbosity: "3",
+		MaxSize:   LogFileMaxSizeFlag.Value,
+		MaxFiles:  LogFileMaxFilesFlag.Value,
+	}
+	if name := VerbosityFlag.Name; ctx.IsSet(name) {
+		config.Verbosity = ctx.String(name)
+	} else if ctx.GlobalIsSet(name) {
+		config.Verbosity = ctx.GlobalString(name)
+	}
+	if name := VModuleFlag.Name; ctx.IsSet(name) {
+		config.Vmodule = ctx.String(name)
+	} else if ctx.GlobalIsSet(name) {
+		config.Vmodule = ctx.GlobalString(name)
+	}
+	if name := LogFileFlag.Name; ctx.IsSet(name) {
+		config.File = ctx.String(name)
+	} else if ctx.GlobalIsSet(name) {
+		config.File = ctx.GlobalString(name)
+	}
+	if name := LogFileMaxSizeFlag.Name; ctx.IsSet(name) {
+		config.MaxSize = ctx.Int(name)
+	} else if ctx.GlobalIsSet(name) {
+		config.MaxSize = ctx.GlobalInt(name)
+	}
+	if name := LogFileMaxFilesFlag.Name; ctx.IsSet(name) {
+		config.MaxFiles = ctx.Int(name)
+	} else if ctx.GlobalIsSet(name) {
+		config.MaxFiles = ctx.GlobalInt(name)
+	}
+	return &config
+}
+
+// CallSender returns the from address used for eth_call queries: the --from
+// override if given, otherwise the loaded account.
+func (c *Config) CallSender() common.Address {
+	if c.CallFrom != params.ZeroAddress {
+		return c.CallFrom
+	}
+	return c.From
+}
+
+// LockedAmount returns the amount in wei to lock, unlock or relock: --lockedNum
+// in whole units if given, otherwise the --value/--value-wei amount.
+func (c *Config) LockedAmount() *big.Int {
+	if c.LockedNum != nil {
+		return new(big.Int).Mul(c.LockedNum, big.NewInt(1e18))
+	}
+	return c.Value
+}
+
+// ParseWei parses a non-negative decimal or 0x prefixed hex amount of wei.
+func ParseWei(s string) (*big.Int, error) {
+	value, ok := math.ParseBig256(s)
+	if !ok || s == "" || value.Sign() < 0 {
+		return nil, fmt.Errorf("invalid --%s %q", ValueWeiFlag.Name, s)
+	}
+	return value, nil
+}
+
+// keystorePassword returns the password of the keystore, either given with
+// --password or read from the terminal without echo with --password-prompt.
+func keystorePassword(ctx *cli.Context) (string, error) {
+	switch {
+	case ctx.IsSet(PasswordPromptFlag.Name) && ctx.IsSet(PasswordFlag.Name):
+		return "", fmt.Errorf("--%s and --%s are mutually exclusive", PasswordFlag.Name, PasswordPromptFlag.Name)
+	case ctx.Bool(PasswordPromptFlag.Name):
+		password, err := prompt.Stdin.PromptPassword("Keystore password: ")
+		if err != nil {
+			return "", fmt.Errorf("failed to read password: %v", err)
+		}
+		return password, nil
+	case ctx.IsSet(PasswordFlag.Name):
+		return ctx.String(PasswordFlag.Name), nil
+	}
+	return "", fmt.Errorf("--%s needs --%s or --%s", KeyStoreFlag.Name, PasswordFlag.Name, PasswordPromptFlag.Name)
+}
+
 func AssemblyConfig(ctx *cli.Context) (*Config, error) {
 	config := Config{}
 	//------------------ pre set --------------------------
 	path := ""
-	password := "XXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXX"
 	config.VoteNum = big.NewInt(int64(0))
+	config.Value = new(big.Int)
 	config.TargetAddress = params.ZeroAddress
 	config.Commission = 1000000 //default 1  be relative to 1000,000
-	config.Verbosity = "3"
 	config.NamePrefix = "validator"
+	config.Output = OutputFlag.Value
+	config.WSPort = WSPortFlag.Value
 
 	//-----------------------------------------------------
 	if ctx.IsSet(KeyStoreFlag.Name) {
 		path = ctx.String(KeyStoreFlag.Name)
 	}
-	if ctx.IsSet(PasswordFlag.Name) {
-		password = ctx.String(PasswordFlag.Name)
+	if ctx.IsSet(AccountFlag.Name) && path == "" {
+		return nil, fmt.Errorf("--%s needs a --%s directory", AccountFlag.Name, KeyStoreFlag.Name)
 	}
 	if ctx.IsSet(CommissionFlag.Name) {
 		config.Commission = ctx.Uint64(CommissionFlag.Name)
@@ -109,12 +218,18 @@ func AssemblyConfig(ctx *cli.Context) (*Config, error) {
 	if ctx.IsSet(TargetAddressFlag.Name) {
 		config.TargetAddress = common.HexToAddress(ctx.String(TargetAddressFlag.Name))
 	}
+	if ctx.IsSet(CallFromFlag.Name) {
+		config.CallFrom = common.HexToAddress(ctx.String(CallFromFlag.Name))
+	}
 	if ctx.IsSet(ValidatorAddressFlag.Name) {
 		config.TargetAddress = common.HexToAddress(ctx.String(ValidatorAddressFlag.Name))
 	}
 	if ctx.IsSet(ValidatorAddressFlag.Name) {
 		config.AccountAddress = common.HexToAddress(ctx.String(ValidatorAddressFlag.Name))
 	}
+	if ctx.IsSet(TokenAddressFlag.Name) {
+		config.TokenAddress = common.HexToAddress(ctx.String(TokenAddressFlag.Name))
+	}
 	if ctx.IsSet(SignerPrivFlag.Name) {
 		config.SignerPriv = ctx.String(SignerPrivFlag.Name)
 	}
@@ -125,7 +240,14 @@ func AssemblyConfig(ctx *cli.Context) (*Config, error) {
 		config.ContractAddress = common.HexToAddress(ctx.String(ContractAddressFlag.Name))
 	}
 	if ctx.IsSet(ValueFlag.Name) {
-		config.Value = ctx.Uint64(ValueFlag.Name)
+		config.Value = new(big.Int).Mul(new(big.Int).SetUint64(ctx.Uint64(ValueFlag.Name)), big.NewInt(1e18))
+	}
+	if ctx.IsSet(ValueWeiFlag.Name) {
+		value, err := ParseWei(ctx.String(ValueWeiFlag.Name))
+		if err != nil {
+			return nil, err
+		}
+		config.Value = value
 	}
 	if ctx.IsSet(DurationFlag.Name) {
 		config.Duration = ctx.Int64(DurationFlag.Name)
@@ -145,8 +267,37 @@ func AssemblyConfig(ctx *cli.Context) (*Config, error) {
 	if ctx.IsSet(RelockIndexFlag.Name) {
 		config.RelockIndex = big.NewInt(ctx.Int64(RelockIndexFlag.Name))
 	}
-	if ctx.IsSet(VerbosityFlag.Name) {
-		config.Verbosity = ctx.String(VerbosityFlag.Name)
+	if ctx.IsSet(DryRunFlag.Name) {
+		config.DryRun = ctx.Bool(DryRunFlag.Name)
+	}
+	if ctx.IsSet(AutoWithdrawFlag.Name) {
+		config.AutoWithdraw = ctx.Bool(AutoWithdrawFlag.Name)
+	}
+	if ctx.IsSet(EstimateFlag.Name) {
+		config.Estimate = ctx.Bool(EstimateFlag.Name)
+	}
+	if ctx.IsSet(SignOnlyFlag.Name) {
+		config.SignOnly = ctx.Bool(SignOnlyFlag.Name)
+	}
+	if ctx.IsSet(NonceFlag.Name) {
+		config.Nonce = ctx.Uint64(NonceFlag.Name)
+		config.NonceSet = true
+	}
+	if config.SignOnly {
+		// Nothing can be queried from the chain, so all of them are required
+		if !ctx.IsSet(NonceFlag.Name) || !ctx.IsSet(ChainIDFlag.Name) || !ctx.IsSet(GasPriceFlag.Name) {
+			return nil, fmt.Errorf("--%s needs --%s, --%s and --%s", SignOnlyFlag.Name, NonceFlag.Name, ChainIDFlag.Name, GasPriceFlag.Name)
+		}
+		config.ChainID = new(big.Int).SetUint64(ctx.Uint64(ChainIDFlag.Name))
+		gasPrice, ok := math.ParseBig256(ctx.String(GasPriceFlag.Name))
+		if !ok || gasPrice.Sign() < 0 {
+			return nil, fmt.Errorf("invalid --%s %q", GasPriceFlag.Name, ctx.String(GasPriceFlag.Name))
+		}
+		config.GasPrice = gasPrice
+		config.SignOutput = ctx.String(SignOutputFlag.Name)
+	}
+	if ctx.IsSet(ConfirmationsFlag.Name) {
+		config.Confirmations = ctx.Uint64(ConfirmationsFlag.Name)
 	}
 	if ctx.IsSet(NamePrefixFlag.Name) {
 		config.NamePrefix = ctx.String(NamePrefixFlag.Name)
@@ -157,11 +308,21 @@ func AssemblyConfig(ctx *cli.Context) (*Config, error) {
 	if ctx.IsSet(RPCPortFlag.Name) {
 		config.Port = ctx.Int(RPCPortFlag.Name)
 	}
+	if ctx.IsSet(WSPortFlag.Name) {
+		config.WSPort = ctx.Int(WSPortFlag.Name)
+	}
 	if ctx.IsSet(GasLimitFlag.Name) {
 		config.GasLimit = ctx.Int64(GasLimitFlag.Name)
 	}
+	if ctx.IsSet(OutputFlag.Name) {
+		config.Output = ctx.String(OutputFlag.Name)
+	}
 	if path != "" {
-		_account, err := account.LoadAccount(path, password)
+		password, err := keystorePassword(ctx)
+		if err != nil {
+			return nil, err
+		}
+		_account, err := account.LoadAccount(path, ctx.String(AccountFlag.Name), password)
 		if err != nil {
 			return nil, err
 		}
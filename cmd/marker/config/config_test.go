@@ -0,0 +1,69 @@
+package config
+
+import (
+	"flag"
+	"math/big"
+	"testing"
+
+	"gopkg.in/urfave/cli.v1"
+)
+
+func TestParseWei(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"1", "1", true},
+		{"1500000000000000001", "1500000000000000001", true},
+		{"0x10", "16", true},
+		{"123456789012345678901234567890", "123456789012345678901234567890", true},
+		{"", "", false},
+		{"1.5", "", false},
+		{"-1", "", false},
+		{"1e18", "", false},
+	}
+	for _, tt := range tests {
+		have, err := ParseWei(tt.in)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseWei(%q): have err %v, want ok %v", tt.in, err, tt.ok)
+			continue
+		}
+		if tt.ok && have.String() != tt.want {
+			t.Errorf("ParseWei(%q): have %v, want %s", tt.in, have, tt.want)
+		}
+	}
+}
+
+func TestLockedAmount(t *testing.T) {
+	wei, _ := new(big.Int).SetString("1500000000000000001", 10)
+	cfg := Config{Value: wei}
+	if have := cfg.LockedAmount(); have.Cmp(wei) != 0 {
+		t.Errorf("without lockedNum: have %v, want %v", have, wei)
+	}
+	cfg.LockedNum = big.NewInt(2)
+	if have, want := cfg.LockedAmount(), new(big.Int).Mul(big.NewInt(2), big.NewInt(1e18)); have.Cmp(want) != 0 {
+		t.Errorf("with lockedNum: have %v, want %v", have, want)
+	}
+}
+
+func TestKeystorePassword(t *testing.T) {
+	newContext := func(args ...string) *cli.Context {
+		set := flag.NewFlagSet("test", flag.ContinueOnError)
+		PasswordFlag.Apply(set)
+		PasswordPromptFlag.Apply(set)
+		if err := set.Parse(args); err != nil {
+			t.Fatalf("failed to parse %v: %v", args, err)
+		}
+		return cli.NewContext(nil, set, nil)
+	}
+	if _, err := keystorePassword(newContext()); err == nil {
+		t.Error("missing password accepted")
+	}
+	if _, err := keystorePassword(newContext("--password", "secret", "--password-prompt")); err == nil {
+		t.Error("both password and password-prompt accepted")
+	}
+	if have, err := keystorePassword(newContext("--password", "secret")); err != nil || have != "secret" {
+		t.Errorf("password mismatch: have (%q, %v), want (%q, nil)", have, err, "secret")
+	}
+}
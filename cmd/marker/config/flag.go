@@ -13,12 +13,20 @@ var (
 	}
 	KeyStoreFlag = cli.StringFlag{
 		Name:  "keystore",
-		Usage: "Keystore file path",
+		Usage: "Keystore file path, or keystore directory path with --account",
+	}
+	AccountFlag = cli.StringFlag{
+		Name:  "account",
+		Usage: "Index or address of the account in the --keystore directory (needed if it holds several key files)",
 	}
 	PasswordFlag = cli.StringFlag{
 		Name:  "password",
 		Usage: "Keystore file`s password",
 	}
+	PasswordPromptFlag = cli.BoolFlag{
+		Name:  "password-prompt",
+		Usage: "Read the keystore password from the terminal instead of --password",
+	}
 
 	NamePrefixFlag = cli.StringFlag{
 		Name:  "namePrefix",
@@ -56,11 +64,68 @@ var (
 		Name:  "relockIndex",
 		Usage: "use for relock",
 	}
+	DryRunFlag = cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "Only show what the transaction would do without sending it",
+	}
+	AutoWithdrawFlag = cli.BoolFlag{
+		Name:  "auto-withdraw",
+		Usage: "After unlocking, wait for the pending withdrawals to become available and withdraw them",
+	}
+	EstimateFlag = cli.BoolFlag{
+		Name:  "estimate",
+		Usage: "Estimate the gas and fee of the transaction and print them without sending it",
+	}
+	SignOnlyFlag = cli.BoolFlag{
+		Name:  "sign-only",
+		Usage: "Sign the transaction offline and print its raw hex instead of sending it (needs --nonce, --chainId and --gasPrice)",
+	}
+	NonceFlag = cli.Uint64Flag{
+		Name:  "nonce",
+		Usage: "Nonce of the first transaction sent or signed, the pending nonce of the account by default",
+	}
+	ChainIDFlag = cli.Uint64Flag{
+		Name:  "chainId",
+		Usage: "Chain ID transactions are signed for with --sign-only",
+	}
+	GasPriceFlag = cli.StringFlag{
+		Name:  "gasPrice",
+		Usage: "Gas price in wei of transactions signed with --sign-only",
+	}
+	SignOutputFlag = cli.StringFlag{
+		Name:  "sign-output",
+		Usage: "File the raw transactions signed with --sign-only are appended to (default: stdout)",
+	}
+	ConfirmationsFlag = cli.Uint64Flag{
+		Name:  "confirmations",
+		Usage: "Number of blocks a sent transaction must be buried under before it is considered final",
+		Value: 0,
+	}
 
 	VerbosityFlag = cli.Int64Flag{
 		Name:  "Verbosity",
 		Usage: "Verbosity of log level",
 	}
+	VModuleFlag = cli.StringFlag{
+		Name:  "vmodule",
+		Usage: "Per-module verbosity: comma-separated list of <pattern>=<level> (e.g. rpc/*=4,cmd/marker/*=3)",
+		Value: "",
+	}
+	LogFileFlag = cli.StringFlag{
+		Name:  "logfile",
+		Usage: "Also write logs to the given file (stderr logging stays enabled)",
+		Value: "",
+	}
+	LogFileMaxSizeFlag = cli.IntFlag{
+		Name:  "logfile.maxsize",
+		Usage: "Size in megabytes after which the log file is rotated",
+		Value: 100,
+	}
+	LogFileMaxFilesFlag = cli.IntFlag{
+		Name:  "logfile.maxfiles",
+		Usage: "Number of rotated log files to keep",
+		Value: 5,
+	}
 
 	RPCListenAddrFlag = cli.StringFlag{
 		Name:  "rpcaddr",
@@ -72,11 +137,20 @@ var (
 		Usage: "HTTP-RPC server listening Port",
 		Value: 8545,
 	}
+	WSPortFlag = cli.IntFlag{
+		Name:  "wsport",
+		Usage: "WS-RPC server listening Port, used by the commands following the chain",
+		Value: 8546,
+	}
 	ValueFlag = cli.Uint64Flag{
 		Name:  "value",
 		Usage: "value units one eth",
 		Value: 0,
 	}
+	ValueWeiFlag = cli.StringFlag{
+		Name:  "value-wei",
+		Usage: "value in wei (decimal or 0x hex), overrides --value. Also used as the amount to lock, unlock or relock when --lockedNum is not given",
+	}
 	DurationFlag = cli.Int64Flag{
 		Name:  "duration",
 		Usage: "duration The time (in seconds) that these requirements persist for.",
@@ -87,6 +161,16 @@ var (
 		Usage: "Target query address",
 		Value: "",
 	}
+	TokenAddressFlag = cli.StringFlag{
+		Name:  "token",
+		Usage: "ERC20 token contract address, defaults to the GoldToken",
+		Value: "",
+	}
+	CallFromFlag = cli.StringFlag{
+		Name:  "from",
+		Usage: "Sender address of eth_call queries (default: the loaded account). Only affects calls, transactions are always sent from the loaded account",
+		Value: "",
+	}
 
 	ValidatorAddressFlag = cli.StringFlag{
 		Name:  "validator",
@@ -113,6 +197,11 @@ var (
 		Usage: "set implementation Address",
 		Value: "",
 	}
+	OutputFlag = cli.StringFlag{
+		Name:  "output",
+		Usage: "Output format of query results: log, json or table",
+		Value: "log",
+	}
 	GasLimitFlag = cli.Int64Flag{
 		Name:  "gasLimit",
 		Usage: "use for sendContractTransaction gasLimit",
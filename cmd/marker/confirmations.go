@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// confirmationPollInterval is how often the head block is polled while waiting
+// for confirmations.
+const confirmationPollInterval = time.Second
+
+// errTxReorged is returned if a transaction is no longer part of the canonical
+// chain while waiting for its confirmations.
+var errTxReorged = errors.New("transaction was reorged out")
+
+// confirmationBackend is the part of the node API needed to wait for confirmations.
+type confirmationBackend interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+// waitConfirmations blocks until the mined transaction is buried under n blocks.
+// The node only serves receipts of canonical transactions, so the receipt is
+// fetched again once enough blocks passed: if the transaction moved to another
+// block, the wait starts over for that block, and if it is gone errTxReorged is
+// returned. Failures to reach the node are retried.
+func waitConfirmations(backend confirmationBackend, txHash common.Hash, n uint64, interval time.Duration) error {
+	logger := log.New("func", "waitConfirmations", "txHash", txHash)
+
+	receipt, err := backend.TransactionReceipt(context.Background(), txHash)
+	if errors.Is(err, ethereum.NotFound) {
+		return fmt.Errorf("%w: %v", errTxReorged, err)
+	} else if err != nil {
+		return err
+	}
+	logger.Info("Waiting for confirmations", "block", receipt.BlockNumber, "confirmations", n)
+	for {
+		head, err := backend.BlockNumber(context.Background())
+		if err != nil {
+			logger.Debug("BlockNumber", "error", err)
+		}
+		if err != nil || head < receipt.BlockNumber.Uint64()+n {
+			time.Sleep(interval)
+			continue
+		}
+		current, err := backend.TransactionReceipt(context.Background(), txHash)
+		if errors.Is(err, ethereum.NotFound) {
+			logger.Warn("Transaction reorged out", "block", receipt.BlockNumber, "blockHash", receipt.BlockHash)
+			return errTxReorged
+		} else if err != nil {
+			logger.Debug("TransactionReceipt", "error", err)
+			time.Sleep(interval)
+			continue
+		}
+		if current.BlockHash != receipt.BlockHash {
+			logger.Warn("Transaction moved to another block", "block", current.BlockNumber, "blockHash", current.BlockHash)
+			receipt = current
+			continue
+		}
+		logger.Info("Transaction confirmed", "block", receipt.BlockNumber, "head", head, "confirmations", head-receipt.BlockNumber.Uint64())
+		return nil
+	}
+}
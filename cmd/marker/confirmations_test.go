@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// confirmationChain is a fake node whose head advances on every BlockNumber
+// call. The receipts are looked up by the current head, and fail as if the node
+// was unreachable at the heads down reports.
+type confirmationChain struct {
+	head     uint64
+	receipts func(head uint64) *types.Receipt
+	down     func(head uint64) bool
+}
+
+func (c *confirmationChain) BlockNumber(ctx context.Context) (uint64, error) {
+	c.head++
+	return c.head, nil
+}
+
+func (c *confirmationChain) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	if c.down != nil && c.down(c.head) {
+		return nil, errConfirmationTransport
+	}
+	if receipt := c.receipts(c.head); receipt != nil {
+		return receipt, nil
+	}
+	return nil, ethereum.NotFound
+}
+
+var errConfirmationTransport = errors.New("connection refused")
+
+func confirmationReceipt(number uint64, hash byte) *types.Receipt {
+	return &types.Receipt{BlockNumber: new(big.Int).SetUint64(number), BlockHash: common.Hash{hash}}
+}
+
+func TestWaitConfirmations(t *testing.T) {
+	chain := &confirmationChain{head: 10, receipts: func(uint64) *types.Receipt { return confirmationReceipt(10, 1) }}
+	if err := waitConfirmations(chain, common.Hash{}, 5, 0); err != nil {
+		t.Fatalf("have err %v, want nil", err)
+	}
+	if chain.head != 15 {
+		t.Errorf("confirmed at head %d, want 15", chain.head)
+	}
+}
+
+func TestWaitConfirmationsMovedBlock(t *testing.T) {
+	// The transaction is reorged into block 12 once the head reaches 13.
+	chain := &confirmationChain{head: 10, receipts: func(head uint64) *types.Receipt {
+		if head < 13 {
+			return confirmationReceipt(10, 1)
+		}
+		return confirmationReceipt(12, 2)
+	}}
+	if err := waitConfirmations(chain, common.Hash{}, 3, 0); err != nil {
+		t.Fatalf("have err %v, want nil", err)
+	}
+	if chain.head != 15 {
+		t.Errorf("confirmed at head %d, want 15", chain.head)
+	}
+}
+
+func TestWaitConfirmationsReorgedOut(t *testing.T) {
+	chain := &confirmationChain{head: 10, receipts: func(head uint64) *types.Receipt {
+		if head < 12 {
+			return confirmationReceipt(10, 1)
+		}
+		return nil
+	}}
+	if err := waitConfirmations(chain, common.Hash{}, 3, 0); !errors.Is(err, errTxReorged) {
+		t.Fatalf("have err %v, want %v", err, errTxReorged)
+	}
+}
+
+func TestWaitConfirmationsTransportError(t *testing.T) {
+	chain := &confirmationChain{head: 10, receipts: func(uint64) *types.Receipt { return confirmationReceipt(10, 1) }, down: func(uint64) bool { return true }}
+	if err := waitConfirmations(chain, common.Hash{}, 3, 0); err != errConfirmationTransport {
+		t.Fatalf("have err %v, want %v", err, errConfirmationTransport)
+	}
+	// Once the transaction was found, failing lookups are retried
+	chain.down = func(head uint64) bool { return head == 13 || head == 14 }
+	if err := waitConfirmations(chain, common.Hash{}, 3, 0); err != nil {
+		t.Fatalf("have err %v, want nil", err)
+	}
+	if chain.head != 15 {
+		t.Errorf("confirmed at head %d, want 15", chain.head)
+	}
+}
@@ -1,6 +1,7 @@
 package connections
 
 import (
+	"context"
 	"fmt"
 	"gopkg.in/urfave/cli.v1"
 
@@ -23,6 +24,13 @@ func DialConn(ctx *cli.Context, config *config.Config) (*ethclient.Client, strin
 	return conn, url
 }
 
+// DialWs connects to the websocket endpoint of the node, needed for subscriptions.
+func DialWs(ctx context.Context, config *config.Config) (*rpc.Client, string, error) {
+	url := fmt.Sprintf("ws://%s:%d", config.Ip, config.WSPort)
+	conn, err := rpc.DialContext(ctx, url)
+	return conn, url, err
+}
+
 func DialRpc(config *config.Config) (*rpc.Client, string) {
 	logger := log.New("func", "dialConn")
 	ip := config.Ip     //utils.RPCListenAddrFlag.Name)
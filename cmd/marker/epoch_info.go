@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/log"
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/mapprotocol/atlas/cmd/marker/connections"
+	"github.com/mapprotocol/atlas/consensus/istanbul"
+)
+
+var epochInfoCommand = cli.Command{
+	Name:   "epochInfo",
+	Usage:  "show the epoch of the latest block and how many blocks remain until the validator set changes",
+	Action: MigrateFlags(epochInfo),
+	Flags:  Flags,
+}
+
+// epochInfoResult describes the position of a block within its epoch.
+type epochInfoResult struct {
+	BlockNumber uint64
+	EpochNumber uint64
+	EpochSize   uint64
+	FirstBlock  uint64
+	LastBlock   uint64
+	Remaining   uint64 // blocks after BlockNumber until the end of the epoch
+}
+
+func computeEpochInfo(number, epochSize uint64) epochInfoResult {
+	epoch := istanbul.GetEpochNumber(number, epochSize)
+	// Epoch 0 only holds the genesis block, so it has no first block.
+	first, _ := istanbul.GetEpochFirstBlockNumber(epoch, epochSize)
+	last := istanbul.GetEpochLastBlockNumber(epoch, epochSize)
+	return epochInfoResult{
+		BlockNumber: number,
+		EpochNumber: epoch,
+		EpochSize:   epochSize,
+		FirstBlock:  first,
+		LastBlock:   last,
+		Remaining:   last - number,
+	}
+}
+
+// queryEpochSize asks the connected node for the epoch size of its chain.
+func queryEpochSize(core *listener) (uint64, error) {
+	client, _ := connections.DialRpc(core.cfg)
+	if client == nil {
+		return 0, fmt.Errorf("failed to connect to %s:%d", core.cfg.Ip, core.cfg.Port)
+	}
+	defer client.Close()
+
+	var epochSize uint64
+	if err := client.Call(&epochSize, "istanbul_getEpochSize"); err != nil {
+		return 0, err
+	}
+	if epochSize == 0 {
+		return 0, fmt.Errorf("node reported an epoch size of 0")
+	}
+	return epochSize, nil
+}
+
+func epochInfo(_ *cli.Context, core *listener) error {
+	epochSize, err := queryEpochSize(core)
+	if err != nil {
+		return err
+	}
+	number, err := core.conn.BlockNumber(context.Background())
+	if err != nil {
+		return err
+	}
+	info := computeEpochInfo(number, epochSize)
+	log.Info("=== epoch info ===", "block", info.BlockNumber, "epoch", info.EpochNumber, "epochSize", info.EpochSize)
+	log.Info("epoch range", "first", info.FirstBlock, "last", info.LastBlock, "remaining", info.Remaining)
+	return nil
+}
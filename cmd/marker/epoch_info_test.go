@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestComputeEpochInfo(t *testing.T) {
+	tests := []struct {
+		number uint64
+		want   epochInfoResult
+	}{
+		{0, epochInfoResult{BlockNumber: 0, EpochNumber: 0, EpochSize: 100, FirstBlock: 0, LastBlock: 0, Remaining: 0}},
+		{1, epochInfoResult{BlockNumber: 1, EpochNumber: 1, EpochSize: 100, FirstBlock: 1, LastBlock: 100, Remaining: 99}},
+		{100, epochInfoResult{BlockNumber: 100, EpochNumber: 1, EpochSize: 100, FirstBlock: 1, LastBlock: 100, Remaining: 0}},
+		{101, epochInfoResult{BlockNumber: 101, EpochNumber: 2, EpochSize: 100, FirstBlock: 101, LastBlock: 200, Remaining: 99}},
+		{250, epochInfoResult{BlockNumber: 250, EpochNumber: 3, EpochSize: 100, FirstBlock: 201, LastBlock: 300, Remaining: 50}},
+	}
+	for _, tt := range tests {
+		if got := computeEpochInfo(tt.number, 100); got != tt.want {
+			t.Errorf("block %d: have %+v, want %+v", tt.number, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	ethchain "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/mapprotocol/atlas/marker/client"
+)
+
+// errNotSent is returned by estimatingBackend instead of sending a transaction.
+var errNotSent = errors.New("transaction estimated, not sent")
+
+// gasEstimator is the part of the RPC client needed to estimate a transaction.
+type gasEstimator interface {
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	EstimateGas(ctx context.Context, msg ethchain.CallMsg) (uint64, error)
+}
+
+// estimateTx estimates the gas the transaction described by msg would use and
+// logs it along with the suggested gas price and the resulting maximum fee.
+func estimateTx(ctx context.Context, backend gasEstimator, msg ethchain.CallMsg) error {
+	gasPrice, err := backend.SuggestGasPrice(ctx)
+	if err != nil {
+		return err
+	}
+	msg.GasPrice = gasPrice
+	gas, err := backend.EstimateGas(ctx, msg)
+	if err != nil {
+		return err
+	}
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(gas), gasPrice)
+	log.Info("=== gas estimate ===", "from", msg.From, "to", msg.To, "gas", gas,
+		"gasPrice", gasPrice, "maxFee", formatUnits(fee, 18)+" MAP")
+	return nil
+}
+
+// estimatingBackend is a client backend that estimates the transactions it is
+// given instead of sending them, failing them with errNotSent.
+type estimatingBackend struct {
+	client.Backend
+}
+
+func (b estimatingBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return err
+	}
+	msg := ethchain.CallMsg{From: from, To: tx.To(), Value: tx.Value(), Data: tx.Data()}
+	if err := estimateTx(ctx, b.Backend, msg); err != nil {
+		return err
+	}
+	return errNotSent
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	ethchain "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/mapprotocol/atlas/marker/client"
+)
+
+type estimateBackend struct {
+	client.Backend
+	msg ethchain.CallMsg
+}
+
+func (b *estimateBackend) SuggestGasPrice(context.Context) (*big.Int, error) {
+	return big.NewInt(1e9), nil
+}
+
+func (b *estimateBackend) EstimateGas(_ context.Context, msg ethchain.CallMsg) (uint64, error) {
+	b.msg = msg
+	return 21000, nil
+}
+
+func TestEstimatingBackend(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	to := common.HexToAddress("0x01")
+
+	chainID := big.NewInt(211)
+	tx, err := types.SignTx(types.NewTransaction(0, to, big.NewInt(5), 100000, big.NewInt(1), []byte{0x01, 0x02}), types.LatestSignerForChainID(chainID), key)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	inner := new(estimateBackend)
+	if err := (estimatingBackend{inner}).SendTransaction(context.Background(), tx); !errors.Is(err, errNotSent) {
+		t.Fatalf("send error mismatch: have %v, want %v", err, errNotSent)
+	}
+	if inner.msg.From != from || *inner.msg.To != to || inner.msg.Value.Cmp(big.NewInt(5)) != 0 || len(inner.msg.Data) != 2 {
+		t.Fatalf("estimated message mismatch: %+v", inner.msg)
+	}
+	if inner.msg.GasPrice.Cmp(big.NewInt(1e9)) != 0 {
+		t.Fatalf("gas price mismatch: have %v, want %v", inner.msg.GasPrice, 1e9)
+	}
+}
@@ -3,9 +3,13 @@ package genesis
 import (
 	"fmt"
 	"github.com/mapprotocol/atlas/helper/fileutils"
+	"io/ioutil"
+	"math/big"
 	"os"
 	"path"
+	"strings"
 
+	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/mapprotocol/atlas/marker/env"
 	"github.com/mapprotocol/atlas/marker/genesis"
@@ -33,6 +37,14 @@ var templateFlags = []cli.Flag{
 		Name:  "epoch",
 		Usage: "Epoch size",
 	},
+	cli.Uint64Flag{
+		Name:  "requesttimeout",
+		Usage: "Base timeout for each Istanbul round in milliseconds",
+	},
+	cli.Uint64Flag{
+		Name:  "lookbackwindow",
+		Usage: "Number of blocks to look back when calculating uptime",
+	},
 	cli.Int64Flag{
 		Name:  "blockgaslimit",
 		Usage: "Block gas limit",
@@ -58,16 +70,73 @@ var markerCfgFlag = cli.StringFlag{
 	Usage: "marker config path",
 }
 
+var allocFlag = cli.StringFlag{
+	Name:  "alloc",
+	Usage: "Json file with extra genesis allocations (address -> {balance, code, storage, nonce})",
+}
+
+var validatorsDirFlag = cli.StringFlag{
+	Name:  "validators-dir",
+	Usage: "Directory of validator keystores used as the initial validator set",
+}
+
+var passwordFileFlag = cli.StringFlag{
+	Name:  "password-file",
+	Usage: "File with the password of the keystores in --validators-dir",
+}
+
+var validatorLockedGoldFlag = cli.Uint64Flag{
+	Name:  "validators.lockedgold",
+	Usage: "MAP (in whole units) locked by each genesis validator (default: the validator locked gold requirement)",
+}
+
+var genesisFileFlag = cli.StringFlag{
+	Name:  "file",
+	Usage: "Path of the genesis.json to verify",
+	Value: "genesis.json",
+}
+
+var verifyGenesisCommand = cli.Command{
+	Name:   "verify",
+	Usage:  "Verifies an existing genesis.json and prints its genesis block hash",
+	Action: verifyGenesis,
+	Flags:  []cli.Flag{genesisFileFlag},
+}
+
+var devOutFlag = cli.StringFlag{
+	Name:  "out",
+	Usage: "Directory to write genesis.json and the validator keystore to",
+	Value: "devnet",
+}
+
+var devAccountsFlag = cli.IntFlag{
+	Name:  "accounts",
+	Usage: "Number of funded developer accounts",
+	Value: 10,
+}
+
+var devGenesisCommand = cli.Command{
+	Name:   "dev",
+	Usage:  "Creates a single-validator devnet genesis with funded accounts from a well-known test mnemonic",
+	Action: createDevGenesis,
+	Flags:  []cli.Flag{devOutFlag, devAccountsFlag, buildpathFlag, passwordFileFlag},
+}
+
 var CreateGenesisCommand = cli.Command{
-	Name:      "genesis",
-	Usage:     "Creates genesis.json from a template and overrides",
-	Action:    createGenesis,
-	ArgsUsage: "",
+	Name:        "genesis",
+	Usage:       "Creates genesis.json from a template and overrides",
+	Action:      createGenesis,
+	ArgsUsage:   "",
+	Subcommands: []cli.Command{verifyGenesisCommand, devGenesisCommand},
 	Flags: append(
 		[]cli.Flag{
 			buildpathFlag,
 			newEnvFlag,
 			markerCfgFlag,
+			allocFlag,
+			validatorsDirFlag,
+			passwordFileFlag,
+			validatorLockedGoldFlag,
 		},
 		templateFlags...),
 }
@@ -106,10 +175,31 @@ func envFromTemplate(ctx *cli.Context, workdir string) (*env.Environment, *genes
 	if err != nil {
 		return nil, nil, err
 	}
+	if err := applyIstanbulFlags(ctx, genesisConfig); err != nil {
+		return nil, nil, err
+	}
 
 	return env, genesisConfig, nil
 }
 
+// applyIstanbulFlags overrides the istanbul parameters of the genesis config
+// with the ones given on the command line and validates the result
+func applyIstanbulFlags(ctx *cli.Context, genesisConfig *genesis.Config) error {
+	if ctx.IsSet("epoch") {
+		genesisConfig.Istanbul.Epoch = ctx.Uint64("epoch")
+	}
+	if ctx.IsSet("blockperiod") {
+		genesisConfig.Istanbul.BlockPeriod = ctx.Uint64("blockperiod")
+	}
+	if ctx.IsSet("requesttimeout") {
+		genesisConfig.Istanbul.RequestTimeout = ctx.Uint64("requesttimeout")
+	}
+	if ctx.IsSet("lookbackwindow") {
+		genesisConfig.Istanbul.LookbackWindow = ctx.Uint64("lookbackwindow")
+	}
+	return genesisConfig.ValidateIstanbul()
+}
+
 func createGenesis(ctx *cli.Context) error {
 	genesis.UnmarshalMarkerConfig(ctx)
 	var workdir string
@@ -131,6 +221,19 @@ func createGenesis(ctx *cli.Context) error {
 		return err
 	}
 
+	if ctx.IsSet(validatorLockedGoldFlag.Name) {
+		lockedGold := new(big.Int).Mul(new(big.Int).SetUint64(ctx.Uint64(validatorLockedGoldFlag.Name)), big.NewInt(1e18))
+		if required := genesisConfig.Validators.ValidatorLockedGoldRequirements.Value; lockedGold.Cmp(required) < 0 {
+			return fmt.Errorf("validator locked gold %v is below the requirement of %v", lockedGold, required)
+		}
+		genesisConfig.Validators.InitialLockedGold = lockedGold
+	}
+	if ctx.IsSet(validatorsDirFlag.Name) {
+		if err := loadGenesisValidators(ctx, workdir); err != nil {
+			return err
+		}
+	}
+
 	buildpath, err := readBuildPath(ctx)
 	if err != nil {
 		return err
@@ -141,6 +244,16 @@ func createGenesis(ctx *cli.Context) error {
 		return err
 	}
 
+	if ctx.IsSet(allocFlag.Name) {
+		alloc, err := genesis.LoadAlloc(ctx.String(allocFlag.Name))
+		if err != nil {
+			return err
+		}
+		if err := genesis.MergeAlloc(generatedGenesis, alloc); err != nil {
+			return err
+		}
+	}
+
 	if ctx.IsSet(newEnvFlag.Name) {
 		if err = env.Save(); err != nil {
 			return err
@@ -149,3 +262,104 @@ func createGenesis(ctx *cli.Context) error {
 
 	return env.SaveGenesis(generatedGenesis)
 }
+
+// createDevGenesis writes a dev preset genesis.json and the keystore of its
+// validator into the --out directory, so that it can be used as datadir
+func createDevGenesis(ctx *cli.Context) error {
+	workdir := ctx.String(devOutFlag.Name)
+	password, err := readPasswordFile(ctx)
+	if err != nil {
+		return err
+	}
+	buildpath, err := readBuildPath(ctx)
+	if err != nil {
+		return err
+	}
+
+	accounts := genesis.DevAccountsConfig(ctx.Int(devAccountsFlag.Name))
+	if err := genesis.UseDevValidator(accounts); err != nil {
+		return err
+	}
+	developers := accounts.DeveloperAccounts()
+	generatedGenesis, err := genesis.GenerateGenesis(ctx, accounts, genesis.DevConfig(), buildpath)
+	if err != nil {
+		return err
+	}
+	if err := genesis.MergeAlloc(generatedGenesis, genesis.DevAlloc(developers)); err != nil {
+		return err
+	}
+
+	devEnv, err := env.New(workdir, &env.Config{ChainID: generatedGenesis.Config.ChainID, Accounts: *accounts})
+	if err != nil {
+		return err
+	}
+	if err := devEnv.SaveGenesis(generatedGenesis); err != nil {
+		return err
+	}
+	validator := accounts.AdminAccount()
+	ks := keystore.NewKeyStore(path.Join(workdir, "keystore"), keystore.StandardScryptN, keystore.StandardScryptP)
+	if _, err := ks.ImportECDSA(validator.PrivateKey, password); err != nil && err != keystore.ErrAccountAlreadyExists {
+		return err
+	}
+
+	log.Warn("Dev accounts are derived from a public mnemonic, never use them outside a local devnet", "mnemonic", genesis.DevMnemonic)
+	log.Info("Validator", "address", validator.Address, "privateKey", validator.PrivateKeyHex())
+	for i, account := range developers {
+		log.Info("Funded account", "index", i, "address", account.Address, "privateKey", account.PrivateKeyHex(), "balance", genesis.DevAccountBalance)
+	}
+	log.Info("Dev genesis created", "genesis", devEnv.GenesisPath(), "keystore", path.Join(workdir, "keystore"))
+	return nil
+}
+
+func readPasswordFile(ctx *cli.Context) (string, error) {
+	if !ctx.IsSet(passwordFileFlag.Name) {
+		return "", nil
+	}
+	data, err := ioutil.ReadFile(ctx.String(passwordFileFlag.Name))
+	if err != nil {
+		return "", fmt.Errorf("failed to read password file: %v", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// loadGenesisValidators replaces the validators of the marker config with the
+// keystores found in --validators-dir and writes a validators.json manifest
+// mapping each validator address to its node id into workdir.
+func loadGenesisValidators(ctx *cli.Context, workdir string) error {
+	password, err := readPasswordFile(ctx)
+	if err != nil {
+		return err
+	}
+	keys, err := genesis.LoadValidatorKeystores(ctx.String(validatorsDirFlag.Name), password)
+	if err != nil {
+		return err
+	}
+	validators, err := genesis.ValidatorInfos(keys)
+	if err != nil {
+		return err
+	}
+	genesis.ValidatorsAT = validators
+
+	manifest := path.Join(workdir, "validators.json")
+	if err := genesis.WriteValidatorManifest(keys, manifest); err != nil {
+		return err
+	}
+	log.Info("Loaded genesis validators", "count", len(validators), "manifest", manifest)
+	return nil
+}
+
+func verifyGenesis(ctx *cli.Context) error {
+	file := ctx.String(genesisFileFlag.Name)
+	hash, err := genesis.VerifyGenesisFile(file)
+	if errs, ok := err.(genesis.GenesisErrors); ok {
+		for _, e := range errs {
+			log.Error("Invalid genesis", "path", e.Path, "err", e.Err)
+		}
+		return fmt.Errorf("%s: %d problems found", file, len(errs))
+	}
+	if err != nil {
+		return err
+	}
+	log.Info("Genesis verified", "file", file, "hash", hash)
+	return nil
+}
@@ -0,0 +1,63 @@
+package genesis
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/mapprotocol/atlas/core/chain"
+	"github.com/mapprotocol/atlas/marker/genesis"
+)
+
+func newTemplateContext(t *testing.T, args ...string) *cli.Context {
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	for _, f := range templateFlags {
+		f.Apply(set)
+	}
+	if err := set.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return cli.NewContext(nil, set, nil)
+}
+
+func TestIstanbulFlagsGolden(t *testing.T) {
+	ctx := newTemplateContext(t, "--epoch", "1000", "--blockperiod", "2", "--requesttimeout", "5000", "--lookbackwindow", "60")
+	genesisConfig := genesis.CreateCommonGenesisConfig()
+	if err := applyIstanbulFlags(ctx, genesisConfig); err != nil {
+		t.Fatalf("failed to apply flags: %v", err)
+	}
+	have, err := json.MarshalIndent(genesis.NewGenesis(genesisConfig, []byte{0x01}, chain.GenesisAlloc{}), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ioutil.ReadFile(filepath.Join("testdata", "genesis_istanbul.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(have, bytes.TrimSpace(want)) {
+		t.Errorf("genesis mismatch:\nhave:\n%s\nwant:\n%s", have, want)
+	}
+}
+
+func TestIstanbulFlagsValidation(t *testing.T) {
+	tests := []struct {
+		args []string
+		ok   bool
+	}{
+		{[]string{"--epoch", "100", "--lookbackwindow", "12"}, true},
+		{[]string{"--epoch", "12", "--lookbackwindow", "12"}, false},
+		{[]string{"--epoch", "10", "--lookbackwindow", "12"}, false},
+		{[]string{"--blockperiod", "0"}, false},
+	}
+	for i, test := range tests {
+		err := applyIstanbulFlags(newTemplateContext(t, test.args...), genesis.CreateCommonGenesisConfig())
+		if (err == nil) != test.ok {
+			t.Errorf("test %d: args %v, have err %v, want ok %v", i, test.args, err, test.ok)
+		}
+	}
+}
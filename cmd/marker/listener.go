@@ -9,6 +9,7 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
 
 	"github.com/mapprotocol/atlas/accounts"
 	"github.com/mapprotocol/atlas/cmd/marker/account"
@@ -20,11 +21,13 @@ import (
 	"github.com/mapprotocol/atlas/helper/bls"
 	"github.com/mapprotocol/atlas/helper/decimal"
 	"github.com/mapprotocol/atlas/helper/decimal/fixed"
+	"github.com/mapprotocol/atlas/marker/client"
 	"github.com/mapprotocol/atlas/params"
 	"sort"
 
 	"gopkg.in/urfave/cli.v1"
 	"math/big"
+	"os"
 	"strings"
 )
 
@@ -41,22 +44,92 @@ type Writer interface {
 type listener struct {
 	cfg    *config.Config
 	conn   *ethclient.Client
+	rpc    *rpc.Client // underlying connection of conn, for batch requests
 	writer Writer
 	msgCh  chan struct{} // wait for msg handles
+
+	offline *offlineBackend // signs transactions instead of sending them, with --sign-only
+	nonce   *uint64         // nonce of the next transaction, set with --nonce
 }
 
 func NewListener(ctx *cli.Context, config *config.Config) *listener {
-	conn, _ := connections.DialConn(ctx, config)
-	return &listener{
+	l := &listener{
 		cfg:   config,
-		conn:  conn,
 		msgCh: make(chan struct{}),
 	}
+	if l.rpc, _ = connections.DialRpc(config); l.rpc != nil {
+		l.conn = ethclient.NewClient(l.rpc)
+	}
+	if config.SignOnly {
+		l.offline = newOfflineBackend(config)
+	}
+	if config.NonceSet {
+		nonce := config.Nonce
+		l.nonce = &nonce
+	}
+	return l
 }
 func (l *listener) setWriter(w *writer) {
+	w.offline = l.offline
+	if l.nonce != nil {
+		w.nonce = l.nonce
+	}
 	l.writer = w
 }
 
+// newClient returns a contract client sending transactions from the
+// configured account.
+func (l *listener) newClient() *client.Client {
+	var signer client.Signer
+	if l.cfg.PrivateKey != nil {
+		signer = client.NewKeySigner(l.cfg.PrivateKey)
+	}
+	gasLimit := uint64(l.cfg.GasLimit)
+	if gasLimit == 0 {
+		gasLimit = DefaultGasLimit
+	}
+	return client.New(l.clientBackend(l.conn), signer, &client.Config{
+		Contracts: client.Contracts{
+			Accounts:   l.cfg.AccountsParameters.AccountsAddress,
+			LockedGold: l.cfg.LockedGoldParameters.LockedGoldAddress,
+			Validators: l.cfg.ValidatorParameters.ValidatorAddress,
+			Election:   l.cfg.ElectionParameters.ElectionAddress,
+		},
+		GasLimit: gasLimit,
+		CallFrom: l.cfg.CallFrom,
+	})
+}
+
+// clientBackend wraps the backend of a contract client according to the
+// flags: the nonce given with --nonce is used, and transactions are estimated
+// with --estimate or signed offline with --sign-only instead of being sent.
+func (l *listener) clientBackend(conn client.Backend) client.Backend {
+	backend := conn
+	if l.nonce != nil {
+		backend = nonceBackend{backend, l.nonce}
+	}
+	if l.cfg.Estimate {
+		backend = estimatingBackend{backend}
+	}
+	if l.offline != nil {
+		backend = l.offline
+	}
+	return backend
+}
+
+// waitTx waits for the transaction sent by a client call and logs its result.
+func (l *listener) waitTx(txHash common.Hash, err error) error {
+	if errors.Is(err, errNotSent) || errors.Is(err, errSigned) {
+		return nil
+	}
+	if err != nil {
+		isContinueError = false
+		log.Error("send transaction", "error", err)
+		return err
+	}
+	return getResult(l.conn, txHash, true, l.cfg.Confirmations)
+}
+
 // waitUntilMsgHandled this function will block untill message is handled
 func (l *listener) waitUntilMsgHandled(counter int) {
 	log.Debug("waitUntilMsgHandled", "counter", counter)
@@ -131,13 +204,13 @@ var lockedMAPCommand = cli.Command{
 }
 var unlockedMAPCommand = cli.Command{
 	Name:   "unlockMap",
-	Usage:  "unlocked MAP",
+	Usage:  "unlocked MAP, printing when it can be withdrawn (--auto-withdraw to wait and withdraw it)",
 	Action: MigrateFlags(unlockedMAP),
 	Flags:  Flags,
 }
 var relockMAPCommand = cli.Command{
 	Name:   "relockMAP",
-	Usage:  "unlocked MAP",
+	Usage:  "relock a pending withdrawal, showing the resulting schedule first (--dry-run to only preview)",
 	Action: MigrateFlags(relockMAP),
 	Flags:  Flags,
 }
@@ -188,6 +261,13 @@ var queryRegisteredValidatorSignersCommand = cli.Command{
 	Flags:  Flags,
 }
 
+var indexOfCommand = cli.Command{
+	Name:   "indexOf",
+	Usage:  "get the index of the `target` signer in the registered validator signers list",
+	Action: MigrateFlags(indexOf),
+	Flags:  Flags,
+}
+
 var getValidatorCommand = cli.Command{
 	Name:   "getValidator",
 	Usage:  "Validator Info",
@@ -394,41 +474,46 @@ func registerValidator(ctx *cli.Context, core *listener) error {
 		log.Info("the account is in PendingDeRegisterValidator list please use revertRegisterValidator command")
 		return nil
 	}
-	greater, lesser := registerUseFor(core)
-	//fmt.Println("=== greater, lesser ===", greater, lesser)
-	//_params := []interface{}{commision, lesser, greater,core.cfg.BlsPub[:], core.cfg.BlsG1Pub[:], core.cfg.BLSProof, core.cfg.PublicKey[1:]}
-	if core.cfg.SignerPriv != "" {
-		SignerPriv := core.cfg.SignerPriv
-		priv, err := crypto.ToECDSA(common.FromHex(SignerPriv))
-		if err != nil {
-			panic(err)
-		}
-		publicAddr := crypto.PubkeyToAddress(priv.PublicKey)
-		_account := &account.Account{Address: publicAddr, PrivateKey: priv}
-		blsPub, err := _account.BLSPublicKey()
-		if err != nil {
-			panic(err)
-		}
-		blsG1Pub, err := _account.BLSG1PublicKey()
-		if err != nil {
-			panic(err)
-		}
-		core.cfg.PublicKey = _account.PublicKey()
-		core.cfg.BlsPub = blsPub
-		core.cfg.BlsG1Pub = blsG1Pub
-		core.cfg.BLSProof = _account.MustBLSProofOfPossession()
-		BLSProofOfPossession := makeBLSProofOfPossessionFromsigner_(core.cfg.From, core)
-		core.cfg.BLSProof = BLSProofOfPossession.Marshal()
+	signer := useSignerKeys(core)
+	validatorParams := client.ValidatorParams{
+		Commission:     commision,
+		ECDSAPublicKey: core.cfg.PublicKey[1:],
+		BLSPublicKey:   core.cfg.BlsPub[:],
+		BLSG1PublicKey: core.cfg.BlsG1Pub[:],
+		BLSProof:       core.cfg.BLSProof,
+	}
+	if err := core.waitTx(core.newClient().RegisterValidator(context.Background(), validatorParams)); err != nil || !isContinueError {
+		return err
 	}
-	validatorParams := [4][]byte{core.cfg.BlsPub[:], core.cfg.BlsG1Pub[:], core.cfg.BLSProof, core.cfg.PublicKey[1:]}
+	return printValidatorIndex(core, signer)
+}
 
-	_params := []interface{}{commision, lesser, greater, validatorParams}
-	ValidatorAddress := core.cfg.ValidatorParameters.ValidatorAddress
-	abiValidators := core.cfg.ValidatorParameters.ValidatorABI
-	m := NewMessage(SolveSendTranstion1, core.msgCh, core.cfg, ValidatorAddress, nil, abiValidators, "registerValidator", _params...)
-	go core.writer.ResolveMessage(m)
-	core.waitUntilMsgHandled(1)
-	return nil
+// useSignerKeys replaces the keys of the loaded account by the ones of the
+// authorized validator signer, if its private key is configured, and returns
+// the signer address. The proof of possession is made over the account.
+func useSignerKeys(core *listener) common.Address {
+	if core.cfg.SignerPriv == "" {
+		return core.cfg.From
+	}
+	priv, err := crypto.ToECDSA(common.FromHex(core.cfg.SignerPriv))
+	if err != nil {
+		panic(err)
+	}
+	publicAddr := crypto.PubkeyToAddress(priv.PublicKey)
+	_account := &account.Account{Address: publicAddr, PrivateKey: priv}
+	blsPub, err := _account.BLSPublicKey()
+	if err != nil {
+		panic(err)
+	}
+	blsG1Pub, err := _account.BLSG1PublicKey()
+	if err != nil {
+		panic(err)
+	}
+	core.cfg.PublicKey = _account.PublicKey()
+	core.cfg.BlsPub = blsPub
+	core.cfg.BlsG1Pub = blsG1Pub
+	core.cfg.BLSProof = makeBLSProofOfPossessionFromsigner_(core.cfg.From, core).Marshal()
+	return publicAddr
 }
 
 func isPendingDeRegisterValidator(core *listener) bool {
@@ -489,17 +574,18 @@ func TestPoc2_getNonce(_ *cli.Context, core *listener) error {
 }
 
 /*
-   note : by account not signer
+   note : by account not signer, the BLS keys are the signer's
 */
-func updateBlsPublicKey(ctx *cli.Context, core *listener) error {
+func updateBlsPublicKey(_ *cli.Context, core *listener) error {
 	log.Info("=== updateBlsPublicKey ===")
-	_params := []interface{}{core.cfg.PublicKey[1:], core.cfg.BlsPub[:], core.cfg.BlsG1Pub[:], core.cfg.BLSProof}
-	ValidatorAddress := core.cfg.ValidatorParameters.ValidatorAddress
-	abiValidators := core.cfg.ValidatorParameters.ValidatorABI
-	m := NewMessage(SolveSendTranstion1, core.msgCh, core.cfg, ValidatorAddress, nil, abiValidators, "updateBlsPublicKey", _params...)
-	go core.writer.ResolveMessage(m)
-	core.waitUntilMsgHandled(1)
-	return nil
+	signer := useSignerKeys(core)
+	log.Info("BLS key", "account", core.cfg.From, "signer", signer, "blsPublicKey", hexutil.Encode(core.cfg.BlsPub[:]))
+	params := client.ValidatorParams{
+		BLSPublicKey:   core.cfg.BlsPub[:],
+		BLSG1PublicKey: core.cfg.BlsG1Pub[:],
+		BLSProof:       core.cfg.BLSProof,
+	}
+	return core.waitTx(core.newClient().UpdateBLSPublicKey(context.Background(), params))
 }
 
 func setNextCommissionUpdate(_ *cli.Context, core *listener) error {
@@ -715,20 +801,15 @@ func deregisterValidator(_ *cli.Context, core *listener) error {
 
 //---------- voter -----------------
 func vote(_ *cli.Context, core *listener) error {
-	ElectionsAddress := core.cfg.ElectionParameters.ElectionAddress
-	abiElections := core.cfg.ElectionParameters.ElectionABI
-	//greater, lesser := getGreaterLesser(core, core.cfg.TargetAddress)
-	greater, lesser, err := getGL(core, core.cfg.TargetAddress)
+	c := core.newClient()
+	amount := new(big.Int).Mul(core.cfg.VoteNum, big.NewInt(1e18))
+	lesser, greater, err := c.VoteLesserGreater(context.Background(), core.cfg.TargetAddress, amount)
 	if err != nil {
 		log.Error("vote", "err", err)
 		return err
 	}
-	amount := new(big.Int).Mul(core.cfg.VoteNum, big.NewInt(1e18))
 	log.Info("=== vote Validator ===", "admin", core.cfg.From, "voteTargetValidator", core.cfg.TargetAddress.String(), "vote MAP Num", core.cfg.VoteNum.String())
-	m := NewMessage(SolveSendTranstion1, core.msgCh, core.cfg, ElectionsAddress, nil, abiElections, "vote", core.cfg.TargetAddress, amount, lesser, greater)
-	go core.writer.ResolveMessage(m)
-	core.waitUntilMsgHandled(1)
-	return nil
+	return core.waitTx(c.Vote(context.Background(), core.cfg.TargetAddress, amount, lesser, greater))
 }
 
 func quicklyVote(ctx *cli.Context, core *listener) error {
@@ -824,8 +905,15 @@ func revokeActive(_ *cli.Context, core *listener) error {
 
 //---------- query -----------------
 func getRegisteredValidatorSigners(_ *cli.Context, core *listener) error {
-	log.Info("==== getRegisteredValidatorSigners ===")
 	Validators := _getRegisteredValidatorSigners(core)
+	if core.cfg.Output == "table" || core.cfg.Output == "json" {
+		rows, err := queryValidatorRows(core, Validators, true)
+		if err != nil {
+			return err
+		}
+		return printValidatorRows(os.Stdout, core.cfg.Output, rows)
+	}
+	log.Info("==== getRegisteredValidatorSigners ===")
 	if len(Validators) == 0 {
 		log.Info("nil")
 	}
@@ -834,6 +922,24 @@ func getRegisteredValidatorSigners(_ *cli.Context, core *listener) error {
 	}
 	return nil
 }
+// printValidatorIndex logs the position of signer in the registered
+// validator signers list.
+func printValidatorIndex(core *listener, signer common.Address) error {
+	index, err := GetIndex(signer, _getRegisteredValidatorSigners(core))
+	if err != nil {
+		return err
+	}
+	log.Info("validator index", "signer", signer, "index", index)
+	return nil
+}
+
+func indexOf(_ *cli.Context, core *listener) error {
+	if core.cfg.TargetAddress == params.ZeroAddress {
+		return errors.New("--target is required")
+	}
+	return printValidatorIndex(core, core.cfg.TargetAddress)
+}
+
 func getValidator(_ *cli.Context, core *listener) error {
 	type ret struct {
 		EcdsaPublicKey      interface{}
@@ -992,6 +1098,13 @@ func getTopValidators(_ *cli.Context, core *listener) error {
 	go core.writer.ResolveMessage(m)
 	core.waitUntilMsgHandled(1)
 	Validators := TopValidators.([]common.Address)
+	if core.cfg.Output == "table" || core.cfg.Output == "json" {
+		rows, err := queryValidatorRows(core, Validators, false)
+		if err != nil {
+			return err
+		}
+		return printValidatorRows(os.Stdout, core.cfg.Output, rows)
+	}
 	for i := 0; i < len(Validators); i++ {
 		log.Info("Validator:", "index", i, "addr", Validators[i])
 	}
@@ -1258,53 +1371,85 @@ func getPendingWithdrawals(_ *cli.Context, core *listener) error {
 
 //--------------------- locked Map ------------------------
 func lockedMAP(_ *cli.Context, core *listener) error {
-	lockedGold := new(big.Int).Mul(core.cfg.LockedNum, big.NewInt(1e18))
+	lockedGold := core.cfg.LockedAmount()
 	log.Info("=== Lock  gold ===")
 	log.Info("Lock  gold", "amount", lockedGold.String())
-	LockedGoldAddress := core.cfg.LockedGoldParameters.LockedGoldAddress
-	abiLockedGold := core.cfg.LockedGoldParameters.LockedGoldABI
-	m := NewMessage(SolveSendTranstion2, core.msgCh, core.cfg, LockedGoldAddress, lockedGold, abiLockedGold, "lock")
-	go core.writer.ResolveMessage(m)
-	core.waitUntilMsgHandled(1)
-	return nil
+	return core.waitTx(core.newClient().LockGold(context.Background(), lockedGold))
 }
 func unlockedMAP(_ *cli.Context, core *listener) error {
-	lockedGold := new(big.Int).Mul(core.cfg.LockedNum, big.NewInt(1e18))
+	lockedGold := core.cfg.LockedAmount()
 	log.Info("=== unLock validator gold ===")
 	log.Info("unLock validator gold", "amount", lockedGold, "admin", core.cfg.From)
 	LockedGoldAddress := core.cfg.LockedGoldParameters.LockedGoldAddress
 	abiLockedGold := core.cfg.LockedGoldParameters.LockedGoldABI
-	m := NewMessage(SolveSendTranstion1, core.msgCh, core.cfg, LockedGoldAddress, nil, abiLockedGold, "unlock", lockedGold)
-	go core.writer.ResolveMessage(m)
-	core.waitUntilMsgHandled(1)
+	if core.cfg.AutoWithdraw && (core.offline != nil || core.cfg.Estimate) {
+		return errors.New("--auto-withdraw can't be used with --sign-only or --estimate")
+	}
+	// With --auto-withdraw and no amount, only the pending withdrawals of
+	// earlier unlocks are withdrawn
+	if lockedGold.Sign() > 0 || !core.cfg.AutoWithdraw {
+		m := NewMessage(SolveSendTranstion1, core.msgCh, core.cfg, LockedGoldAddress, nil, abiLockedGold, "unlock", lockedGold)
+		go core.writer.ResolveMessage(m)
+		core.waitUntilMsgHandled(1)
+		if !isContinueError || core.offline != nil || core.cfg.Estimate {
+			return nil
+		}
+		if err := printUnlockedAvailability(core); err != nil {
+			return err
+		}
+	}
+	if core.cfg.AutoWithdraw {
+		return autoWithdraw(core)
+	}
 	return nil
 }
 func relockMAP(_ *cli.Context, core *listener) error {
-	lockedGold := new(big.Int).Mul(core.cfg.LockedNum, big.NewInt(1e18))
+	lockedGold := core.cfg.LockedAmount()
+	if core.cfg.RelockIndex == nil || lockedGold.Sign() == 0 {
+		return errors.New("relockIndex and lockedNum (or value-wei) are required")
+	}
 	index := core.cfg.RelockIndex
-	log.Info("=== relockMAP validator gold ===")
-	log.Info("relockMAP validator gold", "amount", lockedGold)
 	LockedGoldAddress := core.cfg.LockedGoldParameters.LockedGoldAddress
 	abiLockedGold := core.cfg.LockedGoldParameters.LockedGoldABI
+
+	values, timestamps, err := queryPendingWithdrawals(core, core.cfg.From)
+	if err != nil {
+		return err
+	}
+	selected, remaining, err := previewRelock(values, timestamps, index, lockedGold)
+	if err != nil {
+		return err
+	}
+	printRelockPreview(core.cfg.From, lockedGold, selected, remaining)
+	if core.cfg.DryRun {
+		log.Info("dry run, relock not sent")
+		return nil
+	}
+
+	log.Info("=== relockMAP validator gold ===")
+	log.Info("relockMAP validator gold", "amount", lockedGold)
 	m := NewMessage(SolveSendTranstion1, core.msgCh, core.cfg, LockedGoldAddress, nil, abiLockedGold, "relock", index, lockedGold)
 	go core.writer.ResolveMessage(m)
 	core.waitUntilMsgHandled(1)
 	return nil
 }
 func withdraw(_ *cli.Context, core *listener) error {
-	index := core.cfg.WithdrawIndex
+	sendWithdraw(core, core.cfg.WithdrawIndex)
+	return nil
+}
+
+func sendWithdraw(core *listener, index *big.Int) {
 	LockedGoldAddress := core.cfg.LockedGoldParameters.LockedGoldAddress
 	abiLockedGold := core.cfg.LockedGoldParameters.LockedGoldABI
-	log.Info("=== withdraw validator gold ===", "admin", core.cfg.From.String())
+	log.Info("=== withdraw validator gold ===", "admin", core.cfg.From.String(), "index", index)
 	m := NewMessage(SolveSendTranstion1, core.msgCh, core.cfg, LockedGoldAddress, nil, abiLockedGold, "withdraw", index)
 	go core.writer.ResolveMessage(m)
 	core.waitUntilMsgHandled(1)
-	return nil
 }
 
 //-------------------------- owner ------------------------
 func setValidatorLockedGoldRequirements(_ *cli.Context, core *listener) error {
-	value := new(big.Int).Mul(big.NewInt(int64(core.cfg.Value)), big.NewInt(1e18))
+	value := core.cfg.Value
 	duration := big.NewInt(core.cfg.Duration)
 	ValidatorAddress := core.cfg.ValidatorParameters.ValidatorAddress
 	abiValidators := core.cfg.ValidatorParameters.ValidatorABI
@@ -1377,7 +1522,7 @@ func getContractOwner(_ *cli.Context, core *listener) error {
 }
 
 func setTargetValidatorEpochPayment(_ *cli.Context, core *listener) error {
-	value := new(big.Int).Mul(big.NewInt(int64(core.cfg.Value)), big.NewInt(1e18))
+	value := core.cfg.Value
 	EpochRewardAddress := core.cfg.EpochRewardParameters.EpochRewardsAddress
 	abiEpochReward := core.cfg.EpochRewardParameters.EpochRewardsABI
 	log.Info("=== setTargetValidatorEpochPayment ===", "admin", core.cfg.From.String())
@@ -1472,125 +1617,3 @@ type voteTotal struct {
 	Validator common.Address
 	Value     *big.Int
 }
-
-//getLesser getGreater use for register
-func getGL(core *listener, target common.Address) (common.Address, common.Address, error) {
-	type ret struct {
-		Validators interface{} // indexed
-		Values     interface{}
-	}
-	var t ret
-	electionAddress := core.cfg.ElectionParameters.ElectionAddress
-	abiElection := core.cfg.ElectionParameters.ElectionABI
-	f := func(output []byte) {
-		err := abiElection.UnpackIntoInterface(&t, "getTotalVotesForEligibleValidators", output)
-		if err != nil {
-			isContinueError = false
-			log.Error("getTotalVotesForEligibleValidators setLesserGreater", "err", err)
-		}
-	}
-	m := NewMessageRet2(SolveQueryResult4, core.msgCh, core.cfg, f, electionAddress, nil, abiElection, "getTotalVotesForEligibleValidators")
-	go core.writer.ResolveMessage(m)
-	core.waitUntilMsgHandled(1)
-	validators := (t.Validators).([]common.Address)
-	votes := (t.Values).([]*big.Int)
-	voteTotals := make([]voteTotal, len(validators))
-	for i, addr := range validators {
-		voteTotals[i] = voteTotal{addr, votes[i]}
-	}
-	voteNum := new(big.Int).Mul(core.cfg.VoteNum, big.NewInt(1e18))
-	for _, voteTotal := range voteTotals {
-		if bytes.Equal(voteTotal.Validator.Bytes(), target.Bytes()) {
-			if big.NewInt(0).Cmp(voteNum) < 0 {
-				voteTotal.Value.Add(voteTotal.Value, voteNum)
-			}
-			// Sorting in descending order is necessary to match the order on-chain.
-			sort.SliceStable(voteTotals, func(j, k int) bool {
-				return voteTotals[j].Value.Cmp(voteTotals[k].Value) > 0
-			})
-
-			lesser := params.ZeroAddress
-			greater := params.ZeroAddress
-			for j, voteTotal := range voteTotals {
-				if voteTotal.Validator == target {
-					if j > 0 {
-						greater = voteTotals[j-1].Validator
-					}
-					if j+1 < len(voteTotals) {
-						lesser = voteTotals[j+1].Validator
-					}
-					break
-				}
-			}
-			return greater, lesser, nil
-			break
-		}
-	}
-	return params.ZeroAddress, params.ZeroAddress, NoTargetValidatorError
-}
-
-func registerUseFor(core *listener) (common.Address, common.Address) {
-	electionAddress := core.cfg.ElectionParameters.ElectionAddress
-	abiElection := core.cfg.ElectionParameters.ElectionABI
-	var ret1 interface{}
-	log.Info("=== getTotalVotesForValidator ===", "admin", core.cfg.From)
-	m := NewMessageRet1(SolveQueryResult3, core.msgCh, core.cfg, &ret1, electionAddress, nil, abiElection, "getTotalVotesForValidator", core.cfg.From)
-	go core.writer.ResolveMessage(m)
-	core.waitUntilMsgHandled(1)
-	result := ret1.(*big.Int)
-	log.Info("=== getTotalVotesForValidator ===", "result", result)
-	core.cfg.VoteNum = result
-	G, L, _ := getGL2(core, core.cfg.From)
-	return G, L
-}
-func getGL2(core *listener, target common.Address) (common.Address, common.Address, error) {
-	type ret struct {
-		Validators interface{} // indexed
-		Values     interface{}
-	}
-	var t ret
-	electionAddress := core.cfg.ElectionParameters.ElectionAddress
-	abiElection := core.cfg.ElectionParameters.ElectionABI
-	f := func(output []byte) {
-		err := abiElection.UnpackIntoInterface(&t, "getTotalVotesForEligibleValidators", output)
-		if err != nil {
-			isContinueError = false
-			log.Error("getTotalVotesForEligibleValidators setLesserGreater", "err", err)
-		}
-	}
-	m := NewMessageRet2(SolveQueryResult4, core.msgCh, core.cfg, f, electionAddress, nil, abiElection, "getTotalVotesForEligibleValidators")
-	go core.writer.ResolveMessage(m)
-	core.waitUntilMsgHandled(1)
-	validators := (t.Validators).([]common.Address)
-	votes := (t.Values).([]*big.Int)
-	voteTotals := make([]voteTotal, len(validators))
-	for i, addr := range validators {
-		voteTotals[i] = voteTotal{addr, votes[i]}
-	}
-	voteTotals = append(voteTotals, voteTotal{target, core.cfg.VoteNum})
-	for _, voteTotal := range voteTotals {
-		if bytes.Equal(voteTotal.Validator.Bytes(), target.Bytes()) {
-			// Sorting in descending order is necessary to match the order on-chain.
-			sort.SliceStable(voteTotals, func(j, k int) bool {
-				return voteTotals[j].Value.Cmp(voteTotals[k].Value) > 0
-			})
-
-			lesser := params.ZeroAddress
-			greater := params.ZeroAddress
-			for j, voteTotal := range voteTotals {
-				if voteTotal.Validator == target {
-					if j > 0 {
-						greater = voteTotals[j-1].Validator
-					}
-					if j+1 < len(voteTotals) {
-						lesser = voteTotals[j+1].Validator
-					}
-					break
-				}
-			}
-			return greater, lesser, nil
-			break
-		}
-	}
-	return params.ZeroAddress, params.ZeroAddress, NoTargetValidatorError
-}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/mapprotocol/atlas/cmd/marker/config"
+)
+
+// logFile is the file handler installed by the last startLogger call, kept so
+// that it can be closed when the logger is set up again or the app exits.
+var logFile *rotatingFile
+
+// startLogger installs the root log handler. Logs always go to stderr in the
+// terminal format; if a log file is configured they are written there as well
+// using the logfmt format. The global verbosity can be raised or lowered per
+// source file pattern with the vmodule setting.
+func startLogger(cfg *config.LogConfig) error {
+	var lvl log.Lvl
+	if lvlToInt, err := strconv.Atoi(cfg.Verbosity); err == nil {
+		lvl = log.Lvl(lvlToInt)
+	} else if lvl, err = log.LvlFromString(cfg.Verbosity); err != nil {
+		return err
+	}
+
+	handler := log.StreamHandler(os.Stderr, log.TerminalFormat(false))
+	var file *rotatingFile
+	if cfg.File != "" {
+		var err error
+		file, err = newRotatingFile(cfg.File, int64(cfg.MaxSize)*1024*1024, cfg.MaxFiles)
+		if err != nil {
+			return err
+		}
+		handler = log.MultiHandler(handler, log.StreamHandler(file, log.LogfmtFormat()))
+	}
+
+	glogger := log.NewGlogHandler(handler)
+	glogger.Verbosity(lvl)
+	if err := glogger.Vmodule(cfg.Vmodule); err != nil {
+		if file != nil {
+			file.Close()
+		}
+		return err
+	}
+	log.Root().SetHandler(glogger)
+
+	stopLogger()
+	logFile = file
+	return nil
+}
+
+// stopLogger closes the log file opened by startLogger, if any.
+func stopLogger() {
+	if logFile != nil {
+		logFile.Close()
+		logFile = nil
+	}
+}
+
+// rotatingFile is an io.Writer appending to a file that is rotated once it
+// grows past maxSize bytes. Rotated files are named path.1 (newest) up to
+// path.N (oldest), where N is maxFiles; older ones are removed.
+type rotatingFile struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func newRotatingFile(path string, maxSize int64, maxFiles int) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file, r.size = file, info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate shifts path.i to path.i+1, moves the current file to path.1 and
+// reopens an empty file at path. It must be called with the lock held.
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+	if r.maxFiles <= 0 {
+		if err := os.Remove(r.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return r.open()
+	}
+	os.Remove(r.backupName(r.maxFiles))
+	for i := r.maxFiles - 1; i > 0; i-- {
+		if err := os.Rename(r.backupName(i), r.backupName(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(r.path, r.backupName(1)); err != nil {
+		return err
+	}
+	return r.open()
+}
+
+func (r *rotatingFile) backupName(i int) string {
+	return fmt.Sprintf("%s.%d", r.path, i)
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
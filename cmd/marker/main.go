@@ -5,7 +5,6 @@ import (
 	"gopkg.in/urfave/cli.v1"
 	"os"
 	"sort"
-	"strconv"
 
 	"github.com/ethereum/go-ethereum/log"
 
@@ -19,11 +18,15 @@ var (
 	Flags = []cli.Flag{
 		config.KeyFlag,
 		config.KeyStoreFlag,
+		config.AccountFlag,
 		config.RPCListenAddrFlag,
 		config.RPCPortFlag,
+		config.WSPortFlag,
 		config.ValueFlag,
+		config.ValueWeiFlag,
 		config.DurationFlag,
 		config.PasswordFlag,
+		config.PasswordPromptFlag,
 		config.CommissionFlag,
 		config.RelayerfFlag,
 		config.NamePrefixFlag,
@@ -32,7 +35,18 @@ var (
 		config.LockedNumFlag,
 		config.WithdrawIndexFlag,
 		config.RelockIndexFlag,
+		config.DryRunFlag,
+		config.AutoWithdrawFlag,
+		config.EstimateFlag,
+		config.SignOnlyFlag,
+		config.NonceFlag,
+		config.ChainIDFlag,
+		config.GasPriceFlag,
+		config.SignOutputFlag,
+		config.ConfirmationsFlag,
 		config.TargetAddressFlag,
+		config.TokenAddressFlag,
+		config.CallFromFlag,
 		config.ValidatorAddressFlag,
 		config.AccountAddressFlag,
 		config.SignerPrivFlag,
@@ -40,6 +54,12 @@ var (
 		config.MAPValueFlag,
 		config.GasLimitFlag,
 		config.ImplementationAddressFlag,
+		config.OutputFlag,
+		config.VerbosityFlag,
+		config.VModuleFlag,
+		config.LogFileFlag,
+		config.LogFileMaxSizeFlag,
+		config.LogFileMaxFilesFlag,
 	}
 )
 
@@ -84,13 +104,19 @@ func init() {
 		withdrawCommand,
 		queryTotalVotesForEligibleValidatorsCommand,
 		queryRegisteredValidatorSignersCommand,
+		indexOfCommand,
 		getValidatorCommand,
+		getSlashingInfoCommand,
 		getRewardInfoCommand,
 		getVoterRewardInfoCommand,
 		queryNumRegisteredValidatorsCommand,
 		queryTopValidatorsCommand,
 		queryValidatorEligibilityCommand,
 		getBalanceCommand,
+		tokenBalanceCommand,
+		transferCommand,
+		broadcastCommand,
+		nonceCommand,
 		getValidatorsVotedForByAccountCommand,
 		getTotalVotesCommand,
 		getAccountTotalLockedGoldCommand,
@@ -113,14 +139,26 @@ func init() {
 
 		//---------------------------------
 		voterMonitorCommand,
+		accountCommand,
+		epochInfoCommand,
+		watchValidatorsCommand,
+		completionCommand,
+		completeTargetsCommand,
 	}
 	app.Flags = Flags
+	app.Before = func(ctx *cli.Context) error {
+		return startLogger(config.AssemblyLogConfig(ctx))
+	}
+	app.After = func(ctx *cli.Context) error {
+		stopLogger()
+		return nil
+	}
 	cli.CommandHelpTemplate = OriginCommandHelpTemplate
 	sort.Sort(cli.CommandsByName(app.Commands))
 }
 
 func main() {
-	if err := app.Run(os.Args); err != nil {
+	if err := app.Run(legacyFlagArgs(app, os.Args)); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
@@ -138,12 +176,13 @@ func MigrateFlags(hdl func(ctx *cli.Context, config *listener) error) func(*cli.
 				}
 			}
 		}
-		_config, err := config.AssemblyConfig(ctx)
+		// Command-level logging flags are only visible here, not in app.Before.
+		err := startLogger(config.AssemblyLogConfig(ctx))
 		if err != nil {
 			cli.ShowAppHelpAndExit(ctx, 1)
 			panic(err)
 		}
-		err = startLogger(ctx, _config)
+		_config, err := config.AssemblyConfig(ctx)
 		if err != nil {
 			cli.ShowAppHelpAndExit(ctx, 1)
 			panic(err)
@@ -154,16 +193,3 @@ func MigrateFlags(hdl func(ctx *cli.Context, config *listener) error) func(*cli.
 		return hdl(ctx, core)
 	}
 }
-
-func startLogger(_ *cli.Context, config *config.Config) error {
-	logger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
-	var lvl log.Lvl
-	if lvlToInt, err := strconv.Atoi(config.Verbosity); err == nil {
-		lvl = log.Lvl(lvlToInt)
-	} else if lvl, err = log.LvlFromString(config.Verbosity); err != nil {
-		return err
-	}
-	logger.Verbosity(lvl)
-	log.Root().SetHandler(log.LvlFilterHandler(lvl, logger))
-	return nil
-}
@@ -17,6 +17,9 @@ const (
 
 	//event EpochRewardsDistributedToVoters(address indexed voterAddress, uint256 value);
 	EpochRewardsDistributedToVoters EventSig = "EpochRewardsDistributedToVoters(address,uint256)"
+
+	//event AccountSlashed(address indexed slashed, uint256 penalty, address indexed reporter, uint256 reward);
+	AccountSlashed EventSig = "AccountSlashed(address,uint256,address,uint256)"
 )
 
 type ProposalStatus int
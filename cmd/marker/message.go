@@ -50,7 +50,7 @@ func NewMessage(messageType string, ch chan<- struct{}, cfg *config.Config, to c
 func NewMessageRet1(messageType string, ch chan<- struct{}, cfg *config.Config, ret interface{}, to common.Address, value *big.Int, abi *abi.ABI, abiMethod string, params ...interface{}) Message {
 	return Message{
 		messageType: messageType,
-		from:        cfg.From,
+		from:        cfg.CallSender(),
 		priKey:      cfg.PrivateKey,
 		to:          to,
 		value:       value,
@@ -67,7 +67,7 @@ func NewMessageRet1(messageType string, ch chan<- struct{}, cfg *config.Config,
 func NewMessageRet2(messageType string, ch chan<- struct{}, cfg *config.Config, solveResult func([]byte), to common.Address, value *big.Int, abi *abi.ABI, abiMethod string, params ...interface{}) Message {
 	return Message{
 		messageType: messageType,
-		from:        cfg.From,
+		from:        cfg.CallSender(),
 		priKey:      cfg.PrivateKey,
 		to:          to,
 		value:       value,
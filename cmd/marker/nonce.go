@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/mapprotocol/atlas/marker/client"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var nonceCommand = cli.Command{
+	Name:   "nonce",
+	Usage:  "Prints the latest and pending nonce of the account",
+	Action: MigrateFlags(printNonce),
+	Flags:  Flags,
+}
+
+// nextNonce returns the nonce of the next transaction from the account. With
+// --nonce it is the given one, advanced with every transaction sent so that
+// back-to-back sends don't collide, otherwise the pending nonce of the account,
+// which counts its transactions still in the pool.
+func (w *writer) nextNonce(from common.Address) (uint64, error) {
+	if w.nonce != nil {
+		nonce := *w.nonce
+		*w.nonce++
+		return nonce, nil
+	}
+	nonce, err := w.conn.PendingNonceAt(context.Background(), from)
+	if err != nil {
+		return 0, fmt.Errorf("pending nonce of %v: %v", from, err)
+	}
+	return nonce, nil
+}
+
+// nonceBackend is a client backend handing out the nonce given with --nonce
+// instead of the pending nonce of the account. It is advanced with every nonce
+// handed out, like the one of the writer it is shared with.
+type nonceBackend struct {
+	client.Backend
+	nonce *uint64
+}
+
+func (b nonceBackend) PendingNonceAt(context.Context, common.Address) (uint64, error) {
+	nonce := *b.nonce
+	*b.nonce++
+	return nonce, nil
+}
+
+func printNonce(_ *cli.Context, core *listener) error {
+	latest, err := core.conn.NonceAt(context.Background(), core.cfg.From, nil)
+	if err != nil {
+		return err
+	}
+	pending, err := core.conn.PendingNonceAt(context.Background(), core.cfg.From)
+	if err != nil {
+		return err
+	}
+	log.Info("=== nonce ===", "account", core.cfg.From, "latest", latest, "pending", pending)
+	return nil
+}
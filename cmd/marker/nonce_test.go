@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/mapprotocol/atlas/cmd/marker/config"
+	"github.com/mapprotocol/atlas/marker/client"
+)
+
+func TestNextNonceOverride(t *testing.T) {
+	w := NewWriter(nil, &config.Config{Nonce: 7, NonceSet: true})
+	for want := uint64(7); want < 10; want++ {
+		have, err := w.nextNonce(common.Address{})
+		if err != nil || have != want {
+			t.Fatalf("nonce mismatch: have (%d, %v), want (%d, nil)", have, err, want)
+		}
+	}
+}
+
+// sendBackend records the transactions sent to it, its pending nonce is
+// always zero.
+type sendBackend struct {
+	client.Backend
+	sent []*types.Transaction
+}
+
+func (b *sendBackend) ChainID(context.Context) (*big.Int, error) { return big.NewInt(211), nil }
+
+func (b *sendBackend) PendingNonceAt(context.Context, common.Address) (uint64, error) {
+	return 0, nil
+}
+
+func (b *sendBackend) SuggestGasPrice(context.Context) (*big.Int, error) {
+	return big.NewInt(1e9), nil
+}
+
+func (b *sendBackend) SendTransaction(_ context.Context, tx *types.Transaction) error {
+	b.sent = append(b.sent, tx)
+	return nil
+}
+
+func TestClientNonceOverride(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	cfg := &config.Config{Nonce: 7, NonceSet: true}
+	l := NewListener(nil, cfg)
+	w := NewWriter(nil, cfg)
+	l.setWriter(w)
+
+	backend := new(sendBackend)
+	c := client.New(l.clientBackend(backend), client.NewKeySigner(key), &client.Config{GasLimit: DefaultGasLimit})
+	for i := 0; i < 2; i++ {
+		if _, err := c.LockGold(context.Background(), big.NewInt(1)); err != nil {
+			t.Fatalf("failed to send transaction %d: %v", i, err)
+		}
+	}
+	for i, tx := range backend.sent {
+		if tx.Nonce() != uint64(7+i) {
+			t.Errorf("tx %d: nonce mismatch: have %d, want %d", i, tx.Nonce(), 7+i)
+		}
+	}
+	// The writer continues from the nonces the client used
+	if have, err := w.nextNonce(common.Address{}); err != nil || have != 9 {
+		t.Fatalf("writer nonce mismatch: have (%d, %v), want (9, nil)", have, err)
+	}
+}
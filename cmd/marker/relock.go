@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// queryPendingWithdrawals returns the values and availability timestamps of
+// the pending withdrawals of account, in contract order.
+func queryPendingWithdrawals(core *listener, account common.Address) ([]*big.Int, []*big.Int, error) {
+	type ret []interface{}
+	var (
+		values     interface{}
+		timestamps interface{}
+		unpackErr  error
+	)
+	t := ret{&values, &timestamps}
+	LockedGoldAddress := core.cfg.LockedGoldParameters.LockedGoldAddress
+	abiLockedGold := core.cfg.LockedGoldParameters.LockedGoldABI
+	f := func(output []byte) {
+		unpackErr = abiLockedGold.UnpackIntoInterface(&t, "getPendingWithdrawals", output)
+	}
+	m := NewMessageRet2(SolveQueryResult4, core.msgCh, core.cfg, f, LockedGoldAddress, nil, abiLockedGold, "getPendingWithdrawals", account)
+	go core.writer.ResolveMessage(m)
+	core.waitUntilMsgHandled(1)
+	if unpackErr != nil {
+		return nil, nil, fmt.Errorf("getPendingWithdrawals: %v", unpackErr)
+	}
+	v, _ := values.([]*big.Int)
+	ts, _ := timestamps.([]*big.Int)
+	return v, ts, nil
+}
+
+// previewRelock computes the effect of relocking amount out of the pending
+// withdrawal at index. It mirrors LockedGold.relock: a partial relock lowers
+// the entry's value, while relocking the full value deletes the entry by
+// moving the last pending withdrawal into its slot.
+func previewRelock(values, timestamps []*big.Int, index *big.Int, amount *big.Int) (pendingWithdrawal, []pendingWithdrawal, error) {
+	if !index.IsInt64() || index.Sign() < 0 || index.Int64() >= int64(len(values)) {
+		return pendingWithdrawal{}, nil, fmt.Errorf("relock index %v out of range, %d pending withdrawals", index, len(values))
+	}
+	idx := int(index.Int64())
+	selected := pendingWithdrawal{Index: idx, Value: values[idx], Timestamp: timestamps[idx]}
+	if amount.Sign() <= 0 {
+		return selected, nil, fmt.Errorf("relock amount must be positive, got %v", amount)
+	}
+	if amount.Cmp(values[idx]) > 0 {
+		return selected, nil, fmt.Errorf("relock amount %v exceeds pending withdrawal %d of %v", amount, idx, values[idx])
+	}
+
+	remaining := make([]pendingWithdrawal, 0, len(values))
+	for i := range values {
+		remaining = append(remaining, pendingWithdrawal{Index: i, Value: values[i], Timestamp: timestamps[i]})
+	}
+	if amount.Cmp(values[idx]) == 0 {
+		last := len(remaining) - 1
+		remaining[idx] = remaining[last]
+		remaining[idx].Index = idx
+		remaining = remaining[:last]
+	} else {
+		remaining[idx].Value = new(big.Int).Sub(values[idx], amount)
+	}
+	return selected, remaining, nil
+}
+
+func printRelockPreview(account common.Address, amount *big.Int, selected pendingWithdrawal, remaining []pendingWithdrawal) {
+	log.Info("=== relock preview ===", "account", account)
+	log.Info("relock", "index", selected.Index, "amount", amount, "pending", selected.Value, "timestamp", selected.Timestamp)
+	if len(remaining) == 0 {
+		log.Info("remaining pendingWithdrawals", "result", "nil")
+	}
+	for _, w := range remaining {
+		log.Info("remaining pendingWithdrawal", "index", w.Index, "value", w.Value, "timestamp", w.Timestamp)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestPreviewRelock(t *testing.T) {
+	values := []*big.Int{big.NewInt(10), big.NewInt(20), big.NewInt(30)}
+	timestamps := []*big.Int{big.NewInt(100), big.NewInt(200), big.NewInt(300)}
+
+	tests := []struct {
+		index     int64
+		amount    int64
+		remaining []pendingWithdrawal
+		fail      bool
+	}{
+		// partial relock lowers the selected entry
+		{1, 5, []pendingWithdrawal{
+			{0, big.NewInt(10), big.NewInt(100)},
+			{1, big.NewInt(15), big.NewInt(200)},
+			{2, big.NewInt(30), big.NewInt(300)},
+		}, false},
+		// full relock moves the last entry into the freed slot
+		{0, 10, []pendingWithdrawal{
+			{0, big.NewInt(30), big.NewInt(300)},
+			{1, big.NewInt(20), big.NewInt(200)},
+		}, false},
+		{2, 30, []pendingWithdrawal{
+			{0, big.NewInt(10), big.NewInt(100)},
+			{1, big.NewInt(20), big.NewInt(200)},
+		}, false},
+		{1, 21, nil, true},
+		{3, 1, nil, true},
+		{-1, 1, nil, true},
+		{0, 0, nil, true},
+	}
+	for i, test := range tests {
+		selected, remaining, err := previewRelock(values, timestamps, big.NewInt(test.index), big.NewInt(test.amount))
+		if test.fail {
+			if err == nil {
+				t.Errorf("test %d: expected error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+			continue
+		}
+		if selected.Index != int(test.index) || selected.Value.Cmp(values[test.index]) != 0 {
+			t.Errorf("test %d: selected mismatch: have %+v", i, selected)
+		}
+		if !reflect.DeepEqual(remaining, test.remaining) {
+			t.Errorf("test %d: remaining mismatch: have %v, want %v", i, remaining, test.remaining)
+		}
+	}
+	// the input must be left untouched
+	if values[1].Cmp(big.NewInt(20)) != 0 {
+		t.Errorf("input modified: %v", values)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+
+	ethchain "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/mapprotocol/atlas/cmd/marker/config"
+)
+
+var (
+	// errSigned is returned by offlineBackend instead of sending a transaction.
+	errSigned = errors.New("transaction signed, not sent")
+
+	// errOffline is returned by offlineBackend for everything needing the chain.
+	errOffline = errors.New("not available with --sign-only")
+)
+
+var broadcastCommand = cli.Command{
+	Name:      "broadcast",
+	Usage:     "Sends raw transactions signed with --sign-only, given as hex or read line by line from a file",
+	ArgsUsage: "<hex|file>",
+	Action:    MigrateFlags(broadcast),
+	Flags:     Flags,
+}
+
+// offlineBackend is a client backend that signs transactions without access to
+// the chain. The nonce, chain ID and gas price come from the flags, and every
+// transaction it is given is printed as raw hex and failed with errSigned.
+type offlineBackend struct {
+	mu       sync.Mutex
+	nonce    uint64
+	chainID  *big.Int
+	gasPrice *big.Int
+	output   string // file the transactions are appended to, stdout if empty
+}
+
+func newOfflineBackend(cfg *config.Config) *offlineBackend {
+	return &offlineBackend{
+		nonce:    cfg.Nonce,
+		chainID:  cfg.ChainID,
+		gasPrice: cfg.GasPrice,
+		output:   cfg.SignOutput,
+	}
+}
+
+func (b *offlineBackend) ChainID(context.Context) (*big.Int, error) {
+	return new(big.Int).Set(b.chainID), nil
+}
+
+func (b *offlineBackend) PendingNonceAt(context.Context, common.Address) (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.nonce, nil
+}
+
+func (b *offlineBackend) SuggestGasPrice(context.Context) (*big.Int, error) {
+	return new(big.Int).Set(b.gasPrice), nil
+}
+
+func (b *offlineBackend) EstimateGas(context.Context, ethchain.CallMsg) (uint64, error) {
+	return 0, errOffline
+}
+
+func (b *offlineBackend) CallContract(context.Context, ethchain.CallMsg, *big.Int) ([]byte, error) {
+	return nil, errOffline
+}
+
+func (b *offlineBackend) TransactionReceipt(context.Context, common.Hash) (*types.Receipt, error) {
+	return nil, errOffline
+}
+
+func (b *offlineBackend) SendTransaction(_ context.Context, tx *types.Transaction) error {
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var out io.Writer = os.Stdout
+	if b.output != "" {
+		f, err := os.OpenFile(b.output, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	if _, err := fmt.Fprintln(out, hexutil.Encode(raw)); err != nil {
+		return err
+	}
+	log.Info("=== signed transaction ===", "hash", tx.Hash(), "nonce", tx.Nonce(), "to", tx.To())
+	b.nonce = tx.Nonce() + 1
+	return errSigned
+}
+
+// sign signs a transaction from key with the next nonce and prints it.
+func (b *offlineBackend) sign(key *ecdsa.PrivateKey, to common.Address, value *big.Int, gasLimit uint64, input []byte) error {
+	nonce, _ := b.PendingNonceAt(context.Background(), common.Address{})
+	tx, err := types.SignTx(types.NewTransaction(nonce, to, value, gasLimit, b.gasPrice, input), types.LatestSignerForChainID(b.chainID), key)
+	if err != nil {
+		return err
+	}
+	if err := b.SendTransaction(context.Background(), tx); !errors.Is(err, errSigned) {
+		return err
+	}
+	return nil
+}
+
+// readSignedTxs decodes the raw transaction given as 0x prefixed hex, or the
+// ones stored one per line in the file at arg.
+func readSignedTxs(arg string) ([]*types.Transaction, error) {
+	var lines []string
+	if strings.HasPrefix(arg, "0x") {
+		lines = []string{arg}
+	} else {
+		f, err := os.Open(arg)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, 1024*1024)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	txs := make([]*types.Transaction, len(lines))
+	for i, line := range lines {
+		raw, err := hexutil.Decode(line)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+		txs[i] = new(types.Transaction)
+		if err := txs[i].UnmarshalBinary(raw); err != nil {
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+	}
+	return txs, nil
+}
+
+// broadcast sends the given signed transactions in order, waiting for each of
+// them to be mined before sending the next one.
+func broadcast(ctx *cli.Context, core *listener) error {
+	if ctx.NArg() != 1 {
+		return errors.New("broadcast needs a signed transaction or a file of them")
+	}
+	txs, err := readSignedTxs(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		log.Info("=== broadcast ===", "hash", tx.Hash(), "nonce", tx.Nonce(), "to", tx.To())
+		if err := core.waitTx(tx.Hash(), core.conn.SendTransaction(context.Background(), tx)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/mapprotocol/atlas/cmd/marker/config"
+)
+
+func TestSignOffline(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	to := common.HexToAddress("0x01")
+	output := filepath.Join(t.TempDir(), "signed.txt")
+
+	chainID := big.NewInt(211)
+	b := newOfflineBackend(&config.Config{Nonce: 7, ChainID: chainID, GasPrice: big.NewInt(1e9), SignOutput: output})
+	if err := b.sign(key, to, big.NewInt(5), 21000, nil); err != nil {
+		t.Fatalf("failed to sign first transaction: %v", err)
+	}
+	if err := b.sign(key, to, nil, 100000, []byte{0x01}); err != nil {
+		t.Fatalf("failed to sign second transaction: %v", err)
+	}
+	txs, err := readSignedTxs(output)
+	if err != nil {
+		t.Fatalf("failed to read signed transactions: %v", err)
+	}
+	if len(txs) != 2 {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(txs), 2)
+	}
+	for i, tx := range txs {
+		if tx.Nonce() != uint64(7+i) {
+			t.Errorf("tx %d: nonce mismatch: have %d, want %d", i, tx.Nonce(), 7+i)
+		}
+		sender, err := types.Sender(types.LatestSignerForChainID(chainID), tx)
+		if err != nil || sender != from {
+			t.Errorf("tx %d: sender mismatch: have %x (%v), want %x", i, sender, err, from)
+		}
+		if tx.GasPrice().Cmp(big.NewInt(1e9)) != 0 {
+			t.Errorf("tx %d: gas price mismatch: have %v, want %v", i, tx.GasPrice(), 1e9)
+		}
+	}
+	if _, err := readSignedTxs("0xzz"); err == nil {
+		t.Fatal("invalid hex accepted")
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/mapprotocol/atlas/cmd/marker/mapprotocol"
+	"github.com/mapprotocol/atlas/consensus/istanbul"
+	"github.com/mapprotocol/atlas/params"
+)
+
+var getSlashingInfoCommand = cli.Command{
+	Name:   "getSlashingInfo",
+	Usage:  "show the slashing multiplier and the recorded penalties of the `target` validator",
+	Action: MigrateFlags(getSlashingInfo),
+	Flags:  Flags,
+}
+
+// slashingEvent is a penalty recorded by LockedGold.slash.
+type slashingEvent struct {
+	BlockNumber uint64
+	Epoch       uint64
+	TxHash      common.Hash
+	Penalty     *big.Int
+	Reporter    common.Address
+	Reward      *big.Int
+}
+
+// decodeSlashingLog decodes an AccountSlashed log of LockedGold.
+func decodeSlashingLog(l types.Log, epochSize uint64) (slashingEvent, error) {
+	if len(l.Topics) != 3 || len(l.Data) != 64 {
+		return slashingEvent{}, fmt.Errorf("malformed AccountSlashed log in tx %x", l.TxHash)
+	}
+	return slashingEvent{
+		BlockNumber: l.BlockNumber,
+		Epoch:       istanbul.GetEpochNumber(l.BlockNumber, epochSize),
+		TxHash:      l.TxHash,
+		Penalty:     new(big.Int).SetBytes(l.Data[:32]),
+		Reporter:    common.BytesToAddress(l.Topics[2].Bytes()),
+		Reward:      new(big.Int).SetBytes(l.Data[32:]),
+	}, nil
+}
+
+func getSlashingInfo(_ *cli.Context, core *listener) error {
+	target := core.cfg.TargetAddress
+	if target == params.ZeroAddress {
+		return fmt.Errorf("--target is required")
+	}
+	var (
+		multiplier  interface{}
+		lastSlashed interface{}
+		unpackErr   error
+	)
+	validatorAddress := core.cfg.ValidatorParameters.ValidatorAddress
+	abiValidator := core.cfg.ValidatorParameters.ValidatorABI
+	f := func(output []byte) {
+		var out []interface{}
+		if out, unpackErr = abiValidator.Unpack("getValidator", output); unpackErr == nil {
+			multiplier, lastSlashed = out[len(out)-2], out[len(out)-1]
+		}
+	}
+	m := NewMessageRet2(SolveQueryResult4, core.msgCh, core.cfg, f, validatorAddress, nil, abiValidator, "getValidator", target)
+	go core.writer.ResolveMessage(m)
+	core.waitUntilMsgHandled(1)
+	if unpackErr != nil {
+		return fmt.Errorf("getValidator: %v", unpackErr)
+	}
+	log.Info("=== getSlashingInfo ===", "validator", target)
+	log.Info("", "SlashMultiplier", ConvertToFraction(multiplier), "LastSlashed", lastSlashed)
+
+	epochSize, err := queryEpochSize(core)
+	if err != nil {
+		return err
+	}
+	latest, err := core.conn.BlockNumber(context.Background())
+	if err != nil {
+		return err
+	}
+	lockedGoldAddress := core.cfg.LockedGoldParameters.LockedGoldAddress
+	query := mapprotocol.BuildQuery(lockedGoldAddress, mapprotocol.AccountSlashed, big.NewInt(0), new(big.Int).SetUint64(latest))
+	query.Topics = append(query.Topics, []common.Hash{common.BytesToHash(target.Bytes())})
+	logs, err := core.conn.FilterLogs(context.Background(), query)
+	if err != nil {
+		return err
+	}
+	if len(logs) == 0 {
+		log.Info("no penalties recorded")
+	}
+	for _, l := range logs {
+		event, err := decodeSlashingLog(l, epochSize)
+		if err != nil {
+			return err
+		}
+		log.Info("penalty", "epoch", event.Epoch, "block", event.BlockNumber, "penalty", event.Penalty, "reporter", event.Reporter, "reward", event.Reward, "tx", event.TxHash)
+	}
+	return nil
+}
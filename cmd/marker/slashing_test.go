@@ -0,0 +1,49 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/mapprotocol/atlas/cmd/marker/mapprotocol"
+)
+
+func TestDecodeSlashingLog(t *testing.T) {
+	var (
+		validator = common.HexToAddress("0x1c0edab88dbb72b119039c4d14b1663525b3ac15")
+		reporter  = common.HexToAddress("0xf021")
+		penalty   = new(big.Int).Mul(big.NewInt(100), big.NewInt(1e18))
+		reward    = big.NewInt(1e18)
+	)
+	l := types.Log{
+		Topics: []common.Hash{
+			mapprotocol.AccountSlashed.GetTopic(),
+			common.BytesToHash(validator.Bytes()),
+			common.BytesToHash(reporter.Bytes()),
+		},
+		Data:        append(math.U256Bytes(new(big.Int).Set(penalty)), math.U256Bytes(new(big.Int).Set(reward))...),
+		BlockNumber: 250,
+		TxHash:      common.HexToHash("0x01"),
+	}
+	event, err := decodeSlashingLog(l, 100)
+	if err != nil {
+		t.Fatalf("failed to decode log: %v", err)
+	}
+	if event.Epoch != 3 || event.BlockNumber != 250 {
+		t.Errorf("epoch/block mismatch: have %d/%d, want 3/250", event.Epoch, event.BlockNumber)
+	}
+	if event.Penalty.Cmp(penalty) != 0 || event.Reward.Cmp(reward) != 0 {
+		t.Errorf("penalty/reward mismatch: have %v/%v, want %v/%v", event.Penalty, event.Reward, penalty, reward)
+	}
+	if event.Reporter != reporter {
+		t.Errorf("reporter mismatch: have %x, want %x", event.Reporter, reporter)
+	}
+
+	l.Data = l.Data[:32]
+	if _, err := decodeSlashingLog(l, 100); err == nil {
+		t.Error("decoded a truncated log")
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	ethchain "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/log"
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/mapprotocol/atlas/params"
+)
+
+var tokenBalanceCommand = cli.Command{
+	Name:   "tokenBalance",
+	Usage:  "Gets the balance of `--target` in the ERC20 token at `--token` (the GoldToken by default)",
+	Action: MigrateFlags(tokenBalance),
+	Flags:  Flags,
+}
+
+// tokenBalance queries balanceOf and decimals through the GoldToken ABI, which
+// is ERC20 compatible, so it works for any ERC20 token deployed on atlas. Both
+// calls are sent in a single batch request.
+func tokenBalance(_ *cli.Context, core *listener) error {
+	token := core.cfg.TokenAddress
+	if token == params.ZeroAddress {
+		token = core.cfg.GoldTokenParameters.GoldTokenAddress
+	}
+	abiToken := core.cfg.GoldTokenParameters.GoldTokenABI
+
+	balanceOf, err := abiToken.Pack("balanceOf", core.cfg.TargetAddress)
+	if err != nil {
+		return err
+	}
+	decimalsOf, err := abiToken.Pack("decimals")
+	if err != nil {
+		return err
+	}
+	outputs, err := core.batchCall([]ethchain.CallMsg{
+		{From: core.cfg.CallSender(), To: &token, Data: balanceOf},
+		{From: core.cfg.CallSender(), To: &token, Data: decimalsOf},
+	})
+	if err != nil {
+		return err
+	}
+	balance, err := abiToken.Unpack("balanceOf", outputs[0])
+	if err != nil {
+		return fmt.Errorf("balanceOf: %v", err)
+	}
+	decimals, err := abiToken.Unpack("decimals", outputs[1])
+	if err != nil {
+		return fmt.Errorf("decimals: %v", err)
+	}
+
+	raw := balance[0].(*big.Int)
+	log.Info("=== tokenBalance ===", "token", token, "holder", core.cfg.TargetAddress,
+		"balance", raw.String(), "formatted", formatUnits(raw, decimals[0].(uint8)))
+	return nil
+}
+
+// formatUnits renders an integer token amount with the given number of
+// decimals, dropping trailing zeros of the fraction.
+func formatUnits(amount *big.Int, decimals uint8) string {
+	digits := new(big.Int).Abs(amount).String()
+	if len(digits) <= int(decimals) {
+		digits = strings.Repeat("0", int(decimals)-len(digits)+1) + digits
+	}
+	point := len(digits) - int(decimals)
+	whole, fraction := digits[:point], strings.TrimRight(digits[point:], "0")
+	if amount.Sign() < 0 {
+		whole = "-" + whole
+	}
+	if fraction == "" {
+		return whole
+	}
+	return whole + "." + fraction
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestFormatUnits(t *testing.T) {
+	tests := []struct {
+		amount   string
+		decimals uint8
+		want     string
+	}{
+		{"0", 18, "0"},
+		{"1", 18, "0.000000000000000001"},
+		{"1000000000000000000", 18, "1"},
+		{"1500000000000000000", 18, "1.5"},
+		{"123456789000000000000", 18, "123.456789"},
+		{"-2500000", 6, "-2.5"},
+		{"42", 0, "42"},
+	}
+	for _, tt := range tests {
+		amount, _ := new(big.Int).SetString(tt.amount, 10)
+		if have := formatUnits(amount, tt.decimals); have != tt.want {
+			t.Errorf("formatUnits(%s, %d): have %s, want %s", tt.amount, tt.decimals, have, tt.want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	ethchain "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/mapprotocol/atlas/params"
+)
+
+var transferCommand = cli.Command{
+	Name:   "transfer",
+	Usage:  "Transfers `--value` (or `--value-wei`) GoldToken from the account to `--target`",
+	Action: MigrateFlags(transfer),
+	Flags:  Flags,
+}
+
+// checkTransfer validates a transfer of value to target by an account holding
+// balance. A nil balance is not checked.
+func checkTransfer(target common.Address, value, balance *big.Int) error {
+	if target == params.ZeroAddress {
+		return errors.New("transfer needs a --target address")
+	}
+	if value == nil || value.Sign() <= 0 {
+		return errors.New("transfer needs a positive --value or --value-wei")
+	}
+	if balance != nil && balance.Cmp(value) < 0 {
+		return fmt.Errorf("insufficient balance: have %s MAP, want %s MAP", formatUnits(balance, 18), formatUnits(value, 18))
+	}
+	return nil
+}
+
+// transfer calls transfer on the GoldToken contract. The balance of the account
+// is checked first, unless signing offline.
+func transfer(_ *cli.Context, core *listener) error {
+	GoldTokenAddress := core.cfg.GoldTokenParameters.GoldTokenAddress
+	abiGoldToken := core.cfg.GoldTokenParameters.GoldTokenABI
+
+	var balance *big.Int
+	if core.offline == nil {
+		balanceOf, err := abiGoldToken.Pack("balanceOf", core.cfg.From)
+		if err != nil {
+			return err
+		}
+		outputs, err := core.batchCall([]ethchain.CallMsg{{From: core.cfg.From, To: &GoldTokenAddress, Data: balanceOf}})
+		if err != nil {
+			return err
+		}
+		ret, err := abiGoldToken.Unpack("balanceOf", outputs[0])
+		if err != nil {
+			return fmt.Errorf("balanceOf: %v", err)
+		}
+		balance = ret[0].(*big.Int)
+	}
+	if err := checkTransfer(core.cfg.TargetAddress, core.cfg.Value, balance); err != nil {
+		return err
+	}
+	log.Info("=== transfer ===", "from", core.cfg.From, "to", core.cfg.TargetAddress, "amount", formatUnits(core.cfg.Value, 18)+" MAP")
+	m := NewMessage(SolveSendTranstion1, core.msgCh, core.cfg, GoldTokenAddress, nil, abiGoldToken, "transfer", core.cfg.TargetAddress, core.cfg.Value)
+	go core.writer.ResolveMessage(m)
+	core.waitUntilMsgHandled(1)
+	return nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/mapprotocol/atlas/params"
+)
+
+func TestCheckTransfer(t *testing.T) {
+	target := common.HexToAddress("0x01")
+	tests := []struct {
+		target  common.Address
+		value   *big.Int
+		balance *big.Int
+		ok      bool
+	}{
+		{target, big.NewInt(10), big.NewInt(10), true},
+		{target, big.NewInt(10), nil, true},
+		{target, big.NewInt(10), big.NewInt(9), false},
+		{target, big.NewInt(0), big.NewInt(10), false},
+		{target, nil, big.NewInt(10), false},
+		{params.ZeroAddress, big.NewInt(10), big.NewInt(10), false},
+	}
+	for i, tt := range tests {
+		if err := checkTransfer(tt.target, tt.value, tt.balance); (err == nil) != tt.ok {
+			t.Errorf("test %d: error mismatch: have %v, want ok %v", i, err, tt.ok)
+		}
+	}
+}
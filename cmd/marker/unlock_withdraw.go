@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// autoWithdrawMaxWait is the longest time slept at once while waiting for a
+// pending withdrawal with --auto-withdraw, so progress is logged regularly.
+const autoWithdrawMaxWait = 10 * time.Minute
+
+// maturedWithdrawals returns the pending withdrawals available at the given
+// chain time, in contract order.
+func maturedWithdrawals(values, timestamps []*big.Int, now uint64) []pendingWithdrawal {
+	var matured []pendingWithdrawal
+	for i := range values {
+		if timestamps[i].IsUint64() && timestamps[i].Uint64() <= now {
+			matured = append(matured, pendingWithdrawal{Index: i, Value: values[i], Timestamp: timestamps[i]})
+		}
+	}
+	return matured
+}
+
+// nextWithdrawal returns the pending withdrawal which becomes available first,
+// false if there is none.
+func nextWithdrawal(values, timestamps []*big.Int) (pendingWithdrawal, bool) {
+	next := -1
+	for i := range values {
+		if next < 0 || timestamps[i].Cmp(timestamps[next]) < 0 {
+			next = i
+		}
+	}
+	if next < 0 {
+		return pendingWithdrawal{}, false
+	}
+	return pendingWithdrawal{Index: next, Value: values[next], Timestamp: timestamps[next]}, true
+}
+
+// withdrawalTime formats the time a pending withdrawal becomes available.
+func withdrawalTime(timestamp *big.Int) string {
+	return time.Unix(timestamp.Int64(), 0).UTC().Format(time.RFC3339)
+}
+
+// chainTime returns the timestamp of the head block, which the LockedGold
+// contract compares the withdrawal timestamps with.
+func chainTime(core *listener) (uint64, error) {
+	var head struct {
+		Time hexutil.Uint64 `json:"timestamp"`
+	}
+	if err := core.rpc.Call(&head, "eth_getBlockByNumber", "latest", false); err != nil {
+		return 0, err
+	}
+	return uint64(head.Time), nil
+}
+
+// printUnlockedAvailability logs when the withdrawal created by the unlock just
+// sent, the last pending one, becomes available.
+func printUnlockedAvailability(core *listener) error {
+	values, timestamps, err := queryPendingWithdrawals(core, core.cfg.From)
+	if err != nil {
+		return err
+	}
+	if len(values) == 0 {
+		return errors.New("no pending withdrawal after unlock")
+	}
+	last := len(values) - 1
+	log.Info("Unlocked gold can be withdrawn", "withdrawIndex", last, "value", values[last],
+		"timestamp", timestamps[last], "available", withdrawalTime(timestamps[last]))
+	return nil
+}
+
+// autoWithdraw withdraws the pending withdrawals of the loaded account as they
+// become available, until none is left. Every withdraw moves the last pending
+// withdrawal into the freed index, so the list is queried again after each one.
+func autoWithdraw(core *listener) error {
+	for {
+		values, timestamps, err := queryPendingWithdrawals(core, core.cfg.From)
+		if err != nil {
+			return err
+		}
+		now, err := chainTime(core)
+		if err != nil {
+			return err
+		}
+		if matured := maturedWithdrawals(values, timestamps, now); len(matured) > 0 {
+			sendWithdraw(core, big.NewInt(int64(matured[0].Index)))
+			if !isContinueError {
+				return errors.New("withdraw failed")
+			}
+			continue
+		}
+		next, ok := nextWithdrawal(values, timestamps)
+		if !ok {
+			log.Info("No pending withdrawals left", "account", core.cfg.From)
+			return nil
+		}
+		wait := time.Duration(next.Timestamp.Uint64()-now) * time.Second
+		log.Info("Waiting for pending withdrawal", "withdrawIndex", next.Index, "value", next.Value,
+			"available", withdrawalTime(next.Timestamp), "in", wait)
+		if wait > autoWithdrawMaxWait {
+			wait = autoWithdrawMaxWait
+		}
+		time.Sleep(wait)
+	}
+}
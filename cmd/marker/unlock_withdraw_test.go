@@ -0,0 +1,34 @@
+package main
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestMaturedWithdrawals(t *testing.T) {
+	values := []*big.Int{big.NewInt(10), big.NewInt(20), big.NewInt(30)}
+	timestamps := []*big.Int{big.NewInt(300), big.NewInt(100), big.NewInt(200)}
+
+	if matured := maturedWithdrawals(values, timestamps, 99); len(matured) != 0 {
+		t.Errorf("withdrawals matured too early: %v", matured)
+	}
+	want := []pendingWithdrawal{
+		{1, big.NewInt(20), big.NewInt(100)},
+		{2, big.NewInt(30), big.NewInt(200)},
+	}
+	if matured := maturedWithdrawals(values, timestamps, 200); !reflect.DeepEqual(matured, want) {
+		t.Errorf("matured withdrawals mismatch: have %v, want %v", matured, want)
+	}
+
+	next, ok := nextWithdrawal(values, timestamps)
+	if !ok || next.Index != 1 || next.Timestamp.Int64() != 100 {
+		t.Errorf("next withdrawal mismatch: have %v, %v", next, ok)
+	}
+	if _, ok := nextWithdrawal(nil, nil); ok {
+		t.Errorf("next withdrawal found without pending withdrawals")
+	}
+	if have, want := withdrawalTime(big.NewInt(86400)), "1970-01-02T00:00:00Z"; have != want {
+		t.Errorf("withdrawal time mismatch: have %s, want %s", have, want)
+	}
+}
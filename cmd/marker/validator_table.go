@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"text/tabwriter"
+
+	ethchain "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/mapprotocol/atlas/accounts/abi"
+)
+
+// validatorRow is a validator as listed by the query commands in table or
+// json output.
+type validatorRow struct {
+	Index      int            `json:"index"`
+	Address    common.Address `json:"address"`
+	Votes      *big.Int       `json:"votes"`
+	Commission string         `json:"commission"`
+}
+
+// queryValidatorRows looks up the votes and commission of every validator in
+// addrs. If signers is set, addrs are validator signers and their accounts are
+// looked up first. Every round of calls is sent in a single batch request.
+func queryValidatorRows(core *listener, addrs []common.Address, signers bool) ([]validatorRow, error) {
+	var (
+		sender            = core.cfg.CallSender()
+		accountsAddress   = core.cfg.AccountsParameters.AccountsAddress
+		abiAccounts       = core.cfg.AccountsParameters.AccountsABI
+		validatorsAddress = core.cfg.ValidatorParameters.ValidatorAddress
+		abiValidators     = core.cfg.ValidatorParameters.ValidatorABI
+		electionAddress   = core.cfg.ElectionParameters.ElectionAddress
+		abiElection       = core.cfg.ElectionParameters.ElectionABI
+	)
+	call := func(to common.Address, contract *abi.ABI, method string, args ...interface{}) (ethchain.CallMsg, error) {
+		input, err := contract.Pack(method, args...)
+		if err != nil {
+			return ethchain.CallMsg{}, fmt.Errorf("pack %s: %v", method, err)
+		}
+		return ethchain.CallMsg{From: sender, To: &to, Data: input}, nil
+	}
+	accounts := addrs
+	if signers && len(addrs) > 0 {
+		calls := make([]ethchain.CallMsg, len(addrs))
+		for i, signer := range addrs {
+			msg, err := call(accountsAddress, abiAccounts, "validatorSignerToAccount", signer)
+			if err != nil {
+				return nil, err
+			}
+			calls[i] = msg
+		}
+		outputs, err := core.batchCall(calls)
+		if err != nil {
+			return nil, err
+		}
+		accounts = make([]common.Address, len(addrs))
+		for i, output := range outputs {
+			out, err := abiAccounts.Unpack("validatorSignerToAccount", output)
+			if err != nil {
+				return nil, fmt.Errorf("validatorSignerToAccount %v: %v", addrs[i], err)
+			}
+			accounts[i] = out[0].(common.Address)
+		}
+	}
+	calls := make([]ethchain.CallMsg, 0, 2*len(accounts))
+	for _, account := range accounts {
+		votes, err := call(electionAddress, abiElection, "getTotalVotesForValidator", account)
+		if err != nil {
+			return nil, err
+		}
+		validator, err := call(validatorsAddress, abiValidators, "getValidator", account)
+		if err != nil {
+			return nil, err
+		}
+		calls = append(calls, votes, validator)
+	}
+	outputs, err := core.batchCall(calls)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]validatorRow, len(addrs))
+	for i := range addrs {
+		votes, err := abiElection.Unpack("getTotalVotesForValidator", outputs[2*i])
+		if err != nil {
+			return nil, fmt.Errorf("getTotalVotesForValidator %v: %v", accounts[i], err)
+		}
+		validator, err := abiValidators.Unpack("getValidator", outputs[2*i+1])
+		if err != nil {
+			return nil, fmt.Errorf("getValidator %v: %v", accounts[i], err)
+		}
+		rows[i] = validatorRow{
+			Index:      i,
+			Address:    addrs[i],
+			Votes:      votes[0].(*big.Int),
+			Commission: ConvertToFraction(validator[5]),
+		}
+	}
+	return rows, nil
+}
+
+// printValidatorRows writes the rows in the given output format, either an
+// aligned table or json.
+func printValidatorRows(w io.Writer, output string, rows []validatorRow) error {
+	if output == "json" {
+		data, err := json.MarshalIndent(rows, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "INDEX\tADDRESS\tVOTES\tCOMMISSION\t")
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t\n", row.Index, row.Address.Hex(), row.Votes, row.Commission)
+	}
+	return tw.Flush()
+}
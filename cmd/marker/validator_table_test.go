@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestPrintValidatorRowsTable(t *testing.T) {
+	rows := []validatorRow{
+		{Index: 0, Address: common.HexToAddress("0x01"), Votes: big.NewInt(5), Commission: "0.1"},
+		{Index: 1, Address: common.HexToAddress("0x02"), Votes: big.NewInt(123456789), Commission: "0.25"},
+	}
+	var buf bytes.Buffer
+	if err := printValidatorRows(&buf, "table", rows); err != nil {
+		t.Fatalf("failed to print table: %v", err)
+	}
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != len(rows)+1 {
+		t.Fatalf("line count mismatch: have %d, want %d:\n%s", len(lines), len(rows)+1, buf.String())
+	}
+	// Right aligned columns end at the same offset on every line
+	for _, column := range []string{"VOTES", "COMMISSION"} {
+		end := strings.Index(lines[0], column) + len(column)
+		for i, row := range rows {
+			value := row.Votes.String()
+			if column == "COMMISSION" {
+				value = row.Commission
+			}
+			if have := strings.Index(lines[i+1], value) + len(value); have != end {
+				t.Errorf("row %d: %s column misaligned: ends at %d, want %d:\n%s", i, column, have, end, buf.String())
+			}
+		}
+	}
+	if !strings.Contains(lines[2], rows[1].Address.Hex()) {
+		t.Errorf("row 1 misses address %s: %q", rows[1].Address.Hex(), lines[2])
+	}
+}
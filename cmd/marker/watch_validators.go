@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/mapprotocol/atlas/cmd/marker/connections"
+	"github.com/mapprotocol/atlas/consensus/istanbul"
+)
+
+// watchReconnectDelay is the time waited before dialing the node again after
+// the websocket connection dropped.
+const watchReconnectDelay = 5 * time.Second
+
+var watchValidatorsCommand = cli.Command{
+	Name:   "watchValidators",
+	Usage:  "follow the chain over the websocket endpoint and print the validators added and removed at every epoch",
+	Action: MigrateFlags(watchValidators),
+	Flags:  Flags,
+}
+
+// validatorWatcher remembers the validator set of the last epoch seen.
+type validatorWatcher struct {
+	epochSize  uint64
+	epoch      uint64
+	validators []common.Address // nil until the first head is seen
+}
+
+// diffValidators returns the validators of next missing from prev, and the ones
+// of prev missing from next.
+func diffValidators(prev, next []common.Address) (added, removed []common.Address) {
+	inPrev := make(map[common.Address]bool, len(prev))
+	for _, addr := range prev {
+		inPrev[addr] = true
+	}
+	inNext := make(map[common.Address]bool, len(next))
+	for _, addr := range next {
+		inNext[addr] = true
+		if !inPrev[addr] {
+			added = append(added, addr)
+		}
+	}
+	for _, addr := range prev {
+		if !inNext[addr] {
+			removed = append(removed, addr)
+		}
+	}
+	return added, removed
+}
+
+// newHead fetches the validator set of the block once per epoch and prints
+// how it differs from the previous one. Epochs passed while disconnected are
+// reported as a single change.
+func (w *validatorWatcher) newHead(client *rpc.Client, number uint64) error {
+	epoch := istanbul.GetEpochNumber(number, w.epochSize)
+	if w.validators != nil && epoch <= w.epoch {
+		return nil
+	}
+	validators, err := getValidatorsAt(client, number)
+	if err != nil {
+		return err
+	}
+	if w.validators == nil {
+		log.Info("=== watching validators ===", "block", number, "epoch", epoch, "validators", validators)
+	} else {
+		added, removed := diffValidators(w.validators, validators)
+		log.Info("=== validator set changed ===", "block", number, "epoch", epoch, "added", added, "removed", removed, "size", len(validators))
+	}
+	w.epoch, w.validators = epoch, validators
+	return nil
+}
+
+// getValidatorsAt returns the addresses of the validators signing the block.
+func getValidatorsAt(client *rpc.Client, number uint64) ([]common.Address, error) {
+	var infos []struct {
+		Address common.Address `json:"address"`
+	}
+	blockNr := rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(number))
+	if err := client.Call(&infos, "istanbul_getValidators", blockNr); err != nil {
+		return nil, err
+	}
+	validators := make([]common.Address, len(infos))
+	for i, info := range infos {
+		validators[i] = info.Address
+	}
+	return validators, nil
+}
+
+// watch follows the new heads of the node until the subscription fails.
+func (w *validatorWatcher) watch(core *listener) error {
+	client, url, err := connections.DialWs(context.Background(), core.cfg)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if w.epochSize == 0 {
+		if err := client.Call(&w.epochSize, "istanbul_getEpochSize"); err != nil {
+			return err
+		}
+	}
+	// Only the number of the heads is decoded, the atlas headers don't match
+	// the ones of the go-ethereum client
+	heads := make(chan *struct {
+		Number *hexutil.Big `json:"number"`
+	})
+	sub, err := client.EthSubscribe(context.Background(), heads, "newHeads")
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+	log.Info("Subscribed to new heads", "url", url, "epochSize", w.epochSize)
+
+	for {
+		select {
+		case head := <-heads:
+			if err := w.newHead(client, head.Number.ToInt().Uint64()); err != nil {
+				log.Warn("Failed to fetch validators", "block", head.Number, "err", err)
+			}
+		case err := <-sub.Err():
+			return err
+		}
+	}
+}
+
+func watchValidators(_ *cli.Context, core *listener) error {
+	w := new(validatorWatcher)
+	for {
+		err := w.watch(core)
+		log.Warn("Lost the websocket connection, reconnecting", "err", err, "delay", watchReconnectDelay)
+		time.Sleep(watchReconnectDelay)
+	}
+}
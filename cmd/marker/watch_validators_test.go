@@ -0,0 +1,29 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestDiffValidators(t *testing.T) {
+	a, b, c, d := common.Address{1}, common.Address{2}, common.Address{3}, common.Address{4}
+	tests := []struct {
+		prev, next     []common.Address
+		added, removed []common.Address
+	}{
+		{[]common.Address{a, b}, []common.Address{a, b}, nil, nil},
+		{[]common.Address{a, b}, []common.Address{b, a}, nil, nil},
+		{[]common.Address{a, b}, []common.Address{a, b, c}, []common.Address{c}, nil},
+		{[]common.Address{a, b, c}, []common.Address{a}, nil, []common.Address{b, c}},
+		{[]common.Address{a, b}, []common.Address{c, b, d}, []common.Address{c, d}, []common.Address{a}},
+		{nil, []common.Address{a}, []common.Address{a}, nil},
+	}
+	for i, tt := range tests {
+		added, removed := diffValidators(tt.prev, tt.next)
+		if !reflect.DeepEqual(added, tt.added) || !reflect.DeepEqual(removed, tt.removed) {
+			t.Errorf("test %d: have (%v, %v), want (%v, %v)", i, added, removed, tt.added, tt.removed)
+		}
+	}
+}
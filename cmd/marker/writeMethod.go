@@ -16,13 +16,8 @@ import (
 
 const DefaultGasLimit = 4500000
 
-func sendContractTransaction(client *ethclient.Client, from, toAddress common.Address, value *big.Int, privateKey *ecdsa.PrivateKey, input []byte, gasLimitSeting uint64) common.Hash {
-	// Ensure a valid value field and resolve the account nonce
+func sendContractTransaction(client *ethclient.Client, from, toAddress common.Address, value *big.Int, privateKey *ecdsa.PrivateKey, input []byte, gasLimitSeting uint64, nonce uint64) common.Hash {
 	logger := log.New("func", "sendContractTransaction")
-	nonce, err := client.PendingNonceAt(context.Background(), from)
-	if err != nil {
-		logger.Error("PendingNonceAt", "error", err)
-	}
 	gasPrice, err := client.SuggestGasPrice(context.Background())
 	//gasPrice = big.NewInt(1000 000 000 000)
 	if err != nil {
@@ -65,7 +60,10 @@ func sendContractTransaction(client *ethclient.Client, from, toAddress common.Ad
 	return signedTx.Hash()
 }
 
-func getResult(conn *ethclient.Client, txHash common.Hash, contract bool) {
+// getResult waits until the transaction is mined and logs its outcome. With
+// confirmations set, it then waits for the transaction to be buried under as
+// many blocks and fails if it was reorged out in the meantime.
+func getResult(conn *ethclient.Client, txHash common.Hash, contract bool, confirmations uint64) error {
 	logger := log.New("func", "getResult")
 	logger.Info("Please waiting ", " txHash ", txHash.String())
 	for {
@@ -80,6 +78,10 @@ func getResult(conn *ethclient.Client, txHash common.Hash, contract bool) {
 	}
 
 	queryTx(conn, txHash, contract, false)
+	if confirmations == 0 {
+		return nil
+	}
+	return waitConfirmations(conn, txHash, confirmations, confirmationPollInterval)
 }
 
 func queryTx(conn *ethclient.Client, txHash common.Hash, contract bool, pending bool) {
@@ -1,7 +1,14 @@
 package main
 
 import (
+	"context"
+	"math/big"
+	"os"
+
+	ethchain "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/mapprotocol/atlas/cmd/marker/config"
 	"github.com/mapprotocol/atlas/cmd/marker/connections"
 
@@ -9,27 +16,51 @@ import (
 )
 
 type writer struct {
-	config *config.Config
-	conn   *ethclient.Client
+	config  *config.Config
+	conn    *ethclient.Client
+	offline *offlineBackend // set with --sign-only
+	nonce   *uint64         // nonce of the next transaction, set with --nonce
 }
 
 func NewWriter(ctx *cli.Context, config *config.Config) *writer {
 	conn, _ := connections.DialConn(ctx, config)
-	return &writer{
+	w := &writer{
 		config: config,
 		conn:   conn,
 	}
+	if config.NonceSet {
+		nonce := config.Nonce
+		w.nonce = &nonce
+	}
+	return w
 }
 
 func (w *writer) ResolveMessage(m Message) bool {
-	switch m.messageType {
-	case SolveSendTranstion1:
-		txHash := sendContractTransaction(w.conn, m.from, m.to, nil, m.priKey, m.input, m.gasLimit)
-		getResult(w.conn, txHash, true)
+	if w.config.Estimate && (m.messageType == SolveSendTranstion1 || m.messageType == SolveSendTranstion2) {
+		w.estimate(m)
+		m.DoneCh <- struct{}{}
+		return true
+	}
+	if w.offline != nil && (m.messageType == SolveSendTranstion1 || m.messageType == SolveSendTranstion2) {
+		w.signOffline(m)
 		m.DoneCh <- struct{}{}
-	case SolveSendTranstion2:
-		txHash := sendContractTransaction(w.conn, m.from, m.to, m.value, m.priKey, m.input, m.gasLimit)
-		getResult(w.conn, txHash, true)
+		return true
+	}
+	switch m.messageType {
+	case SolveSendTranstion1, SolveSendTranstion2:
+		var value *big.Int
+		if m.messageType == SolveSendTranstion2 {
+			value = m.value
+		}
+		nonce, err := w.nextNonce(m.from)
+		if err != nil {
+			isContinueError = false
+			log.Error("resolve nonce", "error", err)
+			m.DoneCh <- struct{}{}
+			return true
+		}
+		txHash := sendContractTransaction(w.conn, m.from, m.to, value, m.priKey, m.input, m.gasLimit, nonce)
+		w.getResult(txHash)
 		m.DoneCh <- struct{}{}
 	case SolveQueryResult3:
 		w.handleUnpackMethodSolveType3(m)
@@ -41,3 +72,43 @@ func (w *writer) ResolveMessage(m Message) bool {
 	}
 	return true
 }
+
+// estimate logs the gas estimate of the transaction m would send instead of
+// sending it.
+func (w *writer) estimate(m Message) {
+	msg := ethchain.CallMsg{From: m.from, To: &m.to, Data: m.input}
+	if m.messageType == SolveSendTranstion2 {
+		msg.Value = m.value
+	}
+	if err := estimateTx(context.Background(), w.conn, msg); err != nil {
+		isContinueError = false
+		log.Error("estimate transaction", "error", err)
+	}
+}
+
+// signOffline signs the transaction m would send and prints it instead of
+// sending it.
+func (w *writer) signOffline(m Message) {
+	var value *big.Int
+	if m.messageType == SolveSendTranstion2 {
+		value = m.value
+	}
+	gasLimit := m.gasLimit
+	if gasLimit == 0 {
+		gasLimit = DefaultGasLimit
+	}
+	if err := w.offline.sign(m.priKey, m.to, value, gasLimit, m.input); err != nil {
+		isContinueError = false
+		log.Error("sign transaction", "error", err)
+	}
+}
+
+// getResult waits for a transaction sent by the writer. Messages have no way to
+// report an error back, so a transaction reorged out before reaching its
+// confirmations ends the process.
+func (w *writer) getResult(txHash common.Hash) {
+	if err := getResult(w.conn, txHash, true, w.config.Confirmations); err != nil {
+		log.Error("Transaction not confirmed", "txHash", txHash, "err", err)
+		os.Exit(1)
+	}
+}
@@ -19,18 +19,23 @@ package consensus
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 
 	"github.com/mapprotocol/atlas/chains"
 	"github.com/mapprotocol/atlas/chains/ethereum"
 	"github.com/mapprotocol/atlas/consensus/istanbul"
+	"github.com/mapprotocol/atlas/consensus/istanbul/uptime"
 	"github.com/mapprotocol/atlas/core/state"
 	"github.com/mapprotocol/atlas/core/types"
 	"github.com/mapprotocol/atlas/core/vm"
+	blscrypto "github.com/mapprotocol/atlas/helper/bls"
 	"github.com/mapprotocol/atlas/p2p"
 	"github.com/mapprotocol/atlas/params"
 	params2 "github.com/mapprotocol/atlas/params"
@@ -110,7 +115,8 @@ type Engine interface {
 	//
 	// Note, the method returns immediately and will send the result async. More
 	// than one result may also be returned depending on the consensus algorithm.
-	Seal(chain ChainHeaderReader, block *types.Block) error
+	// Closing stop aborts a sealing request that has not been submitted yet.
+	Seal(chain ChainHeaderReader, block *types.Block, stop <-chan struct{}) error
 
 	// SealHash returns the hash of a block prior to it being sealed.
 	SealHash(header *types.Header) common.Hash
@@ -125,13 +131,21 @@ type Engine interface {
 	// GetValidators returns the list of current validators.
 	GetValidators(blockNumber *big.Int, headerHash common.Hash) []istanbul.Validator
 
+	// GetValidatorBLSKeys returns the serialized BLS public keys of the current
+	// validators, in validator set order.
+	GetValidatorBLSKeys(blockNumber *big.Int, headerHash common.Hash) []blscrypto.SerializedPublicKey
+
 	EpochSize() uint64
 
 	// APIs returns the RPC APIs this consensus engine provides.
 	APIs(chain ChainHeaderReader) []rpc.API
 
 	// Close terminates any background threads maintained by the consensus engine.
+	// Calling Close more than once must be safe.
 	Close() error
+
+	// Closed reports whether Close has been called on the consensus engine.
+	Closed() bool
 }
 
 // PoW is a consensus engine based on proof-of-work.
@@ -158,6 +172,10 @@ type Istanbul interface {
 	// IsPrimaryForSeq returns true if this node is the primary validator for the sequence
 	IsPrimaryForSeq(seq *big.Int) bool
 
+	// Status returns whether this node is validating, announcing or running the
+	// proxied validator engine, along with its roles and the current view
+	Status() istanbul.EngineStatus
+
 	// SetChain injects the blockchain and related functions to the istanbul consensus engine
 	SetChain(chain ChainContext, currentBlock func() *types.Block, stateAt func(common.Hash) (*state.StateDB, error))
 
@@ -167,6 +185,41 @@ type Istanbul interface {
 		validateState func(*types.Block, *state.StateDB, types.Receipts, uint64) error,
 		onNewConsensusBlock func(block *types.Block, receipts []*types.Receipt, logs []*types.Log, state *state.StateDB)) error
 
+	// SetEpochTransitionCallback sets a function called, in chain order, for every epoch
+	// whose last block becomes part of the canonical chain, with the validator sets of
+	// the ending and of the following epoch
+	SetEpochTransitionCallback(onEpochTransition func(epoch uint64, oldSet, newSet []istanbul.Validator))
+
+	// SetDoubleSignCallback sets a function called with the evidence of every
+	// validator detected signing two conflicting proposals or commits, e.g. to
+	// submit it to the slashing contract. Each call runs in its own goroutine
+	SetDoubleSignCallback(onDoubleSign func(evidence *istanbul.DoubleSignEvidence))
+
+	// DoubleSignEvidence retrieves the double signing evidence recorded for the
+	// given epoch
+	DoubleSignEvidence(epoch uint64) ([]*istanbul.DoubleSignEvidence, error)
+
+	// VerifyEpochSnarkData checks that the epoch SNARK data in the body of the
+	// last block of an epoch is signed by a quorum of its validators over the
+	// following validator set, and that other blocks carry none
+	VerifyEpochSnarkData(block *types.Block) error
+
+	// SignersOfBlock returns the validators which signed the block, decoded from
+	// the bitmap of its aggregated seal against the validator set of the block
+	SignersOfBlock(header *types.Header) ([]common.Address, error)
+
+	// SubscribeEpochRewards subscribes to the rewards distributed by the last
+	// block of every epoch reached by the canonical chain
+	SubscribeEpochRewards(ch chan<- istanbul.EpochRewardsProcessed) event.Subscription
+
+	// EpochRewards retrieves the rewards of a recent epoch, nil if they are no
+	// longer kept in memory
+	EpochRewards(epoch uint64) *istanbul.EpochRewardsProcessed
+
+	// RecentEpochRewards retrieves the rewards of the recent epochs kept in
+	// memory, ordered by epoch
+	RecentEpochRewards() []*istanbul.EpochRewardsProcessed
+
 	// StartValidating starts the validating engine
 	StartValidating() error
 
@@ -195,11 +248,51 @@ type Istanbul interface {
 	// LookbackWindow returns the size of the lookback window for calculating uptime (in blocks)
 	LookbackWindow(header *types.Header, state *state.StateDB) uint64
 
+	// LookbackSchedule returns the lookback window sizes along with the blocks they
+	// are activated at
+	LookbackSchedule() uptime.LookbackSchedule
+
+	// EpochSignatureStats returns, for each validator of the given epoch, the number of
+	// blocks of the epoch's monitoring window so far in which it was considered up,
+	// i.e. had signed within the lookback window.
+	EpochSignatureStats(epoch uint64) (map[common.Address]uint64, error)
+
+	// IsValidatorAt returns true if addr is in the validator set for the given block
+	IsValidatorAt(blockNumber *big.Int, headerHash common.Hash, addr common.Address) bool
+
+	// IsProposerInTurn returns true if the header was proposed by the validator
+	// expected to propose it in the first round of its sequence
+	IsProposerInTurn(header *types.Header) (bool, error)
+
 	// ValidatorAddress will return the istanbul engine's validator address
 	ValidatorAddress() common.Address
 
 	// GenerateRandomness will generate the random beacon randomness
 	GenerateRandomness(parentHash common.Hash) (common.Hash, common.Hash, error)
+
+	// SetRandomBeacon replaces the source of the random beacon randomness, nil
+	// restores the default one derived from the validator's key
+	SetRandomBeacon(beacon RandomBeacon)
+
+	// VerifyRandomness checks that the revealed randomness matches the commitment
+	// made for it, using the random contract at the state of the given parent block.
+	VerifyRandomness(parentHash common.Hash, commitment, revealed common.Hash) error
+}
+
+// RandomBeacon is a source of the randomness a validator reveals in the blocks
+// it proposes. The randomness for a block is derived from its parent hash and is
+// committed to in the validator's previous proposal, to be revealed in its next.
+//
+// Other nodes check a revealed randomness against the commitment with the random
+// contract's computeCommitment. Commit must return exactly that value for the
+// randomness Reveal returns, or the blocks of the validator are rejected.
+type RandomBeacon interface {
+	// Commit returns the commitment to the randomness generated for the given
+	// parent hash, as computed by the random contract's computeCommitment
+	Commit(parentHash common.Hash) (commitment common.Hash, err error)
+
+	// Reveal returns the randomness generated for the given parent hash
+	Reveal(parentHash common.Hash) (common.Hash, error)
 }
 
 // ChainContext defines a small collection of methods needed to access the local
@@ -210,6 +303,10 @@ type ChainContext interface {
 	// NewEVMRunnerForCurrentBlock creates the System's EVMRunner for current block & state
 	NewEVMRunnerForCurrentBlock() (vm.EVMRunner, error)
 
+	// NewEVMRunnerForBlock creates a read-only System's EVMRunner for the state
+	// of the given header, failing with ErrStateUnavailable if it was pruned
+	NewEVMRunnerForBlock(header *types.Header) (vm.EVMRunner, error)
+
 	// NewEVMRunner creates the System's EVMRunner for given header & sttate
 	NewEVMRunner(header *types.Header, state types.StateDB) vm.EVMRunner
 }
@@ -239,10 +336,13 @@ type Handler interface {
 	Handshake(peer Peer) (bool, error)
 }
 
-func InitHeaderStore(state *state.StateDB, blockNumber *big.Int) {
+// InitHeaderStore initializes the header store of the relayed chain with the
+// anchor of the relayer config at the genesis block.
+func InitHeaderStore(state *state.StateDB, blockNumber *big.Int, config *params.RelayerConfig) error {
 	if blockNumber.Cmp(big.NewInt(0)) == 0 {
-		initEthereumStore(state)
+		return initEthereumStore(state, config)
 	}
+	return nil
 }
 
 func InitTxVerify(state *state.StateDB, blockNumber *big.Int) {
@@ -255,19 +355,51 @@ func InitTxVerify(state *state.StateDB, blockNumber *big.Int) {
 	}
 }
 
-func initEthereumStore(state *state.StateDB) {
+// EthereumAnchor returns the header and total difficulty the Ethereum header
+// store is initialized with, the Ethereum testnet default if config is nil.
+func EthereumAnchor(config *params.RelayerConfig) (*ethereum.Header, *big.Int, error) {
+	var header ethereum.Header
+	if config == nil {
+		if err := json.Unmarshal([]byte(params.EthereumTestnetGenesisHeader), &header); err != nil {
+			return nil, nil, fmt.Errorf("invalid ethereum testnet header: %v", err)
+		}
+		return &header, params.EthereumTestnetGenesisTD, nil
+	}
+
+	if err := config.Check(); err != nil {
+		return nil, nil, err
+	}
+	if !chains.IsSupportedChain(chains.ChainType(config.ChainID.Uint64())) {
+		return nil, nil, fmt.Errorf("relayer config: unsupported source chain %v", config.ChainID)
+	}
+	if len(config.AnchorHeader) != 0 {
+		if err := json.Unmarshal(config.AnchorHeader, &header); err != nil {
+			return nil, nil, fmt.Errorf("relayer config: invalid anchor header JSON: %v", err)
+		}
+	} else if err := rlp.DecodeBytes(config.AnchorHeaderRLP, &header); err != nil {
+		return nil, nil, fmt.Errorf("relayer config: invalid anchor header RLP: %v", err)
+	}
+	if header.Number == nil || header.Difficulty == nil {
+		return nil, nil, errors.New("relayer config: anchor header without number or difficulty")
+	}
+	if config.AnchorTD.Cmp(header.Difficulty) < 0 {
+		return nil, nil, fmt.Errorf("relayer config: anchor total difficulty %v below the header difficulty %v", config.AnchorTD, header.Difficulty)
+	}
+	return &header, config.AnchorTD, nil
+}
+
+func initEthereumStore(state *state.StateDB, config *params.RelayerConfig) error {
 	key := common.BytesToHash(chains.EthereumHeaderStoreAddress[:])
 	getState := state.GetPOWState(chains.EthereumHeaderStoreAddress, key)
 	if len(getState) == 0 {
-		var header ethereum.Header
-
-		if err := json.Unmarshal([]byte(params.EthereumTestnetGenesisHeader), &header); err != nil {
-			log.Crit("json unmarshal ethereum testnet header failed", "error", err)
+		header, td, err := EthereumAnchor(config)
+		if err != nil {
+			return err
 		}
-
-		if err := ethereum.InitHeaderStore(state, &header, params.EthereumTestnetGenesisTD); err != nil {
-			log.Crit("init header store failed, ", "error", err)
+		if err := ethereum.InitHeaderStore(state, header, td); err != nil {
+			return fmt.Errorf("init header store failed: %v", err)
 		}
 		state.SetCode(params.HeaderStoreAddress, params.HeaderStoreAddress[:])
 	}
+	return nil
 }
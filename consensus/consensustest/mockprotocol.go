@@ -31,8 +31,10 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/mapprotocol/atlas/consensus"
+	"github.com/mapprotocol/atlas/consensus/istanbul"
 	"github.com/mapprotocol/atlas/core/state"
 	"github.com/mapprotocol/atlas/core/types"
+	blscrypto "github.com/mapprotocol/atlas/helper/bls"
 	"github.com/mapprotocol/atlas/p2p"
 	"github.com/mapprotocol/atlas/params"
 )
@@ -128,8 +130,10 @@ type MockEngine struct {
 
 	mode Mode
 
-	fakeFail  uint64        // Block number which fails consensus even in fake mode
-	fakeDelay time.Duration // Time delay to sleep for before returning from verify
+	fakeFails  map[uint64]bool      // Block numbers which fail consensus even in fake mode
+	fakeDelay  time.Duration        // Time delay to sleep for before returning from verify
+	validators []istanbul.Validator // Validators of every block
+	epochSize  uint64               // Size of the epoch, defaultEpochSize if zero
 
 	processBlock        func(block *types.Block, statedb *state.StateDB) (types.Receipts, []*types.Log, uint64, error)
 	validateState       func(block *types.Block, statedb *state.StateDB, receipts types.Receipts, usedGas uint64) error
@@ -141,11 +145,15 @@ const (
 	FullFake
 )
 
+// defaultEpochSize is the epoch size of a MockEngine not given one
+const defaultEpochSize = 100
+
 var (
 	// Max time from current time allowed for blocks, before they're considered future blocks
 	allowedFutureBlockTime = 15 * time.Second
 
 	errZeroBlockTime = errors.New("timestamp equals parent's")
+	errNoFullChain   = errors.New("mockEngine sealing without the state of the chain")
 )
 
 // NewFaker creates a MockEngine consensus engine that accepts
@@ -158,12 +166,16 @@ func NewFaker() *MockEngine {
 }
 
 // NewFakeFailer creates a MockEngine consensus engine that
-// accepts all blocks as valid apart from the single one specified, though they
+// accepts all blocks as valid apart from the ones specified, though they
 // still have to conform to the Ethereum consensus rules.
-func NewFakeFailer(blockNumber uint64) *MockEngine {
+func NewFakeFailer(blockNumbers ...uint64) *MockEngine {
+	fakeFails := make(map[uint64]bool, len(blockNumbers))
+	for _, number := range blockNumbers {
+		fakeFails[number] = true
+	}
 	return &MockEngine{
-		mode:     Fake,
-		fakeFail: blockNumber,
+		mode:      Fake,
+		fakeFails: fakeFails,
 	}
 }
 
@@ -185,6 +197,18 @@ func NewFullFaker() *MockEngine {
 	}
 }
 
+// WithValidators sets the validators returned by the engine for every block.
+func (e *MockEngine) WithValidators(validators []istanbul.Validator) *MockEngine {
+	e.validators = validators
+	return e
+}
+
+// WithEpochSize sets the epoch size of the engine.
+func (e *MockEngine) WithEpochSize(epochSize uint64) *MockEngine {
+	e.epochSize = epochSize
+	return e
+}
+
 func (e *MockEngine) accumulateRewards(config *params.ChainConfig, state *state.StateDB, header *types.Header) {
 	// Simply touch coinbase account
 	reward := big.NewInt(1)
@@ -260,7 +284,7 @@ func (e *MockEngine) VerifySeal(header *types.Header) error {
 
 func (e *MockEngine) verifySeal(header *types.Header) error {
 	time.Sleep(e.fakeDelay)
-	if e.fakeFail == header.Number.Uint64() {
+	if e.fakeFails[header.Number.Uint64()] {
 		return errFakeFail
 	}
 	return nil
@@ -359,10 +383,13 @@ type fullChain interface {
 	StateAt(common.Hash) (*state.StateDB, error)
 }
 
-func (e *MockEngine) Seal(chain consensus.ChainHeaderReader, block *types.Block) error {
+func (e *MockEngine) Seal(chain consensus.ChainHeaderReader, block *types.Block, stop <-chan struct{}) error {
 	header := block.Header()
 	finalBlock := block.WithHeader(header)
-	c := chain.(fullChain)
+	c, ok := chain.(fullChain)
+	if !ok {
+		return errNoFullChain
+	}
 
 	parent := c.CurrentBlock()
 
@@ -390,9 +417,29 @@ func (e *MockEngine) Close() error {
 	return nil
 }
 
+// Closed implements consensus.Engine, the mock engine is never closed.
+func (e *MockEngine) Closed() bool {
+	return false
+}
+
 // EpochSize size of the epoch
 func (e *MockEngine) EpochSize() uint64 {
-	return 100
+	if e.epochSize == 0 {
+		return defaultEpochSize
+	}
+	return e.epochSize
+}
+
+// GetValidators returns the validators set with WithValidators, whatever the block.
+func (e *MockEngine) GetValidators(blockNumber *big.Int, headerHash common.Hash) []istanbul.Validator {
+	validators := make([]istanbul.Validator, len(e.validators))
+	copy(validators, e.validators)
+	return validators
+}
+
+// GetValidatorBLSKeys returns the BLS public keys of the validators set with WithValidators.
+func (e *MockEngine) GetValidatorBLSKeys(blockNumber *big.Int, headerHash common.Hash) []blscrypto.SerializedPublicKey {
+	return istanbul.MapValidatorsToPublicKeys(e.validators)
 }
 
 // SetCallBacks sets call back functions
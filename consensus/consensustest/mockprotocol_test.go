@@ -0,0 +1,87 @@
+// Copyright 2021 MAP Protocol Authors.
+// This file is part of MAP Protocol.
+
+// MAP Protocol is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// MAP Protocol is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with MAP Protocol.  If not, see <http://www.gnu.org/licenses/>.
+
+package consensustest
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethparams "github.com/ethereum/go-ethereum/params"
+
+	"github.com/mapprotocol/atlas/consensus/istanbul"
+	"github.com/mapprotocol/atlas/consensus/istanbul/validator"
+	"github.com/mapprotocol/atlas/core/chain"
+	"github.com/mapprotocol/atlas/core/rawdb"
+	"github.com/mapprotocol/atlas/core/vm"
+	blscrypto "github.com/mapprotocol/atlas/helper/bls"
+	"github.com/mapprotocol/atlas/params"
+)
+
+func TestFakeFailerInsertChain(t *testing.T) {
+	engine := NewFakeFailer(5, 7)
+	db := rawdb.NewMemoryDatabase()
+	genesis := (&chain.Genesis{BaseFee: big.NewInt(ethparams.InitialBaseFee)}).MustCommit(db)
+	blockchain, err := chain.NewBlockChain(db, nil, params.AllEthashProtocolChanges, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	defer blockchain.Stop()
+	blocks, _ := chain.GenerateChain(params.AllEthashProtocolChanges, genesis, engine, db, 8, func(i int, gen *chain.BlockGen) {})
+
+	if n, err := blockchain.InsertChain(blocks); !errors.Is(err, errFakeFail) || n != 4 {
+		t.Errorf("failing block inserted: index %d, err %v", n, err)
+	}
+	for _, block := range blocks {
+		want := block.NumberU64() == 5 || block.NumberU64() == 7
+		if err := engine.VerifySeal(block.Header()); (err == errFakeFail) != want {
+			t.Errorf("block %d: have err %v, want failure %v", block.NumberU64(), err, want)
+		}
+	}
+	if head := blockchain.CurrentBlock().NumberU64(); head != 4 {
+		t.Errorf("head mismatch: have %d, want 4", head)
+	}
+}
+
+func TestValidatorsAndEpochSize(t *testing.T) {
+	engine := NewFaker()
+	if size := engine.EpochSize(); size != defaultEpochSize {
+		t.Errorf("default epoch size mismatch: have %d, want %d", size, defaultEpochSize)
+	}
+	if validators := engine.GetValidators(big.NewInt(1), common.Hash{}); len(validators) != 0 {
+		t.Errorf("validators of an engine without any: %v", validators)
+	}
+
+	validators := []istanbul.Validator{
+		validator.New(common.BytesToAddress([]byte("validator0")), blscrypto.SerializedPublicKey{1}),
+		validator.New(common.BytesToAddress([]byte("validator1")), blscrypto.SerializedPublicKey{2}),
+	}
+	engine = NewFaker().WithValidators(validators).WithEpochSize(17280)
+	if size := engine.EpochSize(); size != 17280 {
+		t.Errorf("epoch size mismatch: have %d, want 17280", size)
+	}
+	for _, number := range []int64{0, 1, 17281} {
+		have := engine.GetValidators(big.NewInt(number), common.Hash{})
+		if len(have) != len(validators) || have[0].Address() != validators[0].Address() || have[1].Address() != validators[1].Address() {
+			t.Errorf("validators at block %d mismatch: have %v, want %v", number, have, validators)
+		}
+	}
+	if keys := engine.GetValidatorBLSKeys(big.NewInt(1), common.Hash{}); len(keys) != 2 || keys[1] != validators[1].BLSPublicKey() {
+		t.Errorf("BLS keys mismatch: %v", keys)
+	}
+}
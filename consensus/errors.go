@@ -27,6 +27,10 @@ var (
 	// that is known, but the state of which is not available.
 	ErrPrunedAncestor = errors.New("pruned ancestor")
 
+	// ErrStateUnavailable is returned when the state of a block is needed, e.g.
+	// to query a system contract, but it was pruned or isn't downloaded yet.
+	ErrStateUnavailable = errors.New("state not available")
+
 	// ErrFutureBlock is returned when a block's timestamp is in the future according
 	// to the current node.
 	ErrFutureBlock = errors.New("block in the future")
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/p2p/enode"
@@ -30,9 +31,13 @@ import (
 	"github.com/mapprotocol/atlas/consensus/istanbul/backend/internal/replica"
 	"github.com/mapprotocol/atlas/consensus/istanbul/core"
 	"github.com/mapprotocol/atlas/consensus/istanbul/proxy"
+	"github.com/mapprotocol/atlas/consensus/istanbul/uptime"
+	"github.com/mapprotocol/atlas/consensus/istanbul/uptime/store"
 	"github.com/mapprotocol/atlas/consensus/istanbul/validator"
+	"github.com/mapprotocol/atlas/contracts/election"
 	"github.com/mapprotocol/atlas/core/types"
 	blscrypto "github.com/mapprotocol/atlas/helper/bls"
+	"github.com/mapprotocol/atlas/params"
 )
 
 // API is a user facing RPC API to dump Istanbul state
@@ -102,6 +107,27 @@ func (api *API) GetSnapshot(number *rpc.BlockNumber) (*Snapshot, error) {
 	return api.istanbul.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
 }
 
+// getParentHeaderByNumberOrHash retrieves the parent header of the requested
+// block or of the current one if unspecified.
+func (api *API) getParentHeaderByNumberOrHash(blockNrOrHash *rpc.BlockNumberOrHash) (*types.Header, error) {
+	if blockNrOrHash == nil {
+		return api.getParentHeaderByNumber(nil)
+	}
+	if number, ok := blockNrOrHash.Number(); ok {
+		return api.getParentHeaderByNumber(&number)
+	}
+	hash, _ := blockNrOrHash.Hash()
+	header := api.chain.GetHeaderByHash(hash)
+	if header == nil || header.Number.Sign() == 0 {
+		return nil, errUnknownBlock
+	}
+	parent := api.chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	if parent == nil {
+		return nil, errUnknownBlock
+	}
+	return parent, nil
+}
+
 // GetValidators retrieves the list validators that must sign a given block.
 func (api *API) GetValidators(number *rpc.BlockNumber) ([]common.Address, error) {
 	header, err := api.getParentHeaderByNumber(number)
@@ -112,14 +138,66 @@ func (api *API) GetValidators(number *rpc.BlockNumber) ([]common.Address, error)
 	return istanbul.MapValidatorsToAddresses(validators), nil
 }
 
+// ValidatorInfo is a validator as returned by istanbul_getValidatorsInfo.
+type ValidatorInfo struct {
+	Index          int                             `json:"index"`
+	Address        common.Address                  `json:"address"`
+	BLSPublicKey   blscrypto.SerializedPublicKey   `json:"blsPublicKey"`
+	BLSG1PublicKey blscrypto.SerializedG1PublicKey `json:"blsG1PublicKey"`
+}
+
+// GetValidatorsInfo retrieves the validators that must sign a given block, along
+// with their BLS public keys and their index in the validator set. The set is
+// taken from the stored epoch snapshots, so it is available for any block
+// whose header is known, even if its state has been pruned.
+func (api *API) GetValidatorsInfo(blockNrOrHash *rpc.BlockNumberOrHash) ([]ValidatorInfo, error) {
+	header, err := api.getParentHeaderByNumberOrHash(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	validators := api.istanbul.GetValidators(header.Number, header.Hash())
+	infos := make([]ValidatorInfo, len(validators))
+	for i, val := range validators {
+		infos[i] = ValidatorInfo{
+			Index:          i,
+			Address:        val.Address(),
+			BLSPublicKey:   val.BLSPublicKey(),
+			BLSG1PublicKey: val.BLSG1PublicKey(),
+		}
+	}
+	return infos, nil
+}
+
+// GetValidatorsBLSAggregate retrieves the aggregate of the BLS public keys of
+// the validators that must sign a given block, which light clients verify the
+// aggregated seals of epoch blocks against.
+func (api *API) GetValidatorsBLSAggregate(blockNrOrHash *rpc.BlockNumberOrHash) (blscrypto.SerializedPublicKey, error) {
+	header, err := api.getParentHeaderByNumberOrHash(blockNrOrHash)
+	if err != nil {
+		return blscrypto.SerializedPublicKey{}, err
+	}
+	keys := api.istanbul.GetValidatorBLSKeys(header.Number, header.Hash())
+	if len(keys) == 0 {
+		return blscrypto.SerializedPublicKey{}, errors.New("empty validator set")
+	}
+	pks := make([]*blscrypto.PublicKey, len(keys))
+	for i, key := range keys {
+		if pks[i], err = blscrypto.UnmarshalPk(key[:]); err != nil {
+			return blscrypto.SerializedPublicKey{}, fmt.Errorf("invalid BLS public key of validator %d: %v", i, err)
+		}
+	}
+	var aggregate blscrypto.SerializedPublicKey
+	copy(aggregate[:], blscrypto.AggregatePK(pks).Marshal())
+	return aggregate, nil
+}
+
 // GetValidatorsBLSPublicKeys retrieves the list of validators BLS public keys that must sign a given block.
 func (api *API) GetValidatorsBLSPublicKeys(number *rpc.BlockNumber) ([]blscrypto.SerializedPublicKey, error) {
 	header, err := api.getParentHeaderByNumber(number)
 	if err != nil {
 		return nil, err
 	}
-	validators := api.istanbul.GetValidators(header.Number, header.Hash())
-	return istanbul.MapValidatorsToPublicKeys(validators), nil
+	return api.istanbul.GetValidatorBLSKeys(header.Number, header.Hash()), nil
 }
 
 // GetProposer retrieves the proposer for a given block number (i.e. sequence) and round.
@@ -199,6 +277,74 @@ func (api *API) GetCurrentRoundState() (*core.RoundStateSummary, error) {
 	return api.istanbul.core.CurrentRoundState().Summary(), nil
 }
 
+// ConsensusStatus is the consensus state of the node as returned by
+// istanbul_getConsensusStatus. Nodes that aren't validating only know the
+// sequence and proposer for round 0 from their chain head.
+type ConsensusStatus struct {
+	Sequence   *big.Int       `json:"sequence"`
+	Round      *big.Int       `json:"round"`
+	State      string         `json:"state,omitempty"`
+	Proposer   common.Address `json:"proposer"`
+	Address    common.Address `json:"address"`
+	Validating bool           `json:"validating"`
+	Primary    bool           `json:"primary"`
+	Announcing bool           `json:"announcing"`
+
+	Prepares     []common.Address `json:"prepares"`
+	Commits      []common.Address `json:"commits"`
+	LastProposal *time.Time       `json:"lastProposal,omitempty"`
+}
+
+// GetConsensusStatus retrieves the current sequence, round and proposer, along
+// with the validators prepares and commits were received from in this round.
+func (api *API) GetConsensusStatus() (*ConsensusStatus, error) {
+	status := &ConsensusStatus{
+		Address:    api.istanbul.Address(),
+		Primary:    api.istanbul.IsPrimary(),
+		Announcing: api.istanbul.isAnnouncing(),
+	}
+	// Only the summary is taken under the core lock, it takes the round state
+	// lock for reading just long enough to copy it.
+	api.istanbul.coreMu.RLock()
+	if api.istanbul.isCoreStarted() {
+		summary := api.istanbul.core.CurrentRoundState().Summary()
+		status.Validating = true
+		status.Sequence, status.Round, status.State = summary.Sequence, summary.Round, summary.State
+		status.Proposer = summary.Proposer
+		status.Prepares, status.Commits = summary.Prepares, summary.Commits
+		if last := api.istanbul.core.LastProposalTime(); !last.IsZero() {
+			status.LastProposal = &last
+		}
+	}
+	api.istanbul.coreMu.RUnlock()
+
+	if status.Validating {
+		status.Primary = api.istanbul.IsPrimaryForSeq(status.Sequence)
+		return status, nil
+	}
+	head := api.chain.CurrentHeader()
+	if head == nil {
+		return nil, errUnknownBlock
+	}
+	status.Sequence = new(big.Int).Add(head.Number, common.Big1)
+	status.Round = new(big.Int)
+	valSet := api.istanbul.getOrderedValidators(head.Number.Uint64(), head.Hash())
+	if valSet == nil || valSet.Size() == 0 {
+		return status, nil
+	}
+	// The genesis block has no author to rotate from
+	lastProposer := params.ZeroAddress
+	if head.Number.Sign() > 0 {
+		author, err := api.istanbul.Author(head)
+		if err != nil {
+			return nil, err
+		}
+		lastProposer = author
+	}
+	status.Proposer = validator.GetProposerSelector(api.istanbul.config.ProposerPolicy)(valSet, lastProposer, 0).Address()
+	return status, nil
+}
+
 func (api *API) ForceRoundChange() (bool, error) {
 	api.istanbul.coreMu.RLock()
 	defer api.istanbul.coreMu.RUnlock()
@@ -231,6 +377,15 @@ func (api *API) GetProxiesInfo() ([]*proxy.ProxyInfo, error) {
 	}
 }
 
+// GetProxyStatus retrieves the connection state and the last relayed message time of
+// the proxied validator's proxies, and which of them is the active one.
+func (api *API) GetProxyStatus() ([]*proxy.ProxyStatus, error) {
+	if !api.istanbul.IsProxiedValidator() {
+		return nil, proxy.ErrNodeNotProxiedValidator
+	}
+	return api.istanbul.proxiedValidatorEngine.GetProxyStatus()
+}
+
 // ProxiedValidators retrieves all of the proxies connected proxied validators.
 // Note that we plan to support validators per proxy in the future, so this function
 // is plural and returns an array of proxied validators.  This is to prevent
@@ -243,16 +398,6 @@ func (api *API) GetProxiedValidators() ([]*proxy.ProxiedValidatorInfo, error) {
 	}
 }
 
-// StartValidating starts the consensus engine
-func (api *API) StartValidating() error {
-	return api.istanbul.MakePrimary()
-}
-
-// StopValidating stops the consensus engine from participating in consensus
-func (api *API) StopValidating() error {
-	return api.istanbul.MakeReplica()
-}
-
 // StartValidatingAtBlock starts the consensus engine on the given
 // block number.
 func (api *API) StartValidatingAtBlock(blockNumber int64) error {
@@ -272,6 +417,12 @@ func (api *API) IsValidating() bool {
 	return api.istanbul.IsValidating()
 }
 
+// GetEngineStatus retrieves the roles of this node, which parts of the engine
+// are running and the current view.
+func (api *API) GetEngineStatus() istanbul.EngineStatus {
+	return api.istanbul.Status()
+}
+
 // GetCurrentReplicaState retrieves the current replica state
 func (api *API) GetCurrentReplicaState() (*replica.ReplicaStateSummary, error) {
 	if api.istanbul.replicaState != nil {
@@ -280,17 +431,214 @@ func (api *API) GetCurrentReplicaState() (*replica.ReplicaStateSummary, error) {
 	return &replica.ReplicaStateSummary{State: "Not a validator"}, nil
 }
 
-// GetLookbackWindow retrieves the current replica state
+// GetEpochSize retrieves the number of blocks in an epoch
+func (api *API) GetEpochSize() uint64 {
+	return api.istanbul.EpochSize()
+}
+
+// GetLookbackWindow retrieves the lookback window in use at the requested block
+// or current if unspecified.
 func (api *API) GetLookbackWindow(number *rpc.BlockNumber) (uint64, error) {
 	header, err := api.getHeaderByNumber(number)
 	if err != nil {
 		return 0, err
 	}
+	return api.istanbul.LookbackWindowAt(header)
+}
 
-	state, err := api.istanbul.stateAt(header.Hash())
+// GetElectedValidators retrieves the validators the election contract elects
+// at the state of the requested block or current if unspecified.
+func (api *API) GetElectedValidators(number *rpc.BlockNumber) ([]common.Address, error) {
+	header, err := api.getHeaderByNumber(number)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	vmRunner, err := api.istanbul.chain.NewEVMRunnerForBlock(header)
+	if err != nil {
+		return nil, err
 	}
+	return election.GetElectedValidators(vmRunner)
+}
+
+// ElectionSize is the number of validators elected as returned by
+// istanbul_getElectionSize.
+type ElectionSize struct {
+	Min uint64 `json:"min"`
+	Max uint64 `json:"max"`
+}
 
-	return api.istanbul.LookbackWindow(header, state), nil
+// GetElectionSize retrieves the minimum and maximum number of validators
+// elected at the state of the requested block or current if unspecified.
+func (api *API) GetElectionSize(number *rpc.BlockNumber) (*ElectionSize, error) {
+	header, err := api.getHeaderByNumber(number)
+	if err != nil {
+		return nil, err
+	}
+	vmRunner, err := api.istanbul.chain.NewEVMRunnerForBlock(header)
+	if err != nil {
+		return nil, err
+	}
+	minElectable, maxElectable, err := election.GetElectableValidators(vmRunner)
+	if err != nil {
+		return nil, err
+	}
+	return &ElectionSize{Min: minElectable.Uint64(), Max: maxElectable.Uint64()}, nil
+}
+
+// ValidatorUptime is the uptime of a validator as returned by istanbul_getEpochUptime.
+type ValidatorUptime struct {
+	Address         common.Address `json:"address"`
+	UpBlocks        uint64         `json:"upBlocks"`
+	LastSignedBlock uint64         `json:"lastSignedBlock"`
+	Uptime          float64        `json:"uptime"`
+}
+
+// EpochUptime is the uptime of the validators of an epoch as returned by
+// istanbul_getEpochUptime.
+type EpochUptime struct {
+	Epoch       uint64            `json:"epoch"`
+	Window      uptime.Window     `json:"window"`
+	LatestBlock uint64            `json:"latestBlock"`
+	Provisional bool              `json:"provisional"` // the epoch is still in progress
+	Validators  []ValidatorUptime `json:"validators"`
+}
+
+// GetEpochUptime retrieves the number of blocks each validator of the given epoch
+// was up for within the monitoring window, along with its uptime fraction.
+// The uptime of the current epoch is provisional until its last block.
+func (api *API) GetEpochUptime(epoch uint64) (*EpochUptime, error) {
+	head := api.chain.CurrentHeader()
+	if head == nil {
+		return nil, errUnknownBlock
+	}
+	epochSize := api.istanbul.EpochSize()
+	if current := istanbul.GetEpochNumber(head.Number.Uint64(), epochSize); epoch > current {
+		return nil, fmt.Errorf("epoch %d is in the future, current epoch is %d", epoch, current)
+	}
+	firstBlock, err := istanbul.GetEpochFirstBlockNumber(epoch, epochSize)
+	if err != nil {
+		return nil, err
+	}
+	last := head
+	if lastBlock := istanbul.GetEpochLastBlockNumber(epoch, epochSize); lastBlock < head.Number.Uint64() {
+		if last = api.chain.GetHeaderByNumber(lastBlock); last == nil {
+			return nil, errUnknownBlock
+		}
+	}
+	window, err := uptime.ScheduledMonitoringWindow(epoch, epochSize, api.istanbul.LookbackSchedule())
+	if err != nil {
+		return nil, err
+	}
+	accumulated := store.New(api.istanbul.db).ReadAccumulatedEpochUptime(epoch)
+	if accumulated == nil {
+		return nil, fmt.Errorf("no accumulated uptime for epoch %d", epoch)
+	}
+	// The validator set of an epoch is the one elected at the end of the previous one
+	parent := api.chain.GetHeaderByNumber(firstBlock - 1)
+	if parent == nil {
+		return nil, errUnknownBlock
+	}
+	validators := api.istanbul.GetValidators(parent.Number, parent.Hash())
+
+	result := &EpochUptime{
+		Epoch:       epoch,
+		Window:      window,
+		LatestBlock: accumulated.LatestBlock,
+		Provisional: last.Number.Uint64() < istanbul.GetEpochLastBlockNumber(epoch, epochSize),
+		Validators:  make([]ValidatorUptime, 0, len(validators)),
+	}
+	for i, val := range validators {
+		if i >= len(accumulated.Entries) {
+			break
+		}
+		entry := accumulated.Entries[i]
+		result.Validators = append(result.Validators, ValidatorUptime{
+			Address:         val.Address(),
+			UpBlocks:        entry.UpBlocks,
+			LastSignedBlock: entry.LastSignedBlock,
+			Uptime:          float64(entry.UpBlocks) / float64(window.Size()),
+		})
+	}
+	return result, nil
+}
+
+// GetSigners retrieves the validators which signed the requested block or
+// current if unspecified.
+func (api *API) GetSigners(number *rpc.BlockNumber) ([]common.Address, error) {
+	header, err := api.getHeaderByNumber(number)
+	if err != nil {
+		return nil, err
+	}
+	return api.istanbul.SignersOfBlock(header)
+}
+
+// GetEvidence retrieves the evidence of validators signing conflicting proposals
+// or commits recorded for the given epoch. Evidence is only kept for the epochs
+// in which it can still be used for slashing.
+func (api *API) GetEvidence(epoch uint64) ([]*istanbul.DoubleSignEvidence, error) {
+	return api.istanbul.DoubleSignEvidence(epoch)
+}
+
+// stopValidatingTimeout is how long AdminAPI.StopValidating waits for the
+// sequence in progress to be committed before stopping the engine anyway.
+var stopValidatingTimeout = 10 * time.Second
+
+// AdminAPI is an RPC API to start and stop the participation of the node in
+// consensus at runtime. All its methods are idempotent.
+type AdminAPI struct {
+	istanbul *Backend
+}
+
+// StartValidating makes this node a primary validator, starting the consensus
+// engine if it isn't running.
+func (api *AdminAPI) StartValidating() error {
+	if err := api.checkValidator(); err != nil {
+		return err
+	}
+	return api.istanbul.MakePrimary()
+}
+
+// StopValidating makes this node a replica, stopping the consensus engine once
+// the sequence in progress is committed or stopValidatingTimeout has passed.
+func (api *AdminAPI) StopValidating() error {
+	if err := api.checkValidator(); err != nil {
+		return err
+	}
+	api.istanbul.coreMu.RLock()
+	var sequence *big.Int
+	if api.istanbul.isCoreStarted() {
+		if view := api.istanbul.core.CurrentView(); view != nil {
+			sequence = view.Sequence
+		}
+	}
+	api.istanbul.coreMu.RUnlock()
+
+	if sequence != nil && !api.istanbul.waitForBlock(sequence, stopValidatingTimeout) {
+		api.istanbul.logger.Warn("Timed out waiting for sequence to complete, stopping validating", "sequence", sequence)
+	}
+	return api.istanbul.MakeReplica()
+}
+
+// StartAnnouncing starts announcing the enode of this node to the validators.
+func (api *AdminAPI) StartAnnouncing() error {
+	if err := api.istanbul.StartAnnouncing(); err != nil && err != istanbul.ErrStartedAnnounce {
+		return err
+	}
+	return nil
+}
+
+// StopAnnouncing stops announcing the enode of this node to the validators.
+func (api *AdminAPI) StopAnnouncing() error {
+	if err := api.istanbul.StopAnnouncing(); err != nil && err != istanbul.ErrStoppedAnnounce {
+		return err
+	}
+	return nil
+}
+
+// checkValidator returns ErrNoValidatorKey if this node can't validate.
+func (api *AdminAPI) checkValidator() error {
+	if api.istanbul.replicaState == nil || api.istanbul.Address() == (common.Address{}) {
+		return istanbul.ErrNoValidatorKey
+	}
+	return nil
 }
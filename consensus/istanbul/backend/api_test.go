@@ -0,0 +1,282 @@
+package backend
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/mapprotocol/atlas/consensus/istanbul"
+	"github.com/mapprotocol/atlas/consensus/istanbul/uptime"
+	"github.com/mapprotocol/atlas/consensus/istanbul/uptime/store"
+	blscrypto "github.com/mapprotocol/atlas/helper/bls"
+)
+
+func TestAPIGetValidators(t *testing.T) {
+	chain, engine := newBlockChain(4, true)
+	defer chain.Stop()
+
+	api := &API{chain: chain, istanbul: engine}
+	number := rpc.PendingBlockNumber
+	addrs, err := api.GetValidators(&number)
+	if err != nil {
+		t.Fatalf("failed to get validators: %v", err)
+	}
+	genesis := chain.Genesis()
+	validators := engine.GetValidators(genesis.Number(), genesis.Hash())
+	if len(addrs) != len(validators) {
+		t.Fatalf("validator count mismatch: have %d, want %d", len(addrs), len(validators))
+	}
+	for i, val := range validators {
+		if addrs[i] != val.Address() {
+			t.Errorf("validator %d mismatch: have %x, want %x", i, addrs[i], val.Address())
+		}
+	}
+}
+
+func TestAPIGetValidatorsInfo(t *testing.T) {
+	chain, engine := newBlockChain(4, true)
+	defer chain.Stop()
+
+	api := &API{chain: chain, istanbul: engine}
+	number := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
+	infos, err := api.GetValidatorsInfo(&number)
+	if err != nil {
+		t.Fatalf("failed to get validators: %v", err)
+	}
+	genesis := chain.Genesis()
+	validators := engine.GetValidators(genesis.Number(), genesis.Hash())
+	if len(infos) != len(validators) {
+		t.Fatalf("validator count mismatch: have %d, want %d", len(infos), len(validators))
+	}
+	for i, val := range validators {
+		if infos[i].Index != i || infos[i].Address != val.Address() || infos[i].BLSPublicKey != val.BLSPublicKey() || infos[i].BLSG1PublicKey != val.BLSG1PublicKey() {
+			t.Errorf("validator %d mismatch: have %+v, want %s", i, infos[i], val)
+		}
+	}
+	// The keys have to survive a JSON round trip, as RPC clients see them
+	blob, err := json.Marshal(infos)
+	if err != nil {
+		t.Fatalf("failed to encode validators: %v", err)
+	}
+	var decoded []ValidatorInfo
+	if err := json.Unmarshal(blob, &decoded); err != nil {
+		t.Fatalf("failed to decode validators: %v", err)
+	}
+	for i := range infos {
+		if decoded[i] != infos[i] {
+			t.Errorf("validator %d JSON round trip mismatch: have %+v, want %+v", i, decoded[i], infos[i])
+		}
+	}
+	// The genesis block has no parent to take the validators from
+	hash := rpc.BlockNumberOrHashWithHash(genesis.Hash(), false)
+	if _, err := api.GetValidatorsInfo(&hash); err == nil {
+		t.Error("validators returned for the genesis block")
+	}
+}
+
+func TestAPIGetValidatorsBLSAggregate(t *testing.T) {
+	chain, engine := newBlockChain(4, true)
+	defer chain.Stop()
+
+	api := &API{chain: chain, istanbul: engine}
+	number := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
+	aggregate, err := api.GetValidatorsBLSAggregate(&number)
+	if err != nil {
+		t.Fatalf("failed to get aggregate: %v", err)
+	}
+	genesis := chain.Genesis()
+	var pks []*blscrypto.PublicKey
+	for _, key := range engine.GetValidatorBLSKeys(genesis.Number(), genesis.Hash()) {
+		pk, err := blscrypto.UnmarshalPk(key[:])
+		if err != nil {
+			t.Fatalf("invalid key: %v", err)
+		}
+		pks = append(pks, pk)
+	}
+	var want blscrypto.SerializedPublicKey
+	copy(want[:], blscrypto.AggregatePK(pks).Marshal())
+	if aggregate != want {
+		t.Errorf("aggregate mismatch: have %x, want %x", aggregate, want)
+	}
+}
+
+func TestAPIGetEpochUptime(t *testing.T) {
+	genesisCfg, nodeKeys := getGenesisAndKeys(1, true)
+	chain, engine, _ := newBlockChainWithKeys(false, common.Address{}, false, genesisCfg, nodeKeys[0])
+	defer stopEngine(engine)
+	defer chain.Stop()
+
+	api := &API{chain: chain, istanbul: engine}
+	if _, err := api.GetEpochUptime(1); err == nil {
+		t.Fatal("uptime returned for a future epoch")
+	}
+	if _, err := makeBlock(nodeKeys, chain, engine, chain.Genesis()); err != nil {
+		t.Fatalf("failed to make block: %v", err)
+	}
+	if _, err := api.GetEpochUptime(1); err == nil {
+		t.Fatal("uptime returned without accumulated uptime")
+	}
+	store.New(engine.db).WriteAccumulatedEpochUptime(1, &uptime.Uptime{
+		LatestBlock: 1,
+		Entries:     []uptime.UptimeEntry{{UpBlocks: 1, LastSignedBlock: 1}},
+	})
+	result, err := api.GetEpochUptime(1)
+	if err != nil {
+		t.Fatalf("failed to get uptime: %v", err)
+	}
+	if !result.Provisional {
+		t.Error("uptime of the current epoch not provisional")
+	}
+	if len(result.Validators) != 1 {
+		t.Fatalf("validator count mismatch: have %d, want 1", len(result.Validators))
+	}
+	genesis := chain.Genesis()
+	val := result.Validators[0]
+	if want := engine.GetValidators(genesis.Number(), genesis.Hash())[0].Address(); val.Address != want {
+		t.Errorf("address mismatch: have %x, want %x", val.Address, want)
+	}
+	if want := 1 / float64(result.Window.Size()); val.UpBlocks != 1 || val.Uptime != want {
+		t.Errorf("uptime mismatch: have (%d, %v), want (1, %v)", val.UpBlocks, val.Uptime, want)
+	}
+	if _, err := api.GetEpochUptime(2); err == nil {
+		t.Error("uptime returned for a future epoch")
+	}
+}
+
+func TestAPIGetConsensusStatus(t *testing.T) {
+	chain, engine := newBlockChain(1, true)
+	defer chain.Stop()
+
+	api := &API{chain: chain, istanbul: engine}
+	status, err := api.GetConsensusStatus()
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+	if !status.Validating || status.Address != engine.Address() {
+		t.Errorf("validator status mismatch: have (%v, %x), want (true, %x)", status.Validating, status.Address, engine.Address())
+	}
+	if status.Sequence == nil || status.Sequence.Uint64() != 1 {
+		t.Errorf("sequence mismatch: have %v, want 1", status.Sequence)
+	}
+	// A node that isn't validating derives the status from its chain head
+	engine.StopValidating()
+	status, err = api.GetConsensusStatus()
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+	if status.Validating || status.Sequence.Uint64() != 1 || status.Round.Sign() != 0 {
+		t.Errorf("status mismatch: have (%v, %v, %v), want (false, 1, 0)", status.Validating, status.Sequence, status.Round)
+	}
+	if status.Proposer != engine.Address() {
+		t.Errorf("proposer mismatch: have %x, want %x", status.Proposer, engine.Address())
+	}
+	if status.LastProposal != nil {
+		t.Errorf("last proposal reported by stopped node: %v", status.LastProposal)
+	}
+}
+
+func TestAdminAPIValidating(t *testing.T) {
+	chain, engine := newBlockChain(1, true)
+	defer chain.Stop()
+	defer stopEngine(engine)
+
+	api := &AdminAPI{istanbul: engine}
+	status := &API{chain: chain, istanbul: engine}
+
+	// No block is sealed, don't wait for the sequence in progress
+	defer func(timeout time.Duration) { stopValidatingTimeout = timeout }(stopValidatingTimeout)
+	stopValidatingTimeout = 0
+
+	// Starting and stopping twice is no error
+	for i := 0; i < 2; i++ {
+		if err := api.StartValidating(); err != nil {
+			t.Fatalf("failed to start validating: %v", err)
+		}
+	}
+	if s, _ := status.GetConsensusStatus(); !s.Validating || !s.Primary {
+		t.Errorf("started node status mismatch: have (%v, %v), want (true, true)", s.Validating, s.Primary)
+	}
+	for i := 0; i < 2; i++ {
+		if err := api.StopValidating(); err != nil {
+			t.Fatalf("failed to stop validating: %v", err)
+		}
+	}
+	if s, _ := status.GetConsensusStatus(); s.Validating || s.Primary {
+		t.Errorf("stopped node status mismatch: have (%v, %v), want (false, false)", s.Validating, s.Primary)
+	}
+	for i := 0; i < 2; i++ {
+		if err := api.StopAnnouncing(); err != nil {
+			t.Fatalf("failed to stop announcing: %v", err)
+		}
+	}
+	if s, _ := status.GetConsensusStatus(); s.Announcing {
+		t.Error("stopped announcing reported as announcing")
+	}
+	for i := 0; i < 2; i++ {
+		if err := api.StartAnnouncing(); err != nil {
+			t.Fatalf("failed to start announcing: %v", err)
+		}
+	}
+	// A node without a validator key can't validate
+	engine.replicaState = nil
+	if err := api.StartValidating(); err != istanbul.ErrNoValidatorKey {
+		t.Errorf("error mismatch: have %v, want %v", err, istanbul.ErrNoValidatorKey)
+	}
+}
+
+func TestAPIGetEvidence(t *testing.T) {
+	chain, engine := newBlockChain(1, true)
+	defer stopEngine(engine)
+	defer chain.Stop()
+
+	reported := make(chan *istanbul.DoubleSignEvidence, 3)
+	engine.SetDoubleSignCallback(func(evidence *istanbul.DoubleSignEvidence) {
+		reported <- evidence
+	})
+	epochSize := engine.EpochSize()
+	report := func(epoch uint64) *istanbul.DoubleSignEvidence {
+		evidence := &istanbul.DoubleSignEvidence{
+			Validator: engine.Address(),
+			Code:      istanbul.MsgCommit,
+			Sequence:  new(big.Int).SetUint64((epoch-1)*epochSize + 1),
+			Round:     big.NewInt(0),
+			First:     []byte{0x01},
+			Second:    []byte{0x02},
+		}
+		engine.ReportDoubleSign(evidence)
+		return evidence
+	}
+	first := report(1)
+
+	api := &API{chain: chain, istanbul: engine}
+	evidence, err := api.GetEvidence(1)
+	if err != nil {
+		t.Fatalf("failed to get evidence: %v", err)
+	}
+	if len(evidence) != 1 || evidence[0].Hash() != first.Hash() {
+		t.Fatalf("evidence mismatch: have %v, want [%v]", evidence, first)
+	}
+	select {
+	case have := <-reported:
+		if have != first {
+			t.Fatalf("callback evidence mismatch: have %v, want %v", have, first)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("callback not called")
+	}
+	// Evidence outside of the slashable window is pruned
+	report(1 + slashableDoubleSignEpochs)
+	if evidence, _ := api.GetEvidence(1); len(evidence) != 1 {
+		t.Fatalf("evidence within the slashable window pruned")
+	}
+	report(2 + slashableDoubleSignEpochs)
+	if evidence, _ := api.GetEvidence(1); len(evidence) != 0 {
+		t.Fatalf("evidence outside of the slashable window not pruned: %v", evidence)
+	}
+	if evidence, _ := api.GetEvidence(2 + slashableDoubleSignEpochs); len(evidence) != 1 {
+		t.Fatalf("evidence count mismatch: have %d, want 1", len(evidence))
+	}
+}
@@ -113,6 +113,10 @@ func New(config *istanbul.Config, db ethdb.Database) consensus.Istanbul {
 	if err != nil {
 		logger.Crit("Failed to create recent snapshots cache", "err", err)
 	}
+	pendingEpochRewards, err := lru.New(pendingEpochRewardsSize)
+	if err != nil {
+		logger.Crit("Failed to create pending epoch rewards cache", "err", err)
+	}
 
 	coreStarted := atomic.Value{}
 	coreStarted.Store(false)
@@ -122,6 +126,8 @@ func New(config *istanbul.Config, db ethdb.Database) consensus.Istanbul {
 		logger:                             logger,
 		db:                                 db,
 		recentSnapshots:                    recentSnapshots,
+		pendingEpochRewards:                pendingEpochRewards,
+		epochRewards:                       newEpochRewardsHistory(epochRewardsHistorySize),
 		coreStarted:                        coreStarted,
 		announceRunning:                    false,
 		gossipCache:                        NewLRUGossipCache(inmemoryPeers, inmemoryMessages),
@@ -236,6 +242,23 @@ type Backend struct {
 	validateState       func(block *types.Block, statedb *state.StateDB, receipts types.Receipts, usedGas uint64) error
 	onNewConsensusBlock func(block *types.Block, receipts []*types.Receipt, logs []*types.Log, state *state.StateDB)
 
+	// onEpochTransition is called for every epoch transition reached by the chain
+	// head, epochTransitionHead is the last head number transitions were checked up to
+	onEpochTransition   func(epoch uint64, oldSet, newSet []istanbul.Validator)
+	epochTransitionHead uint64
+	epochTransitionMu   sync.Mutex
+
+	// Rewards of finalized epoch blocks by state root, and of the recent
+	// canonical epochs
+	pendingEpochRewards *lru.Cache
+	epochRewards        *epochRewardsHistory
+	epochRewardsFeed    event.Feed
+	epochRewardsScope   event.SubscriptionScope
+
+	// onDoubleSign is called with the evidence of every double signing detected
+	onDoubleSign func(evidence *istanbul.DoubleSignEvidence)
+	doubleSignMu sync.RWMutex
+
 	// We need this to be an atomic value so that we can access it in a lock
 	// free way from IsValidating. This is required because StartValidating
 	// makes a call to RefreshValPeers while holding coreMu and RefreshValPeers
@@ -248,6 +271,13 @@ type Backend struct {
 	coreStarted atomic.Value
 	coreMu      sync.RWMutex
 
+	// closeOnce guards Close so that resources are only released once
+	closeOnce sync.Once
+	closeErr  error
+	closed    uint32 // atomic, non-zero once Close has been called
+
+	lookbackWindowOverride uint64 // atomic, non-zero to bypass the lookback window in state (tests only)
+
 	// Snapshots for recent blocks to speed up reorgs
 	recentSnapshots *lru.ARCCache
 
@@ -345,6 +375,10 @@ type Backend struct {
 	randomSeed   []byte
 	randomSeedMu sync.Mutex
 
+	// Source of the random beacon randomness, nil for the one using randomSeed
+	beacon   consensus.RandomBeacon
+	beaconMu sync.RWMutex
+
 	// Test hooks
 	abortCommitHook func(result *istanbulCore.StateProcessResult) bool // Method to call upon committing a proposal
 }
@@ -399,6 +433,29 @@ func (sb *Backend) IsValidator() bool {
 	return sb.config.Validator
 }
 
+// Status implements consensus.Istanbul.Status
+func (sb *Backend) Status() istanbul.EngineStatus {
+	status := istanbul.EngineStatus{
+		Validator:  sb.IsValidator(),
+		Proxy:      sb.IsProxy(),
+		Proxied:    sb.config.Proxied,
+		Primary:    sb.IsPrimary(),
+		Announcing: sb.isAnnouncing(),
+	}
+
+	sb.proxiedValidatorEngineMu.RLock()
+	status.ProxiedValidatorEngine = sb.proxiedValidatorEngineRunning
+	sb.proxiedValidatorEngineMu.RUnlock()
+
+	sb.coreMu.RLock()
+	if sb.isCoreStarted() {
+		status.Validating = true
+		status.CurrentView = sb.core.CurrentView()
+	}
+	sb.coreMu.RUnlock()
+	return status
+}
+
 // ChainConfig returns the configuration from the embedded blockchain reader.
 func (sb *Backend) ChainConfig() *params.ChainConfig {
 	return sb.chain.Config()
@@ -459,9 +516,24 @@ func (sb *Backend) SelfNode() *enode.Node {
 	return sb.p2pserver.Self()
 }
 
-// Close the backend
+// Close the backend. It is safe to call Close multiple times, later calls
+// return the result of the first one.
 func (sb *Backend) Close() error {
+	sb.closeOnce.Do(func() {
+		atomic.StoreUint32(&sb.closed, 1)
+		sb.closeErr = sb.close()
+	})
+	return sb.closeErr
+}
+
+// Closed reports whether Close has been called on the backend
+func (sb *Backend) Closed() bool {
+	return atomic.LoadUint32(&sb.closed) != 0
+}
+
+func (sb *Backend) close() error {
 	sb.delegateSignScope.Close()
+	sb.epochRewardsScope.Close()
 	var errs []error
 	if err := sb.valEnodeTable.Close(); err != nil {
 		errs = append(errs, err)
@@ -505,7 +577,7 @@ func (sb *Backend) NextBlockValidators(proposal istanbul.Proposal) (istanbul.Val
 	}
 
 	// There was no change
-	if len(istExtra.AddedValidators) == 0 && istExtra.RemovedValidators.BitLen() == 0 {
+	if !istExtra.HasValSetDiff() {
 		return sb.ParentBlockValidators(proposal), nil
 	}
 
@@ -515,7 +587,7 @@ func (sb *Backend) NextBlockValidators(proposal istanbul.Proposal) (istanbul.Val
 	}
 	snap = snap.copy()
 
-	addedValidators, err := istanbul.CombineIstanbulExtraToValidatorData(istExtra.AddedValidators, istExtra.AddedValidatorsPublicKeys, istExtra.AddedValidatorsG1PublicKeys)
+	addedValidators, err := istanbul.AddedValidatorsOfExtra(istExtra, snap.validators())
 	if err != nil {
 		return nil, err
 	}
@@ -535,6 +607,45 @@ func (sb *Backend) GetValidators(blockNumber *big.Int, headerHash common.Hash) [
 	return validatorSet.List()
 }
 
+// GetValidatorBLSKeys implements consensus.Engine.GetValidatorBLSKeys
+func (sb *Backend) GetValidatorBLSKeys(blockNumber *big.Int, headerHash common.Hash) []blscrypto.SerializedPublicKey {
+	return istanbul.MapValidatorsToPublicKeys(sb.GetValidators(blockNumber, headerHash))
+}
+
+// IsValidatorAt implements consensus.Istanbul.IsValidatorAt
+func (sb *Backend) IsValidatorAt(blockNumber *big.Int, headerHash common.Hash, addr common.Address) bool {
+	return sb.getValidators(blockNumber.Uint64(), headerHash).ContainsByAddress(addr)
+}
+
+// IsProposerInTurn implements consensus.Istanbul.IsProposerInTurn
+func (sb *Backend) IsProposerInTurn(header *types.Header) (bool, error) {
+	number := header.Number.Uint64()
+	if number == 0 {
+		return false, errUnknownBlock
+	}
+	parent := sb.chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return false, consensus.ErrUnknownAncestor
+	}
+	author, err := sb.Author(header)
+	if err != nil {
+		return false, err
+	}
+	valSet := sb.getOrderedValidators(parent.Number.Uint64(), parent.Hash())
+	if valSet.Size() == 0 {
+		return false, errUnknownBlock
+	}
+	// The genesis block has no author to rotate from
+	lastProposer := params.ZeroAddress
+	if parent.Number.Sign() > 0 {
+		if lastProposer, err = sb.Author(parent); err != nil {
+			return false, err
+		}
+	}
+	proposer := validator.GetProposerSelector(sb.config.ProposerPolicy)(valSet, lastProposer, 0)
+	return proposer.Address() == author, nil
+}
+
 // Commit implements istanbul.Backend.Commit
 func (sb *Backend) Commit(proposal istanbul.Proposal, aggregatedSeal types.IstanbulAggregatedSeal, aggregatedEpochValidatorSetSeal types.IstanbulEpochValidatorSetSeal, result *istanbulCore.StateProcessResult) error {
 	// Check if the proposal is a valid block
@@ -673,8 +784,8 @@ func (sb *Backend) verifyValSetDiff(proposal istanbul.Proposal, block *types.Blo
 
 	newValSet, err := sb.getNewValidatorSet(block.Header(), state)
 	if err != nil {
-		if len(istExtra.AddedValidators) != 0 || istExtra.RemovedValidators.BitLen() != 0 {
-			sb.logger.Error("verifyValSetDiff - Invalid val set diff.  Non empty diff when it should be empty.", "addedValidators", types.ConvertToStringSlice(istExtra.AddedValidators), "removedValidators", istExtra.RemovedValidators.Text(16))
+		if istExtra.HasValSetDiff() {
+			sb.logger.Error("verifyValSetDiff - Invalid val set diff.  Non empty diff when it should be empty.", "addedValidators", types.ConvertToStringSlice(istExtra.AddedValidators), "compactAddedValidators", len(istExtra.CompactAddedValidators), "removedValidators", istExtra.RemovedValidators.Text(16))
 			return errInvalidValidatorSetDiff
 		}
 	} else {
@@ -691,27 +802,25 @@ func (sb *Backend) verifyValSetDiff(proposal istanbul.Proposal, block *types.Blo
 
 		addedValidators, removedValidators := istanbul.ValidatorSetDiff(oldValSet, newValSet)
 
-		addedValidatorsAddresses := make([]common.Address, 0, len(addedValidators))
-		addedValidatorsPublicKeys := make([]blscrypto.SerializedPublicKey, 0, len(addedValidators))
-		addedValidatorsG1PublicKeys := make([]blscrypto.SerializedG1PublicKey, 0, len(addedValidators))
-		for _, val := range addedValidators {
-			addedValidatorsAddresses = append(addedValidatorsAddresses, val.Address)
-			addedValidatorsPublicKeys = append(addedValidatorsPublicKeys, val.BLSPublicKey)
-			addedValidatorsG1PublicKeys = append(addedValidatorsG1PublicKeys, val.BLSG1PublicKey)
-		}
+		// The diff must be the one written by UpdateValSetDiff, in the encoding
+		// in use at this block
+		expected := new(types.IstanbulExtra)
+		setValidatorSetDiff(expected, oldValSet, addedValidators, removedValidators, sb.config.IsCompactValSetDiff(header.Number))
 
-		if !istanbul.CompareValidatorSlices(addedValidatorsAddresses, istExtra.AddedValidators) || removedValidators.Cmp(istExtra.RemovedValidators) != 0 || !istanbul.CompareValidatorPublicKeySlices(addedValidatorsPublicKeys, istExtra.AddedValidatorsPublicKeys) || !istanbul.CompareValidatorG1PublicKeySlices(addedValidatorsG1PublicKeys, istExtra.AddedValidatorsG1PublicKeys) {
+		if !istanbul.CompareCompactAddedValidators(expected.CompactAddedValidators, istExtra.CompactAddedValidators) || !istanbul.CompareValidatorSlices(expected.AddedValidators, istExtra.AddedValidators) || removedValidators.Cmp(istExtra.RemovedValidators) != 0 || !istanbul.CompareValidatorPublicKeySlices(expected.AddedValidatorsPublicKeys, istExtra.AddedValidatorsPublicKeys) || !istanbul.CompareValidatorG1PublicKeySlices(expected.AddedValidatorsG1PublicKeys, istExtra.AddedValidatorsG1PublicKeys) {
 			sb.logger.Error("verifyValSetDiff - Invalid val set diff. Comparison failed. ",
 
 				"got addedValidators", types.ConvertToStringSlice(istExtra.AddedValidators),
+				"got compactAddedValidators", len(istExtra.CompactAddedValidators),
 				"got removedValidators", istExtra.RemovedValidators.Text(16),
 				"got addedValidatorsPublicKeys", istanbul.ConvertPublicKeysToStringSlice(istExtra.AddedValidatorsPublicKeys),
 				"got addedValidatorsG1PublicKeys", istanbul.ConvertG1PublicKeysToStringSlice(istExtra.AddedValidatorsG1PublicKeys),
 
-				"expected addedValidators", types.ConvertToStringSlice(addedValidatorsAddresses),
+				"expected addedValidators", types.ConvertToStringSlice(expected.AddedValidators),
+				"expected compactAddedValidators", len(expected.CompactAddedValidators),
 				"expected removedValidators", removedValidators.Text(16),
-				"expected addedValidatorsPublicKeys", istanbul.ConvertPublicKeysToStringSlice(addedValidatorsPublicKeys),
-				"expected addedValidatorsG1PublicKeys", istanbul.ConvertG1PublicKeysToStringSlice(addedValidatorsG1PublicKeys))
+				"expected addedValidatorsPublicKeys", istanbul.ConvertPublicKeysToStringSlice(expected.AddedValidatorsPublicKeys),
+				"expected addedValidatorsG1PublicKeys", istanbul.ConvertG1PublicKeysToStringSlice(expected.AddedValidatorsG1PublicKeys))
 			return errInvalidValidatorSetDiff
 		}
 	}
@@ -775,6 +884,41 @@ func (sb *Backend) getValidators(number uint64, hash common.Hash) istanbul.Valid
 	return snap.ValSet
 }
 
+// SignersOfBlock implements consensus.Istanbul.SignersOfBlock
+func (sb *Backend) SignersOfBlock(header *types.Header) ([]common.Address, error) {
+	if header.Number.Sign() == 0 {
+		// The genesis block isn't signed
+		return []common.Address{}, nil
+	}
+	extra, err := types.ExtractIstanbulExtra(header)
+	if err != nil {
+		return nil, err
+	}
+	snap, err := sb.snapshot(sb.chain, header.Number.Uint64()-1, header.ParentHash, nil)
+	if err != nil {
+		return nil, err
+	}
+	return signersOfSeal(snap.ValSet, extra.AggregatedSeal)
+}
+
+// signersOfSeal returns the addresses of the validators in the bitmap of the
+// aggregated seal, ordered by their index in the validator set.
+func signersOfSeal(validators istanbul.ValidatorSet, seal types.IstanbulAggregatedSeal) ([]common.Address, error) {
+	if seal.Bitmap == nil {
+		return nil, errEmptyAggregatedSeal
+	}
+	if size := validators.Size(); seal.Bitmap.BitLen() > size {
+		return nil, fmt.Errorf("%w: bitmap of %d bits for %d validators", errInvalidAggregatedSeal, seal.Bitmap.BitLen(), size)
+	}
+	signers := make([]common.Address, 0, validators.Size())
+	for i, val := range validators.List() {
+		if seal.Bitmap.Bit(i) == 1 {
+			signers = append(signers, val.Address())
+		}
+	}
+	return signers, nil
+}
+
 // validatorRandomnessAtBlockNumber calls into the EVM to get the randomness to use in proposer ordering at a given block.
 func (sb *Backend) validatorRandomnessAtBlockNumber(number uint64, hash common.Hash) (common.Hash, error) {
 	lastBlockInPreviousEpoch := number
@@ -989,11 +1133,10 @@ func (sb *Backend) retrieveUncachedValidatorConnSet() (map[common.Address]bool,
 	validatorsSet := make(map[common.Address]bool)
 
 	currentBlock := sb.currentBlock()
-	currentState, err := sb.stateAt(currentBlock.Hash())
+	vmRunner, err := sb.chain.NewEVMRunnerForBlock(currentBlock.Header())
 	if err != nil {
 		return nil, 0, time.Time{}, err
 	}
-	vmRunner := sb.chain.NewEVMRunner(currentBlock.Header(), currentState)
 	electNValidators, err := election.ElectNValidatorSigners(vmRunner, sb.config.AnnounceAdditionalValidatorsToGossip)
 
 	// The validator contract may not be deployed yet.
@@ -17,6 +17,7 @@
 package backend
 
 import (
+	"errors"
 	"fmt"
 	"math/big"
 	"testing"
@@ -24,9 +25,16 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mapprotocol/atlas/accounts"
+	"github.com/mapprotocol/atlas/consensus"
 	"github.com/mapprotocol/atlas/consensus/istanbul"
+	istanbulCore "github.com/mapprotocol/atlas/consensus/istanbul/core"
+	"github.com/mapprotocol/atlas/consensus/istanbul/uptime"
+	"github.com/mapprotocol/atlas/consensus/istanbul/uptime/store"
 	"github.com/mapprotocol/atlas/core"
 	"github.com/mapprotocol/atlas/core/types"
+	"github.com/mapprotocol/atlas/core/vm"
+	blscrypto "github.com/mapprotocol/atlas/helper/bls"
 )
 
 func TestSign(t *testing.T) {
@@ -172,3 +180,366 @@ func TestGetProposer(t *testing.T) {
 	}
 
 }
+
+func TestIsValidatorAt(t *testing.T) {
+	numValidators := 2
+	genesisCfg, nodeKeys := getGenesisAndKeys(numValidators, true)
+	chain, engine, _ := newBlockChainWithKeys(false, common.Address{}, false, genesisCfg, nodeKeys[0])
+	defer chain.Stop()
+
+	genesis := chain.Genesis()
+	for _, key := range nodeKeys {
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		if !engine.IsValidatorAt(genesis.Number(), genesis.Hash(), addr) {
+			t.Errorf("validator %v not found at genesis", addr.Hex())
+		}
+	}
+	if engine.IsValidatorAt(genesis.Number(), genesis.Hash(), common.HexToAddress("0x1234")) {
+		t.Errorf("non-validator reported as validator")
+	}
+}
+
+func TestGetValidatorBLSKeys(t *testing.T) {
+	chain, engine := newBlockChain(3, true)
+	defer chain.Stop()
+
+	genesis := chain.Genesis()
+	validators := engine.GetValidators(genesis.Number(), genesis.Hash())
+	keys := engine.GetValidatorBLSKeys(genesis.Number(), genesis.Hash())
+	if len(keys) != len(validators) {
+		t.Fatalf("key count mismatch: have %d, want %d", len(keys), len(validators))
+	}
+	for i, val := range validators {
+		if keys[i] != val.BLSPublicKey() {
+			t.Errorf("key %d mismatch: have %x, want %x", i, keys[i], val.BLSPublicKey())
+		}
+	}
+}
+
+func TestSignersOfBlock(t *testing.T) {
+	chain, engine := newBlockChain(3, true)
+	defer chain.Stop()
+
+	genesis := chain.Genesis()
+	validators := engine.GetValidators(genesis.Number(), genesis.Hash())
+	if signers, err := engine.SignersOfBlock(genesis.Header()); err != nil || len(signers) != 0 {
+		t.Fatalf("genesis signers mismatch: have %v, %v", signers, err)
+	}
+
+	signers := func(bitmap int64) ([]common.Address, error) {
+		header := makeBlockWithoutSeal(chain, engine, genesis).Header()
+		seal := types.IstanbulAggregatedSeal{Bitmap: big.NewInt(bitmap), Signature: make([]byte, types.IstanbulExtraBlsSignature), Round: big.NewInt(0)}
+		if err := writeAggregatedSeal(header, seal, false); err != nil {
+			t.Fatalf("failed to write aggregated seal: %v", err)
+		}
+		return engine.SignersOfBlock(header)
+	}
+	have, err := signers(0x5)
+	if err != nil {
+		t.Fatalf("failed to get signers: %v", err)
+	}
+	if len(have) != 2 || have[0] != validators[0].Address() || have[1] != validators[2].Address() {
+		t.Errorf("signers mismatch: have %v, want [%x %x]", have, validators[0].Address(), validators[2].Address())
+	}
+	// The bitmap can't reference validators outside of the set
+	if _, err := signers(0x8); !errors.Is(err, errInvalidAggregatedSeal) {
+		t.Errorf("error mismatch: have %v, want %v", err, errInvalidAggregatedSeal)
+	}
+}
+
+func TestVerifyEpochSnarkData(t *testing.T) {
+	genesisCfg, nodeKeys := getGenesisAndKeys(3, true)
+	chain, engine, _ := newBlockChainWithKeys(false, common.Address{}, false, genesisCfg, nodeKeys[0])
+	defer chain.Stop()
+
+	block := makeBlockWithoutSeal(chain, engine, chain.Genesis())
+	if err := engine.VerifyEpochSnarkData(block.WithEpochSnarkData(&types.EmptyEpochSnarkData)); err != nil {
+		t.Errorf("empty data rejected in block %d: %v", block.NumberU64(), err)
+	}
+	// Only the last block of an epoch carries data
+	data := &types.EpochSnarkData{Bitmap: big.NewInt(0x7), Signature: make([]byte, types.IstanbulExtraBlsSignature)}
+	if err := engine.VerifyEpochSnarkData(block.WithEpochSnarkData(data)); !errors.Is(err, errInvalidEpochSnarkData) {
+		t.Errorf("error mismatch: have %v, want %v", err, errInvalidEpochSnarkData)
+	}
+	header := block.Header()
+	header.Number = new(big.Int).SetUint64(engine.config.Epoch)
+	epochBlock := types.NewBlockWithHeader(header).WithEpochSnarkData(&types.EmptyEpochSnarkData)
+	if err := engine.VerifyEpochSnarkData(epochBlock); err != errEmptyEpochSnarkData {
+		t.Errorf("error mismatch: have %v, want %v", err, errEmptyEpochSnarkData)
+	}
+
+	// Data signed by all the validators over the unchanged set is valid
+	seal := types.IstanbulAggregatedSeal{Bitmap: big.NewInt(0x7), Signature: make([]byte, types.IstanbulExtraBlsSignature), Round: big.NewInt(0)}
+	if err := writeAggregatedSeal(header, seal, false); err != nil {
+		t.Fatalf("failed to write aggregated seal: %v", err)
+	}
+	epochBlock = types.NewBlockWithHeader(header)
+	valSet := engine.getValidators(0, chain.Genesis().Hash())
+	message, extraData, err := istanbulCore.EncodeEpochSnarkData(header.Number.Uint64(), engine.config.Epoch, 0, epochBlock.Hash(), chain.Genesis().Hash(), valSet)
+	if err != nil {
+		t.Fatalf("failed to encode epoch data: %v", err)
+	}
+	var signatures [][]byte
+	for _, key := range nodeKeys {
+		signature, err := SignBLSFn(key)(accounts.Account{}, message, extraData, true, true)
+		if err != nil {
+			t.Fatalf("failed to sign epoch data: %v", err)
+		}
+		signatures = append(signatures, signature[:])
+	}
+	aggregated, err := blscrypto.CryptoType().AggregateSignatures(signatures)
+	if err != nil {
+		t.Fatalf("failed to aggregate signatures: %v", err)
+	}
+	data = &types.EpochSnarkData{Bitmap: big.NewInt(0x7), Signature: aggregated}
+	if err := engine.VerifyEpochSnarkData(epochBlock.WithEpochSnarkData(data)); err != nil {
+		t.Errorf("valid data rejected: %v", err)
+	}
+	// The signature doesn't cover a set with a validator missing from the bitmap
+	data = &types.EpochSnarkData{Bitmap: big.NewInt(0x3), Signature: aggregated}
+	if err := engine.VerifyEpochSnarkData(epochBlock.WithEpochSnarkData(data)); !errors.Is(err, errInvalidEpochSnarkData) {
+		t.Errorf("error mismatch: have %v, want %v", err, errInvalidEpochSnarkData)
+	}
+}
+
+func TestEpochSignatureStats(t *testing.T) {
+	chain, engine := newBlockChain(3, true)
+	defer chain.Stop()
+
+	if _, err := engine.EpochSignatureStats(1); err == nil {
+		t.Fatal("stats returned without accumulated uptime")
+	}
+	genesis := chain.Genesis()
+	validators := engine.GetValidators(genesis.Number(), genesis.Hash())
+	accumulated := &uptime.Uptime{LatestBlock: 4}
+	for i := range validators {
+		accumulated.Entries = append(accumulated.Entries, uptime.UptimeEntry{UpBlocks: uint64(i + 1), LastSignedBlock: 3})
+	}
+	store.New(engine.db).WriteAccumulatedEpochUptime(1, accumulated)
+
+	stats, err := engine.EpochSignatureStats(1)
+	if err != nil {
+		t.Fatalf("failed to get stats: %v", err)
+	}
+	if len(stats) != len(validators) {
+		t.Fatalf("stats count mismatch: have %d, want %d", len(stats), len(validators))
+	}
+	for i, val := range validators {
+		if have, want := stats[val.Address()], uint64(i+1); have != want {
+			t.Errorf("validator %d: up blocks mismatch: have %d, want %d", i, have, want)
+		}
+	}
+	if _, err := engine.EpochSignatureStats(0); err == nil {
+		t.Error("stats returned for epoch 0")
+	}
+}
+
+func TestLookbackWindowOverride(t *testing.T) {
+	chain, engine := newBlockChain(1, true)
+	defer chain.Stop()
+
+	header := chain.CurrentHeader()
+	state, err := chain.StateAt(header.Root)
+	if err != nil {
+		t.Fatalf("failed to get state: %v", err)
+	}
+	window := engine.LookbackWindow(header, state)
+
+	// The overridden window doesn't need any state
+	engine.setLookbackWindowOverride(window + 1)
+	if have := engine.LookbackWindow(header, nil); have != window+1 {
+		t.Errorf("overridden window mismatch: have %d, want %d", have, window+1)
+	}
+	engine.setLookbackWindowOverride(0)
+	if have := engine.LookbackWindow(header, state); have != window {
+		t.Errorf("window mismatch after removing override: have %d, want %d", have, window)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	chain, engine := newBlockChain(1, true)
+	defer chain.Stop()
+
+	status := engine.Status()
+	if !status.Validator || !status.Validating || !status.Announcing {
+		t.Errorf("status mismatch: have %+v, want validating and announcing validator", status)
+	}
+	if status.Proxy || status.Proxied || status.ProxiedValidatorEngine {
+		t.Errorf("status mismatch: have %+v, want no proxy", status)
+	}
+	if status.CurrentView == nil || status.CurrentView.Sequence.Sign() <= 0 {
+		t.Errorf("current view mismatch: have %v, want a sequence past genesis", status.CurrentView)
+	}
+
+	if err := engine.StopValidating(); err != nil {
+		t.Fatalf("failed to stop validating: %v", err)
+	}
+	if status := engine.Status(); status.Validating || status.CurrentView != nil {
+		t.Errorf("status mismatch after stopping: have %+v, want not validating", status)
+	}
+}
+
+func TestNewEVMRunnerForBlock(t *testing.T) {
+	chain, engine := newBlockChain(1, true)
+	defer chain.Stop()
+
+	header := chain.CurrentHeader()
+	vmRunner, err := chain.NewEVMRunnerForBlock(header)
+	if err != nil {
+		t.Fatalf("failed to create runner: %v", err)
+	}
+	if _, err := vmRunner.Execute(common.Address{}, nil, 0, common.Big0); !errors.Is(err, vm.ErrWriteProtection) {
+		t.Errorf("execute error mismatch: have %v, want %v", err, vm.ErrWriteProtection)
+	}
+	state, err := chain.StateAt(header.Root)
+	if err != nil {
+		t.Fatalf("failed to get state: %v", err)
+	}
+	window, err := engine.LookbackWindowAt(header)
+	if err != nil {
+		t.Fatalf("failed to get lookback window: %v", err)
+	}
+	if want := engine.LookbackWindow(header, state); window != want {
+		t.Errorf("lookback window mismatch: have %d, want %d", window, want)
+	}
+
+	// A header whose state isn't available
+	pruned := types.CopyHeader(header)
+	pruned.Root = common.HexToHash("0x01")
+	if _, err := chain.NewEVMRunnerForBlock(pruned); !errors.Is(err, consensus.ErrStateUnavailable) {
+		t.Errorf("error mismatch: have %v, want %v", err, consensus.ErrStateUnavailable)
+	}
+}
+
+func TestEpochTransitionCallback(t *testing.T) {
+	chain, engine := newBlockChain(3, true)
+	defer chain.Stop()
+
+	genesis := chain.Genesis()
+	validators := engine.GetValidators(genesis.Number(), genesis.Hash())
+
+	var (
+		epochs  []uint64
+		oldSets [][]istanbul.Validator
+	)
+	callback := func(epoch uint64, oldSet, newSet []istanbul.Validator) {
+		epochs = append(epochs, epoch)
+		oldSets = append(oldSets, oldSet)
+	}
+	defer func(epoch uint64) { engine.config.Epoch = epoch }(engine.config.Epoch)
+
+	// Block 1 doesn't end an epoch of two blocks
+	engine.SetEpochTransitionCallback(callback)
+	engine.config.Epoch = 2
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1), ParentHash: genesis.Hash()})
+	engine.notifyEpochTransitions(block)
+	if len(epochs) != 0 {
+		t.Fatalf("transition reported within an epoch: %v", epochs)
+	}
+	// With single block epochs, block 1 ends epoch 1. Registering the callback
+	// again resets the last seen head.
+	engine.SetEpochTransitionCallback(callback)
+	engine.config.Epoch = 1
+	engine.notifyEpochTransitions(block)
+	if len(epochs) != 1 || epochs[0] != 1 {
+		t.Fatalf("transition epochs mismatch: have %v, want [1]", epochs)
+	}
+	if len(oldSets[0]) != len(validators) {
+		t.Fatalf("old validator set size mismatch: have %d, want %d", len(oldSets[0]), len(validators))
+	}
+	for i, val := range validators {
+		if oldSets[0][i].Address() != val.Address() {
+			t.Errorf("old validator %d mismatch: have %x, want %x", i, oldSets[0][i].Address(), val.Address())
+		}
+	}
+}
+
+func TestEpochRewards(t *testing.T) {
+	chain, engine := newBlockChainWithEpoch(1, istanbul.MinEpochSize)
+	defer chain.Stop()
+
+	rewards := make(chan istanbul.EpochRewardsProcessed, 1)
+	sub := engine.SubscribeEpochRewards(rewards)
+	defer sub.Unsubscribe()
+
+	genesis := chain.Genesis()
+	header := func(number int64, root common.Hash) *types.Header {
+		return &types.Header{Number: big.NewInt(number), ParentHash: genesis.Hash(), Root: root}
+	}
+	// Rewards of blocks which were not finalized by the node aren't published
+	engine.notifyEpochTransitions(types.NewBlockWithHeader(header(istanbul.MinEpochSize, common.Hash{1})))
+	if len(rewards) != 0 || engine.EpochRewards(1) != nil {
+		t.Fatalf("rewards published for an unknown epoch block")
+	}
+
+	// Rewards staged for the canonical epoch block are published with its hash
+	staged := &istanbul.EpochRewardsProcessed{Epoch: 2, BlockNumber: 2 * istanbul.MinEpochSize, TotalVoterRewards: big.NewInt(10), CommunityFund: big.NewInt(1)}
+	engine.stageEpochRewards(common.Hash{2}, staged)
+	block := types.NewBlockWithHeader(header(2*istanbul.MinEpochSize, common.Hash{2}))
+	engine.notifyEpochTransitions(block)
+	select {
+	case ev := <-rewards:
+		if ev.Epoch != 2 || ev.BlockHash != block.Hash() || ev.TotalVoterRewards.Cmp(staged.TotalVoterRewards) != 0 {
+			t.Fatalf("published rewards mismatch: %+v", ev)
+		}
+	default:
+		t.Fatalf("rewards not published")
+	}
+	if have := engine.EpochRewards(2); have == nil || have.BlockHash != block.Hash() {
+		t.Fatalf("kept rewards mismatch: %+v", have)
+	}
+
+	// The history only keeps the rewards of the most recent epochs
+	history := newEpochRewardsHistory(3)
+	for epoch := uint64(1); epoch <= 5; epoch++ {
+		history.add(&istanbul.EpochRewardsProcessed{Epoch: epoch})
+	}
+	history.add(&istanbul.EpochRewardsProcessed{Epoch: 4, BlockNumber: 40})
+	if history.get(2) != nil || history.get(4).BlockNumber != 40 {
+		t.Fatalf("history entries mismatch")
+	}
+	list := history.list()
+	if len(list) != 3 || list[0].Epoch != 3 || list[1].Epoch != 4 || list[2].Epoch != 5 {
+		t.Fatalf("history list mismatch: %v", list)
+	}
+}
+
+func TestCloseTwice(t *testing.T) {
+	chain, engine := newBlockChain(1, true)
+	defer chain.Stop()
+
+	if engine.Closed() {
+		t.Fatal("engine reported closed before Close")
+	}
+	if err := engine.Close(); err != nil {
+		t.Fatalf("first Close failed: %v", err)
+	}
+	if err := engine.Close(); err != nil {
+		t.Fatalf("second Close failed: %v", err)
+	}
+	if !engine.Closed() {
+		t.Error("engine not reported closed after Close")
+	}
+}
+
+func TestIsProposerInTurn(t *testing.T) {
+	numValidators := 1
+	genesisCfg, nodeKeys := getGenesisAndKeys(numValidators, true)
+	chain, engine, _ := newBlockChainWithKeys(false, common.Address{}, false, genesisCfg, nodeKeys[0])
+	defer chain.Stop()
+
+	block, err := makeBlock(nodeKeys, chain, engine, chain.Genesis())
+	if err != nil {
+		t.Fatalf("Failed to make a block: %v", err)
+	}
+	inTurn, err := engine.IsProposerInTurn(block.Header())
+	if err != nil {
+		t.Fatalf("Failed to check the proposer: %v", err)
+	}
+	if !inTurn {
+		t.Errorf("sole validator not in turn")
+	}
+	if _, err := engine.IsProposerInTurn(chain.Genesis().Header()); err == nil {
+		t.Errorf("genesis block accepted")
+	}
+}
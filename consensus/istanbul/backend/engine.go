@@ -29,20 +29,26 @@ import (
 	"github.com/mapprotocol/atlas/consensus/istanbul"
 	istanbulCore "github.com/mapprotocol/atlas/consensus/istanbul/core"
 	"github.com/mapprotocol/atlas/consensus/istanbul/uptime"
+	"github.com/mapprotocol/atlas/consensus/istanbul/uptime/store"
 	"github.com/mapprotocol/atlas/consensus/istanbul/validator"
 	"github.com/mapprotocol/atlas/contracts/blockchain_parameters"
 	ethCore "github.com/mapprotocol/atlas/core"
 	ethChain "github.com/mapprotocol/atlas/core/chain"
+	"github.com/mapprotocol/atlas/core/rawdb"
 	"github.com/mapprotocol/atlas/core/state"
 	"github.com/mapprotocol/atlas/core/types"
+	"github.com/mapprotocol/atlas/core/vm"
 	blscrypto "github.com/mapprotocol/atlas/helper/bls"
 	"golang.org/x/crypto/sha3"
 	"math/big"
+	"runtime"
+	"sync/atomic"
 	"time"
 )
 
 const (
-	inmemorySnapshots             = 128 // Number of recent vote snapshots to keep in memory
+	inmemorySnapshots             = 128  // Number of recent vote snapshots to keep in memory
+	persistedSnapshots            = 1024 // Number of recent epoch snapshots to keep on disk
 	inmemoryPeers                 = 40
 	inmemoryMessages              = 1024
 	mobileAllowedClockSkew uint64 = 5
@@ -84,6 +90,18 @@ var (
 	errUnauthorizedAnnounceMessage = errors.New("unauthorized announce message")
 	// errNotAValidator is returned when the node is not configured as a validator
 	errNotAValidator = errors.New("Not configured as a validator")
+	// errInvalidRandomness is returned when revealed randomness doesn't match the
+	// commitment made for it.
+	errInvalidRandomness = errors.New("revealed randomness does not match commitment")
+	// errInvalidEpochSnarkData is returned if the epoch SNARK data of a block is
+	// present outside of the last block of an epoch or isn't correctly signed.
+	errInvalidEpochSnarkData = errors.New("invalid epoch snark data")
+	// errEmptyEpochSnarkData is returned if the last block of an epoch is missing
+	// its epoch SNARK data.
+	errEmptyEpochSnarkData = errors.New("empty epoch snark data")
+	// errExtraDataTooLarge is returned if the extra-data of a header is larger
+	// than the configured limit.
+	errExtraDataTooLarge = errors.New("header extra-data too large")
 )
 
 var (
@@ -127,10 +145,19 @@ func (sb *Backend) verifyHeader(chain consensus.ChainHeaderReader, header *types
 		return consensus.ErrFutureBlock
 	}
 
+	// The size limit is a consensus rule introduced with the compact validator
+	// set diffs, older headers are not held to it
+	if limit := sb.config.MaxHeaderExtraSize; limit != 0 && sb.config.IsCompactValSetDiff(header.Number) && uint64(len(header.Extra)) > limit {
+		return fmt.Errorf("%w: %d bytes, limit %d", errExtraDataTooLarge, len(header.Extra), limit)
+	}
 	// Ensure that the extra data format is satisfied
-	if _, err := types.ExtractIstanbulExtra(header); err != nil {
+	extra, err := types.ExtractIstanbulExtra(header)
+	if err != nil {
 		return errInvalidExtraDataFormat
 	}
+	if len(extra.CompactAddedValidators) > 0 && !sb.config.IsCompactValSetDiff(header.Number) {
+		return errInvalidValidatorSetDiff
+	}
 
 	return sb.verifyCascadingFields(chain, header, parents)
 }
@@ -199,28 +226,77 @@ func (sb *Backend) verifyCascadingFields(chain consensus.ChainHeaderReader, head
 func (sb *Backend) VerifyHeaders(chain consensus.ChainHeaderReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
 	abort := make(chan struct{})
 	results := make(chan error, len(headers))
-	go func() {
-		errored := false
-		for i, header := range headers {
-			var err error
-			if errored {
-				err = consensus.ErrUnknownAncestor
-			} else {
-				err = sb.verifyHeader(chain, header, headers[:i])
-			}
+	verify := func(index int) error {
+		return sb.verifyHeader(chain, headers[index], headers[:index])
+	}
+	go verifyHeadersConcurrently(len(headers), verify, abort, results)
+	return abort, results
+}
 
-			if err != nil {
-				errored = true
+// verifyHeadersConcurrently runs verify for the indexes of a batch of n headers
+// on a pool of GOMAXPROCS workers and sends the errors to results in the order
+// of the indexes. Once a header fails with an error other than being from the
+// future, no later header is dispatched and all of them fail as having an
+// unknown ancestor. Receiving from abort stops the dispatching, the workers
+// return after verifying the header they are at.
+func verifyHeadersConcurrently(n int, verify func(index int) error, abort <-chan struct{}, results chan<- error) {
+	workers := runtime.GOMAXPROCS(0)
+	if workers > n {
+		workers = n
+	}
+	type result struct {
+		index int
+		err   error
+	}
+	var (
+		inputs  = make(chan int)
+		outputs = make(chan result, n)
+		errs    = make([]error, n)
+		done    = make([]bool, n)
+	)
+	defer close(inputs)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for index := range inputs {
+				outputs <- result{index, verify(index)}
 			}
+		}()
+	}
 
-			select {
-			case <-abort:
-				return
-			case results <- err:
+	var (
+		next   int // index of the next header to dispatch
+		out    int // index of the next result to send
+		failed = n // index of the first header failing with a hard error
+	)
+	for out < n {
+		select {
+		case <-abort:
+			return
+		default:
+		}
+		var in chan int
+		if next < n && next < failed {
+			in = inputs
+		}
+		select {
+		case <-abort:
+			return
+		case in <- next:
+			next++
+		case res := <-outputs:
+			errs[res.index], done[res.index] = res.err, true
+			if res.err != nil && res.err != consensus.ErrFutureBlock && res.index < failed {
+				failed = res.index
+			}
+			for ; out < n && (done[out] || out > failed); out++ {
+				if out > failed {
+					results <- consensus.ErrUnknownAncestor
+				} else {
+					results <- errs[out]
+				}
 			}
 		}
-	}()
-	return abort, results
+	}
 }
 
 // verifySigner checks whether the signer is in parent's validator set
@@ -314,7 +390,12 @@ func (sb *Backend) verifyAggregatedSeals(chain consensus.ChainHeaderReader, head
 }
 
 func (sb *Backend) verifyAggregatedSeal(headerHash common.Hash, validators istanbul.ValidatorSet, aggregatedSeal types.IstanbulAggregatedSeal) error {
-	logger := sb.logger.New("func", "Backend.verifyAggregatedSeal()")
+	return checkAggregatedSeal(sb.logger.New("func", "Backend.verifyAggregatedSeal()"), headerHash, validators, aggregatedSeal)
+}
+
+// checkAggregatedSeal checks that the aggregated seal is signed on the header
+// hash by a quorum of the validators.
+func checkAggregatedSeal(logger log.Logger, headerHash common.Hash, validators istanbul.ValidatorSet, aggregatedSeal types.IstanbulAggregatedSeal) error {
 	if len(aggregatedSeal.Signature) != types.IstanbulExtraBlsSignature {
 		return errInvalidAggregatedSeal
 	}
@@ -342,6 +423,43 @@ func (sb *Backend) verifyAggregatedSeal(headerHash common.Hash, validators istan
 	return nil
 }
 
+// ValidateHeaderExtra checks the istanbul extra-data of a header without an
+// engine or chain: the extra must decode, the aggregated seal must be signed by
+// a quorum of parentValidators, the validator set of the parent block, and the
+// validator set diff must be well formed against it.
+func ValidateHeaderExtra(header *types.Header, parentValidators []istanbul.Validator) error {
+	if header.Number == nil || header.Number.Sign() <= 0 {
+		return errUnknownBlock
+	}
+	extra, err := types.ExtractIstanbulExtra(header)
+	if err != nil {
+		return errInvalidExtraDataFormat
+	}
+	if len(extra.AggregatedSeal.Signature) == 0 {
+		return errEmptyAggregatedSeal
+	}
+
+	// The diff removes validators by their index in the parent set and adds
+	// validators with both of their BLS keys
+	parentValidatorData := istanbul.MapValidatorsToData(parentValidators)
+	added, err := istanbul.AddedValidatorsOfExtra(extra, parentValidatorData)
+	if err != nil {
+		return errInvalidValidatorSetDiff
+	}
+	if extra.RemovedValidators == nil || extra.RemovedValidators.Sign() < 0 || extra.RemovedValidators.BitLen() > len(parentValidators) {
+		return errInvalidValidatorSetDiff
+	}
+
+	validators := validator.NewSet(parentValidatorData)
+	if err := checkAggregatedSeal(log.New("func", "ValidateHeaderExtra"), header.Hash(), validators, extra.AggregatedSeal); err != nil {
+		return err
+	}
+	if !validators.RemoveValidators(extra.RemovedValidators) || !validators.AddValidators(added) {
+		return errInvalidValidatorSetDiff
+	}
+	return nil
+}
+
 // VerifySeal checks whether the crypto seal on a header is valid according to
 // the consensus rules of the given engine.
 func (sb *Backend) VerifySeal(header *types.Header) error {
@@ -419,11 +537,11 @@ func (sb *Backend) UpdateValSetDiff(chain consensus.ChainHeaderReader, header *t
 			}
 
 			// add validators in snapshot to extraData's validators section
-			return writeValidatorSetDiff(header, snap.validators(), newValSet)
+			return writeValidatorSetDiff(header, snap.validators(), newValSet, sb.config.IsCompactValSetDiff(header.Number))
 		}
 	}
 	// If it's not the last block or we were unable to pull the new validator set, then the validator set diff should be empty
-	return writeValidatorSetDiff(header, []istanbul.ValidatorData{}, []istanbul.ValidatorData{})
+	return writeValidatorSetDiff(header, []istanbul.ValidatorData{}, []istanbul.ValidatorData{}, false)
 }
 
 // IsLastBlockOfEpoch returns whether or not a particular header represents the last block in the epoch.
@@ -437,18 +555,80 @@ func (sb *Backend) EpochSize() uint64 {
 }
 
 // LookbackWindow returns the size of the lookback window for calculating uptime (in blocks)
-// Value is constant during an epoch
+// in use at the given block.
 func (sb *Backend) LookbackWindow(header *types.Header, state *state.StateDB) uint64 {
-	// Check if donut was already active at the beginning of the epoch
-	// as we want to activate the change at epoch change
+	window, _ := sb.lookbackWindow(header, func() (vm.EVMRunner, error) {
+		return sb.chain.NewEVMRunner(header, state), nil
+	})
+	return window
+}
 
-	vmRunner := sb.chain.NewEVMRunner(header, state)
-	return uptime.ComputeLookbackWindow(
+// LookbackWindowAt returns the size of the lookback window in use at the given,
+// possibly historical, block, reading it from the state of the block. An error
+// is returned if the state is needed but was pruned.
+func (sb *Backend) LookbackWindowAt(header *types.Header) (uint64, error) {
+	return sb.lookbackWindow(header, func() (vm.EVMRunner, error) {
+		return sb.chain.NewEVMRunnerForBlock(header)
+	})
+}
+
+// lookbackWindow computes the lookback window at header, only creating the
+// runner if the window is read from the blockchain parameters contract.
+func (sb *Backend) lookbackWindow(header *types.Header, newRunner func() (vm.EVMRunner, error)) (uint64, error) {
+	if window := atomic.LoadUint64(&sb.lookbackWindowOverride); window != 0 {
+		return window, nil
+	}
+
+	var runnerErr error
+	window := uptime.ComputeLookbackWindow(
 		sb.config.Epoch,
-		sb.config.DefaultLookbackWindow,
+		sb.LookbackSchedule().At(header.Number.Uint64()),
 		false,
-		func() (uint64, error) { return blockchain_parameters.GetLookbackWindow(vmRunner) },
+		func() (uint64, error) {
+			vmRunner, err := newRunner()
+			if err != nil {
+				runnerErr = err
+				return 0, err
+			}
+			return blockchain_parameters.GetLookbackWindow(vmRunner)
+		},
 	)
+	return window, runnerErr
+}
+
+// LookbackSchedule returns the lookback windows of the chain config along with
+// their activation blocks.
+func (sb *Backend) LookbackSchedule() uptime.LookbackSchedule {
+	if window := atomic.LoadUint64(&sb.lookbackWindowOverride); window != 0 {
+		return uptime.LookbackSchedule{Base: window}
+	}
+	return uptime.LookbackSchedule{Base: sb.config.DefaultLookbackWindow, Changes: sb.config.LookbackWindowChanges}
+}
+
+// EpochSignatureStats implements consensus.Istanbul.EpochSignatureStats
+func (sb *Backend) EpochSignatureStats(epoch uint64) (map[common.Address]uint64, error) {
+	firstBlock, err := istanbul.GetEpochFirstBlockNumber(epoch, sb.EpochSize())
+	if err != nil {
+		return nil, err
+	}
+	// The validator set of an epoch is the one elected at the end of the previous one
+	header := sb.chain.GetHeaderByNumber(firstBlock - 1)
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	accumulated := store.New(sb.db).ReadAccumulatedEpochUptime(epoch)
+	if accumulated == nil {
+		return nil, fmt.Errorf("no accumulated uptime for epoch %d", epoch)
+	}
+	validators := sb.GetValidators(header.Number, header.Hash())
+	stats := make(map[common.Address]uint64, len(validators))
+	for i, val := range validators {
+		if i >= len(accumulated.Entries) {
+			break
+		}
+		stats[val.Address()] = accumulated.Entries[i].UpBlocks
+	}
+	return stats, nil
 }
 
 // Finalize runs any post-transaction state modifications (e.g. block rewards)
@@ -480,10 +660,11 @@ func (sb *Backend) Finalize(chain consensus.ChainHeaderReader, header *types.Hea
 	// Trigger an update to the gas price minimum in the GasPriceMinimum contract based on block congestion
 	snapshot = state.Snapshot()
 
+	var rewards *istanbul.EpochRewardsProcessed
 	lastBlockOfEpoch := istanbul.IsLastBlockOfEpoch(header.Number.Uint64(), sb.config.Epoch)
 	if lastBlockOfEpoch {
 		snapshot = state.Snapshot()
-		err = sb.distributeEpochRewards(header, state)
+		rewards, err = sb.distributeEpochRewards(header, state)
 		if err != nil {
 			sb.logger.Error("Failed to distribute epoch rewards", "blockNumber", header.Number, "err", err)
 			state.RevertToSnapshot(snapshot)
@@ -491,6 +672,9 @@ func (sb *Backend) Finalize(chain consensus.ChainHeaderReader, header *types.Hea
 	}
 
 	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
+	if rewards != nil {
+		sb.stageEpochRewards(header.Root, rewards)
+	}
 	logger.Info("Finalized", "duration", now().Sub(start), "lastInEpoch", lastBlockOfEpoch)
 }
 
@@ -526,8 +710,9 @@ func (sb *Backend) checkIsValidSigner(chain consensus.ChainHeaderReader, header
 }
 
 // Seal generates a new block for the given input block with the local miner's
-// seal place on top and submits it the the consensus engine.
-func (sb *Backend) Seal(chain consensus.ChainHeaderReader, block *types.Block) error {
+// seal place on top and submits it the the consensus engine. If stop is closed
+// before the core handled the block, the core drops the request.
+func (sb *Backend) Seal(chain consensus.ChainHeaderReader, block *types.Block, stop <-chan struct{}) error {
 	header := block.Header()
 
 	// Bail out if we're unauthorized to sign a block
@@ -539,18 +724,33 @@ func (sb *Backend) Seal(chain consensus.ChainHeaderReader, block *types.Block) e
 		return consensus.ErrUnknownAncestor
 	}
 
+	select {
+	case <-stop:
+		return nil
+	default:
+	}
+
 	// update the block header timestamp and signature and propose the block to core engine
 	block, err := sb.signBlock(block)
 	if err != nil {
 		return err
 	}
 
-	// post block into Istanbul engine
-	if err := sb.EventMux().Post(istanbul.RequestEvent{Proposal: block}); err != nil {
+	// post block into Istanbul engine. Post blocks while the core is busy, so
+	// do it in the background and stop waiting once the request is aborted.
+	// The core keeps reading events and drops the request once stop is closed,
+	// so the post returns without the stale block being proposed.
+	errc := make(chan error, 1)
+	go func() {
+		errc <- sb.EventMux().Post(istanbul.RequestEvent{Proposal: block, Stop: stop})
+	}()
+	select {
+	case err := <-errc:
 		return err
+	case <-stop:
+		sb.logger.Debug("Seal aborted", "number", header.Number, "hash", block.Hash())
+		return nil
 	}
-
-	return nil
 }
 
 // signBlock signs block with a seal
@@ -577,6 +777,11 @@ func (sb *Backend) APIs(chain consensus.ChainHeaderReader) []rpc.API {
 		Version:   "1.0",
 		Service:   &API{chain: chain, istanbul: sb},
 		Public:    true,
+	}, {
+		Namespace: "istanbul",
+		Version:   "1.0",
+		Service:   &AdminAPI{istanbul: sb},
+		Public:    false,
 	}}
 }
 
@@ -651,6 +856,15 @@ func (sb *Backend) SetCallBacks(hasBadBlock func(common.Hash) bool,
 	return nil
 }
 
+// SetEpochTransitionCallback implements consensus.Istanbul.SetEpochTransitionCallback
+func (sb *Backend) SetEpochTransitionCallback(onEpochTransition func(epoch uint64, oldSet, newSet []istanbul.Validator)) {
+	sb.epochTransitionMu.Lock()
+	defer sb.epochTransitionMu.Unlock()
+
+	sb.onEpochTransition = onEpochTransition
+	sb.epochTransitionHead = 0
+}
+
 // StartValidating implements consensus.Istanbul.StartValidating
 func (sb *Backend) StartValidating() error {
 	sb.coreMu.Lock()
@@ -668,6 +882,11 @@ func (sb *Backend) StartValidating() error {
 		return errors.New("Must SetCallBacks prior to StartValidating")
 	}
 
+	// A commitment made before a restart has to be revealed by the next proposal
+	if err := sb.recoverRandomCommitment(); err != nil {
+		sb.logger.Warn("Failed to recover randomness commitment", "err", err)
+	}
+
 	sb.logger.Info("Starting istanbul.Engine validating")
 	if err := sb.core.Start(); err != nil {
 		return err
@@ -749,6 +968,31 @@ func (sb *Backend) StopAnnouncing() error {
 	return sb.vph.stopThread()
 }
 
+// isAnnouncing returns whether the announce thread is running.
+func (sb *Backend) isAnnouncing() bool {
+	sb.announceMu.RLock()
+	defer sb.announceMu.RUnlock()
+	return sb.announceRunning
+}
+
+// waitForBlock waits until the chain head reaches the given number, returning
+// false if it doesn't within the timeout.
+func (sb *Backend) waitForBlock(number *big.Int, timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if head := sb.currentBlock(); head != nil && head.Number().Cmp(number) >= 0 {
+			return true
+		}
+		select {
+		case <-ticker.C:
+		case <-deadline:
+			return false
+		}
+	}
+}
+
 // StartProxiedValidatorEngine implements consensus.Istanbul.StartProxiedValidatorEngine
 func (sb *Backend) StartProxiedValidatorEngine() error {
 	sb.proxiedValidatorEngineMu.Lock()
@@ -847,7 +1091,7 @@ func (sb *Backend) snapshot(chain consensus.ChainHeaderReader, number uint64, ha
 		}
 
 		if (blockHash != common.Hash{}) {
-			if s, err := loadSnapshot(sb.config.Epoch, sb.db, blockHash); err == nil {
+			if s, err := loadSnapshot(sb.config.Epoch, sb.db, numberIter, blockHash); err == nil {
 				log.Trace("Loaded validator set snapshot from disk", "number", numberIter, "hash", blockHash)
 				snap = s
 				sb.recentSnapshots.Add(numberIter, snap)
@@ -941,6 +1185,9 @@ func (sb *Backend) snapshot(chain consensus.ChainHeaderReader, number uint64, ha
 			log.Error("Unable to apply headers to snapshots", "headers", headers)
 			return nil, err
 		}
+		if epoch := istanbul.GetEpochNumber(snap.Number, sb.config.Epoch); epoch >= persistedSnapshots {
+			rawdb.PruneIstanbulSnapshots(sb.db, epoch-persistedSnapshots+1)
+		}
 
 		sb.recentSnapshots.Add(numberIter, snap)
 	}
@@ -1056,6 +1303,20 @@ func sigHash(header *types.Header) (hash common.Hash) {
 	return hash
 }
 
+// SealHashFromRLP decodes an RLP encoded header and returns the hash signed by
+// its proposer, the one recovered in ecrecover. It lets external verifiers check
+// the proposer seal of a header they only have the encoding of.
+func SealHashFromRLP(data []byte) (common.Hash, error) {
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(data, header); err != nil {
+		return common.Hash{}, err
+	}
+	if _, err := types.ExtractIstanbulExtra(header); err != nil {
+		return common.Hash{}, err
+	}
+	return sigHash(header), nil
+}
+
 // ecrecover extracts the Ethereum account address from a signed header.
 func ecrecover(header *types.Header) (common.Address, error) {
 	hash := header.Hash()
@@ -1102,18 +1363,17 @@ func writeEmptyIstanbulExtra(header *types.Header) error {
 
 // writeValidatorSetDiff initializes the header's Extra field with any changes in the
 // validator set that occurred since the last block
-func writeValidatorSetDiff(header *types.Header, oldValSet []istanbul.ValidatorData, newValSet []istanbul.ValidatorData) error {
+func writeValidatorSetDiff(header *types.Header, oldValSet []istanbul.ValidatorData, newValSet []istanbul.ValidatorData, compact bool) error {
 	// compensate the lack bytes if header.Extra is not enough IstanbulExtraVanity bytes.
 	if len(header.Extra) < types.IstanbulExtraVanity {
 		header.Extra = append(header.Extra, bytes.Repeat([]byte{0x00}, types.IstanbulExtraVanity-len(header.Extra))...)
 	}
 
 	addedValidators, removedValidators := istanbul.ValidatorSetDiff(oldValSet, newValSet)
-	addedValidatorsAddresses, addedValidatorsPublicKeys, addedValidatorsG1PublicKeys := istanbul.SeparateValidatorDataIntoIstanbulExtra(addedValidators)
-
 	if len(addedValidators) > 0 || removedValidators.BitLen() > 0 {
 		oldValidatorsAddresses, _, _ := istanbul.SeparateValidatorDataIntoIstanbulExtra(oldValSet)
 		newValidatorsAddresses, _, _ := istanbul.SeparateValidatorDataIntoIstanbulExtra(newValSet)
+		addedValidatorsAddresses, _, _ := istanbul.SeparateValidatorDataIntoIstanbulExtra(addedValidators)
 		log.Debug("Setting istanbul header validator fields", "oldValSet", types.ConvertToStringSlice(oldValidatorsAddresses), "newValSet", types.ConvertToStringSlice(newValidatorsAddresses),
 			"addedValidators", types.ConvertToStringSlice(addedValidatorsAddresses), "removedValidators", removedValidators.Text(16))
 	}
@@ -1123,10 +1383,7 @@ func writeValidatorSetDiff(header *types.Header, oldValSet []istanbul.ValidatorD
 		return nil
 	}
 
-	extra.AddedValidators = addedValidatorsAddresses
-	extra.AddedValidatorsPublicKeys = addedValidatorsPublicKeys
-	extra.AddedValidatorsG1PublicKeys = addedValidatorsG1PublicKeys
-	extra.RemovedValidators = removedValidators
+	setValidatorSetDiff(extra, oldValSet, addedValidators, removedValidators, compact)
 
 	// update the header's extra with the new diff
 	payload, err := rlp.EncodeToBytes(extra)
@@ -1138,6 +1395,21 @@ func writeValidatorSetDiff(header *types.Header, oldValSet []istanbul.ValidatorD
 	return nil
 }
 
+// setValidatorSetDiff sets the validator set diff fields of extra, the added
+// validators going either in the AddedValidators lists or in the compact form.
+func setValidatorSetDiff(extra *types.IstanbulExtra, oldValSet, addedValidators []istanbul.ValidatorData, removedValidators *big.Int, compact bool) {
+	if compact && len(addedValidators) > 0 {
+		extra.AddedValidators = []common.Address{}
+		extra.AddedValidatorsPublicKeys = []blscrypto.SerializedPublicKey{}
+		extra.AddedValidatorsG1PublicKeys = []blscrypto.SerializedG1PublicKey{}
+		extra.CompactAddedValidators = istanbul.CompactValidatorSetDiff(oldValSet, addedValidators)
+	} else {
+		extra.AddedValidators, extra.AddedValidatorsPublicKeys, extra.AddedValidatorsG1PublicKeys = istanbul.SeparateValidatorDataIntoIstanbulExtra(addedValidators)
+		extra.CompactAddedValidators = nil
+	}
+	extra.RemovedValidators = removedValidators
+}
+
 // writeSeal writes the extra-data field of the given header with the given seal.
 func writeSeal(h *types.Header, seal []byte) error {
 	if len(seal) != types.IstanbulExtraSeal {
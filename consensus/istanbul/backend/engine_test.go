@@ -18,21 +18,30 @@ package backend
 
 import (
 	"bytes"
+	"errors"
 	"math/big"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/rlp"
 	. "github.com/onsi/gomega"
 
 	"github.com/mapprotocol/atlas/consensus"
 	"github.com/mapprotocol/atlas/consensus/istanbul"
 	"github.com/mapprotocol/atlas/consensus/istanbul/core"
+	"github.com/mapprotocol/atlas/contracts/abis"
+	"github.com/mapprotocol/atlas/contracts/testutil"
 	bccore "github.com/mapprotocol/atlas/core"
+	"github.com/mapprotocol/atlas/core/rawdb"
+	"github.com/mapprotocol/atlas/core/state"
 	"github.com/mapprotocol/atlas/core/types"
+	"github.com/mapprotocol/atlas/core/vm"
 	"github.com/mapprotocol/atlas/helper/bls"
+	"github.com/mapprotocol/atlas/params"
 )
 
 func stopEngine(engine *Backend) {
@@ -55,6 +64,204 @@ func TestPrepare(t *testing.T) {
 	g.Expect(err).To(BeIdenticalTo(consensus.ErrUnknownAncestor))
 }
 
+func TestVerifyRandomness(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	chain, engine := newBlockChain(1, true)
+	defer stopEngine(engine)
+	defer chain.Stop()
+
+	err := engine.VerifyRandomness(common.BytesToHash([]byte("1234567890")), common.Hash{}, common.Hash{})
+	g.Expect(err).To(BeIdenticalTo(consensus.ErrUnknownAncestor))
+
+	useRandomMock(engine)
+	parent := chain.Genesis().Hash()
+	randomness, commitment, err := engine.generateRandomness(parent)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(commitment).ToNot(Equal(common.Hash{}))
+	g.Expect(engine.VerifyRandomness(parent, commitment, randomness)).To(Succeed())
+
+	tampered := common.BytesToHash(append(randomness.Bytes()[1:], 0x01))
+	g.Expect(engine.VerifyRandomness(parent, commitment, tampered)).To(MatchError(errInvalidRandomness))
+}
+
+// hashBeacon reveals the default seeded randomness and commits to its hash,
+// so that commitments can be made without the random contract.
+type hashBeacon struct {
+	seedBeacon
+}
+
+func (b hashBeacon) Commit(parentHash common.Hash) (common.Hash, error) {
+	randomness, err := b.Reveal(parentHash)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(randomness.Bytes()), nil
+}
+
+// randomMock is a random contract committing to the hash of the randomness,
+// like hashBeacon, and returning the last commitments it is given.
+type randomMock struct {
+	testutil.ContractMock
+	lastCommitments map[common.Address]common.Hash
+}
+
+func (rm *randomMock) Commitments(validator common.Address) common.Hash {
+	return rm.lastCommitments[validator]
+}
+
+func (rm *randomMock) ComputeCommitment(randomness common.Hash) common.Hash {
+	return crypto.Keccak256Hash(randomness.Bytes())
+}
+
+// randomChain is a chain whose EVM runners only run the random contract mock.
+type randomChain struct {
+	consensus.ChainContext
+	runner *testutil.MockEVMRunner
+}
+
+func (c randomChain) NewEVMRunnerForCurrentBlock() (vm.EVMRunner, error) {
+	return c.runner, nil
+}
+
+func (c randomChain) NewEVMRunnerForBlock(header *types.Header) (vm.EVMRunner, error) {
+	return c.runner, nil
+}
+
+func (c randomChain) NewEVMRunner(header *types.Header, statedb types.StateDB) vm.EVMRunner {
+	return c.runner
+}
+
+// useRandomMock makes the engine commit to randomness with hashBeacon and run
+// the random contract mock instead of the contracts in the chain state.
+func useRandomMock(engine *Backend) *randomMock {
+	mock := &randomMock{lastCommitments: make(map[common.Address]common.Hash)}
+	mock.ContractMock = testutil.NewContractMock(abis.Random, mock)
+
+	registry := testutil.NewRegistryMock()
+	registry.AddContract(params.RandomRegistryId, common.HexToAddress("0x033"))
+	runner := testutil.NewMockEVMRunner()
+	runner.RegisterContract(params.RegistrySmartContractAddress, registry)
+	runner.RegisterContract(common.HexToAddress("0x033"), mock)
+
+	engine.SetChain(randomChain{engine.chain, runner}, engine.currentBlock, engine.stateAt)
+	engine.SetRandomBeacon(hashBeacon{seedBeacon{engine}})
+	return mock
+}
+
+// Tests that randomness committed to before a restart can still be revealed.
+func TestRandomnessAcrossRestart(t *testing.T) {
+	genesisCfg, nodeKeys := getGenesisAndKeys(1, true)
+	chain, engine, config := newBlockChainWithKeys(false, common.Address{}, false, genesisCfg, nodeKeys[0])
+	defer chain.Stop()
+
+	parent := chain.Genesis().Hash()
+	engine.SetRandomBeacon(hashBeacon{seedBeacon{engine}})
+	randomness, commitment, err := engine.generateRandomness(parent)
+	if err != nil {
+		t.Fatalf("failed to generate randomness: %v", err)
+	}
+	if (commitment == common.Hash{}) {
+		t.Fatalf("no randomness committed to")
+	}
+	stopEngine(engine)
+
+	// Restart the engine on the same database before any block is written
+	restarted := New(config, engine.db).(*Backend)
+	address := crypto.PubkeyToAddress(nodeKeys[0].PublicKey)
+	restarted.Authorize(address, address, &nodeKeys[0].PublicKey, DecryptFn(nodeKeys[0]), SignFn(nodeKeys[0]), SignBLSFn(nodeKeys[0]), SignHashFn(nodeKeys[0]))
+	restarted.SetChain(chain, chain.CurrentBlock, func(hash common.Hash) (*state.StateDB, error) {
+		return chain.StateAt(chain.GetHeaderByHash(hash).Root)
+	})
+	restarted.SetRandomBeacon(hashBeacon{seedBeacon{restarted}})
+
+	cached := rawdb.ReadRandomCommitmentCache(restarted.db, commitment)
+	if cached != parent {
+		t.Fatalf("commitment cache mismatch: have %x, want %x", cached, parent)
+	}
+	revealed, recommitted, err := restarted.generateRandomness(cached)
+	if err != nil {
+		t.Fatalf("failed to regenerate randomness: %v", err)
+	}
+	if revealed != randomness || recommitted != commitment {
+		t.Fatalf("regenerated randomness mismatch: have (%x, %x), want (%x, %x)", revealed, recommitted, randomness, commitment)
+	}
+}
+
+// Tests that a randomness commitment missing from the commitment cache is
+// rebuilt from the blocks of the current epoch when validating starts.
+func TestRecoverRandomCommitment(t *testing.T) {
+	genesisCfg, nodeKeys := getGenesisAndKeys(1, true)
+	chain, engine, _ := newBlockChainWithKeys(false, common.Address{}, false, genesisCfg, nodeKeys[0])
+	defer stopEngine(engine)
+	defer chain.Stop()
+
+	block, err := makeBlock(nodeKeys, chain, engine, chain.Genesis())
+	if err != nil {
+		t.Fatalf("failed to make block: %v", err)
+	}
+	mock := useRandomMock(engine)
+
+	// The block committed to randomness, but the cache entry got lost
+	_, commitment, err := engine.generateRandomness(block.ParentHash())
+	if err != nil {
+		t.Fatalf("failed to generate randomness: %v", err)
+	}
+	mock.lastCommitments[engine.ValidatorAddress()] = commitment
+	rawdb.DeleteRandomCommitmentCache(engine.db, commitment)
+
+	if err := engine.StopValidating(); err != nil {
+		t.Fatalf("failed to stop validating: %v", err)
+	}
+	if err := engine.StartValidating(); err != nil {
+		t.Fatalf("failed to start validating: %v", err)
+	}
+	if cached := rawdb.ReadRandomCommitmentCache(engine.db, commitment); cached != block.ParentHash() {
+		t.Fatalf("recovered commitment cache mismatch: have %x, want %x", cached, block.ParentHash())
+	}
+}
+
+// fixedBeacon is a deterministic random beacon revealing the parent hash.
+type fixedBeacon struct{}
+
+func (fixedBeacon) Commit(parentHash common.Hash) (common.Hash, error) {
+	return crypto.Keccak256Hash(parentHash.Bytes()), nil
+}
+
+func (fixedBeacon) Reveal(parentHash common.Hash) (common.Hash, error) {
+	return parentHash, nil
+}
+
+func TestSetRandomBeacon(t *testing.T) {
+	chain, engine := newBlockChain(1, true)
+	defer stopEngine(engine)
+	defer chain.Stop()
+
+	parent := chain.Genesis().Hash()
+	if _, ok := engine.randomBeacon().(seedBeacon); !ok {
+		t.Fatalf("default beacon mismatch: have %T", engine.randomBeacon())
+	}
+	seeded, err := engine.randomBeacon().Reveal(parent)
+	if err != nil {
+		t.Fatalf("failed to reveal default randomness: %v", err)
+	}
+	engine.SetRandomBeacon(fixedBeacon{})
+	randomness, commitment, err := engine.generateRandomness(parent)
+	if err != nil {
+		t.Fatalf("failed to generate randomness: %v", err)
+	}
+	if randomness != parent || commitment != crypto.Keccak256Hash(parent.Bytes()) {
+		t.Fatalf("randomness mismatch: have (%x, %x), want the fixed beacon's", randomness, commitment)
+	}
+	if cached := rawdb.ReadRandomCommitmentCache(engine.db, commitment); cached != parent {
+		t.Fatalf("commitment cache mismatch: have %x, want %x", cached, parent)
+	}
+	engine.SetRandomBeacon(nil)
+	if revealed, err := engine.randomBeacon().Reveal(parent); err != nil || revealed != seeded {
+		t.Fatalf("restored beacon mismatch: have (%x, %v), want (%x, nil)", revealed, err, seeded)
+	}
+}
+
 func TestMakeBlockWithSignature(t *testing.T) {
 	g := NewGomegaWithT(t)
 
@@ -87,7 +294,7 @@ func TestSealCommitted(t *testing.T) {
 	expectedBlock, _ := engine.signBlock(block)
 
 	go func() {
-		if err := engine.Seal(chain, block); err != nil {
+		if err := engine.Seal(chain, block, nil); err != nil {
 			t.Errorf("Failed to seal the block: %v", err)
 		}
 	}()
@@ -106,6 +313,30 @@ func TestSealCommitted(t *testing.T) {
 	}
 }
 
+func TestSealStopped(t *testing.T) {
+	chain, engine := newBlockChain(1, true)
+	defer stopEngine(engine)
+	defer chain.Stop()
+
+	block := makeBlockWithoutSeal(chain, engine, chain.Genesis())
+
+	newHeadCh := make(chan bccore.ChainHeadEvent, 10)
+	sub := chain.SubscribeChainHeadEvent(newHeadCh)
+	defer sub.Unsubscribe()
+
+	stop := make(chan struct{})
+	close(stop)
+	if err := engine.Seal(chain, block, stop); err != nil {
+		t.Fatalf("Failed to seal the block: %v", err)
+	}
+
+	select {
+	case newHead := <-newHeadCh:
+		t.Fatalf("Unexpected new block %v after the seal was stopped", newHead.Block.Hash())
+	case <-time.After(500 * time.Millisecond):
+	}
+}
+
 func TestVerifyHeader(t *testing.T) {
 	g := NewGomegaWithT(t)
 	chain, engine := newBlockChain(1, true)
@@ -142,6 +373,26 @@ func TestVerifyHeader(t *testing.T) {
 	header.Time = uint64(now().Unix() + 10)
 	err = engine.VerifyHeader(chain, header, false)
 	g.Expect(err).Should(BeIdenticalTo(consensus.ErrFutureBlock))
+
+	// extra data over the size limit, only enforced from the compact
+	// validator set diff fork on
+	header = makeBlockWithoutSeal(chain, engine, chain.Genesis()).Header()
+	header.Extra = append(header.Extra, make([]byte, engine.config.MaxHeaderExtraSize)...)
+	err = engine.VerifyHeader(chain, header, false)
+	g.Expect(errors.Is(err, errExtraDataTooLarge)).Should(BeFalse())
+	engine.config.CompactValSetDiffBlock = big.NewInt(0)
+	err = engine.VerifyHeader(chain, header, false)
+	engine.config.CompactValSetDiffBlock = nil
+	g.Expect(errors.Is(err, errExtraDataTooLarge)).Should(BeTrue())
+
+	// compact validator set diff before its fork
+	header = makeBlockWithoutSeal(chain, engine, chain.Genesis()).Header()
+	extra, _ := types.ExtractIstanbulExtra(header)
+	extra.CompactAddedValidators = []types.CompactAddedValidator{{Address: common.HexToAddress("0x01"), KeyIndex: 1}}
+	payload, _ := rlp.EncodeToBytes(extra)
+	header.Extra = append(header.Extra[:types.IstanbulExtraVanity], payload...)
+	err = engine.VerifyHeader(chain, header, false)
+	g.Expect(err).Should(BeIdenticalTo(errInvalidValidatorSetDiff))
 }
 
 func TestVerifySeal(t *testing.T) {
@@ -192,6 +443,38 @@ func TestVerifySeal(t *testing.T) {
 	g.Expect(err).ToNot(HaveOccurred())
 }
 
+func TestValidateHeaderExtra(t *testing.T) {
+	g := NewGomegaWithT(t)
+	genesisCfg, nodeKeys := getGenesisAndKeys(1, true)
+	chain, engine, _ := newBlockChainWithKeys(false, common.Address{}, false, genesisCfg, nodeKeys[0])
+	defer stopEngine(engine)
+	defer chain.Stop()
+
+	block, err := makeBlock(nodeKeys, chain, engine, chain.Genesis())
+	g.Expect(err).ToNot(HaveOccurred())
+	parentValidators := engine.GetValidators(big.NewInt(0), chain.Genesis().Hash())
+	g.Expect(ValidateHeaderExtra(block.Header(), parentValidators)).To(Succeed())
+
+	// the seal is not made by the given validators
+	others, _ := newTestValidatorSet(1)
+	g.Expect(ValidateHeaderExtra(block.Header(), others.List())).Should(BeIdenticalTo(errInvalidSignature))
+
+	header := block.Header()
+	header.Extra = nil
+	g.Expect(ValidateHeaderExtra(header, parentValidators)).Should(BeIdenticalTo(errInvalidExtraDataFormat))
+
+	// an added validator without its G1 key is an invalid diff
+	header = block.Header()
+	extra, err := types.ExtractIstanbulExtra(header)
+	g.Expect(err).ToNot(HaveOccurred())
+	extra.AddedValidators = append(extra.AddedValidators, common.Address{0x01})
+	extra.AddedValidatorsPublicKeys = append(extra.AddedValidatorsPublicKeys, bls.SerializedPublicKey{})
+	encoded, err := rlp.EncodeToBytes(extra)
+	g.Expect(err).ToNot(HaveOccurred())
+	header.Extra = append(header.Extra[:types.IstanbulExtraVanity], encoded...)
+	g.Expect(ValidateHeaderExtra(header, parentValidators)).Should(BeIdenticalTo(errInvalidValidatorSetDiff))
+}
+
 func TestVerifyHeaders(t *testing.T) {
 	numValidators := 1
 	genesisCfg, nodeKeys := getGenesisAndKeys(numValidators, true)
@@ -303,6 +586,172 @@ func TestVerifyHeaders(t *testing.T) {
 	})
 }
 
+func TestVerifyHeadersConcurrently(t *testing.T) {
+	hardErr := errors.New("bad header")
+	run := func(n int, verify func(int) error) []error {
+		results := make(chan error, n)
+		verifyHeadersConcurrently(n, verify, make(chan struct{}), results)
+		errs := make([]error, n)
+		for i := range errs {
+			errs[i] = <-results
+		}
+		return errs
+	}
+	// Results come in input order, and future blocks don't fail later headers
+	errs := run(64, func(index int) error {
+		time.Sleep(time.Duration(64-index) * 10 * time.Microsecond)
+		if index%8 == 0 {
+			return consensus.ErrFutureBlock
+		}
+		return nil
+	})
+	for i, err := range errs {
+		var want error
+		if i%8 == 0 {
+			want = consensus.ErrFutureBlock
+		}
+		if err != want {
+			t.Fatalf("result %d mismatch: have %v, want %v", i, err, want)
+		}
+	}
+	// A hard error fails every later header, and those after the in-flight
+	// ones are never verified
+	var verified int32
+	errs = run(2048, func(index int) error {
+		atomic.AddInt32(&verified, 1)
+		if index == 10 {
+			return hardErr
+		}
+		return nil
+	})
+	for i, err := range errs {
+		var want error
+		switch {
+		case i == 10:
+			want = hardErr
+		case i > 10:
+			want = consensus.ErrUnknownAncestor
+		}
+		if err != want {
+			t.Fatalf("result %d mismatch: have %v, want %v", i, err, want)
+		}
+	}
+	if n := atomic.LoadInt32(&verified); n >= 2048 {
+		t.Errorf("verified %d headers after a hard error", n)
+	}
+}
+
+func TestVerifyHeadersConcurrentlyAbort(t *testing.T) {
+	const work = 20 * time.Millisecond
+	var started int32
+	verify := func(int) error {
+		atomic.AddInt32(&started, 1)
+		time.Sleep(work)
+		return nil
+	}
+	abort, results := make(chan struct{}), make(chan error, 1024)
+	go verifyHeadersConcurrently(1024, verify, abort, results)
+	<-results
+	close(abort)
+
+	// Workers may still finish the header they were at, but none starts a new one
+	time.Sleep(work + work/2)
+	stopped := atomic.LoadInt32(&started)
+	time.Sleep(2 * work)
+	if n := atomic.LoadInt32(&started); n != stopped {
+		t.Fatalf("workers still verifying after abort: %d headers started, %d at stop", n, stopped)
+	}
+	if stopped >= 1024 {
+		t.Fatalf("all headers verified despite abort")
+	}
+}
+
+func TestSealHashFromRLP(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	header := &types.Header{Number: big.NewInt(1), Time: 1}
+	if err := writeEmptyIstanbulExtra(header); err != nil {
+		t.Fatalf("failed to write extra: %v", err)
+	}
+	seal, err := crypto.Sign(sigHash(header).Bytes(), key)
+	if err != nil {
+		t.Fatalf("failed to sign header: %v", err)
+	}
+	if err := writeSeal(header, seal); err != nil {
+		t.Fatalf("failed to write seal: %v", err)
+	}
+	data, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		t.Fatalf("failed to encode header: %v", err)
+	}
+
+	hash, err := SealHashFromRLP(data)
+	if err != nil {
+		t.Fatalf("failed to compute the seal hash: %v", err)
+	}
+	if hash != sigHash(header) {
+		t.Errorf("seal hash mismatch: have %x, want %x", hash, sigHash(header))
+	}
+	if addr, err := istanbul.GetSignatureAddress(hash.Bytes(), seal); err != nil || addr != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Errorf("recovered signer mismatch: have (%x, %v), want %x", addr, err, crypto.PubkeyToAddress(key.PublicKey))
+	}
+
+	if _, err := SealHashFromRLP(data[:len(data)-1]); err == nil {
+		t.Errorf("truncated header accepted")
+	}
+	header.Extra = nil
+	if data, err = rlp.EncodeToBytes(header); err != nil {
+		t.Fatalf("failed to encode header: %v", err)
+	}
+	if _, err := SealHashFromRLP(data); err == nil {
+		t.Errorf("header without istanbul extra accepted")
+	}
+}
+
+// BenchmarkVerifyHeaders measures the verification of batches of 2048 signed
+// headers, recovering the signer of each header on the worker pool.
+func BenchmarkVerifyHeaders(b *testing.B) {
+	key, _ := crypto.GenerateKey()
+	headers := make([]*types.Header, 2048)
+	for i := range headers {
+		headers[i] = &types.Header{Number: big.NewInt(int64(i + 1)), Time: uint64(i)}
+		if err := writeEmptyIstanbulExtra(headers[i]); err != nil {
+			b.Fatalf("failed to write extra: %v", err)
+		}
+		seal, err := crypto.Sign(sigHash(headers[i]).Bytes(), key)
+		if err != nil {
+			b.Fatalf("failed to sign header: %v", err)
+		}
+		if err := writeSeal(headers[i], seal); err != nil {
+			b.Fatalf("failed to write seal: %v", err)
+		}
+	}
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+	verify := func(index int) error {
+		extra, err := types.ExtractIstanbulExtra(headers[index])
+		if err != nil {
+			return err
+		}
+		addr, err := istanbul.GetSignatureAddress(sigHash(headers[index]).Bytes(), extra.Seal)
+		if err != nil {
+			return err
+		}
+		if addr != signer {
+			return errUnauthorized
+		}
+		return nil
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		results := make(chan error, len(headers))
+		verifyHeadersConcurrently(len(headers), verify, make(chan struct{}), results)
+		for range headers {
+			if err := <-results; err != nil {
+				b.Fatalf("verification failed: %v", err)
+			}
+		}
+	}
+}
+
 func TestVerifyHeaderWithoutFullChain(t *testing.T) {
 	chain, engine := newBlockChain(1, false)
 	defer stopEngine(engine)
@@ -354,7 +803,7 @@ func TestPrepareExtra(t *testing.T) {
 		Extra: append(make([]byte, types.IstanbulExtraVanity), extra...),
 	}
 
-	err = writeValidatorSetDiff(h, oldValidators, newValidators)
+	err = writeValidatorSetDiff(h, oldValidators, newValidators, false)
 	g.Expect(err).ToNot(HaveOccurred())
 
 	// the header must have the updated extra data
@@ -0,0 +1,114 @@
+package backend
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/mapprotocol/atlas/consensus/istanbul"
+	"github.com/mapprotocol/atlas/core/types"
+)
+
+const (
+	// epochRewardsHistorySize is the number of recent epochs whose rewards are
+	// kept in memory.
+	epochRewardsHistorySize = 16
+
+	// pendingEpochRewardsSize is the number of epoch blocks finalized but not
+	// yet part of the canonical chain whose rewards are kept, e.g. competing
+	// proposals of the same epoch block.
+	pendingEpochRewardsSize = 8
+)
+
+// epochRewardsHistory is a ring buffer of the rewards of the recent epochs.
+type epochRewardsHistory struct {
+	entries []*istanbul.EpochRewardsProcessed
+	next    int
+	mu      sync.RWMutex
+}
+
+func newEpochRewardsHistory(size int) *epochRewardsHistory {
+	return &epochRewardsHistory{entries: make([]*istanbul.EpochRewardsProcessed, size)}
+}
+
+// add records rewards, replacing the ones of the same epoch after a reorg or
+// the oldest ones once the buffer is full.
+func (h *epochRewardsHistory) add(rewards *istanbul.EpochRewardsProcessed) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, entry := range h.entries {
+		if entry != nil && entry.Epoch == rewards.Epoch {
+			h.entries[i] = rewards
+			return
+		}
+	}
+	h.entries[h.next] = rewards
+	h.next = (h.next + 1) % len(h.entries)
+}
+
+// get returns the rewards of the given epoch, nil if not kept.
+func (h *epochRewardsHistory) get(epoch uint64) *istanbul.EpochRewardsProcessed {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, entry := range h.entries {
+		if entry != nil && entry.Epoch == epoch {
+			return entry
+		}
+	}
+	return nil
+}
+
+// list returns the kept rewards ordered by epoch.
+func (h *epochRewardsHistory) list() []*istanbul.EpochRewardsProcessed {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	list := make([]*istanbul.EpochRewardsProcessed, 0, len(h.entries))
+	for _, entry := range h.entries {
+		if entry != nil {
+			list = append(list, entry)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Epoch < list[j].Epoch })
+	return list
+}
+
+// stageEpochRewards keeps the rewards distributed while finalizing an epoch
+// block until it becomes part of the canonical chain. The hash of the block
+// isn't known yet, so they are looked up by its state root.
+func (sb *Backend) stageEpochRewards(root common.Hash, rewards *istanbul.EpochRewardsProcessed) {
+	sb.pendingEpochRewards.Add(root, rewards)
+}
+
+// publishEpochRewards records and sends the rewards distributed by the
+// canonical epoch block header, if it was finalized by this node.
+func (sb *Backend) publishEpochRewards(header *types.Header) {
+	pending, ok := sb.pendingEpochRewards.Get(header.Root)
+	if !ok {
+		sb.logger.Debug("Rewards of epoch block not found", "number", header.Number, "root", header.Root)
+		return
+	}
+	rewards := *pending.(*istanbul.EpochRewardsProcessed)
+	rewards.BlockHash = header.Hash()
+
+	sb.epochRewards.add(&rewards)
+	sb.epochRewardsFeed.Send(rewards)
+}
+
+// SubscribeEpochRewards implements consensus.Istanbul.SubscribeEpochRewards
+func (sb *Backend) SubscribeEpochRewards(ch chan<- istanbul.EpochRewardsProcessed) event.Subscription {
+	return sb.epochRewardsScope.Track(sb.epochRewardsFeed.Subscribe(ch))
+}
+
+// EpochRewards implements consensus.Istanbul.EpochRewards
+func (sb *Backend) EpochRewards(epoch uint64) *istanbul.EpochRewardsProcessed {
+	return sb.epochRewards.get(epoch)
+}
+
+// RecentEpochRewards implements consensus.Istanbul.RecentEpochRewards
+func (sb *Backend) RecentEpochRewards() []*istanbul.EpochRewardsProcessed {
+	return sb.epochRewards.list()
+}
@@ -0,0 +1,72 @@
+package backend
+
+import (
+	"fmt"
+
+	"github.com/mapprotocol/atlas/consensus/istanbul"
+	istanbulCore "github.com/mapprotocol/atlas/consensus/istanbul/core"
+	"github.com/mapprotocol/atlas/core/types"
+	blscrypto "github.com/mapprotocol/atlas/helper/bls"
+)
+
+// VerifyEpochSnarkData implements consensus.Istanbul.VerifyEpochSnarkData
+func (sb *Backend) VerifyEpochSnarkData(block *types.Block) error {
+	number := block.NumberU64()
+	data := block.EpochSnarkData()
+	if number == 0 || !istanbul.IsLastBlockOfEpoch(number, sb.config.Epoch) {
+		if data != nil && !data.IsEmpty() {
+			return fmt.Errorf("%w: data in block %d which doesn't end an epoch", errInvalidEpochSnarkData, number)
+		}
+		return nil
+	}
+	if data == nil || data.IsEmpty() || data.Bitmap == nil {
+		return errEmptyEpochSnarkData
+	}
+
+	header := block.Header()
+	extra, err := types.ExtractIstanbulExtra(header)
+	if err != nil {
+		return err
+	}
+	if extra.AggregatedSeal.Round == nil {
+		return errEmptyAggregatedSeal
+	}
+	snap, err := sb.snapshot(sb.chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return err
+	}
+	// The epoch data is signed by the validators of the block over the set
+	// following it
+	newValSet := snap.ValSet.Copy()
+	addedValidators, err := istanbul.AddedValidatorsOfExtra(extra, snap.validators())
+	if err != nil {
+		return err
+	}
+	if !newValSet.RemoveValidators(extra.RemovedValidators) || !newValSet.AddValidators(addedValidators) {
+		return errInvalidValidatorSetDiff
+	}
+	parentEpochHash := sb.HashForBlock(number - sb.config.Epoch)
+	message, extraData, err := istanbulCore.EncodeEpochSnarkData(number, sb.config.Epoch, uint8(extra.AggregatedSeal.Round.Uint64()), block.Hash(), parentEpochHash, newValSet)
+	if err != nil {
+		return err
+	}
+
+	if size := snap.ValSet.Size(); data.Bitmap.BitLen() > size {
+		return fmt.Errorf("%w: bitmap of %d bits for %d validators", errInvalidEpochSnarkData, data.Bitmap.BitLen(), size)
+	}
+	publicKeys := []blscrypto.SerializedPublicKey{}
+	for i, val := range snap.ValSet.List() {
+		if data.Bitmap.Bit(i) == 1 {
+			publicKeys = append(publicKeys, val.BLSPublicKey())
+		}
+	}
+	if len(publicKeys) < snap.ValSet.MinQuorumSize() {
+		sb.logger.Error("Epoch SNARK data does not aggregate enough seals", "number", number, "numSeals", len(publicKeys), "minimum quorum size", snap.ValSet.MinQuorumSize())
+		return errInsufficientSeals
+	}
+	if err := blscrypto.CryptoType().VerifyAggregatedSignature(publicKeys, message, extraData, data.Signature, true, true); err != nil {
+		sb.logger.Error("Unable to verify epoch SNARK data signature", "number", number, "err", err)
+		return fmt.Errorf("%w: %v", errInvalidEpochSnarkData, err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package backend
+
+import (
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/mapprotocol/atlas/consensus/istanbul"
+	"github.com/mapprotocol/atlas/core/rawdb"
+)
+
+// slashableDoubleSignEpochs is the number of epochs following the one of a
+// double signing for which its evidence is kept, as it can only be used to
+// slash the validator within this window.
+const slashableDoubleSignEpochs = 2
+
+// ReportDoubleSign implements core.CoreBackend.ReportDoubleSign. The evidence
+// is stored with the epoch of its sequence and passed to the double signing
+// callback, which runs in its own goroutine so that it can't stall the
+// consensus core. Evidence which can no longer be slashed is pruned.
+func (sb *Backend) ReportDoubleSign(evidence *istanbul.DoubleSignEvidence) {
+	epoch := istanbul.GetEpochNumber(evidence.Sequence.Uint64(), sb.EpochSize())
+	sb.logger.Warn("Validator signed conflicting messages", "address", evidence.Validator, "code", evidence.Code, "sequence", evidence.Sequence, "round", evidence.Round, "epoch", epoch)
+
+	data, err := rlp.EncodeToBytes(evidence)
+	if err != nil {
+		sb.logger.Error("Failed to encode double sign evidence", "err", err)
+		return
+	}
+	rawdb.WriteDoubleSignEvidence(sb.db, epoch, evidence.Hash(), data)
+	if epoch > slashableDoubleSignEpochs {
+		if pruned := rawdb.PruneDoubleSignEvidenceBefore(sb.db, epoch-slashableDoubleSignEpochs); pruned > 0 {
+			sb.logger.Debug("Pruned double sign evidence", "before", epoch-slashableDoubleSignEpochs, "count", pruned)
+		}
+	}
+
+	sb.doubleSignMu.RLock()
+	onDoubleSign := sb.onDoubleSign
+	sb.doubleSignMu.RUnlock()
+	if onDoubleSign != nil {
+		go onDoubleSign(evidence)
+	}
+}
+
+// SetDoubleSignCallback implements consensus.Istanbul.SetDoubleSignCallback
+func (sb *Backend) SetDoubleSignCallback(onDoubleSign func(evidence *istanbul.DoubleSignEvidence)) {
+	sb.doubleSignMu.Lock()
+	defer sb.doubleSignMu.Unlock()
+
+	sb.onDoubleSign = onDoubleSign
+}
+
+// DoubleSignEvidence retrieves the double signing evidence recorded for the
+// given epoch.
+func (sb *Backend) DoubleSignEvidence(epoch uint64) ([]*istanbul.DoubleSignEvidence, error) {
+	blobs := rawdb.ReadDoubleSignEvidence(sb.db, epoch)
+	evidence := make([]*istanbul.DoubleSignEvidence, len(blobs))
+	for i, blob := range blobs {
+		evidence[i] = new(istanbul.DoubleSignEvidence)
+		if err := rlp.DecodeBytes(blob, evidence[i]); err != nil {
+			return nil, err
+		}
+	}
+	return evidence, nil
+}
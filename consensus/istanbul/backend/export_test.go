@@ -0,0 +1,10 @@
+package backend
+
+import "sync/atomic"
+
+// setLookbackWindowOverride makes LookbackWindow return the given window for
+// every block instead of reading it from state, a zero window removes the
+// override.
+func (sb *Backend) setLookbackWindowOverride(window uint64) {
+	atomic.StoreUint64(&sb.lookbackWindowOverride, window)
+}
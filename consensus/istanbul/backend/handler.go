@@ -56,6 +56,14 @@ func (sb *Backend) HandleMsg(addr common.Address, msg p2p.Msg, peer consensus.Pe
 		return true, errDecodeFailed
 	}
 
+	// Messages relayed by the proxies are tracked for their health check, and consensus
+	// messages relayed by several of them are only handled once.
+	if sb.IsProxiedValidator() && peer.PurposeIsSet(p2p.ProxyPurpose) {
+		if !sb.proxiedValidatorEngine.CheckProxyMsg(peer.Node().ID(), msg.Code, data) {
+			return true, nil
+		}
+	}
+
 	if sb.IsProxy() {
 		switch msg.Code {
 		// TODO(Joshua): Decide to pull out specific proxy handlers
@@ -304,14 +312,13 @@ func (sb *Backend) UpdateMetricsForParentOfBlock(child *types.Block) {
 		}
 	}
 
-	parentState, err := sb.stateAt(childHeader.ParentHash)
+	// The parents lookback window at the time will be used.
+	// However, the value used for updating the validator scores is the one set at the last epoch block.
+	lookbackWindow, err := sb.LookbackWindowAt(parentHeader)
 	if err != nil {
 		sb.logger.Error("Error obtaining block state", "block_number", parentHeader.Number, "err", err.Error())
 		return
 	}
-	// The parents lookback window at the time will be used.
-	// However, the value used for updating the validator scores is the one set at the last epoch block.
-	lookbackWindow := sb.LookbackWindow(parentHeader, parentState)
 
 	// Report downtime events
 	if sb.blocksElectedButNotSignedGauge.Value() >= int64(lookbackWindow) {
@@ -333,6 +340,10 @@ func (sb *Backend) newChainHead(newBlock *types.Block) {
 	// Update metrics for whether we were elected and signed the parent of this block.
 	sb.UpdateMetricsForParentOfBlock(newBlock)
 
+	sb.notifyEpochTransitions(newBlock)
+
+	sb.pruneUptime(newBlock.NumberU64())
+
 	// If this is the last block of the epoch:
 	// * Print an easy to find log message giving our address and whether we're elected in next epoch.
 	// * If this is a node maintaining validator connections (e.g. a proxy or a standalone validator), refresh the validator enode table.
@@ -366,6 +377,44 @@ func (sb *Backend) newChainHead(newBlock *types.Block) {
 	sb.logger.Trace("End newChainHead", "number", newBlock.Number().Uint64())
 }
 
+// notifyEpochTransitions publishes the epoch rewards and calls the epoch
+// transition callback for the last block of every epoch between the previous
+// chain head and newBlock. Chain head events are only sent for the last block
+// of an imported batch, so several epochs may have ended since the previous one.
+func (sb *Backend) notifyEpochTransitions(newBlock *types.Block) {
+	sb.epochTransitionMu.Lock()
+	defer sb.epochTransitionMu.Unlock()
+
+	head := newBlock.NumberU64()
+	from := sb.epochTransitionHead + 1
+	if sb.epochTransitionHead == 0 || sb.epochTransitionHead >= head {
+		// First head seen or a reorg, only the new head itself can be checked
+		from = head
+	}
+	sb.epochTransitionHead = head
+
+	for number := from; number <= head; number++ {
+		if number == 0 || !istanbul.IsLastBlockOfEpoch(number, sb.config.Epoch) {
+			continue
+		}
+		header := newBlock.Header()
+		if number != head {
+			if header = sb.chain.GetHeaderByNumber(number); header == nil {
+				sb.logger.Warn("Missing epoch block for transition", "number", number)
+				continue
+			}
+		}
+		sb.publishEpochRewards(header)
+
+		if sb.onEpochTransition == nil {
+			continue
+		}
+		oldSet := sb.getValidators(number-1, header.ParentHash).List()
+		newSet := sb.getValidators(number, header.Hash()).List()
+		sb.onEpochTransition(istanbul.GetEpochNumber(number, sb.config.Epoch), oldSet, newSet)
+	}
+}
+
 func (sb *Backend) RegisterPeer(peer consensus.Peer, isProxiedPeer bool) error {
 	// TODO: For added security, we may want verify that all newly connected proxied peer has the
 	// correct validator key
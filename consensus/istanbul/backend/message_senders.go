@@ -86,6 +86,19 @@ func (sb *Backend) Gossip(payload []byte, ethMsgCode uint64) error {
 	// Get all connected peers
 	peersToSendMsg := sb.broadcaster.FindPeers(nil, p2p.AnyPurpose)
 
+	// A proxied validator only gossips through its active proxy, which the other proxies
+	// replace if it stops relaying.
+	if sb.IsProxiedValidator() {
+		activeProxy, err := sb.proxiedValidatorEngine.GetActiveProxy()
+		if err != nil {
+			logger.Warn("Error in retrieving the active proxy", "err", err)
+		} else if activeProxy != nil {
+			if peer, ok := peersToSendMsg[activeProxy.ID()]; ok {
+				peersToSendMsg = map[enode.ID]consensus.Peer{activeProxy.ID(): peer}
+			}
+		}
+	}
+
 	// Mark that this node gossiped/processed this message, so that it will ignore it if
 	// one of it's peers sends the message to it.
 	sb.gossipCache.MarkMessageProcessedBySelf(payload)
@@ -38,7 +38,9 @@ import (
 	"time"
 )
 
-func (sb *Backend) distributeEpochRewards(header *types.Header, state *state.StateDB) error {
+// distributeEpochRewards distributes the rewards of the epoch ended by header
+// and returns what was paid.
+func (sb *Backend) distributeEpochRewards(header *types.Header, state *state.StateDB) (*istanbul.EpochRewardsProcessed, error) {
 	start := time.Now()
 	defer sb.rewardDistributionTimer.UpdateSince(start)
 	logger := sb.logger.New("func", "Backend.distributeEpochPaymentsAndRewards", "blocknum", header.Number.Uint64())
@@ -47,12 +49,12 @@ func (sb *Backend) distributeEpochRewards(header *types.Header, state *state.Sta
 
 	communityPartnerAddress, err := epoch_rewards.GetCommunityPartnerAddress(vmRunner)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	validatorVoterReward, communityReward, relayerReward, err := epoch_rewards.CalculateTargetEpochRewards(vmRunner)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if communityPartnerAddress == params.ZeroAddress {
@@ -67,34 +69,34 @@ func (sb *Backend) distributeEpochRewards(header *types.Header, state *state.Sta
 	if len(signerSet) == 0 {
 		err := errors.New("Unable to fetch validator set to update scores and distribute rewards")
 		logger.Error(err.Error())
-		return err
+		return nil, err
 	}
 	validators_, err := sb.GetAccountsFromSigners(vmRunner, signerSet)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	uptimeRets, ignores, err := sb.updateValidatorScores(header, state, signerSet)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	scores, err := sb.calculatePaymentScoreDenominator(vmRunner, uptimeRets, ignores)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	// Reward Validators And voters
-	totalValidatorRewards, voterRewardData, err := sb.distributeValidatorRewards(vmRunner, signerSet, validators_, validatorVoterReward, scores)
+	totalValidatorRewards, validatorPayments, voterRewardData, err := sb.distributeValidatorRewards(vmRunner, signerSet, validators_, validatorVoterReward, scores)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	log.Info("totalValidatorRewards", "maxReward", totalValidatorRewards.String())
 	totalVoterRewards, err := sb.distributeVoterRewards(vmRunner, validators_, voterRewardData)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	log.Info("distributeVoterRewards", "totalVoterRewards", totalVoterRewards.String())
 	if communityReward.Cmp(new(big.Int)) != 0 {
 		if err = gold_token.Mint(vmRunner, communityPartnerAddress, communityReward); err != nil {
-			return err
+			return nil, err
 		}
 	}
 	// mint to relayer
@@ -103,7 +105,7 @@ func (sb *Backend) distributeEpochRewards(header *types.Header, state *state.Sta
 		if relayerAddress != params.ZeroAddress {
 			if err = gold_token.Mint(vmRunner, relayerAddress, relayerReward); err != nil {
 				log.Error("reward to relayer fail", "relayerAddress", relayerAddress, "relayerReward", relayerReward.String())
-				return err
+				return nil, err
 			}
 			log.Info("reward to relayer success", "relayerAddress", relayerAddress, "relayerReward", relayerReward.String())
 		}
@@ -111,35 +113,48 @@ func (sb *Backend) distributeEpochRewards(header *types.Header, state *state.Sta
 	//----------------------------- deRegister -------------------
 	deRegisters, err := sb.deRegisterAllValidatorsInPending(vmRunner)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	log.Info("deRegister AllValidators InPending", "deRegisters", deRegisters)
 
 	//----------------------------- Automatic active -------------------
 	b, err := sb.activeAllPending(vmRunner, validators_)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	log.Info("Automatic active pending voter", "success", b)
 	//----------------------------------------------------------------------
 
-	return nil
+	epoch := istanbul.GetEpochNumber(header.Number.Uint64(), sb.EpochSize())
+	return &istanbul.EpochRewardsProcessed{
+		Epoch:             epoch,
+		BlockNumber:       header.Number.Uint64(),
+		ValidatorPayments: validatorPayments,
+		TotalVoterRewards: totalVoterRewards,
+		CommunityFund:     communityReward,
+	}, nil
+}
+
+// pruneUptime removes the uptime of the epochs far enough behind the chain head
+// not to be used again. It is driven by chain head events rather than done in
+// Finalize, which also runs when older blocks are processed again.
+func (sb *Backend) pruneUptime(head uint64) {
+	uptime.NewMonitor(store.New(sb.db), sb.EpochSize(), 0).Prune(head)
 }
 
 func (sb *Backend) updateValidatorScores(header *types.Header, state *state.StateDB, valSet []istanbul.Validator) ([]*big.Int, []bool, error) {
 	epoch := istanbul.GetEpochNumber(header.Number.Uint64(), sb.EpochSize())
 	logger := sb.logger.New("func", "Backend.updateValidatorScores", "blocknum", header.Number.Uint64(), "epoch", epoch, "epochsize", sb.EpochSize())
 	ignore := make([]bool, len(valSet), len(valSet))
-	// header (&state) == lastBlockOfEpoch
-	// sb.LookbackWindow(header, state) => value at the end of epoch
-	// It doesn't matter which was the value at the beginning but how it ends.
-	// Notice that exposed metrics compute based on current block (not last of epoch) so if lookback window changed during the epoch, metric uptime score might differ
-	lookbackWindow := sb.LookbackWindow(header, state)
+	// The uptime of every block was accounted with the lookback window in use at
+	// it, so the scores follow the schedule as well, including its changes within
+	// the epoch
+	schedule := sb.LookbackSchedule()
 
-	logger = logger.New("window", lookbackWindow)
+	logger = logger.New("window", schedule.At(header.Number.Uint64()))
 	logger.Trace("Updating validator scores")
 
-	monitor := uptime.NewMonitor(store.New(sb.db), sb.EpochSize(), lookbackWindow)
+	monitor := uptime.NewScheduledMonitor(store.New(sb.db), sb.EpochSize(), schedule)
 	uptimes, err := monitor.ComputeValidatorsUptime(epoch, len(valSet))
 	if err != nil {
 		return nil, nil, err
@@ -166,8 +181,9 @@ func (sb *Backend) updateValidatorScores(header *types.Header, state *state.Stat
 /*
 @param maxReward is epochReward for all validators
 */
-func (sb *Backend) distributeValidatorRewards(vmRunner vm.EVMRunner, signerSet []istanbul.Validator, valSets []common.Address, maxReward *big.Int, scoreDenominator *big.Int) (*big.Int, map[common.Address]*big.Int, error) {
+func (sb *Backend) distributeValidatorRewards(vmRunner vm.EVMRunner, signerSet []istanbul.Validator, valSets []common.Address, maxReward *big.Int, scoreDenominator *big.Int) (*big.Int, map[common.Address]*big.Int, map[common.Address]*big.Int, error) {
 	totalValidatorRewards := big.NewInt(0)
+	validatorRewards := make(map[common.Address]*big.Int, len(signerSet))
 	voterRewards := make(map[common.Address]*big.Int, len(signerSet))
 	for i, val := range signerSet {
 		sb.logger.Debug("Distributing epoch reward for validator", "address", val.Address())
@@ -176,10 +192,11 @@ func (sb *Backend) distributeValidatorRewards(vmRunner vm.EVMRunner, signerSet [
 			sb.logger.Error("Error in distributing rewards to validator", "address", val.Address(), "err", err)
 			continue
 		}
+		validatorRewards[valSets[i]] = validatorReward
 		voterRewards[valSets[i]] = voterReward
 		totalValidatorRewards.Add(totalValidatorRewards, validatorReward)
 	}
-	return totalValidatorRewards, voterRewards, nil
+	return totalValidatorRewards, validatorRewards, voterRewards, nil
 }
 
 func (sb *Backend) setInitialGoldTokenTotalSupplyIfUnset(vmRunner vm.EVMRunner) error {
@@ -17,18 +17,81 @@
 package backend
 
 import (
+	"errors"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mapprotocol/atlas/consensus"
+	"github.com/mapprotocol/atlas/consensus/istanbul"
 	"github.com/mapprotocol/atlas/contracts/random"
+	"github.com/mapprotocol/atlas/core/rawdb"
 )
 
 // String for creating the random seed
 var randomSeedString = []byte("Randomness seed string")
 
-// GenerateRandomness will generate the random beacon randomness
-func (sb *Backend) GenerateRandomness(parentHash common.Hash) (common.Hash, common.Hash, error) {
-	logger := sb.logger.New("func", "GenerateRandomness")
+// seedBeacon is the default random beacon. The randomness is the hash of the
+// parent hash and a seed signed with the validator's key, so that it can be
+// regenerated but not predicted by others, and the commitment is computed by
+// the random contract.
+type seedBeacon struct {
+	sb *Backend
+}
+
+func (b seedBeacon) Reveal(parentHash common.Hash) (common.Hash, error) {
+	sb := b.sb
+	sb.randomSeedMu.Lock()
+	defer sb.randomSeedMu.Unlock()
+
+	if sb.randomSeed == nil {
+		seed, err := sb.wallets().Ecdsa.SignHash(common.BytesToHash(randomSeedString))
+		if err != nil {
+			sb.logger.Error("Failed to create randomSeed", "err", err)
+			return common.Hash{}, err
+		}
+		sb.randomSeed = seed
+	}
+	return crypto.Keccak256Hash(append(sb.randomSeed, parentHash.Bytes()...)), nil
+}
+
+func (b seedBeacon) Commit(parentHash common.Hash) (common.Hash, error) {
+	randomness, err := b.Reveal(parentHash)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	vmRunner, err := b.sb.chain.NewEVMRunnerForCurrentBlock()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	// The logic to compute the commitment via the randomness is in the random smart contract.
+	// That logic is stateless, so passing in any block header and state is fine.  There is a TODO for
+	// that commitment computation logic to be removed fromthe random smart contract.
+	return random.ComputeCommitment(vmRunner, randomness)
+}
+
+// SetRandomBeacon replaces the source of the random beacon randomness, nil
+// restores the default one derived from the validator's key.
+func (sb *Backend) SetRandomBeacon(beacon consensus.RandomBeacon) {
+	sb.beaconMu.Lock()
+	defer sb.beaconMu.Unlock()
+	sb.beacon = beacon
+}
+
+// randomBeacon returns the source of the random beacon randomness.
+func (sb *Backend) randomBeacon() consensus.RandomBeacon {
+	sb.beaconMu.RLock()
+	defer sb.beaconMu.RUnlock()
+	if sb.beacon == nil {
+		return seedBeacon{sb}
+	}
+	return sb.beacon
+}
 
+// GenerateRandomness will generate the random beacon randomness. The parent
+// hash of every commitment generated is written to the randomness commitment
+// cache before it is returned, so that the randomness can be revealed even if
+// the node restarts before the block committing to it is written.
+func (sb *Backend) GenerateRandomness(parentHash common.Hash) (common.Hash, common.Hash, error) {
 	// TODO(HF) check which state the vm runner should use (probably not current block's)
 	vmRunner, err := sb.chain.NewEVMRunnerForCurrentBlock()
 	if err != nil {
@@ -38,29 +101,129 @@ func (sb *Backend) GenerateRandomness(parentHash common.Hash) (common.Hash, comm
 	if !random.IsRunning(vmRunner) {
 		return common.Hash{}, common.Hash{}, nil
 	}
+	return sb.generateRandomness(parentHash)
+}
 
-	sb.randomSeedMu.Lock()
-	if sb.randomSeed == nil {
-		var err error
-		w := sb.wallets()
-		sb.randomSeed, err = w.Ecdsa.SignHash(common.BytesToHash(randomSeedString))
-		if err != nil {
-			logger.Error("Failed to create randomSeed", "err", err)
-			sb.randomSeedMu.Unlock()
-			return common.Hash{}, common.Hash{}, err
-		}
-	}
-	sb.randomSeedMu.Unlock()
+// generateRandomness reveals the randomness of the random beacon for the given
+// parent hash and commits to it, recording the parent hash of a non-zero
+// commitment in the randomness commitment cache.
+func (sb *Backend) generateRandomness(parentHash common.Hash) (common.Hash, common.Hash, error) {
+	logger := sb.logger.New("func", "generateRandomness")
 
-	randomness := crypto.Keccak256Hash(append(sb.randomSeed, parentHash.Bytes()...))
-	// The logic to compute the commitment via the randomness is in the random smart contract.
-	// That logic is stateless, so passing in any block header and state is fine.  There is a TODO for
-	// that commitment computation logic to be removed fromthe random smart contract.
-	commitment, err := random.ComputeCommitment(vmRunner, randomness)
+	beacon := sb.randomBeacon()
+	randomness, err := beacon.Reveal(parentHash)
+	if err != nil {
+		logger.Error("Failed to generate randomness", "err", err)
+		return common.Hash{}, common.Hash{}, err
+	}
+	commitment, err := beacon.Commit(parentHash)
 	if err != nil {
 		logger.Error("Failed to compute commitment", "err", err)
 		return common.Hash{}, common.Hash{}, err
 	}
+	if (commitment != common.Hash{}) {
+		rawdb.WriteRandomCommitmentCache(sb.db, commitment, parentHash)
+	}
 
 	return randomness, commitment, nil
 }
+
+// recoverRandomCommitment makes sure the parent hash of the last randomness
+// commitment of this validator is in the randomness commitment cache, so that
+// its next proposal reveals the committed randomness. If it isn't, the blocks
+// of the current epoch are searched for the last one authored by this
+// validator, whose parent the commitment was generated from.
+func (sb *Backend) recoverRandomCommitment() error {
+	if sb.currentBlock == nil || sb.stateAt == nil {
+		return nil
+	}
+	head := sb.currentBlock()
+	if head == nil || head.NumberU64() == 0 {
+		return nil
+	}
+	state, err := sb.stateAt(head.Hash())
+	if err != nil {
+		return err
+	}
+	vmRunner := sb.chain.NewEVMRunner(head.Header(), state)
+	if !random.IsRunning(vmRunner) {
+		return nil
+	}
+	validator := sb.ValidatorAddress()
+	commitment, err := random.GetLastCommitment(vmRunner, validator)
+	if err != nil {
+		return err
+	}
+	if (commitment == common.Hash{}) || (rawdb.ReadRandomCommitmentCache(sb.db, commitment) != common.Hash{}) {
+		return nil
+	}
+	sb.logger.Info("Recovering randomness commitment", "commitment", commitment)
+
+	first := istanbul.MustGetEpochFirstBlockGivenBlockNumber(head.NumberU64(), sb.EpochSize())
+	for header := head.Header(); header != nil && header.Number.Uint64() >= first; header = sb.chain.GetHeader(header.ParentHash, header.Number.Uint64()-1) {
+		if author, err := sb.Author(header); err != nil || author != validator {
+			continue
+		}
+		// Regenerating the commitment writes it to the cache
+		_, generated, err := sb.GenerateRandomness(header.ParentHash)
+		if err != nil {
+			return err
+		}
+		if generated == commitment {
+			return nil
+		}
+	}
+	return errors.New("randomness commitment not found in the current epoch")
+}
+
+// VerifyRandomness checks that revealed is the randomness committed to by
+// commitment, computing the commitment with the random contract at the state of
+// the block with the given parent hash. A zero commitment, made before the
+// first reveal of a validator, must come with zero randomness.
+//
+// If the commitment was made by this node, the randomness is also regenerated
+// from the local seed and the parent hash the randomness commitment cache
+// records for it.
+func (sb *Backend) VerifyRandomness(parentHash common.Hash, commitment, revealed common.Hash) error {
+	logger := sb.logger.New("func", "VerifyRandomness", "parent", parentHash)
+
+	header := sb.chain.GetHeaderByHash(parentHash)
+	if header == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	state, err := sb.stateAt(parentHash)
+	if err != nil {
+		return err
+	}
+	vmRunner := sb.chain.NewEVMRunner(header, state)
+	if !random.IsRunning(vmRunner) {
+		return nil
+	}
+
+	if (commitment == common.Hash{}) {
+		if (revealed != common.Hash{}) {
+			return errInvalidRandomness
+		}
+		return nil
+	}
+	computed, err := random.ComputeCommitment(vmRunner, revealed)
+	if err != nil {
+		return err
+	}
+	if computed != commitment {
+		logger.Debug("Randomness commitment mismatch", "commitment", commitment, "computed", computed)
+		return errInvalidRandomness
+	}
+
+	if committedParent := rawdb.ReadRandomCommitmentCache(sb.db, commitment); (committedParent != common.Hash{}) {
+		randomness, _, err := sb.GenerateRandomness(committedParent)
+		if err != nil {
+			return err
+		}
+		if randomness != revealed {
+			logger.Debug("Revealed randomness differs from the regenerated one", "commitment", commitment, "committedParent", committedParent)
+			return errInvalidRandomness
+		}
+	}
+	return nil
+}
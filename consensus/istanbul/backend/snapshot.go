@@ -18,12 +18,14 @@ package backend
 
 import (
 	"encoding/json"
+	"errors"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/mapprotocol/atlas/consensus/istanbul"
 	"github.com/mapprotocol/atlas/consensus/istanbul/validator"
+	"github.com/mapprotocol/atlas/core/rawdb"
 	"github.com/mapprotocol/atlas/core/types"
 )
 
@@ -53,16 +55,30 @@ func newSnapshot(epoch uint64, number uint64, hash common.Hash, valSet istanbul.
 	return snap
 }
 
-// loadSnapshot loads an existing snapshot from the database.
-func loadSnapshot(epoch uint64, db ethdb.Database, hash common.Hash) (*Snapshot, error) {
-	blob, err := db.Get(append([]byte(dbKeySnapshotPrefix), hash[:]...))
-	if err != nil {
-		return nil, err
+// errSnapshotNotFound is returned by loadSnapshot if no snapshot of the given
+// block is stored.
+var errSnapshotNotFound = errors.New("snapshot not found")
+
+// loadSnapshot loads an existing snapshot of the given epoch block from the
+// database. Snapshots stored by hash by earlier versions are still found. A
+// corrupted snapshot is reported as an error, so it gets recomputed.
+func loadSnapshot(epoch uint64, db ethdb.Database, number uint64, hash common.Hash) (*Snapshot, error) {
+	blob := rawdb.ReadIstanbulSnapshot(db, istanbul.GetEpochNumber(number, epoch))
+	if len(blob) == 0 {
+		blob, _ = db.Get(append([]byte(dbKeySnapshotPrefix), hash[:]...))
+	}
+	if len(blob) == 0 {
+		return nil, errSnapshotNotFound
 	}
 	snap := new(Snapshot)
 	if err := json.Unmarshal(blob, snap); err != nil {
+		log.Warn("Discarding corrupted validator set snapshot", "number", number, "hash", hash, "err", err)
 		return nil, err
 	}
+	// The snapshot of the epoch may belong to another fork
+	if snap.Hash != hash {
+		return nil, errSnapshotNotFound
+	}
 
 	if !snap.ValSet.HasBLSKeyCache() {
 		log.Debug("Updating outdated snapshot", "hash", hash)
@@ -83,7 +99,8 @@ func (s *Snapshot) store(db ethdb.Database) error {
 	if err != nil {
 		return err
 	}
-	return db.Put(append([]byte(dbKeySnapshotPrefix), s.Hash[:]...), blob)
+	rawdb.WriteIstanbulSnapshot(db, istanbul.GetEpochNumber(s.Number, s.Epoch), blob)
+	return nil
 }
 
 // copy creates a deep copy of the snapshot, though not the individual votes.
@@ -136,7 +153,7 @@ func (s *Snapshot) apply(headers []*types.Header, db ethdb.Database) (*Snapshot,
 			return nil, err
 		}
 
-		validators, err := istanbul.CombineIstanbulExtraToValidatorData(istExtra.AddedValidators, istExtra.AddedValidatorsPublicKeys, istExtra.AddedValidatorsG1PublicKeys)
+		validators, err := istanbul.AddedValidatorsOfExtra(istExtra, snap.validators())
 		if err != nil {
 			log.Error("Error in combining addresses and public keys")
 			return nil, errInvalidValidatorSetDiff
@@ -235,7 +235,7 @@ func TestValSetChange(t *testing.T) {
 		genesis.ExtraData = append(make([]byte, types.IstanbulExtraVanity), extra...)
 		b := genesis.ToBlock(nil)
 		h := b.Header()
-		err := writeValidatorSetDiff(h, []istanbul.ValidatorData{}, validators)
+		err := writeValidatorSetDiff(h, []istanbul.ValidatorData{}, validators, false)
 		if err != nil {
 			t.Errorf("Could not update genesis validator set, got err: %v", err)
 		}
@@ -371,7 +371,7 @@ func TestSaveAndLoad(t *testing.T) {
 		t.Errorf("store snapshot failed: %v", err)
 	}
 
-	snap1, err := loadSnapshot(snap.Epoch, db, snap.Hash)
+	snap1, err := loadSnapshot(snap.Epoch, db, snap.Number, snap.Hash)
 	if err != nil {
 		t.Errorf("load snapshot failed: %v", err)
 	}
@@ -385,3 +385,113 @@ func TestSaveAndLoad(t *testing.T) {
 		t.Errorf("validator set mismatch: have %v, want %v", snap1.ValSet, snap.ValSet)
 	}
 }
+
+func TestLoadInvalidSnapshot(t *testing.T) {
+	snap := &Snapshot{
+		Epoch:  5,
+		Number: 10,
+		Hash:   common.HexToHash("1234567890"),
+		ValSet: validator.NewSet([]istanbul.ValidatorData{
+			{Address: common.BytesToAddress([]byte("1234567894"))},
+		}),
+	}
+	db := rawdb.NewMemoryDatabase()
+	if err := snap.store(db); err != nil {
+		t.Fatalf("store snapshot failed: %v", err)
+	}
+	// A snapshot of the same epoch on another fork must not be used
+	if _, err := loadSnapshot(snap.Epoch, db, snap.Number, common.HexToHash("0987654321")); err != errSnapshotNotFound {
+		t.Errorf("snapshot of another fork: have %v, want %v", err, errSnapshotNotFound)
+	}
+	// Snapshots stored by hash are still found
+	blob := rawdb.ReadIstanbulSnapshot(db, 2)
+	rawdb.DeleteIstanbulSnapshot(db, 2)
+	if _, err := loadSnapshot(snap.Epoch, db, snap.Number, snap.Hash); err != errSnapshotNotFound {
+		t.Errorf("missing snapshot: have %v, want %v", err, errSnapshotNotFound)
+	}
+	db.Put(append([]byte(dbKeySnapshotPrefix), snap.Hash[:]...), blob)
+	if _, err := loadSnapshot(snap.Epoch, db, snap.Number, snap.Hash); err != nil {
+		t.Errorf("legacy snapshot: have %v, want nil", err)
+	}
+	// Corrupted snapshots are reported, not loaded
+	rawdb.WriteIstanbulSnapshot(db, 2, blob[:len(blob)/2])
+	if _, err := loadSnapshot(snap.Epoch, db, snap.Number, snap.Hash); err == nil {
+		t.Errorf("corrupted snapshot loaded")
+	}
+}
+
+func TestSnapshotRecomputedIfCorrupted(t *testing.T) {
+	chain, engine := newBlockChain(1, true)
+	defer chain.Stop()
+
+	genesis := chain.Genesis()
+	want, err := engine.snapshot(chain, 0, genesis.Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to create snapshot: %v", err)
+	}
+	rawdb.WriteIstanbulSnapshot(engine.db, 0, []byte("{corrupted"))
+	engine.recentSnapshots.Purge()
+
+	have, err := engine.snapshot(chain, 0, genesis.Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to recompute snapshot: %v", err)
+	}
+	if have.ValSet.Size() != want.ValSet.Size() {
+		t.Errorf("validator set size mismatch: have %d, want %d", have.ValSet.Size(), want.ValSet.Size())
+	}
+	if _, err := loadSnapshot(engine.config.Epoch, engine.db, 0, genesis.Hash()); err != nil {
+		t.Errorf("recomputed snapshot not stored: %v", err)
+	}
+}
+
+// Tests that the validator set is rebuilt across a compact validator set diff
+// from the headers alone, without any state.
+func TestSnapshotApplyCompactValSetDiff(t *testing.T) {
+	accounts := newTesterAccountPool()
+	validatorData := func(name string, seed byte) istanbul.ValidatorData {
+		return istanbul.ValidatorData{
+			Address:        accounts.address(name),
+			BLSPublicKey:   bls.SerializedPublicKey{seed},
+			BLSG1PublicKey: bls.SerializedG1PublicKey{seed},
+		}
+	}
+	parent := []istanbul.ValidatorData{validatorData("A", 1), validatorData("B", 2), validatorData("C", 3)}
+	// D takes over the keys of B, E joins with keys of its own
+	moved := validatorData("D", 2)
+	next := []istanbul.ValidatorData{parent[0], parent[2], moved, validatorData("E", 4)}
+
+	added, removed := istanbul.ValidatorSetDiff(parent, next)
+	extra := &types.IstanbulExtra{
+		CompactAddedValidators: istanbul.CompactValidatorSetDiff(parent, added),
+		RemovedValidators:      removed,
+		AggregatedSeal:         types.IstanbulAggregatedSeal{},
+		ParentAggregatedSeal:   types.IstanbulAggregatedSeal{},
+	}
+	if extra.CompactAddedValidators[0].KeyIndex != 2 || len(extra.CompactAddedValidators[1].BLSPublicKey) != bls.PUBLICKEYBYTES {
+		t.Fatalf("compact diff mismatch: %+v", extra.CompactAddedValidators)
+	}
+	payload, err := rlp.EncodeToBytes(extra)
+	if err != nil {
+		t.Fatal(err)
+	}
+	epoch := uint64(5)
+	header := &types.Header{
+		Number: new(big.Int).SetUint64(epoch),
+		Extra:  append(make([]byte, types.IstanbulExtraVanity), payload...),
+	}
+	accounts.sign(header, "A")
+
+	snap := newSnapshot(epoch, 0, common.Hash{}, validator.NewSet(parent))
+	db := rawdb.NewMemoryDatabase()
+	defer db.Close()
+	snap, err = snap.apply([]*types.Header{header}, db)
+	if err != nil {
+		t.Fatalf("failed to apply compact diff: %v", err)
+	}
+	result := snap.validators()
+	sort.Sort(istanbul.ValidatorsDataByAddress(result))
+	sort.Sort(istanbul.ValidatorsDataByAddress(next))
+	if !reflect.DeepEqual(result, next) {
+		t.Errorf("validators mismatch: have %v, want %v", result, next)
+	}
+}
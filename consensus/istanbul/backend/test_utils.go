@@ -37,6 +37,15 @@ func newBlockChain(n int, isFullChain bool) (*chain.BlockChain, *Backend) {
 	return bc, be
 }
 
+// newBlockChainWithEpoch is newBlockChain with the given epoch size.
+func newBlockChainWithEpoch(n int, epoch uint64) (*chain.BlockChain, *Backend) {
+	genesis, nodeKeys := getGenesisAndKeys(n, true)
+	genesis.Config.Istanbul.Epoch = epoch
+
+	bc, be, _ := newBlockChainWithKeys(false, common.Address{}, false, genesis, nodeKeys[0])
+	return bc, be
+}
+
 func newBlockChainWithKeys(isProxy bool, proxiedValAddress common.Address, isProxied bool, genesis *chain.Genesis, privateKey *ecdsa.PrivateKey) (*chain.BlockChain, *Backend, *istanbul.Config) {
 	memDB := rawdb.NewMemoryDatabase()
 	config := *istanbul.DefaultConfig
@@ -201,7 +210,7 @@ func makeBlock(keys []*ecdsa.PrivateKey, chain *chain.BlockChain, engine *Backen
 	defer sub.Unsubscribe()
 
 	// start seal request (this is non-blocking)
-	err := engine.Seal(chain, block)
+	err := engine.Seal(chain, block, nil)
 	if err != nil {
 		return nil, err
 	}
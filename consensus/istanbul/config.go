@@ -18,6 +18,7 @@ package istanbul
 
 import (
 	"fmt"
+	"math/big"
 
 	params2 "github.com/mapprotocol/atlas/params"
 
@@ -28,6 +29,11 @@ import (
 const (
 	//MinEpochSize represents the minimum permissible epoch size
 	MinEpochSize = 3
+
+	// DefaultMaxHeaderExtraSize is the largest header extra-data accepted from
+	// the compact validator set diff fork on when the chain config doesn't set
+	// one, as some tooling fails on headers over 64 KiB.
+	DefaultMaxHeaderExtraSize = 64 * 1024
 )
 
 // ProposerPolicy represents the policy used to order elected validators within an epoch
@@ -41,20 +47,24 @@ const (
 
 // Config represents the istanbul consensus engine
 type Config struct {
-	RequestTimeout              uint64         `toml:",omitempty"` // The timeout for each Istanbul round in milliseconds.
-	TimeoutBackoffFactor        uint64         `toml:",omitempty"` // Timeout at subsequent rounds is: RequestTimeout + 2**round * TimeoutBackoffFactor (in milliseconds)
-	MinResendRoundChangeTimeout uint64         `toml:",omitempty"` // Minimum interval with which to resend RoundChange messages for same round
-	MaxResendRoundChangeTimeout uint64         `toml:",omitempty"` // Maximum interval with which to resend RoundChange messages for same round
-	BlockPeriod                 uint64         `toml:",omitempty"` // Default minimum difference between two consecutive block's timestamps in second
-	ProposerPolicy              ProposerPolicy `toml:",omitempty"` // The policy for proposer selection
-	Epoch                       uint64         `toml:",omitempty"` // The number of blocks after which to checkpoint and reset the pending votes
-	DefaultLookbackWindow       uint64         `toml:",omitempty"` // The default value for how many blocks in a row a validator must miss to be considered "down"
-	ReplicaStateDBPath          string         `toml:",omitempty"` // The location for the validator replica state DB
-	ValidatorEnodeDBPath        string         `toml:",omitempty"` // The location for the validator enodes DB
-	VersionCertificateDBPath    string         `toml:",omitempty"` // The location for the signed announce version DB
-	RoundStateDBPath            string         `toml:",omitempty"` // The location for the round states DB
-	Validator                   bool           `toml:",omitempty"` // Specified if this node is configured to validate  (specifically if --mine command line is set)
-	Replica                     bool           `toml:",omitempty"` // Specified if this node is configured to be a replica
+	RequestTimeout              uint64                         `toml:",omitempty"` // The timeout for each Istanbul round in milliseconds.
+	TimeoutBackoffFactor        uint64                         `toml:",omitempty"` // Timeout at subsequent rounds is: RequestTimeout + 2**round * TimeoutBackoffFactor (in milliseconds)
+	MinResendRoundChangeTimeout uint64                         `toml:",omitempty"` // Minimum interval with which to resend RoundChange messages for same round
+	MaxResendRoundChangeTimeout uint64                         `toml:",omitempty"` // Maximum interval with which to resend RoundChange messages for same round
+	MaxFutureSequences          uint64                         `toml:",omitempty"` // Consensus messages more than this many sequences ahead of the current one are rejected
+	BlockPeriod                 uint64                         `toml:",omitempty"` // Default minimum difference between two consecutive block's timestamps in second
+	ProposerPolicy              ProposerPolicy                 `toml:",omitempty"` // The policy for proposer selection
+	Epoch                       uint64                         `toml:",omitempty"` // The number of blocks after which to checkpoint and reset the pending votes
+	DefaultLookbackWindow       uint64                         `toml:",omitempty"` // The default value for how many blocks in a row a validator must miss to be considered "down"
+	LookbackWindowChanges       []params2.LookbackWindowChange `toml:",omitempty"` // Lookback windows replacing DefaultLookbackWindow from their activation block on
+	CompactValSetDiffBlock      *big.Int                       `toml:",omitempty"` // Block from which the validator set diffs use the compact encoding (nil = never)
+	MaxHeaderExtraSize          uint64                         `toml:",omitempty"` // Size in bytes above which the extra-data of a header from CompactValSetDiffBlock on is rejected
+	ReplicaStateDBPath          string                         `toml:",omitempty"` // The location for the validator replica state DB
+	ValidatorEnodeDBPath        string                         `toml:",omitempty"` // The location for the validator enodes DB
+	VersionCertificateDBPath    string                         `toml:",omitempty"` // The location for the signed announce version DB
+	RoundStateDBPath            string                         `toml:",omitempty"` // The location for the round states DB
+	Validator                   bool                           `toml:",omitempty"` // Specified if this node is configured to validate  (specifically if --mine command line is set)
+	Replica                     bool                           `toml:",omitempty"` // Specified if this node is configured to be a replica
 
 	// Proxy Configs
 	Proxy                   bool           `toml:",omitempty"` // Specifies if this node is a proxy
@@ -85,10 +95,12 @@ var DefaultConfig = &Config{
 	TimeoutBackoffFactor:           1000,
 	MinResendRoundChangeTimeout:    15 * 1000,
 	MaxResendRoundChangeTimeout:    2 * 60 * 1000,
+	MaxFutureSequences:             10,
 	BlockPeriod:                    5,
 	ProposerPolicy:                 ShuffledRoundRobin,
 	Epoch:                          30000,
 	DefaultLookbackWindow:          12,
+	MaxHeaderExtraSize:             DefaultMaxHeaderExtraSize,
 	ReplicaStateDBPath:             "",
 	ValidatorEnodeDBPath:           "",
 	VersionCertificateDBPath:       "",
@@ -103,7 +115,7 @@ var DefaultConfig = &Config{
 	LoadTestCSVFile:                                "", // disable by default
 }
 
-//ApplyParamsChainConfigToConfig applies the istanbul config values from params.chainConfig to the istanbul.Config config
+// ApplyParamsChainConfigToConfig applies the istanbul config values from params.chainConfig to the istanbul.Config config
 func ApplyParamsChainConfigToConfig(chainConfig *params2.ChainConfig, config *Config) error {
 	if chainConfig.Istanbul.Epoch != 0 {
 		if chainConfig.Istanbul.Epoch < MinEpochSize {
@@ -124,7 +136,21 @@ func ApplyParamsChainConfigToConfig(chainConfig *params2.ChainConfig, config *Co
 	if chainConfig.Istanbul.LookbackWindow >= chainConfig.Istanbul.Epoch-2 {
 		return fmt.Errorf("istanbul.lookbackwindow must be less than istanbul.epoch-2")
 	}
+	if err := chainConfig.Istanbul.CheckLookbackWindows(); err != nil {
+		return err
+	}
+	config.LookbackWindowChanges = chainConfig.Istanbul.LookbackWindowChanges
+	config.CompactValSetDiffBlock = chainConfig.Istanbul.CompactValSetDiffBlock
+	if chainConfig.Istanbul.MaxHeaderExtraSize != 0 {
+		config.MaxHeaderExtraSize = chainConfig.Istanbul.MaxHeaderExtraSize
+	}
 	config.ProposerPolicy = ProposerPolicy(chainConfig.Istanbul.ProposerPolicy)
 
 	return nil
 }
+
+// IsCompactValSetDiff returns whether the validator set diff of the header of
+// the given block uses the compact encoding.
+func (c *Config) IsCompactValSetDiff(number *big.Int) bool {
+	return c.CompactValSetDiffBlock != nil && c.CompactValSetDiffBlock.Cmp(number) <= 0
+}
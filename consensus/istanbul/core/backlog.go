@@ -17,6 +17,7 @@
 package core
 
 import (
+	"container/list"
 	"fmt"
 	"math/big"
 	"sort"
@@ -25,6 +26,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/prque"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/mapprotocol/atlas/consensus/istanbul"
 )
 
@@ -37,11 +39,14 @@ var (
 		istanbul.MsgPrepare:    3,
 	}
 
-	// Do not accept messages for views more than this many sequences in the future.
-	acceptMaxFutureSequence             = big.NewInt(10)
-	acceptMaxFutureMsgsFromOneValidator = 1000
-	acceptMaxFutureMessages             = 10 * 1000
-	acceptMaxFutureMessagesPruneBatch   = 100
+	// Do not accept messages for views more than this many sequences in the future,
+	// unless configured otherwise with istanbul.Config.MaxFutureSequences.
+	acceptMaxFutureSequence             uint64 = 10
+	acceptMaxFutureMsgsFromOneValidator        = 1000
+	acceptMaxFutureMessages                    = 10 * 1000
+
+	backlogDroppedMeter = metrics.NewRegisteredMeter("consensus/istanbul/core/backlog/dropped", nil) // future messages rejected by the backlog
+	backlogEvictedMeter = metrics.NewRegisteredMeter("consensus/istanbul/core/backlog/evicted", nil) // stored messages evicted to honour the backlog caps
 )
 
 // checkMessage checks the message state
@@ -106,11 +111,25 @@ type MsgBacklog interface {
 	updateState(view *istanbul.View, state State)
 }
 
+// backlogEntry keeps track of a message stored in the backlog, to evict it when
+// the backlog is full
+type backlogEntry struct {
+	seq     uint64
+	index   int           // index of the message in the priority queue of its sequence
+	srcElem *list.Element // element of the message in msgsBySrc, oldest first
+	ageElem *list.Element // element of the message in msgsByAge, oldest first
+}
+
 type msgBacklogImpl struct {
 	backlogBySeq  map[uint64]*prque.Prque
 	msgCountBySrc map[common.Address]int
 	msgCount      int
 
+	entries           map[*istanbul.Message]*backlogEntry
+	msgsBySrc         map[common.Address]*list.List
+	msgsByAge         *list.List
+	maxFutureSequence *big.Int
+
 	currentView  *istanbul.View
 	currentState State
 
@@ -120,7 +139,7 @@ type msgBacklogImpl struct {
 	logger       log.Logger
 }
 
-func newMsgBacklog(msgProcessor func(*istanbul.Message), checkMessage func(msgCode uint64, msgView *istanbul.View) error) MsgBacklog {
+func newMsgBacklog(msgProcessor func(*istanbul.Message), checkMessage func(msgCode uint64, msgView *istanbul.View) error, maxFutureSequence uint64) MsgBacklog {
 	initialView := &istanbul.View{
 		Round:    big.NewInt(0),
 		Sequence: big.NewInt(1),
@@ -131,6 +150,11 @@ func newMsgBacklog(msgProcessor func(*istanbul.Message), checkMessage func(msgCo
 		msgCountBySrc: make(map[common.Address]int),
 		msgCount:      0,
 
+		entries:           make(map[*istanbul.Message]*backlogEntry),
+		msgsBySrc:         make(map[common.Address]*list.List),
+		msgsByAge:         list.New(),
+		maxFutureSequence: new(big.Int).SetUint64(maxFutureSequence),
+
 		currentView:  initialView,
 		currentState: StateAcceptRequest,
 
@@ -150,55 +174,107 @@ func (c *msgBacklogImpl) store(msg *istanbul.Message) {
 	defer c.backlogsMu.Unlock()
 
 	// Never accept messages too far into the future
-	if view.Sequence.Cmp(new(big.Int).Add(c.currentView.Sequence, acceptMaxFutureSequence)) > 0 {
+	if view.Sequence.Cmp(new(big.Int).Add(c.currentView.Sequence, c.maxFutureSequence)) > 0 {
 		logger.Debug("Dropping message", "reason", "too far in the future", "m", msg)
+		backlogDroppedMeter.Mark(1)
 		return
 	}
 
 	if view.Round.Cmp(maxRoundForPriorityQueue) >= 0 {
 		logger.Debug("Dropping message", "reason", "round exceeds PQ bounds check", "m", msg)
-		return
-	}
-
-	// Check and inc per-validator future message limit
-	if c.msgCountBySrc[msg.Address] > acceptMaxFutureMsgsFromOneValidator {
-		logger.Debug("Dropping message", "reason", "exceeds per-address cap")
+		backlogDroppedMeter.Mark(1)
 		return
 	}
 
 	logger.Trace("Store future message", "m", msg, "m_seq", view.Sequence, "m_round", view.Round)
-	c.msgCountBySrc[msg.Address]++
-	c.msgCount++
+	c.track(msg, view.Sequence.Uint64())
 
 	// Add message to per-seq list
 	backlogForSeq := c.backlogBySeq[view.Sequence.Uint64()]
 	if backlogForSeq == nil {
-		backlogForSeq = prque.New(nil)
+		backlogForSeq = prque.New(c.setIndex)
 		c.backlogBySeq[view.Sequence.Uint64()] = backlogForSeq
 	}
 
 	backlogForSeq.Push(msg, toPriority(msg.Code, view))
 
-	// After insert, remove messages if we have more than "acceptMaxFutureMessages"
-	c.removeMessagesOverflow()
+	// After insert, evict the oldest messages beyond the per-validator and total caps
+	c.removeMessagesOverflow(msg.Address)
 }
 
-// removeMessagesOverflow will remove messages if necessary to maintain the number of messages <= acceptMaxFutureMessages
-// For that, it will remove messages that further on the future
-func (c *msgBacklogImpl) removeMessagesOverflow() {
-	// Keep backlog below total max size by pruning future-most sequence first
-	// (we always leave one sequence's entire messages and rely on per-validator limits)
+// removeMessagesOverflow evicts the oldest message of src if it has more than acceptMaxFutureMsgsFromOneValidator
+// messages, then the oldest message if the backlog has more than acceptMaxFutureMessages.
+// Evicting the oldest messages rather than rejecting new ones keeps the backlog useful when a peer floods it.
+func (c *msgBacklogImpl) removeMessagesOverflow(src common.Address) {
+	if c.msgCountBySrc[src] > acceptMaxFutureMsgsFromOneValidator {
+		c.logger.Debug("Evicting message", "reason", "exceeds per-address cap", "from", src)
+		c.evict(c.msgsBySrc[src].Front().Value.(*istanbul.Message))
+	}
 	if c.msgCount > acceptMaxFutureMessages {
-		backlogSeqs := c.getSortedBacklogSeqs()
-		for i := len(backlogSeqs) - 1; i > 0; i-- {
-			seq := backlogSeqs[i]
-			if seq <= c.currentView.Sequence.Uint64() ||
-				c.msgCount < (acceptMaxFutureMessages-acceptMaxFutureMessagesPruneBatch) {
-				break
-			}
-			c.clearBacklogForSeq(seq)
+		c.logger.Debug("Evicting message", "reason", "exceeds total cap")
+		c.evict(c.msgsByAge.Front().Value.(*istanbul.Message))
+	}
+}
+
+// track records a message about to be stored in the backlog of seq.
+// Call with backlogsMu held.
+func (c *msgBacklogImpl) track(msg *istanbul.Message, seq uint64) {
+	msgsFromSrc := c.msgsBySrc[msg.Address]
+	if msgsFromSrc == nil {
+		msgsFromSrc = list.New()
+		c.msgsBySrc[msg.Address] = msgsFromSrc
+	}
+	c.entries[msg] = &backlogEntry{
+		seq:     seq,
+		srcElem: msgsFromSrc.PushBack(msg),
+		ageElem: c.msgsByAge.PushBack(msg),
+	}
+	c.msgCountBySrc[msg.Address]++
+	c.msgCount++
+}
+
+// untrack forgets a message popped from the backlog.
+// Call with backlogsMu held.
+func (c *msgBacklogImpl) untrack(msg *istanbul.Message) {
+	entry := c.entries[msg]
+	if entry == nil {
+		return
+	}
+	delete(c.entries, msg)
+	c.msgsBySrc[msg.Address].Remove(entry.srcElem)
+	c.msgsByAge.Remove(entry.ageElem)
+
+	c.msgCountBySrc[msg.Address]--
+	if c.msgCountBySrc[msg.Address] == 0 {
+		delete(c.msgCountBySrc, msg.Address)
+		delete(c.msgsBySrc, msg.Address)
+	}
+	c.msgCount--
+}
+
+// evict removes a stored message from the backlog.
+// Call with backlogsMu held.
+func (c *msgBacklogImpl) evict(msg *istanbul.Message) {
+	entry := c.entries[msg]
+	if entry == nil {
+		return
+	}
+	if backlogForSeq := c.backlogBySeq[entry.seq]; backlogForSeq != nil {
+		backlogForSeq.Remove(entry.index)
+		if backlogForSeq.Size() == 0 {
+			delete(c.backlogBySeq, entry.seq)
 		}
 	}
+	c.untrack(msg)
+	backlogEvictedMeter.Mark(1)
+}
+
+// setIndex is the prque index callback, keeping the index of the stored messages
+// in the priority queue of their sequence up to date.
+func (c *msgBacklogImpl) setIndex(data interface{}, index int) {
+	if entry := c.entries[data.(*istanbul.Message)]; entry != nil {
+		entry.index = index
+	}
 }
 
 // Return slice of sequences present in backlog sorted in ascending order
@@ -243,11 +319,7 @@ func (c *msgBacklogImpl) processBacklogForSeq(seq uint64, process func(*istanbul
 			break
 		}
 
-		c.msgCountBySrc[msg.Address]--
-		if c.msgCountBySrc[msg.Address] == 0 {
-			delete(c.msgCountBySrc, msg.Address)
-		}
-		c.msgCount--
+		c.untrack(msg)
 	}
 
 	if backlogForSeq.Size() == 0 {
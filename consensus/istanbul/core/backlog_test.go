@@ -217,6 +217,7 @@ func TestStoreBacklog(t *testing.T) {
 	backlog := newMsgBacklog(
 		func(msg *istanbul.Message) {},
 		func(msgCode uint64, msgView *istanbul.View) error { return nil },
+		acceptMaxFutureSequence,
 	).(*msgBacklogImpl)
 	defer backlog.clearBacklogForSeq(12)
 
@@ -296,6 +297,7 @@ func TestClearBacklogForSequence(t *testing.T) {
 	backlog := newMsgBacklog(
 		func(msg *istanbul.Message) { processed = true },
 		func(msgCode uint64, msgView *istanbul.View) error { return nil },
+		acceptMaxFutureSequence,
 	).(*msgBacklogImpl)
 
 	// The backlog's state is sequence number 1, round 0.  Store future messages with sequence number 2
@@ -340,6 +342,7 @@ func TestProcessFutureBacklog(t *testing.T) {
 	backlog := newMsgBacklog(
 		func(msg *istanbul.Message) {},
 		func(msgCode uint64, msgView *istanbul.View) error { return nil },
+		acceptMaxFutureSequence,
 	).(*msgBacklogImpl)
 	defer backlog.clearBacklogForSeq(12)
 
@@ -440,6 +443,7 @@ func testProcessBacklog(t *testing.T, msg *istanbul.Message) {
 	backlog := newMsgBacklog(
 		registerCall,
 		func(msgCode uint64, msgView *istanbul.View) error { return nil },
+		acceptMaxFutureSequence,
 	).(*msgBacklogImpl)
 	defer backlog.clearBacklogForSeq(12)
 
@@ -465,3 +469,59 @@ func testProcessBacklog(t *testing.T, msg *istanbul.Message) {
 	}
 
 }
+
+func TestBacklogFlood(t *testing.T) {
+	backlog := newMsgBacklog(
+		func(msg *istanbul.Message) {},
+		func(msgCode uint64, msgView *istanbul.View) error { return nil },
+		acceptMaxFutureSequence,
+	).(*msgBacklogImpl)
+
+	prepare := func(seq uint64, src common.Address) *istanbul.Message {
+		return istanbul.NewPrepareMessage(&istanbul.Subject{
+			View:   &istanbul.View{Round: big.NewInt(0), Sequence: new(big.Int).SetUint64(seq)},
+			Digest: common.BytesToHash([]byte("1234567890")),
+		}, src)
+	}
+	checkBounded := func(want int) {
+		t.Helper()
+		stored := 0
+		for _, backlogForSeq := range backlog.backlogBySeq {
+			stored += backlogForSeq.Size()
+		}
+		if backlog.msgCount != want || stored != want || len(backlog.entries) != want || backlog.msgsByAge.Len() != want {
+			t.Fatalf("backlog size mismatch: count %d, stored %d, tracked %d/%d, want %d", backlog.msgCount, stored, len(backlog.entries), backlog.msgsByAge.Len(), want)
+		}
+	}
+
+	// A validator flooding the backlog only evicts its own oldest messages
+	flooder := common.BytesToAddress([]byte("flooder"))
+	first := prepare(2, flooder)
+	backlog.store(first)
+	for i := 0; i < 2*acceptMaxFutureMsgsFromOneValidator; i++ {
+		backlog.store(prepare(2+uint64(i%5), flooder))
+	}
+	checkBounded(acceptMaxFutureMsgsFromOneValidator)
+	if _, ok := backlog.entries[first]; ok {
+		t.Errorf("oldest message of the flooding validator not evicted")
+	}
+
+	// Messages too many sequences ahead are rejected
+	backlog.store(prepare(1+acceptMaxFutureSequence+1, common.BytesToAddress([]byte("other"))))
+	checkBounded(acceptMaxFutureMsgsFromOneValidator)
+
+	// Past the total cap, the oldest messages of all validators are evicted
+	for v := 0; v < 2*acceptMaxFutureMessages/acceptMaxFutureMsgsFromOneValidator; v++ {
+		src := common.BigToAddress(big.NewInt(int64(v + 1)))
+		for i := 0; i < acceptMaxFutureMsgsFromOneValidator; i++ {
+			backlog.store(prepare(3, src))
+		}
+	}
+	checkBounded(acceptMaxFutureMessages)
+	if count, ok := backlog.msgCountBySrc[flooder]; ok {
+		t.Errorf("oldest messages not evicted first: %d messages left from the flooding validator", count)
+	}
+
+	backlog.clearBacklogForSeq(3)
+	checkBounded(0)
+}
@@ -54,14 +54,6 @@ func (c *core) generateEpochValidatorSetData(blockNumber uint64, round uint8, bl
 		return nil, nil, false, errNotLastBlockInEpoch
 	}
 
-	// Serialize the public keys for the validators in the validator set.
-	blsPubKeys := []blscrypto.SerializedPublicKey{}
-	for _, v := range newValSet.List() {
-		blsPubKeys = append(blsPubKeys, v.BLSPublicKey())
-	}
-
-	maxNonSigners := uint32(newValSet.Size() - newValSet.MinQuorumSize())
-
 	// Before the Donut fork, use the snark data encoding with epoch entropy.
 
 	// Retrieve the block hash for the last block of the previous epoch.
@@ -70,16 +62,29 @@ func (c *core) generateEpochValidatorSetData(blockNumber uint64, round uint8, bl
 		return nil, nil, false, errors.New("unknown block")
 	}
 
-	maxNonSigners = maxValidators - uint32(newValSet.MinQuorumSize())
-	message, extraData, err := blscrypto.CryptoType().EncodeEpochSnarkDataCIP22(
+	message, extraData, err := EncodeEpochSnarkData(blockNumber, c.config.Epoch, round, blockHash, parentEpochBlockHash, newValSet)
+	// This is after the Donut hardfork, so signify this uses CIP22.
+	return message, extraData, true, err
+}
+
+// EncodeEpochSnarkData serializes the validator set following the last block
+// of an epoch for the Plumo SNARK circuit. The validators sign the returned
+// message and extra data with the CIP22 composite hasher in their commits.
+func EncodeEpochSnarkData(blockNumber, epochSize uint64, round uint8, blockHash, parentEpochBlockHash common.Hash, newValSet istanbul.ValidatorSet) ([]byte, []byte, error) {
+	// Serialize the public keys for the validators in the validator set.
+	blsPubKeys := []blscrypto.SerializedPublicKey{}
+	for _, v := range newValSet.List() {
+		blsPubKeys = append(blsPubKeys, v.BLSPublicKey())
+	}
+
+	maxNonSigners := maxValidators - uint32(newValSet.MinQuorumSize())
+	return blscrypto.CryptoType().EncodeEpochSnarkDataCIP22(
 		blsPubKeys, maxNonSigners, maxValidators,
-		uint16(istanbul.GetEpochNumber(blockNumber, c.config.Epoch)),
+		uint16(istanbul.GetEpochNumber(blockNumber, epochSize)),
 		round,
 		blscrypto.EpochEntropyFromHash(blockHash),
 		blscrypto.EpochEntropyFromHash(parentEpochBlockHash),
 	)
-	// This is after the Donut hardfork, so signify this uses CIP22.
-	return message, extraData, true, err
 }
 
 func (c *core) broadcastCommit(sub *istanbul.Subject) {
@@ -195,6 +200,7 @@ func (c *core) handleCheckedCommitForCurrentSequence(msg *istanbul.Message, comm
 	if err := c.verifyEpochValidatorSetSeal(commit, c.current.Proposal().Number().Uint64(), newValSet, validator); err != nil {
 		return errInvalidEpochValidatorSetSeal
 	}
+	c.checkDoubleSign(msg)
 
 	// ensure that the commit is in the current proposal
 	if err := c.verifyCommit(commit); err != nil {
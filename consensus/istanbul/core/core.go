@@ -22,6 +22,7 @@ import (
 	"math"
 	"math/big"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -29,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
+	lru "github.com/hashicorp/golang-lru"
 	"github.com/mapprotocol/atlas/consensus"
 	"github.com/mapprotocol/atlas/consensus/istanbul"
 	"github.com/mapprotocol/atlas/consensus/istanbul/validator"
@@ -102,6 +104,10 @@ type CoreBackend interface {
 
 	IsPrimaryForSeq(seq *big.Int) bool
 	UpdateReplicaState(seq *big.Int)
+
+	// ReportDoubleSign records the evidence of a validator signing two
+	// conflicting proposals or commits
+	ReportDoubleSign(evidence *istanbul.DoubleSignEvidence)
 }
 
 type core struct {
@@ -125,17 +131,27 @@ type core struct {
 
 	backlog MsgBacklog
 
+	// Hashes of the messages recently received, to drop replays before verifying their signature
+	recentMessages *lru.Cache
+
 	rsdb      RoundStateDB
 	current   RoundState
 	handlerWg *sync.WaitGroup
 
 	roundChangeSet *roundChangeSet
 
+	// Preprepares and commits seen in the current sequence, to detect double signing
+	doubleSigns doubleSignDetector
+
 	pendingRequests   *prque.Prque
 	pendingRequestsMu *sync.Mutex
 
 	consensusTimestamp time.Time
 
+	// Unix time in nanoseconds at which the last proposal from the proposer of
+	// its round was received, accessed atomically
+	lastProposalTime int64
+
 	// Time from accepting a pre-prepare (after block verifcation) to preparing or committing
 	consensusPrepareTimeGauge metrics.Gauge
 	consensusCommitTimeGauge  metrics.Gauge
@@ -145,6 +161,8 @@ type core struct {
 	handlePrePrepareTimer metrics.Timer
 	handlePrepareTimer    metrics.Timer
 	handleCommitTimer     metrics.Timer
+	// Messages dropped as replays of recently received ones
+	replayedMessagesMeter metrics.Meter
 }
 
 // New creates an Istanbul consensus core
@@ -171,13 +189,19 @@ func New(backend CoreBackend, config *istanbul.Config) Engine {
 		handlePrePrepareTimer:     metrics.NewRegisteredTimer("consensus/istanbul/core/handle_preprepare", nil),
 		handlePrepareTimer:        metrics.NewRegisteredTimer("consensus/istanbul/core/handle_prepare", nil),
 		handleCommitTimer:         metrics.NewRegisteredTimer("consensus/istanbul/core/handle_commit", nil),
+		replayedMessagesMeter:     metrics.NewRegisteredMeter("consensus/istanbul/core/replayed", nil),
+	}
+	c.recentMessages, _ = lru.New(recentMessagesCacheSize)
+	maxFutureSequence := config.MaxFutureSequences
+	if maxFutureSequence == 0 {
+		maxFutureSequence = acceptMaxFutureSequence
 	}
 	msgBacklog := newMsgBacklog(
 		func(msg *istanbul.Message) {
 			c.sendEvent(backlogEvent{
 				msg: msg,
 			})
-		}, c.checkMessage)
+		}, c.checkMessage, maxFutureSequence)
 	c.backlog = msgBacklog
 	c.validateFn = c.checkValidatorSignature
 	//c.logger = istanbul.NewIstLogger(
@@ -207,6 +231,15 @@ func (c *core) CurrentView() *istanbul.View {
 
 func (c *core) CurrentRoundState() RoundState { return c.current }
 
+// LastProposalTime returns when the last proposal from the proposer of its round
+// was received, or the zero time if none was.
+func (c *core) LastProposalTime() time.Time {
+	if t := atomic.LoadInt64(&c.lastProposalTime); t != 0 {
+		return time.Unix(0, t)
+	}
+	return time.Time{}
+}
+
 func (c *core) ParentCommits() MessageSet {
 	if c.current == nil {
 		return nil
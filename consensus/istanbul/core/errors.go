@@ -33,6 +33,9 @@ var (
 	errOldMessage = errors.New("old message")
 	// errInvalidMessage is returned when the message is malformed.
 	errInvalidMessage = errors.New("invalid message")
+	// errAbortedRequest is returned when the request was aborted, e.g. by a
+	// new head, before being handled.
+	errAbortedRequest = errors.New("aborted request")
 	// errInvalidPreparedCertificateProposal is returned when the PREPARED certificate has an invalid proposal.
 	errInvalidPreparedCertificateProposal = errors.New("invalid proposal in PREPARED certificate")
 	// errInvalidPreparedCertificateNumMsgs is returned when the PREPARED certificate has an incorrect number of messages.
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/mapprotocol/atlas/consensus/istanbul"
+)
+
+// doubleSignKey identifies the messages of a validator which must not conflict
+// within a sequence.
+type doubleSignKey struct {
+	address common.Address
+	code    uint64
+	round   uint64
+}
+
+// doubleSignDetector remembers the first preprepare and commit of every
+// validator in each round of a sequence, to detect validators signing a second
+// one for a different proposal.
+type doubleSignDetector struct {
+	sequence *big.Int
+	seen     map[doubleSignKey]*istanbul.Message
+}
+
+// observe records msg, a preprepare or commit with a checked signature, and
+// returns the message of the same kind signed by its sender for another
+// proposal in the same round, if any. Seeing a message of a later sequence
+// forgets the messages of the earlier ones.
+func (d *doubleSignDetector) observe(msg *istanbul.Message) *istanbul.Message {
+	view, digest := istanbul.SignedDigest(msg)
+	if view == nil {
+		return nil
+	}
+	if d.sequence == nil || view.Sequence.Cmp(d.sequence) > 0 {
+		d.sequence = new(big.Int).Set(view.Sequence)
+		d.seen = make(map[doubleSignKey]*istanbul.Message)
+	} else if view.Sequence.Cmp(d.sequence) < 0 {
+		return nil
+	}
+	key := doubleSignKey{address: msg.Address, code: msg.Code, round: view.Round.Uint64()}
+	first, ok := d.seen[key]
+	if !ok {
+		d.seen[key] = msg
+		return nil
+	}
+	if _, firstDigest := istanbul.SignedDigest(first); firstDigest == digest {
+		return nil
+	}
+	return first
+}
+
+// checkDoubleSign reports the sender of msg to the backend if it signed another
+// message of the same kind for a different proposal in the same round.
+func (c *core) checkDoubleSign(msg *istanbul.Message) {
+	first := c.doubleSigns.observe(msg)
+	if first == nil {
+		return
+	}
+	evidence, err := istanbul.NewDoubleSignEvidence(first, msg)
+	if err != nil {
+		c.newLogger("func", "checkDoubleSign").Error("Failed to create double signing evidence", "address", msg.Address, "err", err)
+		return
+	}
+	c.backend.ReportDoubleSign(evidence)
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/mapprotocol/atlas/consensus/istanbul"
+	"github.com/mapprotocol/atlas/core/types"
+)
+
+func TestCheckDoubleSign(t *testing.T) {
+	sys := NewTestSystemWithBackend(1, 0)
+	backend := sys.backends[0]
+	c := backend.engine.(*core)
+
+	view := istanbul.View{Sequence: big.NewInt(4), Round: big.NewInt(0)}
+	commit := func(view istanbul.View, proposal istanbul.Proposal) *istanbul.Message {
+		msg, err := backend.getCommitMessage(view, proposal)
+		if err != nil {
+			t.Fatalf("failed to create commit: %v", err)
+		}
+		return &msg
+	}
+	proposal := func(number int64, time uint64) istanbul.Proposal {
+		return types.NewBlock(&types.Header{Number: big.NewInt(number), Time: time}, nil, nil, nil)
+	}
+	first, second := proposal(4, 0), proposal(4, 1)
+
+	c.checkDoubleSign(commit(view, first))
+	c.checkDoubleSign(commit(view, first))
+	// A commit for another proposal in a later round is no double signing
+	c.checkDoubleSign(commit(istanbul.View{Sequence: big.NewInt(4), Round: big.NewInt(1)}, second))
+	if len(backend.doubleSigns) != 0 {
+		t.Fatalf("double signing reported for consistent commits: %v", backend.doubleSigns)
+	}
+	c.checkDoubleSign(commit(view, second))
+	if len(backend.doubleSigns) != 1 {
+		t.Fatalf("reported double signs mismatch: have %d, want 1", len(backend.doubleSigns))
+	}
+	evidence := backend.doubleSigns[0]
+	if evidence.Validator != backend.address || evidence.Code != istanbul.MsgCommit || evidence.Sequence.Cmp(view.Sequence) != 0 || evidence.Round.Cmp(view.Round) != 0 {
+		t.Fatalf("evidence mismatch: %+v", evidence)
+	}
+	firstMsg, secondMsg, err := evidence.Messages(c.validateFn)
+	if err != nil {
+		t.Fatalf("failed to decode evidence messages: %v", err)
+	}
+	if firstMsg.Commit().Subject.Digest != first.Hash() || secondMsg.Commit().Subject.Digest != second.Hash() {
+		t.Fatalf("evidence digests mismatch: have (%x, %x), want (%x, %x)", firstMsg.Commit().Subject.Digest, secondMsg.Commit().Subject.Digest, first.Hash(), second.Hash())
+	}
+
+	// Commits of a past sequence are forgotten once a later one is seen
+	c.checkDoubleSign(commit(istanbul.View{Sequence: big.NewInt(5), Round: big.NewInt(0)}, proposal(5, 0)))
+	c.checkDoubleSign(commit(view, proposal(4, 2)))
+	if len(backend.doubleSigns) != 1 {
+		t.Fatalf("double signing reported for a past sequence")
+	}
+}
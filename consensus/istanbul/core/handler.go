@@ -21,9 +21,13 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/mapprotocol/atlas/consensus/istanbul"
 )
 
+// The number of hashes of received messages remembered to drop replays
+const recentMessagesCacheSize = 4096
+
 // Start implements core.Engine.Start
 func (c *core) Start() error {
 	roundState, err := c.createRoundState()
@@ -108,14 +112,22 @@ func (c *core) handleEvents() {
 			case istanbul.RequestEvent:
 				r := &istanbul.Request{
 					Proposal: ev.Proposal,
+					Stop:     ev.Stop,
 				}
 				err := c.handleRequest(r)
 				if err == errFutureMessage {
 					c.storeRequestMsg(r)
 				}
 			case istanbul.MessageEvent:
-				if err := c.handleMsg(ev.Payload); err != nil && err != errFutureMessage && err != errOldMessage {
-					logger.Warn("Error in handling istanbul message", "err", err)
+				hash := crypto.Keccak256Hash(ev.Payload)
+				if c.isReplay(hash) {
+					logger.Trace("Dropping replayed istanbul message")
+				} else {
+					err := c.handleMsg(ev.Payload)
+					c.recordMessage(hash, err)
+					if err != nil && err != errFutureMessage && err != errOldMessage {
+						logger.Warn("Error in handling istanbul message", "err", err)
+					}
 				}
 			case backlogEvent:
 				if payload, err := ev.msg.Payload(); err != nil {
@@ -159,6 +171,28 @@ func (c *core) sendEvent(ev interface{}) {
 	c.backend.EventMux().Post(ev)
 }
 
+// isReplay returns whether the payload hash is the one of a message recently
+// handled, so that replays are dropped before decoding the message and checking
+// its signature.  Messages from the backlog are handled again on purpose and
+// must not be checked.
+func (c *core) isReplay(hash common.Hash) bool {
+	if c.recentMessages.Contains(hash) {
+		c.replayedMessagesMeter.Mark(1)
+		return true
+	}
+	return false
+}
+
+// recordMessage records the payload hash of a message once it was handled, or
+// stored in the backlog as a future message, with the given result. Messages
+// failing to be handled are not recorded, so that invalid messages neither
+// evict valid ones from the cache nor get a valid copy of them dropped.
+func (c *core) recordMessage(hash common.Hash, err error) {
+	if err == nil || err == errFutureMessage {
+		c.recentMessages.Add(hash, struct{}{})
+	}
+}
+
 func (c *core) handleMsg(payload []byte) error {
 	logger := c.newLogger("func", "handleMsg")
 
@@ -17,10 +17,12 @@
 package core
 
 import (
+	"errors"
 	"math/big"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -305,3 +307,37 @@ func benchmarkE2EHandlePrepare(b *testing.B, sys *testSystem) {
 		}
 	}
 }
+
+func TestReplayedMessages(t *testing.T) {
+	sys := NewTestSystemWithBackend(1, 0)
+	c := sys.backends[0].engine.(*core)
+
+	payload := func(seq int64) []byte {
+		m := istanbul.NewPrepareMessage(&istanbul.Subject{
+			View:   &istanbul.View{Sequence: big.NewInt(seq), Round: big.NewInt(0)},
+			Digest: common.BytesToHash([]byte("1234567890")),
+		}, sys.backends[0].Address())
+		payload, err := m.Payload()
+		require.NoError(t, err)
+		return payload
+	}
+
+	hash := func(seq int64) common.Hash {
+		return crypto.Keccak256Hash(payload(seq))
+	}
+
+	assert.False(t, c.isReplay(hash(1)), "first message dropped as a replay")
+	c.recordMessage(hash(1), errors.New("invalid message"))
+	assert.False(t, c.isReplay(hash(1)), "message failing to be handled recorded")
+	c.recordMessage(hash(1), nil)
+	assert.True(t, c.isReplay(hash(1)), "replayed message not dropped")
+	c.recordMessage(hash(2), errFutureMessage)
+	assert.True(t, c.isReplay(hash(2)), "replayed future message not dropped")
+	assert.False(t, c.isReplay(hash(3)), "other message dropped as a replay")
+
+	// Flooding with distinct messages keeps the cache bounded
+	for seq := int64(3); seq < 3+2*recentMessagesCacheSize; seq++ {
+		c.recordMessage(hash(seq), nil)
+	}
+	assert.Equal(t, recentMessagesCacheSize, c.recentMessages.Len())
+}
@@ -17,6 +17,7 @@
 package core
 
 import (
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -85,6 +86,8 @@ func (c *core) handlePreprepare(msg *istanbul.Message) error {
 		logger.Warn("Ignore preprepare message from non-proposer", "actual_proposer", proposerForMsgRound.Address())
 		return errNotFromProposer
 	}
+	c.checkDoubleSign(msg)
+	atomic.StoreInt64(&c.lastProposalTime, time.Now().UnixNano())
 
 	// If round > 0, handle the ROUND CHANGE certificate. If round = 0, it should not have a ROUND CHANGE certificate
 	if preprepare.View.Round.Cmp(common.Big0) > 0 {
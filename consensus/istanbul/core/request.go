@@ -30,6 +30,9 @@ func (c *core) handleRequest(request *istanbul.Request) error {
 	if err == errInvalidMessage {
 		logger.Warn("invalid request")
 		return err
+	} else if err == errAbortedRequest {
+		logger.Debug("Dropping aborted request", "number", request.Proposal.Number(), "hash", request.Proposal.Hash())
+		return err
 	} else if err != nil {
 		logger.Warn("unexpected request", "err", err, "number", request.Proposal.Number(), "hash", request.Proposal.Hash())
 		return err
@@ -50,12 +53,16 @@ func (c *core) handleRequest(request *istanbul.Request) error {
 
 // check request state
 // return errInvalidMessage if the message is invalid
+// return errAbortedRequest if the request was aborted
 // return errFutureMessage if the sequence of proposal is larger than current sequence
 // return errOldMessage if the sequence of proposal is smaller than current sequence
 func (c *core) checkRequestMsg(request *istanbul.Request) error {
 	if request == nil || request.Proposal == nil {
 		return errInvalidMessage
 	}
+	if request.Aborted() {
+		return errAbortedRequest
+	}
 
 	if c := c.current.Sequence().Cmp(request.Proposal.Number()); c > 0 {
 		return errOldMessage
@@ -105,6 +112,7 @@ func (c *core) processPendingRequests() {
 
 			go c.sendEvent(istanbul.RequestEvent{
 				Proposal: r.Proposal,
+				Stop:     r.Stop,
 			})
 		} else if err == errFutureMessage {
 			c.logger.Trace("Stop processing request", "number", r.Proposal.Number(), "hash", r.Proposal.Hash())
@@ -137,3 +137,40 @@ func TestStoreRequestMsg(t *testing.T) {
 		t.Error("unexpected timeout occurs")
 	}
 }
+
+func TestHandleAbortedRequest(t *testing.T) {
+	valSet := newTestValidatorSet(4)
+	c := &core{
+		config:  istanbul.DefaultConfig,
+		logger:  log.New("backend", "test", "id", 0),
+		backend: &testSystemBackend{events: new(event.TypeMux)},
+		current: newRoundState(&istanbul.View{
+			Sequence: big.NewInt(1),
+			Round:    big.NewInt(0),
+		}, valSet, valSet.GetByIndex(0)),
+		pendingRequests:   prque.New(nil),
+		pendingRequestsMu: new(sync.Mutex),
+	}
+
+	// A request aborted before the core handled it is never proposed
+	stop := make(chan struct{})
+	close(stop)
+	request := &istanbul.Request{Proposal: makeBlock(1), Stop: stop}
+	if err := c.handleRequest(request); err != errAbortedRequest {
+		t.Errorf("error mismatch: have %v, want %v", err, errAbortedRequest)
+	}
+	if pending := c.current.PendingRequest(); pending != nil {
+		t.Errorf("aborted request became pending: %v", pending.Proposal.Hash())
+	}
+
+	// Nor once stored as a future request
+	c.storeRequestMsg(request)
+	c.subscribeEvents()
+	defer c.unsubscribeEvents()
+	c.processPendingRequests()
+	select {
+	case ev := <-c.events.Chan():
+		t.Errorf("unexpected event for aborted request: %v", ev.Data)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
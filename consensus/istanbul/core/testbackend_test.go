@@ -59,6 +59,7 @@ type testSystemBackend struct {
 
 	committedMsgs []testCommittedMsgs
 	sentMsgs      [][]byte // store the message when Send is called by core
+	doubleSigns   []*istanbul.DoubleSignEvidence
 
 	key     ecdsa.PrivateKey
 	blsKey  []byte
@@ -271,6 +272,10 @@ func (self *testSystemBackend) ParentBlockValidators(proposal istanbul.Proposal)
 
 func (self *testSystemBackend) UpdateReplicaState(seq *big.Int) { /* pass */ }
 
+func (self *testSystemBackend) ReportDoubleSign(evidence *istanbul.DoubleSignEvidence) {
+	self.doubleSigns = append(self.doubleSigns, evidence)
+}
+
 func (self *testSystemBackend) finalizeAndReturnMessage(msg *istanbul.Message) (istanbul.Message, error) {
 	message := new(istanbul.Message)
 	data, err := self.engine.(*core).finalizeMessage(msg)
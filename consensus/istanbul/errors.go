@@ -40,8 +40,13 @@ var (
 	ErrStoppedVPHThread = errors.New("stopped validator peer handler thread")
 	// ErrStartedVPHThread is returned if validator peer handler thread is already started
 	ErrStartedVPHThread = errors.New("started validator peer handler thread")
+	// ErrNoValidatorKey is returned if validating is requested from a node not
+	// configured as a validator or without a validator key
+	ErrNoValidatorKey = errors.New("not configured with a validator key")
 	// ErrValidatorNotProxied is returned if the validator is not configured to be proxied
 	ErrValidatorNotProxied = errors.New("validator not proxied")
 	// ErrInvalidEnodeCertMsgMapOldVersion is returned if a validator sends old enode certificate message
 	ErrInvalidEnodeCertMsgMapOldVersion = errors.New("invalid enode certificate message map because of old version")
+	// ErrNoDoubleSign is returned if two messages are not conflicting messages signed by the same validator
+	ErrNoDoubleSign = errors.New("messages are not double signed")
 )
@@ -16,11 +16,17 @@
 
 package istanbul
 
-import "github.com/ethereum/go-ethereum/p2p/enode"
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
 
 // RequestEvent is posted to propose a proposal
 type RequestEvent struct {
 	Proposal Proposal
+	Stop     <-chan struct{} // closed if the proposal is aborted, nil if it can't be
 }
 
 // MessageEvent is posted for Istanbul engine communication
@@ -37,3 +43,14 @@ type MessageWithPeerIDEvent struct {
 // FinalCommittedEvent is posted when a proposal is committed
 type FinalCommittedEvent struct {
 }
+
+// EpochRewardsProcessed is posted when the last block of an epoch, which
+// distributes the rewards of the epoch, becomes part of the canonical chain
+type EpochRewardsProcessed struct {
+	Epoch             uint64
+	BlockNumber       uint64
+	BlockHash         common.Hash
+	ValidatorPayments map[common.Address]*big.Int // Rewards paid to each validator account
+	TotalVoterRewards *big.Int
+	CommunityFund     *big.Int
+}
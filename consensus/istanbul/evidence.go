@@ -0,0 +1,84 @@
+package istanbul
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// DoubleSignEvidence is a pair of conflicting consensus messages signed by a
+// validator for the same sequence and round: two proposals, or two commits for
+// different proposals. The messages are kept as signed, as required to prove
+// the double signing to the slashing contract.
+type DoubleSignEvidence struct {
+	Validator common.Address `json:"validator"`
+	Code      uint64         `json:"code"`
+	Sequence  *big.Int       `json:"sequence"`
+	Round     *big.Int       `json:"round"`
+	First     hexutil.Bytes  `json:"first"`  // payload of the message seen first
+	Second    hexutil.Bytes  `json:"second"` // payload of the conflicting message
+}
+
+// SignedDigest returns the view of a preprepare or commit message along with
+// the hash of the proposal it signs, or nil for other messages.
+func SignedDigest(msg *Message) (*View, common.Hash) {
+	switch msg.Code {
+	case MsgPreprepare:
+		if preprepare := msg.Preprepare(); preprepare != nil && preprepare.Proposal != nil {
+			return preprepare.View, preprepare.Proposal.Hash()
+		}
+	case MsgCommit:
+		if commit := msg.Commit(); commit != nil && commit.Subject != nil {
+			return commit.Subject.View, commit.Subject.Digest
+		}
+	}
+	return nil, common.Hash{}
+}
+
+// NewDoubleSignEvidence creates the evidence of the sender of first and second
+// signing both, returning ErrNoDoubleSign if they don't conflict.
+func NewDoubleSignEvidence(first, second *Message) (*DoubleSignEvidence, error) {
+	if first.Address != second.Address || first.Code != second.Code {
+		return nil, ErrNoDoubleSign
+	}
+	firstView, firstDigest := SignedDigest(first)
+	secondView, secondDigest := SignedDigest(second)
+	if firstView == nil || secondView == nil || firstView.Cmp(secondView) != 0 || firstDigest == secondDigest {
+		return nil, ErrNoDoubleSign
+	}
+	firstPayload, err := first.Payload()
+	if err != nil {
+		return nil, err
+	}
+	secondPayload, err := second.Payload()
+	if err != nil {
+		return nil, err
+	}
+	return &DoubleSignEvidence{
+		Validator: first.Address,
+		Code:      first.Code,
+		Sequence:  new(big.Int).Set(firstView.Sequence),
+		Round:     new(big.Int).Set(firstView.Round),
+		First:     firstPayload,
+		Second:    secondPayload,
+	}, nil
+}
+
+// Hash returns the hash identifying the evidence.
+func (e *DoubleSignEvidence) Hash() common.Hash {
+	return RLPHash(e)
+}
+
+// Messages decodes the conflicting messages of the evidence, checking their
+// signatures with validateFn if it is set.
+func (e *DoubleSignEvidence) Messages(validateFn func([]byte, []byte) (common.Address, error)) (*Message, *Message, error) {
+	first, second := new(Message), new(Message)
+	if err := first.FromPayload(e.First, validateFn); err != nil {
+		return nil, nil, err
+	}
+	if err := second.FromPayload(e.Second, validateFn); err != nil {
+		return nil, nil, err
+	}
+	return first, second, nil
+}
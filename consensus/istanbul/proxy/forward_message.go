@@ -18,6 +18,7 @@ package proxy
 
 import (
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/mapprotocol/atlas/consensus"
 	"github.com/mapprotocol/atlas/consensus/istanbul"
 	"github.com/mapprotocol/atlas/core/types"
@@ -28,14 +29,37 @@ func (pv *proxiedValidatorEngine) sendForwardMsg(ps *proxySet, destAddresses []c
 
 	logger.Info("Sending forward msg", "ethMsgCode", ethMsgCode, "destAddresses", types.ConvertToStringSlice(destAddresses))
 
+	// Only send each proxy the destinations assigned to it, so that no message is forwarded twice
+	// to a validator.  Unassigned destinations are left to the active proxy.
+	var proxyDestAddresses map[enode.ID][]common.Address
+	if destAddresses != nil {
+		proxyDestAddresses = make(map[enode.ID][]common.Address)
+		valAssignments := ps.getValidatorAssignments(destAddresses, nil)
+		for _, destAddress := range destAddresses {
+			proxy := valAssignments[destAddress]
+			if proxy == nil {
+				proxy = ps.getActiveProxy()
+			}
+			if proxy != nil {
+				proxyDestAddresses[proxy.ID()] = append(proxyDestAddresses[proxy.ID()], destAddress)
+			}
+		}
+	}
+
 	// Send the forward messages to the proxies
 	for _, proxy := range ps.proxiesByID {
 		if proxy.IsPeered() {
+			proxyDests := destAddresses
+			if destAddresses != nil {
+				if proxyDests = proxyDestAddresses[proxy.ID()]; len(proxyDests) == 0 {
+					continue
+				}
+			}
 
 			// Convert the message to a fwdMessage
 			msg := istanbul.NewForwardMessage(&istanbul.ForwardMessage{
 				Code:          ethMsgCode,
-				DestAddresses: destAddresses,
+				DestAddresses: proxyDests,
 				Msg:           payload,
 			}, pv.backend.Address())
 
@@ -17,6 +17,8 @@
 package proxy
 
 import (
+	"github.com/ethereum/go-ethereum/crypto"
+	lru "github.com/hashicorp/golang-lru"
 	"github.com/mapprotocol/atlas/core/types"
 	"github.com/mapprotocol/atlas/p2p"
 	"sync"
@@ -86,6 +88,20 @@ type fwdMsgInfo struct {
 	payload       []byte
 }
 
+const (
+	// The period of the proxies' health check
+	proxyHealthCheckPeriod = 1 * time.Second
+
+	// The time a peered proxy may relay no message while another one does before
+	// it is considered stalled, and its validators and announce duties are moved
+	// to the other proxies.
+	proxyRelayTimeout = 5 * time.Second
+
+	// The number of hashes of consensus messages relayed by the proxies remembered
+	// to drop the ones relayed by several proxies.
+	relayedMsgCacheSize = 1024
+)
+
 type proxiedValidatorEngine struct {
 	config  *istanbul.Config
 	logger  log.Logger
@@ -114,6 +130,10 @@ type proxiedValidatorEngine struct {
 	sendFwdMsgsCh chan *fwdMsgInfo // Used to send a forward message to all of the proxies
 
 	newBlockchainEpoch chan struct{} // Used to notify to the thread that a new blockchain epoch has started
+
+	relays      map[enode.ID]time.Time // Time of the last message relayed by each proxy since the last health check
+	relaysMu    sync.Mutex
+	relayedMsgs *lru.Cache // Hashes of the consensus messages recently relayed by the proxies
 }
 
 // proxiedValThreadOpFunc is a function type to define operations executed with run's local state as parameters.
@@ -142,7 +162,15 @@ func NewProxiedValidatorEngine(backend BackendForProxiedValidatorEngine, config
 		sendEnodeCertsCh:        make(chan map[enode.ID]*istanbul.EnodeCertMsg),
 		sendFwdMsgsCh:           make(chan *fwdMsgInfo),
 		newBlockchainEpoch:      make(chan struct{}),
+
+		relays: make(map[enode.ID]time.Time),
+	}
+
+	relayedMsgs, err := lru.New(relayedMsgCacheSize)
+	if err != nil {
+		return nil, err
 	}
+	pv.relayedMsgs = relayedMsgs
 
 	return pv, nil
 }
@@ -373,6 +401,82 @@ func (pv *proxiedValidatorEngine) NewEpoch() error {
 	return nil
 }
 
+// CheckProxyMsg records that the proxy peer relayed a message, for the proxies'
+// health check, and returns false if it is a consensus message already relayed by
+// another proxy.  This happens when validators are reassigned between proxies, as
+// both may briefly be connected to the same remote validator.
+func (pv *proxiedValidatorEngine) CheckProxyMsg(peerID enode.ID, msgCode uint64, payload []byte) bool {
+	pv.relaysMu.Lock()
+	pv.relays[peerID] = time.Now()
+	pv.relaysMu.Unlock()
+
+	if msgCode != istanbul.ConsensusMsg {
+		return true
+	}
+	if seen, _ := pv.relayedMsgs.ContainsOrAdd(crypto.Keccak256Hash(payload), true); seen {
+		pv.logger.Trace("Dropping consensus message already relayed by another proxy", "proxyID", peerID)
+		return false
+	}
+	return true
+}
+
+// takeRelays returns the time of the last message relayed by each proxy since
+// the previous call.
+func (pv *proxiedValidatorEngine) takeRelays() map[enode.ID]time.Time {
+	pv.relaysMu.Lock()
+	defer pv.relaysMu.Unlock()
+
+	relays := pv.relays
+	pv.relays = make(map[enode.ID]time.Time)
+	return relays
+}
+
+// GetActiveProxy will return the proxy gossiping this node's announce messages, nil if
+// no proxy is peered.
+func (pv *proxiedValidatorEngine) GetActiveProxy() (*Proxy, error) {
+	var activeProxy *Proxy
+
+	if !pv.Running() {
+		return nil, istanbul.ErrStoppedProxiedValidatorEngine
+	}
+
+	select {
+	case pv.proxiedValThreadOpCh <- func(ps *proxySet) {
+		activeProxy = ps.getActiveProxy()
+	}:
+		<-pv.proxiedValThreadOpDoneCh
+
+	case <-pv.quit:
+		return nil, istanbul.ErrStoppedProxiedValidatorEngine
+
+	}
+
+	return activeProxy, nil
+}
+
+// GetProxyStatus will return the connection state and last relayed message time of all
+// the proxies.
+func (pv *proxiedValidatorEngine) GetProxyStatus() ([]*ProxyStatus, error) {
+	var statuses []*ProxyStatus
+
+	if !pv.Running() {
+		return nil, istanbul.ErrStoppedProxiedValidatorEngine
+	}
+
+	select {
+	case pv.proxiedValThreadOpCh <- func(ps *proxySet) {
+		statuses = ps.getProxyStatus()
+	}:
+		<-pv.proxiedValThreadOpDoneCh
+
+	case <-pv.quit:
+		return nil, istanbul.ErrStoppedProxiedValidatorEngine
+
+	}
+
+	return statuses, nil
+}
+
 // run handles changes to proxies and validator assignments
 func (pv *proxiedValidatorEngine) threadRun() {
 	var (
@@ -383,6 +487,10 @@ func (pv *proxiedValidatorEngine) threadRun() {
 		// The duration of time between thread update, which are occasional check-ins to ensure proxy/validator assignments are as intended
 		schedulerPeriod time.Duration = 30 * time.Second
 
+		// The minimum time a proxy stays stalled before it is assigned validators again,
+		// to check whether it relays their messages.
+		minProxyStalledTime time.Duration = 5 * time.Minute
+
 		// Used to keep track of proxies & validators the proxies are associated with
 		ps *proxySet = newProxySet(newConsistentHashingPolicy())
 	)
@@ -394,6 +502,9 @@ func (pv *proxiedValidatorEngine) threadRun() {
 	schedulerTicker := time.NewTicker(schedulerPeriod)
 	defer schedulerTicker.Stop()
 
+	healthTicker := time.NewTicker(proxyHealthCheckPeriod)
+	defer healthTicker.Stop()
+
 	pv.updateValidatorAssignments(ps)
 
 loop:
@@ -443,6 +554,7 @@ loop:
 				}
 				pv.backend.RemovePeer(proxy.node, p2p.ProxyPurpose)
 			}
+			pv.updateActiveProxy(ps)
 
 		case connectedPeer := <-pv.addProxyPeer:
 			// Proxied peer just connected.
@@ -457,6 +569,7 @@ loop:
 					pv.backend.UpdateAnnounceVersion()
 					pv.sendValEnodeShareMsgs(ps)
 				}
+				pv.updateActiveProxy(ps)
 			}
 
 		case disconnectedPeer := <-pv.removeProxyPeer:
@@ -465,6 +578,7 @@ loop:
 			if ps.getProxy(peerID) != nil {
 				logger.Debug("Disconnected proxy peer", "peerID", peerID, "chan", "removeProxyPeer")
 				ps.removeProxyPeer(peerID)
+				pv.updateActiveProxy(ps)
 			}
 
 		case proxyHandlerOp := <-pv.proxiedValThreadOpCh:
@@ -491,6 +605,18 @@ loop:
 		case fwdMsg := <-pv.sendFwdMsgsCh:
 			pv.sendForwardMsg(ps, fwdMsg.destAddresses, fwdMsg.ethMsgCode, fwdMsg.payload)
 
+		case <-healthTicker.C:
+			// Move the validators and announce duties of proxies which stopped relaying to the
+			// healthy ones.
+			ps.recordRelays(pv.takeRelays())
+			valsReassigned := ps.checkProxyHealth(time.Now(), proxyRelayTimeout)
+			pv.updateActiveProxy(ps)
+			if valsReassigned {
+				logger.Info("Remote validator to proxy assignment has changed.  Sending val enode share messages and updating announce version")
+				pv.backend.UpdateAnnounceVersion()
+				pv.sendValEnodeShareMsgs(ps)
+			}
+
 		case <-schedulerTicker.C:
 			logger.Trace("schedulerTicker ticked")
 
@@ -499,7 +625,9 @@ loop:
 			// network disconnect then a quick reconnect, the validator assignments wouldn't be changed.
 			// If no reassignments were made, then resend all enode certificates and val enode share messages to the
 			// proxies, in case previous attempts failed.
-			if valsReassigned := ps.unassignDisconnectedProxies(minProxyDisconnectTime); valsReassigned {
+			valsReassigned := ps.unassignDisconnectedProxies(minProxyDisconnectTime)
+			valsReassigned = ps.retryStalledProxies(minProxyStalledTime) || valsReassigned
+			if valsReassigned {
 				pv.backend.UpdateAnnounceVersion()
 				pv.sendValEnodeShareMsgs(ps)
 			} else {
@@ -520,6 +648,18 @@ loop:
 	}
 }
 
+// updateActiveProxy elects a new active proxy if the current one is no longer
+// peered or healthy.
+func (pv *proxiedValidatorEngine) updateActiveProxy(ps *proxySet) {
+	if changed := ps.updateActiveProxy(); changed {
+		activeProxy := "nil"
+		if active := ps.getActiveProxy(); active != nil {
+			activeProxy = active.String()
+		}
+		pv.logger.Info("Active proxy changed", "proxy", activeProxy)
+	}
+}
+
 // sendValEnodeShareMsgs sends a ValEnodeShare Message to each proxy to update the proxie's validator enode table.
 // This is a no-op for replica validators.
 func (pv *proxiedValidatorEngine) sendValEnodeShareMsgs(ps *proxySet) {
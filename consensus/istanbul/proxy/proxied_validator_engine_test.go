@@ -25,6 +25,7 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 
 	"github.com/mapprotocol/atlas/consensus/consensustest"
+	"github.com/mapprotocol/atlas/consensus/istanbul"
 	"github.com/mapprotocol/atlas/consensus/istanbul/backend/backendtest"
 	"github.com/mapprotocol/atlas/p2p"
 )
@@ -135,3 +136,30 @@ func TestAddProxy(t *testing.T) {
 		t.Errorf("Proxied validator announce version was not updated.  announceVersion: %d, valBE.GetAnnounceVersion(): %d", announceVersion, valBE.GetAnnounceVersion())
 	}
 }
+
+func TestCheckProxyMsg(t *testing.T) {
+	genesisCfg, nodeKeys := backendtest.GetGenesisAndKeys(1, true)
+	valBEi, _ := backendtest.NewTestBackend(false, common.Address{}, true, genesisCfg, nodeKeys[0])
+	pv := valBEi.(BackendForProxiedValidatorEngine).GetProxiedValidatorEngine().(*proxiedValidatorEngine)
+
+	proxy0ID, proxy1ID := createProxyConfig(0).InternalNode.ID(), createProxyConfig(1).InternalNode.ID()
+	payload := []byte("consensus message")
+
+	if !pv.CheckProxyMsg(proxy0ID, istanbul.ConsensusMsg, payload) {
+		t.Errorf("first relay of a consensus message dropped")
+	}
+	if pv.CheckProxyMsg(proxy1ID, istanbul.ConsensusMsg, payload) {
+		t.Errorf("consensus message relayed by a second proxy not dropped")
+	}
+	if !pv.CheckProxyMsg(proxy1ID, istanbul.EnodeCertificateMsg, payload) || !pv.CheckProxyMsg(proxy1ID, istanbul.EnodeCertificateMsg, payload) {
+		t.Errorf("non consensus message dropped")
+	}
+
+	relays := pv.takeRelays()
+	if _, ok := relays[proxy0ID]; !ok || len(relays) != 2 {
+		t.Errorf("relays mismatch: %v", relays)
+	}
+	if relays := pv.takeRelays(); len(relays) != 0 {
+		t.Errorf("relays not reset: %v", relays)
+	}
+}
@@ -17,6 +17,7 @@
 package proxy
 
 import (
+	"bytes"
 	"github.com/mapprotocol/atlas/core/types"
 	"time"
 
@@ -35,6 +36,7 @@ type proxySet struct {
 	proxiesByID    map[enode.ID]*Proxy // all proxies known by this node, whether or not they are peered
 	valAssignments *valAssignments     // the mappings of proxy<->remote validators
 	valAssigner    assignmentPolicy    // used for assigning peered proxies with remote validators
+	activeProxy    *Proxy              // the peered proxy gossiping this node's announce messages, nil if none is peered
 	logger         log.Logger
 }
 
@@ -84,6 +86,9 @@ func (ps *proxySet) removeProxy(proxyID enode.ID) bool {
 	}
 	valsReassigned := ps.valAssigner.removeProxy(proxy, ps.valAssignments)
 	delete(ps.proxiesByID, proxyID)
+	if ps.activeProxy == proxy {
+		ps.activeProxy = nil
+	}
 	return valsReassigned
 }
 
@@ -97,6 +102,8 @@ func (ps *proxySet) setProxyPeer(proxyID enode.ID, peer consensus.Peer) bool {
 	valsReassigned := false
 	if proxy != nil {
 		proxy.peer = peer
+		proxy.connectTS = time.Now()
+		proxy.stalled = false
 		logger.Trace("Assigning validators to proxy", "proxyID", proxyID)
 		valsReassigned = ps.valAssigner.assignProxy(proxy, ps.valAssignments)
 	}
@@ -113,6 +120,112 @@ func (ps *proxySet) removeProxyPeer(proxyID enode.ID) {
 	}
 }
 
+// recordRelays sets the time of the last message relayed by each of the proxies
+// in relays.
+func (ps *proxySet) recordRelays(relays map[enode.ID]time.Time) {
+	for proxyID, relayTS := range relays {
+		if proxy := ps.getProxy(proxyID); proxy != nil && relayTS.After(proxy.lastMsgTS) {
+			proxy.lastMsgTS = relayTS
+		}
+	}
+}
+
+// checkProxyHealth marks as stalled the peered proxies which relayed no message
+// within timeout while another peered proxy did, and reassigns their validators
+// to the other proxies.  Stalled proxies relaying again get assigned validators
+// back.  If no proxy relayed within timeout, the network is assumed quiet and
+// no proxy is marked stalled.
+// Will return true if any of the validators got reassigned to a different proxy.
+func (ps *proxySet) checkProxyHealth(now time.Time, timeout time.Duration) bool {
+	logger := ps.logger.New("func", "checkProxyHealth")
+
+	anyRelaying := false
+	for _, proxy := range ps.proxiesByID {
+		if proxy.peer != nil && !proxy.stalled && now.Sub(proxy.lastActivityTS()) < timeout {
+			anyRelaying = true
+			break
+		}
+	}
+
+	valsReassigned := false
+	for _, proxy := range ps.proxiesByID {
+		if proxy.peer == nil {
+			continue
+		}
+		idle := now.Sub(proxy.lastActivityTS()) >= timeout
+		if !proxy.stalled && idle && anyRelaying {
+			logger.Warn("Proxy stopped relaying messages, reassigning its validators", "proxy", proxy.String(), "lastMsgTS", proxy.lastMsgTS)
+			proxy.stalled = true
+			proxy.stalledTS = now
+			valsReassigned = ps.valAssigner.removeProxy(proxy, ps.valAssignments) || valsReassigned
+		} else if proxy.stalled && !idle {
+			logger.Info("Proxy is relaying messages again, assigning it validators", "proxy", proxy.String())
+			proxy.stalled = false
+			valsReassigned = ps.valAssigner.assignProxy(proxy, ps.valAssignments) || valsReassigned
+		}
+	}
+
+	return valsReassigned
+}
+
+// retryStalledProxies assigns validators back to the proxies stalled for at
+// least minAge, since a stalled proxy with no validators has nothing to relay
+// and could otherwise never recover.  They are marked stalled again if they
+// still don't relay.
+// Will return true if any of the validators got reassigned to a different proxy.
+func (ps *proxySet) retryStalledProxies(minAge time.Duration) bool {
+	logger := ps.logger.New("func", "retryStalledProxies")
+	valsReassigned := false
+	for _, proxy := range ps.proxiesByID {
+		if proxy.peer != nil && proxy.stalled && time.Since(proxy.stalledTS) >= minAge {
+			logger.Debug("Retrying stalled proxy", "proxy", proxy.String())
+			proxy.stalled = false
+			proxy.connectTS = time.Now()
+			valsReassigned = ps.valAssigner.assignProxy(proxy, ps.valAssignments) || valsReassigned
+		}
+	}
+
+	return valsReassigned
+}
+
+// updateActiveProxy keeps the active proxy while it is peered and not stalled,
+// and otherwise elects the healthy peered proxy which relayed a message last.
+// Will return true if the active proxy changed.
+func (ps *proxySet) updateActiveProxy() bool {
+	if active := ps.activeProxy; active != nil && active.peer != nil && !active.stalled {
+		return false
+	}
+
+	var elected *Proxy
+	for _, proxy := range ps.proxiesByID {
+		if proxy.peer == nil || proxy.stalled {
+			continue
+		}
+		if elected == nil || proxy.lastActivityTS().After(elected.lastActivityTS()) ||
+			(proxy.lastActivityTS().Equal(elected.lastActivityTS()) && bytes.Compare(proxy.ID().Bytes(), elected.ID().Bytes()) < 0) {
+			elected = proxy
+		}
+	}
+
+	changed := elected != ps.activeProxy
+	ps.activeProxy = elected
+	return changed
+}
+
+// getActiveProxy returns the proxy gossiping this node's announce messages
+func (ps *proxySet) getActiveProxy() *Proxy {
+	return ps.activeProxy
+}
+
+// getProxyStatus returns the status of all the proxies in the proxySet
+func (ps *proxySet) getProxyStatus() []*ProxyStatus {
+	statuses := make([]*ProxyStatus, 0, len(ps.proxiesByID))
+	for _, proxy := range ps.proxiesByID {
+		statuses = append(statuses, NewProxyStatus(proxy, proxy == ps.activeProxy))
+	}
+	return statuses
+}
+
 // addRemoteValidators adds remote validators to be assigned by the valAssigner
 func (ps *proxySet) addRemoteValidators(validators []common.Address) bool {
 	ps.logger.Trace("adding remote validators to the proxy set", "validators", types.ConvertToStringSlice(validators))
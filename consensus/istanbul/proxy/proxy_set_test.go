@@ -21,6 +21,7 @@ import (
 	"math/rand"
 	"net"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -348,3 +349,84 @@ func proxyOutput(p *Proxy) string {
 		return p.node.ID().String()
 	}
 }
+
+func TestProxySetHealth(t *testing.T) {
+	proxy0Config := createProxyConfig(0)
+	proxy1Config := createProxyConfig(1)
+	proxy0ID := proxy0Config.InternalNode.ID()
+	proxy1ID := proxy1Config.InternalNode.ID()
+
+	remoteVals := []common.Address{
+		common.BytesToAddress([]byte("32526362351")),
+		common.BytesToAddress([]byte("64362643436")),
+		common.BytesToAddress([]byte("72436452463")),
+		common.BytesToAddress([]byte("46346373463")),
+	}
+
+	ps := newProxySet(newConsistentHashingPolicy())
+	ps.addRemoteValidators(remoteVals)
+	for _, proxyConfig := range []*istanbul.ProxyConfig{proxy0Config, proxy1Config} {
+		ps.addProxy(proxyConfig)
+		ps.setProxyPeer(proxyConfig.InternalNode.ID(), consensustest.NewMockPeer(proxyConfig.InternalNode, p2p.ProxyPurpose))
+	}
+	proxy0, proxy1 := ps.getProxy(proxy0ID), ps.getProxy(proxy1ID)
+
+	now := time.Now()
+	proxy0.connectTS = now.Add(-time.Minute)
+	proxy1.connectTS = now.Add(-time.Minute)
+
+	// A quiet network doesn't stall any proxy
+	if ps.checkProxyHealth(now, proxyRelayTimeout) || proxy0.stalled || proxy1.stalled {
+		t.Fatalf("proxies stalled without any relayed message")
+	}
+	ps.recordRelays(map[enode.ID]time.Time{proxy1ID: now.Add(-2 * time.Second)})
+	ps.updateActiveProxy()
+	if ps.getActiveProxy() != proxy1 {
+		t.Fatalf("active proxy mismatch: have %v, want %v", ps.getActiveProxy(), proxy1)
+	}
+
+	// proxy1 stops relaying while proxy0 relays
+	proxy0RelayTS := now.Add(time.Second)
+	ps.recordRelays(map[enode.ID]time.Time{proxy0ID: proxy0RelayTS})
+	now = now.Add(proxyRelayTimeout)
+	if !ps.checkProxyHealth(now, proxyRelayTimeout) {
+		t.Fatalf("validators of the stalled proxy not reassigned")
+	}
+	if !proxy1.stalled || proxy0.stalled {
+		t.Fatalf("stalled proxies mismatch: proxy0 %v, proxy1 %v", proxy0.stalled, proxy1.stalled)
+	}
+	for val, proxy := range ps.getValidatorAssignments(nil, nil) {
+		if proxy != proxy0 {
+			t.Errorf("validator %v assigned to %v, want %v", val, proxy, proxy0)
+		}
+	}
+	if !ps.updateActiveProxy() || ps.getActiveProxy() != proxy0 {
+		t.Fatalf("active proxy did not fail over: have %v, want %v", ps.getActiveProxy(), proxy0)
+	}
+	for _, status := range ps.getProxyStatus() {
+		if status.InternalNode.ID() == proxy1ID && (status.State != ProxyStalled || status.Active) {
+			t.Errorf("proxy1 status mismatch: %+v", status)
+		}
+		if status.InternalNode.ID() == proxy0ID && (status.State != ProxyConnected || !status.Active || status.LastMessageTS != proxy0RelayTS.Unix()) {
+			t.Errorf("proxy0 status mismatch: %+v", status)
+		}
+	}
+
+	// proxy1 relays again and gets validators back, while proxy0 stays active
+	ps.recordRelays(map[enode.ID]time.Time{proxy1ID: now})
+	if !ps.checkProxyHealth(now, proxyRelayTimeout) || proxy1.stalled {
+		t.Fatalf("recovered proxy still stalled")
+	}
+	if len(ps.getValidatorAssignments(nil, []enode.ID{proxy1ID})) == 0 {
+		t.Errorf("no validator assigned to the recovered proxy")
+	}
+	if ps.updateActiveProxy() || ps.getActiveProxy() != proxy0 {
+		t.Errorf("active proxy changed while healthy: have %v, want %v", ps.getActiveProxy(), proxy0)
+	}
+
+	// The active proxy disconnects
+	ps.removeProxyPeer(proxy0ID)
+	if !ps.updateActiveProxy() || ps.getActiveProxy() != proxy1 {
+		t.Errorf("active proxy did not fail over: have %v, want %v", ps.getActiveProxy(), proxy1)
+	}
+}
@@ -124,6 +124,19 @@ type ProxiedValidatorEngine interface {
 
 	// NewEpoch will notify the proxied validator's thread that a new epoch started
 	NewEpoch() error
+
+	// CheckProxyMsg records that the proxy peer relayed a message and returns
+	// whether it should be handled, i.e. it is not a consensus message already
+	// relayed by another proxy.
+	CheckProxyMsg(peerID enode.ID, msgCode uint64, payload []byte) bool
+
+	// GetActiveProxy will retrieve the proxy gossiping this node's announce messages,
+	// nil if no proxy is connected.
+	GetActiveProxy() (*Proxy, error)
+
+	// GetProxyStatus will retrieve the connection state and the last relayed message
+	// time of all the proxies.
+	GetProxyStatus() ([]*ProxyStatus, error)
 }
 
 // ==============================================
@@ -135,6 +148,10 @@ type Proxy struct {
 	externalNode *enode.Node    // Enode for the external network interface
 	peer         consensus.Peer // Connected proxy peer.  Is nil if this node is not connected to the proxy
 	disconnectTS time.Time      // Timestamp when this proxy's peer last disconnected. Initially set to the timestamp of when the proxy was added
+	connectTS    time.Time      // Timestamp when this proxy's peer last connected
+	lastMsgTS    time.Time      // Timestamp of the last message the proxy relayed to this node
+	stalled      bool           // Set if the proxy stopped relaying messages while connected, its validators are then assigned to other proxies
+	stalledTS    time.Time      // Timestamp when the proxy was marked stalled
 }
 
 func (p *Proxy) ID() enode.ID {
@@ -149,6 +166,15 @@ func (p *Proxy) IsPeered() bool {
 	return p.peer != nil
 }
 
+// lastActivityTS returns the timestamp of the last message relayed by the proxy,
+// or of its connection if it relayed none since.
+func (p *Proxy) lastActivityTS() time.Time {
+	if p.lastMsgTS.After(p.connectTS) {
+		return p.lastMsgTS
+	}
+	return p.connectTS
+}
+
 func (p *Proxy) String() string {
 	return fmt.Sprintf("{internalNode: %v, externalNode %v, dcTimestamp: %v, ID: %v}", p.node, p.externalNode, p.disconnectTS, p.ID())
 }
@@ -172,6 +198,41 @@ func NewProxyInfo(p *Proxy, assignedVals []common.Address) *ProxyInfo {
 	}
 }
 
+// Connection states of a proxy as reported in ProxyStatus
+const (
+	ProxyDisconnected = "disconnected"
+	ProxyConnected    = "connected"
+	ProxyStalled      = "stalled"
+)
+
+// ProxyStatus is used to provide the health of a proxy via an RPC
+type ProxyStatus struct {
+	InternalNode  *enode.Node `json:"internalEnodeUrl"`
+	ExternalNode  *enode.Node `json:"externalEnodeUrl"`
+	State         string      `json:"state"`                // One of ProxyDisconnected, ProxyConnected or ProxyStalled
+	Active        bool        `json:"active"`               // Whether the proxy gossips this node's announce messages
+	LastMessageTS int64       `json:"lastMessageTimestamp"` // Unix time of the last message relayed by the proxy, 0 if none
+}
+
+func NewProxyStatus(p *Proxy, active bool) *ProxyStatus {
+	status := &ProxyStatus{
+		InternalNode: p.node,
+		ExternalNode: p.ExternalNode(),
+		State:        ProxyDisconnected,
+		Active:       active,
+	}
+	if p.IsPeered() {
+		status.State = ProxyConnected
+		if p.stalled {
+			status.State = ProxyStalled
+		}
+	}
+	if !p.lastMsgTS.IsZero() {
+		status.LastMessageTS = p.lastMsgTS.Unix()
+	}
+	return status
+}
+
 // ==============================================
 //
 // define the proxied validator info object
@@ -70,6 +70,17 @@ type Proposal interface {
 
 type Request struct {
 	Proposal Proposal
+	Stop     <-chan struct{} // closed once the request is aborted, not encoded
+}
+
+// Aborted returns whether the stop channel of the request was closed.
+func (b *Request) Aborted() bool {
+	select {
+	case <-b.Stop:
+		return true
+	default:
+		return false
+	}
 }
 
 // EncodeRLP serializes b into the Ethereum RLP format.
@@ -93,6 +104,19 @@ func (b *Request) DecodeRLP(s *rlp.Stream) error {
 
 // ## View ##############################################################
 
+// EngineStatus summarizes the roles and running parts of the engine, so that
+// the health of a node can be reported in one call.
+type EngineStatus struct {
+	Validator              bool  `json:"validator"`
+	Proxy                  bool  `json:"proxy"`
+	Proxied                bool  `json:"proxied"`
+	Primary                bool  `json:"primary"`
+	Validating             bool  `json:"validating"`
+	Announcing             bool  `json:"announcing"`
+	ProxiedValidatorEngine bool  `json:"proxiedValidatorEngine"` // the proxied validator engine is running
+	CurrentView            *View `json:"currentView"`            // nil if not validating
+}
+
 // View includes a round number and a sequence number.
 // Sequence is the block number we'd like to commit.
 // Each round has a number and is composed by 3 steps: preprepare, prepare and commit.
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/mapprotocol/atlas/consensus/istanbul"
+	"github.com/mapprotocol/atlas/params"
 )
 
 const (
@@ -87,3 +88,49 @@ func MonitoringWindow(epochNumber uint64, epochSize uint64, lookbackWindowSize u
 		End:   epochLastBlock - BlocksToSkipAtEpochEnd,
 	}, nil
 }
+
+// LookbackSchedule is the lookback window in use at every block: Base until the
+// first change is activated and then the window of the latest activated change.
+type LookbackSchedule struct {
+	Base    uint64
+	Changes []params.LookbackWindowChange // in increasing order of activation block
+}
+
+// At returns the lookback window in use at the given block.
+func (s LookbackSchedule) At(block uint64) uint64 {
+	window := s.Base
+	for _, change := range s.Changes {
+		if change.Block > block {
+			break
+		}
+		window = change.Window
+	}
+	return window
+}
+
+// Max returns the largest lookback window in use between from and to, both inclusive.
+func (s LookbackSchedule) Max(from, to uint64) uint64 {
+	window := s.At(from)
+	for _, change := range s.Changes {
+		if change.Block > from && change.Block <= to && change.Window > window {
+			window = change.Window
+		}
+	}
+	return window
+}
+
+// ScheduledMonitoringWindow is a MonitoringWindow variant for a lookback window
+// that may change during the epoch. Monitoring starts once the largest window in
+// use within the epoch fits after its first block, so that no lookback window of
+// a monitored block crosses the epoch boundary.
+func ScheduledMonitoringWindow(epochNumber uint64, epochSize uint64, schedule LookbackSchedule) (Window, error) {
+	if epochNumber == 0 {
+		return Window{}, errors.New("no monitoring window for epoch 0")
+	}
+	epochFirstBlock, err := istanbul.GetEpochFirstBlockNumber(epochNumber, epochSize)
+	if err != nil {
+		return Window{}, err
+	}
+	epochLastBlock := istanbul.GetEpochLastBlockNumber(epochNumber, epochSize)
+	return MonitoringWindow(epochNumber, epochSize, schedule.Max(epochFirstBlock, epochLastBlock))
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/mapprotocol/atlas/consensus/istanbul"
+	"github.com/mapprotocol/atlas/params"
 )
 
 func TestEpochSizeIsConsistentWithSkippedBlock(t *testing.T) {
@@ -85,3 +86,21 @@ func TestComputeLookbackWindow(t *testing.T) {
 		})
 	}
 }
+
+func TestLookbackSchedule(t *testing.T) {
+	schedule := LookbackSchedule{Base: 12, Changes: []params.LookbackWindowChange{{Block: 100, Window: 20}, {Block: 200, Window: 6}}}
+	for block, want := range map[uint64]uint64{0: 12, 99: 12, 100: 20, 199: 20, 200: 6, 1000: 6} {
+		if have := schedule.At(block); have != want {
+			t.Errorf("At(%d) = %d, want %d", block, have, want)
+		}
+	}
+	if have := schedule.Max(50, 150); have != 20 {
+		t.Errorf("Max(50, 150) = %d, want 20", have)
+	}
+	if have := schedule.Max(150, 250); have != 20 {
+		t.Errorf("Max(150, 250) = %d, want 20", have)
+	}
+	if have := schedule.Max(200, 250); have != 6 {
+		t.Errorf("Max(200, 250) = %d, want 6", have)
+	}
+}
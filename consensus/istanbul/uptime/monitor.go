@@ -17,8 +17,22 @@ import (
 type Store interface {
 	ReadAccumulatedEpochUptime(epoch uint64) *Uptime
 	WriteAccumulatedEpochUptime(epoch uint64, uptime *Uptime)
+	// PruneAccumulatedEpochUptime removes the uptime entries of all epochs before
+	// the given one and returns how many were removed.
+	PruneAccumulatedEpochUptime(before uint64) int
 }
 
+// RetainedBlocks is the number of blocks behind the chain head whose uptime
+// entries are kept. Blocks are processed again when their state is regenerated,
+// e.g. for tracing, on a reorg or a setHead, so this reaches well beyond the 128
+// blocks re-executed by default.
+const RetainedBlocks = 1024
+
+// retainedEpochs is the number of epochs, counting back from the one of the
+// oldest retained block, whose uptime entries are kept. The epoch before it is
+// kept since its last block is processed with the uptime accumulated for it.
+const retainedEpochs = 2
+
 // Uptime contains the latest block for which uptime metrics were accounted. It also contains
 // an array of Entries where the `i`th entry represents the uptime statistics of the `i`th validator
 // in the validator set for that epoch
@@ -41,8 +55,8 @@ func (u *UptimeEntry) String() string {
 
 // Monitor is responsible for monitoring uptime by processing blocks
 type Monitor struct {
-	epochSize      uint64
-	lookbackWindow uint64
+	epochSize uint64
+	schedule  LookbackSchedule
 
 	logger log.Logger
 	store  Store
@@ -50,18 +64,28 @@ type Monitor struct {
 
 // NewMonitor creates a new uptime monitor
 func NewMonitor(store Store, epochSize, lookbackWindow uint64) *Monitor {
+	return NewScheduledMonitor(store, epochSize, LookbackSchedule{Base: lookbackWindow})
+}
+
+// NewScheduledMonitor creates a new uptime monitor using the lookback window
+// of the schedule in use at every monitored block
+func NewScheduledMonitor(store Store, epochSize uint64, schedule LookbackSchedule) *Monitor {
 	return &Monitor{
-		epochSize:      epochSize,
-		lookbackWindow: lookbackWindow,
-		store:          store,
-		logger:         log.New("module", "uptime-monitor"),
+		epochSize: epochSize,
+		schedule:  schedule,
+		store:     store,
+		logger:    log.New("module", "uptime-monitor"),
 	}
 }
 
 // MonitoringWindow returns the monitoring window for the given epoch in the format
 // [firstBlock, lastBlock] both inclusive
 func (um *Monitor) MonitoringWindow(epoch uint64) Window {
-	return MustMonitoringWindow(epoch, um.epochSize, um.lookbackWindow)
+	w, err := ScheduledMonitoringWindow(epoch, um.epochSize, um.schedule)
+	if err != nil {
+		panic(err)
+	}
+	return w
 }
 
 // ComputeValidatorsUptime retrieves the uptime score for each validator for a given epoch
@@ -105,6 +129,23 @@ func (um *Monitor) ComputeValidatorsUptime(epoch uint64, valSetSize int) ([]*big
 	return uptimes, nil
 }
 
+// Prune removes the uptime entries that are no longer needed at the given chain
+// head. The entries of the epochs of the last RetainedBlocks blocks and of the
+// epoch before them are never removed.
+func (um *Monitor) Prune(head uint64) {
+	if head < RetainedBlocks {
+		return
+	}
+	oldest := istanbul.GetEpochNumber(head-RetainedBlocks, um.epochSize)
+	if oldest < retainedEpochs {
+		return
+	}
+	before := oldest - retainedEpochs + 1
+	if pruned := um.store.PruneAccumulatedEpochUptime(before); pruned > 0 {
+		um.logger.Debug("Pruned accumulated uptime", "before", before, "epochs", pruned)
+	}
+}
+
 // ProcessBlock uses the block's signature bitmap (which encodes who signed the parent block) to update the epoch's Uptime data
 func (um *Monitor) ProcessBlock(block *types.Block) error {
 	// The epoch's first block's aggregated parent signatures is for the previous epoch's valset.
@@ -128,7 +169,7 @@ func (um *Monitor) ProcessBlock(block *types.Block) error {
 	// We only update the uptime for blocks which are greater than the last block we saw.
 	// This ensures that we do not count the same block twice for any reason.
 	if uptime == nil || uptime.LatestBlock < block.NumberU64() {
-		uptime = updateUptime(uptime, block.NumberU64()-1, signedValidatorsBitmap, um.lookbackWindow, um.MonitoringWindow(epochNum))
+		uptime = updateUptime(uptime, block.NumberU64()-1, signedValidatorsBitmap, um.schedule.At(block.NumberU64()-1), um.MonitoringWindow(epochNum))
 		uptime.LatestBlock = block.NumberU64()
 		um.store.WriteAccumulatedEpochUptime(epochNum, uptime)
 	} else {
@@ -4,6 +4,11 @@ import (
 	"math/big"
 	"reflect"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/mapprotocol/atlas/core/types"
+	"github.com/mapprotocol/atlas/params"
 )
 
 func TestUptime(t *testing.T) {
@@ -102,3 +107,125 @@ func TestUptimeSingle(t *testing.T) {
 		t.Fatalf("uptimes were not updated correctly, got %v, expected %v", uptimes, expected)
 	}
 }
+
+// memoryStore is an in-memory Store.
+type memoryStore map[uint64]*Uptime
+
+func (s memoryStore) ReadAccumulatedEpochUptime(epoch uint64) *Uptime { return s[epoch] }
+func (s memoryStore) WriteAccumulatedEpochUptime(epoch uint64, uptime *Uptime) {
+	s[epoch] = uptime
+}
+func (s memoryStore) PruneAccumulatedEpochUptime(before uint64) int {
+	pruned := 0
+	for epoch := range s {
+		if epoch < before {
+			delete(s, epoch)
+			pruned++
+		}
+	}
+	return pruned
+}
+
+func TestPruneKeepsInFlightEpochs(t *testing.T) {
+	store := make(memoryStore)
+	for epoch := uint64(0); epoch <= 20; epoch++ {
+		store[epoch] = &Uptime{LatestBlock: epoch}
+	}
+	monitor := NewMonitor(store, 100, 12)
+
+	// Nothing to prune while the head is close to the first epochs
+	monitor.Prune(0)
+	monitor.Prune(RetainedBlocks + 100)
+	if len(store) != 21 {
+		t.Fatalf("entries pruned too early: %d left", len(store))
+	}
+	for _, oldest := range []uint64{4, 8, 8} {
+		monitor.Prune(RetainedBlocks + oldest*100)
+		// The epoch of the oldest retained block, the one before it and all
+		// later ones must be kept.
+		for epoch := oldest - 1; epoch <= 20; epoch++ {
+			if store[epoch] == nil {
+				t.Fatalf("epoch %d pruned with oldest retained epoch %d", epoch, oldest)
+			}
+		}
+	}
+	for epoch := uint64(0); epoch < 7; epoch++ {
+		if store[epoch] != nil {
+			t.Errorf("epoch %d not pruned", epoch)
+		}
+	}
+}
+
+// signedBlock returns a block whose parent seal is signed by the validators in bitmap.
+func signedBlock(t *testing.T, number uint64, bitmap *big.Int) *types.Block {
+	seal := types.IstanbulAggregatedSeal{Bitmap: bitmap, Signature: []byte{}, Round: big.NewInt(0)}
+	extra, err := rlp.EncodeToBytes(&types.IstanbulExtra{
+		RemovedValidators:    big.NewInt(0),
+		AggregatedSeal:       seal,
+		ParentAggregatedSeal: seal,
+	})
+	if err != nil {
+		t.Fatalf("failed to encode extra: %v", err)
+	}
+	return types.NewBlockWithHeader(&types.Header{
+		Number: new(big.Int).SetUint64(number),
+		Extra:  append(make([]byte, types.IstanbulExtraVanity), extra...),
+	})
+}
+
+func TestScheduledUptimeAcrossWindowChange(t *testing.T) {
+	const epochSize = 20
+	// Validator 0 signs every block, validator 1 misses every 4th and validator 2
+	// signs every 5th
+	bitmap := func(number uint64) *big.Int {
+		bits := big.NewInt(1)
+		if number%4 != 0 {
+			bits.SetBit(bits, 1, 1)
+		}
+		if number%5 == 0 {
+			bits.SetBit(bits, 2, 1)
+		}
+		return bits
+	}
+	// The window grows at the start of epoch 3 and shrinks within epoch 4
+	schedule := LookbackSchedule{Base: 3, Changes: []params.LookbackWindowChange{{Block: 41, Window: 5}, {Block: 67, Window: 4}}}
+	scheduled := NewScheduledMonitor(make(memoryStore), epochSize, schedule)
+	fixed := map[uint64]*Monitor{
+		3: NewMonitor(make(memoryStore), epochSize, 3),
+		5: NewMonitor(make(memoryStore), epochSize, 5),
+	}
+	for number := uint64(1); number <= 4*epochSize; number++ {
+		block := signedBlock(t, number, bitmap(number-1))
+		for _, monitor := range append([]*Monitor{scheduled}, fixed[3], fixed[5]) {
+			if err := monitor.ProcessBlock(block); err != nil {
+				t.Fatalf("block %d: failed to process: %v", number, err)
+			}
+		}
+	}
+	// Epochs with a single window score as with that window fixed
+	for epoch, window := range map[uint64]uint64{1: 3, 2: 3, 3: 5} {
+		have, err := scheduled.ComputeValidatorsUptime(epoch, 3)
+		if err != nil {
+			t.Fatalf("epoch %d: failed to compute uptime: %v", epoch, err)
+		}
+		want, _ := fixed[window].ComputeValidatorsUptime(epoch, 3)
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("epoch %d: uptime mismatch with fixed window %d: have %v, want %v", epoch, window, have, want)
+		}
+	}
+	// In the transition epoch monitoring starts after the larger window
+	if have, want := scheduled.MonitoringWindow(4), (Window{Start: 65, End: 78}); have != want {
+		t.Errorf("transition monitoring window mismatch: have %v, want %v", have, want)
+	}
+	uptimes, err := scheduled.ComputeValidatorsUptime(4, 3)
+	if err != nil {
+		t.Fatalf("failed to compute transition uptime: %v", err)
+	}
+	if uptimes[0].Cmp(params.Fixidity1) != 0 {
+		t.Errorf("always signing validator uptime mismatch: have %v, want %v", uptimes[0], params.Fixidity1)
+	}
+	// Validator 2 is up for the whole window of 5 but misses blocks once it shrinks to 4
+	if full, _ := fixed[5].ComputeValidatorsUptime(4, 3); uptimes[2].Cmp(full[2]) >= 0 {
+		t.Errorf("validator 2 uptime not below the window of 5 one: have %v, window of 5 %v", uptimes[2], full[2])
+	}
+}
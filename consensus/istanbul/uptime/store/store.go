@@ -22,3 +22,6 @@ func (us *uptimeStoreImpl) ReadAccumulatedEpochUptime(epoch uint64) *uptime.Upti
 func (us *uptimeStoreImpl) WriteAccumulatedEpochUptime(epoch uint64, uptime *uptime.Uptime) {
 	rawdb.WriteAccumulatedEpochUptime(us.db, epoch, uptime)
 }
+func (us *uptimeStoreImpl) PruneAccumulatedEpochUptime(before uint64) int {
+	return rawdb.PruneUptimeBefore(us.db, before)
+}
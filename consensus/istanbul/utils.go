@@ -17,11 +17,13 @@
 package istanbul
 
 import (
+	"bytes"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"math/big"
 
+	"github.com/mapprotocol/atlas/core/types"
 	blscrypto "github.com/mapprotocol/atlas/helper/bls"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -171,6 +173,68 @@ func ValidatorSetDiff(oldValSet []ValidatorData, newValSet []ValidatorData) ([]V
 	return addedValidators, removedValidatorsBitmap
 }
 
+// CompactValidatorSetDiff encodes addedValidators, the validators added to
+// oldValSet by a diff, for the CompactAddedValidators of an istanbul extra. The
+// BLS keys of a validator are replaced by a reference to the first validator of
+// oldValSet registered with the same keys, if any.
+func CompactValidatorSetDiff(oldValSet []ValidatorData, addedValidators []ValidatorData) []types.CompactAddedValidator {
+	keyIndices := make(map[blscrypto.SerializedPublicKey]int, len(oldValSet))
+	for i := len(oldValSet) - 1; i >= 0; i-- {
+		keyIndices[oldValSet[i].BLSPublicKey] = i
+	}
+	compact := make([]types.CompactAddedValidator, 0, len(addedValidators))
+	for _, val := range addedValidators {
+		entry := types.CompactAddedValidator{Address: val.Address}
+		if index, ok := keyIndices[val.BLSPublicKey]; ok && oldValSet[index].BLSG1PublicKey == val.BLSG1PublicKey {
+			entry.KeyIndex = uint64(index) + 1
+		} else {
+			entry.BLSPublicKey = common.CopyBytes(val.BLSPublicKey[:])
+			entry.BLSG1PublicKey = common.CopyBytes(val.BLSG1PublicKey[:])
+		}
+		compact = append(compact, entry)
+	}
+	return compact
+}
+
+// AddedValidatorsOfExtra returns the validators added by the validator set diff
+// of extra, whether encoded in the AddedValidators lists or in the compact
+// form. parentValidators is the validator set the diff applies to, holding the
+// BLS keys referenced by a compact diff.
+func AddedValidatorsOfExtra(extra *types.IstanbulExtra, parentValidators []ValidatorData) ([]ValidatorData, error) {
+	if len(extra.CompactAddedValidators) == 0 {
+		if len(extra.AddedValidatorsG1PublicKeys) != len(extra.AddedValidators) {
+			return nil, errInvalidValidatorSetDiffSize
+		}
+		return CombineIstanbulExtraToValidatorData(extra.AddedValidators, extra.AddedValidatorsPublicKeys, extra.AddedValidatorsG1PublicKeys)
+	}
+	// Both encodings can't be mixed
+	if len(extra.AddedValidators) > 0 || len(extra.AddedValidatorsPublicKeys) > 0 || len(extra.AddedValidatorsG1PublicKeys) > 0 {
+		return nil, errInvalidCompactValidatorSetDiff
+	}
+	validators := make([]ValidatorData, 0, len(extra.CompactAddedValidators))
+	for i, entry := range extra.CompactAddedValidators {
+		val := ValidatorData{Address: entry.Address}
+		switch {
+		case entry.KeyIndex == 0:
+			if len(entry.BLSPublicKey) != blscrypto.PUBLICKEYBYTES || len(entry.BLSG1PublicKey) != blscrypto.G1PUBLICKEYBYTES {
+				return nil, fmt.Errorf("%w: keys of added validator %d have %d and %d bytes", errInvalidCompactValidatorSetDiff, i, len(entry.BLSPublicKey), len(entry.BLSG1PublicKey))
+			}
+			copy(val.BLSPublicKey[:], entry.BLSPublicKey)
+			copy(val.BLSG1PublicKey[:], entry.BLSG1PublicKey)
+		case entry.KeyIndex <= uint64(len(parentValidators)):
+			if len(entry.BLSPublicKey) != 0 || len(entry.BLSG1PublicKey) != 0 {
+				return nil, fmt.Errorf("%w: added validator %d has both keys and a key index", errInvalidCompactValidatorSetDiff, i)
+			}
+			parent := parentValidators[entry.KeyIndex-1]
+			val.BLSPublicKey, val.BLSG1PublicKey = parent.BLSPublicKey, parent.BLSG1PublicKey
+		default:
+			return nil, fmt.Errorf("%w: key index %d of added validator %d out of %d validators", errInvalidCompactValidatorSetDiff, entry.KeyIndex, i, len(parentValidators))
+		}
+		validators = append(validators, val)
+	}
+	return validators, nil
+}
+
 // CompareValidatorSlices compares 2 validator slices and indicate if they are equal.
 // Equality is defined as: valseSet1[i] must be equal to valSet2[i] for every i.
 // (aka. order matters)
@@ -216,6 +280,22 @@ func CompareValidatorG1PublicKeySlices(valSet1 []blscrypto.SerializedG1PublicKey
 	return true
 }
 
+// CompareCompactAddedValidators compares 2 compact validator set diffs, in order.
+func CompareCompactAddedValidators(diff1 []types.CompactAddedValidator, diff2 []types.CompactAddedValidator) bool {
+	if len(diff1) != len(diff2) {
+		return false
+	}
+
+	for i := 0; i < len(diff1); i++ {
+		if diff1[i].Address != diff2[i].Address || diff1[i].KeyIndex != diff2[i].KeyIndex ||
+			!bytes.Equal(diff1[i].BLSPublicKey, diff2[i].BLSPublicKey) || !bytes.Equal(diff1[i].BLSG1PublicKey, diff2[i].BLSG1PublicKey) {
+			return false
+		}
+	}
+
+	return true
+}
+
 func ConvertPublicKeysToStringSlice(publicKeys []blscrypto.SerializedPublicKey) []string {
 	publicKeyStrs := []string{}
 	for i := 0; i < len(publicKeys); i++ {
@@ -244,9 +324,11 @@ func GetNodeID(enodeURL string) (*enode.ID, error) {
 	return &id, nil
 }
 
+// RandomnessCommitmentDBPrefix is the key prefix of the cached commitment entries.
+var RandomnessCommitmentDBPrefix = []byte("db-randomness-prefix")
+
 // RandomnessCommitmentDBLocation will return the key for where the
 // given commitment's cached key-value entry
 func RandomnessCommitmentDBLocation(commitment common.Hash) []byte {
-	dbRandomnessPrefix := []byte("db-randomness-prefix")
-	return append(dbRandomnessPrefix, commitment.Bytes()...)
+	return append(append([]byte{}, RandomnessCommitmentDBPrefix...), commitment.Bytes()...)
 }
@@ -17,11 +17,15 @@
 package istanbul
 
 import (
+	"bytes"
+	"errors"
 	"math/big"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
 
+	"github.com/mapprotocol/atlas/core/types"
 	"github.com/mapprotocol/atlas/helper/bls"
 )
 
@@ -284,3 +288,86 @@ func TestGetEpochNumber(t *testing.T) {
 		})
 	}
 }
+
+func testValidatorData(seed byte) ValidatorData {
+	return ValidatorData{
+		Address:        common.BytesToAddress([]byte{seed}),
+		BLSPublicKey:   bls.SerializedPublicKey{seed},
+		BLSG1PublicKey: bls.SerializedG1PublicKey{seed},
+	}
+}
+
+func TestCompactValidatorSetDiff(t *testing.T) {
+	parent := []ValidatorData{testValidatorData(1), testValidatorData(2), testValidatorData(3)}
+	// Validator 2 moves to a new address with the same keys, validator 4 joins
+	moved := parent[1]
+	moved.Address = common.BytesToAddress([]byte{0x22})
+	next := []ValidatorData{parent[0], moved, parent[2], testValidatorData(4)}
+
+	added, removed := ValidatorSetDiff(parent, next)
+	compact := CompactValidatorSetDiff(parent, added)
+	if len(compact) != 2 || compact[0].KeyIndex != 2 || len(compact[0].BLSPublicKey) != 0 || compact[1].KeyIndex != 0 {
+		t.Fatalf("compact diff mismatch: %+v", compact)
+	}
+
+	// Both encodings of the diff decode to the same validators
+	addrs, keys, g1Keys := SeparateValidatorDataIntoIstanbulExtra(added)
+	legacy := &types.IstanbulExtra{AddedValidators: addrs, AddedValidatorsPublicKeys: keys, AddedValidatorsG1PublicKeys: g1Keys, RemovedValidators: removed}
+	compactExtra := &types.IstanbulExtra{CompactAddedValidators: compact, RemovedValidators: removed}
+	for name, extra := range map[string]*types.IstanbulExtra{"legacy": legacy, "compact": compactExtra} {
+		decoded, err := AddedValidatorsOfExtra(extra, parent)
+		if err != nil {
+			t.Fatalf("%s: failed to decode diff: %v", name, err)
+		}
+		if len(decoded) != len(added) || decoded[0] != added[0] || decoded[1] != added[1] {
+			t.Errorf("%s: added validators mismatch: have %v, want %v", name, decoded, added)
+		}
+	}
+
+	// Key references outside of the parent set and mixed encodings are rejected
+	invalid := []*types.IstanbulExtra{
+		{CompactAddedValidators: []types.CompactAddedValidator{{KeyIndex: 4}}},
+		{CompactAddedValidators: []types.CompactAddedValidator{{KeyIndex: 1, BLSPublicKey: make([]byte, bls.PUBLICKEYBYTES)}}},
+		{CompactAddedValidators: []types.CompactAddedValidator{{BLSPublicKey: []byte{1}}}},
+		{CompactAddedValidators: compact, AddedValidators: addrs},
+	}
+	for i, extra := range invalid {
+		if _, err := AddedValidatorsOfExtra(extra, parent); !errors.Is(err, errInvalidCompactValidatorSetDiff) {
+			t.Errorf("invalid diff %d: have %v, want %v", i, err, errInvalidCompactValidatorSetDiff)
+		}
+	}
+}
+
+func TestLegacyIstanbulExtraEncoding(t *testing.T) {
+	added := []ValidatorData{testValidatorData(1), testValidatorData(2)}
+	addrs, keys, g1Keys := SeparateValidatorDataIntoIstanbulExtra(added)
+	seal := types.IstanbulAggregatedSeal{Bitmap: big.NewInt(3), Signature: []byte{1}, Round: big.NewInt(0)}
+
+	// The extra as encoded by nodes without the compact diff
+	legacy, err := rlp.EncodeToBytes([]interface{}{addrs, keys, g1Keys, big.NewInt(1), []byte{}, &seal, &seal})
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := &types.Header{Extra: append(make([]byte, types.IstanbulExtraVanity), legacy...)}
+	extra, err := types.ExtractIstanbulExtra(header)
+	if err != nil {
+		t.Fatalf("failed to decode legacy extra: %v", err)
+	}
+	if extra.CompactAddedValidators != nil {
+		t.Errorf("compact diff decoded from legacy extra: %v", extra.CompactAddedValidators)
+	}
+	decoded, err := AddedValidatorsOfExtra(extra, nil)
+	if err != nil || len(decoded) != 2 || decoded[0] != added[0] || decoded[1] != added[1] {
+		t.Errorf("legacy diff mismatch: have %v, %v, want %v", decoded, err, added)
+	}
+	// Re-encoding leaves the extra, and so the header hashes, unchanged
+	if encoded, err := rlp.EncodeToBytes(extra); err != nil || !bytes.Equal(encoded, legacy) {
+		t.Errorf("legacy extra re-encoded differently: %x, %v", encoded, err)
+	}
+
+	// An empty compact list would be a second encoding of the same extra
+	withEmpty, _ := rlp.EncodeToBytes([]interface{}{addrs, keys, g1Keys, big.NewInt(1), []byte{}, &seal, &seal, []types.CompactAddedValidator{}})
+	if _, err := types.ExtractIstanbulExtra(&types.Header{Extra: append(make([]byte, types.IstanbulExtraVanity), withEmpty...)}); err == nil {
+		t.Errorf("extra with an empty compact diff accepted")
+	}
+}
@@ -29,6 +29,9 @@ import (
 
 var (
 	errInvalidValidatorSetDiffSize = errors.New("istanbul extra validator set data has different size")
+	// errInvalidCompactValidatorSetDiff is returned if the compact validator set
+	// diff of an istanbul extra can't be decoded.
+	errInvalidCompactValidatorSetDiff = errors.New("invalid compact validator set diff")
 )
 
 type ValidatorData struct {
@@ -86,6 +89,17 @@ func MapValidatorsToAddresses(validators []Validator) []common.Address {
 	return returnList
 }
 
+// MapValidatorsToData maps a slice of validator to a slice of validator data
+func MapValidatorsToData(validators []Validator) []ValidatorData {
+	returnList := make([]ValidatorData, len(validators))
+
+	for i, val := range validators {
+		returnList[i] = *val.AsData()
+	}
+
+	return returnList
+}
+
 // MapValidatorsToPublicKeys maps a slice of validator to a slice of public keys
 func MapValidatorsToPublicKeys(validators []Validator) []blscrypto.SerializedPublicKey {
 	returnList := make([]blscrypto.SerializedPublicKey, len(validators))
@@ -0,0 +1,53 @@
+// Copyright 2021 MAP Protocol Authors.
+// This file is part of MAP Protocol.
+
+// MAP Protocol is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// MAP Protocol is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with MAP Protocol.  If not, see <http://www.gnu.org/licenses/>.
+
+package validator
+
+import (
+	"errors"
+
+	"github.com/mapprotocol/atlas/consensus/istanbul"
+	"github.com/mapprotocol/atlas/core/types"
+)
+
+// errInvalidValidatorSetDiff is returned if the header contains an invalid validator set diff
+var errInvalidValidatorSetDiff = errors.New("invalid validator set diff")
+
+// ApplyValSetDiff applies the validator set diff carried in the istanbul extra
+// data of header to the parent validator set and returns the resulting set. It
+// is the inverse of the diff written by UpdateValSetDiff at the end of an epoch
+// and only needs headers, so light clients can follow the validator set by
+// walking epoch headers. The parent validators are not modified.
+func ApplyValSetDiff(parentValidators []istanbul.Validator, header *types.Header) ([]istanbul.Validator, error) {
+	istExtra, err := types.ExtractIstanbulExtra(header)
+	if err != nil {
+		return nil, err
+	}
+	parentData := istanbul.MapValidatorsToData(parentValidators)
+	added, err := istanbul.AddedValidatorsOfExtra(istExtra, parentData)
+	if err != nil {
+		return nil, err
+	}
+
+	valSet := NewSet(parentData)
+	if !valSet.RemoveValidators(istExtra.RemovedValidators) {
+		return nil, errInvalidValidatorSetDiff
+	}
+	if !valSet.AddValidators(added) {
+		return nil, errInvalidValidatorSetDiff
+	}
+	return valSet.List(), nil
+}
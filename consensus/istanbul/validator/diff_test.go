@@ -0,0 +1,147 @@
+// Copyright 2021 MAP Protocol Authors.
+// This file is part of MAP Protocol.
+
+// MAP Protocol is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// MAP Protocol is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with MAP Protocol.  If not, see <http://www.gnu.org/licenses/>.
+
+package validator
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/mapprotocol/atlas/consensus/istanbul"
+	"github.com/mapprotocol/atlas/core/types"
+	"github.com/mapprotocol/atlas/helper/bls"
+)
+
+func newTestValidatorData(t *testing.T) istanbul.ValidatorData {
+	key, _ := crypto.GenerateKey()
+	blsPrivateKey, _ := bls.CryptoType().ECDSAToBLS(key)
+	blsPublicKey, err := bls.CryptoType().PrivateToPublic(blsPrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blsG1PublicKey, err := bls.CryptoType().PrivateToG1Public(blsPrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return istanbul.ValidatorData{
+		Address:        crypto.PubkeyToAddress(key.PublicKey),
+		BLSPublicKey:   blsPublicKey,
+		BLSG1PublicKey: blsG1PublicKey,
+	}
+}
+
+func newDiffHeader(t *testing.T, added []istanbul.ValidatorData, removed *big.Int) *types.Header {
+	extra := types.IstanbulExtra{
+		AddedValidators:             make([]common.Address, len(added)),
+		AddedValidatorsPublicKeys:   make([]bls.SerializedPublicKey, len(added)),
+		AddedValidatorsG1PublicKeys: make([]bls.SerializedG1PublicKey, len(added)),
+		RemovedValidators:           removed,
+		Seal:                        []byte{},
+	}
+	for i, val := range added {
+		extra.AddedValidators[i] = val.Address
+		extra.AddedValidatorsPublicKeys[i] = val.BLSPublicKey
+		extra.AddedValidatorsG1PublicKeys[i] = val.BLSG1PublicKey
+	}
+	payload, err := rlp.EncodeToBytes(&extra)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &types.Header{Number: big.NewInt(100), Extra: append(make([]byte, types.IstanbulExtraVanity), payload...)}
+}
+
+func TestApplyValSetDiff(t *testing.T) {
+	var data []istanbul.ValidatorData
+	for i := 0; i < 5; i++ {
+		data = append(data, newTestValidatorData(t))
+	}
+	parent := NewSet(data[:3]).List()
+
+	// Remove validator 1, add validators 3 and 4, i.e. the diff UpdateValSetDiff would write
+	added, removed := istanbul.ValidatorSetDiff(data[:3], []istanbul.ValidatorData{data[0], data[2], data[3], data[4]})
+	next, err := ApplyValSetDiff(parent, newDiffHeader(t, added, removed))
+	if err != nil {
+		t.Fatalf("failed to apply diff: %v", err)
+	}
+	want := []common.Address{data[0].Address, data[2].Address, data[3].Address, data[4].Address}
+	if have := istanbul.MapValidatorsToAddresses(next); !istanbul.CompareValidatorSlices(have, want) {
+		t.Errorf("validator set mismatch: have %v, want %v", have, want)
+	}
+	if len(parent) != 3 || parent[1].Address() != data[1].Address {
+		t.Errorf("parent validators modified")
+	}
+
+	// An empty diff keeps the set unchanged
+	next, err = ApplyValSetDiff(parent, newDiffHeader(t, nil, big.NewInt(0)))
+	if err != nil {
+		t.Fatalf("failed to apply empty diff: %v", err)
+	}
+	if have := istanbul.MapValidatorsToAddresses(next); !istanbul.CompareValidatorSlices(have, istanbul.MapValidatorsToAddresses(parent)) {
+		t.Errorf("empty diff changed validator set: %v", have)
+	}
+
+	// Invalid diffs: removal out of range and re-adding an existing validator
+	if _, err := ApplyValSetDiff(parent, newDiffHeader(t, nil, big.NewInt(1<<5))); err == nil {
+		t.Errorf("out of range removal accepted")
+	}
+	if _, err := ApplyValSetDiff(parent, newDiffHeader(t, data[:1], big.NewInt(0))); err == nil {
+		t.Errorf("duplicate validator accepted")
+	}
+	if _, err := ApplyValSetDiff(parent, &types.Header{Number: big.NewInt(100)}); err == nil {
+		t.Errorf("header without istanbul extra accepted")
+	}
+}
+
+func TestApplyCompactValSetDiff(t *testing.T) {
+	var data []istanbul.ValidatorData
+	for i := 0; i < 4; i++ {
+		data = append(data, newTestValidatorData(t))
+	}
+	parent := NewSet(data[:3]).List()
+
+	// The same diff in both encodings gives the same validator set
+	nextData := []istanbul.ValidatorData{data[0], data[2], data[3]}
+	added, removed := istanbul.ValidatorSetDiff(data[:3], nextData)
+	extra := types.IstanbulExtra{
+		CompactAddedValidators: istanbul.CompactValidatorSetDiff(data[:3], added),
+		RemovedValidators:      removed,
+		Seal:                   []byte{},
+	}
+	payload, err := rlp.EncodeToBytes(&extra)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compact, err := ApplyValSetDiff(parent, &types.Header{Number: big.NewInt(100), Extra: append(make([]byte, types.IstanbulExtraVanity), payload...)})
+	if err != nil {
+		t.Fatalf("failed to apply compact diff: %v", err)
+	}
+	legacy, err := ApplyValSetDiff(parent, newDiffHeader(t, added, removed))
+	if err != nil {
+		t.Fatalf("failed to apply legacy diff: %v", err)
+	}
+	if len(compact) != len(legacy) {
+		t.Fatalf("validator set size mismatch: compact %d, legacy %d", len(compact), len(legacy))
+	}
+	for i := range compact {
+		if compact[i].Address() != legacy[i].Address() || compact[i].BLSPublicKey() != legacy[i].BLSPublicKey() || compact[i].BLSG1PublicKey() != legacy[i].BLSG1PublicKey() {
+			t.Errorf("validator %d mismatch: compact %v, legacy %v", i, compact[i], legacy[i])
+		}
+	}
+}
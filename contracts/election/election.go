@@ -48,11 +48,21 @@ func GetElectedValidators(vmRunner vm.EVMRunner) ([]common.Address, error) {
 	return newValSet, nil
 }
 
-func ElectNValidatorSigners(vmRunner vm.EVMRunner, additionalAboveMaxElectable int64) ([]common.Address, error) {
-	// Get the electable min and max
+// GetElectableValidators returns the minimum and maximum number of validators
+// elected.
+func GetElectableValidators(vmRunner vm.EVMRunner) (*big.Int, *big.Int, error) {
 	var minElectableValidators *big.Int
 	var maxElectableValidators *big.Int
 	err := getElectableValidatorsMethod.Query(vmRunner, &[]interface{}{&minElectableValidators, &maxElectableValidators})
+	if err != nil {
+		return nil, nil, err
+	}
+	return minElectableValidators, maxElectableValidators, nil
+}
+
+func ElectNValidatorSigners(vmRunner vm.EVMRunner, additionalAboveMaxElectable int64) ([]common.Address, error) {
+	// Get the electable min and max
+	minElectableValidators, maxElectableValidators, err := GetElectableValidators(vmRunner)
 	if err != nil {
 		return nil, err
 	}
@@ -8,12 +8,16 @@ import (
 
 // AddBlockReceipt checks whether logs were emitted by the core contract calls made as part
 // of block processing outside of transactions.  If there are any, it creates a receipt for
-// them (the so-called "block receipt") and appends it to receipts
+// them (the so-called "block receipt") and appends it to receipts. The block receipt and its
+// logs have the block hash as transaction hash.
 func AddBlockReceipt(receipts types.Receipts, statedb *state.StateDB, blockHash common.Hash) types.Receipts {
 	if len(statedb.GetLogs(common.Hash{})) > 0 {
 		receipt := types.NewReceipt(nil, false, 0)
 		receipt.Logs = statedb.GetLogs(common.Hash{})
 		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+		receipt.TxHash = blockHash
+		receipt.BlockHash = blockHash
+		receipt.TransactionIndex = uint(len(receipts))
 		for i := range receipt.Logs {
 			receipt.Logs[i].TxIndex = uint(len(receipts))
 			receipt.Logs[i].TxHash = blockHash
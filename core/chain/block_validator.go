@@ -58,15 +58,14 @@ func (v *BlockValidator) ValidateBody(block *types.Block) error {
 		return core.ErrKnownBlock
 	}
 	// Header validity is known at this point, check the uncles and transactions
-	header := block.Header()
 	//if err := v.engine.VerifyUncles(v.bc, block); err != nil {
 	//	return err
 	//}
 	//if hash := types.CalcUncleHash(block.Uncles()); hash != header.UncleHash {
 	//	return fmt.Errorf("uncle root hash mismatch: have %x, want %x", hash, header.UncleHash)
 	//}
-	if hash := types.DeriveSha(block.Transactions(), trie.NewStackTrie(nil)); hash != header.TxHash {
-		return fmt.Errorf("transaction root hash mismatch: have %x, want %x", hash, header.TxHash)
+	if _, err := runValidationSteps(bodyValidationSteps(v.config, v.engine, block)); err != nil {
+		return err
 	}
 	if !v.bc.HasBlockAndState(block.ParentHash(), block.NumberU64()-1) {
 		if !v.bc.HasBlock(block.ParentHash(), block.NumberU64()-1) {
@@ -82,27 +81,97 @@ func (v *BlockValidator) ValidateBody(block *types.Block) error {
 // itself. ValidateState returns a database batch if the validation was a success
 // otherwise nil and an error is returned.
 func (v *BlockValidator) ValidateState(block *types.Block, statedb *state.StateDB, receipts types.Receipts, usedGas uint64) error {
-	header := block.Header()
-	if block.GasUsed() != usedGas {
-		return fmt.Errorf("invalid gas used (remote: %d local: %d)", block.GasUsed(), usedGas)
-	}
-	// Validate the received block's bloom with the one derived from the generated receipts.
-	// For valid blocks this should always validate to true.
-	rbloom := types.CreateBloom(receipts)
-	if rbloom != header.Bloom {
-		return fmt.Errorf("invalid bloom (remote: %x  local: %x)", header.Bloom, rbloom)
+	_, err := runValidationSteps(stateValidationSteps(v.config, block, statedb, receipts, usedGas))
+	return err
+}
+
+// mismatchError is returned by a validation step when a value in the block
+// header differs from the one computed from the block. It carries both values
+// for the replay besides the error reported by the validator.
+type mismatchError struct {
+	error
+	remote   string // value in the header
+	computed string // value computed from the block
+}
+
+// validationStep is one of the checks of the block validator, named after the
+// replay step reporting it.
+type validationStep struct {
+	name  string
+	check func() error
+}
+
+// runValidationSteps runs the steps in order, and returns the name and error
+// of the first one failing.
+func runValidationSteps(steps []validationStep) (string, error) {
+	for _, step := range steps {
+		if err := step.check(); err != nil {
+			return step.name, err
+		}
 	}
-	// Tre receipt Trie's root (R = (Tr [[H1, R1], ... [Hn, Rn]]))
-	receiptSha := types.DeriveSha(receipts, trie.NewStackTrie(nil))
-	if receiptSha != header.ReceiptHash {
-		return fmt.Errorf("invalid receipt root hash (remote: %x local: %x)", header.ReceiptHash, receiptSha)
+	return "", nil
+}
+
+// bodyValidationSteps returns the checks ValidateBody makes on the contents of
+// the block.
+func bodyValidationSteps(config *params.ChainConfig, engine consensus.Engine, block *types.Block) []validationStep {
+	header := block.Header()
+	return []validationStep{
+		{ReplayStepTxRoot, func() error {
+			if hash := types.DeriveSha(block.Transactions(), trie.NewStackTrie(nil)); hash != header.TxHash {
+				err := fmt.Errorf("transaction root hash mismatch: have %x, want %x", hash, header.TxHash)
+				return &mismatchError{err, header.TxHash.Hex(), hash.Hex()}
+			}
+			return nil
+		}},
+		{ReplayStepEpochSnarkData, func() error {
+			if istEngine, isIstanbul := engine.(consensus.Istanbul); isIstanbul && config.IsEpochSnark(header.Number) {
+				return istEngine.VerifyEpochSnarkData(block)
+			}
+			return nil
+		}},
 	}
-	// Validate the state root against the received state root and throw
-	// an error if they don't match.
-	if root := statedb.IntermediateRoot(v.config.IsEIP158(header.Number)); header.Root != root {
-		return fmt.Errorf("invalid merkle root (remote: %x local: %x)", header.Root, root)
+}
+
+// stateValidationSteps returns the checks ValidateState makes on the result of
+// processing the block.
+func stateValidationSteps(config *params.ChainConfig, block *types.Block, statedb *state.StateDB, receipts types.Receipts, usedGas uint64) []validationStep {
+	header := block.Header()
+	return []validationStep{
+		{ReplayStepGasUsed, func() error {
+			if block.GasUsed() != usedGas {
+				err := fmt.Errorf("invalid gas used (remote: %d local: %d)", block.GasUsed(), usedGas)
+				return &mismatchError{err, fmt.Sprint(block.GasUsed()), fmt.Sprint(usedGas)}
+			}
+			return nil
+		}},
+		// Validate the received block's bloom with the one derived from the generated receipts.
+		// For valid blocks this should always validate to true.
+		{ReplayStepBloom, func() error {
+			if rbloom := types.CreateBloom(receipts); rbloom != header.Bloom {
+				err := fmt.Errorf("invalid bloom (remote: %x  local: %x)", header.Bloom, rbloom)
+				return &mismatchError{err, fmt.Sprintf("%x", header.Bloom), fmt.Sprintf("%x", rbloom)}
+			}
+			return nil
+		}},
+		// Tre receipt Trie's root (R = (Tr [[H1, R1], ... [Hn, Rn]]))
+		{ReplayStepReceiptRoot, func() error {
+			if receiptSha := types.DeriveSha(receipts, trie.NewStackTrie(nil)); receiptSha != header.ReceiptHash {
+				err := fmt.Errorf("invalid receipt root hash (remote: %x local: %x)", header.ReceiptHash, receiptSha)
+				return &mismatchError{err, header.ReceiptHash.Hex(), receiptSha.Hex()}
+			}
+			return nil
+		}},
+		// Validate the state root against the received state root and throw
+		// an error if they don't match.
+		{ReplayStepStateRoot, func() error {
+			if root := statedb.IntermediateRoot(config.IsEIP158(header.Number)); header.Root != root {
+				err := fmt.Errorf("invalid merkle root (remote: %x local: %x)", header.Root, root)
+				return &mismatchError{err, header.Root.Hex(), root.Hex()}
+			}
+			return nil
+		}},
 	}
-	return nil
 }
 
 // CalcGasLimit computes the gas limit of the next block after parent. It aims
@@ -40,8 +40,10 @@ import (
 	lru "github.com/hashicorp/golang-lru"
 
 	"github.com/mapprotocol/atlas/consensus"
+	"github.com/mapprotocol/atlas/consensus/istanbul"
 	"github.com/mapprotocol/atlas/consensus/istanbul/uptime"
 	"github.com/mapprotocol/atlas/consensus/istanbul/uptime/store"
+	"github.com/mapprotocol/atlas/contracts/random"
 	"github.com/mapprotocol/atlas/core"
 	"github.com/mapprotocol/atlas/core/abstract"
 	"github.com/mapprotocol/atlas/core/rawdb"
@@ -98,7 +100,12 @@ const (
 	maxTimeFutureBlocks = 30
 	TriesInMemory       = 128
 
+	randomCommitmentRetention = 10000            // Number of blocks behind the head whose randomness commitments are cached
+	randomCommitmentPruneTime = 10 * time.Minute // Interval between two randomness commitment cache prunings
+
 	// BlockChainVersion ensures that an incompatible database forces a resync from scratch.
+	// Changes which can be converted in place register a step run by rawdb.MigrateSchema
+	// when the version is raised.
 	//
 	// Changelog:
 	//
@@ -229,6 +236,16 @@ func (bc *BlockChain) NewEVMRunnerForCurrentBlock() (vm.EVMRunner, error) {
 	return vmcontext.NewEVMRunner(bc, block.Header(), state), nil
 }
 
+// NewEVMRunnerForBlock creates a read-only EVMRunner over the state of the
+// given, possibly historical, header.
+func (bc *BlockChain) NewEVMRunnerForBlock(header *types.Header) (vm.EVMRunner, error) {
+	state, err := bc.StateAt(header.Root)
+	if err != nil {
+		return nil, fmt.Errorf("%w: block %d (%x): %v", consensus.ErrStateUnavailable, header.Number, header.Hash(), err)
+	}
+	return vmcontext.NewReadOnlyEVMRunner(vmcontext.NewEVMRunner(bc, header, state)), nil
+}
+
 // NewBlockChain returns a fully initialised block chain using information
 // available in the database. It initialises the default Ethereum Validator and
 // Processor.
@@ -400,6 +417,10 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	bc.wg.Add(1)
 	go bc.futureBlocksLoop()
 
+	// Start randomness commitment cache pruner.
+	bc.wg.Add(1)
+	go bc.pruneRandomCommitmentsLoop()
+
 	// Start tx indexer/unindexer.
 	if txLookupLimit != nil {
 		bc.txLookupLimit = *txLookupLimit
@@ -453,6 +474,11 @@ func (bc *BlockChain) loadLastState() error {
 		log.Warn("Empty database, resetting chain")
 		return bc.Reset()
 	}
+	// Report how stale the database is before loading anything heavier
+	meta := rawdb.ReadHeadBlockMeta(bc.db)
+	if meta != nil && meta.Hash == head {
+		log.Info(fmt.Sprintf("Head block #%d (age %s)", meta.Number, common.PrettyAge(time.Unix(int64(meta.Time), 0))), "hash", meta.Hash)
+	}
 	// Make sure the entire head block is available
 	currentBlock := bc.GetBlockByHash(head)
 	if currentBlock == nil {
@@ -460,6 +486,10 @@ func (bc *BlockChain) loadLastState() error {
 		log.Warn("Head block missing, resetting chain", "hash", head)
 		return bc.Reset()
 	}
+	// Databases written before the head block summary existed lack it
+	if meta == nil || meta.Hash != head {
+		rawdb.WriteHeadBlockMeta(bc.db, currentBlock.Header())
+	}
 	// Everything seems to be fine, set as the head block
 	bc.currentBlock.Store(currentBlock)
 	headBlockGauge.Update(int64(currentBlock.NumberU64()))
@@ -572,7 +602,8 @@ func (bc *BlockChain) SetHeadBeyondRoot(head uint64, root common.Hash) (uint64,
 					newHeadBlock = bc.GetBlock(newHeadBlock.ParentHash(), newHeadBlock.NumberU64()-1) // Keep rewinding
 				}
 			}
-			rawdb.WriteHeadBlockHash(db, newHeadBlock.Hash())
+			rawdb.WriteHeadBlock(db, newHeadBlock.Header())
+			rawdb.WriteFinalizedBlockHash(db, newHeadBlock.Hash())
 
 			// Degrade the chain markers if they are explicitly reverted.
 			// In theory we should update all in-memory markers in the
@@ -813,7 +844,13 @@ func (bc *BlockChain) writeHeadBlock(block *types.Block) {
 	batch := bc.db.NewBatch()
 	rawdb.WriteCanonicalHash(batch, block.Hash(), block.NumberU64())
 	rawdb.WriteTxLookupEntriesByBlock(batch, block)
-	rawdb.WriteHeadBlockHash(batch, block.Hash())
+	rawdb.WriteHeadBlock(batch, block.Header())
+
+	// Istanbul has instant finality, the new head is final as soon as it's imported
+	rawdb.WriteFinalizedBlockHash(batch, block.Hash())
+	if config := bc.chainConfig.Istanbul; config != nil && config.Epoch != 0 && istanbul.IsLastBlockOfEpoch(block.NumberU64(), config.Epoch) {
+		rawdb.WriteEpochBoundary(batch, istanbul.GetEpochNumber(block.NumberU64(), config.Epoch), block.Hash(), block.NumberU64())
+	}
 
 	// If the block is better than our head or is on a different chain, force update heads
 	if updateHeads {
@@ -1485,8 +1522,7 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 			log.Error("Found two blocks with same height", "old", hash, "new", block.Hash())
 		}
 
-		lookbackWindow := istEngine.LookbackWindow(block.Header(), state)
-		uptimeMonitor := uptime.NewMonitor(store.New(bc.db), bc.chainConfig.Istanbul.Epoch, lookbackWindow)
+		uptimeMonitor := uptime.NewScheduledMonitor(store.New(bc.db), bc.chainConfig.Istanbul.Epoch, istEngine.LookbackSchedule())
 		err = uptimeMonitor.ProcessBlock(block)
 		if err != nil {
 			return NonStatTy, err
@@ -1669,6 +1705,12 @@ func (bc *BlockChain) addFutureBlock(block *types.Block) error {
 //
 // After insertion is done, all accumulated events will be fired.
 func (bc *BlockChain) InsertChain(chain types.Blocks) (int, error) {
+	return bc.InsertChainFromPeer(chain, "")
+}
+
+// InsertChainFromPeer is InsertChain for blocks received from the given peer,
+// which is recorded along with any of them rejected as bad.
+func (bc *BlockChain) InsertChainFromPeer(chain types.Blocks, peer string) (int, error) {
 	// Sanity check that we have something meaningful to import
 	if len(chain) == 0 {
 		return 0, nil
@@ -1698,7 +1740,7 @@ func (bc *BlockChain) InsertChain(chain types.Blocks) (int, error) {
 		return 0, errChainStopped
 	}
 	defer bc.chainmu.Unlock()
-	return bc.insertChain(chain, true)
+	return bc.insertChain(chain, true, peer)
 }
 
 // InsertChainWithoutSealVerification works exactly the same
@@ -1711,7 +1753,7 @@ func (bc *BlockChain) InsertChainWithoutSealVerification(block *types.Block) (in
 		return 0, errChainStopped
 	}
 	defer bc.chainmu.Unlock()
-	return bc.insertChain(types.Blocks([]*types.Block{block}), false)
+	return bc.insertChain(types.Blocks([]*types.Block{block}), false, "")
 }
 
 // insertChain is the internal implementation of InsertChain, which assumes that
@@ -1722,7 +1764,7 @@ func (bc *BlockChain) InsertChainWithoutSealVerification(block *types.Block) (in
 // racey behaviour. If a sidechain import is in progress, and the historic state
 // is imported, but then new canon-head is added before the actual sidechain
 // completes, then the historic state could be pruned again
-func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, error) {
+func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool, peer string) (int, error) {
 	// If the chain is terminating, don't even bother starting up.
 	if bc.insertStopped() {
 		return 0, nil
@@ -1804,7 +1846,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, er
 	// First block is pruned, insert as sidechain and reorg only if TD grows enough
 	case errors.Is(err, consensus.ErrPrunedAncestor):
 		log.Debug("Pruned ancestor, inserting as sidechain", "number", block.Number(), "hash", block.Hash())
-		return bc.insertSideChain(block, it)
+		return bc.insertSideChain(block, it, peer)
 
 	// First block is future, shove it (and all children) to the future queue (unknown ancestor)
 	case errors.Is(err, consensus.ErrFutureBlock) || (errors.Is(err, consensus.ErrUnknownAncestor) && bc.futureBlocks.Contains(it.first().ParentHash())):
@@ -1825,7 +1867,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, er
 	case err != nil:
 		bc.futureBlocks.Remove(block.Hash())
 		stats.ignored += len(it.chain)
-		bc.reportBlock(block, nil, err)
+		bc.reportBlock(block, nil, err, peer)
 		return it.index, err
 	}
 	// No validation errors for the first block (or chain prefix skipped)
@@ -1848,7 +1890,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, er
 		}
 		// If the header is a banned one, straight out abort
 		if BadHashes[block.Hash()] {
-			bc.reportBlock(block, nil, core.ErrBannedHash)
+			bc.reportBlock(block, nil, core.ErrBannedHash, peer)
 			return it.index, core.ErrBannedHash
 		}
 		// If the block is known (in the middle of the chain), it's a special case for
@@ -1923,7 +1965,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, er
 		substart := time.Now()
 		receipts, logs, usedGas, err := bc.processor.Process(block, statedb, bc.vmConfig)
 		if err != nil {
-			bc.reportBlock(block, receipts, err)
+			bc.reportBlock(block, receipts, err, peer)
 			atomic.StoreUint32(&followupInterrupt, 1)
 			return it.index, err
 		}
@@ -1944,7 +1986,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, er
 		// Validate the state using the default validator
 		substart = time.Now()
 		if err := bc.validator.ValidateState(block, statedb, receipts, usedGas); err != nil {
-			bc.reportBlock(block, receipts, err)
+			bc.reportBlock(block, receipts, err, peer)
 			atomic.StoreUint32(&followupInterrupt, 1)
 			return it.index, err
 		}
@@ -2026,7 +2068,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, er
 //
 // The method writes all (header-and-body-valid) blocks to disk, then tries to
 // switch over to the new chain if the TD exceeded the current chain.
-func (bc *BlockChain) insertSideChain(block *types.Block, it *insertIterator) (int, error) {
+func (bc *BlockChain) insertSideChain(block *types.Block, it *insertIterator, peer string) (int, error) {
 	var (
 		externTd *big.Int
 		current  = bc.CurrentBlock()
@@ -2124,7 +2166,7 @@ func (bc *BlockChain) insertSideChain(block *types.Block, it *insertIterator) (i
 		// memory here.
 		if len(blocks) >= 2048 || memory > 64*1024*1024 {
 			log.Info("Importing heavy sidechain segment", "blocks", len(blocks), "start", blocks[0].NumberU64(), "end", block.NumberU64())
-			if _, err := bc.insertChain(blocks, false); err != nil {
+			if _, err := bc.insertChain(blocks, false, peer); err != nil {
 				return 0, err
 			}
 			blocks, memory = blocks[:0], 0
@@ -2138,7 +2180,7 @@ func (bc *BlockChain) insertSideChain(block *types.Block, it *insertIterator) (i
 	}
 	if len(blocks) > 0 {
 		log.Info("Importing sidechain segment", "start", blocks[0].NumberU64(), "end", blocks[len(blocks)-1].NumberU64())
-		return bc.insertChain(blocks, false)
+		return bc.insertChain(blocks, false, peer)
 	}
 	return 0, nil
 }
@@ -2326,6 +2368,49 @@ func (bc *BlockChain) futureBlocksLoop() {
 	}
 }
 
+// pruneRandomCommitmentsLoop periodically drops the randomness commitment cache
+// entries that fell behind the retention window, except for the validator's
+// last commitment, which its next proposal reveals.
+func (bc *BlockChain) pruneRandomCommitmentsLoop() {
+	defer bc.wg.Done()
+
+	pruneTimer := time.NewTicker(randomCommitmentPruneTime)
+	defer pruneTimer.Stop()
+	for {
+		select {
+		case <-pruneTimer.C:
+			keep, err := bc.lastRandomCommitment()
+			if err != nil {
+				log.Debug("Skipping randomness commitment cache pruning", "err", err)
+				continue
+			}
+			if pruned := rawdb.PruneRandomCommitments(bc.db, randomCommitmentRetention, keep); pruned > 0 {
+				log.Debug("Pruned randomness commitment cache", "entries", pruned)
+			}
+		case <-bc.quit:
+			return
+		}
+	}
+}
+
+// lastRandomCommitment returns the last randomness commitment of the local
+// validator in the random contract at the current block, or the zero hash if
+// there is none.
+func (bc *BlockChain) lastRandomCommitment() (common.Hash, error) {
+	istEngine, isIstanbul := bc.engine.(consensus.Istanbul)
+	if !isIstanbul || (istEngine.ValidatorAddress() == common.Address{}) {
+		return common.Hash{}, nil
+	}
+	vmRunner, err := bc.NewEVMRunnerForCurrentBlock()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if !random.IsRunning(vmRunner) {
+		return common.Hash{}, nil
+	}
+	return random.GetLastCommitment(vmRunner, istEngine.ValidatorAddress())
+}
+
 // maintainTxIndex is responsible for the construction and deletion of the
 // transaction index.
 //
@@ -2414,9 +2499,14 @@ func (bc *BlockChain) maintainTxIndex(ancients uint64) {
 	}
 }
 
-// reportBlock logs a bad block error.
-func (bc *BlockChain) reportBlock(block *types.Block, receipts types.Receipts, err error) {
-	rawdb.WriteBadBlock(bc.db, block)
+// reportBlock logs a bad block error and records it along with the peer it was
+// received from, empty if unknown.
+func (bc *BlockChain) reportBlock(block *types.Block, receipts types.Receipts, err error, peer string) {
+	meta := &rawdb.BadBlockMeta{Received: uint64(time.Now().Unix()), Peer: peer}
+	if err != nil {
+		meta.Error = err.Error()
+	}
+	rawdb.WriteBadBlock(bc.db, block, meta)
 
 	var receiptString string
 	for i, receipt := range receipts {
@@ -2504,6 +2594,43 @@ func (bc *BlockChain) GetCanonicalHash(number uint64) common.Hash {
 	return bc.hc.GetCanonicalHash(number)
 }
 
+// GetEpochBoundary returns the last block of the given epoch on the canonical
+// chain, or nil if the epoch isn't over yet. Boundaries missing from databases
+// written before they were recorded, or left stale by a rewind, are backfilled
+// from the canonical chain using the configured epoch size.
+func (bc *BlockChain) GetEpochBoundary(epoch uint64) *rawdb.EpochBoundary {
+	if boundary := rawdb.ReadEpochBoundary(bc.db, epoch); boundary != nil && bc.GetCanonicalHash(boundary.Number) == boundary.Hash {
+		return boundary
+	}
+	if bc.chainConfig.Istanbul == nil || bc.chainConfig.Istanbul.Epoch == 0 {
+		return nil
+	}
+	number := istanbul.GetEpochLastBlockNumber(epoch, bc.chainConfig.Istanbul.Epoch)
+	if number > bc.CurrentBlock().NumberU64() {
+		return nil
+	}
+	hash := bc.GetCanonicalHash(number)
+	if hash == (common.Hash{}) {
+		return nil
+	}
+	rawdb.WriteEpochBoundary(bc.db, epoch, hash, number)
+	return &rawdb.EpochBoundary{Hash: hash, Number: number}
+}
+
+// LatestFinalizedEpoch returns the last epoch that is over at the current head,
+// along with its last block.
+func (bc *BlockChain) LatestFinalizedEpoch() (uint64, *rawdb.EpochBoundary) {
+	if bc.chainConfig.Istanbul == nil || bc.chainConfig.Istanbul.Epoch == 0 {
+		return 0, nil
+	}
+	head := bc.CurrentBlock().NumberU64()
+	epoch := istanbul.GetEpochNumber(head, bc.chainConfig.Istanbul.Epoch)
+	if !istanbul.IsLastBlockOfEpoch(head, bc.chainConfig.Istanbul.Epoch) {
+		epoch--
+	}
+	return epoch, bc.GetEpochBoundary(epoch)
+}
+
 // GetAncestor retrieves the Nth ancestor of a given block. It assumes that either the given block or
 // a close ancestor of it is canonical. maxNonCanonical points to a downwards counter limiting the
 // number of blocks to be individually checked before we reach the canonical chain.
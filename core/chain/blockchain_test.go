@@ -163,12 +163,12 @@ func testBlockChainImport(chain types.Blocks, blockchain *BlockChain) error {
 		}
 		receipts, _, usedGas, err := blockchain.processor.Process(block, statedb, vm.Config{})
 		if err != nil {
-			blockchain.reportBlock(block, receipts, err)
+			blockchain.reportBlock(block, receipts, err, "")
 			return err
 		}
 		err = blockchain.validator.ValidateState(block, statedb, receipts, usedGas)
 		if err != nil {
-			blockchain.reportBlock(block, receipts, err)
+			blockchain.reportBlock(block, receipts, err, "")
 			return err
 		}
 
@@ -214,6 +214,53 @@ func TestLastBlock(t *testing.T) {
 	}
 }
 
+// Tests that the last blocks of the epochs are recorded on import, and that
+// missing or stale entries are backfilled from the canonical chain.
+func TestEpochBoundaries(t *testing.T) {
+	config := *params.AllEthashProtocolChanges
+	istanbulConfig := *config.Istanbul
+	istanbulConfig.Epoch = 4
+	config.Istanbul = &istanbulConfig
+
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		genesis = (&Genesis{BaseFee: big.NewInt(ethparams.InitialBaseFee)}).MustCommit(db)
+	)
+	blockchain, err := NewBlockChain(db, nil, &config, consensustest.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer blockchain.Stop()
+
+	blocks := makeBlockChain(genesis, 10, consensustest.NewFaker(), db, canonicalSeed)
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	for epoch, number := range map[uint64]uint64{1: 4, 2: 8} {
+		boundary := rawdb.ReadEpochBoundary(db, epoch)
+		if boundary == nil || boundary.Number != number || boundary.Hash != blocks[number-1].Hash() {
+			t.Errorf("epoch %d boundary mismatch: have %v, want #%d [%x]", epoch, boundary, number, blocks[number-1].Hash())
+		}
+	}
+	if epoch, boundary := blockchain.LatestFinalizedEpoch(); epoch != 2 || boundary == nil || boundary.Number != 8 {
+		t.Errorf("latest finalized epoch mismatch: have %d (%v), want 2 (#8)", epoch, boundary)
+	}
+	if boundary := blockchain.GetEpochBoundary(3); boundary != nil {
+		t.Errorf("unfinished epoch returned: %v", boundary)
+	}
+	// The genesis epoch predates the recording and a stale entry gets replaced
+	if boundary := blockchain.GetEpochBoundary(0); boundary == nil || boundary.Hash != genesis.Hash() {
+		t.Errorf("genesis epoch boundary mismatch: have %v, want %x", boundary, genesis.Hash())
+	}
+	rawdb.WriteEpochBoundary(db, 1, common.Hash{0x01}, 4)
+	if boundary := blockchain.GetEpochBoundary(1); boundary == nil || boundary.Hash != blocks[3].Hash() {
+		t.Errorf("stale epoch boundary not replaced: have %v, want %x", boundary, blocks[3].Hash())
+	}
+	if boundary := rawdb.ReadEpochBoundary(db, 1); boundary == nil || boundary.Hash != blocks[3].Hash() {
+		t.Errorf("backfilled epoch boundary not stored: have %v", boundary)
+	}
+}
+
 // Tests that given a starting canonical chain of a given size, it can be extended
 // with various length chains.
 func TestExtendCanonicalHeaders(t *testing.T) { testExtendCanonical(t, false) }
@@ -475,7 +522,13 @@ func testBadHashes(t *testing.T, full bool) {
 		BadHashes[blocks[2].Header().Hash()] = true
 		defer func() { delete(BadHashes, blocks[2].Header().Hash()) }()
 
-		_, err = blockchain.InsertChain(blocks)
+		_, err = blockchain.InsertChainFromPeer(blocks, "peer")
+
+		// The rejected block is recorded with the peer it came from
+		bad := rawdb.ReadAllBadBlocks(db)
+		if len(bad) != 1 || bad[0].Block.Hash() != blocks[2].Hash() || bad[0].Meta == nil || bad[0].Meta.Peer != "peer" {
+			t.Errorf("bad block record mismatch: %+v", bad)
+		}
 	} else {
 		headers := makeHeaderChain(blockchain.CurrentHeader(), 3, consensustest.NewFaker(), db, 10)
 
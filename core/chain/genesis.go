@@ -175,6 +175,11 @@ func SetupGenesisBlockWithOverride(db ethdb.Database, genesis *Genesis, override
 	if genesis != nil && genesis.Config == nil {
 		return params.AllEthashProtocolChanges, common.Hash{}, errGenesisNoConfig
 	}
+	if genesis != nil {
+		if _, _, err := consensus.EthereumAnchor(genesis.Config.Relayer); err != nil {
+			return genesis.Config, common.Hash{}, err
+		}
+	}
 	// Just commit the new block if there is no stored genesis block.
 	stored := rawdb.ReadCanonicalHash(db, 0)
 	if (stored == common.Hash{}) {
@@ -245,10 +250,25 @@ func SetupGenesisBlockWithOverride(db ethdb.Database, genesis *Genesis, override
 	if compatErr != nil && *height != 0 && compatErr.RewindTo != 0 {
 		return newcfg, stored, compatErr
 	}
+	// An upgraded config applies from the block after the head on, the blocks
+	// up to it keep the config they were processed with in the history
+	if configChanged(storedcfg, newcfg) {
+		if err := rawdb.WriteChainConfigWithHeight(db, stored, *height+1, newcfg); err != nil {
+			return newcfg, stored, err
+		}
+		return newcfg, stored, nil
+	}
 	rawdb.WriteChainConfig(db, stored, newcfg)
 	return newcfg, stored, nil
 }
 
+// configChanged reports whether the chain configs differ in their stored form.
+func configChanged(stored, config *params.ChainConfig) bool {
+	a, errA := json.Marshal(stored)
+	b, errB := json.Marshal(config)
+	return errA != nil || errB != nil || !bytes.Equal(a, b)
+}
+
 // StoreGenesisSupply computes the total supply of the genesis block and stores
 // it in the db.
 func (g *Genesis) StoreGenesisSupply(db ethdb.Database) error {
@@ -279,6 +299,8 @@ func (g *Genesis) configOrDefault(ghash common.Hash) *params.ChainConfig {
 
 // ToBlock creates the genesis block and writes state of a genesis specification
 // to the given database (or discards it if nil).
+// It panics if the relayer anchor of the config is invalid, which Commit and
+// SetupGenesisBlock check beforehand.
 func (g *Genesis) ToBlock(db ethdb.Database) *types.Block {
 	if db == nil {
 		db = rawdb.NewMemoryDatabase()
@@ -297,7 +319,13 @@ func (g *Genesis) ToBlock(db ethdb.Database) *types.Block {
 	}
 
 	// pre compiled
-	consensus.InitHeaderStore(statedb, new(big.Int).SetUint64(g.Number))
+	var relayer *params.RelayerConfig
+	if g.Config != nil {
+		relayer = g.Config.Relayer
+	}
+	if err := consensus.InitHeaderStore(statedb, new(big.Int).SetUint64(g.Number), relayer); err != nil {
+		panic(err)
+	}
 	consensus.InitTxVerify(statedb, new(big.Int).SetUint64(g.Number))
 
 	root := statedb.IntermediateRoot(false)
@@ -333,6 +361,12 @@ func (g *Genesis) ToBlock(db ethdb.Database) *types.Block {
 // Commit writes the block and state of a genesis specification to the database.
 // The block is committed as the canonical head block.
 func (g *Genesis) Commit(db ethdb.Database) (*types.Block, error) {
+	if g.Config != nil {
+		// A bad relayer anchor would make ToBlock panic
+		if _, _, err := consensus.EthereumAnchor(g.Config.Relayer); err != nil {
+			return nil, err
+		}
+	}
 	block := g.ToBlock(db)
 	if block.Number().Sign() != 0 {
 		return nil, errors.New("can't commit genesis block with number > 0")
@@ -349,7 +383,8 @@ func (g *Genesis) Commit(db ethdb.Database) (*types.Block, error) {
 	rawdb.WriteBlock(db, block)
 	rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), nil)
 	rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
-	rawdb.WriteHeadBlockHash(db, block.Hash())
+	rawdb.WriteHeadBlock(db, block.Header())
+	rawdb.WriteFinalizedBlockHash(db, block.Hash())
 	rawdb.WriteHeadFastBlockHash(db, block.Hash())
 	rawdb.WriteHeadHeaderHash(db, block.Hash())
 	rawdb.WriteChainConfig(db, block.Hash(), config)
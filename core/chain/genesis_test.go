@@ -30,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/trie"
 
+	"github.com/mapprotocol/atlas/chains/ethereum"
 	"github.com/mapprotocol/atlas/core/rawdb"
 	"github.com/mapprotocol/atlas/core/state"
 	"github.com/mapprotocol/atlas/core/types"
@@ -248,7 +249,7 @@ func TestReadPoc2Contracts(t *testing.T) {
 
 	//////////////////////////////////pro compiled////////////////////////////////////
 	Number := uint64(0)
-	consensus.InitHeaderStore(statedb, new(big.Int).SetUint64(Number))
+	consensus.InitHeaderStore(statedb, new(big.Int).SetUint64(Number), nil)
 	consensus.InitTxVerify(statedb, new(big.Int).SetUint64(Number))
 	////////////////////////////////////////////////////////////////////////////
 	root := statedb.IntermediateRoot(false)
@@ -396,3 +397,90 @@ func rlpHash(x interface{}) (h common.Hash) {
 	hw.Sum(h[:0])
 	return h
 }
+
+func TestGenesisRelayerAnchor(t *testing.T) {
+	var anchor ethereum.Header
+	if err := json.Unmarshal([]byte(params2.EthereumTestnetGenesisHeader), &anchor); err != nil {
+		t.Fatalf("failed to decode testnet header: %v", err)
+	}
+	anchor.Number = big.NewInt(14000000)
+	anchorRLP, err := rlp.EncodeToBytes(&anchor)
+	if err != nil {
+		t.Fatalf("failed to encode anchor: %v", err)
+	}
+	storedNumber := func(relayer *params2.RelayerConfig) (uint64, error) {
+		config := *params2.TestChainConfig
+		config.Relayer = relayer
+		db := rawdb.NewMemoryDatabase()
+		if _, _, err := SetupGenesisBlock(db, &Genesis{Config: &config}); err != nil {
+			return 0, err
+		}
+		statedb, err := state.New(rawdb.ReadHeader(db, rawdb.ReadCanonicalHash(db, 0), 0).Root, state.NewDatabase(db), nil)
+		if err != nil {
+			return 0, err
+		}
+		hs := ethereum.NewHeaderStore()
+		if err := hs.Load(statedb); err != nil {
+			return 0, err
+		}
+		return hs.CurrentNumber(), nil
+	}
+
+	// Chains without a relayer config keep the testnet anchor
+	if number, err := storedNumber(nil); err != nil || number != 12065860 {
+		t.Errorf("default anchor mismatch: have (%d, %v), want (12065860, nil)", number, err)
+	}
+	td := big.NewInt(50000000000000000)
+	if number, err := storedNumber(&params2.RelayerConfig{ChainID: big.NewInt(1), AnchorHeaderRLP: anchorRLP, AnchorTD: td}); err != nil || number != 14000000 {
+		t.Errorf("RLP anchor mismatch: have (%d, %v), want (14000000, nil)", number, err)
+	}
+	anchorJSON := json.RawMessage(params2.EthereumTestnetGenesisHeader)
+	if number, err := storedNumber(&params2.RelayerConfig{ChainID: big.NewInt(3), AnchorHeader: anchorJSON, AnchorTD: td}); err != nil || number != 12065860 {
+		t.Errorf("JSON anchor mismatch: have (%d, %v), want (12065860, nil)", number, err)
+	}
+
+	// Bad anchors fail the genesis setup
+	for i, relayer := range []*params2.RelayerConfig{
+		{ChainID: big.NewInt(1), AnchorHeaderRLP: []byte{0x01, 0x02}, AnchorTD: td},
+		{ChainID: big.NewInt(1), AnchorHeader: json.RawMessage(`{"number":`), AnchorTD: td},
+		{ChainID: big.NewInt(1), AnchorHeader: anchorJSON, AnchorHeaderRLP: anchorRLP, AnchorTD: td},
+		{ChainID: big.NewInt(1), AnchorHeaderRLP: anchorRLP},
+		{ChainID: big.NewInt(1), AnchorHeaderRLP: anchorRLP, AnchorTD: big.NewInt(1)},
+		{ChainID: big.NewInt(5), AnchorHeaderRLP: anchorRLP, AnchorTD: td},
+	} {
+		if _, err := storedNumber(relayer); err == nil {
+			t.Errorf("relayer config %d: bad anchor accepted", i)
+		}
+	}
+}
+
+// Tests that upgrading the chain config keeps the old one for the blocks
+// processed with it.
+func TestSetupGenesisConfigHistory(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	config := *params2.TestChainConfig
+	if _, _, err := SetupGenesisBlock(db, &Genesis{Config: &config}); err != nil {
+		t.Fatalf("failed to set up genesis: %v", err)
+	}
+	genesisHash := rawdb.ReadCanonicalHash(db, 0)
+
+	// Advance the chain to block 10, then schedule a fork after it
+	head := &types.Header{Number: big.NewInt(10), ParentHash: genesisHash}
+	rawdb.WriteHeader(db, head)
+	rawdb.WriteHeadHeaderHash(db, head.Hash())
+
+	upgraded := config
+	upgraded.DonutBlock = big.NewInt(20)
+	if _, _, err := SetupGenesisBlock(db, &Genesis{Config: &upgraded}); err != nil {
+		t.Fatalf("failed to upgrade config: %v", err)
+	}
+	if old := rawdb.ReadChainConfigAt(db, genesisHash, 10); old == nil || old.DonutBlock != nil {
+		t.Errorf("config at the old head mismatch: have %v, want no donut block", old)
+	}
+	if current := rawdb.ReadChainConfigAt(db, genesisHash, 11); current == nil || current.DonutBlock == nil || current.DonutBlock.Uint64() != 20 {
+		t.Errorf("config after the old head mismatch: have %v, want donut block 20", current)
+	}
+	if current := rawdb.ReadChainConfig(db, genesisHash); current.DonutBlock == nil {
+		t.Errorf("current config not upgraded: %v", current)
+	}
+}
@@ -0,0 +1,79 @@
+package chain
+
+import (
+	"fmt"
+
+	"github.com/mapprotocol/atlas/core/types"
+)
+
+// The steps of a block replay, in the order they are run.
+const (
+	ReplayStepHeader         = "header"
+	ReplayStepTxRoot         = "transactionRoot"
+	ReplayStepEpochSnarkData = "epochSnarkData"
+	ReplayStepProcess        = "process"
+	ReplayStepGasUsed        = "gasUsed"
+	ReplayStepBloom          = "bloom"
+	ReplayStepReceiptRoot    = "receiptRoot"
+	ReplayStepStateRoot      = "stateRoot"
+)
+
+// ReplayResult describes the outcome of re-executing a block on the state of
+// its parent. Step is the first step which failed, empty if the block is valid,
+// with the value in the block and the one computed when they differ.
+type ReplayResult struct {
+	Hash     string `json:"hash"`
+	Number   uint64 `json:"number"`
+	Step     string `json:"failedStep,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Expected string `json:"expected,omitempty"`
+	Computed string `json:"computed,omitempty"`
+	GasUsed  uint64 `json:"gasUsed"`
+	Receipts int    `json:"receipts"`
+}
+
+func (r *ReplayResult) fail(step string, err error) *ReplayResult {
+	r.Step = step
+	if mismatch, ok := err.(*mismatchError); ok {
+		r.Expected, r.Computed = mismatch.remote, mismatch.computed
+	} else {
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// ReplayBlock runs the checks made when importing block again, verifying its
+// header, running the body checks of the block validator and processing it on
+// the state of its parent followed by the state checks, and reports the first
+// one failing. The body checks skip whether the block is already known.
+// The chain isn't modified. An error is returned if the block can't be
+// replayed, e.g. if the state of its parent is missing.
+func (bc *BlockChain) ReplayBlock(block *types.Block) (*ReplayResult, error) {
+	parent := bc.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %x of block %d not found", block.ParentHash(), block.NumberU64())
+	}
+	statedb, err := bc.StateAt(parent.Root)
+	if err != nil {
+		return nil, fmt.Errorf("state of parent %x not available: %v", block.ParentHash(), err)
+	}
+	result := &ReplayResult{Hash: block.Hash().Hex(), Number: block.NumberU64()}
+
+	if err := bc.engine.VerifyHeader(bc, block.Header(), true); err != nil {
+		return result.fail(ReplayStepHeader, err), nil
+	}
+	// The same checks as the validator, each one reported on its own
+	if step, err := runValidationSteps(bodyValidationSteps(bc.chainConfig, bc.engine, block)); err != nil {
+		return result.fail(step, err), nil
+	}
+	receipts, _, usedGas, err := bc.processor.Process(block, statedb, bc.vmConfig)
+	if err != nil {
+		return result.fail(ReplayStepProcess, err), nil
+	}
+	result.GasUsed, result.Receipts = usedGas, len(receipts)
+
+	if step, err := runValidationSteps(stateValidationSteps(bc.chainConfig, block, statedb, receipts, usedGas)); err != nil {
+		return result.fail(step, err), nil
+	}
+	return result, nil
+}
@@ -0,0 +1,63 @@
+package chain
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/mapprotocol/atlas/consensus/consensustest"
+	"github.com/mapprotocol/atlas/core/rawdb"
+	"github.com/mapprotocol/atlas/core/types"
+	"github.com/mapprotocol/atlas/core/vm"
+	"github.com/mapprotocol/atlas/params"
+)
+
+func TestReplayBlock(t *testing.T) {
+	var (
+		db        = rawdb.NewMemoryDatabase()
+		gspec     = &Genesis{Config: params.TestChainConfig}
+		genesis   = gspec.MustCommit(db)
+		blocks, _ = GenerateChain(params.TestChainConfig, genesis, consensustest.NewFaker(), db, 3, nil)
+	)
+	blockchain, _ := NewBlockChain(db, nil, params.TestChainConfig, consensustest.NewFaker(), vm.Config{}, nil, nil)
+	defer blockchain.Stop()
+	if _, err := blockchain.InsertChain(blocks[:2]); err != nil {
+		t.Fatalf("failed to insert blocks: %v", err)
+	}
+	tamper := func(modify func(header *types.Header)) *types.Block {
+		header := blocks[2].Header()
+		modify(header)
+		return types.NewBlockWithHeader(header).WithBody(blocks[2].Transactions(), blocks[2].Randomness(), blocks[2].EpochSnarkData())
+	}
+	tests := []struct {
+		block *types.Block
+		step  string
+	}{
+		{blocks[2], ""},
+		{tamper(func(header *types.Header) { header.GasUsed++ }), ReplayStepGasUsed},
+		{tamper(func(header *types.Header) { header.ReceiptHash = common.Hash{1} }), ReplayStepReceiptRoot},
+		{tamper(func(header *types.Header) { header.Root = common.Hash{1} }), ReplayStepStateRoot},
+		{tamper(func(header *types.Header) { header.TxHash = common.Hash{1} }), ReplayStepTxRoot},
+	}
+	for i, tt := range tests {
+		result, err := blockchain.ReplayBlock(tt.block)
+		if err != nil {
+			t.Fatalf("test %d: failed to replay block: %v", i, err)
+		}
+		if result.Step != tt.step {
+			t.Errorf("test %d: failed step mismatch: have %q (%s), want %q", i, result.Step, result.Error, tt.step)
+		}
+		if tt.step != "" && result.Expected == result.Computed {
+			t.Errorf("test %d: no difference reported: %+v", i, result)
+		}
+	}
+
+	// The replay doesn't change the chain
+	if head := blockchain.CurrentBlock().NumberU64(); head != 2 {
+		t.Errorf("head changed by the replay: have %d, want 2", head)
+	}
+	// Blocks without their parent state can't be replayed
+	orphan := types.NewBlockWithHeader(&types.Header{Number: blocks[2].Number(), ParentHash: common.Hash{1}})
+	if _, err := blockchain.ReplayBlock(orphan); err == nil {
+		t.Errorf("block without parent replayed")
+	}
+}
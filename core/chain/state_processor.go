@@ -111,6 +111,10 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 	p.engine.Finalize(p.bc, header, statedb, block.Transactions())
 
 	receipts = AddBlockReceipt(receipts, statedb, block.Hash())
+	if len(receipts) > len(block.Transactions()) {
+		// The logs of the block receipt are delivered along with the ones of the transactions
+		allLogs = append(allLogs, receipts[len(receipts)-1].Logs...)
+	}
 	return receipts, allLogs, *usedGas, nil
 }
 
@@ -21,14 +21,18 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"math"
 	"math/big"
+	"runtime"
 	"sort"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rlp"
 
+	"github.com/mapprotocol/atlas/chains"
 	"github.com/mapprotocol/atlas/consensus/istanbul"
 	"github.com/mapprotocol/atlas/consensus/istanbul/uptime"
 	"github.com/mapprotocol/atlas/core/types"
@@ -175,6 +179,29 @@ func DeleteHeaderNumber(db ethdb.KeyValueWriter, hash common.Hash) {
 	}
 }
 
+// VerifyHeaderNumberConsistency walks the canonical chain between from and to
+// (both inclusive) and checks that the number->hash and hash->number mappings
+// agree. The canonical hashes whose reverse mapping is missing or points to a
+// different number are returned. Numbers without a canonical hash are skipped.
+func VerifyHeaderNumberConsistency(db ethdb.Reader, from, to uint64) ([]common.Hash, error) {
+	if from > to {
+		return nil, fmt.Errorf("invalid range: from %d > to %d", from, to)
+	}
+	var mismatches []common.Hash
+	for number := from; ; number++ {
+		hash := ReadCanonicalHash(db, number)
+		if hash != (common.Hash{}) {
+			if n := ReadHeaderNumber(db, hash); n == nil || *n != number {
+				mismatches = append(mismatches, hash)
+			}
+		}
+		if number == to {
+			break
+		}
+	}
+	return mismatches, nil
+}
+
 // ReadHeadHeaderHash retrieves the hash of the current canonical head header.
 func ReadHeadHeaderHash(db ethdb.KeyValueReader) common.Hash {
 	data, _ := db.Get(headHeaderKey)
@@ -207,6 +234,68 @@ func WriteHeadBlockHash(db ethdb.KeyValueWriter, hash common.Hash) {
 	}
 }
 
+// HeadBlockMeta summarizes the head block, so that it can be reported without
+// decoding the head header.
+type HeadBlockMeta struct {
+	Number uint64
+	Hash   common.Hash
+	Root   common.Hash
+	Time   uint64
+}
+
+// ReadHeadBlockMeta retrieves the summary of the current canonical head block,
+// or nil if it was never stored.
+func ReadHeadBlockMeta(db ethdb.KeyValueReader) *HeadBlockMeta {
+	data, _ := db.Get(headBlockMetaKey)
+	if len(data) == 0 {
+		return nil
+	}
+	meta := new(HeadBlockMeta)
+	if err := rlp.DecodeBytes(data, meta); err != nil {
+		log.Error("Invalid head block meta RLP", "err", err)
+		return nil
+	}
+	return meta
+}
+
+// WriteHeadBlockMeta stores the summary of the head block.
+func WriteHeadBlockMeta(db ethdb.KeyValueWriter, header *types.Header) {
+	data, err := rlp.EncodeToBytes(&HeadBlockMeta{
+		Number: header.Number.Uint64(),
+		Hash:   header.Hash(),
+		Root:   header.Root,
+		Time:   header.Time,
+	})
+	if err != nil {
+		log.Crit("Failed to RLP encode head block meta", "err", err)
+	}
+	if err := db.Put(headBlockMetaKey, data); err != nil {
+		log.Crit("Failed to store head block meta", "err", err)
+	}
+}
+
+// WriteHeadBlock stores the head block's hash along with its summary.
+func WriteHeadBlock(db ethdb.KeyValueWriter, header *types.Header) {
+	WriteHeadBlockHash(db, header.Hash())
+	WriteHeadBlockMeta(db, header)
+}
+
+// ReadFinalizedBlockHash retrieves the hash of the latest finalized block.
+func ReadFinalizedBlockHash(db ethdb.KeyValueReader) common.Hash {
+	data, _ := db.Get(finalizedBlockKey)
+	if len(data) == 0 {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// WriteFinalizedBlockHash stores the hash of the latest finalized block.
+func WriteFinalizedBlockHash(db ethdb.KeyValueWriter, hash common.Hash) {
+	if err := db.Put(finalizedBlockKey, hash.Bytes()); err != nil {
+		log.Crit("Failed to store last finalized block's hash", "err", err)
+	}
+}
+
 // ReadHeadFastBlockHash retrieves the hash of the current fast-sync head block.
 func ReadHeadFastBlockHash(db ethdb.KeyValueReader) common.Hash {
 	data, _ := db.Get(headFastBlockKey)
@@ -306,17 +395,25 @@ func WriteFastTxLookupLimit(db ethdb.KeyValueWriter, number uint64) {
 
 // ReadHeaderRLP retrieves a block header in its raw RLP database encoding.
 func ReadHeaderRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawValue {
+	start := headerMetrics.start()
+	data, source := readHeaderRLP(db, hash, number)
+	headerMetrics.mark(start, source)
+	return data
+}
+
+// readHeaderRLP implements ReadHeaderRLP, also reporting where the data was found.
+func readHeaderRLP(db ethdb.Reader, hash common.Hash, number uint64) (rlp.RawValue, dataSource) {
 	// First try to look up the data in ancient database. Extra hash
 	// comparison is necessary since ancient database only maintains
 	// the canonical data.
 	data, _ := db.Ancient(freezerHeaderTable, number)
 	if len(data) > 0 {
-		return data
+		return data, sourceAncient
 	}
 	// Then try to look up the data in leveldb.
 	data, _ = db.Get(headerKey(number, hash))
 	if len(data) > 0 {
-		return data
+		return data, sourceKV
 	}
 	// In the background freezer is moving data from leveldb to flatten files.
 	// So during the first check for ancient db, the data is not yet in there,
@@ -324,9 +421,9 @@ func ReadHeaderRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawValu
 	// result in a not found error.
 	data, _ = db.Ancient(freezerHeaderTable, number)
 	if len(data) > 0 {
-		return data
+		return data, sourceAncient
 	}
-	return nil // Can't find the data anywhere.
+	return nil, sourceMiss // Can't find the data anywhere.
 }
 
 // HasHeader verifies the existence of a block header corresponding to the hash.
@@ -342,16 +439,56 @@ func HasHeader(db ethdb.Reader, hash common.Hash, number uint64) bool {
 
 // ReadHeader retrieves the block header corresponding to the hash.
 func ReadHeader(db ethdb.Reader, hash common.Hash, number uint64) *types.Header {
+	header, err := ReadHeaderErr(db, hash, number)
+	if err != nil && err != ErrMissingHeader {
+		log.Error("Invalid block header RLP", "hash", hash, "err", err)
+	}
+	return header
+}
+
+// ReadHeaderErr is ReadHeader returning ErrMissingHeader if the header isn't
+// stored and an error wrapping ErrCorruptData if it can't be decoded.
+func ReadHeaderErr(db ethdb.Reader, hash common.Hash, number uint64) (*types.Header, error) {
 	data := ReadHeaderRLP(db, hash, number)
 	if len(data) == 0 {
-		return nil
+		return nil, ErrMissingHeader
 	}
 	header := new(types.Header)
 	if err := rlp.Decode(bytes.NewReader(data), header); err != nil {
-		log.Error("Invalid block header RLP", "hash", hash, "err", err)
+		return nil, fmt.Errorf("%w: header of block #%d [%x]: %v", ErrCorruptData, number, hash, err)
+	}
+	return header, nil
+}
+
+// ReadCanonicalHeader retrieves the canonical header with the given number, or
+// nil if there is no canonical hash or header for it.
+func ReadCanonicalHeader(db ethdb.Reader, number uint64) *types.Header {
+	hash := ReadCanonicalHash(db, number)
+	if hash == (common.Hash{}) {
 		return nil
 	}
-	return header
+	return ReadHeader(db, hash, number)
+}
+
+// ReadHeadersInRange retrieves the canonical headers between first and last,
+// both limits being inclusive. Numbers without a canonical hash or header are
+// skipped, so the result is ordered by number but may contain gaps.
+func ReadHeadersInRange(db ethdb.Reader, first, last uint64) []*types.Header {
+	if first > last {
+		return nil
+	}
+	var headers []*types.Header
+	for number := first; ; number++ {
+		if hash := ReadCanonicalHash(db, number); hash != (common.Hash{}) {
+			if header := ReadHeader(db, hash, number); header != nil {
+				headers = append(headers, header)
+			}
+		}
+		if number == last {
+			break
+		}
+	}
+	return headers
 }
 
 // WriteHeader stores a block header into the database and also stores the hash-
@@ -393,6 +530,14 @@ func deleteHeaderWithoutNumber(db ethdb.KeyValueWriter, hash common.Hash, number
 
 // ReadBodyRLP retrieves the block body (transactions and uncles) in RLP encoding.
 func ReadBodyRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawValue {
+	start := bodyMetrics.start()
+	data, source := readBodyRLP(db, hash, number)
+	bodyMetrics.mark(start, source)
+	return data
+}
+
+// readBodyRLP implements ReadBodyRLP, also reporting where the data was found.
+func readBodyRLP(db ethdb.Reader, hash common.Hash, number uint64) (rlp.RawValue, dataSource) {
 	// First try to look up the data in ancient database. Extra hash
 	// comparison is necessary since ancient database only maintains
 	// the canonical data.
@@ -400,13 +545,13 @@ func ReadBodyRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawValue
 	if len(data) > 0 {
 		h, _ := db.Ancient(freezerHashTable, number)
 		if common.BytesToHash(h) == hash {
-			return data
+			return data, sourceAncient
 		}
 	}
 	// Then try to look up the data in leveldb.
 	data, _ = db.Get(blockBodyKey(number, hash))
 	if len(data) > 0 {
-		return data
+		return data, sourceKV
 	}
 	// In the background freezer is moving data from leveldb to flatten files.
 	// So during the first check for ancient db, the data is not yet in there,
@@ -416,10 +561,10 @@ func ReadBodyRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawValue
 	if len(data) > 0 {
 		h, _ := db.Ancient(freezerHashTable, number)
 		if common.BytesToHash(h) == hash {
-			return data
+			return data, sourceAncient
 		}
 	}
-	return nil // Can't find the data anywhere.
+	return nil, sourceMiss // Can't find the data anywhere.
 }
 
 // ReadCanonicalBodyRLP retrieves the block body (transactions and uncles) for the canonical
@@ -441,6 +586,82 @@ func ReadCanonicalBodyRLP(db ethdb.Reader, number uint64) rlp.RawValue {
 	return data
 }
 
+// Positions of the fields of an RLP encoded block body, see types.Body.
+const (
+	bodyTransactionsField = iota
+	bodyRandomnessField
+	bodyEpochSnarkDataField
+	bodyFields
+)
+
+// splitBodyRLP splits an RLP encoded block body into the raw encodings of its
+// fields without decoding them.
+func splitBodyRLP(body rlp.RawValue) ([]rlp.RawValue, error) {
+	rest, _, err := rlp.SplitList(body)
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]rlp.RawValue, bodyFields)
+	for i := range fields {
+		_, _, tail, err := rlp.Split(rest)
+		if err != nil {
+			return nil, err
+		}
+		fields[i] = rest[:len(rest)-len(tail)]
+		rest = tail
+	}
+	return fields, nil
+}
+
+// readBodyField retrieves the raw encoding of a canonical block body field. For
+// ancient blocks it is read from the given freezer table, for recent ones from
+// the block body.
+func readBodyField(db ethdb.Reader, kind string, field int, number uint64) rlp.RawValue {
+	if data, _ := db.Ancient(kind, number); len(data) > 0 {
+		return data
+	}
+	body := ReadCanonicalBodyRLP(db, number)
+	if len(body) == 0 {
+		return nil
+	}
+	fields, err := splitBodyRLP(body)
+	if err != nil {
+		log.Error("Invalid block body RLP", "number", number, "err", err)
+		return nil
+	}
+	return fields[field]
+}
+
+// ReadRandomness retrieves the randomness of the canonical block with the given
+// number without decoding the rest of its body.
+func ReadRandomness(db ethdb.Reader, number uint64) *types.Randomness {
+	data := readBodyField(db, freezerRandomnessTable, bodyRandomnessField, number)
+	if len(data) == 0 {
+		return nil
+	}
+	randomness := new(types.Randomness)
+	if err := rlp.DecodeBytes(data, randomness); err != nil {
+		log.Error("Invalid block randomness RLP", "number", number, "err", err)
+		return nil
+	}
+	return randomness
+}
+
+// ReadEpochSnarkData retrieves the epoch SNARK data of the canonical block with
+// the given number without decoding the rest of its body.
+func ReadEpochSnarkData(db ethdb.Reader, number uint64) *types.EpochSnarkData {
+	data := readBodyField(db, freezerEpochSnarkDataTable, bodyEpochSnarkDataField, number)
+	if len(data) == 0 {
+		return nil
+	}
+	snarkData := new(types.EpochSnarkData)
+	if err := rlp.DecodeBytes(data, snarkData); err != nil {
+		log.Error("Invalid epoch snark data RLP", "number", number, "err", err)
+		return nil
+	}
+	return snarkData
+}
+
 // WriteBodyRLP stores an RLP encoded block body into the database.
 func WriteBodyRLP(db ethdb.KeyValueWriter, hash common.Hash, number uint64, rlp rlp.RawValue) {
 	if err := db.Put(blockBodyKey(number, hash), rlp); err != nil {
@@ -461,16 +682,25 @@ func HasBody(db ethdb.Reader, hash common.Hash, number uint64) bool {
 
 // ReadBody retrieves the block body corresponding to the hash.
 func ReadBody(db ethdb.Reader, hash common.Hash, number uint64) *types.Body {
+	body, err := ReadBodyErr(db, hash, number)
+	if err != nil && err != ErrMissingBody {
+		log.Error("Invalid block body RLP", "hash", hash, "err", err)
+	}
+	return body
+}
+
+// ReadBodyErr is ReadBody returning ErrMissingBody if the body isn't stored and
+// an error wrapping ErrCorruptData if it can't be decoded.
+func ReadBodyErr(db ethdb.Reader, hash common.Hash, number uint64) (*types.Body, error) {
 	data := ReadBodyRLP(db, hash, number)
 	if len(data) == 0 {
-		return nil
+		return nil, ErrMissingBody
 	}
 	body := new(types.Body)
 	if err := rlp.Decode(bytes.NewReader(data), body); err != nil {
-		log.Error("Invalid block body RLP", "hash", hash, "err", err)
-		return nil
+		return nil, fmt.Errorf("%w: body of block #%d [%x]: %v", ErrCorruptData, number, hash, err)
 	}
-	return body
+	return body, nil
 }
 
 // WriteBody stores a block body into the database.
@@ -491,6 +721,14 @@ func DeleteBody(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
 
 // ReadTdRLP retrieves a block's total difficulty corresponding to the hash in RLP encoding.
 func ReadTdRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawValue {
+	start := tdMetrics.start()
+	data, source := readTdRLP(db, hash, number)
+	tdMetrics.mark(start, source)
+	return data
+}
+
+// readTdRLP implements ReadTdRLP, also reporting where the data was found.
+func readTdRLP(db ethdb.Reader, hash common.Hash, number uint64) (rlp.RawValue, dataSource) {
 	// First try to look up the data in ancient database. Extra hash
 	// comparison is necessary since ancient database only maintains
 	// the canonical data.
@@ -498,13 +736,13 @@ func ReadTdRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawValue {
 	if len(data) > 0 {
 		h, _ := db.Ancient(freezerHashTable, number)
 		if common.BytesToHash(h) == hash {
-			return data
+			return data, sourceAncient
 		}
 	}
 	// Then try to look up the data in leveldb.
 	data, _ = db.Get(headerTDKey(number, hash))
 	if len(data) > 0 {
-		return data
+		return data, sourceKV
 	}
 	// In the background freezer is moving data from leveldb to flatten files.
 	// So during the first check for ancient db, the data is not yet in there,
@@ -514,24 +752,33 @@ func ReadTdRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawValue {
 	if len(data) > 0 {
 		h, _ := db.Ancient(freezerHashTable, number)
 		if common.BytesToHash(h) == hash {
-			return data
+			return data, sourceAncient
 		}
 	}
-	return nil // Can't find the data anywhere.
+	return nil, sourceMiss // Can't find the data anywhere.
 }
 
 // ReadTd retrieves a block's total difficulty corresponding to the hash.
 func ReadTd(db ethdb.Reader, hash common.Hash, number uint64) *big.Int {
+	td, err := ReadTdErr(db, hash, number)
+	if err != nil && err != ErrMissingTd {
+		log.Error("Invalid block total difficulty RLP", "hash", hash, "err", err)
+	}
+	return td
+}
+
+// ReadTdErr is ReadTd returning ErrMissingTd if the total difficulty isn't
+// stored and an error wrapping ErrCorruptData if it can't be decoded.
+func ReadTdErr(db ethdb.Reader, hash common.Hash, number uint64) (*big.Int, error) {
 	data := ReadTdRLP(db, hash, number)
 	if len(data) == 0 {
-		return nil
+		return nil, ErrMissingTd
 	}
 	td := new(big.Int)
 	if err := rlp.Decode(bytes.NewReader(data), td); err != nil {
-		log.Error("Invalid block total difficulty RLP", "hash", hash, "err", err)
-		return nil
+		return nil, fmt.Errorf("%w: total difficulty of block #%d [%x]: %v", ErrCorruptData, number, hash, err)
 	}
-	return td
+	return td, nil
 }
 
 // WriteTd stores the total difficulty of a block into the database.
@@ -566,6 +813,14 @@ func HasReceipts(db ethdb.Reader, hash common.Hash, number uint64) bool {
 
 // ReadReceiptsRLP retrieves all the transaction receipts belonging to a block in RLP encoding.
 func ReadReceiptsRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawValue {
+	start := receiptsMetrics.start()
+	data, source := readReceiptsRLP(db, hash, number)
+	receiptsMetrics.mark(start, source)
+	return data
+}
+
+// readReceiptsRLP implements ReadReceiptsRLP, also reporting where the data was found.
+func readReceiptsRLP(db ethdb.Reader, hash common.Hash, number uint64) (rlp.RawValue, dataSource) {
 	// First try to look up the data in ancient database. Extra hash
 	// comparison is necessary since ancient database only maintains
 	// the canonical data.
@@ -573,13 +828,13 @@ func ReadReceiptsRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawVa
 	if len(data) > 0 {
 		h, _ := db.Ancient(freezerHashTable, number)
 		if common.BytesToHash(h) == hash {
-			return data
+			return data, sourceAncient
 		}
 	}
 	// Then try to look up the data in leveldb.
 	data, _ = db.Get(blockReceiptsKey(number, hash))
 	if len(data) > 0 {
-		return data
+		return data, sourceKV
 	}
 	// In the background freezer is moving data from leveldb to flatten files.
 	// So during the first check for ancient db, the data is not yet in there,
@@ -589,32 +844,103 @@ func ReadReceiptsRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawVa
 	if len(data) > 0 {
 		h, _ := db.Ancient(freezerHashTable, number)
 		if common.BytesToHash(h) == hash {
-			return data
+			return data, sourceAncient
 		}
 	}
-	return nil // Can't find the data anywhere.
+	return nil, sourceMiss // Can't find the data anywhere.
 }
 
 // ReadRawReceipts retrieves all the transaction receipts belonging to a block.
 // The receipt metadata fields are not guaranteed to be populated, so they
 // should not be used. Use ReadReceipts instead if the metadata is needed.
 func ReadRawReceipts(db ethdb.Reader, hash common.Hash, number uint64) types.Receipts {
+	receipts, err := readRawReceipts(db, hash, number)
+	if err != nil {
+		if err != ErrNoReceipts {
+			log.Error("Invalid receipt array RLP", "hash", hash, "err", err)
+		}
+		return nil
+	}
+	return receipts
+}
+
+// readRawReceipts implements ReadRawReceipts, returning ErrNoReceipts if no
+// receipts are stored and the decoding error if they are invalid.
+func readRawReceipts(db ethdb.Reader, hash common.Hash, number uint64) (types.Receipts, error) {
 	// Retrieve the flattened receipt slice
 	data := ReadReceiptsRLP(db, hash, number)
 	if len(data) == 0 {
-		return nil
+		return nil, ErrNoReceipts
+	}
+	// Large blocks are decoded concurrently, receipt by receipt
+	if content, _, err := rlp.SplitList(data); err == nil {
+		if count, err := rlp.CountValues(content); err == nil && count > parallelReceiptsThreshold {
+			return decodeReceiptsParallel(content, count)
+		}
 	}
 	// Convert the receipts from their storage form to their internal representation
 	storageReceipts := []*types.ReceiptForStorage{}
 	if err := rlp.DecodeBytes(data, &storageReceipts); err != nil {
-		log.Error("Invalid receipt array RLP", "hash", hash, "err", err)
-		return nil
+		return nil, err
 	}
 	receipts := make(types.Receipts, len(storageReceipts))
 	for i, storageReceipt := range storageReceipts {
 		receipts[i] = (*types.Receipt)(storageReceipt)
 	}
-	return receipts
+	return receipts, nil
+}
+
+// parallelReceiptsThreshold is the number of receipts above which
+// ReadRawReceipts decodes them over multiple goroutines.
+const parallelReceiptsThreshold = 256
+
+// decodeReceiptsParallel decodes the count storage receipts in the content of
+// an RLP list, spreading them over a worker per CPU.
+func decodeReceiptsParallel(content []byte, count int) (types.Receipts, error) {
+	blobs := make([][]byte, 0, count)
+	for len(content) > 0 {
+		_, _, rest, err := rlp.Split(content)
+		if err != nil {
+			return nil, err
+		}
+		blobs = append(blobs, content[:len(content)-len(rest)])
+		content = rest
+	}
+	var (
+		receipts = make(types.Receipts, len(blobs))
+		errs     = make([]error, len(blobs))
+		next     = make(chan int, len(blobs))
+		wg       sync.WaitGroup
+	)
+	for i := range blobs {
+		next <- i
+	}
+	close(next)
+
+	workers := runtime.NumCPU()
+	if workers > len(blobs) {
+		workers = len(blobs)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				receipt := new(types.ReceiptForStorage)
+				if errs[i] = rlp.DecodeBytes(blobs[i], receipt); errs[i] == nil {
+					receipts[i] = (*types.Receipt)(receipt)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("receipt %d: %v", i, err)
+		}
+	}
+	return receipts, nil
 }
 
 // ReadReceipts retrieves all the transaction receipts belonging to a block, including
@@ -625,21 +951,65 @@ func ReadRawReceipts(db ethdb.Reader, hash common.Hash, number uint64) types.Rec
 // corresponding block body, so if the block body is not found it will return nil even
 // if the receipt itself is stored.
 func ReadReceipts(db ethdb.Reader, hash common.Hash, number uint64, config *params.ChainConfig) types.Receipts {
+	receipts, err := ReadReceiptsErr(db, hash, number, config)
+	switch {
+	case err == nil:
+		return receipts
+	case errors.Is(err, ErrNoReceipts):
+	case errors.Is(err, ErrMissingBody):
+		log.Error("Missing body but have receipt", "hash", hash, "number", number)
+	case errors.Is(err, ErrReceiptCountMismatch):
+		log.Error("Failed to derive block receipts fields", "hash", hash, "number", number, "err", err)
+	default:
+		log.Error("Failed to read block receipts", "hash", hash, "number", number, "err", err)
+	}
+	return nil
+}
+
+// ErrReceiptCountMismatch is returned by ReadReceiptsErr if the number of stored
+// receipts matches neither the number of transactions in the block body nor
+// that number plus the block finalization receipt (only IBFT).
+var ErrReceiptCountMismatch = errors.New("transaction and receipt count mismatch")
+
+// ReadReceiptsErr is ReadReceipts reporting why no receipts are returned:
+// ErrNoReceipts if none are stored, ErrMissingBody if the block body they are
+// derived from isn't, an error wrapping ErrCorruptData if the receipts or the
+// body can't be decoded and an error wrapping ErrReceiptCountMismatch if they
+// don't belong to the body's transactions.
+func ReadReceiptsErr(db ethdb.Reader, hash common.Hash, number uint64, config *params.ChainConfig) (types.Receipts, error) {
 	// We're deriving many fields from the block body, retrieve beside the receipt
-	receipts := ReadRawReceipts(db, hash, number)
-	if receipts == nil {
-		return nil
+	receipts, err := readRawReceipts(db, hash, number)
+	if err != nil {
+		if err == ErrNoReceipts {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: receipts of block #%d [%x]: %v", ErrCorruptData, number, hash, err)
 	}
-	body := ReadBody(db, hash, number)
-	if body == nil {
-		log.Error("Missing body but have receipt", "hash", hash, "number", number)
-		return nil
+	body, err := ReadBodyErr(db, hash, number)
+	if err == ErrMissingBody {
+		return nil, fmt.Errorf("%w of block #%d [%x] with %d receipts", ErrMissingBody, number, hash, len(receipts))
+	} else if err != nil {
+		return nil, err
+	}
+	if txs := len(body.Transactions); !(txs == len(receipts) || txs+1 == len(receipts)) {
+		return nil, fmt.Errorf("%w in block #%d [%x]: have %d receipts, want %d or %d with the finalization receipt",
+			ErrReceiptCountMismatch, number, hash, len(receipts), txs, txs+1)
 	}
 	if err := receipts.DeriveFields(config, hash, number, body.Transactions); err != nil {
-		log.Error("Failed to derive block receipts fields", "hash", hash, "number", number, "err", err)
+		return nil, fmt.Errorf("failed to derive receipt fields of block #%d [%x]: %v", number, hash, err)
+	}
+	return receipts, nil
+}
+
+// ReadReceiptsAt is ReadReceipts deriving the metadata fields with the chain
+// config the block was processed with.
+func ReadReceiptsAt(db ethdb.Reader, hash common.Hash, number uint64, configs ChainConfigProvider) types.Receipts {
+	config := configs.ChainConfigAt(number)
+	if config == nil {
+		log.Error("Missing chain config of block", "hash", hash, "number", number)
 		return nil
 	}
-	return receipts
+	return ReadReceipts(db, hash, number, config)
 }
 
 // WriteReceipts stores all the transaction receipts belonging to a block.
@@ -659,6 +1029,29 @@ func WriteReceipts(db ethdb.KeyValueWriter, hash common.Hash, number uint64, rec
 	}
 }
 
+// WriteReceiptsChecked is WriteReceipts for receipts that have not been verified
+// yet. Before writing, it checks that the receipts belong to the given header:
+// their logs bloom must match the header's and their count must match the
+// number of transactions in the stored body, plus the optional IBFT block
+// finalization receipt. Nothing is written on a mismatch.
+func WriteReceiptsChecked(db ethdb.Database, hash common.Hash, number uint64, receipts types.Receipts, header *types.Header) error {
+	if header.Hash() != hash || header.Number.Uint64() != number {
+		return fmt.Errorf("header #%d [%x] does not match receipts block #%d [%x]", header.Number, header.Hash(), number, hash)
+	}
+	body := ReadBody(db, hash, number)
+	if body == nil {
+		return fmt.Errorf("missing body of block #%d [%x]", number, hash)
+	}
+	if txs := len(body.Transactions); !(txs == len(receipts) || txs+1 == len(receipts)) {
+		return fmt.Errorf("receipt count mismatch: have %d, want %d transactions", len(receipts), txs)
+	}
+	if bloom := types.CreateBloom(receipts); bloom != header.Bloom {
+		return fmt.Errorf("logs bloom mismatch: have %x, want %x", bloom, header.Bloom)
+	}
+	WriteReceipts(db, hash, number, receipts)
+	return nil
+}
+
 // DeleteReceipts removes all receipt data associated with a block hash.
 func DeleteReceipts(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
 	if err := db.Delete(blockReceiptsKey(number, hash)); err != nil {
@@ -728,35 +1121,144 @@ func deriveLogFields(receipts []*receiptLogs, hash common.Hash, number uint64, t
 	return nil
 }
 
+var (
+	// ErrNoReceipts is returned by the log accessors if no receipts are stored
+	// for the requested block.
+	ErrNoReceipts = errors.New("no receipts stored")
+
+	// ErrMissingBody is returned by the log accessors if the receipts of the
+	// requested block are stored but its body isn't, so the body has to be
+	// retrieved again before the logs can be derived.
+	ErrMissingBody = errors.New("missing block body")
+
+	// ErrMissingHeader is returned by ReadHeaderErr and ReadBlockErr if the
+	// header of the requested block isn't stored.
+	ErrMissingHeader = errors.New("missing block header")
+
+	// ErrMissingTd is returned by ReadTdErr if the total difficulty of the
+	// requested block isn't stored.
+	ErrMissingTd = errors.New("missing total difficulty")
+
+	// ErrCorruptData is wrapped by the errors of the Read*Err accessors if the
+	// stored data can't be decoded, as opposed to not being stored.
+	ErrCorruptData = errors.New("corrupt database entry")
+)
+
 // ReadLogs retrieves the logs for all transactions in a block. The log fields
-// are populated with metadata. In case the receipts or the block body
-// are not found, a nil is returned.
-func ReadLogs(db ethdb.Reader, hash common.Hash, number uint64) [][]*types.Log {
+// are populated with metadata. In case the receipts are not found
+// ErrNoReceipts is returned, in case the block body is not found
+// ErrMissingBody is returned.
+func ReadLogs(db ethdb.Reader, hash common.Hash, number uint64) ([][]*types.Log, error) {
 	// Retrieve the flattened receipt slice
 	data := ReadReceiptsRLP(db, hash, number)
 	if len(data) == 0 {
-		return nil
+		return nil, ErrNoReceipts
 	}
 	receipts := []*receiptLogs{}
 	if err := rlp.DecodeBytes(data, &receipts); err != nil {
 		log.Error("Invalid receipt array RLP", "hash", hash, "err", err)
-		return nil
+		return nil, err
 	}
 
 	body := ReadBody(db, hash, number)
 	if body == nil {
 		log.Error("Missing body but have receipt", "hash", hash, "number", number)
-		return nil
+		return nil, ErrMissingBody
 	}
 	if err := deriveLogFields(receipts, hash, number, body.Transactions); err != nil {
 		log.Error("Failed to derive block receipts fields", "hash", hash, "number", number, "err", err)
-		return nil
+		return nil, err
 	}
 	logs := make([][]*types.Log, len(receipts))
 	for i, receipt := range receipts {
 		logs[i] = receipt.Logs
 	}
-	return logs
+	return logs, nil
+}
+
+// ReadLogsForTx retrieves the logs of the transaction at txIndex in a block,
+// with the log fields populated with metadata. Only the receipts up to the
+// requested one are decoded. An index one past the last transaction refers to
+// the block finalization receipt (only IBFT), whose logs carry the block hash
+// as transaction hash. The errors are the same as for ReadLogs.
+func ReadLogsForTx(db ethdb.Reader, hash common.Hash, number uint64, txIndex uint) ([]*types.Log, error) {
+	data := ReadReceiptsRLP(db, hash, number)
+	if len(data) == 0 {
+		return nil, ErrNoReceipts
+	}
+	body := ReadBody(db, hash, number)
+	if body == nil {
+		log.Error("Missing body but have receipt", "hash", hash, "number", number)
+		return nil, ErrMissingBody
+	}
+	if txIndex > uint(len(body.Transactions)) {
+		return nil, fmt.Errorf("transaction index %d out of range", txIndex)
+	}
+	// Skip over the preceding receipts, only counting their logs
+	s := rlp.NewStream(bytes.NewReader(data), uint64(len(data)))
+	if _, err := s.List(); err != nil {
+		return nil, err
+	}
+	logIndex := uint(0)
+	for i := uint(0); i < txIndex; i++ {
+		logs, err := countReceiptLogs(s)
+		if err == rlp.EOL {
+			return nil, errors.New("transaction and receipt count mismatch")
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid receipt #%d RLP: %v", i, err)
+		}
+		logIndex += logs
+	}
+	receipt := new(receiptLogs)
+	if err := s.Decode(receipt); err == rlp.EOL {
+		// Only the finalization receipt may be missing
+		return nil, fmt.Errorf("transaction index %d out of range", txIndex)
+	} else if err != nil {
+		return nil, fmt.Errorf("invalid receipt #%d RLP: %v", txIndex, err)
+	}
+	txHash := hash
+	if txIndex < uint(len(body.Transactions)) {
+		txHash = body.Transactions[txIndex].Hash()
+	}
+	for _, l := range receipt.Logs {
+		l.BlockNumber = number
+		l.BlockHash = hash
+		l.TxHash = txHash
+		l.TxIndex = txIndex
+		l.Index = logIndex
+		logIndex++
+	}
+	return receipt.Logs, nil
+}
+
+// countReceiptLogs skips the next stored receipt in s, returning the number of
+// logs it contains.
+func countReceiptLogs(s *rlp.Stream) (uint, error) {
+	if _, err := s.List(); err != nil {
+		return 0, err
+	}
+	// Skip the status and the cumulative gas used
+	for i := 0; i < 2; i++ {
+		if _, err := s.Raw(); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := s.List(); err != nil {
+		return 0, err
+	}
+	var logs uint
+	for {
+		if _, err := s.Raw(); err == rlp.EOL {
+			break
+		} else if err != nil {
+			return 0, err
+		}
+		logs++
+	}
+	if err := s.ListEnd(); err != nil {
+		return 0, err
+	}
+	return logs, s.ListEnd()
 }
 
 // ReadBlock retrieves an entire block corresponding to the hash, assembling it
@@ -777,54 +1279,214 @@ func ReadBlock(db ethdb.Reader, hash common.Hash, number uint64) *types.Block {
 	return types.NewBlockWithHeader(header).WithBody(body.Transactions, body.Randomness, body.EpochSnarkData)
 }
 
+// ReadBlockErr is ReadBlock returning the error of ReadHeaderErr or ReadBodyErr
+// if the block can't be assembled.
+func ReadBlockErr(db ethdb.Reader, hash common.Hash, number uint64) (*types.Block, error) {
+	header, err := ReadHeaderErr(db, hash, number)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ReadBodyErr(db, hash, number)
+	if err != nil {
+		return nil, err
+	}
+	return types.NewBlockWithHeader(header).WithBody(body.Transactions, body.Randomness, body.EpochSnarkData), nil
+}
+
+// ReadCanonicalBlock retrieves the canonical block with the given number, or
+// nil if there is no canonical hash or the block could not be assembled.
+func ReadCanonicalBlock(db ethdb.Reader, number uint64) *types.Block {
+	hash := ReadCanonicalHash(db, number)
+	if hash == (common.Hash{}) {
+		return nil
+	}
+	return ReadBlock(db, hash, number)
+}
+
 // WriteBlock serializes a block into the database, header and body separately.
 func WriteBlock(db ethdb.KeyValueWriter, block *types.Block) {
 	WriteBody(db, block.Hash(), block.NumberU64(), block.Body())
 	WriteHeader(db, block.Header())
 }
 
-// WriteAncientBlocks writes entire block data into ancient store and returns the total written size.
-func WriteAncientBlocks(db ethdb.AncientWriter, blocks []*types.Block, receipts []types.Receipts, td *big.Int) (int64, error) {
-	var (
-		tdSum      = new(big.Int).Set(td)
-		stReceipts []*types.ReceiptForStorage
-	)
-	return db.ModifyAncients(func(op ethdb.AncientWriteOp) error {
-		for i, block := range blocks {
-			// Convert receipts to storage format and sum up total difficulty.
-			stReceipts = stReceipts[:0]
-			for _, receipt := range receipts[i] {
-				stReceipts = append(stReceipts, (*types.ReceiptForStorage)(receipt))
-			}
-			header := block.Header()
-			if i > 0 {
-				tdSum.Add(tdSum, block.TotalDifficulty())
-			}
-			if err := writeAncientBlock(op, block, header, stReceipts, tdSum); err != nil {
-				return err
-			}
+// ancientRangeReadBytes is the read buffer size used by ReadAncientRange for a
+// single ReadAncients call.
+const ancientRangeReadBytes = 2 * 1024 * 1024
+
+// readAncientItems reads count consecutive items of an ancient table starting
+// at start, using as few ReadAncients calls as the buffer size allows.
+func readAncientItems(db ethdb.AncientReader, kind string, start, count uint64) ([][]byte, error) {
+	items := make([][]byte, 0, count)
+	for uint64(len(items)) < count {
+		next := start + uint64(len(items))
+		batch, err := db.ReadAncients(kind, next, count-uint64(len(items)), ancientRangeReadBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ancient %s #%d: %v", kind, next, err)
 		}
-		return nil
-	})
+		if len(batch) == 0 {
+			return nil, fmt.Errorf("missing ancient %s #%d", kind, next)
+		}
+		items = append(items, batch...)
+	}
+	return items, nil
 }
 
-func writeAncientBlock(op ethdb.AncientWriteOp, block *types.Block, header *types.Header, receipts []*types.ReceiptForStorage, td *big.Int) error {
-	num := block.NumberU64()
-	if err := op.AppendRaw(freezerHashTable, num, block.Hash().Bytes()); err != nil {
-		return fmt.Errorf("can't add block %d hash: %v", num, err)
-	}
-	if err := op.Append(freezerHeaderTable, num, header); err != nil {
-		return fmt.Errorf("can't append block header %d: %v", num, err)
-	}
-	if err := op.Append(freezerBodiesTable, num, block.Body()); err != nil {
-		return fmt.Errorf("can't append block body %d: %v", num, err)
+// ReadAncientRange retrieves count consecutive blocks starting at start from
+// the ancient store, together with their raw receipts and total difficulties.
+// Each table is read in bulk instead of item by item, and every header is
+// checked against the canonical hash recorded in the freezer hash table.
+func ReadAncientRange(db ethdb.Reader, start, count uint64) ([]*types.Block, []types.Receipts, []*big.Int, error) {
+	if count == 0 {
+		return nil, nil, nil, nil
 	}
-	if err := op.Append(freezerReceiptTable, num, receipts); err != nil {
-		return fmt.Errorf("can't append block %d receipts: %v", num, err)
+	var tables = make(map[string][][]byte)
+	for _, kind := range []string{freezerHashTable, freezerHeaderTable, freezerBodiesTable, freezerReceiptTable, freezerDifficultyTable} {
+		items, err := readAncientItems(db, kind, start, count)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		tables[kind] = items
 	}
-	if err := op.Append(freezerDifficultyTable, num, td); err != nil {
+	var (
+		blocks   = make([]*types.Block, count)
+		receipts = make([]types.Receipts, count)
+		tds      = make([]*big.Int, count)
+	)
+	for i := uint64(0); i < count; i++ {
+		number := start + i
+
+		header := new(types.Header)
+		if err := rlp.DecodeBytes(tables[freezerHeaderTable][i], header); err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid ancient header #%d: %v", number, err)
+		}
+		if hash := common.BytesToHash(tables[freezerHashTable][i]); header.Hash() != hash {
+			return nil, nil, nil, fmt.Errorf("ancient header #%d hash mismatch: have %x, want %x", number, header.Hash(), hash)
+		}
+		body := new(types.Body)
+		if err := rlp.DecodeBytes(tables[freezerBodiesTable][i], body); err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid ancient body #%d: %v", number, err)
+		}
+		blocks[i] = types.NewBlockWithHeader(header).WithBody(body.Transactions, body.Randomness, body.EpochSnarkData)
+
+		var storageReceipts []*types.ReceiptForStorage
+		if err := rlp.DecodeBytes(tables[freezerReceiptTable][i], &storageReceipts); err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid ancient receipts #%d: %v", number, err)
+		}
+		receipts[i] = make(types.Receipts, len(storageReceipts))
+		for j, receipt := range storageReceipts {
+			receipts[i][j] = (*types.Receipt)(receipt)
+		}
+
+		tds[i] = new(big.Int)
+		if err := rlp.DecodeBytes(tables[freezerDifficultyTable][i], tds[i]); err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid ancient total difficulty #%d: %v", number, err)
+		}
+	}
+	return blocks, receipts, tds, nil
+}
+
+// ReadHeaderRange returns the RLP encoded canonical headers of up to count
+// consecutive blocks starting at from, in ascending order, along with the
+// number of headers found. The frozen part of the range is read from the
+// ancient store in bulk, only the remaining tail is looked up one by one.
+// The range ends early at the first missing header.
+func ReadHeaderRange(db ethdb.Reader, from, count uint64) ([]rlp.RawValue, uint64) {
+	return readCanonicalRange(db, freezerHeaderTable, from, count, func(number uint64) rlp.RawValue {
+		hash := ReadCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			return nil
+		}
+		return ReadHeaderRLP(db, hash, number)
+	})
+}
+
+// ReadCanonicalBodyRange is the block body equivalent of ReadHeaderRange.
+func ReadCanonicalBodyRange(db ethdb.Reader, from, count uint64) ([]rlp.RawValue, uint64) {
+	return readCanonicalRange(db, freezerBodiesTable, from, count, func(number uint64) rlp.RawValue {
+		return ReadCanonicalBodyRLP(db, number)
+	})
+}
+
+// readCanonicalRange reads the frozen items of the range from the given ancient
+// table and the rest with read, stopping at the first missing item.
+func readCanonicalRange(db ethdb.Reader, kind string, from, count uint64, read func(uint64) rlp.RawValue) ([]rlp.RawValue, uint64) {
+	if count == 0 {
+		return nil, 0
+	}
+	items := make([]rlp.RawValue, 0, count)
+	if frozen, err := db.Ancients(); err == nil && from < frozen {
+		want := frozen - from
+		if want > count {
+			want = count
+		}
+		for uint64(len(items)) < want {
+			batch, err := db.ReadAncients(kind, from+uint64(len(items)), want-uint64(len(items)), ancientRangeReadBytes)
+			if err != nil || len(batch) == 0 {
+				break // leave the rest to the per-item fallback
+			}
+			for _, item := range batch {
+				items = append(items, item)
+			}
+		}
+	}
+	for number := from + uint64(len(items)); uint64(len(items)) < count; number++ {
+		data := read(number)
+		if len(data) == 0 {
+			break
+		}
+		items = append(items, data)
+	}
+	return items, uint64(len(items))
+}
+
+// WriteAncientBlocks writes entire block data into ancient store and returns the total written size.
+func WriteAncientBlocks(db ethdb.AncientWriter, blocks []*types.Block, receipts []types.Receipts, td *big.Int) (int64, error) {
+	var (
+		tdSum      = new(big.Int).Set(td)
+		stReceipts []*types.ReceiptForStorage
+	)
+	return db.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i, block := range blocks {
+			// Convert receipts to storage format and sum up total difficulty.
+			stReceipts = stReceipts[:0]
+			for _, receipt := range receipts[i] {
+				stReceipts = append(stReceipts, (*types.ReceiptForStorage)(receipt))
+			}
+			header := block.Header()
+			if i > 0 {
+				tdSum.Add(tdSum, block.TotalDifficulty())
+			}
+			if err := writeAncientBlock(op, block, header, stReceipts, tdSum); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func writeAncientBlock(op ethdb.AncientWriteOp, block *types.Block, header *types.Header, receipts []*types.ReceiptForStorage, td *big.Int) error {
+	num := block.NumberU64()
+	if err := op.AppendRaw(freezerHashTable, num, block.Hash().Bytes()); err != nil {
+		return fmt.Errorf("can't add block %d hash: %v", num, err)
+	}
+	if err := op.Append(freezerHeaderTable, num, header); err != nil {
+		return fmt.Errorf("can't append block header %d: %v", num, err)
+	}
+	if err := op.Append(freezerBodiesTable, num, block.Body()); err != nil {
+		return fmt.Errorf("can't append block body %d: %v", num, err)
+	}
+	if err := op.Append(freezerReceiptTable, num, receipts); err != nil {
+		return fmt.Errorf("can't append block %d receipts: %v", num, err)
+	}
+	if err := op.Append(freezerDifficultyTable, num, td); err != nil {
 		return fmt.Errorf("can't append block %d total difficulty: %v", num, err)
 	}
+	if err := op.Append(freezerRandomnessTable, num, block.Randomness()); err != nil {
+		return fmt.Errorf("can't append block %d randomness: %v", num, err)
+	}
+	if err := op.Append(freezerEpochSnarkDataTable, num, block.EpochSnarkData()); err != nil {
+		return fmt.Errorf("can't append block %d epoch snark data: %v", num, err)
+	}
 	return nil
 }
 
@@ -845,11 +1507,168 @@ func DeleteBlockWithoutNumber(db ethdb.KeyValueWriter, hash common.Hash, number
 	DeleteTd(db, hash, number)
 }
 
-const badBlockToKeep = 10
+// PruneBlockRange removes the headers, bodies, receipts, total difficulties and
+// canonical hashes of every block numbered from to to (both inclusive), side
+// chains included, and then compacts the affected key space. Frozen blocks are
+// not touched. It returns the number of removed blocks.
+func PruneBlockRange(db ethdb.KeyValueStore, from, to uint64) (int, error) {
+	if from > to {
+		return 0, fmt.Errorf("invalid range: from %d > to %d", from, to)
+	}
+	batch := db.NewBatch()
+	blocks := ReadAllHashesInRange(db, from, to)
+	for _, block := range blocks {
+		DeleteBlock(batch, block.Hash, block.Number)
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return 0, err
+			}
+			batch.Reset()
+		}
+	}
+	for number := from; ; number++ {
+		DeleteCanonicalHash(batch, number)
+		if number == to {
+			break
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return 0, err
+	}
+	// The hash to number mappings are keyed by hash and thus scattered over
+	// the whole headerNumberPrefix space, which is not worth compacting here.
+	for _, prefix := range [][]byte{headerPrefix, blockBodyPrefix, blockReceiptsPrefix} {
+		start := append(append([]byte{}, prefix...), encodeBlockNumber(from)...)
+		limit := append(append([]byte{}, prefix...), encodeBlockNumber(to+1)...)
+		if to == math.MaxUint64 {
+			limit = []byte{prefix[0] + 1}
+		}
+		if err := CompactRange(db, start, limit); err != nil {
+			return len(blocks), err
+		}
+	}
+	return len(blocks), nil
+}
+
+// DeleteBodiesAndReceiptsRange removes the bodies and receipts of the blocks
+// numbered from to to (both inclusive) whose hash is given by hashFn, keeping
+// their headers, total difficulties and canonical hashes. Numbers for which
+// hashFn returns the zero hash are skipped. An inverted range deletes nothing.
+func DeleteBodiesAndReceiptsRange(db ethdb.KeyValueWriter, from, to uint64, hashFn func(uint64) common.Hash) {
+	if from > to {
+		return
+	}
+	for number := from; ; number++ {
+		if hash := hashFn(number); hash != (common.Hash{}) {
+			DeleteBody(db, hash, number)
+			DeleteReceipts(db, hash, number)
+		}
+		if number == to {
+			break
+		}
+	}
+}
+
+// FrozenBlocksError is returned by DeleteBlocksFrom if the blocks to delete
+// reach into the ancient store, which has to be truncated to From items first.
+type FrozenBlocksError struct {
+	From   uint64 // first block to delete
+	Frozen uint64 // number of frozen blocks
+}
+
+func (e *FrozenBlocksError) Error() string {
+	return fmt.Sprintf("blocks #%d-#%d are frozen, truncate the ancients to %d items first", e.From, e.Frozen-1, e.From)
+}
+
+// DeleteBlocksFrom removes the headers, bodies, receipts, total difficulties,
+// canonical hashes and hash to number mappings of every block numbered from
+// number on, side chains included. Rather than looking up every block, the
+// header, body and receipt key spaces are iterated from number on and deleted
+// in large batches, which makes it suitable for rewinding far back. Frozen
+// blocks can't be deleted, a *FrozenBlocksError is returned if number is below
+// the ancient limit. It returns the number of removed blocks.
+func DeleteBlocksFrom(db ethdb.Database, number uint64) (int, error) {
+	if frozen, err := db.Ancients(); err == nil && number < frozen {
+		return 0, &FrozenBlocksError{From: number, Frozen: frozen}
+	}
+	var (
+		batch   = db.NewBatch()
+		start   = encodeBlockNumber(number)
+		deleted int
+	)
+	for _, prefix := range [][]byte{headerPrefix, blockBodyPrefix, blockReceiptsPrefix} {
+		it := db.NewIterator(prefix, start)
+		for it.Next() {
+			key := it.Key()
+			switch {
+			case len(key) == len(prefix)+8+common.HashLength:
+				if bytes.Equal(prefix, headerPrefix) {
+					// A header, drop its hash to number mapping along with it
+					if err := batch.Delete(headerNumberKey(common.BytesToHash(key[len(key)-common.HashLength:]))); err != nil {
+						it.Release()
+						return deleted, err
+					}
+					deleted++
+				}
+			case bytes.Equal(prefix, headerPrefix) && bytes.HasSuffix(key, headerTDSuffix) && len(key) == len(headerPrefix)+8+common.HashLength+len(headerTDSuffix):
+			case bytes.Equal(prefix, headerPrefix) && bytes.HasSuffix(key, headerHashSuffix) && len(key) == len(headerPrefix)+8+len(headerHashSuffix):
+			default:
+				continue // Not a block key, leave it alone
+			}
+			if err := batch.Delete(key); err != nil {
+				it.Release()
+				return deleted, err
+			}
+			if batch.ValueSize() > ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					it.Release()
+					return deleted, err
+				}
+				batch.Reset()
+			}
+		}
+		it.Release()
+		if err := it.Error(); err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, batch.Write()
+}
+
+// badBlockToKeep is the number of bad blocks retained in the database.
+var badBlockToKeep = 10
+
+// SetBadBlockRetention sets the number of bad blocks retained in the database.
+// It is meant to be called once at startup, before any bad block is written.
+// Non-positive values are ignored.
+func SetBadBlockRetention(n int) {
+	if n > 0 {
+		badBlockToKeep = n
+	}
+}
+
+// BadBlockMeta records when and why a bad block was rejected.
+type BadBlockMeta struct {
+	Received uint64 // Unix time the block was rejected at
+	Peer     string // Peer the block was received from, empty if unknown
+	Error    string // Validation error the block was rejected with
+}
+
+// BadBlock is a rejected block along with its rejection metadata. Meta is nil
+// for entries written before the metadata was recorded.
+type BadBlock struct {
+	Block *types.Block
+	Meta  *BadBlockMeta
+}
 
 type badBlock struct {
 	Header *types.Header
 	Body   *types.Body
+	Meta   *BadBlockMeta `rlp:"optional"`
+}
+
+func (b *badBlock) block() *types.Block {
+	return types.NewBlockWithHeader(b.Header).WithBody(b.Body.Transactions, b.Body.Randomness, b.Body.EpochSnarkData)
 }
 
 // badBlockList implements the sort interface to allow sorting a list of
@@ -862,8 +1681,9 @@ func (s badBlockList) Less(i, j int) bool {
 }
 func (s badBlockList) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
 
-// ReadBadBlock retrieves the bad block with the corresponding block hash.
-func ReadBadBlock(db ethdb.Reader, hash common.Hash) *types.Block {
+// readBadBlocks retrieves the stored bad block list, sorted in reverse order
+// by number.
+func readBadBlocks(db ethdb.KeyValueReader) badBlockList {
 	blob, err := db.Get(badBlockKey)
 	if err != nil {
 		return nil
@@ -872,35 +1692,46 @@ func ReadBadBlock(db ethdb.Reader, hash common.Hash) *types.Block {
 	if err := rlp.DecodeBytes(blob, &badBlocks); err != nil {
 		return nil
 	}
-	for _, bad := range badBlocks {
+	return badBlocks
+}
+
+// ReadBadBlock retrieves the bad block with the corresponding block hash.
+func ReadBadBlock(db ethdb.Reader, hash common.Hash) *types.Block {
+	for _, bad := range readBadBlocks(db) {
 		if bad.Header.Hash() == hash {
-			return types.NewBlockWithHeader(bad.Header).WithBody(bad.Body.Transactions, bad.Body.Randomness, bad.Body.EpochSnarkData)
+			return bad.block()
 		}
 	}
 	return nil
 }
 
-// ReadAllBadBlocks retrieves all the bad blocks in the database.
-// All returned blocks are sorted in reverse order by number.
-func ReadAllBadBlocks(db ethdb.Reader) []*types.Block {
-	blob, err := db.Get(badBlockKey)
-	if err != nil {
-		return nil
-	}
-	var badBlocks badBlockList
-	if err := rlp.DecodeBytes(blob, &badBlocks); err != nil {
-		return nil
+// ReadAllBadBlocks retrieves all the bad blocks in the database along with the
+// reason they were rejected for. All returned blocks are sorted in reverse
+// order by number.
+func ReadAllBadBlocks(db ethdb.Reader) []*BadBlock {
+	var blocks []*BadBlock
+	for _, bad := range readBadBlocks(db) {
+		blocks = append(blocks, &BadBlock{Block: bad.block(), Meta: bad.Meta})
 	}
-	var blocks []*types.Block
-	for _, bad := range badBlocks {
-		blocks = append(blocks, types.NewBlockWithHeader(bad.Header).WithBody(bad.Body.Transactions, bad.Body.Randomness, bad.Body.EpochSnarkData))
+	return blocks
+}
+
+// ReadBadBlocksInRange retrieves the bad blocks numbered from from to to (both
+// inclusive). The returned blocks are sorted in reverse order by number.
+func ReadBadBlocksInRange(db ethdb.Reader, from, to uint64) []*BadBlock {
+	var blocks []*BadBlock
+	for _, bad := range readBadBlocks(db) {
+		if number := bad.Header.Number.Uint64(); number >= from && number <= to {
+			blocks = append(blocks, &BadBlock{Block: bad.block(), Meta: bad.Meta})
+		}
 	}
 	return blocks
 }
 
-// WriteBadBlock serializes the bad block into the database. If the cumulated
-// bad blocks exceeds the limitation, the oldest will be dropped.
-func WriteBadBlock(db ethdb.KeyValueStore, block *types.Block) {
+// WriteBadBlock serializes the bad block and its rejection metadata, which may
+// be nil, into the database. If the cumulated bad blocks exceeds the limitation,
+// the oldest will be dropped.
+func WriteBadBlock(db ethdb.KeyValueStore, block *types.Block, meta *BadBlockMeta) {
 	blob, err := db.Get(badBlockKey)
 	if err != nil {
 		log.Warn("Failed to load old bad blocks", "error", err)
@@ -920,6 +1751,7 @@ func WriteBadBlock(db ethdb.KeyValueStore, block *types.Block) {
 	badBlocks = append(badBlocks, &badBlock{
 		Header: block.Header(),
 		Body:   block.Body(),
+		Meta:   meta,
 	})
 	sort.Sort(sort.Reverse(badBlocks))
 	if len(badBlocks) > badBlockToKeep {
@@ -994,356 +1826,167 @@ func ReadHeadBlock(db ethdb.Reader) *types.Block {
 	return ReadBlock(db, headBlockHash, *headBlockNumber)
 }
 
-//---------------------------chains----------------------------------
-// ReadCanonicalHashChains retrieves the hash assigned to a canonical block number.
-//func ReadCanonicalHashChains(db DatabaseReader, number uint64, m ChainType) common.Hash {
-//	data, _ := db.Get(headerHashKeyChains(m, number))
-//	if len(data) == 0 {
-//		return common.Hash{}
-//	}
-//	return common.BytesToHash(data)
-//}
-//
-//// ReadFirstCanonicalHashChains
-//func ReadFirstCanonicalHashChains(db DatabaseReader, m ChainType) common.Hash {
-//	data, _ := db.Get(headerHashKeyChains(m, uint64(0)))
-//	if len(data) == 0 {
-//		return common.Hash{}
-//	}
-//	return common.BytesToHash(data)
-//}
-//
-//// WriteCanonicalHashChains stores the hash assigned to a canonical block number.
-//func WriteCanonicalHashChains(db DatabaseWriter, hash common.Hash, number uint64, m ChainType) {
-//	if err := db.Put(headerHashKeyChains(m, number), hash.Bytes()); err != nil {
-//		log.Crit("Failed to store number to hash mapping", "err", err)
-//	}
-//}
-//
-//// DeleteCanonicalHashChains removes the number to hash canonical mapping.
-//func DeleteCanonicalHashChains(db DatabaseDeleter, number uint64, m ChainType) {
-//	if err := db.Delete(headerHashKeyChains(m, number)); err != nil {
-//		log.Crit("Failed to delete number to hash mapping", "err", err)
-//	}
-//}
-//
-//// ReadHeaderNumberChains returns the header number assigned to a hash.
-//func ReadHeaderNumberChains(db DatabaseReader, hash common.Hash, m ChainType) *uint64 {
-//	data, _ := db.Get(headerNumberKeyChains(m, hash))
-//	if len(data) != 8 {
-//		return nil
-//	}
-//	number := binary.BigEndian.Uint64(data)
-//	return &number
-//}
-//
-//// ReadHeadHeaderHashChains retrieves the hash of the current canonical head header.
-//func ReadHeadHeaderHashChains(db DatabaseReader, m ChainType) common.Hash {
-//	data, _ := db.Get(m.setTypeKey(headHeaderKey))
-//	if len(data) == 0 {
-//		return common.Hash{}
-//	}
-//	return common.BytesToHash(data)
-//}
-//
-//// WriteHeadHeaderHashChains stores the hash of the current canonical head header.
-//func WriteHeadHeaderHashChains(db DatabaseWriter, hash common.Hash, m ChainType) {
-//	if err := db.Put(m.setTypeKey(headHeaderKey), hash.Bytes()); err != nil {
-//		log.Crit("Failed to store last header's hash", "err", err)
-//	}
-//}
-//
-//// ReadHeadBlockHashChains retrieves the hash of the current canonical head block.
-//func ReadHeadBlockHashChains(db DatabaseReader, m ChainType) common.Hash {
-//	data, _ := db.Get(m.setTypeKey(headBlockKey))
-//	if len(data) == 0 {
-//		return common.Hash{}
-//	}
-//	return common.BytesToHash(data)
-//}
-//
-//// WriteHeadBlockHashChains stores the head block's hash.
-//func WriteHeadBlockHashChains(db DatabaseWriter, hash common.Hash, m ChainType) {
-//	if err := db.Put(m.setTypeKey(headBlockKey), hash.Bytes()); err != nil {
-//		log.Crit("Failed to store last block's hash", "err", err)
-//	}
-//}
-//
-//// ReadLastBlockHashChains retrieves the hash of the current canonical head block.
-//func ReadLastBlockHashChains(db DatabaseReader, m ChainType) common.Hash {
-//	data, _ := db.Get(m.setTypeKey(lastBlockKey))
-//	if len(data) == 0 {
-//		return common.Hash{}
-//	}
-//	return common.BytesToHash(data)
-//}
-//
-//// WriteLastBlockHashChains stores the head block's hash.
-//func WriteLastBlockHashChains(db DatabaseWriter, hash common.Hash, m ChainType) {
-//	if err := db.Put(m.setTypeKey(lastBlockKey), hash.Bytes()); err != nil {
-//		log.Crit("Failed to store last block's hash", "err", err)
-//	}
-//}
-//
-//// ReadHeadFastBlockHashChains retrieves the hash of the current fast-sync head block.
-//func ReadHeadFastBlockHashChains(db DatabaseReader, m ChainType) common.Hash {
-//	data, _ := db.Get(m.setTypeKey(headFastBlockKey))
-//	if len(data) == 0 {
-//		return common.Hash{}
-//	}
-//	return common.BytesToHash(data)
-//}
-//
-//// WriteHeadFastBlockHashChains stores the hash of the current fast-sync head block.
-//func WriteHeadFastBlockHashChains(db DatabaseWriter, hash common.Hash, m ChainType) {
-//	if err := db.Put(m.setTypeKey(headFastBlockKey), hash.Bytes()); err != nil {
-//		log.Crit("Failed to store last fast block's hash", "err", err)
-//	}
-//}
-//
-//// ReadFastTrieProgressChains retrieves the number of tries nodes fast synced to allow
-//// reporting correct numbers across restarts.
-//func ReadFastTrieProgressChains(db DatabaseReader, m ChainType) uint64 {
-//	data, _ := db.Get(m.setTypeKey(fastTrieProgressKey))
-//	if len(data) == 0 {
-//		return 0
-//	}
-//	return new(big.Int).SetBytes(data).Uint64()
-//}
-//
-//// WriteFastTrieProgressChains stores the fast sync trie process counter to support
-//// retrieving it across restarts.
-//func WriteFastTrieProgressChains(db DatabaseWriter, count uint64, m ChainType) {
-//	if err := db.Put(m.setTypeKey(fastTrieProgressKey), new(big.Int).SetUint64(count).Bytes()); err != nil {
-//		log.Crit("Failed to store fast sync trie progress", "err", err)
-//	}
-//}
-//
-//// ReadHeaderRLPChains retrieves a block header in its raw RLP database encoding.
-//func ReadHeaderRLPChains(db DatabaseReader, hash common.Hash, number uint64, m ChainType) rlp.RawValue {
-//	data, err := db.Get(headerKeyChains(m, number, hash))
-//	if err != nil {
-//		return rlp.RawValue{}
-//	}
-//	return data
-//}
-//
-//// HasHeaderChains verifies the existence of a block header corresponding to the hash.
-//func HasHeaderChains(db DatabaseReader, hash common.Hash, number uint64, m ChainType) bool {
-//	if has, err := db.Has(headerKeyChains(m, number, hash)); !has || err != nil {
-//		return false
-//	}
-//	return true
-//}
-//
-//// ReadHeaderChains retrieves the block header corresponding to the hash.
-//func ReadHeaderChains(db DatabaseReader, hash common.Hash, number uint64, m ChainType) *ethereum.Header {
-//	data := ReadHeaderRLPChains(db, hash, number, m)
-//	if len(data) == 0 {
-//		return nil
-//	}
-//	header := new(ethereum.Header)
-//	if err := rlp.Decode(bytes.NewReader(data), header); err != nil {
-//		log.Error("Invalid block header RLP", "hash", hash, "err", err)
-//		return nil
-//	}
-//	return header
-//}
-//
-//// WriteHeaderChains stores a block header into the database and also stores the hash-
-//// to-number mapping.
-//func WriteHeaderChains(db DatabaseWriter, header *ethereum.Header, m ChainType) {
-//	// Write the hash -> number mapping
-//	var (
-//		hash    = header.Hash()
-//		number  = header.Number.Uint64()
-//		encoded = encodeBlockNumber(number)
-//	)
-//	key := headerNumberKeyChains(m, hash)
-//	if err := db.Put(key, encoded); err != nil {
-//		log.Crit("Failed to store hash to number mapping", "err", err)
-//	}
-//	// Write the encoded header
-//	data, err := rlp.EncodeToBytes(header)
-//	//log.Info("=========   size of fast hash",len(data),"number of fastblock",number)
-//	if err != nil {
-//		log.Crit("Failed to RLP encode header", "err", err)
-//	}
-//	key = headerKeyChains(m, number, hash)
-//	if err := db.Put(key, data); err != nil {
-//		log.Crit("Failed to store header", "err", err)
-//	}
-//}
-//
-//// DeleteHeaderChains removes all block header data associated with a hash.
-//func DeleteHeaderChains(db DatabaseDeleter, hash common.Hash, number uint64, m ChainType) {
-//	if err := db.Delete(headerKeyChains(m, number, hash)); err != nil {
-//		log.Crit("Failed to delete header", "err", err)
-//	}
-//	if err := db.Delete(headerNumberKeyChains(m, hash)); err != nil {
-//		log.Crit("Failed to delete hash to number mapping", "err", err)
-//	}
-//}
-//
-//// ReadBodyRLPChains retrieves the block body (transactions and uncles) in RLP encoding.
-//func ReadBodyRLPChains(db DatabaseReader, hash common.Hash, number uint64, m ChainType) rlp.RawValue {
-//	data, _ := db.Get(blockBodyKeyChains(m, number, hash))
-//	return data
-//}
-//
-//// WriteBodyRLPChains stores an RLP encoded block body into the database.
-//func WriteBodyRLPChains(db DatabaseWriter, hash common.Hash, number uint64, rlp rlp.RawValue, m ChainType) {
-//	if err := db.Put(blockBodyKeyChains(m, number, hash), rlp); err != nil {
-//		log.Crit("Failed to store block body", "err", err)
-//	}
-//}
-//
-//// HasBodyChains verifies the existence of a block body corresponding to the hash.
-//func HasBodyChains(db DatabaseReader, hash common.Hash, number uint64, m ChainType) bool {
-//	if has, err := db.Has(blockBodyKeyChains(m, number, hash)); !has || err != nil {
-//		return false
-//	}
-//	return true
-//}
-//
-//// ReadBodyChains retrieves the block body corresponding to the hash.
-//func ReadBodyChains(db DatabaseReader, hash common.Hash, number uint64, m ChainType) *types.Body {
-//	data := ReadBodyRLPChains(db, hash, number, m)
-//	if len(data) == 0 {
-//		return nil
-//	}
-//	body := new(types.Body)
-//	if err := rlp.Decode(bytes.NewReader(data), body); err != nil {
-//		log.Error("Invalid block body RLP", "hash", hash, "err", err)
-//		return nil
-//	}
-//	return body
-//}
-//
-//// WriteBodyChains storea a block body into the database.
-//func WriteBodyChains(db DatabaseWriter, hash common.Hash, number uint64, body *types.Body, m ChainType) {
-//	data, err := rlp.EncodeToBytes(body)
-//	if err != nil {
-//		log.Crit("Failed to RLP encode body", "err", err)
-//	}
-//	WriteBodyRLPChains(db, hash, number, data, m)
-//}
-//
-//// DeleteBodyChains removes all block body data associated with a hash.
-//func DeleteBodyChains(db DatabaseDeleter, hash common.Hash, number uint64, m ChainType) {
-//	if err := db.Delete(blockBodyKeyChains(m, number, hash)); err != nil {
-//		log.Crit("Failed to delete block body", "err", err)
-//	}
-//}
-//
-//// ReadTdChains retrieves a block's total difficulty corresponding to the hash.
-//func ReadTdChains(db DatabaseReader, hash common.Hash, number uint64, m ChainType) *big.Int {
-//	data, _ := db.Get(headerTDKeyChains(m, number, hash))
-//	if len(data) == 0 {
-//		return nil
-//	}
-//	td := new(big.Int)
-//	if err := rlp.Decode(bytes.NewReader(data), td); err != nil {
-//		log.Error("Invalid block total difficulty RLP", "hash", hash, "err", err)
-//		return nil
-//	}
-//	return td
-//}
-//
-//// WriteTdChains stores the total difficulty of a block into the database.
-//func WriteTdChains(db DatabaseWriter, hash common.Hash, number uint64, td *big.Int, m ChainType) {
-//	data, err := rlp.EncodeToBytes(td)
-//	if err != nil {
-//		log.Crit("Failed to RLP encode block total difficulty", "err", err)
-//	}
-//	if err := db.Put(headerTDKeyChains(m, number, hash), data); err != nil {
-//		log.Crit("Failed to store block total difficulty", "err", err)
-//	}
-//}
-//
-//// HasReceiptsChains verifies the existence of all the transaction receipts belonging
-//// to a block.
-//func HasReceiptsChains(db DatabaseReader, hash common.Hash, number uint64, m ChainType) bool {
-//	if has, err := db.Has(blockReceiptsKeyChains(m, number, hash)); !has || err != nil {
-//		return false
-//	}
-//	return true
-//}
-//
-//// ReadReceiptsChains retrieves all the transaction receipts belonging to a block.
-//func ReadReceiptsChains(db DatabaseReader, hash common.Hash, number uint64, m ChainType) types.Receipts {
-//	// Retrieve the flattened receipt slice
-//	data, _ := db.Get(blockReceiptsKeyChains(m, number, hash))
-//	if len(data) == 0 {
-//		return nil
-//	}
-//	// Convert the revceipts from their storage form to their internal representation
-//	storageReceipts := []*types.ReceiptForStorage{}
-//	if err := rlp.DecodeBytes(data, &storageReceipts); err != nil {
-//		log.Error("Invalid receipt array RLP", "hash", hash, "err", err)
-//		return nil
-//	}
-//	receipts := make(types.Receipts, len(storageReceipts))
-//	logIndex := uint(0)
-//	for i, receipt := range storageReceipts {
-//		// Assemble deriving fields for log.
-//		for _, log := range receipt.Logs {
-//			log.TxHash = receipt.TxHash
-//			log.BlockHash = hash
-//			log.BlockNumber = number
-//			log.TxIndex = uint(i)
-//			log.Index = logIndex
-//			logIndex += 1
-//		}
-//		receipts[i] = (*types.Receipt)(receipt)
-//		receipts[i].BlockHash = hash
-//		receipts[i].BlockNumber = big.NewInt(0).SetUint64(number)
-//		receipts[i].TransactionIndex = uint(i)
-//	}
-//	return receipts
-//}
-//
-//// WriteReceiptsChains stores all the transaction receipts belonging to a block.
-//func WriteReceiptsChains(db DatabaseWriter, hash common.Hash, number uint64, receipts types.Receipts, m ChainType) {
-//	// Convert the receipts into their storage form and serialize them
-//	storageReceipts := make([]*types.ReceiptForStorage, len(receipts))
-//	for i, receipt := range receipts {
-//		storageReceipts[i] = (*types.ReceiptForStorage)(receipt)
-//	}
-//	bytes, err := rlp.EncodeToBytes(storageReceipts)
-//	if err != nil {
-//		log.Crit("Failed to encode block receipts", "err", err)
-//	}
-//	if err := db.Put(blockReceiptsKeyChains(m, number, hash), bytes); err != nil {
-//		log.Crit("Failed to store block receipts", "err", err)
-//	}
-//}
-//
-//// DeleteReceiptsChains removes all receipt data associated with a block hash.
-//func DeleteReceiptsChains(db DatabaseDeleter, hash common.Hash, number uint64, m ChainType) {
-//	if err := db.Delete(blockReceiptsKeyChains(m, number, hash)); err != nil {
-//		log.Crit("Failed to delete block receipts", "err", err)
-//	}
-//}
-//
-//// WriteChainConfigChains writes the chain config settings to the database.
-//func WriteChainConfigChains(db DatabaseWriter, hash common.Hash, cfg *params.ChainConfig, m ChainType) {
-//	if cfg == nil {
-//		return
-//	}
-//	data, err := json.Marshal(cfg)
-//	if err != nil {
-//		log.Crit("Failed to JSON encode chain config", "err", err)
-//	}
-//	if err := db.Put(configKeyChains(hash, m), data); err != nil {
-//		log.Crit("Failed to store chain config", "err", err)
-//	}
-//}
-//
-//// configKey = configPrefix + hash
-//func configKeyChains(hash common.Hash, m ChainType) []byte {
-//	return append(m.setTypeKey(configPrefix), hash.Bytes()...)
-//}
+// ReadCanonicalHashChains retrieves the hash assigned to a canonical block number
+// of the given foreign chain.
+func ReadCanonicalHashChains(db ethdb.KeyValueReader, number uint64, m chains.ChainType) common.Hash {
+	data, _ := db.Get(headerHashKeyChains(m, number))
+	if len(data) == 0 {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// WriteCanonicalHashChains stores the hash assigned to a canonical block number
+// of the given foreign chain.
+func WriteCanonicalHashChains(db ethdb.KeyValueWriter, hash common.Hash, number uint64, m chains.ChainType) {
+	if err := db.Put(headerHashKeyChains(m, number), hash.Bytes()); err != nil {
+		log.Crit("Failed to store number to hash mapping", "chain", m, "err", err)
+	}
+}
+
+// DeleteCanonicalHashChains removes the number to hash canonical mapping of the
+// given foreign chain.
+func DeleteCanonicalHashChains(db ethdb.KeyValueWriter, number uint64, m chains.ChainType) {
+	if err := db.Delete(headerHashKeyChains(m, number)); err != nil {
+		log.Crit("Failed to delete number to hash mapping", "chain", m, "err", err)
+	}
+}
+
+// ReadHeaderNumberChains returns the header number assigned to a hash of the
+// given foreign chain.
+func ReadHeaderNumberChains(db ethdb.KeyValueReader, hash common.Hash, m chains.ChainType) *uint64 {
+	data, _ := db.Get(headerNumberKeyChains(m, hash))
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// readHashChains retrieves a hash marker of the given foreign chain.
+func readHashChains(db ethdb.KeyValueReader, key []byte, m chains.ChainType) common.Hash {
+	data, _ := db.Get(chainsKey(m, key))
+	if len(data) == 0 {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// writeHashChains stores a hash marker of the given foreign chain.
+func writeHashChains(db ethdb.KeyValueWriter, key []byte, hash common.Hash, m chains.ChainType) {
+	if err := db.Put(chainsKey(m, key), hash.Bytes()); err != nil {
+		log.Crit("Failed to store hash marker", "chain", m, "key", string(key), "err", err)
+	}
+}
+
+// ReadHeadHeaderHashChains retrieves the hash of the current canonical head header
+// of the given foreign chain.
+func ReadHeadHeaderHashChains(db ethdb.KeyValueReader, m chains.ChainType) common.Hash {
+	return readHashChains(db, headHeaderKey, m)
+}
+
+// WriteHeadHeaderHashChains stores the hash of the current canonical head header
+// of the given foreign chain.
+func WriteHeadHeaderHashChains(db ethdb.KeyValueWriter, hash common.Hash, m chains.ChainType) {
+	writeHashChains(db, headHeaderKey, hash, m)
+}
+
+// ReadHeadBlockHashChains retrieves the hash of the current canonical head block
+// of the given foreign chain.
+func ReadHeadBlockHashChains(db ethdb.KeyValueReader, m chains.ChainType) common.Hash {
+	return readHashChains(db, headBlockKey, m)
+}
+
+// WriteHeadBlockHashChains stores the head block's hash of the given foreign chain.
+func WriteHeadBlockHashChains(db ethdb.KeyValueWriter, hash common.Hash, m chains.ChainType) {
+	writeHashChains(db, headBlockKey, hash, m)
+}
+
+// ReadLastBlockHashChains retrieves the hash of the last processed block of the
+// given foreign chain.
+func ReadLastBlockHashChains(db ethdb.KeyValueReader, m chains.ChainType) common.Hash {
+	return readHashChains(db, lastBlockKey, m)
+}
+
+// WriteLastBlockHashChains stores the last processed block's hash of the given
+// foreign chain.
+func WriteLastBlockHashChains(db ethdb.KeyValueWriter, hash common.Hash, m chains.ChainType) {
+	writeHashChains(db, lastBlockKey, hash, m)
+}
+
+// ReadHeaderRLPChains retrieves a block header of the given foreign chain in its
+// raw RLP database encoding.
+func ReadHeaderRLPChains(db ethdb.KeyValueReader, hash common.Hash, number uint64, m chains.ChainType) rlp.RawValue {
+	data, _ := db.Get(headerKeyChains(m, number, hash))
+	return data
+}
+
+// HasHeaderChains verifies the existence of a block header of the given foreign
+// chain corresponding to the hash.
+func HasHeaderChains(db ethdb.KeyValueReader, hash common.Hash, number uint64, m chains.ChainType) bool {
+	if has, err := db.Has(headerKeyChains(m, number, hash)); !has || err != nil {
+		return false
+	}
+	return true
+}
+
+// WriteHeaderRLPChains stores an RLP encoded block header of the given foreign
+// chain and also stores the hash-to-number mapping. The hash is passed in since
+// the hashing rules differ between chains.
+func WriteHeaderRLPChains(db ethdb.KeyValueWriter, hash common.Hash, number uint64, header rlp.RawValue, m chains.ChainType) {
+	if err := db.Put(headerNumberKeyChains(m, hash), encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store hash to number mapping", "chain", m, "err", err)
+	}
+	if err := db.Put(headerKeyChains(m, number, hash), header); err != nil {
+		log.Crit("Failed to store header", "chain", m, "err", err)
+	}
+}
+
+// DeleteHeaderChains removes all block header data of the given foreign chain
+// associated with a hash.
+func DeleteHeaderChains(db ethdb.KeyValueWriter, hash common.Hash, number uint64, m chains.ChainType) {
+	if err := db.Delete(headerKeyChains(m, number, hash)); err != nil {
+		log.Crit("Failed to delete header", "chain", m, "err", err)
+	}
+	if err := db.Delete(headerNumberKeyChains(m, hash)); err != nil {
+		log.Crit("Failed to delete hash to number mapping", "chain", m, "err", err)
+	}
+}
+
+// ReadTdChains retrieves a block's total difficulty of the given foreign chain
+// corresponding to the hash.
+func ReadTdChains(db ethdb.KeyValueReader, hash common.Hash, number uint64, m chains.ChainType) *big.Int {
+	data, _ := db.Get(headerTDKeyChains(m, number, hash))
+	if len(data) == 0 {
+		return nil
+	}
+	td := new(big.Int)
+	if err := rlp.Decode(bytes.NewReader(data), td); err != nil {
+		log.Error("Invalid block total difficulty RLP", "chain", m, "hash", hash, "err", err)
+		return nil
+	}
+	return td
+}
+
+// WriteTdChains stores the total difficulty of a block of the given foreign chain
+// into the database.
+func WriteTdChains(db ethdb.KeyValueWriter, hash common.Hash, number uint64, td *big.Int, m chains.ChainType) {
+	data, err := rlp.EncodeToBytes(td)
+	if err != nil {
+		log.Crit("Failed to RLP encode block total difficulty", "chain", m, "err", err)
+	}
+	if err := db.Put(headerTDKeyChains(m, number, hash), data); err != nil {
+		log.Crit("Failed to store block total difficulty", "chain", m, "err", err)
+	}
+}
+
+// DeleteTdChains removes the total difficulty of a block of the given foreign
+// chain from the database.
+func DeleteTdChains(db ethdb.KeyValueWriter, hash common.Hash, number uint64, m chains.ChainType) {
+	if err := db.Delete(headerTDKeyChains(m, number, hash)); err != nil {
+		log.Crit("Failed to delete block total difficulty", "chain", m, "err", err)
+	}
+}
 
 // WriteRandomCommitmentCache will write a random beacon commitment's associated block parent hash
 // (which is used to calculate the commitmented random number).
@@ -1364,6 +2007,61 @@ func ReadRandomCommitmentCache(db ethdb.Reader, commitment common.Hash) common.H
 	return common.BytesToHash(parentHash)
 }
 
+// DeleteRandomCommitmentCache removes the cached parent hash of a random beacon commitment.
+func DeleteRandomCommitmentCache(db ethdb.KeyValueWriter, commitment common.Hash) {
+	if err := db.Delete(istanbul.RandomnessCommitmentDBLocation(commitment)); err != nil {
+		log.Crit("Failed to delete randomness commitment cache entry", "err", err)
+	}
+}
+
+// PruneRandomCommitments removes the randomness commitment cache entries whose
+// parent block is more than keepLastN blocks behind the head header, and the
+// entries whose parent header is not known at all. Entries on side chains are
+// aged by their own block number, so a recently reorged commitment survives
+// until it falls behind the horizon. The entry of the keep commitment, the last
+// one of the local validator, is never removed, however old, as its next
+// proposal reveals the committed randomness. It returns the number of removed
+// entries.
+func PruneRandomCommitments(db ethdb.KeyValueStore, keepLastN uint64, keep common.Hash) int {
+	head := ReadHeaderNumber(db, ReadHeadHeaderHash(db))
+	if head == nil || *head <= keepLastN {
+		return 0
+	}
+	horizon := *head - keepLastN
+
+	it := db.NewIterator(istanbul.RandomnessCommitmentDBPrefix, nil)
+	defer it.Release()
+
+	batch := db.NewBatch()
+	pruned := 0
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(istanbul.RandomnessCommitmentDBPrefix)+common.HashLength {
+			continue
+		}
+		commitment := common.BytesToHash(key[len(istanbul.RandomnessCommitmentDBPrefix):])
+		if (keep != common.Hash{}) && commitment == keep {
+			continue
+		}
+		if number := ReadHeaderNumber(db, common.BytesToHash(it.Value())); number != nil && *number >= horizon {
+			continue
+		}
+		DeleteRandomCommitmentCache(batch, commitment)
+		pruned++
+
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to prune randomness commitments", "err", err)
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to prune randomness commitments", "err", err)
+	}
+	return pruned
+}
+
 // ReadAccumulatedEpochUptime retrieves the so-far accumulated uptime array for the validators of the specified epoch
 func ReadAccumulatedEpochUptime(db ethdb.Reader, epoch uint64) *uptime.Uptime {
 	data, _ := db.Get(uptimeKey(epoch))
@@ -1379,7 +2077,8 @@ func ReadAccumulatedEpochUptime(db ethdb.Reader, epoch uint64) *uptime.Uptime {
 	return uptime
 }
 
-// WriteAccumulatedEpochUptime updates the accumulated uptime array for the validators of the specified epoch
+// WriteAccumulatedEpochUptime updates the accumulated uptime array for the validators of the specified epoch,
+// along with the number of the last block it accounts for.
 func WriteAccumulatedEpochUptime(db ethdb.KeyValueWriter, epoch uint64, uptime *uptime.Uptime) {
 	data, err := rlp.EncodeToBytes(uptime)
 	if err != nil {
@@ -1388,10 +2087,288 @@ func WriteAccumulatedEpochUptime(db ethdb.KeyValueWriter, epoch uint64, uptime *
 	if err := db.Put(uptimeKey(epoch), data); err != nil {
 		log.Crit("Failed to store updated uptime", "err", err)
 	}
+	if err := db.Put(epochUptimeHeadKey(epoch), encodeBlockNumber(uptime.LatestBlock)); err != nil {
+		log.Crit("Failed to store uptime head", "err", err)
+	}
+}
+
+// ReadEpochUptimeHead retrieves the number of the last block accounted for in
+// the accumulated uptime of the specified epoch, without decoding the uptime.
+// Comparing it to the chain head tells whether the uptime is up to date. It is
+// 0 if the epoch has no accumulated uptime.
+func ReadEpochUptimeHead(db ethdb.Reader, epoch uint64) uint64 {
+	data, _ := db.Get(epochUptimeHeadKey(epoch))
+	if len(data) == 8 {
+		return binary.BigEndian.Uint64(data)
+	}
+	// Uptime stored before the head was tracked separately
+	if uptime := ReadAccumulatedEpochUptime(db, epoch); uptime != nil {
+		return uptime.LatestBlock
+	}
+	return 0
+}
+
+// DeleteAccumulatedEpochUptime removes the accumulated uptime of the specified epoch.
+func DeleteAccumulatedEpochUptime(db ethdb.KeyValueWriter, epoch uint64) {
+	if err := db.Delete(uptimeKey(epoch)); err != nil {
+		log.Crit("Failed to delete uptime", "err", err)
+	}
+	if err := db.Delete(epochUptimeHeadKey(epoch)); err != nil {
+		log.Crit("Failed to delete uptime head", "err", err)
+	}
+}
+
+// ReadAllUptimeEpochs retrieves the epochs with an accumulated uptime entry, in
+// ascending order.
+func ReadAllUptimeEpochs(db ethdb.Iteratee) []uint64 {
+	it := db.NewIterator(uptimePrefix, nil)
+	defer it.Release()
+
+	var epochs []uint64
+	for it.Next() {
+		if key := it.Key(); len(key) == len(uptimePrefix)+8 {
+			epochs = append(epochs, binary.BigEndian.Uint64(key[len(uptimePrefix):]))
+		}
+	}
+	return epochs
+}
+
+// ReadLatestEpochUptime retrieves the highest epoch with an accumulated uptime
+// entry along with its uptime, or (0, nil) if there is none.
+func ReadLatestEpochUptime(db ethdb.Iteratee) (uint64, *uptime.Uptime) {
+	it := db.NewIterator(uptimePrefix, nil)
+	defer it.Release()
+
+	var (
+		epoch uint64
+		data  []byte
+	)
+	for it.Next() {
+		if key := it.Key(); len(key) == len(uptimePrefix)+8 {
+			epoch = binary.BigEndian.Uint64(key[len(uptimePrefix):])
+			data = common.CopyBytes(it.Value())
+		}
+	}
+	if data == nil {
+		return 0, nil
+	}
+	uptime := new(uptime.Uptime)
+	if err := rlp.Decode(bytes.NewReader(data), uptime); err != nil {
+		log.Error("Invalid uptime RLP", "epoch", epoch, "err", err)
+		return 0, nil
+	}
+	return epoch, uptime
+}
+
+// PruneUptimeBefore removes the accumulated uptime of all epochs before the
+// given one and returns the number of removed entries.
+func PruneUptimeBefore(db ethdb.KeyValueStore, epoch uint64) int {
+	batch := db.NewBatch()
+	pruned := 0
+	for _, e := range ReadAllUptimeEpochs(db) {
+		if e >= epoch {
+			break
+		}
+		DeleteAccumulatedEpochUptime(batch, e)
+		pruned++
+
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to prune uptime", "err", err)
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to prune uptime", "err", err)
+	}
+	return pruned
 }
 
 // uptimeKey = uptimePrefix + epoch number
 func uptimeKey(epoch uint64) []byte {
 	// abuse encodeBlockNumber for epochs
-	return append([]byte("uptime"), encodeBlockNumber(epoch)...)
+	return append(append([]byte{}, uptimePrefix...), encodeBlockNumber(epoch)...)
+}
+
+// epochUptimeHeadKey = epochUptimeHeadPrefix + epoch number
+func epochUptimeHeadKey(epoch uint64) []byte {
+	return append(append([]byte{}, epochUptimeHeadPrefix...), encodeBlockNumber(epoch)...)
+}
+
+// EpochBoundary is the last block of an epoch.
+type EpochBoundary struct {
+	Hash   common.Hash
+	Number uint64
+}
+
+// ReadEpochBoundary retrieves the last block of the specified epoch.
+func ReadEpochBoundary(db ethdb.KeyValueReader, epoch uint64) *EpochBoundary {
+	data, _ := db.Get(epochBoundaryKey(epoch))
+	if len(data) == 0 {
+		return nil
+	}
+	boundary := new(EpochBoundary)
+	if err := rlp.DecodeBytes(data, boundary); err != nil {
+		log.Error("Invalid epoch boundary RLP", "epoch", epoch, "err", err)
+		return nil
+	}
+	return boundary
+}
+
+// WriteEpochBoundary stores the last block of the specified epoch.
+func WriteEpochBoundary(db ethdb.KeyValueWriter, epoch uint64, hash common.Hash, number uint64) {
+	data, err := rlp.EncodeToBytes(&EpochBoundary{Hash: hash, Number: number})
+	if err != nil {
+		log.Crit("Failed to RLP encode epoch boundary", "err", err)
+	}
+	if err := db.Put(epochBoundaryKey(epoch), data); err != nil {
+		log.Crit("Failed to store epoch boundary", "err", err)
+	}
+}
+
+// epochBoundaryKey = epochBoundaryPrefix + epoch number
+func epochBoundaryKey(epoch uint64) []byte {
+	return append(append([]byte{}, epochBoundaryPrefix...), encodeBlockNumber(epoch)...)
+}
+
+// ReadIstanbulSnapshot retrieves the encoded istanbul validator set snapshot of
+// the specified epoch.
+func ReadIstanbulSnapshot(db ethdb.KeyValueReader, epoch uint64) []byte {
+	data, _ := db.Get(istanbulSnapshotKey(epoch))
+	return data
+}
+
+// WriteIstanbulSnapshot stores the encoded istanbul validator set snapshot of
+// the specified epoch.
+func WriteIstanbulSnapshot(db ethdb.KeyValueWriter, epoch uint64, blob []byte) {
+	if err := db.Put(istanbulSnapshotKey(epoch), blob); err != nil {
+		log.Crit("Failed to store istanbul snapshot", "err", err)
+	}
+}
+
+// DeleteIstanbulSnapshot removes the istanbul validator set snapshot of the
+// specified epoch.
+func DeleteIstanbulSnapshot(db ethdb.KeyValueWriter, epoch uint64) {
+	if err := db.Delete(istanbulSnapshotKey(epoch)); err != nil {
+		log.Crit("Failed to delete istanbul snapshot", "err", err)
+	}
+}
+
+// ReadAllIstanbulSnapshotEpochs retrieves the epochs with a stored istanbul
+// validator set snapshot, in ascending order.
+func ReadAllIstanbulSnapshotEpochs(db ethdb.Iteratee) []uint64 {
+	it := db.NewIterator(istanbulSnapshotPrefix, nil)
+	defer it.Release()
+
+	var epochs []uint64
+	for it.Next() {
+		if key := it.Key(); len(key) == len(istanbulSnapshotPrefix)+8 {
+			epochs = append(epochs, binary.BigEndian.Uint64(key[len(istanbulSnapshotPrefix):]))
+		}
+	}
+	return epochs
+}
+
+// PruneIstanbulSnapshots removes the istanbul validator set snapshots of the
+// epochs before the given one, and returns the number of removed entries. Only
+// the keys in that range are visited, so pruning as new epochs are stored only
+// touches the snapshots which just fell out of the retention.
+func PruneIstanbulSnapshots(db ethdb.KeyValueStore, before uint64) int {
+	it := db.NewIterator(istanbulSnapshotPrefix, nil)
+	defer it.Release()
+
+	end := istanbulSnapshotKey(before)
+	batch := db.NewBatch()
+	pruned := 0
+	for it.Next() {
+		key := it.Key()
+		if bytes.Compare(key, end) >= 0 {
+			break
+		}
+		if len(key) != len(istanbulSnapshotPrefix)+8 {
+			continue
+		}
+		DeleteIstanbulSnapshot(batch, binary.BigEndian.Uint64(key[len(istanbulSnapshotPrefix):]))
+		pruned++
+
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to prune istanbul snapshots", "err", err)
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to prune istanbul snapshots", "err", err)
+	}
+	return pruned
+}
+
+// istanbulSnapshotKey = istanbulSnapshotPrefix + epoch number
+func istanbulSnapshotKey(epoch uint64) []byte {
+	return append(append([]byte{}, istanbulSnapshotPrefix...), encodeBlockNumber(epoch)...)
+}
+
+// ReadDoubleSignEvidence retrieves the encoded double signing evidence recorded
+// for the specified epoch, ordered by evidence hash.
+func ReadDoubleSignEvidence(db ethdb.Iteratee, epoch uint64) [][]byte {
+	it := db.NewIterator(doubleSignEvidenceKey(epoch, common.Hash{})[:len(doubleSignEvidencePrefix)+8], nil)
+	defer it.Release()
+
+	var blobs [][]byte
+	for it.Next() {
+		if len(it.Key()) == len(doubleSignEvidencePrefix)+8+common.HashLength {
+			blobs = append(blobs, common.CopyBytes(it.Value()))
+		}
+	}
+	return blobs
+}
+
+// WriteDoubleSignEvidence stores the encoded double signing evidence with the
+// given hash for the specified epoch.
+func WriteDoubleSignEvidence(db ethdb.KeyValueWriter, epoch uint64, hash common.Hash, blob []byte) {
+	if err := db.Put(doubleSignEvidenceKey(epoch, hash), blob); err != nil {
+		log.Crit("Failed to store double sign evidence", "err", err)
+	}
+}
+
+// PruneDoubleSignEvidenceBefore removes the double signing evidence of all
+// epochs before the given one and returns the number of removed entries.
+func PruneDoubleSignEvidenceBefore(db ethdb.KeyValueStore, epoch uint64) int {
+	it := db.NewIterator(doubleSignEvidencePrefix, nil)
+	defer it.Release()
+
+	batch := db.NewBatch()
+	pruned := 0
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(doubleSignEvidencePrefix)+8+common.HashLength {
+			continue
+		}
+		if binary.BigEndian.Uint64(key[len(doubleSignEvidencePrefix):]) >= epoch {
+			break
+		}
+		if err := batch.Delete(key); err != nil {
+			log.Crit("Failed to delete double sign evidence", "err", err)
+		}
+		pruned++
+
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to prune double sign evidence", "err", err)
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to prune double sign evidence", "err", err)
+	}
+	return pruned
+}
+
+// doubleSignEvidenceKey = doubleSignEvidencePrefix + epoch number + evidence hash
+func doubleSignEvidenceKey(epoch uint64, hash common.Hash) []byte {
+	key := append(append([]byte{}, doubleSignEvidencePrefix...), encodeBlockNumber(epoch)...)
+	return append(key, hash.Bytes()...)
 }
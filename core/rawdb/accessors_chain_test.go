@@ -19,6 +19,7 @@ package rawdb
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/big"
@@ -29,9 +30,12 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/rlp"
 	"golang.org/x/crypto/sha3"
 
+	"github.com/mapprotocol/atlas/chains"
+	"github.com/mapprotocol/atlas/consensus/istanbul/uptime"
 	"github.com/mapprotocol/atlas/core/types"
 	"github.com/mapprotocol/atlas/params"
 )
@@ -204,7 +208,7 @@ func TestBadBlockStorage(t *testing.T) {
 		t.Fatalf("Non existent block returned: %v", entry)
 	}
 	// Write and verify the block in the database
-	WriteBadBlock(db, block)
+	WriteBadBlock(db, block, nil)
 	if entry := ReadBadBlock(db, block.Hash()); entry == nil {
 		t.Fatalf("Stored block not found")
 	} else if entry.Hash() != block.Hash() {
@@ -217,10 +221,10 @@ func TestBadBlockStorage(t *testing.T) {
 		TxHash:      types.EmptyRootHash,
 		ReceiptHash: types.EmptyRootHash,
 	})
-	WriteBadBlock(db, blockTwo)
+	WriteBadBlock(db, blockTwo, nil)
 
 	// Write the block one again, should be filtered out.
-	WriteBadBlock(db, block)
+	WriteBadBlock(db, block, nil)
 	badBlocks := ReadAllBadBlocks(db)
 	if len(badBlocks) != 2 {
 		t.Fatalf("Failed to load all bad blocks")
@@ -235,15 +239,15 @@ func TestBadBlockStorage(t *testing.T) {
 			TxHash:      types.EmptyRootHash,
 			ReceiptHash: types.EmptyRootHash,
 		})
-		WriteBadBlock(db, block)
+		WriteBadBlock(db, block, nil)
 	}
 	badBlocks = ReadAllBadBlocks(db)
 	if len(badBlocks) != badBlockToKeep {
 		t.Fatalf("The number of persised bad blocks in incorrect %d", len(badBlocks))
 	}
 	for i := 0; i < len(badBlocks)-1; i++ {
-		if badBlocks[i].NumberU64() < badBlocks[i+1].NumberU64() {
-			t.Fatalf("The bad blocks are not sorted #[%d](%d) < #[%d](%d)", i, i+1, badBlocks[i].NumberU64(), badBlocks[i+1].NumberU64())
+		if badBlocks[i].Block.NumberU64() < badBlocks[i+1].Block.NumberU64() {
+			t.Fatalf("The bad blocks are not sorted #[%d](%d) < #[%d](%d)", i, i+1, badBlocks[i].Block.NumberU64(), badBlocks[i+1].Block.NumberU64())
 		}
 	}
 
@@ -255,6 +259,83 @@ func TestBadBlockStorage(t *testing.T) {
 	}
 }
 
+// Tests that bad blocks keep their rejection metadata, can be looked up by
+// number range and honour the configured retention.
+func TestBadBlockMetaAndRange(t *testing.T) {
+	defer func(keep int) { badBlockToKeep = keep }(badBlockToKeep)
+	SetBadBlockRetention(5)
+
+	db := NewMemoryDatabase()
+	for n := 1; n <= 8; n++ {
+		block := types.NewBlockWithHeader(&types.Header{
+			Number:      big.NewInt(int64(n)),
+			Extra:       []byte("bad block"),
+			TxHash:      types.EmptyRootHash,
+			ReceiptHash: types.EmptyRootHash,
+		})
+		WriteBadBlock(db, block, &BadBlockMeta{Received: uint64(n), Peer: "peer", Error: fmt.Sprintf("error %d", n)})
+	}
+	badBlocks := ReadAllBadBlocks(db)
+	if len(badBlocks) != 5 {
+		t.Fatalf("bad block count mismatch: have %d, want %d", len(badBlocks), 5)
+	}
+	for _, bad := range badBlocks {
+		n := bad.Block.NumberU64()
+		if bad.Meta == nil {
+			t.Fatalf("bad block %d: metadata missing", n)
+		}
+		if want := fmt.Sprintf("error %d", n); bad.Meta.Error != want || bad.Meta.Received != n || bad.Meta.Peer != "peer" {
+			t.Fatalf("bad block %d: metadata mismatch: have %+v", n, bad.Meta)
+		}
+	}
+	ranged := ReadBadBlocksInRange(db, 5, 6)
+	if len(ranged) != 2 || ranged[0].Block.NumberU64() != 6 || ranged[1].Block.NumberU64() != 5 {
+		t.Fatalf("ranged bad blocks mismatch: have %d entries", len(ranged))
+	}
+	if ranged := ReadBadBlocksInRange(db, 1, 3); len(ranged) != 0 {
+		t.Fatalf("pruned bad blocks returned: have %d entries", len(ranged))
+	}
+}
+
+// Tests that bad blocks stored before the rejection metadata was recorded
+// still decode.
+func TestLegacyBadBlockDecoding(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	block := types.NewBlockWithHeader(&types.Header{
+		Number:      big.NewInt(1),
+		Extra:       []byte("legacy bad block"),
+		TxHash:      types.EmptyRootHash,
+		ReceiptHash: types.EmptyRootHash,
+	})
+	type legacyBadBlock struct {
+		Header *types.Header
+		Body   *types.Body
+	}
+	blob, err := rlp.EncodeToBytes([]*legacyBadBlock{{Header: block.Header(), Body: block.Body()}})
+	if err != nil {
+		t.Fatalf("failed to encode legacy bad blocks: %v", err)
+	}
+	if err := db.Put(badBlockKey, blob); err != nil {
+		t.Fatalf("failed to store legacy bad blocks: %v", err)
+	}
+	badBlocks := ReadAllBadBlocks(db)
+	if len(badBlocks) != 1 {
+		t.Fatalf("bad block count mismatch: have %d, want %d", len(badBlocks), 1)
+	}
+	if badBlocks[0].Block.Hash() != block.Hash() {
+		t.Fatalf("bad block mismatch: have %x, want %x", badBlocks[0].Block.Hash(), block.Hash())
+	}
+	if badBlocks[0].Meta != nil {
+		t.Fatalf("legacy bad block has metadata: %+v", badBlocks[0].Meta)
+	}
+	// Appending to a legacy list must keep the old entry around.
+	WriteBadBlock(db, types.NewBlockWithHeader(&types.Header{Number: big.NewInt(2)}), &BadBlockMeta{Error: "bad"})
+	if badBlocks := ReadAllBadBlocks(db); len(badBlocks) != 2 || badBlocks[0].Meta == nil || badBlocks[1].Meta != nil {
+		t.Fatalf("mixed bad block list mismatch")
+	}
+}
+
 // Tests block total difficulty storage and retrieval operations.
 func TestTdStorage(t *testing.T) {
 	db := NewMemoryDatabase()
@@ -301,6 +382,44 @@ func TestCanonicalMappingStorage(t *testing.T) {
 	}
 }
 
+// Tests that canonical blocks and headers can be retrieved by number.
+func TestCanonicalBlockStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	block := types.NewBlockWithHeader(&types.Header{
+		Number:      big.NewInt(3),
+		Extra:       []byte("canonical block"),
+		TxHash:      types.EmptyRootHash,
+		ReceiptHash: types.EmptyRootHash,
+	})
+	// Neither the block nor its canonical mapping are known
+	if ReadCanonicalBlock(db, 3) != nil || ReadCanonicalHeader(db, 3) != nil {
+		t.Fatalf("Non existent canonical block returned")
+	}
+	// A canonical mapping without the block yields nothing
+	WriteCanonicalHash(db, block.Hash(), 3)
+	if ReadCanonicalBlock(db, 3) != nil || ReadCanonicalHeader(db, 3) != nil {
+		t.Fatalf("Canonical block returned without stored data")
+	}
+	// The header alone is not a block
+	WriteHeader(db, block.Header())
+	if header := ReadCanonicalHeader(db, 3); header == nil || header.Hash() != block.Hash() {
+		t.Fatalf("Canonical header mismatch: have %v, want %v", header, block.Header())
+	}
+	if ReadCanonicalBlock(db, 3) != nil {
+		t.Fatalf("Canonical block returned without body")
+	}
+	WriteBody(db, block.Hash(), 3, block.Body())
+	if entry := ReadCanonicalBlock(db, 3); entry == nil || entry.Hash() != block.Hash() {
+		t.Fatalf("Canonical block mismatch: have %v, want %v", entry, block)
+	}
+	// Blocks off the canonical chain are not returned
+	DeleteCanonicalHash(db, 3)
+	if ReadCanonicalBlock(db, 3) != nil || ReadCanonicalHeader(db, 3) != nil {
+		t.Fatalf("Non canonical block returned")
+	}
+}
+
 // Tests that head headers and head blocks can be assigned, individually.
 func TestHeadStorage(t *testing.T) {
 	db := NewMemoryDatabase()
@@ -308,6 +427,7 @@ func TestHeadStorage(t *testing.T) {
 	blockHead := types.NewBlockWithHeader(&types.Header{Extra: []byte("test block header")})
 	blockFull := types.NewBlockWithHeader(&types.Header{Extra: []byte("test block full")})
 	blockFast := types.NewBlockWithHeader(&types.Header{Extra: []byte("test block fast")})
+	blockFinal := types.NewBlockWithHeader(&types.Header{Extra: []byte("test block finalized")})
 
 	// Check that no head entries are in a pristine database
 	if entry := ReadHeadHeaderHash(db); entry != (common.Hash{}) {
@@ -319,10 +439,14 @@ func TestHeadStorage(t *testing.T) {
 	if entry := ReadHeadFastBlockHash(db); entry != (common.Hash{}) {
 		t.Fatalf("Non fast head block entry returned: %v", entry)
 	}
+	if entry := ReadFinalizedBlockHash(db); entry != (common.Hash{}) {
+		t.Fatalf("Non finalized block entry returned: %v", entry)
+	}
 	// Assign separate entries for the head header and block
 	WriteHeadHeaderHash(db, blockHead.Hash())
 	WriteHeadBlockHash(db, blockFull.Hash())
 	WriteHeadFastBlockHash(db, blockFast.Hash())
+	WriteFinalizedBlockHash(db, blockFinal.Hash())
 
 	// Check that both heads are present, and different (i.e. two heads maintained)
 	if entry := ReadHeadHeaderHash(db); entry != blockHead.Hash() {
@@ -334,6 +458,27 @@ func TestHeadStorage(t *testing.T) {
 	if entry := ReadHeadFastBlockHash(db); entry != blockFast.Hash() {
 		t.Fatalf("Fast head block hash mismatch: have %v, want %v", entry, blockFast.Hash())
 	}
+	if entry := ReadFinalizedBlockHash(db); entry != blockFinal.Hash() {
+		t.Fatalf("Finalized block hash mismatch: have %v, want %v", entry, blockFinal.Hash())
+	}
+}
+
+// Tests that the head block summary is stored along with the head block hash.
+func TestHeadBlockMeta(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	if meta := ReadHeadBlockMeta(db); meta != nil {
+		t.Fatalf("Non existent head block meta returned: %v", meta)
+	}
+	header := &types.Header{Number: big.NewInt(42), Root: common.Hash{0x01}, Time: 1600000000, Extra: []byte("head")}
+	WriteHeadBlock(db, header)
+	if hash := ReadHeadBlockHash(db); hash != header.Hash() {
+		t.Fatalf("Head block hash mismatch: have %x, want %x", hash, header.Hash())
+	}
+	want := &HeadBlockMeta{Number: 42, Hash: header.Hash(), Root: common.Hash{0x01}, Time: 1600000000}
+	if meta := ReadHeadBlockMeta(db); !reflect.DeepEqual(meta, want) {
+		t.Fatalf("Head block meta mismatch: have %+v, want %+v", meta, want)
+	}
 }
 
 // Tests that receipts associated with a single block can be stored and retrieved.
@@ -409,6 +554,58 @@ func TestBlockReceiptStorage(t *testing.T) {
 	}
 }
 
+// Tests that receipts only get written if they are consistent with the block.
+func TestWriteReceiptsChecked(t *testing.T) {
+	tx1 := types.NewTransaction(1, common.HexToAddress("0x1"), big.NewInt(1), 1, big.NewInt(1), nil)
+	tx2 := types.NewTransaction(2, common.HexToAddress("0x2"), big.NewInt(2), 2, big.NewInt(2), nil)
+	newReceipt := func(addr byte) *types.Receipt {
+		return &types.Receipt{
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: 1,
+			Logs:              []*types.Log{{Address: common.BytesToAddress([]byte{addr})}},
+		}
+	}
+	receipts := types.Receipts{newReceipt(0x11), newReceipt(0x22)}
+	finalized := types.Receipts{receipts[0], receipts[1], newReceipt(0x33)}
+
+	tests := []struct {
+		name     string
+		block    *types.Block // receipts used to compute the header's bloom
+		receipts types.Receipts
+		ok       bool
+	}{
+		{"matching", types.NewBlock(&types.Header{Number: big.NewInt(1)}, types.Transactions{tx1, tx2}, receipts, nil), receipts, true},
+		{"finalization receipt", types.NewBlock(&types.Header{Number: big.NewInt(2)}, types.Transactions{tx1, tx2}, finalized, nil), finalized, true},
+		{"missing receipt", types.NewBlock(&types.Header{Number: big.NewInt(3)}, types.Transactions{tx1, tx2}, receipts, nil), receipts[:1], false},
+		{"extra receipts", types.NewBlock(&types.Header{Number: big.NewInt(4)}, types.Transactions{tx1}, receipts[:1], nil), finalized, false},
+		{"bloom mismatch", types.NewBlock(&types.Header{Number: big.NewInt(5)}, types.Transactions{tx1, tx2}, receipts, nil), types.Receipts{receipts[0], newReceipt(0x44)}, false},
+	}
+	for _, tt := range tests {
+		db := NewMemoryDatabase()
+		hash, number := tt.block.Hash(), tt.block.NumberU64()
+		WriteBody(db, hash, number, tt.block.Body())
+
+		err := WriteReceiptsChecked(db, hash, number, tt.receipts, tt.block.Header())
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: have err %v, want ok %v", tt.name, err, tt.ok)
+		}
+		if stored := ReadRawReceipts(db, hash, number) != nil; stored != tt.ok {
+			t.Errorf("%s: receipts stored %v, want %v", tt.name, stored, tt.ok)
+		}
+	}
+	// The header must belong to the block the receipts are written for
+	db := NewMemoryDatabase()
+	block := tests[0].block
+	WriteBody(db, block.Hash(), block.NumberU64(), block.Body())
+	if err := WriteReceiptsChecked(db, block.Hash(), block.NumberU64(), receipts, tests[1].block.Header()); err == nil {
+		t.Errorf("receipts written for a foreign header")
+	}
+	// Receipts of a block without a body can't be checked
+	if err := WriteReceiptsChecked(NewMemoryDatabase(), block.Hash(), block.NumberU64(), receipts, block.Header()); err == nil {
+		t.Errorf("receipts written without a body")
+	}
+}
+
 func checkReceiptsRLP(have, want types.Receipts) error {
 	if len(have) != len(want) {
 		return fmt.Errorf("receipts sizes mismatch: have %d, want %d", len(have), len(want))
@@ -429,6 +626,219 @@ func checkReceiptsRLP(have, want types.Receipts) error {
 	return nil
 }
 
+// writeReceiptTestBlock stores a canonical block of n transactions with
+// receipts and lookup entries, and returns the block.
+func writeReceiptTestBlock(db ethdb.KeyValueWriter, n int) *types.Block {
+	txs := make([]*types.Transaction, n)
+	receipts := make(types.Receipts, n)
+	for i := 0; i < n; i++ {
+		if i%3 == 2 {
+			txs[i] = types.NewContractCreation(uint64(i), big.NewInt(0), 100000, big.NewInt(1), []byte{0x60, byte(i)})
+		} else {
+			txs[i] = types.NewTransaction(uint64(i), common.BytesToAddress([]byte{byte(i)}), big.NewInt(int64(i)), 21000, big.NewInt(1), nil)
+		}
+		receipts[i] = &types.Receipt{
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: uint64(21000 * (i + 1)),
+			Logs:              make([]*types.Log, i%4),
+		}
+		for j := range receipts[i].Logs {
+			receipts[i].Logs[j] = &types.Log{Address: common.BytesToAddress([]byte{byte(i), byte(j)}), Data: []byte{byte(j)}}
+		}
+	}
+	block := types.NewBlock(&types.Header{Number: big.NewInt(42)}, txs, nil, &types.Randomness{})
+	WriteBlock(db, block)
+	WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+	WriteTxLookupEntriesByBlock(db, block)
+	WriteReceipts(db, block.Hash(), block.NumberU64(), receipts)
+	return block
+}
+
+// Tests that a single receipt read by transaction hash matches the receipt
+// derived from the whole block.
+func TestReadReceipt(t *testing.T) {
+	db := NewMemoryDatabase()
+	block := writeReceiptTestBlock(db, 10)
+
+	want := ReadReceipts(db, block.Hash(), block.NumberU64(), params.TestChainConfig)
+	for i, tx := range block.Transactions() {
+		receipt, hash, number, index := ReadReceipt(db, tx.Hash(), params.TestChainConfig)
+		if receipt == nil {
+			t.Fatalf("tx #%d: receipt not found", i)
+		}
+		if hash != block.Hash() || number != block.NumberU64() || index != uint64(i) {
+			t.Errorf("tx #%d: positional metadata mismatch: have %x/%d/%d, want %x/%d/%d", i, hash, number, index, block.Hash(), block.NumberU64(), i)
+		}
+		if !reflect.DeepEqual(receipt, want[i]) {
+			t.Errorf("tx #%d: receipt mismatch: have %+v, want %+v", i, receipt, want[i])
+		}
+	}
+	if receipt, _, _, _ := ReadReceipt(db, common.Hash{0x01}, params.TestChainConfig); receipt != nil {
+		t.Errorf("receipt returned for unknown transaction")
+	}
+	// A block finalization receipt after the transaction ones is accepted, but
+	// receipts not matching the transactions aren't
+	last := block.Transactions()[len(block.Transactions())-1].Hash()
+	receipts := ReadRawReceipts(db, block.Hash(), block.NumberU64())
+	WriteReceipts(db, block.Hash(), block.NumberU64(), append(receipts, &types.Receipt{Status: types.ReceiptStatusSuccessful}))
+	if receipt, _, _, _ := ReadReceipt(db, last, params.TestChainConfig); receipt == nil {
+		t.Errorf("receipt not found next to the finalization receipt")
+	}
+	WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[:len(receipts)-2])
+	if receipt, _, _, _ := ReadReceipt(db, block.Transactions()[0].Hash(), params.TestChainConfig); receipt != nil {
+		t.Errorf("receipt returned from mismatching receipts")
+	}
+}
+
+// Tests that receipts of large blocks decoded concurrently are the same as
+// decoded one after the other, including the IBFT block finalization receipt.
+func TestReadRawReceiptsParallel(t *testing.T) {
+	db := NewMemoryDatabase()
+	block := writeReceiptTestBlock(db, 2*parallelReceiptsThreshold)
+
+	// Append a block finalization receipt to the stored ones
+	receipts := ReadRawReceipts(db, block.Hash(), block.NumberU64())
+	receipts = append(receipts, &types.Receipt{
+		Status:            types.ReceiptStatusSuccessful,
+		CumulativeGasUsed: receipts[len(receipts)-1].CumulativeGasUsed,
+		Logs:              []*types.Log{{Address: common.Address{0xff}}},
+	})
+	WriteReceipts(db, block.Hash(), block.NumberU64(), receipts)
+
+	var want []*types.ReceiptForStorage
+	if err := rlp.DecodeBytes(ReadReceiptsRLP(db, block.Hash(), block.NumberU64()), &want); err != nil {
+		t.Fatalf("failed to decode receipts: %v", err)
+	}
+	have := ReadRawReceipts(db, block.Hash(), block.NumberU64())
+	if len(have) != len(want) {
+		t.Fatalf("receipt count mismatch: have %d, want %d", len(have), len(want))
+	}
+	for i := range have {
+		if !reflect.DeepEqual(have[i], (*types.Receipt)(want[i])) {
+			t.Fatalf("receipt #%d mismatch: have %+v, want %+v", i, have[i], want[i])
+		}
+	}
+	// The derived log indexes must be consecutive across all the receipts
+	derived := ReadReceipts(db, block.Hash(), block.NumberU64(), params.TestChainConfig)
+	if len(derived) != len(want) {
+		t.Fatalf("derived receipt count mismatch: have %d, want %d", len(derived), len(want))
+	}
+	var index uint
+	for i, receipt := range derived {
+		for _, log := range receipt.Logs {
+			if log.Index != index || log.TxIndex != uint(i) {
+				t.Fatalf("receipt #%d: log position mismatch: have %d/%d, want %d/%d", i, log.Index, log.TxIndex, index, i)
+			}
+			index++
+		}
+	}
+	if last := derived[len(derived)-1].Logs[0]; last.TxHash != block.Hash() {
+		t.Errorf("finalization log tx hash mismatch: have %x, want %x", last.TxHash, block.Hash())
+	}
+}
+
+// Tests that ReadReceiptsErr tells apart why the receipts of a block can't be read.
+func TestReadReceiptsErr(t *testing.T) {
+	db := NewMemoryDatabase()
+	block := writeReceiptTestBlock(db, 3)
+	hash, number := block.Hash(), block.NumberU64()
+
+	if receipts, err := ReadReceiptsErr(db, hash, number, params.TestChainConfig); err != nil || len(receipts) != 3 {
+		t.Fatalf("receipts mismatch: have (%d, %v), want (3, nil)", len(receipts), err)
+	}
+	// Two receipts can't belong to three transactions, four can
+	receipts := ReadRawReceipts(db, hash, number)
+	WriteReceipts(db, hash, number, receipts[:2])
+	if _, err := ReadReceiptsErr(db, hash, number, params.TestChainConfig); !errors.Is(err, ErrReceiptCountMismatch) {
+		t.Errorf("error mismatch: have %v, want %v", err, ErrReceiptCountMismatch)
+	}
+	WriteReceipts(db, hash, number, append(receipts, &types.Receipt{Status: types.ReceiptStatusSuccessful}))
+	if receipts, err := ReadReceiptsErr(db, hash, number, params.TestChainConfig); err != nil || len(receipts) != 4 {
+		t.Errorf("receipts with finalization receipt mismatch: have (%d, %v), want (4, nil)", len(receipts), err)
+	}
+	DeleteBody(db, hash, number)
+	if _, err := ReadReceiptsErr(db, hash, number, params.TestChainConfig); !errors.Is(err, ErrMissingBody) {
+		t.Errorf("error mismatch: have %v, want %v", err, ErrMissingBody)
+	}
+	DeleteReceipts(db, hash, number)
+	if _, err := ReadReceiptsErr(db, hash, number, params.TestChainConfig); err != ErrNoReceipts {
+		t.Errorf("error mismatch: have %v, want %v", err, ErrNoReceipts)
+	}
+}
+
+// Tests that the Read*Err accessors tell apart missing and corrupt entries.
+func TestReadErrAccessors(t *testing.T) {
+	db := NewMemoryDatabase()
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(7), Extra: []byte("test block")})
+	hash, number := block.Hash(), block.NumberU64()
+
+	if _, err := ReadHeaderErr(db, hash, number); err != ErrMissingHeader {
+		t.Errorf("header error mismatch: have %v, want %v", err, ErrMissingHeader)
+	}
+	if _, err := ReadBodyErr(db, hash, number); err != ErrMissingBody {
+		t.Errorf("body error mismatch: have %v, want %v", err, ErrMissingBody)
+	}
+	if _, err := ReadTdErr(db, hash, number); err != ErrMissingTd {
+		t.Errorf("td error mismatch: have %v, want %v", err, ErrMissingTd)
+	}
+	WriteBlock(db, block)
+	WriteTd(db, hash, number, big.NewInt(42))
+	if have, err := ReadBlockErr(db, hash, number); err != nil || have.Hash() != hash {
+		t.Fatalf("block mismatch: have (%v, %v), want (%x, nil)", have, err, hash)
+	}
+	if td, err := ReadTdErr(db, hash, number); err != nil || td.Cmp(big.NewInt(42)) != 0 {
+		t.Errorf("td mismatch: have (%v, %v), want (42, nil)", td, err)
+	}
+
+	// Corrupt entries are reported, not treated as missing
+	garbage := []byte{0xc3, 0x01}
+	for _, key := range [][]byte{headerKey(number, hash), blockBodyKey(number, hash), headerTDKey(number, hash)} {
+		if err := db.Put(key, garbage); err != nil {
+			t.Fatalf("failed to corrupt entry: %v", err)
+		}
+	}
+	if _, err := ReadHeaderErr(db, hash, number); !errors.Is(err, ErrCorruptData) {
+		t.Errorf("header error mismatch: have %v, want %v", err, ErrCorruptData)
+	}
+	if _, err := ReadBodyErr(db, hash, number); !errors.Is(err, ErrCorruptData) {
+		t.Errorf("body error mismatch: have %v, want %v", err, ErrCorruptData)
+	}
+	if _, err := ReadTdErr(db, hash, number); !errors.Is(err, ErrCorruptData) {
+		t.Errorf("td error mismatch: have %v, want %v", err, ErrCorruptData)
+	}
+	if _, err := ReadBlockErr(db, hash, number); !errors.Is(err, ErrCorruptData) {
+		t.Errorf("block error mismatch: have %v, want %v", err, ErrCorruptData)
+	}
+	// The nil returning accessors keep their behaviour
+	if header := ReadHeader(db, hash, number); header != nil {
+		t.Errorf("corrupt header returned: %v", header)
+	}
+}
+
+func BenchmarkReadRawReceipts(b *testing.B) {
+	db := NewMemoryDatabase()
+	block := writeReceiptTestBlock(db, 500)
+	data := ReadReceiptsRLP(db, block.Hash(), block.NumberU64())
+
+	b.Run("serial", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var receipts []*types.ReceiptForStorage
+			if err := rlp.DecodeBytes(data, &receipts); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("parallel", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if receipts := ReadRawReceipts(db, block.Hash(), block.NumberU64()); len(receipts) != 500 {
+				b.Fatal("receipts not found")
+			}
+		}
+	})
+}
+
 func TestAncientStorage(t *testing.T) {
 	// Freezer style fast import the chain.
 	frdir, err := ioutil.TempDir("", "")
@@ -494,6 +904,654 @@ func TestAncientStorage(t *testing.T) {
 	}
 }
 
+func TestReadAncientRange(t *testing.T) {
+	frdir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temp freezer dir: %v", err)
+	}
+	defer os.RemoveAll(frdir)
+
+	db, err := NewDatabaseWithFreezer(NewMemoryDatabase(), frdir, "", false)
+	if err != nil {
+		t.Fatalf("failed to create database with ancient backend")
+	}
+	defer db.Close()
+
+	blocks := makeTestBlocks(8, 3)
+	receipts := makeTestReceipts(8, 3)
+	if _, err := WriteAncientBlocks(db, blocks, receipts, big.NewInt(100)); err != nil {
+		t.Fatalf("failed to write ancient blocks: %v", err)
+	}
+
+	haveBlocks, haveReceipts, haveTds, err := ReadAncientRange(db, 2, 5)
+	if err != nil {
+		t.Fatalf("failed to read ancient range: %v", err)
+	}
+	if len(haveBlocks) != 5 || len(haveReceipts) != 5 || len(haveTds) != 5 {
+		t.Fatalf("range length mismatch: have %d/%d/%d, want 5", len(haveBlocks), len(haveReceipts), len(haveTds))
+	}
+	for i, block := range haveBlocks {
+		want := blocks[i+2]
+		if block.Hash() != want.Hash() {
+			t.Errorf("block #%d: hash mismatch: have %x, want %x", want.NumberU64(), block.Hash(), want.Hash())
+		}
+		if len(block.Transactions()) != len(want.Transactions()) {
+			t.Errorf("block #%d: tx count mismatch: have %d, want %d", want.NumberU64(), len(block.Transactions()), len(want.Transactions()))
+		}
+		if len(haveReceipts[i]) != 3 || haveReceipts[i][0].CumulativeGasUsed != receipts[i+2][0].CumulativeGasUsed {
+			t.Errorf("block #%d: receipts mismatch", want.NumberU64())
+		}
+		if td := ReadTd(db, want.Hash(), want.NumberU64()); haveTds[i].Cmp(td) != 0 {
+			t.Errorf("block #%d: td mismatch: have %v, want %v", want.NumberU64(), haveTds[i], td)
+		}
+	}
+	if _, _, _, err := ReadAncientRange(db, 6, 3); err == nil {
+		t.Errorf("range beyond the ancient store accepted")
+	}
+	if _, _, _, err := ReadAncientRange(NewMemoryDatabase(), 0, 1); err == nil {
+		t.Errorf("range read without ancient store succeeded")
+	}
+}
+
+// newRangeTestDatabase returns a database holding a canonical chain of n
+// blocks, of which the first frozen ones are in the ancient store.
+func newRangeTestDatabase(tb testing.TB, n, frozen int) (ethdb.Database, []*types.Block, func()) {
+	frdir, err := ioutil.TempDir("", "")
+	if err != nil {
+		tb.Fatalf("failed to create temp freezer dir: %v", err)
+	}
+	db, err := NewDatabaseWithFreezer(NewMemoryDatabase(), frdir, "", false)
+	if err != nil {
+		os.RemoveAll(frdir)
+		tb.Fatalf("failed to create database with ancient backend")
+	}
+	blocks := makeTestBlocks(n, 1)
+	if _, err := WriteAncientBlocks(db, blocks[:frozen], makeTestReceipts(frozen, 1), big.NewInt(100)); err != nil {
+		tb.Fatalf("failed to write ancient blocks: %v", err)
+	}
+	for _, block := range blocks[frozen:] {
+		WriteBlock(db, block)
+		WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+	}
+	return db, blocks, func() {
+		db.Close()
+		os.RemoveAll(frdir)
+	}
+}
+
+func TestReadHeaderRange(t *testing.T) {
+	db, blocks, cleanup := newRangeTestDatabase(t, 10, 6)
+	defer cleanup()
+
+	tests := []struct {
+		from, count uint64
+		want        uint64
+	}{
+		{0, 0, 0},
+		{0, 4, 4},  // frozen only
+		{7, 3, 3},  // leveldb only
+		{3, 6, 6},  // spanning both
+		{4, 10, 6}, // cut short at the head
+		{10, 5, 0}, // beyond the head
+		{0, 100, 10},
+	}
+	for _, tt := range tests {
+		headers, n := ReadHeaderRange(db, tt.from, tt.count)
+		if n != tt.want || uint64(len(headers)) != n {
+			t.Errorf("headers [%d, +%d): have %d (%d items), want %d", tt.from, tt.count, n, len(headers), tt.want)
+			continue
+		}
+		for i, data := range headers {
+			if want, _ := rlp.EncodeToBytes(blocks[tt.from+uint64(i)].Header()); !bytes.Equal(data, want) {
+				t.Errorf("header #%d mismatch", tt.from+uint64(i))
+			}
+		}
+		bodies, n := ReadCanonicalBodyRange(db, tt.from, tt.count)
+		if n != tt.want || uint64(len(bodies)) != n {
+			t.Errorf("bodies [%d, +%d): have %d (%d items), want %d", tt.from, tt.count, n, len(bodies), tt.want)
+			continue
+		}
+		for i, data := range bodies {
+			if want, _ := rlp.EncodeToBytes(blocks[tt.from+uint64(i)].Body()); !bytes.Equal(data, want) {
+				t.Errorf("body #%d mismatch", tt.from+uint64(i))
+			}
+		}
+	}
+	// A gap in leveldb ends the range early.
+	DeleteCanonicalHash(db, 8)
+	if _, n := ReadHeaderRange(db, 5, 5); n != 3 {
+		t.Errorf("headers across a gap: have %d, want 3", n)
+	}
+	// Without an ancient store everything comes from leveldb.
+	memdb := NewMemoryDatabase()
+	for _, block := range blocks {
+		WriteBlock(memdb, block)
+		WriteCanonicalHash(memdb, block.Hash(), block.NumberU64())
+	}
+	if _, n := ReadCanonicalBodyRange(memdb, 2, 5); n != 5 {
+		t.Errorf("bodies without ancient store: have %d, want 5", n)
+	}
+}
+
+// BenchmarkReadHeaderRange measures serving a 192 header skeleton request from
+// the freezer, in bulk and one header at a time.
+func BenchmarkReadHeaderRange(b *testing.B) {
+	const count = 192
+	db, _, cleanup := newRangeTestDatabase(b, 2*count, 2*count)
+	defer cleanup()
+
+	b.Run("range", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, n := ReadHeaderRange(db, count/2, count); n != count {
+				b.Fatalf("have %d headers, want %d", n, count)
+			}
+		}
+	})
+	b.Run("single", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for number := uint64(count / 2); number < count/2+count; number++ {
+				if len(ReadHeaderRLP(db, ReadCanonicalHash(db, number), number)) == 0 {
+					b.Fatalf("header #%d missing", number)
+				}
+			}
+		}
+	})
+}
+
+// Tests that the randomness and epoch SNARK data of canonical blocks can be
+// read both from the ancient store and from recent bodies.
+func TestReadBodyFields(t *testing.T) {
+	frdir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temp freezer dir: %v", err)
+	}
+	defer os.RemoveAll(frdir)
+
+	db, err := NewDatabaseWithFreezer(NewMemoryDatabase(), frdir, "", false)
+	if err != nil {
+		t.Fatalf("failed to create database with ancient backend")
+	}
+	defer db.Close()
+
+	blocks := makeBodyFieldTestBlocks(8)
+	if _, err := WriteAncientBlocks(db, blocks[:5], makeTestReceipts(5, 1), big.NewInt(100)); err != nil {
+		t.Fatalf("failed to write ancient blocks: %v", err)
+	}
+	for _, block := range blocks[5:] {
+		WriteBlock(db, block)
+		WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+	}
+	for _, block := range blocks {
+		number := block.NumberU64()
+		if number < 5 {
+			if data, _ := db.Ancient(freezerEpochSnarkDataTable, number); len(data) == 0 {
+				t.Errorf("block #%d: epoch snark data not frozen", number)
+			}
+		}
+		have, _ := rlp.EncodeToBytes(ReadRandomness(db, number))
+		want, _ := rlp.EncodeToBytes(block.Randomness())
+		if !bytes.Equal(have, want) {
+			t.Errorf("block #%d: randomness mismatch: have %x, want %x", number, have, want)
+		}
+		have, _ = rlp.EncodeToBytes(ReadEpochSnarkData(db, number))
+		want, _ = rlp.EncodeToBytes(block.EpochSnarkData())
+		if !bytes.Equal(have, want) {
+			t.Errorf("block #%d: epoch snark data mismatch: have %x, want %x", number, have, want)
+		}
+	}
+	if ReadRandomness(db, 8) != nil || ReadEpochSnarkData(db, 8) != nil {
+		t.Errorf("body fields returned for a missing block")
+	}
+}
+
+func TestUptimeEpochs(t *testing.T) {
+	db := NewMemoryDatabase()
+	if epoch, u := ReadLatestEpochUptime(db); epoch != 0 || u != nil {
+		t.Fatalf("latest uptime of an empty database: have epoch %d, uptime %v", epoch, u)
+	}
+	for _, epoch := range []uint64{7, 1, 300, 2, 5} {
+		WriteAccumulatedEpochUptime(db, epoch, &uptime.Uptime{LatestBlock: epoch})
+	}
+	// A key sharing the prefix but not the layout is not an uptime entry.
+	db.Put(append(append([]byte{}, uptimePrefix...), 0x01), []byte{0x01})
+
+	if have, want := ReadAllUptimeEpochs(db), []uint64{1, 2, 5, 7, 300}; !reflect.DeepEqual(have, want) {
+		t.Fatalf("epochs mismatch: have %v, want %v", have, want)
+	}
+	if epoch, u := ReadLatestEpochUptime(db); epoch != 300 || u == nil || u.LatestBlock != 300 {
+		t.Fatalf("latest uptime mismatch: have epoch %d, uptime %v", epoch, u)
+	}
+	DeleteAccumulatedEpochUptime(db, 2)
+	if ReadAccumulatedEpochUptime(db, 2) != nil {
+		t.Fatalf("deleted uptime returned")
+	}
+	if pruned := PruneUptimeBefore(db, 7); pruned != 2 {
+		t.Fatalf("pruned entries mismatch: have %d, want 2", pruned)
+	}
+	if have, want := ReadAllUptimeEpochs(db), []uint64{7, 300}; !reflect.DeepEqual(have, want) {
+		t.Fatalf("epochs after pruning mismatch: have %v, want %v", have, want)
+	}
+	if u := ReadAccumulatedEpochUptime(db, 7); u == nil || u.LatestBlock != 7 {
+		t.Fatalf("retained uptime mismatch: have %v", u)
+	}
+	if pruned := PruneUptimeBefore(db, 7); pruned != 0 {
+		t.Fatalf("pruned entries mismatch: have %d, want 0", pruned)
+	}
+}
+
+func TestEpochUptimeHead(t *testing.T) {
+	db := NewMemoryDatabase()
+	if head := ReadEpochUptimeHead(db, 1); head != 0 {
+		t.Fatalf("uptime head of a missing epoch: have %d, want 0", head)
+	}
+	for _, block := range []uint64{10, 11, 15} {
+		WriteAccumulatedEpochUptime(db, 1, &uptime.Uptime{LatestBlock: block})
+		if head := ReadEpochUptimeHead(db, 1); head != block {
+			t.Fatalf("uptime head mismatch: have %d, want %d", head, block)
+		}
+	}
+	// Uptime written without the separate head falls back to the record
+	db.Delete(epochUptimeHeadKey(1))
+	if head := ReadEpochUptimeHead(db, 1); head != 15 {
+		t.Fatalf("uptime head fallback mismatch: have %d, want 15", head)
+	}
+	DeleteAccumulatedEpochUptime(db, 1)
+	if head := ReadEpochUptimeHead(db, 1); head != 0 {
+		t.Fatalf("uptime head of a deleted epoch: have %d, want 0", head)
+	}
+}
+
+func TestDoubleSignEvidenceStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+	if blobs := ReadDoubleSignEvidence(db, 1); len(blobs) != 0 {
+		t.Fatalf("evidence returned from an empty database: %x", blobs)
+	}
+	for epoch := uint64(1); epoch <= 3; epoch++ {
+		WriteDoubleSignEvidence(db, epoch, common.Hash{0x02}, []byte{byte(epoch), 0x02})
+		WriteDoubleSignEvidence(db, epoch, common.Hash{0x01}, []byte{byte(epoch), 0x01})
+	}
+	if have, want := ReadDoubleSignEvidence(db, 2), [][]byte{{0x02, 0x01}, {0x02, 0x02}}; !reflect.DeepEqual(have, want) {
+		t.Fatalf("evidence mismatch: have %x, want %x", have, want)
+	}
+	if pruned := PruneDoubleSignEvidenceBefore(db, 3); pruned != 4 {
+		t.Fatalf("pruned entries mismatch: have %d, want 4", pruned)
+	}
+	if blobs := ReadDoubleSignEvidence(db, 2); len(blobs) != 0 {
+		t.Fatalf("pruned evidence returned: %x", blobs)
+	}
+	if blobs := ReadDoubleSignEvidence(db, 3); len(blobs) != 2 {
+		t.Fatalf("retained evidence count mismatch: have %d, want 2", len(blobs))
+	}
+}
+
+func TestIstanbulSnapshotStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	if blob := ReadIstanbulSnapshot(db, 1); blob != nil {
+		t.Fatalf("non existent snapshot returned: %x", blob)
+	}
+	for epoch := uint64(0); epoch < 5; epoch++ {
+		WriteIstanbulSnapshot(db, epoch, []byte{byte(epoch), 0xff})
+	}
+	if blob := ReadIstanbulSnapshot(db, 3); !bytes.Equal(blob, []byte{3, 0xff}) {
+		t.Fatalf("snapshot mismatch: have %x, want %x", blob, []byte{3, 0xff})
+	}
+	DeleteIstanbulSnapshot(db, 3)
+	if blob := ReadIstanbulSnapshot(db, 3); blob != nil {
+		t.Fatalf("deleted snapshot returned: %x", blob)
+	}
+	if have, want := ReadAllIstanbulSnapshotEpochs(db), []uint64{0, 1, 2, 4}; !reflect.DeepEqual(have, want) {
+		t.Fatalf("snapshot epochs mismatch: have %v, want %v", have, want)
+	}
+	if pruned := PruneIstanbulSnapshots(db, 0); pruned != 0 {
+		t.Fatalf("pruned snapshots within retention: have %d, want 0", pruned)
+	}
+	if pruned := PruneIstanbulSnapshots(db, 2); pruned != 2 {
+		t.Fatalf("pruned snapshots mismatch: have %d, want 2", pruned)
+	}
+	// Pruning the same range again finds nothing left in it
+	if pruned := PruneIstanbulSnapshots(db, 2); pruned != 0 {
+		t.Fatalf("pruned snapshots mismatch: have %d, want 0", pruned)
+	}
+	if have, want := ReadAllIstanbulSnapshotEpochs(db), []uint64{2, 4}; !reflect.DeepEqual(have, want) {
+		t.Fatalf("retained epochs mismatch: have %v, want %v", have, want)
+	}
+}
+
+func TestPruneRandomCommitments(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	// Canonical chain 0..20, plus side chain headers at 5 and 18.
+	var canonical []*types.Header
+	for i := uint64(0); i <= 20; i++ {
+		header := &types.Header{Number: new(big.Int).SetUint64(i), Extra: []byte("canonical")}
+		WriteHeader(db, header)
+		WriteCanonicalHash(db, header.Hash(), i)
+		canonical = append(canonical, header)
+	}
+	WriteHeadHeaderHash(db, canonical[20].Hash())
+
+	oldSide := &types.Header{Number: big.NewInt(5), Extra: []byte("side")}
+	newSide := &types.Header{Number: big.NewInt(18), Extra: []byte("side")}
+	WriteHeader(db, oldSide)
+	WriteHeader(db, newSide)
+
+	entries := map[common.Hash]common.Hash{
+		{0x01}: canonical[3].Hash(),  // behind the horizon
+		{0x02}: canonical[10].Hash(), // right at the horizon
+		{0x03}: canonical[19].Hash(), // recent
+		{0x04}: oldSide.Hash(),       // reorged out, behind the horizon
+		{0x05}: newSide.Hash(),       // reorged out, recent
+		{0x06}: {0xff},               // unknown parent
+		{0x07}: canonical[1].Hash(),  // behind the horizon, but the validator's last
+	}
+	for commitment, parent := range entries {
+		WriteRandomCommitmentCache(db, commitment, parent)
+	}
+	if pruned := PruneRandomCommitments(db, 10, common.Hash{0x07}); pruned != 3 {
+		t.Fatalf("pruned entries mismatch: have %d, want 3", pruned)
+	}
+	for commitment, parent := range entries {
+		want := parent
+		switch commitment {
+		case common.Hash{0x01}, common.Hash{0x04}, common.Hash{0x06}:
+			want = common.Hash{}
+		}
+		if have := ReadRandomCommitmentCache(db, commitment); have != want {
+			t.Errorf("commitment %x: have %x, want %x", commitment, have, want)
+		}
+	}
+	DeleteRandomCommitmentCache(db, common.Hash{0x03})
+	if have := ReadRandomCommitmentCache(db, common.Hash{0x03}); have != (common.Hash{}) {
+		t.Fatalf("deleted commitment returned %x", have)
+	}
+	// Nothing is pruned while the chain is shorter than the retention.
+	if pruned := PruneRandomCommitments(db, 100, common.Hash{}); pruned != 0 {
+		t.Fatalf("pruned entries mismatch: have %d, want 0", pruned)
+	}
+}
+
+func TestPruneBlockRange(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	var blocks []*types.Block
+	write := func(number uint64, extra string, canonical bool) {
+		block := types.NewBlockWithHeader(&types.Header{Number: new(big.Int).SetUint64(number), Extra: []byte(extra)})
+		WriteBlock(db, block)
+		WriteTd(db, block.Hash(), number, big.NewInt(int64(number)))
+		WriteReceipts(db, block.Hash(), number, makeTestReceipts(1, 1)[0])
+		if canonical {
+			WriteCanonicalHash(db, block.Hash(), number)
+		}
+		blocks = append(blocks, block)
+	}
+	for i := uint64(0); i < 10; i++ {
+		write(i, "canonical", true)
+	}
+	write(4, "side", false)
+
+	if _, err := PruneBlockRange(db, 5, 3); err == nil {
+		t.Fatalf("inverted range accepted")
+	}
+	pruned, err := PruneBlockRange(db, 3, 5)
+	if err != nil {
+		t.Fatalf("failed to prune block range: %v", err)
+	}
+	if pruned != 4 {
+		t.Fatalf("pruned blocks mismatch: have %d, want 4", pruned)
+	}
+	for _, block := range blocks {
+		var (
+			hash, number = block.Hash(), block.NumberU64()
+			want         = number < 3 || number > 5
+		)
+		if have := HasHeader(db, hash, number); have != want {
+			t.Errorf("block %d (%x): header present %v, want %v", number, hash, have, want)
+		}
+		if have := ReadHeaderNumber(db, hash) != nil; have != want {
+			t.Errorf("block %d (%x): number mapping present %v, want %v", number, hash, have, want)
+		}
+		if have := HasBody(db, hash, number); have != want {
+			t.Errorf("block %d (%x): body present %v, want %v", number, hash, have, want)
+		}
+		if have := HasReceipts(db, hash, number); have != want {
+			t.Errorf("block %d (%x): receipts present %v, want %v", number, hash, have, want)
+		}
+		if have := ReadTd(db, hash, number) != nil; have != want {
+			t.Errorf("block %d (%x): td present %v, want %v", number, hash, have, want)
+		}
+		if have := ReadCanonicalHash(db, number) != (common.Hash{}); have != want {
+			t.Errorf("block %d: canonical hash present %v, want %v", number, have, want)
+		}
+	}
+}
+
+func TestDeleteBodiesAndReceiptsRange(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	var (
+		blocks []*types.Block
+		parent common.Hash
+	)
+	for i := uint64(0); i < 10; i++ {
+		block := types.NewBlockWithHeader(&types.Header{Number: new(big.Int).SetUint64(i), ParentHash: parent})
+		parent = block.Hash()
+		WriteBlock(db, block)
+		WriteReceipts(db, block.Hash(), i, makeTestReceipts(1, 1)[0])
+		WriteCanonicalHash(db, block.Hash(), i)
+		blocks = append(blocks, block)
+	}
+	DeleteBodiesAndReceiptsRange(db, 5, 3, func(number uint64) common.Hash { return ReadCanonicalHash(db, number) })
+	DeleteBodiesAndReceiptsRange(db, 3, 5, func(number uint64) common.Hash { return ReadCanonicalHash(db, number) })
+
+	for _, block := range blocks {
+		var (
+			hash, number = block.Hash(), block.NumberU64()
+			want         = number < 3 || number > 5
+		)
+		if !HasHeader(db, hash, number) || ReadCanonicalHash(db, number) != hash {
+			t.Errorf("block %d: header or canonical hash deleted", number)
+		}
+		if have := HasBody(db, hash, number); have != want {
+			t.Errorf("block %d: body present %v, want %v", number, have, want)
+		}
+		if have := HasReceipts(db, hash, number); have != want {
+			t.Errorf("block %d: receipts present %v, want %v", number, have, want)
+		}
+	}
+	if ancestor := FindCommonAncestor(db, blocks[9].Header(), blocks[4].Header()); ancestor == nil || ancestor.Hash() != blocks[4].Hash() {
+		t.Errorf("common ancestor mismatch: have %v, want %x", ancestor, blocks[4].Hash())
+	}
+}
+
+func TestDeleteBlocksFrom(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	var blocks []*types.Block
+	for i := uint64(0); i < 10; i++ {
+		block := types.NewBlockWithHeader(&types.Header{Number: new(big.Int).SetUint64(i), Extra: []byte("canonical")})
+		WriteBlock(db, block)
+		WriteTd(db, block.Hash(), i, big.NewInt(int64(i)))
+		WriteReceipts(db, block.Hash(), i, makeTestReceipts(1, 1)[0])
+		WriteCanonicalHash(db, block.Hash(), i)
+		blocks = append(blocks, block)
+	}
+	side := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(7), Extra: []byte("side")})
+	WriteBlock(db, side)
+	WriteTd(db, side.Hash(), 7, big.NewInt(7))
+	blocks = append(blocks, side)
+
+	deleted, err := DeleteBlocksFrom(db, 6)
+	if err != nil {
+		t.Fatalf("failed to delete blocks: %v", err)
+	}
+	if deleted != 5 {
+		t.Fatalf("deleted blocks mismatch: have %d, want 5", deleted)
+	}
+	for _, block := range blocks {
+		var (
+			hash, number = block.Hash(), block.NumberU64()
+			want         = number < 6
+		)
+		if have := HasHeader(db, hash, number); have != want {
+			t.Errorf("block %d (%x): header present %v, want %v", number, hash, have, want)
+		}
+		if have := ReadHeaderNumber(db, hash) != nil; have != want {
+			t.Errorf("block %d (%x): number mapping present %v, want %v", number, hash, have, want)
+		}
+		if have := HasBody(db, hash, number); have != want {
+			t.Errorf("block %d (%x): body present %v, want %v", number, hash, have, want)
+		}
+		if have := ReadTd(db, hash, number) != nil; have != want {
+			t.Errorf("block %d (%x): td present %v, want %v", number, hash, have, want)
+		}
+		if have := ReadCanonicalHash(db, number) != (common.Hash{}); have != want {
+			t.Errorf("block %d: canonical hash present %v, want %v", number, have, want)
+		}
+	}
+	for i := 0; i < 6; i++ {
+		if !HasReceipts(db, blocks[i].Hash(), uint64(i)) {
+			t.Errorf("block %d: receipts deleted", i)
+		}
+	}
+}
+
+// Tests that blocks in the ancient store are not deleted, but the truncation
+// needed to delete them is reported.
+func TestDeleteBlocksFromAncients(t *testing.T) {
+	frdir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temp freezer dir: %v", err)
+	}
+	defer os.RemoveAll(frdir)
+
+	db, err := NewDatabaseWithFreezer(NewMemoryDatabase(), frdir, "", false)
+	if err != nil {
+		t.Fatalf("failed to create database with ancient backend: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := WriteAncientBlocks(db, makeTestBlocks(4, 1), makeTestReceipts(4, 1), big.NewInt(1)); err != nil {
+		t.Fatalf("failed to write ancient blocks: %v", err)
+	}
+	_, err = DeleteBlocksFrom(db, 2)
+	frozenErr, ok := err.(*FrozenBlocksError)
+	if !ok {
+		t.Fatalf("error mismatch: have %v, want *FrozenBlocksError", err)
+	}
+	if frozenErr.From != 2 || frozenErr.Frozen != 4 {
+		t.Fatalf("frozen error mismatch: have %+v, want from 2, frozen 4", frozenErr)
+	}
+	if _, err := DeleteBlocksFrom(db, 4); err != nil {
+		t.Fatalf("failed to delete blocks above the ancients: %v", err)
+	}
+}
+
+// Tests that headers of several foreign chains can be stored next to the native
+// chain without their keys colliding.
+func TestChainsHeaderStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	var (
+		foreign = []chains.ChainType{chains.ChainTypeETH, chains.ChainTypeETHTest}
+		native  []*types.Header
+	)
+	// Interleave native and foreign headers sharing the same numbers and hashes.
+	for i := uint64(0); i < 5; i++ {
+		header := &types.Header{Number: new(big.Int).SetUint64(i), Extra: []byte("native")}
+		WriteHeader(db, header)
+		WriteTd(db, header.Hash(), i, big.NewInt(1))
+		WriteCanonicalHash(db, header.Hash(), i)
+		native = append(native, header)
+
+		for _, m := range foreign {
+			WriteHeaderRLPChains(db, header.Hash(), i, []byte{byte(m), byte(i)}, m)
+			WriteTdChains(db, header.Hash(), i, big.NewInt(int64(m)*100+int64(i)), m)
+			WriteCanonicalHashChains(db, header.Hash(), i, m)
+		}
+	}
+	WriteHeadHeaderHash(db, native[4].Hash())
+	for _, m := range foreign {
+		WriteHeadHeaderHashChains(db, common.Hash{byte(m)}, m)
+		WriteHeadBlockHashChains(db, common.Hash{byte(m), 1}, m)
+		WriteLastBlockHashChains(db, common.Hash{byte(m), 2}, m)
+	}
+	// Every foreign entry lives under the chains prefix.
+	it := db.NewIterator(nil, nil)
+	foreignKeys := 0
+	for it.Next() {
+		if bytes.HasPrefix(it.Key(), chainsPrefix) {
+			foreignKeys++
+		}
+	}
+	it.Release()
+	if want := len(foreign) * (5*4 + 3); foreignKeys != want {
+		t.Fatalf("foreign key count mismatch: have %d, want %d", foreignKeys, want)
+	}
+	for i, header := range native {
+		number := uint64(i)
+		if have := ReadAllHashes(db, number); !reflect.DeepEqual(have, []common.Hash{header.Hash()}) {
+			t.Errorf("native hashes of %d mismatch: have %x", number, have)
+		}
+		if have := ReadHeader(db, header.Hash(), number); have == nil || have.Hash() != header.Hash() {
+			t.Errorf("native header %d mismatch: have %v", number, have)
+		}
+		if have := ReadTd(db, header.Hash(), number); have == nil || have.Int64() != 1 {
+			t.Errorf("native td %d mismatch: have %v", number, have)
+		}
+		for _, m := range foreign {
+			if have, want := ReadHeaderRLPChains(db, header.Hash(), number, m), []byte{byte(m), byte(i)}; !bytes.Equal(have, want) {
+				t.Errorf("chain %d header %d mismatch: have %x, want %x", m, number, have, want)
+			}
+			if have := ReadHeaderNumberChains(db, header.Hash(), m); have == nil || *have != number {
+				t.Errorf("chain %d header number mismatch: have %v, want %d", m, have, number)
+			}
+			if have := ReadTdChains(db, header.Hash(), number, m); have == nil || have.Int64() != int64(m)*100+int64(i) {
+				t.Errorf("chain %d td %d mismatch: have %v", m, number, have)
+			}
+			if have := ReadCanonicalHashChains(db, number, m); have != header.Hash() {
+				t.Errorf("chain %d canonical hash %d mismatch: have %x, want %x", m, number, have, header.Hash())
+			}
+		}
+	}
+	if have := ReadHeadHeaderHash(db); have != native[4].Hash() {
+		t.Errorf("native head header mismatch: have %x, want %x", have, native[4].Hash())
+	}
+	for _, m := range foreign {
+		if have, want := ReadHeadHeaderHashChains(db, m), (common.Hash{byte(m)}); have != want {
+			t.Errorf("chain %d head header mismatch: have %x, want %x", m, have, want)
+		}
+		if have, want := ReadHeadBlockHashChains(db, m), (common.Hash{byte(m), 1}); have != want {
+			t.Errorf("chain %d head block mismatch: have %x, want %x", m, have, want)
+		}
+		if have, want := ReadLastBlockHashChains(db, m), (common.Hash{byte(m), 2}); have != want {
+			t.Errorf("chain %d last block mismatch: have %x, want %x", m, have, want)
+		}
+	}
+	// Deleting the data of one chain leaves the others untouched.
+	hash := native[2].Hash()
+	DeleteHeaderChains(db, hash, 2, chains.ChainTypeETH)
+	DeleteTdChains(db, hash, 2, chains.ChainTypeETH)
+	DeleteCanonicalHashChains(db, 2, chains.ChainTypeETH)
+	if HasHeaderChains(db, hash, 2, chains.ChainTypeETH) || ReadHeaderNumberChains(db, hash, chains.ChainTypeETH) != nil {
+		t.Errorf("deleted header returned")
+	}
+	if ReadTdChains(db, hash, 2, chains.ChainTypeETH) != nil {
+		t.Errorf("deleted td returned")
+	}
+	if have := ReadCanonicalHashChains(db, 2, chains.ChainTypeETH); have != (common.Hash{}) {
+		t.Errorf("deleted canonical hash returned %x", have)
+	}
+	if !HasHeaderChains(db, hash, 2, chains.ChainTypeETHTest) || ReadCanonicalHashChains(db, 2, chains.ChainTypeETHTest) != hash {
+		t.Errorf("header of other chain deleted")
+	}
+	if !HasHeader(db, hash, 2) || ReadHeaderNumber(db, hash) == nil || ReadCanonicalHash(db, 2) != hash {
+		t.Errorf("native header deleted")
+	}
+}
+
 func TestCanonicalHashIteration(t *testing.T) {
 	var cases = []struct {
 		from, to uint64
@@ -566,6 +1624,77 @@ func TestHashesInRange(t *testing.T) {
 	}
 }
 
+func TestHeadersInRange(t *testing.T) {
+	db := NewMemoryDatabase()
+	for i := 0; i < 10; i++ {
+		// Leave a gap in the canonical chain at number 5
+		if i == 5 {
+			continue
+		}
+		header := &types.Header{Number: big.NewInt(int64(i)), Extra: []byte("test header")}
+		WriteHeader(db, header)
+		WriteCanonicalHash(db, header.Hash(), uint64(i))
+	}
+	// Write a non-canonical sibling that must not show up in the result
+	WriteHeader(db, &types.Header{Number: big.NewInt(3), Extra: []byte("side chain")})
+
+	var cases = []struct {
+		first, last uint64
+		expect      []uint64
+	}{
+		{0, 9, []uint64{0, 1, 2, 3, 4, 6, 7, 8, 9}},
+		{3, 6, []uint64{3, 4, 6}},
+		{5, 5, nil},
+		{8, 20, []uint64{8, 9}},
+		{6, 2, nil},
+	}
+	for i, c := range cases {
+		var numbers []uint64
+		for _, header := range ReadHeadersInRange(db, c.first, c.last) {
+			if header.Hash() != ReadCanonicalHash(db, header.Number.Uint64()) {
+				t.Fatalf("Case %d returned non-canonical header %d", i, header.Number)
+			}
+			numbers = append(numbers, header.Number.Uint64())
+		}
+		if !reflect.DeepEqual(numbers, c.expect) {
+			t.Fatalf("Case %d failed, want %v, got %v", i, c.expect, numbers)
+		}
+	}
+}
+
+func TestVerifyHeaderNumberConsistency(t *testing.T) {
+	db := NewMemoryDatabase()
+	var headers []*types.Header
+	for i := 0; i < 8; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i)), Extra: []byte("test header")}
+		WriteHeader(db, header)
+		WriteCanonicalHash(db, header.Hash(), uint64(i))
+		headers = append(headers, header)
+	}
+	if mismatches, err := VerifyHeaderNumberConsistency(db, 0, 7); err != nil {
+		t.Fatalf("Failed to verify consistent database: %v", err)
+	} else if len(mismatches) != 0 {
+		t.Fatalf("Consistent database reported mismatches: %v", mismatches)
+	}
+	// Corrupt the reverse mapping of one header and drop another one
+	WriteHeaderNumber(db, headers[3].Hash(), 4)
+	DeleteHeaderNumber(db, headers[6].Hash())
+
+	mismatches, err := VerifyHeaderNumberConsistency(db, 0, 7)
+	if err != nil {
+		t.Fatalf("Failed to verify corrupted database: %v", err)
+	}
+	if want := []common.Hash{headers[3].Hash(), headers[6].Hash()}; !reflect.DeepEqual(mismatches, want) {
+		t.Fatalf("Mismatch list wrong: have %v, want %v", mismatches, want)
+	}
+	if mismatches, _ := VerifyHeaderNumberConsistency(db, 4, 5); len(mismatches) != 0 {
+		t.Fatalf("Range outside corruption reported mismatches: %v", mismatches)
+	}
+	if _, err := VerifyHeaderNumberConsistency(db, 5, 4); err == nil {
+		t.Fatalf("Inverted range accepted")
+	}
+}
+
 // This measures the write speed of the WriteAncientBlocks operation.
 func BenchmarkWriteAncientBlocks(b *testing.B) {
 	// Open freezer database.
@@ -646,6 +1775,18 @@ func makeTestBlocks(nblock int, txsPerBlock int) []*types.Block {
 	return blocks
 }
 
+// makeBodyFieldTestBlocks creates test blocks with distinct randomness and
+// epoch SNARK data.
+func makeBodyFieldTestBlocks(n int) []*types.Block {
+	blocks := makeTestBlocks(n, 1)
+	for i, block := range blocks {
+		randomness := &types.Randomness{Revealed: common.Hash{byte(i)}, Committed: common.Hash{byte(i), 1}}
+		snarkData := &types.EpochSnarkData{Bitmap: big.NewInt(int64(i)), Signature: []byte{byte(i), 2}}
+		blocks[i] = block.WithBody(block.Transactions(), randomness, snarkData)
+	}
+	return blocks
+}
+
 // makeTestReceipts creates fake receipts for the ancient write benchmark.
 func makeTestReceipts(n int, nPerBlock int) []types.Receipts {
 	receipts := make([]*types.Receipt, nPerBlock)
@@ -736,9 +1877,9 @@ func TestReadLogs(t *testing.T) {
 	// Insert the receipt slice into the database and check presence
 	WriteReceipts(db, hash, 0, receipts)
 
-	logs := ReadLogs(db, hash, 0)
-	if len(logs) == 0 {
-		t.Fatalf("no logs returned")
+	logs, err := ReadLogs(db, hash, 0)
+	if err != nil {
+		t.Fatalf("failed to read logs: %v", err)
 	}
 	if have, want := len(logs), 2; have != want {
 		t.Fatalf("unexpected number of logs returned, have %d want %d", have, want)
@@ -771,6 +1912,91 @@ func TestReadLogs(t *testing.T) {
 	}
 }
 
+// Tests that the logs of a single transaction can be retrieved, including the
+// ones of the block finalization receipt.
+func TestReadLogsForTx(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	tx1 := types.NewTransaction(1, common.HexToAddress("0x1"), big.NewInt(1), 1, big.NewInt(1), nil)
+	tx2 := types.NewTransaction(2, common.HexToAddress("0x2"), big.NewInt(2), 2, big.NewInt(2), nil)
+	body := &types.Body{Transactions: types.Transactions{tx1, tx2}}
+
+	receipts := []*types.Receipt{
+		{
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: 1,
+			Logs: []*types.Log{
+				{Address: common.BytesToAddress([]byte{0x11})},
+				{Address: common.BytesToAddress([]byte{0x01, 0x11})},
+			},
+		},
+		{
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: 2,
+			Logs: []*types.Log{
+				{Address: common.BytesToAddress([]byte{0x22})},
+			},
+		},
+		{
+			// Block finalization receipt, without a transaction
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: 2,
+			Logs: []*types.Log{
+				{Address: common.BytesToAddress([]byte{0x33})},
+				{Address: common.BytesToAddress([]byte{0x03, 0x33})},
+			},
+		},
+	}
+	hash := common.BytesToHash([]byte{0x03, 0x14})
+
+	if _, err := ReadLogsForTx(db, hash, 1, 0); err != ErrNoReceipts {
+		t.Fatalf("logs without receipts: have %v, want %v", err, ErrNoReceipts)
+	}
+	if _, err := ReadLogs(db, hash, 1); err != ErrNoReceipts {
+		t.Fatalf("block logs without receipts: have %v, want %v", err, ErrNoReceipts)
+	}
+	WriteReceipts(db, hash, 1, receipts)
+	if _, err := ReadLogsForTx(db, hash, 1, 0); err != ErrMissingBody {
+		t.Fatalf("logs without body: have %v, want %v", err, ErrMissingBody)
+	}
+	if _, err := ReadLogs(db, hash, 1); err != ErrMissingBody {
+		t.Fatalf("block logs without body: have %v, want %v", err, ErrMissingBody)
+	}
+	WriteBody(db, hash, 1, body)
+
+	all, err := ReadLogs(db, hash, 1)
+	if err != nil {
+		t.Fatalf("failed to read block logs: %v", err)
+	}
+	for i := range receipts {
+		logs, err := ReadLogsForTx(db, hash, 1, uint(i))
+		if err != nil {
+			t.Fatalf("receipt #%d: failed to read logs: %v", i, err)
+		}
+		if len(logs) != len(all[i]) {
+			t.Fatalf("receipt #%d: log count mismatch: have %d, want %d", i, len(logs), len(all[i]))
+		}
+		for j, l := range logs {
+			rlpHave, _ := rlp.EncodeToBytes(newFullLogRLP(l))
+			rlpWant, _ := rlp.EncodeToBytes(newFullLogRLP(all[i][j]))
+			if !bytes.Equal(rlpHave, rlpWant) {
+				t.Fatalf("receipt #%d log #%d: mismatch: have %x, want %x", i, j, rlpHave, rlpWant)
+			}
+		}
+	}
+	if logs, _ := ReadLogsForTx(db, hash, 1, 2); logs[1].TxHash != hash || logs[1].Index != 4 {
+		t.Fatalf("finalization log mismatch: have tx %x index %d, want %x 4", logs[1].TxHash, logs[1].Index, hash)
+	}
+	if _, err := ReadLogsForTx(db, hash, 1, 3); err == nil {
+		t.Fatalf("out of range transaction index accepted")
+	}
+	// Without a finalization receipt, the index past the transactions is invalid
+	WriteReceipts(db, hash, 1, receipts[:2])
+	if _, err := ReadLogsForTx(db, hash, 1, 2); err == nil {
+		t.Fatalf("missing finalization receipt accepted")
+	}
+}
+
 func TestDeriveLogFields(t *testing.T) {
 	// Create a few transactions to have receipts for
 	to2 := common.HexToAddress("0x2")
@@ -875,3 +2101,50 @@ func BenchmarkDecodeRLPLogs(b *testing.B) {
 		}
 	})
 }
+
+// Tests that the block finalization receipt of epoch blocks, with and without
+// transactions, is read back with the block hash as transaction hash.
+func TestReadFinalizationReceipt(t *testing.T) {
+	tx := types.NewTransaction(1, common.HexToAddress("0x1"), big.NewInt(1), 1, big.NewInt(1), nil)
+	for _, txs := range []types.Transactions{nil, {tx}} {
+		db := NewMemoryDatabase()
+		hash, number := common.BytesToHash([]byte{0x03, 0x14}), uint64(100)
+
+		var receipts types.Receipts
+		for range txs {
+			receipts = append(receipts, &types.Receipt{
+				Status:            types.ReceiptStatusSuccessful,
+				CumulativeGasUsed: 1,
+				Logs:              []*types.Log{{Address: common.BytesToAddress([]byte{0x11})}},
+			})
+		}
+		receipts = append(receipts, &types.Receipt{
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: 1,
+			Logs: []*types.Log{
+				{Address: common.BytesToAddress([]byte{0x22})},
+				{Address: common.BytesToAddress([]byte{0x02, 0x22})},
+			},
+		})
+		WriteBody(db, hash, number, &types.Body{Transactions: txs})
+		WriteReceipts(db, hash, number, receipts)
+
+		read := ReadReceipts(db, hash, number, params.TestChainConfig)
+		if len(read) != len(txs)+1 {
+			t.Fatalf("%d txs: receipt count mismatch: have %d, want %d", len(txs), len(read), len(txs)+1)
+		}
+		final := read[len(txs)]
+		if final.TxHash != hash || final.BlockHash != hash || final.BlockNumber.Uint64() != number || final.TransactionIndex != uint(len(txs)) {
+			t.Errorf("%d txs: finalization receipt fields mismatch: %+v", len(txs), final)
+		}
+		logs, err := ReadLogs(db, hash, number)
+		if err != nil {
+			t.Fatalf("%d txs: failed to read logs: %v", len(txs), err)
+		}
+		for k, l := range logs[len(txs)] {
+			if l.TxHash != hash || l.TxIndex != uint(len(txs)) || l.Index != uint(len(txs)+k) {
+				t.Errorf("%d txs: finalization log %d fields mismatch: %+v", len(txs), k, l)
+			}
+		}
+	}
+}
@@ -0,0 +1,274 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/mapprotocol/atlas/core/types"
+)
+
+// headerExportMagic prefixes every header export, followed by the RLP encoded
+// headerExportVersion and the RLP stream of headers.
+var headerExportMagic = []byte("atlashdr")
+
+// headerExportVersion is the version of the header export format. It has to be
+// bumped whenever the encoding of the stream changes.
+const headerExportVersion uint64 = 1
+
+// blockExportMagic prefixes every block export, followed by the RLP encoded
+// blockExportVersion and the RLP stream of exportedBlock entries.
+var blockExportMagic = []byte("atlasblk")
+
+// blockExportVersion is the version of the block export format. It has to be
+// bumped whenever the encoding of the stream changes.
+const blockExportVersion uint64 = 1
+
+var (
+	errInvalidHeaderExport = errors.New("invalid header export: bad magic")
+	errHeaderExportVersion = errors.New("unsupported header export version")
+	errInvalidBlockExport  = errors.New("invalid block export: bad magic")
+	errBlockExportVersion  = errors.New("unsupported block export version")
+)
+
+// DefaultImportBatchSize is the number of blocks ImportBlocks writes per batch
+// unless the caller gives another one.
+const DefaultImportBatchSize = 1024
+
+// exportedBlock is a single entry of a block export. The receipts are kept in
+// their storage encoding.
+type exportedBlock struct {
+	Block    *types.Block
+	Receipts rlp.RawValue
+	Td       *big.Int
+}
+
+// ExportHeaders writes the canonical headers between from and to (both
+// inclusive) to w as an RLP stream prefixed with the export magic and version.
+func ExportHeaders(db ethdb.Reader, from, to uint64, w io.Writer) error {
+	if from > to {
+		return fmt.Errorf("invalid range: from %d > to %d", from, to)
+	}
+	if _, err := w.Write(headerExportMagic); err != nil {
+		return err
+	}
+	if err := rlp.Encode(w, headerExportVersion); err != nil {
+		return err
+	}
+	for number := from; ; number++ {
+		hash := ReadCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			return fmt.Errorf("missing canonical hash #%d", number)
+		}
+		data := ReadHeaderRLP(db, hash, number)
+		if len(data) == 0 {
+			return fmt.Errorf("missing header #%d [%x]", number, hash)
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+		if number == to {
+			return nil
+		}
+	}
+}
+
+// ImportHeaders reads a header export written by ExportHeaders from r and
+// stores the headers along with their hash->number and canonical number->hash
+// mappings. The headers must form a contiguous chain. It returns the number of
+// imported headers.
+func ImportHeaders(db ethdb.KeyValueWriter, r io.Reader) (uint64, error) {
+	magic := make([]byte, len(headerExportMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || !bytes.Equal(magic, headerExportMagic) {
+		return 0, errInvalidHeaderExport
+	}
+	stream := rlp.NewStream(r, 0)
+	version, err := stream.Uint()
+	if err != nil {
+		return 0, fmt.Errorf("invalid header export version: %v", err)
+	}
+	if version != headerExportVersion {
+		return 0, fmt.Errorf("%w: have %d, want %d", errHeaderExportVersion, version, headerExportVersion)
+	}
+	var (
+		count  uint64
+		parent *types.Header
+	)
+	for {
+		header := new(types.Header)
+		if err := stream.Decode(header); err == io.EOF {
+			return count, nil
+		} else if err != nil {
+			return count, fmt.Errorf("header %d: %v", count, err)
+		}
+		if parent != nil && (header.Number.Uint64() != parent.Number.Uint64()+1 || header.ParentHash != parent.Hash()) {
+			return count, fmt.Errorf("non-contiguous header #%d [%x] after #%d [%x]", header.Number, header.Hash(), parent.Number, parent.Hash())
+		}
+		WriteHeader(db, header)
+		WriteCanonicalHash(db, header.Hash(), header.Number.Uint64())
+		parent = header
+		count++
+	}
+}
+
+// ExportBlocks writes the canonical blocks between first and last (both
+// inclusive) along with their receipts and total difficulty to w as an RLP
+// stream prefixed with the export magic and version.
+func ExportBlocks(db ethdb.Reader, w io.Writer, first, last uint64) error {
+	if first > last {
+		return fmt.Errorf("invalid range: first %d > last %d", first, last)
+	}
+	if _, err := w.Write(blockExportMagic); err != nil {
+		return err
+	}
+	if err := rlp.Encode(w, blockExportVersion); err != nil {
+		return err
+	}
+	for number := first; ; number++ {
+		hash := ReadCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			return fmt.Errorf("missing canonical hash #%d", number)
+		}
+		block := ReadBlock(db, hash, number)
+		if block == nil {
+			return fmt.Errorf("missing block #%d [%x]", number, hash)
+		}
+		td := ReadTd(db, hash, number)
+		if td == nil {
+			return fmt.Errorf("missing total difficulty #%d [%x]", number, hash)
+		}
+		receipts := ReadReceiptsRLP(db, hash, number)
+		if len(receipts) == 0 {
+			return fmt.Errorf("missing receipts #%d [%x]", number, hash)
+		}
+		if err := rlp.Encode(w, &exportedBlock{Block: block, Receipts: receipts, Td: td}); err != nil {
+			return err
+		}
+		if number == last {
+			return nil
+		}
+	}
+}
+
+// ImportBlocks reads a block export written by ExportBlocks from r and stores
+// the blocks, receipts and total difficulties along with their canonical
+// mappings. The first block must extend a header already in the database and
+// the rest must form a contiguous chain. Blocks are written in batches of
+// batchSize, or DefaultImportBatchSize if it isn't positive; on invalid input
+// the import aborts with the offending block number, keeping the blocks
+// imported before it. It returns the number of imported blocks.
+func ImportBlocks(db ethdb.KeyValueStore, r io.Reader, batchSize int) (uint64, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultImportBatchSize
+	}
+	magic := make([]byte, len(blockExportMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || !bytes.Equal(magic, blockExportMagic) {
+		return 0, errInvalidBlockExport
+	}
+	stream := rlp.NewStream(r, 0)
+	version, err := stream.Uint()
+	if err != nil {
+		return 0, fmt.Errorf("invalid block export version: %v", err)
+	}
+	if version != blockExportVersion {
+		return 0, fmt.Errorf("%w: have %d, want %d", errBlockExportVersion, version, blockExportVersion)
+	}
+	var (
+		batch   = db.NewBatch()
+		pending uint64
+		count   uint64
+		parent  *types.Header
+	)
+	flush := func() error {
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		batch.Reset()
+		count += pending
+		pending = 0
+		return nil
+	}
+	abort := func(err error) (uint64, error) {
+		if ferr := flush(); ferr != nil {
+			return count, ferr
+		}
+		return count, err
+	}
+	for {
+		var entry exportedBlock
+		if err := stream.Decode(&entry); err == io.EOF {
+			return count, flush()
+		} else if err != nil {
+			if parent == nil {
+				return abort(fmt.Errorf("block %d: %v", count+pending, err))
+			}
+			return abort(fmt.Errorf("block #%d: %v", parent.Number.Uint64()+1, err))
+		}
+		block := entry.Block
+		number := block.NumberU64()
+
+		// Validate the linkage to the previous block or, for the first one, to the
+		// local chain
+		if parent == nil {
+			if number > 0 && !HasHeader(db, block.ParentHash(), number-1) {
+				return abort(fmt.Errorf("block #%d [%x]: unknown parent %x", number, block.Hash(), block.ParentHash()))
+			}
+		} else if number != parent.Number.Uint64()+1 || block.ParentHash() != parent.Hash() {
+			return abort(fmt.Errorf("block #%d [%x]: non-contiguous after #%d [%x]", number, block.Hash(), parent.Number, parent.Hash()))
+		}
+		// Validate the block contents against its header
+		if hash := types.DeriveSha(block.Transactions(), trie.NewStackTrie(nil)); hash != block.TxHash() {
+			return abort(fmt.Errorf("block #%d [%x]: transaction root mismatch: have %x, want %x", number, block.Hash(), hash, block.TxHash()))
+		}
+		var storageReceipts []*types.ReceiptForStorage
+		if err := rlp.DecodeBytes(entry.Receipts, &storageReceipts); err != nil {
+			return abort(fmt.Errorf("block #%d [%x]: invalid receipts: %v", number, block.Hash(), err))
+		}
+		receipts := make(types.Receipts, len(storageReceipts))
+		for i, receipt := range storageReceipts {
+			receipts[i] = (*types.Receipt)(receipt)
+		}
+		if txs := len(block.Transactions()); !(txs == len(receipts) || txs+1 == len(receipts)) {
+			return abort(fmt.Errorf("block #%d [%x]: receipt count mismatch: have %d, want %d transactions", number, block.Hash(), len(receipts), txs))
+		}
+		if bloom := types.CreateBloom(receipts); bloom != block.Bloom() {
+			return abort(fmt.Errorf("block #%d [%x]: logs bloom mismatch: have %x, want %x", number, block.Hash(), bloom, block.Bloom()))
+		}
+		if entry.Td == nil || entry.Td.Cmp(block.Difficulty()) < 0 {
+			return abort(fmt.Errorf("block #%d [%x]: invalid total difficulty %v", number, block.Hash(), entry.Td))
+		}
+		WriteBlock(batch, block)
+		WriteReceipts(batch, block.Hash(), number, receipts)
+		WriteTd(batch, block.Hash(), number, entry.Td)
+		WriteCanonicalHash(batch, block.Hash(), number)
+		parent = block.Header()
+
+		if pending++; pending >= uint64(batchSize) {
+			if err := flush(); err != nil {
+				return count, err
+			}
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/mapprotocol/atlas/core/types"
+)
+
+// makeTestHeaderChain creates a contiguous chain of n headers starting at genesis.
+func makeTestHeaderChain(n int) []*types.Header {
+	headers := []*types.Header{{Number: big.NewInt(0), Extra: []byte("genesis")}}
+	for i := 1; i < n; i++ {
+		headers = append(headers, &types.Header{Number: big.NewInt(int64(i)), ParentHash: headers[i-1].Hash(), Extra: []byte("test header")})
+	}
+	return headers
+}
+
+func TestExportImportHeaders(t *testing.T) {
+	db := NewMemoryDatabase()
+	headers := makeTestHeaderChain(10)
+	for _, header := range headers {
+		WriteHeader(db, header)
+		WriteCanonicalHash(db, header.Hash(), header.Number.Uint64())
+	}
+
+	var buf bytes.Buffer
+	if err := ExportHeaders(db, 2, 7, &buf); err != nil {
+		t.Fatalf("Failed to export headers: %v", err)
+	}
+	export := buf.Bytes()
+
+	imported := NewMemoryDatabase()
+	count, err := ImportHeaders(imported, bytes.NewReader(export))
+	if err != nil {
+		t.Fatalf("Failed to import headers: %v", err)
+	}
+	if count != 6 {
+		t.Fatalf("Imported header count mismatch: have %d, want %d", count, 6)
+	}
+	for _, header := range headers[2:8] {
+		number := header.Number.Uint64()
+		if hash := ReadCanonicalHash(imported, number); hash != header.Hash() {
+			t.Errorf("Canonical hash #%d mismatch: have %x, want %x", number, hash, header.Hash())
+		}
+		if n := ReadHeaderNumber(imported, header.Hash()); n == nil || *n != number {
+			t.Errorf("Header number of #%d mismatch: have %v", number, n)
+		}
+		if h := ReadHeader(imported, header.Hash(), number); h == nil || h.Hash() != header.Hash() {
+			t.Errorf("Header #%d not imported", number)
+		}
+	}
+	if hash := ReadCanonicalHash(imported, 8); hash != (common.Hash{}) {
+		t.Errorf("Header outside the exported range imported")
+	}
+
+	// Corrupted magic and unknown versions must be rejected
+	corrupted := append([]byte{}, export...)
+	corrupted[0] ^= 0xff
+	if _, err := ImportHeaders(NewMemoryDatabase(), bytes.NewReader(corrupted)); err != errInvalidHeaderExport {
+		t.Errorf("Corrupted magic: have %v, want %v", err, errInvalidHeaderExport)
+	}
+	versioned := append([]byte{}, export...)
+	versioned[len(headerExportMagic)] = 0x02
+	if _, err := ImportHeaders(NewMemoryDatabase(), bytes.NewReader(versioned)); !errors.Is(err, errHeaderExportVersion) {
+		t.Errorf("Unknown version: have %v, want %v", err, errHeaderExportVersion)
+	}
+	// Missing headers must fail the export
+	if err := ExportHeaders(db, 8, 12, &bytes.Buffer{}); err == nil {
+		t.Errorf("Export of missing headers succeeded")
+	}
+}
+
+// makeTestBlockChain writes a contiguous canonical chain of n blocks, along with
+// their receipts and total difficulties, into db.
+func makeTestBlockChain(db ethdb.KeyValueWriter, n int) []*types.Block {
+	var blocks []*types.Block
+	for i := 0; i < n; i++ {
+		header := &types.Header{
+			Number:      big.NewInt(int64(i)),
+			Difficulty:  big.NewInt(1),
+			Extra:       []byte("test block"),
+			TxHash:      types.EmptyRootHash,
+			ReceiptHash: types.EmptyRootHash,
+		}
+		if i > 0 {
+			header.ParentHash = blocks[i-1].Hash()
+		}
+		block := types.NewBlockWithHeader(header).WithBody(nil, &types.Randomness{Revealed: common.Hash{byte(i)}}, &types.EpochSnarkData{Bitmap: big.NewInt(int64(i)), Signature: []byte{byte(i)}})
+		WriteBlock(db, block)
+		WriteReceipts(db, block.Hash(), block.NumberU64(), nil)
+		WriteTd(db, block.Hash(), block.NumberU64(), big.NewInt(int64(i+1)))
+		WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+func TestExportImportBlocks(t *testing.T) {
+	db := NewMemoryDatabase()
+	blocks := makeTestBlockChain(db, 10)
+
+	var buf bytes.Buffer
+	if err := ExportBlocks(db, &buf, 1, 7); err != nil {
+		t.Fatalf("Failed to export blocks: %v", err)
+	}
+	imported := NewMemoryDatabase()
+	WriteBlock(imported, blocks[0])
+
+	count, err := ImportBlocks(imported, bytes.NewReader(buf.Bytes()), 2)
+	if err != nil {
+		t.Fatalf("Failed to import blocks: %v", err)
+	}
+	if count != 7 {
+		t.Fatalf("Imported block count mismatch: have %d, want %d", count, 7)
+	}
+	for _, block := range blocks[1:8] {
+		number := block.NumberU64()
+		if hash := ReadCanonicalHash(imported, number); hash != block.Hash() {
+			t.Errorf("Canonical hash #%d mismatch: have %x, want %x", number, hash, block.Hash())
+		}
+		entry := ReadBlock(imported, block.Hash(), number)
+		if entry == nil {
+			t.Fatalf("Block #%d not imported", number)
+		}
+		if entry.Randomness().Revealed != block.Randomness().Revealed || !bytes.Equal(entry.EpochSnarkData().Signature, block.EpochSnarkData().Signature) {
+			t.Errorf("Block #%d body mismatch", number)
+		}
+		if td := ReadTd(imported, block.Hash(), number); td == nil || td.Uint64() != number+1 {
+			t.Errorf("Total difficulty #%d mismatch: have %v, want %d", number, td, number+1)
+		}
+		if !HasReceipts(imported, block.Hash(), number) {
+			t.Errorf("Receipts #%d not imported", number)
+		}
+	}
+	if hash := ReadCanonicalHash(imported, 8); hash != (common.Hash{}) {
+		t.Errorf("Block outside the exported range imported")
+	}
+	// Segments not extending the local chain must be rejected
+	if _, err := ImportBlocks(NewMemoryDatabase(), bytes.NewReader(buf.Bytes()), 0); err == nil {
+		t.Errorf("Import without parent succeeded")
+	}
+	// Corrupted magic must be rejected
+	corrupted := append([]byte{}, buf.Bytes()...)
+	corrupted[0] ^= 0xff
+	if _, err := ImportBlocks(NewMemoryDatabase(), bytes.NewReader(corrupted), 0); err != errInvalidBlockExport {
+		t.Errorf("Corrupted magic: have %v, want %v", err, errInvalidBlockExport)
+	}
+}
+
+func TestImportBlocksOutOfOrder(t *testing.T) {
+	db := NewMemoryDatabase()
+	blocks := makeTestBlockChain(db, 6)
+
+	// Splice an export of #1-#2 with one of #4-#5
+	var head, tail bytes.Buffer
+	if err := ExportBlocks(db, &head, 1, 2); err != nil {
+		t.Fatalf("Failed to export blocks: %v", err)
+	}
+	if err := ExportBlocks(db, &tail, 4, 5); err != nil {
+		t.Fatalf("Failed to export blocks: %v", err)
+	}
+	skip := len(blockExportMagic) + 1 // magic and single byte version
+	export := append(head.Bytes(), tail.Bytes()[skip:]...)
+
+	imported := NewMemoryDatabase()
+	WriteBlock(imported, blocks[0])
+	count, err := ImportBlocks(imported, bytes.NewReader(export), 0)
+	if err == nil || !strings.Contains(err.Error(), "#4") {
+		t.Fatalf("Out of order import error mismatch: have %v, want error on #4", err)
+	}
+	if count != 2 {
+		t.Fatalf("Imported block count mismatch: have %d, want %d", count, 2)
+	}
+	for _, block := range blocks[1:3] {
+		if ReadBlock(imported, block.Hash(), block.NumberU64()) == nil {
+			t.Errorf("Block #%d imported before the failure not readable", block.NumberU64())
+		}
+	}
+	if HasHeader(imported, blocks[4].Hash(), 4) {
+		t.Errorf("Out of order block imported")
+	}
+}
@@ -18,6 +18,7 @@ package rawdb
 
 import (
 	"bytes"
+	"errors"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -32,26 +33,62 @@ import (
 // ReadTxLookupEntry retrieves the positional metadata associated with a transaction
 // hash to allow retrieving the transaction or receipt by hash.
 func ReadTxLookupEntry(db ethdb.Reader, hash common.Hash) *uint64 {
+	number, err := readTxLookupEntry(db, hash)
+	if err != nil && err != ErrTxLookupNotFound {
+		log.Error("Invalid transaction lookup entry RLP", "hash", hash, "err", err)
+	}
+	return number
+}
+
+var (
+	// ErrTxLookupNotFound is returned by ReadTxLookupEntryChecked if there is no
+	// lookup entry for a transaction and all the blocks are indexed.
+	ErrTxLookupNotFound = errors.New("transaction lookup entry not found")
+
+	// ErrTxLookupUnindexed is returned by ReadTxLookupEntryChecked if there is
+	// no lookup entry for a transaction, but the blocks below the transaction
+	// index tail are not indexed, so it may belong to one of them.
+	ErrTxLookupUnindexed = errors.New("transaction lookup entry not found, blocks below the index tail are not indexed")
+)
+
+// ReadTxLookupEntryChecked is ReadTxLookupEntry reporting why no block number is
+// returned. A missing entry is told apart from a transaction that may be in a
+// block the lookup index has been pruned from or is not yet built for.
+func ReadTxLookupEntryChecked(db ethdb.Reader, hash common.Hash) (*uint64, error) {
+	number, err := readTxLookupEntry(db, hash)
+	if err == ErrTxLookupNotFound {
+		if tail := ReadTxIndexTail(db); tail != nil && *tail > 0 {
+			return nil, ErrTxLookupUnindexed
+		}
+	}
+	return number, err
+}
+
+// readTxLookupEntry implements ReadTxLookupEntry, decoding the entries of every
+// database version.
+func readTxLookupEntry(db ethdb.Reader, hash common.Hash) (*uint64, error) {
 	data, _ := db.Get(txLookupKey(hash))
 	if len(data) == 0 {
-		return nil
+		return nil, ErrTxLookupNotFound
 	}
 	// Database v6 tx lookup just stores the block number
 	if len(data) < common.HashLength {
 		number := new(big.Int).SetBytes(data).Uint64()
-		return &number
+		return &number, nil
 	}
 	// Database v4-v5 tx lookup format just stores the hash
 	if len(data) == common.HashLength {
-		return ReadHeaderNumber(db, common.BytesToHash(data))
+		if number := ReadHeaderNumber(db, common.BytesToHash(data)); number != nil {
+			return number, nil
+		}
+		return nil, ErrTxLookupNotFound
 	}
 	// Finally try database v3 tx lookup format
 	var entry LegacyTxLookupEntry
 	if err := rlp.DecodeBytes(data, &entry); err != nil {
-		log.Error("Invalid transaction lookup entry RLP", "hash", hash, "blob", data, "err", err)
-		return nil
+		return nil, err
 	}
-	return &entry.BlockIndex
+	return &entry.BlockIndex, nil
 }
 
 // writeTxLookupEntry stores a positional metadata for a transaction,
@@ -132,7 +169,11 @@ func ReadReceipt(db ethdb.Reader, hash common.Hash, config *params.ChainConfig)
 		return nil, common.Hash{}, 0, 0
 	}
 	// Read all the receipts from the block and return the one with the matching hash
-	receipts := ReadReceipts(db, blockHash, *blockNumber, config)
+	receipts, err := ReadReceiptsErr(db, blockHash, *blockNumber, config)
+	if err != nil {
+		log.Error("Failed to read receipt", "number", *blockNumber, "hash", blockHash, "txhash", hash, "err", err)
+		return nil, common.Hash{}, 0, 0
+	}
 	for receiptIndex, receipt := range receipts {
 		if receipt.TxHash == hash {
 			return receipt, blockHash, *blockNumber, uint64(receiptIndex)
@@ -143,7 +184,8 @@ func ReadReceipt(db ethdb.Reader, hash common.Hash, config *params.ChainConfig)
 }
 
 // ReadBloomBits retrieves the compressed bloom bit vector belonging to the given
-// section and bit index from the.
+// section and bit index from the database. The head is the hash of the last
+// block of the section, so the vectors of forked sections are kept apart.
 func ReadBloomBits(db ethdb.KeyValueReader, bit uint, section uint64, head common.Hash) ([]byte, error) {
 	return db.Get(bloomBitsKey(bit, section, head))
 }
@@ -136,6 +136,56 @@ func TestLookupStorage(t *testing.T) {
 	}
 }
 
+// Tests that a missing transaction lookup entry is reported as unindexed only
+// if the lookup index does not cover all the blocks.
+func TestReadTxLookupEntryChecked(t *testing.T) {
+	db := NewMemoryDatabase()
+	tx := types.NewTransaction(1, common.BytesToAddress([]byte{0x11}), big.NewInt(111), 1111, big.NewInt(11111), nil)
+	block := types.NewBlock(&types.Header{Number: big.NewInt(314)}, []*types.Transaction{tx}, nil, &types.Randomness{})
+
+	if _, err := ReadTxLookupEntryChecked(db, tx.Hash()); err != ErrTxLookupNotFound {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrTxLookupNotFound)
+	}
+	WriteTxIndexTail(db, 100)
+	if _, err := ReadTxLookupEntryChecked(db, tx.Hash()); err != ErrTxLookupUnindexed {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrTxLookupUnindexed)
+	}
+	WriteTxLookupEntriesByBlock(db, block)
+	number, err := ReadTxLookupEntryChecked(db, tx.Hash())
+	if err != nil {
+		t.Fatalf("failed to read lookup entry: %v", err)
+	}
+	if *number != block.NumberU64() {
+		t.Fatalf("block number mismatch: have %d, want %d", *number, block.NumberU64())
+	}
+}
+
+// Tests that the bloom bits of the same section on two forks are kept apart by
+// their head hash.
+func TestBloomBitsForks(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	forks := map[common.Hash][]byte{
+		params.MainnetGenesisHash: {0x01, 0x02},
+		params.RinkebyGenesisHash: {0x03, 0x04},
+	}
+	for head, bits := range forks {
+		WriteBloomBits(db, 7, 3, head, bits)
+	}
+	for head, want := range forks {
+		have, err := ReadBloomBits(db, 7, 3, head)
+		if err != nil {
+			t.Fatalf("Bloombits of head %x not found: %v", head, err)
+		}
+		if !bytes.Equal(have, want) {
+			t.Fatalf("Bloombits of head %x mismatch: have %x, want %x", head, have, want)
+		}
+	}
+	if _, err := ReadBloomBits(db, 7, 3, common.Hash{0x01}); err == nil {
+		t.Fatalf("Bloombits of unknown head returned")
+	}
+}
+
 func TestDeleteBloomBits(t *testing.T) {
 	// Prepare testing data
 	db := NewMemoryDatabase()
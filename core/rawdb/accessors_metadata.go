@@ -18,6 +18,9 @@ package rawdb
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -82,6 +85,106 @@ func WriteChainConfig(db ethdb.KeyValueWriter, hash common.Hash, cfg *params.Cha
 	}
 }
 
+// ChainConfigEntry is a chain config in the config history along with the
+// height of the first block it applies to.
+type ChainConfigEntry struct {
+	Height uint64
+	Config []byte // JSON encoded params.ChainConfig
+}
+
+// ReadChainConfigHistory retrieves the chain configs recorded for the given
+// genesis hash, ordered by ascending activation height.
+func ReadChainConfigHistory(db ethdb.KeyValueReader, hash common.Hash) []ChainConfigEntry {
+	data, _ := db.Get(configHistoryKey(hash))
+	if len(data) == 0 {
+		return nil
+	}
+	var entries []ChainConfigEntry
+	if err := rlp.DecodeBytes(data, &entries); err != nil {
+		log.Error("Invalid chain config history RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return entries
+}
+
+// WriteChainConfigWithHeight appends a chain config activated at the given
+// height to the config history of the genesis hash, and stores it as the
+// current chain config too. The history is append-only, the height can't be
+// below the one of the last recorded config, which is replaced if activated at
+// the same height. The first write records the current chain config as active
+// from genesis.
+func WriteChainConfigWithHeight(db ethdb.KeyValueStore, hash common.Hash, height uint64, cfg *params.ChainConfig) error {
+	if cfg == nil {
+		return errors.New("nil chain config")
+	}
+	entries := ReadChainConfigHistory(db, hash)
+	if n := len(entries); n > 0 && entries[n-1].Height > height {
+		return fmt.Errorf("chain config at height %d before the last one at %d", height, entries[n-1].Height)
+	} else if n > 0 && entries[n-1].Height == height {
+		entries = entries[:n-1]
+	}
+	// Starting the history, keep the config the chain ran with so far for the
+	// blocks before the new one
+	if len(entries) == 0 && height > 0 {
+		if data, _ := db.Get(configKey(hash)); len(data) > 0 {
+			entries = append(entries, ChainConfigEntry{Height: 0, Config: data})
+		}
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	enc, err := rlp.EncodeToBytes(append(entries, ChainConfigEntry{Height: height, Config: data}))
+	if err != nil {
+		return err
+	}
+	if err := db.Put(configHistoryKey(hash), enc); err != nil {
+		log.Crit("Failed to store chain config history", "err", err)
+	}
+	WriteChainConfig(db, hash, cfg)
+	return nil
+}
+
+// ReadChainConfigAt retrieves the chain config the block at the given height
+// was processed with: the recorded config with the highest activation height
+// not above it. Chains without a history get the single config stored by
+// WriteChainConfig.
+func ReadChainConfigAt(db ethdb.KeyValueReader, hash common.Hash, height uint64) *params.ChainConfig {
+	entries := ReadChainConfigHistory(db, hash)
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].Height > height })
+	if i == 0 {
+		return ReadChainConfig(db, hash)
+	}
+	var config params.ChainConfig
+	if err := json.Unmarshal(entries[i-1].Config, &config); err != nil {
+		log.Error("Invalid chain config JSON", "hash", hash, "height", entries[i-1].Height, "err", err)
+		return nil
+	}
+	return &config
+}
+
+// ChainConfigProvider returns the chain config a block was processed with.
+type ChainConfigProvider interface {
+	ChainConfigAt(number uint64) *params.ChainConfig
+}
+
+// chainConfigHistory is a ChainConfigProvider reading the config history of
+// a genesis hash from the database.
+type chainConfigHistory struct {
+	db      ethdb.KeyValueReader
+	genesis common.Hash
+}
+
+// NewChainConfigProvider returns a ChainConfigProvider picking the configs
+// from the config history of the given genesis hash.
+func NewChainConfigProvider(db ethdb.KeyValueReader, genesis common.Hash) ChainConfigProvider {
+	return &chainConfigHistory{db: db, genesis: genesis}
+}
+
+func (h *chainConfigHistory) ChainConfigAt(number uint64) *params.ChainConfig {
+	return ReadChainConfigAt(h.db, h.genesis, number)
+}
+
 // crashList is a list of unclean-shutdown-markers, for rlp-encoding to the
 // database
 type crashList struct {
@@ -0,0 +1,108 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/mapprotocol/atlas/params"
+)
+
+// Tests that the chain config of a block is picked from the config history by
+// activation height.
+func TestChainConfigHistory(t *testing.T) {
+	db := NewMemoryDatabase()
+	genesis := params.MainnetGenesisHash
+
+	base := *params.TestChainConfig
+	WriteChainConfig(db, genesis, &base)
+	if config := ReadChainConfigAt(db, genesis, 100); config == nil || config.ChainID.Cmp(base.ChainID) != 0 {
+		t.Fatalf("config without history mismatch: have %v, want chain id %v", config, base.ChainID)
+	}
+	forks := []uint64{10, 20, 20}
+	for i, height := range forks {
+		config := *params.TestChainConfig
+		config.ChainID = big.NewInt(int64(i + 2))
+		if err := WriteChainConfigWithHeight(db, genesis, height, &config); err != nil {
+			t.Fatalf("failed to write config at height %d: %v", height, err)
+		}
+	}
+	if err := WriteChainConfigWithHeight(db, genesis, 15, params.TestChainConfig); err == nil {
+		t.Fatal("config before a recorded height accepted")
+	}
+	// The config in use before the first fork is recorded from genesis, and the
+	// second config at height 20 replaced the first one
+	if history := ReadChainConfigHistory(db, genesis); len(history) != 3 {
+		t.Fatalf("history length mismatch: have %d, want %d", len(history), 3)
+	}
+	if config := ReadChainConfig(db, genesis); config.ChainID.Int64() != 4 {
+		t.Errorf("current config chain id mismatch: have %v, want %d", config.ChainID, 4)
+	}
+	provider := NewChainConfigProvider(db, genesis)
+	for height, want := range map[uint64]int64{0: 1, 9: 1, 10: 2, 19: 2, 20: 4, 1000: 4} {
+		config := provider.ChainConfigAt(height)
+		if config == nil || config.ChainID.Int64() != want {
+			t.Errorf("height %d: chain id mismatch: have %v, want %d", height, config, want)
+		}
+	}
+}
+
+func TestMigrateSchema(t *testing.T) {
+	defer func(migrations map[uint64]func(ethdb.Database) error) { schemaMigrations = migrations }(schemaMigrations)
+
+	var applied []uint64
+	step := func(version uint64) func(ethdb.Database) error {
+		return func(db ethdb.Database) error {
+			if have := ReadDatabaseVersion(db); have == nil || *have != version-1 {
+				t.Errorf("step %d run on version %v", version, have)
+			}
+			applied = append(applied, version)
+			return nil
+		}
+	}
+	failure := errors.New("failure")
+	schemaMigrations = map[uint64]func(ethdb.Database) error{
+		2: step(2),
+		4: step(4),
+		5: func(ethdb.Database) error { return failure },
+	}
+	db := NewMemoryDatabase()
+	WriteDatabaseVersion(db, 1)
+
+	if err := MigrateSchema(db, 1, 4); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if len(applied) != 2 || applied[0] != 2 || applied[1] != 4 {
+		t.Errorf("applied steps mismatch: have %v, want [2 4]", applied)
+	}
+	if version := ReadDatabaseVersion(db); version == nil || *version != 4 {
+		t.Errorf("version mismatch: have %v, want 4", version)
+	}
+	// A failing step leaves the version of the last completed one
+	if err := MigrateSchema(db, 4, 6); err == nil {
+		t.Errorf("failing step not reported")
+	}
+	if version := ReadDatabaseVersion(db); *version != 4 {
+		t.Errorf("version after failure mismatch: have %d, want 4", *version)
+	}
+	if err := MigrateSchema(db, 4, 3); err == nil {
+		t.Errorf("downgrade allowed")
+	}
+}
@@ -0,0 +1,66 @@
+package rawdb
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// dataSource is where a chain accessor found the requested data.
+type dataSource int
+
+const (
+	sourceMiss    dataSource = iota // Data not found anywhere
+	sourceKV                        // Data found in the key-value store
+	sourceAncient                   // Data found in the ancient store
+)
+
+// accessorMetrics counts where the reads of a chain accessor are served from
+// and how long they take.
+type accessorMetrics struct {
+	ancient metrics.Counter
+	kv      metrics.Counter
+	miss    metrics.Counter
+	latency metrics.Timer
+}
+
+func newAccessorMetrics(name string) *accessorMetrics {
+	return &accessorMetrics{
+		ancient: metrics.NewRegisteredCounter("rawdb/"+name+"/ancient", nil),
+		kv:      metrics.NewRegisteredCounter("rawdb/"+name+"/kv", nil),
+		miss:    metrics.NewRegisteredCounter("rawdb/"+name+"/miss", nil),
+		latency: metrics.NewRegisteredTimer("rawdb/"+name+"/latency", nil),
+	}
+}
+
+var (
+	headerMetrics   = newAccessorMetrics("header")
+	bodyMetrics     = newAccessorMetrics("body")
+	receiptsMetrics = newAccessorMetrics("receipts")
+	tdMetrics       = newAccessorMetrics("td")
+)
+
+// start returns the start time of a read, or the zero time if metrics are
+// disabled, so that the hot path doesn't pay for querying the clock.
+func (m *accessorMetrics) start() time.Time {
+	if !metrics.Enabled {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// mark records a read started at start and served from source.
+func (m *accessorMetrics) mark(start time.Time, source dataSource) {
+	if !metrics.Enabled {
+		return
+	}
+	switch source {
+	case sourceAncient:
+		m.ancient.Inc(1)
+	case sourceKV:
+		m.kv.Inc(1)
+	default:
+		m.miss.Inc(1)
+	}
+	m.latency.UpdateSince(start)
+}
@@ -0,0 +1,85 @@
+package rawdb
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+func TestAccessorMetrics(t *testing.T) {
+	defer func(enabled bool) { metrics.Enabled = enabled }(metrics.Enabled)
+	metrics.Enabled = true
+
+	frdir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temp freezer dir: %v", err)
+	}
+	defer os.RemoveAll(frdir)
+
+	db, err := NewDatabaseWithFreezer(NewMemoryDatabase(), frdir, "", false)
+	if err != nil {
+		t.Fatalf("failed to create database with ancient backend: %v", err)
+	}
+	defer db.Close()
+
+	blocks := makeTestBlocks(2, 0)
+	if _, err := WriteAncientBlocks(db, blocks[:1], makeTestReceipts(1, 0), big.NewInt(1)); err != nil {
+		t.Fatalf("failed to write ancient blocks: %v", err)
+	}
+	WriteHeader(db, blocks[1].Header())
+
+	// The package level metrics were created before metrics got enabled
+	saved := headerMetrics
+	defer func() { headerMetrics = saved }()
+	headerMetrics = newAccessorMetrics("test/header")
+
+	ReadHeaderRLP(db, blocks[0].Hash(), 0)
+	ReadHeaderRLP(db, blocks[1].Hash(), 1)
+	ReadHeaderRLP(db, blocks[1].Hash(), 1)
+	ReadHeaderRLP(db, common.Hash{0x01}, 2)
+
+	if have := headerMetrics.ancient.Count(); have != 1 {
+		t.Errorf("ancient hits mismatch: have %d, want 1", have)
+	}
+	if have := headerMetrics.kv.Count(); have != 2 {
+		t.Errorf("key-value hits mismatch: have %d, want 2", have)
+	}
+	if have := headerMetrics.miss.Count(); have != 1 {
+		t.Errorf("misses mismatch: have %d, want 1", have)
+	}
+	if have := headerMetrics.latency.Count(); have != 4 {
+		t.Errorf("timed reads mismatch: have %d, want 4", have)
+	}
+}
+
+func BenchmarkReadHeaderRLP(b *testing.B) {
+	db := NewMemoryDatabase()
+	header := makeTestBlocks(1, 0)[0].Header()
+	WriteHeader(db, header)
+	hash, number := header.Hash(), header.Number.Uint64()
+
+	defer func(enabled bool) { metrics.Enabled = enabled }(metrics.Enabled)
+	for _, enabled := range []bool{false, true} {
+		name := "metrics-off"
+		if enabled {
+			name = "metrics-on"
+		}
+		b.Run(name, func(b *testing.B) {
+			metrics.Enabled = enabled
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				ReadHeaderRLP(db, hash, number)
+			}
+		})
+	}
+	b.Run("raw", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			readHeaderRLP(db, hash, number)
+		}
+	})
+}
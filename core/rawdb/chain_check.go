@@ -0,0 +1,155 @@
+package rawdb
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// CanonicalIssue is an inconsistency of the canonical chain at a block.
+type CanonicalIssue struct {
+	Number uint64
+	Hash   common.Hash
+	Reason string
+}
+
+func (i CanonicalIssue) String() string {
+	return fmt.Sprintf("#%d [%x]: %s", i.Number, i.Hash, i.Reason)
+}
+
+// CanonicalReport is the result of checking a range of the canonical chain.
+type CanonicalReport struct {
+	From, To uint64
+
+	Gaps   []uint64         // numbers without a canonical hash
+	Issues []CanonicalIssue // canonical blocks failing a check
+
+	// Consistent is the number of blocks at the start of the range passing
+	// all the checks, Consistent == To-From+1 if the whole range does.
+	Consistent uint64
+}
+
+// OK returns whether the whole checked range is consistent.
+func (r *CanonicalReport) OK() bool {
+	return len(r.Gaps) == 0 && len(r.Issues) == 0
+}
+
+// CheckCanonicalChain walks the canonical chain between from and to (both
+// inclusive) and checks that:
+//
+//   - every number has a canonical hash, whose header is stored and maps back
+//     to the number,
+//   - every header links to the canonical hash of the number before it,
+//   - the total difficulty is stored and increases with every block,
+//   - the blocks up to the head block have their body and receipts stored.
+//
+// Every gap and failed check found is returned in the report.
+func CheckCanonicalChain(db ethdb.Reader, from, to uint64) (*CanonicalReport, error) {
+	if from > to {
+		return nil, fmt.Errorf("invalid range: from %d > to %d", from, to)
+	}
+	full := uint64(0)
+	if head := ReadHeaderNumber(db, ReadHeadBlockHash(db)); head != nil {
+		full = *head
+	}
+	report := &CanonicalReport{From: from, To: to}
+
+	var (
+		prevHash   common.Hash
+		prevTd     *big.Int
+		consistent = true
+	)
+	if from > 0 {
+		prevHash = ReadCanonicalHash(db, from-1)
+		prevTd = ReadTd(db, prevHash, from-1)
+	}
+	for number := from; ; number++ {
+		issues := len(report.Issues)
+		hash := ReadCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			report.Gaps = append(report.Gaps, number)
+			prevTd = nil
+		} else {
+			issue := func(format string, args ...interface{}) {
+				report.Issues = append(report.Issues, CanonicalIssue{Number: number, Hash: hash, Reason: fmt.Sprintf(format, args...)})
+			}
+			if n := ReadHeaderNumber(db, hash); n == nil || *n != number {
+				issue("hash->number mapping missing or mismatched")
+			}
+			header := ReadHeader(db, hash, number)
+			switch {
+			case header == nil:
+				issue("missing header")
+			case number > 0 && prevHash != (common.Hash{}) && header.ParentHash != prevHash:
+				issue("parent mismatch: have %x, want %x", header.ParentHash, prevHash)
+			}
+			td := ReadTd(db, hash, number)
+			switch {
+			case td == nil:
+				issue("missing total difficulty")
+			case number > 0 && prevTd != nil && td.Cmp(prevTd) <= 0:
+				issue("total difficulty %v not above parent's %v", td, prevTd)
+			}
+			if number <= full {
+				if !HasBody(db, hash, number) {
+					issue("missing body")
+				}
+				if !HasReceipts(db, hash, number) {
+					issue("missing receipts")
+				}
+			}
+			prevTd = td
+		}
+		if consistent && len(report.Gaps) == 0 && len(report.Issues) == issues {
+			report.Consistent++
+		} else {
+			consistent = false
+		}
+		prevHash = hash
+		if number == to {
+			break
+		}
+	}
+	return report, nil
+}
+
+// RepairCanonicalChain checks the canonical chain from genesis to the head
+// header and rewinds the head header, fast block and block pointers to the
+// highest block below which the chain is fully consistent. No data is deleted.
+// It returns the number of the resulting head header.
+func RepairCanonicalChain(db ethdb.Database) (uint64, error) {
+	head := ReadHeaderNumber(db, ReadHeadHeaderHash(db))
+	if head == nil {
+		return 0, fmt.Errorf("missing head header")
+	}
+	report, err := CheckCanonicalChain(db, 0, *head)
+	if err != nil {
+		return 0, err
+	}
+	if report.OK() {
+		return *head, nil
+	}
+	if report.Consistent == 0 {
+		return 0, fmt.Errorf("genesis block is inconsistent")
+	}
+	last := report.Consistent - 1
+	hash := ReadCanonicalHash(db, last)
+	log.Warn("Rewinding inconsistent canonical chain", "head", *head, "consistent", last, "hash", hash)
+
+	WriteHeadHeaderHash(db, hash)
+	for _, pointer := range []struct {
+		read  func(ethdb.KeyValueReader) common.Hash
+		write func(ethdb.KeyValueWriter, common.Hash)
+	}{
+		{ReadHeadBlockHash, WriteHeadBlockHash},
+		{ReadHeadFastBlockHash, WriteHeadFastBlockHash},
+	} {
+		if n := ReadHeaderNumber(db, pointer.read(db)); n == nil || *n > last {
+			pointer.write(db, hash)
+		}
+	}
+	return last, nil
+}
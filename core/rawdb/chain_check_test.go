@@ -0,0 +1,94 @@
+package rawdb
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/mapprotocol/atlas/core/types"
+)
+
+// writeCanonicalTestChain stores a linked canonical chain of n full blocks
+// with total difficulties and receipts, and sets the head pointers to its end.
+func writeCanonicalTestChain(db ethdb.KeyValueWriter, n int) []*types.Block {
+	blocks := make([]*types.Block, n)
+	parent := common.Hash{}
+	for i := range blocks {
+		blocks[i] = types.NewBlockWithHeader(&types.Header{
+			ParentHash: parent,
+			Number:     big.NewInt(int64(i)),
+			Extra:      []byte("test block"),
+		})
+		parent = blocks[i].Hash()
+
+		WriteBlock(db, blocks[i])
+		WriteCanonicalHash(db, blocks[i].Hash(), uint64(i))
+		WriteTd(db, blocks[i].Hash(), uint64(i), big.NewInt(int64(i+1)))
+		WriteReceipts(db, blocks[i].Hash(), uint64(i), nil)
+	}
+	head := blocks[n-1].Hash()
+	WriteHeadHeaderHash(db, head)
+	WriteHeadFastBlockHash(db, head)
+	WriteHeadBlockHash(db, head)
+	return blocks
+}
+
+func TestCheckRepairCanonicalChain(t *testing.T) {
+	db := NewMemoryDatabase()
+	blocks := writeCanonicalTestChain(db, 10)
+
+	if _, err := CheckCanonicalChain(db, 5, 4); err == nil {
+		t.Fatal("invalid range accepted")
+	}
+	report, err := CheckCanonicalChain(db, 0, 9)
+	if err != nil {
+		t.Fatalf("failed to check chain: %v", err)
+	}
+	if !report.OK() || report.Consistent != 10 {
+		t.Fatalf("consistent chain reported inconsistent: %+v", report)
+	}
+	// Point #7 to a header of a side chain and drop the receipts of #5
+	side := types.NewBlockWithHeader(&types.Header{ParentHash: common.Hash{0x01}, Number: big.NewInt(7)})
+	WriteBlock(db, side)
+	WriteTd(db, side.Hash(), 7, big.NewInt(8))
+	WriteReceipts(db, side.Hash(), 7, nil)
+	WriteCanonicalHash(db, side.Hash(), 7)
+	DeleteReceipts(db, blocks[5].Hash(), 5)
+
+	report, err = CheckCanonicalChain(db, 0, 9)
+	if err != nil {
+		t.Fatalf("failed to check chain: %v", err)
+	}
+	// #7 has the wrong parent and #8 no longer links to #7
+	if len(report.Issues) != 3 || report.Consistent != 5 {
+		t.Fatalf("report mismatch: have %d issues, %d consistent, want 3 issues, 5 consistent: %v", len(report.Issues), report.Consistent, report.Issues)
+	}
+	for i, number := range []uint64{5, 7, 8} {
+		if report.Issues[i].Number != number {
+			t.Errorf("issue %d: number mismatch: have %d, want %d", i, report.Issues[i].Number, number)
+		}
+	}
+	// A missing canonical hash is a gap
+	DeleteCanonicalHash(db, 9)
+	if report, _ = CheckCanonicalChain(db, 8, 9); len(report.Gaps) != 1 || report.Gaps[0] != 9 {
+		t.Fatalf("gaps mismatch: have %v, want [9]", report.Gaps)
+	}
+	// Repairing rewinds all the heads to the last consistent block
+	head, err := RepairCanonicalChain(db)
+	if err != nil || head != 4 {
+		t.Fatalf("repair result mismatch: have (%d, %v), want (4, nil)", head, err)
+	}
+	for name, hash := range map[string]common.Hash{
+		"header": ReadHeadHeaderHash(db),
+		"fast":   ReadHeadFastBlockHash(db),
+		"block":  ReadHeadBlockHash(db),
+	} {
+		if hash != blocks[4].Hash() {
+			t.Errorf("head %s mismatch: have %x, want %x", name, hash, blocks[4].Hash())
+		}
+	}
+	if head, err := RepairCanonicalChain(db); err != nil || head != 4 {
+		t.Fatalf("repair of consistent chain: have (%d, %v), want (4, nil)", head, err)
+	}
+}
@@ -25,6 +25,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/mapprotocol/atlas/core/types"
+	"github.com/mapprotocol/atlas/params"
 )
 
 func TestChainIterator(t *testing.T) {
@@ -206,3 +207,82 @@ func TestIndexTransactions(t *testing.T) {
 	verify(8, 11, true, 8)
 	verify(0, 8, false, 8)
 }
+
+// Tests that the lookup entries of a range can be recreated after the tail was
+// moved forward, and that blocks carrying a block finalization receipt next to
+// the transaction receipts are indexed and unindexed like any other block.
+func TestReindexTransactions(t *testing.T) {
+	chainDb := NewMemoryDatabase()
+
+	block := types.NewBlock(&types.Header{Number: big.NewInt(0)}, nil, nil, &types.Randomness{})
+	WriteBlock(chainDb, block)
+	WriteCanonicalHash(chainDb, block.Hash(), block.NumberU64())
+
+	to := common.BytesToAddress([]byte{0x11})
+	blocks := []*types.Block{block}
+	for i := uint64(1); i <= 6; i++ {
+		txs := []*types.Transaction{
+			types.NewTransaction(2*i, to, big.NewInt(111), 21000, big.NewInt(1), nil),
+			types.NewTransaction(2*i+1, to, big.NewInt(111), 21000, big.NewInt(1), nil),
+		}
+		receipts := types.Receipts{
+			{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 21000, Logs: []*types.Log{{Address: to}}},
+			{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 42000, Logs: []*types.Log{{Address: to}}},
+			// block finalization receipt
+			{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 42000, Logs: []*types.Log{{Address: to}}},
+		}
+		block = types.NewBlock(&types.Header{Number: new(big.Int).SetUint64(i)}, txs, nil, &types.Randomness{})
+		WriteBlock(chainDb, block)
+		WriteCanonicalHash(chainDb, block.Hash(), block.NumberU64())
+		WriteReceipts(chainDb, block.Hash(), block.NumberU64(), receipts)
+		blocks = append(blocks, block)
+	}
+	// verify checks whether the tx indices of the blocks in the range [from, to)
+	// are as expected.
+	verify := func(from, to int, exist bool, tail uint64) {
+		for i := from; i < to; i++ {
+			for _, tx := range blocks[i].Transactions() {
+				number := ReadTxLookupEntry(chainDb, tx.Hash())
+				if exist && (number == nil || *number != uint64(i)) {
+					t.Fatalf("Transaction index of block %d missing", i)
+				}
+				if !exist && number != nil {
+					t.Fatalf("Transaction index of block %d is not deleted", i)
+				}
+			}
+		}
+		if number := ReadTxIndexTail(chainDb); number == nil || *number != tail {
+			t.Fatalf("Transaction tail mismatch: have %v, want %d", number, tail)
+		}
+	}
+	IndexTransactions(chainDb, 0, 7, nil)
+	verify(0, 7, true, 0)
+
+	UnindexTransactions(chainDb, 0, 4, nil)
+	verify(0, 4, false, 4)
+	verify(4, 7, true, 4)
+
+	// Move the tail backwards again
+	IndexTransactions(chainDb, 2, 4, nil)
+	verify(0, 2, false, 2)
+	verify(2, 7, true, 2)
+
+	// The finalization receipt must not disturb reading the reindexed receipts
+	tx := blocks[3].Transactions()[1]
+	receipt, hash, number, index := ReadReceipt(chainDb, tx.Hash(), params.TestChainConfig)
+	if receipt == nil {
+		t.Fatalf("Receipt of reindexed transaction not found")
+	}
+	if hash != blocks[3].Hash() || number != 3 || index != 1 {
+		t.Fatalf("Receipt positional metadata mismatch: have %x/%d/%d, want %x/%d/%d", hash, number, index, blocks[3].Hash(), 3, 1)
+	}
+	if receipt.GasUsed != 21000 || receipt.Logs[0].Index != 1 {
+		t.Fatalf("Receipt derived fields mismatch: have gas %d log index %d, want 21000 1", receipt.GasUsed, receipt.Logs[0].Index)
+	}
+	if logs, _ := ReadLogs(chainDb, blocks[3].Hash(), 3); len(logs) != 3 || logs[2][0].TxHash != blocks[3].Hash() {
+		t.Fatalf("Block finalization logs mismatch")
+	}
+
+	UnindexTransactions(chainDb, 2, 7, nil)
+	verify(0, 7, false, 7)
+}
@@ -30,6 +30,8 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb/memorydb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/olekukonko/tablewriter"
+
+	"github.com/mapprotocol/atlas/consensus/istanbul"
 )
 
 // freezerdb is a database wrapper that enabled freezer data retrievals.
@@ -250,6 +252,18 @@ func NewLevelDBDatabaseWithFreezer(file string, cache int, handles int, freezer
 	return frdb, nil
 }
 
+// CompactRange flattens the key range [start, limit) of the database so the
+// space of deleted entries is reclaimed. A nil start or limit is treated as the
+// beginning or end of the key space.
+func CompactRange(db ethdb.Compacter, start, limit []byte) error {
+	cstart := time.Now()
+	if err := db.Compact(start, limit); err != nil {
+		return fmt.Errorf("failed to compact range %#x-%#x: %w", start, limit, err)
+	}
+	log.Debug("Compacted database range", "start", fmt.Sprintf("%#x", start), "limit", fmt.Sprintf("%#x", limit), "elapsed", common.PrettyDuration(time.Since(cstart)))
+	return nil
+}
+
 type counter uint64
 
 func (c counter) String() string {
@@ -307,12 +321,22 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		bloomBits       stat
 		cliqueSnaps     stat
 
+		// Atlas specific statistics
+		istanbulSnaps stat
+		uptimes       stat
+		epochBounds   stat
+		randomness    stat
+		chainsData    stat
+		doubleSigns   stat
+
 		// Ancient store statistics
 		ancientHeadersSize  common.StorageSize
 		ancientBodiesSize   common.StorageSize
 		ancientReceiptsSize common.StorageSize
 		ancientTdsSize      common.StorageSize
 		ancientHashesSize   common.StorageSize
+		ancientRandomSize   common.StorageSize
+		ancientSnarkSize    common.StorageSize
 
 		// Les statistic
 		chtTrieNodes   stat
@@ -359,6 +383,8 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 			preimages.Add(size)
 		case bytes.HasPrefix(key, configPrefix) && len(key) == (len(configPrefix)+common.HashLength):
 			metadata.Add(size)
+		case bytes.HasPrefix(key, configHistoryPrefix) && len(key) == (len(configHistoryPrefix)+common.HashLength):
+			metadata.Add(size)
 		case bytes.HasPrefix(key, bloomBitsPrefix) && len(key) == (len(bloomBitsPrefix)+10+common.HashLength):
 			bloomBits.Add(size)
 		case bytes.HasPrefix(key, BloomBitsIndexPrefix):
@@ -373,13 +399,27 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 			bytes.HasPrefix(key, []byte("bltIndex-")) ||
 			bytes.HasPrefix(key, []byte("bltRoot-")): // Bloomtrie sub
 			bloomTrieNodes.Add(size)
+		case bytes.HasPrefix(key, []byte("istanbul-snapshot")) && len(key) == 17+common.HashLength: // Istanbul backend snapshots
+			istanbulSnaps.Add(size)
+		case bytes.HasPrefix(key, istanbulSnapshotPrefix) && len(key) == len(istanbulSnapshotPrefix)+8:
+			istanbulSnaps.Add(size)
+		case bytes.HasPrefix(key, uptimePrefix) && len(key) == len(uptimePrefix)+8:
+			uptimes.Add(size)
+		case bytes.HasPrefix(key, epochBoundaryPrefix) && len(key) == len(epochBoundaryPrefix)+8:
+			epochBounds.Add(size)
+		case bytes.HasPrefix(key, istanbul.RandomnessCommitmentDBPrefix) && len(key) == len(istanbul.RandomnessCommitmentDBPrefix)+common.HashLength:
+			randomness.Add(size)
+		case bytes.HasPrefix(key, chainsPrefix) && len(key) > len(chainsPrefix)+8:
+			chainsData.Add(size)
+		case bytes.HasPrefix(key, doubleSignEvidencePrefix) && len(key) == len(doubleSignEvidencePrefix)+8+common.HashLength:
+			doubleSigns.Add(size)
 		default:
 			var accounted bool
 			for _, meta := range [][]byte{
-				databaseVersionKey, headHeaderKey, headBlockKey, headFastBlockKey, lastPivotKey,
+				databaseVersionKey, headHeaderKey, headBlockKey, headBlockMetaKey, finalizedBlockKey, lastBlockKey, headFastBlockKey, lastPivotKey,
 				fastTrieProgressKey, snapshotDisabledKey, snapshotRootKey, snapshotJournalKey,
-				snapshotGeneratorKey, snapshotRecoveryKey, txIndexTailKey, fastTxLookupLimitKey,
-				uncleanShutdownKey, badBlockKey,
+				snapshotGeneratorKey, snapshotRecoveryKey, snapshotSyncStatusKey, txIndexTailKey,
+				fastTxLookupLimitKey, uncleanShutdownKey, badBlockKey,
 			} {
 				if bytes.Equal(key, meta) {
 					metadata.Add(size)
@@ -398,8 +438,8 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		}
 	}
 	// Inspect append-only file store then.
-	ancientSizes := []*common.StorageSize{&ancientHeadersSize, &ancientBodiesSize, &ancientReceiptsSize, &ancientHashesSize, &ancientTdsSize}
-	for i, category := range []string{freezerHeaderTable, freezerBodiesTable, freezerReceiptTable, freezerHashTable, freezerDifficultyTable} {
+	ancientSizes := []*common.StorageSize{&ancientHeadersSize, &ancientBodiesSize, &ancientReceiptsSize, &ancientHashesSize, &ancientTdsSize, &ancientRandomSize, &ancientSnarkSize}
+	for i, category := range []string{freezerHeaderTable, freezerBodiesTable, freezerReceiptTable, freezerHashTable, freezerDifficultyTable, freezerRandomnessTable, freezerEpochSnarkDataTable} {
 		if size, err := db.AncientSize(category); err == nil {
 			*ancientSizes[i] += common.StorageSize(size)
 			total += common.StorageSize(size)
@@ -426,12 +466,21 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		{"Key-Value store", "Account snapshot", accountSnaps.Size(), accountSnaps.Count()},
 		{"Key-Value store", "Storage snapshot", storageSnaps.Size(), storageSnaps.Count()},
 		{"Key-Value store", "Clique snapshots", cliqueSnaps.Size(), cliqueSnaps.Count()},
+		{"Key-Value store", "Istanbul snapshots", istanbulSnaps.Size(), istanbulSnaps.Count()},
+		{"Key-Value store", "Epoch uptimes", uptimes.Size(), uptimes.Count()},
+		{"Key-Value store", "Epoch boundaries", epochBounds.Size(), epochBounds.Count()},
+		{"Key-Value store", "Randomness commitments", randomness.Size(), randomness.Count()},
+		{"Key-Value store", "Foreign chain data", chainsData.Size(), chainsData.Count()},
+		{"Key-Value store", "Double sign evidence", doubleSigns.Size(), doubleSigns.Count()},
 		{"Key-Value store", "Singleton metadata", metadata.Size(), metadata.Count()},
+		{"Key-Value store", "Unaccounted", unaccounted.Size(), unaccounted.Count()},
 		{"Ancient store", "Headers", ancientHeadersSize.String(), ancients.String()},
 		{"Ancient store", "Bodies", ancientBodiesSize.String(), ancients.String()},
 		{"Ancient store", "Receipt lists", ancientReceiptsSize.String(), ancients.String()},
 		{"Ancient store", "Difficulties", ancientTdsSize.String(), ancients.String()},
 		{"Ancient store", "Block number->hash", ancientHashesSize.String(), ancients.String()},
+		{"Ancient store", "Randomness", ancientRandomSize.String(), ancients.String()},
+		{"Ancient store", "Epoch SNARK data", ancientSnarkSize.String(), ancients.String()},
 		{"Light client", "CHT trie nodes", chtTrieNodes.Size(), chtTrieNodes.Count()},
 		{"Light client", "Bloom trie nodes", bloomTrieNodes.Size(), bloomTrieNodes.Count()},
 	}
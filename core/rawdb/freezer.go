@@ -300,8 +300,13 @@ func (f *freezer) Sync() error {
 	return nil
 }
 
-// repair truncates all data tables to the same length.
+// repair truncates all data tables to the same length. The tables derived from
+// the block bodies are first rebuilt if they lag behind the bodies table, which
+// is the case for databases frozen before those tables were introduced.
 func (f *freezer) repair() error {
+	if err := f.backfillBodyTables(); err != nil {
+		return err
+	}
 	min := uint64(math.MaxUint64)
 	for _, table := range f.tables {
 		items := atomic.LoadUint64(&table.items)
@@ -318,6 +323,71 @@ func (f *freezer) repair() error {
 	return nil
 }
 
+// backfillBodyTables fills the randomness and epoch SNARK data tables up to the
+// length of the bodies table by splitting the frozen block bodies.
+func (f *freezer) backfillBodyTables() error {
+	var (
+		bodies     = f.tables[freezerBodiesTable]
+		randomness = f.tables[freezerRandomnessTable]
+		snarkData  = f.tables[freezerEpochSnarkDataTable]
+	)
+	if bodies == nil || randomness == nil || snarkData == nil {
+		return nil
+	}
+	head := atomic.LoadUint64(&bodies.items)
+	next := atomic.LoadUint64(&randomness.items)
+	if items := atomic.LoadUint64(&snarkData.items); items < next {
+		next = items
+	}
+	if next >= head {
+		return nil
+	}
+	// Both tables have to continue from the same item.
+	for _, table := range []*freezerTable{randomness, snarkData} {
+		if err := table.truncate(next); err != nil {
+			return err
+		}
+	}
+	log.Info("Migrating ancient block bodies", "from", next, "to", head)
+
+	var (
+		randomnessBatch = randomness.newBatch()
+		snarkDataBatch  = snarkData.newBatch()
+		start           = time.Now()
+		logged          = start
+	)
+	for next < head {
+		items, err := bodies.RetrieveItems(next, head-next, ancientRangeReadBytes)
+		if err != nil {
+			return err
+		}
+		for _, body := range items {
+			blobs, err := splitBodyRLP(body)
+			if err != nil {
+				return fmt.Errorf("invalid ancient body #%d: %v", next, err)
+			}
+			if err := randomnessBatch.AppendRaw(next, blobs[bodyRandomnessField]); err != nil {
+				return err
+			}
+			if err := snarkDataBatch.AppendRaw(next, blobs[bodyEpochSnarkDataField]); err != nil {
+				return err
+			}
+			next++
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Migrating ancient block bodies", "number", next, "head", head, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	for _, batch := range []*freezerTableBatch{randomnessBatch, snarkDataBatch} {
+		if err := batch.commit(); err != nil {
+			return err
+		}
+	}
+	log.Info("Migrated ancient block bodies", "items", head, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
 // freeze is a background thread that periodically checks the blockchain for any
 // import progress and moves ancient data from the fast database into the freezer.
 //
@@ -513,6 +583,10 @@ func (f *freezer) freezeRange(nfdb *nofreezedb, number, limit uint64) (hashes []
 			if len(td) == 0 {
 				return fmt.Errorf("total difficulty missing, can't freeze block %d", number)
 			}
+			fields, err := splitBodyRLP(body)
+			if err != nil {
+				return fmt.Errorf("invalid block body, can't freeze block %d: %v", number, err)
+			}
 
 			// Write to the batch.
 			if err := op.AppendRaw(freezerHashTable, number, hash[:]); err != nil {
@@ -530,6 +604,12 @@ func (f *freezer) freezeRange(nfdb *nofreezedb, number, limit uint64) (hashes []
 			if err := op.AppendRaw(freezerDifficultyTable, number, td); err != nil {
 				return fmt.Errorf("can't write td to freezer: %v", err)
 			}
+			if err := op.AppendRaw(freezerRandomnessTable, number, fields[bodyRandomnessField]); err != nil {
+				return fmt.Errorf("can't write randomness to freezer: %v", err)
+			}
+			if err := op.AppendRaw(freezerEpochSnarkDataTable, number, fields[bodyEpochSnarkDataField]); err != nil {
+				return fmt.Errorf("can't write epoch snark data to freezer: %v", err)
+			}
 
 			hashes = append(hashes, hash)
 		}
@@ -0,0 +1,154 @@
+package rawdb
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/mapprotocol/atlas/core/types"
+)
+
+// ancientStore returns the freezer backing db, or errNotSupported if db has no
+// ancient store.
+func ancientStore(db ethdb.Database) (*freezer, error) {
+	frdb, ok := db.(*freezerdb)
+	if !ok {
+		return nil, errNotSupported
+	}
+	return frdb.AncientStore.(*freezer), nil
+}
+
+// ValidateAncients checks the consistency of the ancient store of db with
+// itself and with the key-value store:
+//
+// - all the freezer tables must hold the same number of items,
+// - the hash of every frozen header must match the hash table,
+// - the key-value store must continue the chain where the freezer left off.
+//
+// It returns the number of leading ancient items that are fully consistent
+// along with the first inconsistency found, or nil if there is none.
+func ValidateAncients(db ethdb.Database) (uint64, error) {
+	f, err := ancientStore(db)
+	if err != nil {
+		return 0, err
+	}
+	f.writeLock.Lock()
+	defer f.writeLock.Unlock()
+
+	return f.validate(db)
+}
+
+// RepairAncients truncates all the freezer tables of db to the last fully
+// consistent item reported by ValidateAncients. It returns the number of items
+// left in the freezer, along with any inconsistency that truncation cannot fix.
+func RepairAncients(db ethdb.Database) (uint64, error) {
+	f, err := ancientStore(db)
+	if err != nil {
+		return 0, err
+	}
+	if f.readonly {
+		return 0, errReadOnly
+	}
+	f.writeLock.Lock()
+	defer f.writeLock.Unlock()
+
+	valid, err := f.validate(db)
+	if err == nil {
+		return valid, nil
+	}
+	log.Warn("Repairing ancient database", "items", valid, "err", err)
+	for name, table := range f.tables {
+		if err := table.truncate(valid); err != nil {
+			return valid, fmt.Errorf("failed to truncate table %s: %v", name, err)
+		}
+	}
+	atomic.StoreUint64(&f.frozen, valid)
+	return f.validate(db)
+}
+
+// validate implements ValidateAncients. The caller must hold the write lock.
+func (f *freezer) validate(db ethdb.KeyValueReader) (uint64, error) {
+	// All tables have to be of the same length
+	names := make([]string, 0, len(f.tables))
+	for name := range f.tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	valid := uint64(math.MaxUint64)
+	for _, name := range names {
+		if items := atomic.LoadUint64(&f.tables[name].items); items < valid {
+			valid = items
+		}
+	}
+	frozen := atomic.LoadUint64(&f.frozen)
+	if valid > frozen {
+		valid = frozen
+	}
+	var lengthErr error
+	for _, name := range names {
+		if items := atomic.LoadUint64(&f.tables[name].items); items != frozen {
+			lengthErr = fmt.Errorf("ancient table %s has %d items, want %d", name, items, frozen)
+			break
+		}
+	}
+	// Every frozen header has to match its hash, up to the common length
+	var (
+		hashes  = f.tables[freezerHashTable]
+		headers = f.tables[freezerHeaderTable]
+	)
+	for next := uint64(0); next < valid; {
+		hashBlobs, err := hashes.RetrieveItems(next, valid-next, ancientRangeReadBytes)
+		if err != nil {
+			return next, fmt.Errorf("failed to read ancient hash #%d: %v", next, err)
+		}
+		headerBlobs, err := headers.RetrieveItems(next, uint64(len(hashBlobs)), ancientRangeReadBytes)
+		if err != nil {
+			return next, fmt.Errorf("failed to read ancient header #%d: %v", next, err)
+		}
+		for i, header := range headerBlobs {
+			if hash := crypto.Keccak256(header); !bytes.Equal(hash, hashBlobs[i]) {
+				return next, fmt.Errorf("ancient header #%d hash mismatch: have %x, want %x", next, hash, hashBlobs[i])
+			}
+			next++
+		}
+	}
+	if lengthErr != nil {
+		return valid, lengthErr
+	}
+	// The key-value store has to continue where the freezer left off
+	if frozen == 0 {
+		return frozen, nil
+	}
+	head := ReadHeaderNumber(db, ReadHeadHeaderHash(db))
+	if head == nil || *head < frozen {
+		return frozen, nil
+	}
+	blob, _ := db.Get(headerHashKey(frozen))
+	if len(blob) == 0 {
+		return frozen, fmt.Errorf("gap (#%d) in the chain between ancients and key-value store", frozen)
+	}
+	data, _ := db.Get(headerKey(frozen, common.BytesToHash(blob)))
+	if len(data) == 0 {
+		return frozen, fmt.Errorf("missing header #%d [%x] after the ancients", frozen, blob)
+	}
+	parent, err := hashes.Retrieve(frozen - 1)
+	if err != nil {
+		return frozen, fmt.Errorf("failed to read ancient hash #%d: %v", frozen-1, err)
+	}
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(data, header); err != nil {
+		return frozen, fmt.Errorf("invalid header #%d [%x] after the ancients: %v", frozen, blob, err)
+	}
+	if !bytes.Equal(header.ParentHash.Bytes(), parent) {
+		return frozen, fmt.Errorf("header #%d parent mismatch: have %x, want %x (ancients)", frozen, header.ParentHash, parent)
+	}
+	return frozen, nil
+}
@@ -0,0 +1,95 @@
+package rawdb
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/mapprotocol/atlas/core/types"
+)
+
+func TestValidateRepairAncients(t *testing.T) {
+	frdir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temp freezer dir: %v", err)
+	}
+	defer os.RemoveAll(frdir)
+
+	db, err := NewDatabaseWithFreezer(NewMemoryDatabase(), frdir, "", false)
+	if err != nil {
+		t.Fatalf("failed to create database with ancient backend: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := ValidateAncients(NewMemoryDatabase()); err != errNotSupported {
+		t.Fatalf("validation without freezer: have %v, want %v", err, errNotSupported)
+	}
+	blocks := makeTestBlocks(10, 1)
+	if _, err := WriteAncientBlocks(db, blocks, makeTestReceipts(10, 1), big.NewInt(1)); err != nil {
+		t.Fatalf("failed to write ancient blocks: %v", err)
+	}
+	if valid, err := ValidateAncients(db); err != nil || valid != 10 {
+		t.Fatalf("consistent ancients: have (%d, %v), want (10, nil)", valid, err)
+	}
+	f := db.(*freezerdb).AncientStore.(*freezer)
+
+	// Truncate a single table, the others have to follow it
+	if err := f.tables[freezerBodiesTable].truncate(6); err != nil {
+		t.Fatalf("failed to truncate bodies: %v", err)
+	}
+	if valid, err := ValidateAncients(db); err == nil || valid != 6 {
+		t.Fatalf("truncated table: have (%d, %v), want (6, error)", valid, err)
+	}
+	if valid, err := RepairAncients(db); err != nil || valid != 6 {
+		t.Fatalf("repair of truncated table: have (%d, %v), want (6, nil)", valid, err)
+	}
+	if frozen, _ := db.Ancients(); frozen != 6 {
+		t.Fatalf("frozen items mismatch: have %d, want 6", frozen)
+	}
+	for name, table := range f.tables {
+		if table.items != 6 {
+			t.Errorf("table %s items mismatch: have %d, want 6", name, table.items)
+		}
+	}
+	// Replace a frozen hash, everything from it on has to be dropped
+	hashes := f.tables[freezerHashTable]
+	if err := hashes.truncate(4); err != nil {
+		t.Fatalf("failed to truncate hashes: %v", err)
+	}
+	batch := hashes.newBatch()
+	batch.AppendRaw(4, common.Hash{0xff}.Bytes())
+	batch.AppendRaw(5, blocks[5].Hash().Bytes())
+	if err := batch.commit(); err != nil {
+		t.Fatalf("failed to append hashes: %v", err)
+	}
+	if valid, err := ValidateAncients(db); err == nil || !strings.Contains(err.Error(), "#4") || valid != 4 {
+		t.Fatalf("corrupted hash: have (%d, %v), want (4, error on #4)", valid, err)
+	}
+	if valid, err := RepairAncients(db); err != nil || valid != 4 {
+		t.Fatalf("repair of corrupted hash: have (%d, %v), want (4, nil)", valid, err)
+	}
+	if ReadCanonicalHash(db, 3) != blocks[3].Hash() || ReadCanonicalHash(db, 4) != (common.Hash{}) {
+		t.Fatalf("ancient chain mismatch after repair")
+	}
+	// A key-value chain not extending the ancients can't be repaired by truncation
+	header := &types.Header{Number: big.NewInt(4), ParentHash: common.Hash{0xee}}
+	WriteHeader(db, header)
+	WriteCanonicalHash(db, header.Hash(), 4)
+	WriteHeadHeaderHash(db, header.Hash())
+	if valid, err := ValidateAncients(db); err == nil || valid != 4 {
+		t.Fatalf("disconnected key-value chain: have (%d, %v), want (4, error)", valid, err)
+	}
+	if _, err := RepairAncients(db); err == nil {
+		t.Fatalf("repair of disconnected key-value chain succeeded")
+	}
+	header.ParentHash = blocks[3].Hash()
+	WriteHeader(db, header)
+	WriteCanonicalHash(db, header.Hash(), 4)
+	WriteHeadHeaderHash(db, header.Hash())
+	if valid, err := ValidateAncients(db); err != nil || valid != 4 {
+		t.Fatalf("connected key-value chain: have (%d, %v), want (4, nil)", valid, err)
+	}
+}
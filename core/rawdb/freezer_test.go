@@ -30,6 +30,8 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/stretchr/testify/require"
+
+	"github.com/mapprotocol/atlas/core/types"
 )
 
 var freezerTestTableDef = map[string]bool{"test": true}
@@ -253,6 +255,66 @@ func TestFreezerConcurrentModifyTruncate(t *testing.T) {
 	}
 }
 
+// Tests that opening a chain freezer created before the randomness and epoch
+// SNARK data tables existed fills them from the frozen block bodies.
+func TestFreezerBodyTablesMigration(t *testing.T) {
+	t.Parallel()
+
+	legacyTables := make(map[string]bool)
+	for kind, disableSnappy := range FreezerNoSnappy {
+		if kind != freezerRandomnessTable && kind != freezerEpochSnarkDataTable {
+			legacyTables[kind] = disableSnappy
+		}
+	}
+	f, dir := newFreezerForTesting(t, legacyTables)
+	defer os.RemoveAll(dir)
+
+	blocks := makeBodyFieldTestBlocks(20)
+	_, err := f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for _, block := range blocks {
+			num := block.NumberU64()
+			require.NoError(t, op.AppendRaw(freezerHashTable, num, block.Hash().Bytes()))
+			require.NoError(t, op.Append(freezerHeaderTable, num, block.Header()))
+			require.NoError(t, op.Append(freezerBodiesTable, num, block.Body()))
+			require.NoError(t, op.Append(freezerReceiptTable, num, []*types.ReceiptForStorage{}))
+			require.NoError(t, op.Append(freezerDifficultyTable, num, big.NewInt(1)))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	f.Close()
+
+	f, err = newFreezer(dir, "", false, 2049, FreezerNoSnappy)
+	if err != nil {
+		t.Fatalf("can't reopen freezer with the new tables: %v", err)
+	}
+	defer f.Close()
+
+	checkAncientCount(t, f, freezerBodiesTable, uint64(len(blocks)))
+	checkAncientCount(t, f, freezerRandomnessTable, uint64(len(blocks)))
+	checkAncientCount(t, f, freezerEpochSnarkDataTable, uint64(len(blocks)))
+	for _, block := range blocks {
+		num := block.NumberU64()
+		have, _ := f.Ancient(freezerRandomnessTable, num)
+		want, _ := rlp.EncodeToBytes(block.Randomness())
+		if !bytes.Equal(have, want) {
+			t.Errorf("block #%d: wrong randomness %x, want %x", num, have, want)
+		}
+		have, _ = f.Ancient(freezerEpochSnarkDataTable, num)
+		want, _ = rlp.EncodeToBytes(block.EpochSnarkData())
+		if !bytes.Equal(have, want) {
+			t.Errorf("block #%d: wrong epoch snark data %x, want %x", num, have, want)
+		}
+	}
+	// The migrated tables take part in regular writes.
+	next := makeBodyFieldTestBlocks(21)[20]
+	_, err = f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		return writeAncientBlock(op, next, next.Header(), nil, big.NewInt(1))
+	})
+	require.NoError(t, err)
+	checkAncientCount(t, f, freezerEpochSnarkDataTable, uint64(len(blocks)+1))
+}
+
 func newFreezerForTesting(t *testing.T, tables map[string]bool) (*freezer, string) {
 	t.Helper()
 
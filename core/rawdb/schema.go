@@ -23,6 +23,8 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/mapprotocol/atlas/chains"
 )
 
 // The fields below define the low level database schema prefixing.
@@ -38,6 +40,12 @@ var (
 
 	lastBlockKey = []byte("LastBlockIndex")
 
+	// headBlockMetaKey tracks a summary of the latest known full block.
+	headBlockMetaKey = []byte("LastBlockMeta")
+
+	// finalizedBlockKey tracks the latest known finalized block's hash.
+	finalizedBlockKey = []byte("LastFinalized")
+
 	// headFastBlockKey tracks the latest known incomplete block's hash duirng fast sync.
 	headFastBlockKey = []byte("LastFast")
 
@@ -92,8 +100,15 @@ var (
 	SnapshotStoragePrefix = []byte("o") // SnapshotStoragePrefix + account hash + storage hash -> storage trie value
 	CodePrefix            = []byte("c") // CodePrefix + code hash -> account code
 
-	preimagePrefix = []byte("secure-key-")      // preimagePrefix + hash -> preimage
-	configPrefix   = []byte("ethereum-config-") // config prefix for the db
+	preimagePrefix           = []byte("secure-key-")      // preimagePrefix + hash -> preimage
+	configPrefix             = []byte("ethereum-config-") // config prefix for the db
+	uptimePrefix             = []byte("uptime")           // uptimePrefix + epoch (uint64 big endian) -> accumulated uptime
+	epochUptimeHeadPrefix    = []byte("epoch-uptime-h")   // epochUptimeHeadPrefix + epoch (uint64 big endian) -> last block in the accumulated uptime
+	istanbulSnapshotPrefix   = []byte("ist-snapshot")     // istanbulSnapshotPrefix + epoch (uint64 big endian) -> validator set snapshot
+	doubleSignEvidencePrefix = []byte("double-sign-")     // doubleSignEvidencePrefix + epoch (uint64 big endian) + evidence hash -> double signing evidence
+	epochBoundaryPrefix      = []byte("epoch-boundary-")  // epochBoundaryPrefix + epoch (uint64 big endian) -> last block of the epoch
+	chainsPrefix             = []byte("chains-")          // chainsPrefix + chain type (uint64 big endian) + native key -> foreign chain data
+	configHistoryPrefix      = []byte("config-history-")  // configHistoryPrefix + genesis hash -> chain configs by activation height
 
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
 	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
@@ -117,16 +132,27 @@ const (
 
 	// freezerDifficultyTable indicates the name of the freezer total difficulty table.
 	freezerDifficultyTable = "diffs"
+
+	// freezerRandomnessTable indicates the name of the freezer block randomness table.
+	// Its items are a copy of the randomness field of the frozen block bodies.
+	freezerRandomnessTable = "randomness"
+
+	// freezerEpochSnarkDataTable indicates the name of the freezer epoch SNARK data
+	// table. Its items are a copy of the epoch SNARK data field of the frozen block
+	// bodies.
+	freezerEpochSnarkDataTable = "epochsnark"
 )
 
 // FreezerNoSnappy configures whether compression is disabled for the ancient-tables.
-// Hashes and difficulties don't compress well.
+// Hashes, difficulties and randomness don't compress well.
 var FreezerNoSnappy = map[string]bool{
-	freezerHeaderTable:     false,
-	freezerHashTable:       true,
-	freezerBodiesTable:     false,
-	freezerReceiptTable:    false,
-	freezerDifficultyTable: true,
+	freezerHeaderTable:         false,
+	freezerHashTable:           true,
+	freezerBodiesTable:         false,
+	freezerReceiptTable:        false,
+	freezerDifficultyTable:     true,
+	freezerRandomnessTable:     true,
+	freezerEpochSnarkDataTable: false,
 }
 
 // LegacyTxLookupEntry is the legacy TxLookupEntry definition with some unnecessary
@@ -233,60 +259,35 @@ func configKey(hash common.Hash) []byte {
 	return append(configPrefix, hash.Bytes()...)
 }
 
-//--------------- mark ---------
-//type ChainType uint64
-//
-//func (t ChainType) toByte() []byte {
-//	b := make([]byte, 8)
-//	binary.BigEndian.PutUint64(b, uint64(t))
-//	return b
-//}
-//func (t ChainType) len() int {
-//	return 8
-//}
-//
-//// change the visit Number by append chain mark   mark + number
-//func (t ChainType) setTypeNumber(n uint64) []byte {
-//	return append(t.toByte(), encodeBlockNumber(n)...)
-//}
-//
-////change the visit key by append chain mark      key  + mark
-//func (t ChainType) setTypeKey(b []byte) []byte {
-//	return append(b, t.toByte()...)
-//}
-//
-////  key  + mark + number
-//func (t ChainType) setTypeKeyNum(b []byte, n uint64) []byte {
-//	return append(append(b, t.toByte()...), encodeBlockNumber(n)...)
-//}
-//
-////--------------- chains ---------
-//// headerKey = headerPrefix + num (uint64 big endian) + hash
-//func headerKeyChains(t ChainType, number uint64, hash common.Hash) []byte {
-//	return append(append(headerPrefix, t.setTypeNumber(number)...), hash.Bytes()...)
-//}
-//
-//// headerTDKey = headerPrefix + num (uint64 big endian) + hash + headerTDSuffix
-//func headerTDKeyChains(t ChainType, number uint64, hash common.Hash) []byte {
-//	return append(headerKeyChains(t, number, hash), headerTDSuffix...)
-//}
-//
-//// headerHashKey = headerPrefix + num (uint64 big endian) + headerHashSuffix
-//func headerHashKeyChains(t ChainType, number uint64) []byte {
-//	return append(append(headerPrefix, t.setTypeNumber(number)...), headerHashSuffix...)
-//}
-//
-//// headerNumberKey = headerNumberPrefix + hash
-//func headerNumberKeyChains(t ChainType, hash common.Hash) []byte {
-//	return append(t.setTypeKey(headerNumberPrefix), hash.Bytes()...)
-//}
+// configHistoryKey = configHistoryPrefix + hash
+func configHistoryKey(hash common.Hash) []byte {
+	return append(append([]byte{}, configHistoryPrefix...), hash.Bytes()...)
+}
 
-// blockBodyKey = blockBodyPrefix + num (uint64 big endian) + hash
-//func blockBodyKeyChains(t ChainType, number uint64, hash common.Hash) []byte {
-//	return append(append(blockBodyPrefix, t.setTypeNumber(number)...), hash.Bytes()...)
-//}
-//
-//// blockReceiptsKey = blockReceiptsPrefix + num (uint64 big endian) + hash
-//func blockReceiptsKeyChains(t ChainType, number uint64, hash common.Hash) []byte {
-//	return append(append(blockReceiptsPrefix, t.setTypeNumber(number)...), hash.Bytes()...)
-//}
+// chainsKey = chainsPrefix + chain type (uint64 big endian) + key
+func chainsKey(chain chains.ChainType, key []byte) []byte {
+	out := make([]byte, 0, len(chainsPrefix)+8+len(key))
+	out = append(out, chainsPrefix...)
+	out = append(out, encodeBlockNumber(uint64(chain))...)
+	return append(out, key...)
+}
+
+// headerKeyChains = chainsKey(chain, headerKey)
+func headerKeyChains(chain chains.ChainType, number uint64, hash common.Hash) []byte {
+	return chainsKey(chain, headerKey(number, hash))
+}
+
+// headerTDKeyChains = chainsKey(chain, headerTDKey)
+func headerTDKeyChains(chain chains.ChainType, number uint64, hash common.Hash) []byte {
+	return chainsKey(chain, headerTDKey(number, hash))
+}
+
+// headerHashKeyChains = chainsKey(chain, headerHashKey)
+func headerHashKeyChains(chain chains.ChainType, number uint64) []byte {
+	return chainsKey(chain, headerHashKey(number))
+}
+
+// headerNumberKeyChains = chainsKey(chain, headerNumberKey)
+func headerNumberKeyChains(chain chains.ChainType, hash common.Hash) []byte {
+	return chainsKey(chain, headerNumberKey(hash))
+}
@@ -0,0 +1,34 @@
+package rawdb
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// schemaMigrations holds the steps upgrading the database to a version from
+// the previous one, by the version they upgrade to. A format change of the
+// stored data registers its step here along with the new version, so that
+// existing databases are converted instead of being misread.
+var schemaMigrations = map[uint64]func(db ethdb.Database) error{}
+
+// MigrateSchema upgrades the database from version from to version to by
+// running the registered steps in order. The version is written after every
+// step, so an interrupted migration resumes from the last completed one.
+// Versions without a step need no conversion.
+func MigrateSchema(db ethdb.Database, from, to uint64) error {
+	if from > to {
+		return fmt.Errorf("database version %d is newer than %d", from, to)
+	}
+	for version := from + 1; version <= to; version++ {
+		if migrate, ok := schemaMigrations[version]; ok {
+			log.Info("Migrating database schema", "from", version-1, "to", version)
+			if err := migrate(db); err != nil {
+				return fmt.Errorf("failed to migrate database to version %d: %v", version, err)
+			}
+		}
+		WriteDatabaseVersion(db, version)
+	}
+	return nil
+}
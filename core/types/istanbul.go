@@ -19,6 +19,8 @@ var (
 	// ErrInvalidIstanbulHeaderExtra is returned if the length of extra-data is less than 32 bytes
 	ErrInvalidIstanbulHeaderExtra = errors.New("invalid istanbul header extra-data")
 	EmptyBlockSeal                = []byte{}
+
+	errEmptyCompactAddedValidators = errors.New("empty compact added validators in istanbul extra")
 )
 
 // ExtractIstanbulExtra extracts all values of the IstanbulExtra from the header. It returns an
@@ -77,11 +79,30 @@ type IstanbulExtra struct {
 	AggregatedSeal IstanbulAggregatedSeal
 	// ParentAggregatedSeal contains and aggregated BLS signature for the previous block.
 	ParentAggregatedSeal IstanbulAggregatedSeal
+	// CompactAddedValidators are the validators added in the block by a compact
+	// validator set diff, replacing the three AddedValidators lists. It is only
+	// encoded when not empty, so the extra of older headers is unchanged.
+	CompactAddedValidators []CompactAddedValidator
+}
+
+// CompactAddedValidator is a validator added by a compact validator set diff.
+// KeyIndex is zero when its BLS keys are given, otherwise the keys are left out
+// and are the ones of the validator at KeyIndex-1 in the parent validator set.
+type CompactAddedValidator struct {
+	Address        common.Address
+	KeyIndex       uint64
+	BLSPublicKey   []byte
+	BLSG1PublicKey []byte
+}
+
+// HasValSetDiff returns whether the extra adds or removes validators.
+func (ist *IstanbulExtra) HasValSetDiff() bool {
+	return len(ist.AddedValidators) > 0 || len(ist.CompactAddedValidators) > 0 || ist.RemovedValidators.BitLen() > 0
 }
 
 // EncodeRLP serializes ist into the Ethereum RLP format.
 func (ist *IstanbulExtra) EncodeRLP(w io.Writer) error {
-	return rlp.Encode(w, []interface{}{
+	fields := []interface{}{
 		ist.AddedValidators,
 		ist.AddedValidatorsPublicKeys,
 		ist.AddedValidatorsG1PublicKeys,
@@ -89,7 +110,11 @@ func (ist *IstanbulExtra) EncodeRLP(w io.Writer) error {
 		ist.Seal,
 		&ist.AggregatedSeal,
 		&ist.ParentAggregatedSeal,
-	})
+	}
+	if len(ist.CompactAddedValidators) > 0 {
+		fields = append(fields, ist.CompactAddedValidators)
+	}
+	return rlp.Encode(w, fields)
 }
 
 // DecodeRLP implements rlp.Decoder, and load the istanbul fields from a RLP stream.
@@ -102,11 +127,18 @@ func (ist *IstanbulExtra) DecodeRLP(s *rlp.Stream) error {
 		Seal                        []byte
 		AggregatedSeal              IstanbulAggregatedSeal
 		ParentAggregatedSeal        IstanbulAggregatedSeal
+		CompactAddedValidators      []CompactAddedValidator `rlp:"optional"`
 	}
 	if err := s.Decode(&istanbulExtra); err != nil {
 		return err
 	}
+	// An empty compact list is never encoded, accepting it would give the same
+	// extra two encodings
+	if istanbulExtra.CompactAddedValidators != nil && len(istanbulExtra.CompactAddedValidators) == 0 {
+		return errEmptyCompactAddedValidators
+	}
 	ist.AddedValidators, ist.AddedValidatorsPublicKeys, ist.AddedValidatorsG1PublicKeys, ist.RemovedValidators, ist.Seal, ist.AggregatedSeal, ist.ParentAggregatedSeal = istanbulExtra.AddedValidators, istanbulExtra.AddedValidatorsPublicKeys, istanbulExtra.AddedValidatorsG1PublicKeys, istanbulExtra.RemovedValidators, istanbulExtra.Seal, istanbulExtra.AggregatedSeal, istanbulExtra.ParentAggregatedSeal
+	ist.CompactAddedValidators = istanbulExtra.CompactAddedValidators
 	return nil
 }
 
@@ -22,6 +22,8 @@ import (
 	"fmt"
 	"io"
 	"math/big"
+	"runtime"
+	"sync"
 	"unsafe"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -405,18 +407,82 @@ func (rs Receipts) EncodeIndex(i int, w *bytes.Buffer) {
 	}
 }
 
+// parallelDeriveThreshold is the number of receipts above which DeriveFields
+// spreads the work over multiple goroutines.
+const parallelDeriveThreshold = 256
+
 // DeriveFields fills the receipts with their computed fields based on consensus
 // data and contextual infos like containing block and transactions.
 func (rs Receipts) DeriveFields(config *params.ChainConfig, hash common.Hash, number uint64, txs Transactions) error {
 	signer := MakeSigner(config, new(big.Int).SetUint64(number))
 
-	logIndex := uint(0)
 	// The receipts may include an additional "block finalization" receipt (only IBFT)
 	if !(len(txs) == len(rs) || len(txs)+1 == len(rs)) {
 		return errors.New("transaction and receipt count mismatch")
 	}
+	var logIndex uint
+	if len(txs) <= parallelDeriveThreshold {
+		logIndex = rs.deriveRange(signer, hash, number, txs, 0, len(txs), 0)
+	} else {
+		logIndex = rs.deriveParallel(signer, hash, number, txs)
+	}
+
+	// Handle block finalization receipt (only IBFT), which has the block hash
+	// as transaction hash
+	if len(txs)+1 == len(rs) {
+		j := len(txs)
+		rs[j].TxHash = hash
+		rs[j].BlockHash = hash
+		rs[j].BlockNumber = new(big.Int).SetUint64(number)
+		rs[j].TransactionIndex = uint(j)
+		rs[j].GasUsed = 0
+		for k := 0; k < len(rs[j].Logs); k++ {
+			rs[j].Logs[k].BlockNumber = number
+			rs[j].Logs[k].BlockHash = hash
+			rs[j].Logs[k].TxHash = hash
+			rs[j].Logs[k].TxIndex = uint(j)
+			rs[j].Logs[k].Index = logIndex
+			logIndex++
+		}
+	}
+	return nil
+}
 
-	for i := 0; i < len(txs); i++ {
+// deriveParallel derives the fields of the receipts of txs, splitting them in
+// chunks derived concurrently. The log index each chunk starts at is counted
+// upfront, so the result is the same as deriving them in order. It returns the
+// index of the next log.
+func (rs Receipts) deriveParallel(signer Signer, hash common.Hash, number uint64, txs Transactions) uint {
+	workers := runtime.NumCPU()
+	size := (len(txs) + workers - 1) / workers
+
+	var (
+		wg       sync.WaitGroup
+		logIndex uint
+	)
+	for from := 0; from < len(txs); from += size {
+		to := from + size
+		if to > len(txs) {
+			to = len(txs)
+		}
+		wg.Add(1)
+		go func(from, to int, logIndex uint) {
+			defer wg.Done()
+			rs.deriveRange(signer, hash, number, txs, from, to, logIndex)
+		}(from, to, logIndex)
+
+		for i := from; i < to; i++ {
+			logIndex += uint(len(rs[i].Logs))
+		}
+	}
+	wg.Wait()
+	return logIndex
+}
+
+// deriveRange derives the fields of the receipts of txs[start:end], numbering
+// their logs from logIndex. It returns the index of the next log.
+func (rs Receipts) deriveRange(signer Signer, hash common.Hash, number uint64, txs Transactions, start, end int, logIndex uint) uint {
+	for i := start; i < end; i++ {
 		// The transaction type and hash can be retrieved from the transaction itself
 		rs[i].Type = txs[i].Type()
 		rs[i].TxHash = txs[i].Hash()
@@ -448,18 +514,5 @@ func (rs Receipts) DeriveFields(config *params.ChainConfig, hash common.Hash, nu
 			logIndex++
 		}
 	}
-
-	// Handle block finalization receipt (only IBFT)
-	if len(txs)+1 == len(rs) {
-		j := len(txs)
-		for k := 0; k < len(rs[j].Logs); k++ {
-			rs[j].Logs[k].BlockNumber = number
-			rs[j].Logs[k].BlockHash = hash
-			rs[j].Logs[k].TxHash = hash
-			rs[j].Logs[k].TxIndex = uint(j)
-			rs[j].Logs[k].Index = logIndex
-			logIndex++
-		}
-	}
-	return nil
+	return logIndex
 }
@@ -338,6 +338,73 @@ func TestDeriveFields(t *testing.T) {
 	}
 }
 
+// makeDeriveTestBlock creates n transactions, every third a contract creation,
+// and their receipts with a few logs each.
+func makeDeriveTestBlock(n int) (Transactions, Receipts) {
+	txs := make(Transactions, n)
+	receipts := make(Receipts, n)
+	for i := 0; i < n; i++ {
+		if i%3 == 2 {
+			txs[i] = NewContractCreation(uint64(i), big.NewInt(0), 100000, big.NewInt(1), nil)
+		} else {
+			txs[i] = NewTransaction(uint64(i), common.BytesToAddress([]byte{byte(i)}), big.NewInt(1), 21000, big.NewInt(1), nil)
+		}
+		receipts[i] = &Receipt{CumulativeGasUsed: uint64(21000 * (i + 1)), Logs: make([]*Log, i%4)}
+		for j := range receipts[i].Logs {
+			receipts[i].Logs[j] = &Log{Address: common.BytesToAddress([]byte{byte(i), byte(j)})}
+		}
+	}
+	return txs, receipts
+}
+
+// Tests that deriving the fields of many receipts concurrently gives the same
+// result as deriving them in order, block finalization receipt included.
+func TestDeriveFieldsParallel(t *testing.T) {
+	number := uint64(1)
+	hash := common.BytesToHash([]byte{0x03, 0x14})
+
+	txs, receipts := makeDeriveTestBlock(2 * parallelDeriveThreshold)
+	receipts = append(receipts, &Receipt{Logs: []*Log{{}, {}}})
+	if err := receipts.DeriveFields(params.TestChainConfig, hash, number, txs); err != nil {
+		t.Fatalf("DeriveFields(...) = %v, want <nil>", err)
+	}
+	_, want := makeDeriveTestBlock(2 * parallelDeriveThreshold)
+	want = append(want, &Receipt{Logs: []*Log{{}, {}}})
+	signer := MakeSigner(params.TestChainConfig, new(big.Int).SetUint64(number))
+	next := want.deriveRange(signer, hash, number, txs, 0, len(txs), 0)
+	final := want[len(txs)]
+	final.TxHash, final.BlockHash, final.BlockNumber = hash, hash, new(big.Int).SetUint64(number)
+	final.TransactionIndex = uint(len(txs))
+	for k, log := range final.Logs {
+		log.BlockNumber, log.BlockHash, log.TxHash = number, hash, hash
+		log.TxIndex, log.Index = uint(len(txs)), next+uint(k)
+	}
+	if !reflect.DeepEqual(receipts, want) {
+		t.Fatal("concurrently derived receipts differ from the serially derived ones")
+	}
+}
+
+func BenchmarkDeriveFields(b *testing.B) {
+	txs, receipts := makeDeriveTestBlock(500)
+	hash := common.BytesToHash([]byte{0x03, 0x14})
+	signer := MakeSigner(params.TestChainConfig, big.NewInt(1))
+
+	b.Run("serial", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			receipts.deriveRange(signer, hash, 1, txs, 0, len(txs), 0)
+		}
+	})
+	b.Run("parallel", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := receipts.DeriveFields(params.TestChainConfig, hash, 1, txs); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
 // TestTypedReceiptEncodingDecoding reproduces a flaw that existed in the receipt
 // rlp decoder, which failed due to a shadowing error.
 func TestTypedReceiptEncodingDecoding(t *testing.T) {
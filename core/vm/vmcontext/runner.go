@@ -110,3 +110,21 @@ func (sev *SharedEVMRunner) Query(recipient common.Address, input []byte, gas ui
 	ret, _, err = sev.StaticCall(vm.AccountRef(VMAddress), recipient, input, gas)
 	return ret, err
 }
+
+// readOnlyEVMRunner is an EVMRunner which only allows queries, for runners
+// over historical states which must not be modified.
+type readOnlyEVMRunner struct{ vm.EVMRunner }
+
+// NewReadOnlyEVMRunner wraps runner so that Execute and ExecuteFrom fail with
+// vm.ErrWriteProtection.
+func NewReadOnlyEVMRunner(runner vm.EVMRunner) vm.EVMRunner {
+	return &readOnlyEVMRunner{runner}
+}
+
+func (ro *readOnlyEVMRunner) Execute(recipient common.Address, input []byte, gas uint64, value *big.Int) (ret []byte, err error) {
+	return nil, vm.ErrWriteProtection
+}
+
+func (ro *readOnlyEVMRunner) ExecuteFrom(sender, recipient common.Address, input []byte, gas uint64, value *big.Int) (ret []byte, err error) {
+	return nil, vm.ErrWriteProtection
+}
@@ -0,0 +1,167 @@
+// Package client implements typed access to the atlas core contracts over
+// RPC, so that validator and voter operations can be scripted from Go
+// programs instead of the marker command line.
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	ethchain "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/mapprotocol/atlas/accounts/abi"
+	"github.com/mapprotocol/atlas/marker/contract"
+	"github.com/mapprotocol/atlas/marker/env"
+)
+
+// ErrNoSigner is returned when a transaction is attempted on a client
+// created without a Signer.
+var ErrNoSigner = errors.New("client has no signer")
+
+// receiptPollInterval is the interval WaitMined polls for the receipt with.
+const receiptPollInterval = 200 * time.Millisecond
+
+// Contracts holds the addresses the client sends calls to.
+type Contracts struct {
+	Accounts   common.Address
+	LockedGold common.Address
+	Validators common.Address
+	Election   common.Address
+}
+
+// DefaultContracts returns the proxy addresses the core contracts are
+// deployed at in genesis.
+func DefaultContracts() Contracts {
+	return Contracts{
+		Accounts:   env.MustProxyAddressFor("Accounts"),
+		LockedGold: env.MustProxyAddressFor("LockedGold"),
+		Validators: env.MustProxyAddressFor("Validators"),
+		Election:   env.MustProxyAddressFor("Election"),
+	}
+}
+
+// Config holds the optional client settings.
+type Config struct {
+	Contracts Contracts
+	// GasLimit is used for every transaction, zero means estimate it.
+	GasLimit uint64
+	// CallFrom overrides the sender of eth_call queries, it defaults to the
+	// signer's address.
+	CallFrom common.Address
+}
+
+// Client sends typed calls and transactions to the core contracts.
+type Client struct {
+	backend Backend
+	signer  Signer
+	cfg     Config
+}
+
+// New creates a client on top of backend. signer may be nil for read-only
+// use. The genesis contract addresses are used unless cfg sets others.
+func New(backend Backend, signer Signer, cfg *Config) *Client {
+	c := &Client{backend: backend, signer: signer}
+	if cfg != nil {
+		c.cfg = *cfg
+	}
+	if c.cfg.Contracts == (Contracts{}) {
+		c.cfg.Contracts = DefaultContracts()
+	}
+	return c
+}
+
+// callSender returns the address eth_call queries are sent from.
+func (c *Client) callSender() common.Address {
+	if c.cfg.CallFrom != (common.Address{}) || c.signer == nil {
+		return c.cfg.CallFrom
+	}
+	return c.signer.Address()
+}
+
+// call executes method on the contract at to against the latest state and
+// returns the unpacked outputs.
+func (c *Client) call(ctx context.Context, to common.Address, contractABI *abi.ABI, method string, args ...interface{}) ([]interface{}, error) {
+	input, err := contractABI.Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", method, err)
+	}
+	msg := ethchain.CallMsg{From: c.callSender(), To: &to, Data: input}
+	output, err := c.backend.CallContract(ctx, msg, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", method, err)
+	}
+	ret, err := contractABI.Unpack(method, output)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", method, err)
+	}
+	return ret, nil
+}
+
+// transact signs and sends a transaction calling method on the contract at
+// to, and returns its hash without waiting for it to be mined.
+func (c *Client) transact(ctx context.Context, to common.Address, value *big.Int, contractABI *abi.ABI, method string, args ...interface{}) (common.Hash, error) {
+	if c.signer == nil {
+		return common.Hash{}, ErrNoSigner
+	}
+	input, err := contractABI.Pack(method, args...)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("%s: %v", method, err)
+	}
+	from := c.signer.Address()
+	nonce, err := c.backend.PendingNonceAt(ctx, from)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	gasPrice, err := c.backend.SuggestGasPrice(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	gasLimit := c.cfg.GasLimit
+	if gasLimit == 0 {
+		msg := ethchain.CallMsg{From: from, To: &to, GasPrice: gasPrice, Value: value, Data: input}
+		if gasLimit, err = c.backend.EstimateGas(ctx, msg); err != nil {
+			return common.Hash{}, fmt.Errorf("%s: %v", method, err)
+		}
+	}
+	chainID, err := c.backend.ChainID(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	tx, err := c.signer.SignTx(types.NewTransaction(nonce, to, value, gasLimit, gasPrice, input), chainID)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err := c.backend.SendTransaction(ctx, tx); err != nil {
+		return common.Hash{}, fmt.Errorf("%s: %w", method, err)
+	}
+	return tx.Hash(), nil
+}
+
+// WaitMined blocks until the transaction with the given hash is mined or ctx
+// is done, and returns its receipt.
+func (c *Client) WaitMined(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	ticker := time.NewTicker(receiptPollInterval)
+	defer ticker.Stop()
+	for {
+		receipt, err := c.backend.TransactionReceipt(ctx, txHash)
+		if err == nil {
+			return receipt, nil
+		}
+		if !errors.Is(err, ethchain.NotFound) {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func lockedGoldABI() *abi.ABI { return contract.AbiFor("LockedGold") }
+func validatorsABI() *abi.ABI { return contract.AbiFor("Validators") }
+func electionABI() *abi.ABI   { return contract.AbiFor("Election") }
@@ -0,0 +1,207 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	ethchain "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mapprotocol/atlas/accounts/abi"
+)
+
+var testChainID = big.NewInt(211)
+
+// simBackend answers calls to the core contracts from canned method outputs
+// and records the transactions sent to it.
+type simBackend struct {
+	outputs map[string][]interface{} // method name -> return values
+	sent    []*types.Transaction
+}
+
+func (b *simBackend) ChainID(context.Context) (*big.Int, error) { return testChainID, nil }
+
+func (b *simBackend) CallContract(_ context.Context, msg ethchain.CallMsg, _ *big.Int) ([]byte, error) {
+	for _, contractABI := range []*abi.ABI{lockedGoldABI(), validatorsABI(), electionABI()} {
+		method, err := contractABI.MethodById(msg.Data)
+		if err != nil {
+			continue
+		}
+		ret, ok := b.outputs[method.Name]
+		if !ok {
+			return nil, fmt.Errorf("unexpected call to %s", method.Name)
+		}
+		return method.Outputs.Pack(ret...)
+	}
+	return nil, errors.New("unknown method")
+}
+
+func (b *simBackend) PendingNonceAt(context.Context, common.Address) (uint64, error) {
+	return uint64(len(b.sent)), nil
+}
+
+func (b *simBackend) SuggestGasPrice(context.Context) (*big.Int, error) { return big.NewInt(1e9), nil }
+
+func (b *simBackend) EstimateGas(context.Context, ethchain.CallMsg) (uint64, error) {
+	return 100000, nil
+}
+
+func (b *simBackend) SendTransaction(_ context.Context, tx *types.Transaction) error {
+	b.sent = append(b.sent, tx)
+	return nil
+}
+
+func (b *simBackend) TransactionReceipt(_ context.Context, txHash common.Hash) (*types.Receipt, error) {
+	for _, tx := range b.sent {
+		if tx.Hash() == txHash {
+			return &types.Receipt{TxHash: txHash, Status: types.ReceiptStatusSuccessful}, nil
+		}
+	}
+	return nil, ethchain.NotFound
+}
+
+func newTestClient(t *testing.T, backend *simBackend) (*Client, common.Address) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(backend, NewKeySigner(key), nil), crypto.PubkeyToAddress(key.PublicKey)
+}
+
+// checkTx verifies that tx was signed by from and calls method with args on to.
+func checkTx(t *testing.T, tx *types.Transaction, from, to common.Address, contractABI *abi.ABI, method string, args ...interface{}) {
+	t.Helper()
+	sender, err := types.Sender(types.LatestSignerForChainID(testChainID), tx)
+	if err != nil {
+		t.Fatalf("invalid signature: %v", err)
+	}
+	if sender != from {
+		t.Errorf("sender mismatch: have %x, want %x", sender, from)
+	}
+	if *tx.To() != to {
+		t.Errorf("recipient mismatch: have %x, want %x", *tx.To(), to)
+	}
+	want, err := contractABI.Pack(method, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(tx.Data(), want) {
+		t.Errorf("%s input mismatch: have %x, want %x", method, tx.Data(), want)
+	}
+}
+
+func TestLockGold(t *testing.T) {
+	backend := new(simBackend)
+	client, from := newTestClient(t, backend)
+
+	amount := new(big.Int).Mul(big.NewInt(10), big.NewInt(1e18))
+	hash, err := client.LockGold(context.Background(), amount)
+	if err != nil {
+		t.Fatalf("LockGold failed: %v", err)
+	}
+	if len(backend.sent) != 1 {
+		t.Fatalf("sent %d transactions, want 1", len(backend.sent))
+	}
+	tx := backend.sent[0]
+	checkTx(t, tx, from, DefaultContracts().LockedGold, lockedGoldABI(), "lock")
+	if tx.Value().Cmp(amount) != 0 {
+		t.Errorf("value mismatch: have %v, want %v", tx.Value(), amount)
+	}
+	receipt, err := client.WaitMined(context.Background(), hash)
+	if err != nil {
+		t.Fatalf("WaitMined failed: %v", err)
+	}
+	if receipt.TxHash != tx.Hash() {
+		t.Errorf("receipt hash mismatch: have %x, want %x", receipt.TxHash, tx.Hash())
+	}
+}
+
+func TestVote(t *testing.T) {
+	var (
+		a = common.HexToAddress("0x0a")
+		b = common.HexToAddress("0x0b")
+		c = common.HexToAddress("0x0c")
+	)
+	backend := &simBackend{outputs: map[string][]interface{}{
+		"getTotalVotesForEligibleValidators": {
+			[]common.Address{a, b, c},
+			[]*big.Int{big.NewInt(300), big.NewInt(200), big.NewInt(100)},
+		},
+	}}
+	client, from := newTestClient(t, backend)
+
+	// 150 votes move c between a and b.
+	lesser, greater, err := client.VoteLesserGreater(context.Background(), c, big.NewInt(150))
+	if err != nil {
+		t.Fatalf("VoteLesserGreater failed: %v", err)
+	}
+	if lesser != b || greater != a {
+		t.Errorf("lesser/greater mismatch: have %x/%x, want %x/%x", lesser, greater, b, a)
+	}
+	if _, _, err := client.VoteLesserGreater(context.Background(), from, big.NewInt(1)); err != ErrNotEligible {
+		t.Errorf("vote for ineligible validator: have %v, want %v", err, ErrNotEligible)
+	}
+
+	if _, err := client.Vote(context.Background(), c, big.NewInt(150), lesser, greater); err != nil {
+		t.Fatalf("Vote failed: %v", err)
+	}
+	checkTx(t, backend.sent[0], from, DefaultContracts().Election, electionABI(), "vote", c, big.NewInt(150), lesser, greater)
+}
+
+func TestRegisterValidator(t *testing.T) {
+	var (
+		a = common.HexToAddress("0x0a")
+		b = common.HexToAddress("0x0b")
+	)
+	backend := &simBackend{outputs: map[string][]interface{}{
+		"getTotalVotesForEligibleValidators": {
+			[]common.Address{a, b},
+			[]*big.Int{big.NewInt(300), big.NewInt(100)},
+		},
+		"getTotalVotesForValidator": {big.NewInt(200)},
+	}}
+	client, from := newTestClient(t, backend)
+
+	params := ValidatorParams{
+		Commission:     big.NewInt(100000),
+		ECDSAPublicKey: bytes.Repeat([]byte{1}, 64),
+		BLSPublicKey:   bytes.Repeat([]byte{2}, 129),
+		BLSG1PublicKey: bytes.Repeat([]byte{3}, 128),
+		BLSProof:       bytes.Repeat([]byte{4}, 64),
+	}
+	if _, err := client.RegisterValidator(context.Background(), params); err != nil {
+		t.Fatalf("RegisterValidator failed: %v", err)
+	}
+	keys := [][]byte{params.BLSPublicKey, params.BLSG1PublicKey, params.BLSProof, params.ECDSAPublicKey}
+	checkTx(t, backend.sent[0], from, DefaultContracts().Validators, validatorsABI(), "registerValidator", params.Commission, b, a, keys)
+
+	readOnly := New(backend, nil, nil)
+	if _, err := readOnly.RegisterValidator(context.Background(), params); err != ErrNoSigner {
+		t.Errorf("register without signer: have %v, want %v", err, ErrNoSigner)
+	}
+}
+
+func TestUpdateBLSPublicKey(t *testing.T) {
+	backend := new(simBackend)
+	client, from := newTestClient(t, backend)
+
+	params := ValidatorParams{
+		BLSPublicKey:   bytes.Repeat([]byte{2}, 129),
+		BLSG1PublicKey: bytes.Repeat([]byte{3}, 128),
+		BLSProof:       bytes.Repeat([]byte{4}, 64),
+	}
+	if _, err := client.UpdateBLSPublicKey(context.Background(), params); err != nil {
+		t.Fatalf("UpdateBLSPublicKey failed: %v", err)
+	}
+	checkTx(t, backend.sent[0], from, DefaultContracts().Validators, validatorsABI(), "updateBlsPublicKey", params.BLSPublicKey, params.BLSG1PublicKey, params.BLSProof)
+
+	readOnly := New(backend, nil, nil)
+	if _, err := readOnly.UpdateBLSPublicKey(context.Background(), params); err != ErrNoSigner {
+		t.Errorf("update without signer: have %v, want %v", err, ErrNoSigner)
+	}
+}
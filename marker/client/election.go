@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrNotEligible is returned when votes are cast for a validator that is not
+// in the eligible validator list.
+var ErrNotEligible = errors.New("validator is not eligible")
+
+// VoteTotal is the total number of votes received by an eligible validator.
+type VoteTotal struct {
+	Validator common.Address
+	Value     *big.Int
+}
+
+// TotalVotesForEligibleValidators returns the eligible validators with their
+// total votes, in the order of the on-chain sorted list.
+func (c *Client) TotalVotesForEligibleValidators(ctx context.Context) ([]VoteTotal, error) {
+	ret, err := c.call(ctx, c.cfg.Contracts.Election, electionABI(), "getTotalVotesForEligibleValidators")
+	if err != nil {
+		return nil, err
+	}
+	validators := ret[0].([]common.Address)
+	values := ret[1].([]*big.Int)
+	totals := make([]VoteTotal, len(validators))
+	for i, validator := range validators {
+		totals[i] = VoteTotal{Validator: validator, Value: values[i]}
+	}
+	return totals, nil
+}
+
+// TotalVotesForValidator returns the total votes received by validator.
+func (c *Client) TotalVotesForValidator(ctx context.Context, validator common.Address) (*big.Int, error) {
+	ret, err := c.call(ctx, c.cfg.Contracts.Election, electionABI(), "getTotalVotesForValidator", validator)
+	if err != nil {
+		return nil, err
+	}
+	return ret[0].(*big.Int), nil
+}
+
+// VoteLesserGreater returns the lesser and greater neighbours validator will
+// have in the sorted eligible list once amount votes are added to it.
+func (c *Client) VoteLesserGreater(ctx context.Context, validator common.Address, amount *big.Int) (common.Address, common.Address, error) {
+	totals, err := c.TotalVotesForEligibleValidators(ctx)
+	if err != nil {
+		return common.Address{}, common.Address{}, err
+	}
+	found := false
+	for i, total := range totals {
+		if total.Validator == validator {
+			totals[i].Value = new(big.Int).Add(total.Value, amount)
+			found = true
+			break
+		}
+	}
+	if !found {
+		return common.Address{}, common.Address{}, ErrNotEligible
+	}
+	lesser, greater := lesserGreater(totals, validator)
+	return lesser, greater, nil
+}
+
+// Vote casts amount (in wei) of the signer's nonvoting locked gold for
+// validator. lesser and greater are the validator's neighbours in the sorted
+// eligible list after the vote, see VoteLesserGreater.
+func (c *Client) Vote(ctx context.Context, validator common.Address, amount *big.Int, lesser, greater common.Address) (common.Hash, error) {
+	return c.transact(ctx, c.cfg.Contracts.Election, nil, electionABI(), "vote", validator, amount, lesser, greater)
+}
+
+// lesserGreater sorts totals by descending votes, the order of the on-chain
+// list, and returns the neighbours of target in it.
+func lesserGreater(totals []VoteTotal, target common.Address) (lesser, greater common.Address) {
+	sort.SliceStable(totals, func(i, j int) bool {
+		return totals[i].Value.Cmp(totals[j].Value) > 0
+	})
+	for i, total := range totals {
+		if total.Validator != target {
+			continue
+		}
+		if i > 0 {
+			greater = totals[i-1].Validator
+		}
+		if i+1 < len(totals) {
+			lesser = totals[i+1].Validator
+		}
+		break
+	}
+	return lesser, greater
+}
@@ -0,0 +1,28 @@
+package client
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// LockGold locks amount (in wei) of the signer's balance in LockedGold.
+func (c *Client) LockGold(ctx context.Context, amount *big.Int) (common.Hash, error) {
+	return c.transact(ctx, c.cfg.Contracts.LockedGold, amount, lockedGoldABI(), "lock")
+}
+
+// UnlockGold starts unlocking amount (in wei) of the signer's nonvoting
+// locked gold, making it withdrawable after the unlocking period.
+func (c *Client) UnlockGold(ctx context.Context, amount *big.Int) (common.Hash, error) {
+	return c.transact(ctx, c.cfg.Contracts.LockedGold, nil, lockedGoldABI(), "unlock", amount)
+}
+
+// TotalLockedGold returns the total locked gold of account.
+func (c *Client) TotalLockedGold(ctx context.Context, account common.Address) (*big.Int, error) {
+	ret, err := c.call(ctx, c.cfg.Contracts.LockedGold, lockedGoldABI(), "getAccountTotalLockedGold", account)
+	if err != nil {
+		return nil, err
+	}
+	return ret[0].(*big.Int), nil
+}
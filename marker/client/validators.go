@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ValidatorParams holds the keys and commission a validator registers with.
+type ValidatorParams struct {
+	Commission *big.Int
+	// ECDSAPublicKey is the uncompressed signer public key without the
+	// 0x04 prefix.
+	ECDSAPublicKey []byte
+	BLSPublicKey   []byte
+	BLSG1PublicKey []byte
+	// BLSProof is the BLS proof of possession of the signer key over the
+	// validator account address.
+	BLSProof []byte
+}
+
+// IsPendingDeRegisterValidator reports whether the signer's validator is
+// waiting to be deregistered.
+func (c *Client) IsPendingDeRegisterValidator(ctx context.Context) (bool, error) {
+	ret, err := c.call(ctx, c.cfg.Contracts.Validators, validatorsABI(), "isPendingDeRegisterValidator")
+	if err != nil {
+		return false, err
+	}
+	return ret[0].(bool), nil
+}
+
+// RegisteredValidators returns the accounts of all registered validators.
+func (c *Client) RegisteredValidators(ctx context.Context) ([]common.Address, error) {
+	ret, err := c.call(ctx, c.cfg.Contracts.Validators, validatorsABI(), "getRegisteredValidators")
+	if err != nil {
+		return nil, err
+	}
+	return ret[0].([]common.Address), nil
+}
+
+// RegisterLesserGreater returns the lesser and greater neighbours the
+// signer's account will have in the sorted eligible list once registered
+// with the votes it already holds.
+func (c *Client) RegisterLesserGreater(ctx context.Context) (common.Address, common.Address, error) {
+	if c.signer == nil {
+		return common.Address{}, common.Address{}, ErrNoSigner
+	}
+	account := c.signer.Address()
+	votes, err := c.TotalVotesForValidator(ctx, account)
+	if err != nil {
+		return common.Address{}, common.Address{}, err
+	}
+	totals, err := c.TotalVotesForEligibleValidators(ctx)
+	if err != nil {
+		return common.Address{}, common.Address{}, err
+	}
+	lesser, greater := lesserGreater(append(totals, VoteTotal{Validator: account, Value: votes}), account)
+	return lesser, greater, nil
+}
+
+// RegisterValidator registers the signer's account as a validator.
+func (c *Client) RegisterValidator(ctx context.Context, params ValidatorParams) (common.Hash, error) {
+	lesser, greater, err := c.RegisterLesserGreater(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	keys := [][]byte{params.BLSPublicKey, params.BLSG1PublicKey, params.BLSProof, params.ECDSAPublicKey}
+	return c.transact(ctx, c.cfg.Contracts.Validators, nil, validatorsABI(), "registerValidator", params.Commission, lesser, greater, keys)
+}
+
+// UpdateBLSPublicKey registers new BLS keys for the signer's account without
+// touching the rest of its validator registration. Only the BLS fields of
+// params are used.
+func (c *Client) UpdateBLSPublicKey(ctx context.Context, params ValidatorParams) (common.Hash, error) {
+	return c.transact(ctx, c.cfg.Contracts.Validators, nil, validatorsABI(), "updateBlsPublicKey", params.BLSPublicKey, params.BLSG1PublicKey, params.BLSProof)
+}
+
+// DeregisterValidator starts deregistering the signer's validator.
+func (c *Client) DeregisterValidator(ctx context.Context) (common.Hash, error) {
+	return c.transact(ctx, c.cfg.Contracts.Validators, nil, validatorsABI(), "deregisterValidator")
+}
@@ -96,6 +96,21 @@ var libraries = []string{
 // Libraries returns all atlas-blockchain library names
 func Libraries() []string { return libraries }
 
+var coreContracts = []string{
+	"Registry",
+	"GoldToken",
+	"Accounts",
+	"LockedGold",
+	"Validators",
+	"Election",
+	"EpochRewards",
+	"Random",
+	"BlockchainParameters",
+}
+
+// CoreContracts returns the names of the proxied core contracts deployed in genesis
+func CoreContracts() []string { return coreContracts }
+
 // LibraryAddressFor obtains the address for a core contract
 func LibraryAddressFor(name string) (common.Address, error) {
 	address, ok := libraryAddresses[name]
@@ -152,3 +167,19 @@ func MustProxyAddressFor(name string) common.Address {
 	}
 	return address
 }
+
+// ReservedAddressName returns the name of the library or core contract (or
+// proxy) deployed at the given address in genesis, if any.
+func ReservedAddressName(address common.Address) (string, bool) {
+	for name, reserved := range libraryAddresses {
+		if reserved == address {
+			return name, true
+		}
+	}
+	for name, reserved := range genesisAddresses {
+		if reserved == address {
+			return name, true
+		}
+	}
+	return "", false
+}
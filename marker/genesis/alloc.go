@@ -0,0 +1,37 @@
+package genesis
+
+import (
+	"fmt"
+
+	"github.com/mapprotocol/atlas/core/chain"
+	"github.com/mapprotocol/atlas/marker/env"
+	"github.com/mapprotocol/atlas/marker/internal/utils"
+)
+
+// LoadAlloc reads a genesis allocation file in the standard geth format
+// (address -> {balance, code, storage, nonce}).
+func LoadAlloc(path string) (chain.GenesisAlloc, error) {
+	alloc := make(chain.GenesisAlloc)
+	if err := utils.ReadJson(&alloc, path); err != nil {
+		return nil, fmt.Errorf("failed to read alloc file %s: %v", path, err)
+	}
+	return alloc, nil
+}
+
+// MergeAlloc adds the given allocations to the genesis on top of the generated
+// system contract state. Entries for an address reserved for a system contract
+// are rejected; any other existing entry is overwritten.
+func MergeAlloc(genesis *chain.Genesis, alloc chain.GenesisAlloc) error {
+	for address := range alloc {
+		if name, ok := env.ReservedAddressName(address); ok {
+			return fmt.Errorf("alloc entry %s collides with reserved system contract address of %s", address.Hex(), name)
+		}
+	}
+	if genesis.Alloc == nil {
+		genesis.Alloc = make(chain.GenesisAlloc, len(alloc))
+	}
+	for address, account := range alloc {
+		genesis.Alloc[address] = account
+	}
+	return nil
+}
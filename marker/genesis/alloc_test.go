@@ -0,0 +1,114 @@
+package genesis
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/mapprotocol/atlas/core/chain"
+	"github.com/mapprotocol/atlas/core/rawdb"
+	"github.com/mapprotocol/atlas/core/state"
+	"github.com/mapprotocol/atlas/marker/env"
+	"github.com/mapprotocol/atlas/marker/internal/utils"
+	"github.com/mapprotocol/atlas/params"
+)
+
+func writeAllocFile(t *testing.T, dir, content string) string {
+	path := filepath.Join(dir, "alloc.json")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMergeAllocRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "marker-alloc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		funded   = common.HexToAddress("0x1000000000000000000000000000000000000001")
+		deployed = common.HexToAddress("0x1000000000000000000000000000000000000002")
+		system   = env.MustImplAddressFor("Accounts")
+		slot     = common.HexToHash("0x01")
+	)
+	alloc, err := LoadAlloc(writeAllocFile(t, dir, `{
+		"0x1000000000000000000000000000000000000001": {"balance": "0x3e8"},
+		"1000000000000000000000000000000000000002": {
+			"balance": "0x0",
+			"nonce": "0x5",
+			"code": "0x6001",
+			"storage": {"0x0000000000000000000000000000000000000000000000000000000000000001": "0x00000000000000000000000000000000000000000000000000000000000000ff"}
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("failed to load alloc: %v", err)
+	}
+	genesis := &chain.Genesis{
+		Config:   params.MainnetChainConfig,
+		GasLimit: 20000000,
+		Alloc: chain.GenesisAlloc{
+			system: {Balance: big.NewInt(0), Code: []byte{0x60, 0x00}},
+		},
+	}
+	if err := MergeAlloc(genesis, alloc); err != nil {
+		t.Fatalf("failed to merge alloc: %v", err)
+	}
+
+	// Round-trip through genesis.json and the chain's genesis loader
+	path := filepath.Join(dir, "genesis.json")
+	if err := utils.WriteJson(genesis, path); err != nil {
+		t.Fatal(err)
+	}
+	loaded := new(chain.Genesis)
+	if err := utils.ReadJson(loaded, path); err != nil {
+		t.Fatalf("failed to read genesis: %v", err)
+	}
+	db := rawdb.NewMemoryDatabase()
+	_, hash, err := chain.SetupGenesisBlock(db, loaded)
+	if err != nil {
+		t.Fatalf("failed to setup genesis: %v", err)
+	}
+	block := rawdb.ReadBlock(db, hash, 0)
+	statedb, err := state.New(block.Root(), state.NewDatabase(db), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance := statedb.GetBalance(funded); balance.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("funded balance mismatch: have %v, want %v", balance, 1000)
+	}
+	if nonce := statedb.GetNonce(deployed); nonce != 5 {
+		t.Errorf("deployed nonce mismatch: have %d, want %d", nonce, 5)
+	}
+	if code := statedb.GetCode(deployed); string(code) != "\x60\x01" {
+		t.Errorf("deployed code mismatch: have %x, want %x", code, []byte{0x60, 0x01})
+	}
+	if value := statedb.GetState(deployed, slot); value != common.HexToHash("0xff") {
+		t.Errorf("deployed storage mismatch: have %x, want %x", value, common.HexToHash("0xff"))
+	}
+	if code := statedb.GetCode(system); len(code) == 0 {
+		t.Errorf("system contract allocation lost")
+	}
+}
+
+func TestMergeAllocRejectsReserved(t *testing.T) {
+	reserved := env.MustProxyAddressFor("Validators")
+	genesis := &chain.Genesis{Alloc: chain.GenesisAlloc{}}
+	err := MergeAlloc(genesis, chain.GenesisAlloc{reserved: {Balance: big.NewInt(1)}})
+	if err == nil {
+		t.Fatalf("reserved address accepted")
+	}
+	if !strings.Contains(err.Error(), reserved.Hex()) {
+		t.Errorf("error does not name the address: %v", err)
+	}
+	if len(genesis.Alloc) != 0 {
+		t.Errorf("genesis modified on error")
+	}
+}
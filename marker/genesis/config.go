@@ -1,6 +1,7 @@
 package genesis
 
 import (
+	"errors"
 	"github.com/mapprotocol/atlas/helper/decimal/bigintstr"
 	"github.com/mapprotocol/atlas/helper/decimal/fixed"
 	"math/big"
@@ -45,7 +46,21 @@ func (cfg *Config) Save(filepath string) error {
 
 // ChainConfig returns the chain config objt for the blockchain
 func (cfg *Config) ChainConfig() *params.ChainConfig {
-	return params.MainnetChainConfig
+	chainConfig := *params.MainnetChainConfig
+	istanbul := cfg.Istanbul
+	chainConfig.Istanbul = &istanbul
+	return &chainConfig
+}
+
+// ValidateIstanbul checks the consistency of the istanbul parameters
+func (cfg *Config) ValidateIstanbul() error {
+	if cfg.Istanbul.BlockPeriod == 0 {
+		return errors.New("istanbul block period must be non-zero")
+	}
+	if cfg.Istanbul.Epoch == 0 {
+		return errors.New("istanbul epoch size must be non-zero")
+	}
+	return cfg.Istanbul.CheckLookbackWindows()
 }
 
 // HardforkConfig contains atlas hardforks activation blocks
@@ -126,7 +141,16 @@ type ValidatorsParameters struct {
 	CommissionUpdateDelay           uint64                 `json:"commissionUpdateDelay"`
 	PledgeMultiplierInReward        *fixed.Fixed           `json:"pledgeMultiplierInReward"`
 	DowntimeGracePeriod             uint64                 `json:"downtimeGracePeriod"`
-	Commission                      *big.Int               `json:"commission"` // commission for genesis registered validator
+	Commission                      *big.Int               `json:"commission"`                  // commission for genesis registered validator
+	InitialLockedGold               *big.Int               `json:"initialLockedGold,omitempty"` // locked and voted by each genesis validator, defaults to the locked gold requirement
+}
+
+// GenesisLockedGold returns the amount locked by each genesis validator
+func (v *ValidatorsParameters) GenesisLockedGold() *big.Int {
+	if v.InitialLockedGold != nil {
+		return v.InitialLockedGold
+	}
+	return v.ValidatorLockedGoldRequirements.Value
 }
 
 // EpochRewardsParameters are the initial configuration parameters for EpochRewards
@@ -0,0 +1,68 @@
+package genesis
+
+import (
+	"math/big"
+
+	"github.com/mapprotocol/atlas/core/chain"
+	"github.com/mapprotocol/atlas/marker/env"
+)
+
+// DevMnemonic is the well-known mnemonic the dev preset derives its accounts
+// from. Everybody knows the keys, never use it outside a local devnet.
+const DevMnemonic = "test test test test test test test test test test test junk"
+
+// Consensus parameters of the dev preset. The engine requires the lookback
+// window, at least 3 blocks, to be less than epoch-2, so 6 blocks is the
+// shortest epoch possible.
+const (
+	DevEpoch          = 6
+	DevBlockPeriod    = 1
+	DevLookbackWindow = 3
+)
+
+// DevAccountBalance is the balance each funded dev account starts with (1,000,000 MAP)
+var DevAccountBalance = new(big.Int).Mul(big.NewInt(1000000), big.NewInt(1e18))
+
+// DevConfig returns the genesis config of the dev preset: a short epoch and a
+// one second block period so a devnet produces blocks and epochs quickly
+func DevConfig() *Config {
+	genesisConfig := CreateCommonGenesisConfig()
+	genesisConfig.Istanbul.Epoch = DevEpoch
+	genesisConfig.Istanbul.BlockPeriod = DevBlockPeriod
+	genesisConfig.Istanbul.LookbackWindow = DevLookbackWindow
+	genesisConfig.Election.MinElectableValidators = 1
+	return genesisConfig
+}
+
+// DevAccountsConfig returns the accounts config of the dev preset with a single
+// validator and numAccounts funded developer accounts
+func DevAccountsConfig(numAccounts int) *env.AccountsConfig {
+	return &env.AccountsConfig{
+		Mnemonic:             DevMnemonic,
+		NumValidators:        1,
+		NumDeveloperAccounts: numAccounts,
+		UseValidatorAsAdmin:  true,
+	}
+}
+
+// DevAlloc funds each of the given accounts with DevAccountBalance
+func DevAlloc(accounts []env.Account) chain.GenesisAlloc {
+	alloc := make(chain.GenesisAlloc, len(accounts))
+	for _, account := range accounts {
+		alloc[account.Address] = chain.GenesisAccount{Balance: new(big.Int).Set(DevAccountBalance)}
+	}
+	return alloc
+}
+
+// UseDevValidator makes the dev validator the only genesis validator and the
+// admin of the core contracts
+func UseDevValidator(accounts *env.AccountsConfig) error {
+	validator := accounts.AdminAccount()
+	validators, err := ValidatorInfos([]ValidatorKey{{Keystore: "dev", Account: validator}})
+	if err != nil {
+		return err
+	}
+	ValidatorsAT = validators
+	AdminAddr = validator.Address
+	return nil
+}
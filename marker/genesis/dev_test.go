@@ -0,0 +1,77 @@
+package genesis
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mapprotocol/atlas/consensus/istanbul"
+	"github.com/mapprotocol/atlas/core/chain"
+	"github.com/mapprotocol/atlas/core/rawdb"
+	"github.com/mapprotocol/atlas/core/state"
+)
+
+func TestDevAccountsDeterministic(t *testing.T) {
+	first := DevAccountsConfig(3).DeveloperAccounts()
+	second := DevAccountsConfig(3).DeveloperAccounts()
+	if len(first) != 3 {
+		t.Fatalf("account count mismatch: have %d, want %d", len(first), 3)
+	}
+	for i := range first {
+		if first[i].Address != second[i].Address || first[i].PrivateKeyHex() != second[i].PrivateKeyHex() {
+			t.Errorf("account %d not deterministic: %v != %v", i, first[i].Address, second[i].Address)
+		}
+	}
+}
+
+// TestDevGenesisSetup boots the dev preset through the core genesis setup. The
+// system contracts need the truffle build artifacts, so only the parts produced
+// by the preset itself are included.
+func TestDevGenesisSetup(t *testing.T) {
+	accounts := DevAccountsConfig(2)
+	if err := UseDevValidator(accounts); err != nil {
+		t.Fatalf("failed to set up dev validator: %v", err)
+	}
+	if len(ValidatorsAT) != 1 || ValidatorsAT[0].getAddress() != accounts.AdminAccount().Address || AdminAddr != accounts.AdminAccount().Address {
+		t.Fatalf("dev validator not installed: %v", ValidatorsAT)
+	}
+	genesisConfig := DevConfig()
+	if err := genesisConfig.ValidateIstanbul(); err != nil {
+		t.Fatalf("invalid dev config: %v", err)
+	}
+	// The node builds its engine config with the same checks
+	engineConfig := *istanbul.DefaultConfig
+	if err := istanbul.ApplyParamsChainConfigToConfig(genesisConfig.ChainConfig(), &engineConfig); err != nil {
+		t.Fatalf("dev config rejected by the engine: %v", err)
+	}
+	extraData, err := generateGenesisExtraData(ValidatorsAT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	developers := accounts.DeveloperAccounts()
+	data, err := json.Marshal(NewGenesis(genesisConfig, extraData, DevAlloc(developers)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	genesis := new(chain.Genesis)
+	if err := json.Unmarshal(data, genesis); err != nil {
+		t.Fatalf("failed to decode genesis: %v", err)
+	}
+	db := rawdb.NewMemoryDatabase()
+	config, hash, err := chain.SetupGenesisBlock(db, genesis)
+	if err != nil {
+		t.Fatalf("failed to set up genesis: %v", err)
+	}
+	if config.Istanbul.Epoch != DevEpoch || config.Istanbul.BlockPeriod != DevBlockPeriod {
+		t.Errorf("istanbul config mismatch: have %v", config.Istanbul)
+	}
+	statedb, err := state.New(rawdb.ReadBlock(db, hash, 0).Root(), state.NewDatabase(db), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, account := range developers {
+		if balance := statedb.GetBalance(account.Address); balance.Cmp(DevAccountBalance) != 0 {
+			t.Errorf("account %v balance mismatch: have %v, want %v", account.Address, balance, DevAccountBalance)
+		}
+	}
+}
@@ -54,10 +54,17 @@ func GenerateGenesis(_ *cli.Context, accounts *env.AccountsConfig, cfg *Config,
 	if err != nil {
 		return nil, err
 	}
-	genesis := chain.UseForGenesisBlock()
+	return NewGenesis(cfg, extraData, genesisAlloc), nil
+}
+
+// NewGenesis assembles a genesis from the default genesis block, the chain config
+// derived from cfg and the given extra data and allocations
+func NewGenesis(cfg *Config, extraData []byte, alloc chain.GenesisAlloc) *chain.Genesis {
+	genesis := *chain.UseForGenesisBlock()
+	genesis.Config = cfg.ChainConfig()
 	genesis.ExtraData = extraData
-	genesis.Alloc = genesisAlloc
-	return genesis, nil
+	genesis.Alloc = alloc
+	return &genesis
 }
 
 func generateGenesisExtraData(validatorAccounts []AccoutInfo) ([]byte, error) {
@@ -360,7 +360,7 @@ func (ctx *deployContext) createAccounts(accs []AccoutInfo, namePrefix string) e
 
 func (ctx *deployContext) registerValidators() error {
 	validatorAccounts := ValidatorsAT
-	requiredAmount := ctx.genesisConfig.Validators.ValidatorLockedGoldRequirements.Value
+	requiredAmount := ctx.genesisConfig.Validators.GenesisLockedGold()
 	if err := ctx.createAccounts(validatorAccounts, "validator"); err != nil {
 		return err
 	}
@@ -412,7 +412,7 @@ func (ctx *deployContext) voteForValidators() error {
 	election := ctx.contract("Election")
 
 	// value previously locked on registerValidatorGroups()
-	lockedGoldOnValidator := ctx.genesisConfig.Validators.ValidatorLockedGoldRequirements.Value
+	lockedGoldOnValidator := ctx.genesisConfig.Validators.GenesisLockedGold()
 
 	// current validator order (see `addFirstMember` on addValidatorsToGroup) is:
 	// [ validatorZero, validatorOne, ..., lastvalidator]
@@ -0,0 +1,111 @@
+package genesis
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+
+	"github.com/mapprotocol/atlas/marker/env"
+	"github.com/mapprotocol/atlas/marker/internal/utils"
+)
+
+// ValidatorKey is a genesis validator loaded from a keystore file
+type ValidatorKey struct {
+	Keystore string
+	Account  *env.Account
+}
+
+// ValidatorManifestEntry describes the node identity of a genesis validator.
+// Only the public node id, as in its enode URL, is recorded, the private key
+// stays in the keystore.
+type ValidatorManifestEntry struct {
+	Keystore string `json:"keystore"`
+	NodeID   string `json:"nodeId"`
+}
+
+// LoadValidatorKeystores decrypts every keystore file in dir with the given
+// password. Hidden files and subdirectories are skipped; any file that can't be
+// read or decrypted fails the whole load.
+func LoadValidatorKeystores(dir, password string) ([]ValidatorKey, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var keys []ValidatorKey
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, file.Name())
+		keyjson, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read keystore %s: %v", path, err)
+		}
+		key, err := keystore.DecryptKey(keyjson, password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt keystore %s: %v", path, err)
+		}
+		keys = append(keys, ValidatorKey{
+			Keystore: file.Name(),
+			Account:  &env.Account{Address: key.Address, PrivateKey: key.PrivateKey},
+		})
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no keystores found in %s", dir)
+	}
+	return keys, nil
+}
+
+// ValidatorInfos derives the ECDSA and BLS public keys and the proof of
+// possession of each validator. Every validator signs with its own account.
+func ValidatorInfos(keys []ValidatorKey) ([]AccoutInfo, error) {
+	infos := make([]AccoutInfo, len(keys))
+	for i, key := range keys {
+		blsPub, err := key.Account.BLSPublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("keystore %s: %v", key.Keystore, err)
+		}
+		blsG1Pub, err := key.Account.BLSG1PublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("keystore %s: %v", key.Keystore, err)
+		}
+		pop, err := key.Account.BLSProofOfPossession()
+		if err != nil {
+			return nil, fmt.Errorf("keystore %s: %v", key.Keystore, err)
+		}
+		blsPubText, _ := blsPub.MarshalText()
+		blsG1PubText, _ := blsG1Pub.MarshalText()
+		infos[i] = AccoutInfo{
+			Address:              key.Account.Address.Hex(),
+			SignerAddress:        key.Account.Address.Hex(),
+			PublicKeyHex:         hexutil.Encode(key.Account.PublicKey()),
+			BLSPubKey:            string(blsPubText),
+			BLSG1PubKey:          string(blsG1PubText),
+			BLSProofOfPossession: hexutil.Encode(pop),
+		}
+	}
+	return infos, nil
+}
+
+// ValidatorManifest maps each validator address to its node id
+func ValidatorManifest(keys []ValidatorKey) map[common.Address]ValidatorManifestEntry {
+	manifest := make(map[common.Address]ValidatorManifestEntry, len(keys))
+	for _, key := range keys {
+		manifest[key.Account.Address] = ValidatorManifestEntry{
+			Keystore: key.Keystore,
+			NodeID:   enode.PubkeyToIDV4(&key.Account.PrivateKey.PublicKey).String(),
+		}
+	}
+	return manifest
+}
+
+// WriteValidatorManifest writes the validator manifest as json to path
+func WriteValidatorManifest(keys []ValidatorKey, path string) error {
+	return utils.WriteJson(ValidatorManifest(keys), path)
+}
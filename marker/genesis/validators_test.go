@@ -0,0 +1,101 @@
+package genesis
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/google/uuid"
+)
+
+func writeTestKeystore(t *testing.T, dir, name, password string) *keystore.Key {
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := &keystore.Key{
+		Id:         uuid.New(),
+		Address:    crypto.PubkeyToAddress(privateKey.PublicKey),
+		PrivateKey: privateKey,
+	}
+	keyjson, err := keystore.EncryptKey(key, password, keystore.LightScryptN, keystore.LightScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, name), keyjson, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestLoadValidatorKeystores(t *testing.T) {
+	dir, err := ioutil.TempDir("", "marker-validators")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	first := writeTestKeystore(t, dir, "a.json", "secret")
+	second := writeTestKeystore(t, dir, "b.json", "secret")
+	ioutil.WriteFile(filepath.Join(dir, ".hidden"), []byte("garbage"), 0600)
+
+	keys, err := LoadValidatorKeystores(dir, "secret")
+	if err != nil {
+		t.Fatalf("failed to load keystores: %v", err)
+	}
+	if len(keys) != 2 || keys[0].Account.Address != first.Address || keys[1].Account.Address != second.Address {
+		t.Fatalf("loaded keys mismatch: %v", keys)
+	}
+
+	infos, err := ValidatorInfos(keys)
+	if err != nil {
+		t.Fatalf("failed to derive validator infos: %v", err)
+	}
+	for i, info := range infos {
+		if info.getAddress() != keys[i].Account.Address || info.SignerAddress_() != keys[i].Account.Address {
+			t.Errorf("validator %d: address mismatch", i)
+		}
+		if _, err := info.BLSPublicKey(); err != nil {
+			t.Errorf("validator %d: invalid bls public key: %v", i, err)
+		}
+		if _, err := info.BLSG1PublicKey(); err != nil {
+			t.Errorf("validator %d: invalid bls g1 public key: %v", i, err)
+		}
+		if _, err := info.BLSProofOfPossession_(); err != nil {
+			t.Errorf("validator %d: invalid proof of possession: %v", i, err)
+		}
+	}
+	if _, err := generateGenesisExtraData(infos); err != nil {
+		t.Errorf("failed to generate extra data: %v", err)
+	}
+
+	manifest := ValidatorManifest(keys)
+	wantID := enode.PubkeyToIDV4(&keys[0].Account.PrivateKey.PublicKey).String()
+	if entry := manifest[first.Address]; entry.Keystore != "a.json" || entry.NodeID != wantID {
+		t.Errorf("manifest entry mismatch: %+v", entry)
+	}
+}
+
+func TestLoadValidatorKeystoresFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "marker-validators")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestKeystore(t, dir, "good.json", "secret")
+	writeTestKeystore(t, dir, "other-password.json", "other")
+
+	_, err = LoadValidatorKeystores(dir, "secret")
+	if err == nil {
+		t.Fatalf("keystore with wrong password accepted")
+	}
+	if !strings.Contains(err.Error(), "other-password.json") {
+		t.Errorf("error does not name the keystore: %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package genesis
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/mapprotocol/atlas/consensus"
+	"github.com/mapprotocol/atlas/core/chain"
+	"github.com/mapprotocol/atlas/core/types"
+	"github.com/mapprotocol/atlas/marker/env"
+	"github.com/mapprotocol/atlas/params"
+)
+
+// GenesisError is a problem found in a genesis document, located by a path
+// into the JSON document (e.g. $.config.istanbul.epoch)
+type GenesisError struct {
+	Path string
+	Err  error
+}
+
+func (e *GenesisError) Error() string { return fmt.Sprintf("%s: %v", e.Path, e.Err) }
+
+// GenesisErrors is the list of all problems found in a genesis document
+type GenesisErrors []*GenesisError
+
+func (errs GenesisErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// VerifyGenesisFile verifies the genesis json file at path, see VerifyGenesis
+func VerifyGenesisFile(path string) (common.Hash, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return VerifyGenesis(data)
+}
+
+// VerifyGenesis checks that a genesis json document describes a chain atlas
+// can boot: the document must decode, the chain config must be consistent,
+// the extra data must hold the istanbul extra and all libraries and core
+// contracts (proxy and implementation) must be allocated with code at their
+// reserved addresses. On success the genesis block hash is returned, otherwise
+// a *GenesisError for undecodable documents or GenesisErrors listing every
+// violation.
+func VerifyGenesis(data []byte) (common.Hash, error) {
+	genesis, allocKeys, err := decodeGenesis(data)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	var errs GenesisErrors
+	report := func(path string, format string, args ...interface{}) {
+		errs = append(errs, &GenesisError{Path: path, Err: fmt.Errorf(format, args...)})
+	}
+
+	config := genesis.Config
+	if config == nil {
+		report("$.config", "missing")
+		return common.Hash{}, errs
+	}
+	if config.ChainID == nil {
+		report("$.config.chainId", "missing")
+	}
+	// The istanbul and relayer sections are checked on their own below, so
+	// that their errors are reported at their path.
+	forks := *config
+	forks.Istanbul, forks.Relayer = nil, nil
+	if err := forks.CheckConfigForkOrder(); err != nil {
+		report("$.config", "%v", err)
+	}
+	// The relayer anchor is decoded when the genesis block is built
+	if _, _, err := consensus.EthereumAnchor(config.Relayer); err != nil {
+		report("$.config.relayer", "%v", err)
+	}
+	if ist := config.Istanbul; ist == nil {
+		report("$.config.istanbul", "missing")
+	} else {
+		if ist.Epoch == 0 {
+			report("$.config.istanbul.epoch", "must be non-zero")
+		} else if err := ist.CheckLookbackWindows(); err != nil {
+			report("$.config.istanbul.lookbackwindow", "%v", err)
+		}
+		if ist.BlockPeriod == 0 {
+			report("$.config.istanbul.blockperiod", "must be non-zero")
+		}
+		if ist.RequestTimeout == 0 {
+			report("$.config.istanbul.requesttimeout", "must be non-zero")
+		}
+	}
+	if _, err := types.ExtractIstanbulExtra(&types.Header{Extra: genesis.ExtraData}); err != nil {
+		report("$.extraData", "invalid istanbul extra: %v", err)
+	}
+
+	checkCode := func(name string, address common.Address) {
+		key, ok := allocKeys[address]
+		if !ok {
+			report(allocPath(strings.ToLower(strings.TrimPrefix(address.Hex(), "0x"))), "%s missing", name)
+			return
+		}
+		if len(genesis.Alloc[address].Code) == 0 {
+			report(allocPath(key)+".code", "%s has no code", name)
+		}
+	}
+	for _, name := range env.Libraries() {
+		checkCode(name, env.MustLibraryAddressFor(name))
+	}
+	for _, name := range env.CoreContracts() {
+		checkCode(name+"Proxy", env.MustProxyAddressFor(name))
+		checkCode(name, env.MustImplAddressFor(name))
+	}
+	if len(errs) > 0 {
+		return common.Hash{}, errs
+	}
+	return genesis.ToBlock(nil).Hash(), nil
+}
+
+// decodeGenesis decodes the genesis document section by section so decoding
+// errors can be reported with their location. It also returns the original
+// alloc key of every address.
+func decodeGenesis(data []byte) (*chain.Genesis, map[common.Address]string, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, nil, jsonError("$", err)
+	}
+	for _, field := range []string{"config", "gasLimit", "alloc"} {
+		if _, ok := doc[field]; !ok {
+			return nil, nil, &GenesisError{Path: "$." + field, Err: errors.New("missing")}
+		}
+	}
+	if err := json.Unmarshal(doc["config"], new(params.ChainConfig)); err != nil {
+		return nil, nil, jsonError("$.config", err)
+	}
+
+	var alloc map[string]json.RawMessage
+	if err := json.Unmarshal(doc["alloc"], &alloc); err != nil {
+		return nil, nil, jsonError("$.alloc", err)
+	}
+	keys := make([]string, 0, len(alloc))
+	for key := range alloc {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	allocKeys := make(map[common.Address]string, len(alloc))
+	for _, key := range keys {
+		if !common.IsHexAddress(key) {
+			return nil, nil, &GenesisError{Path: allocPath(key), Err: errors.New("invalid address")}
+		}
+		if err := json.Unmarshal(alloc[key], new(chain.GenesisAccount)); err != nil {
+			return nil, nil, jsonError(allocPath(key), err)
+		}
+		address := common.HexToAddress(key)
+		if other, ok := allocKeys[address]; ok {
+			return nil, nil, &GenesisError{Path: allocPath(key), Err: fmt.Errorf("duplicate of %s", allocPath(other))}
+		}
+		allocKeys[address] = key
+	}
+
+	genesis := new(chain.Genesis)
+	if err := json.Unmarshal(data, genesis); err != nil {
+		return nil, nil, jsonError("$", err)
+	}
+	return genesis, allocKeys, nil
+}
+
+func allocPath(key string) string {
+	return fmt.Sprintf("$.alloc[%q]", key)
+}
+
+// jsonError locates a json decoding error below path
+func jsonError(path string, err error) *GenesisError {
+	switch err := err.(type) {
+	case *json.UnmarshalTypeError:
+		if err.Field != "" {
+			path += "." + err.Field
+		}
+		return &GenesisError{Path: path, Err: fmt.Errorf("cannot use %s as %v", err.Value, err.Type)}
+	case *json.SyntaxError:
+		return &GenesisError{Path: path, Err: fmt.Errorf("%v (at offset %d)", err, err.Offset)}
+	}
+	return &GenesisError{Path: path, Err: err}
+}
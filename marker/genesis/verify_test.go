@@ -0,0 +1,174 @@
+package genesis
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/mapprotocol/atlas/core/chain"
+	"github.com/mapprotocol/atlas/marker/env"
+	"github.com/mapprotocol/atlas/params"
+)
+
+func newVerifiableGenesis(t *testing.T) *chain.Genesis {
+	extraData, err := generateGenesisExtraData(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alloc := make(chain.GenesisAlloc)
+	code := []byte{0x60, 0x00}
+	for _, name := range env.Libraries() {
+		alloc[env.MustLibraryAddressFor(name)] = chain.GenesisAccount{Balance: big.NewInt(0), Code: code}
+	}
+	for _, name := range env.CoreContracts() {
+		alloc[env.MustProxyAddressFor(name)] = chain.GenesisAccount{Balance: big.NewInt(0), Code: code}
+		alloc[env.MustImplAddressFor(name)] = chain.GenesisAccount{Balance: big.NewInt(0), Code: code}
+	}
+	return NewGenesis(CreateCommonGenesisConfig(), extraData, alloc)
+}
+
+func marshalGenesis(t *testing.T, genesis *chain.Genesis) []byte {
+	data, err := json.Marshal(genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestVerifyGenesis(t *testing.T) {
+	genesis := newVerifiableGenesis(t)
+	hash, err := VerifyGenesis(marshalGenesis(t, genesis))
+	if err != nil {
+		t.Fatalf("valid genesis rejected: %v", err)
+	}
+	if want := genesis.ToBlock(nil).Hash(); hash != want {
+		t.Errorf("genesis hash mismatch: have %x, want %x", hash, want)
+	}
+}
+
+func TestVerifyGenesisViolations(t *testing.T) {
+	lockedGoldProxy := strings.ToLower(strings.TrimPrefix(env.MustProxyAddressFor("LockedGold").Hex(), "0x"))
+
+	tests := []struct {
+		name   string
+		modify func(g *chain.Genesis)
+		paths  []string
+	}{
+		{
+			name: "null config",
+			modify: func(g *chain.Genesis) {
+				g.Config = nil
+			},
+			paths: []string{"$.config"},
+		},
+		{
+			name: "lookback window too large",
+			modify: func(g *chain.Genesis) {
+				g.Config.Istanbul.LookbackWindow = g.Config.Istanbul.Epoch
+			},
+			paths: []string{"$.config.istanbul.lookbackwindow"},
+		},
+		{
+			name: "lookback window too small",
+			modify: func(g *chain.Genesis) {
+				g.Config.Istanbul.LookbackWindow = 2
+			},
+			paths: []string{"$.config.istanbul.lookbackwindow"},
+		},
+		{
+			name: "unsupported relayer chain",
+			modify: func(g *chain.Genesis) {
+				g.Config.Relayer = &params.RelayerConfig{ChainID: big.NewInt(5), AnchorHeader: json.RawMessage(`{}`), AnchorTD: big.NewInt(1)}
+			},
+			paths: []string{"$.config.relayer"},
+		},
+		{
+			name: "missing istanbul config",
+			modify: func(g *chain.Genesis) {
+				g.Config.Istanbul = nil
+			},
+			paths: []string{"$.config.istanbul"},
+		},
+		{
+			name: "missing system contract code",
+			modify: func(g *chain.Genesis) {
+				g.Alloc[env.MustProxyAddressFor("LockedGold")] = chain.GenesisAccount{Balance: big.NewInt(0)}
+				delete(g.Alloc, env.MustImplAddressFor("Election"))
+			},
+			paths: []string{`$.alloc["` + lockedGoldProxy + `"].code`, `$.alloc["` + strings.ToLower(strings.TrimPrefix(env.MustImplAddressFor("Election").Hex(), "0x")) + `"]`},
+		},
+		{
+			name: "invalid extra data",
+			modify: func(g *chain.Genesis) {
+				g.ExtraData = []byte{0x01}
+			},
+			paths: []string{"$.extraData"},
+		},
+	}
+	for _, test := range tests {
+		genesis := newVerifiableGenesis(t)
+		test.modify(genesis)
+		_, err := VerifyGenesis(marshalGenesis(t, genesis))
+		errs, ok := err.(GenesisErrors)
+		if !ok {
+			t.Errorf("%s: expected GenesisErrors, got %v", test.name, err)
+			continue
+		}
+		var paths []string
+		for _, e := range errs {
+			paths = append(paths, e.Path)
+		}
+		if strings.Join(paths, ",") != strings.Join(test.paths, ",") {
+			t.Errorf("%s: paths mismatch: have %v, want %v", test.name, paths, test.paths)
+		}
+	}
+}
+
+func TestVerifyGenesisDecodeErrors(t *testing.T) {
+	valid := marshalGenesis(t, newVerifiableGenesis(t))
+	tests := []struct {
+		name   string
+		modify func(doc map[string]interface{})
+		path   string
+	}{
+		{
+			name: "wrong epoch type",
+			modify: func(doc map[string]interface{}) {
+				doc["config"].(map[string]interface{})["istanbul"].(map[string]interface{})["epoch"] = "many"
+			},
+			path: "$.config.istanbul.epoch",
+		},
+		{
+			name: "invalid alloc address",
+			modify: func(doc map[string]interface{}) {
+				doc["alloc"].(map[string]interface{})["0xnotanaddress"] = map[string]interface{}{"balance": "0x0"}
+			},
+			path: `$.alloc["0xnotanaddress"]`,
+		},
+		{
+			name: "missing alloc",
+			modify: func(doc map[string]interface{}) {
+				delete(doc, "alloc")
+			},
+			path: "$.alloc",
+		},
+	}
+	for _, test := range tests {
+		var doc map[string]interface{}
+		if err := json.Unmarshal(valid, &doc); err != nil {
+			t.Fatal(err)
+		}
+		test.modify(doc)
+		data, _ := json.Marshal(doc)
+		_, err := VerifyGenesis(data)
+		gerr, ok := err.(*GenesisError)
+		if !ok {
+			t.Errorf("%s: expected *GenesisError, got %v", test.name, err)
+			continue
+		}
+		if gerr.Path != test.path {
+			t.Errorf("%s: path mismatch: have %s, want %s", test.name, gerr.Path, test.path)
+		}
+	}
+}
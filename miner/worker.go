@@ -367,7 +367,7 @@ func (w *worker) constructAndSubmitNewBlock(ctx context.Context) {
 		if w.fullTaskHook != nil {
 			w.fullTaskHook()
 		}
-		w.submitTaskToEngine(&task{receipts: b.receipts, state: b.state, block: block, createdAt: time.Now()})
+		w.submitTaskToEngine(&task{receipts: b.receipts, state: b.state, block: block, createdAt: time.Now()}, ctx.Done())
 
 		fees := totalFees(block, b.receipts)
 		log.Info("Commit new mining work", "number", block.Number(), "txs", b.tcount, "gas", block.GasUsed(),
@@ -448,7 +448,9 @@ func (w *worker) updatePendingBlock(b *blockState) {
 	w.snapshotState = b.state.Copy()
 }
 
-func (w *worker) submitTaskToEngine(task *task) {
+// submitTaskToEngine hands the task's block to the engine for sealing. The seal
+// request is abandoned once stop is closed, e.g. when a new head arrives.
+func (w *worker) submitTaskToEngine(task *task, stop <-chan struct{}) {
 	if w.newTaskHook != nil {
 		w.newTaskHook(task)
 	}
@@ -457,7 +459,7 @@ func (w *worker) submitTaskToEngine(task *task) {
 		return
 	}
 
-	if err := w.engine.Seal(w.chain, task.block); err != nil {
+	if err := w.engine.Seal(w.chain, task.block, stop); err != nil {
 		log.Warn("Block sealing failed", "err", err)
 	}
 }
@@ -3,6 +3,7 @@ package params
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -87,6 +88,10 @@ type ChainConfig struct {
 
 	DonutBlock *big.Int `json:"donutBlock,omitempty"` // Donut switch block (nil = no fork, 0 = already activated)
 
+	// EpochSnarkBlock activates the verification of the epoch SNARK data in the
+	// bodies of the last blocks of epochs
+	EpochSnarkBlock *big.Int `json:"epochSnarkBlock,omitempty"` // Epoch SNARK data switch block (nil = not verified, 0 = already activated)
+
 	//YoloV3Block   *big.Int `json:"yoloV3Block,omitempty"`   // YOLO v3: Gas repricings TODO @holiman add EIP references
 	EWASMBlock    *big.Int `json:"ewasmBlock,omitempty"`    // EWASM switch block (nil = no fork, 0 = already activated)
 	CatalystBlock *big.Int `json:"catalystBlock,omitempty"` // Catalyst switch block (nil = no fork, 0 = already on catalyst)
@@ -98,6 +103,10 @@ type ChainConfig struct {
 	// Various consensus engines
 	Istanbul *IstanbulConfig `json:"istanbul,omitempty"`
 
+	// Relayer is the anchor of the header store of the relayed chain, the
+	// Ethereum testnet default if nil
+	Relayer *RelayerConfig `json:"relayer,omitempty"`
+
 	// This does not belong here but passing it to every function is not possible since that breaks
 	// some implemented interfaces and introduces churn across the geth codebase.
 	FullHeaderChainAvailable bool // False for lightest Sync mode, true otherwise
@@ -137,6 +146,88 @@ type IstanbulConfig struct {
 	// have timeouts of this + additional time that increases with round
 	// number.
 	RequestTimeout uint64 `json:"requesttimeout,omitempty"`
+
+	// LookbackWindowChanges replace LookbackWindow from their activation
+	// block on, in increasing order of activation block.
+	LookbackWindowChanges []LookbackWindowChange `json:"lookbackwindowchanges,omitempty"`
+
+	// CompactValSetDiffBlock switches the validator set diffs of epoch headers
+	// to the compact encoding from this block on (nil = no fork).
+	CompactValSetDiffBlock *big.Int `json:"compactvalsetdiffblock,omitempty"`
+
+	// MaxHeaderExtraSize is the size in bytes above which the extra-data of a
+	// header from CompactValSetDiffBlock on is rejected, 64 KiB if zero.
+	MaxHeaderExtraSize uint64 `json:"maxheaderextrasize,omitempty"`
+}
+
+// IsCompactValSetDiff returns whether num is either equal to the compact
+// validator set diff switch block or greater.
+func (c *IstanbulConfig) IsCompactValSetDiff(num *big.Int) bool {
+	return isForked(c.CompactValSetDiffBlock, num)
+}
+
+// RelayerConfig is the header the store of the relayed source chain is
+// initialized with at genesis, given either as JSON or RLP encoded, along with
+// its total difficulty.
+type RelayerConfig struct {
+	ChainID         *big.Int        `json:"chainId"`                   // Chain id of the source chain
+	AnchorHeader    json.RawMessage `json:"anchorHeader,omitempty"`    // Anchor header in JSON
+	AnchorHeaderRLP hexutil.Bytes   `json:"anchorHeaderRLP,omitempty"` // Anchor header RLP encoded, instead of JSON
+	AnchorTD        *big.Int        `json:"anchorTD"`                  // Total difficulty of the chain up to the anchor header
+}
+
+// Check checks that the relayer config has a source chain, exactly one anchor
+// header encoding and a positive total difficulty. The anchor header itself is
+// decoded by the header store of the source chain.
+func (c *RelayerConfig) Check() error {
+	if c.ChainID == nil || c.ChainID.Sign() <= 0 {
+		return errors.New("relayer config: missing source chain id")
+	}
+	if (len(c.AnchorHeader) == 0) == (len(c.AnchorHeaderRLP) == 0) {
+		return errors.New("relayer config: exactly one of anchorHeader and anchorHeaderRLP must be set")
+	}
+	if c.AnchorTD == nil || c.AnchorTD.Sign() <= 0 {
+		return errors.New("relayer config: missing anchor total difficulty")
+	}
+	return nil
+}
+
+// LookbackWindowChange activates an uptime lookback window from a block on.
+type LookbackWindowChange struct {
+	Block  uint64 `json:"block"`
+	Window uint64 `json:"window"`
+}
+
+// minLookbackWindow is the smallest lookback window the uptime monitor accepts.
+const minLookbackWindow = 3
+
+// CheckLookbackWindows checks that the lookback window changes are ordered by
+// activation block and that every window is at least 3 blocks and leaves room
+// in the epoch for the 2 blocks not monitored at its end.
+func (c *IstanbulConfig) CheckLookbackWindows() error {
+	check := func(window uint64) error {
+		if window < minLookbackWindow {
+			return fmt.Errorf("istanbul lookback window %d is smaller than %d", window, minLookbackWindow)
+		}
+		if c.Epoch != 0 && window+2 >= c.Epoch {
+			return fmt.Errorf("istanbul lookback window %d must be less than epoch-2 (%d)", window, c.Epoch-2)
+		}
+		return nil
+	}
+	if c.LookbackWindow != 0 {
+		if err := check(c.LookbackWindow); err != nil {
+			return err
+		}
+	}
+	for i, change := range c.LookbackWindowChanges {
+		if i > 0 && change.Block <= c.LookbackWindowChanges[i-1].Block {
+			return fmt.Errorf("istanbul lookback window change at block %d is not after block %d", change.Block, c.LookbackWindowChanges[i-1].Block)
+		}
+		if err := check(change.Window); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // String implements the stringer interface, returning the consensus engine details.
@@ -244,6 +335,11 @@ func (c *ChainConfig) IsEWASM(num *big.Int) bool {
 	return isForked(c.EWASMBlock, num)
 }
 
+// IsEpochSnark returns whether num is either equal to the epoch SNARK data switch block or greater.
+func (c *ChainConfig) IsEpochSnark(num *big.Int) bool {
+	return isForked(c.EpochSnarkBlock, num)
+}
+
 // CheckCompatible checks whether scheduled fork transitions have been imported
 // with a mismatching chain configuration.
 func (c *ChainConfig) CheckCompatible(newcfg *ChainConfig, height uint64) *ConfigCompatError {
@@ -303,6 +399,14 @@ func (c *ChainConfig) CheckConfigForkOrder() error {
 			lastFork = cur
 		}
 	}
+	if c.Relayer != nil {
+		if err := c.Relayer.Check(); err != nil {
+			return err
+		}
+	}
+	if c.Istanbul != nil {
+		return c.Istanbul.CheckLookbackWindows()
+	}
 	return nil
 }
 
@@ -356,6 +460,12 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.EWASMBlock, newcfg.EWASMBlock, head) {
 		return newCompatError("ewasm fork block", c.EWASMBlock, newcfg.EWASMBlock)
 	}
+	if isForkIncompatible(c.EpochSnarkBlock, newcfg.EpochSnarkBlock, head) {
+		return newCompatError("epoch SNARK data block", c.EpochSnarkBlock, newcfg.EpochSnarkBlock)
+	}
+	if c.Istanbul != nil && newcfg.Istanbul != nil && isForkIncompatible(c.Istanbul.CompactValSetDiffBlock, newcfg.Istanbul.CompactValSetDiffBlock, head) {
+		return newCompatError("compact validator set diff block", c.Istanbul.CompactValSetDiffBlock, newcfg.Istanbul.CompactValSetDiffBlock)
+	}
 	return nil
 }
 